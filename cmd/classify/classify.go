@@ -20,6 +20,7 @@ import (
 	"github.com/baalimago/kinoview/internal/media"
 	"github.com/baalimago/kinoview/internal/media/storage"
 	"github.com/baalimago/kinoview/internal/media/stream"
+	"github.com/baalimago/kinoview/internal/media/titles"
 	"github.com/baalimago/kinoview/internal/model"
 )
 
@@ -39,12 +40,14 @@ type command struct {
 
 	flagset *flag.FlagSet
 
-	model   *string
-	workers *int
+	model         *string
+	escalateBelow *float64
+	workers       *int
 
 	rate     *float64
 	burst    *int
 	cooldown *time.Duration
+	titles   *string
 
 	store stationStorage
 
@@ -112,10 +115,21 @@ func (c *command) Setup(ctx context.Context) error {
 	if c.cooldown != nil {
 		storeOpts = append(storeOpts, storage.WithClassificationStartupCooldown(*c.cooldown))
 	}
+	var titleDataset *titles.Dataset
+	if c.titles != nil && *c.titles != "" {
+		titleDataset, err = titles.Open(*c.titles)
+		if err != nil {
+			return fmt.Errorf("-titles: %w", err)
+		}
+		// Up front, rather than stalling the first classification
+		if err := titleDataset.Index(); err != nil {
+			return fmt.Errorf("-titles: %w", err)
+		}
+		storeOpts = append(storeOpts, storage.WithMetadataProvider(titleDataset))
+	}
 	c.store = storage.NewStore(storeOpts...)
 
 	classifierConf := models.Configurations{
-		Model:     *c.model,
 		ConfigDir: c.configDir,
 		InternalTools: []models.ToolName{
 			models.CatTool,
@@ -126,18 +140,41 @@ func (c *command) Setup(ctx context.Context) error {
 		},
 	}
 
+	var classifierTools []models.LLMTool
 	// Fetch subtitles tool (if OpenSubtitles API key is configured)
 	if subsManager != nil {
-		fetchTool := tools.NewFetchSubtitlesTool(c.store, subsManager, subsCacheDir)
+		fetchTool := tools.NewFetchSubtitlesTool(c.store, subsManager, subsCacheDir, nil)
 		if fetchTool != nil {
-			c.store.SetClassifier(classifier.NewWithTools(classifierConf, []models.LLMTool{fetchTool}))
+			classifierTools = append(classifierTools, fetchTool)
 		} else {
-			ancli.Warnf("OPENSUBTITLES_API_KEY not set — fetch_subtitles tool will not be available")
-			c.store.SetClassifier(classifier.New(classifierConf))
+			ancli.Warnf("neither OPENSUBTITLES_API_KEY nor KINOVIEW_SUBTITLE_DIR set — fetch_subtitles tool will not be available")
+		}
+	}
+	if titleDataset != nil {
+		lookupTool, err := tools.NewLookupTitleTool(titleDataset)
+		if err != nil {
+			return fmt.Errorf("failed to create lookup_title tool: %w", err)
+		}
+		classifierTools = append(classifierTools, lookupTool)
+	}
+	routerOpts := []classifier.RouterOption{
+		classifier.WithOutcomeLog(path.Join(c.configDir, "classifier_outcomes.jsonl")),
+	}
+	if c.escalateBelow != nil {
+		routerOpts = append(routerOpts, classifier.WithEscalationThreshold(*c.escalateBelow))
+	}
+	clifier, err := classifier.NewRouted(*c.model, func(m string) agents.Classifier {
+		conf := classifierConf
+		conf.Model = m
+		if len(classifierTools) > 0 {
+			return classifier.NewWithTools(conf, classifierTools)
 		}
-	} else {
-		c.store.SetClassifier(classifier.New(classifierConf))
+		return classifier.New(conf)
+	}, routerOpts...)
+	if err != nil {
+		return fmt.Errorf("-model: %w", err)
 	}
+	c.store.SetClassifier(clifier)
 
 	_, err = c.store.Setup(ctx)
 	if err != nil {
@@ -227,11 +264,13 @@ func (c *command) Run(ctx context.Context) error {
 func (c *command) Flagset() *flag.FlagSet {
 	fs := flag.NewFlagSet("classify", flag.ContinueOnError)
 
-	c.model = fs.String("model", "gpt-5", "set to LLM text model you'd like to use for the classifier. Supports multiple vendors automatically via clai.")
+	c.model = fs.String("model", "gpt-5", "set to LLM text model you'd like to use for the classifier. Supports multiple vendors automatically via clai. Several models separated by ',' are tried cheapest first, and routes separated by ';' pick them by mime type, see the -classifier flag of serve.")
+	c.escalateBelow = fs.Float64("escalateBelow", classifier.DefaultEscalationThreshold, "confidence, from 0 to 1, below which a classification is handed on to the next model, 0 to escalate on failures only")
 	c.workers = fs.Int("workers", 2, "set amount of workers to classify the media with")
 	c.rate = fs.Float64("classificationRate", 0.2, "classifications per second")
 	c.burst = fs.Int("classificationBurst", 3, "max burst before rate limit kicks in")
 	c.cooldown = fs.Duration("classificationStartupCooldown", 10*time.Second, "delay before first classification is admitted")
+	c.titles = fs.String("titles", "", "path to a TSV dataset of titles, such as IMDb's title.basics.tsv, to look metadata up in before asking the classifier")
 
 	c.flagset = fs
 	return fs
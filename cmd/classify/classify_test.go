@@ -57,6 +57,14 @@ func (m *mockStorage) VideoHandlerFunc() http.HandlerFunc {
 	return nil
 }
 
+func (m *mockStorage) AudioHandlerFunc() http.HandlerFunc {
+	return nil
+}
+
+func (m *mockStorage) ThumbnailHandlerFunc() http.HandlerFunc {
+	return nil
+}
+
 func (m *mockStorage) ImageHandlerFunc() http.HandlerFunc {
 	return nil
 }
@@ -69,6 +77,14 @@ func (m *mockStorage) StreamHandlerFunc() http.HandlerFunc {
 	return nil
 }
 
+func (m *mockStorage) AttachmentHandlerFunc() http.HandlerFunc {
+	return nil
+}
+
+func (m *mockStorage) DualStreamHandlerFunc() http.HandlerFunc {
+	return nil
+}
+
 func (m *mockStorage) AddToClassificationQueue(i model.Item) {
 	m.addToClassificationCalls++
 	m.addedItems = append(m.addedItems, i)
@@ -0,0 +1,316 @@
+package llm
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+// ── scoreboard subcommand ──────────────────────────────────────────────────
+
+type scoreboardCmd struct {
+	flagset  *flag.FlagSet
+	dir      *string
+	outcomes *string
+	since    *time.Duration
+	asJSON   *bool
+}
+
+func scoreboardCommand() *scoreboardCmd {
+	return &scoreboardCmd{}
+}
+
+func (c *scoreboardCmd) Describe() string {
+	return "Score the classifier models by success rate and cost."
+}
+
+func (c *scoreboardCmd) Help() string {
+	return "Score each classifier model by how often its classifications were accepted,\n" +
+		"and what they cost, joining the outcomes the classifier records with the cost\n" +
+		"of the classifier queries in clai's persisted conversation files.\n" +
+		"Defaults to ~/.config/kinoview/classifier_outcomes.jsonl and\n" +
+		"~/.config/kinoview/clai/conversations.\n\n" +
+		"Flags:\n" +
+		"  --dir       Override conversation directory.\n" +
+		"  --outcomes  Override classifier outcomes file.\n" +
+		"  --since     Only include outcomes and queries within this duration (e.g. 168h).\n" +
+		"  --json      Output machine-readable JSON.\n\n" +
+		"An attempt is accepted when it's kept, unsure when it was handed on to the\n" +
+		"next model for low confidence and failed when the model erred.\n" +
+		"NOTE: cost_usd is clai-reported, see 'kinoview llm usage --help'."
+}
+
+func (c *scoreboardCmd) Setup(ctx context.Context) error {
+	return nil
+}
+
+func (c *scoreboardCmd) Flagset() *flag.FlagSet {
+	fs := flag.NewFlagSet("scoreboard", flag.ContinueOnError)
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		configDir = "~/.config"
+	}
+	c.dir = fs.String("dir", path.Join(configDir, "kinoview", "clai", "conversations"), "clai conversations directory")
+	c.outcomes = fs.String("outcomes", path.Join(configDir, "kinoview", "classifier_outcomes.jsonl"), "classifier outcomes file")
+	c.since = fs.Duration("since", 0, "only include outcomes and queries within this duration (e.g. 168h)")
+	c.asJSON = fs.Bool("json", false, "output as JSON")
+	c.flagset = fs
+	return fs
+}
+
+func (c *scoreboardCmd) Run(ctx context.Context) error {
+	cutoff := time.Time{}
+	if *c.since > 0 {
+		cutoff = time.Now().Add(-*c.since)
+	}
+	sb := newScoreboard(cutoff)
+
+	skipped, err := sb.readOutcomes(*c.outcomes)
+	if err != nil {
+		return err
+	}
+	if skipped > 0 {
+		fmt.Fprintf(os.Stderr, "skipped %d unreadable outcomes\n", skipped)
+	}
+
+	// The cost is a nice to have, the outcomes alone make a scoreboard
+	if info, err := os.Stat(*c.dir); err == nil && info.IsDir() {
+		err = filepath.WalkDir(*c.dir, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !strings.HasSuffix(d.Name(), ".json") {
+				return nil
+			}
+			agent, queries, parseErr := parseConversation(p)
+			if parseErr != nil || agent != "classifier" {
+				return nil
+			}
+			sb.addQueries(queries)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to walk conversation directory: %w", err)
+		}
+	} else {
+		fmt.Fprintf(os.Stderr, "conversation directory not accessible, scoring without cost: %s\n", *c.dir)
+	}
+
+	rows := sb.rows()
+	if len(rows) == 0 {
+		fmt.Println("no classifier outcomes found")
+		return nil
+	}
+	if *c.asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(rows); err != nil {
+			return fmt.Errorf("failed to encode JSON: %w", err)
+		}
+		return nil
+	}
+	printScoreboard(rows)
+	fmt.Println()
+	fmt.Println("cost_usd is clai-reported and may not match provider invoices.")
+	return nil
+}
+
+// ── scoring ────────────────────────────────────────────────────────────────
+
+type score struct {
+	attempts int
+	accepted int
+	unsure   int
+	failed   int
+	queries  int
+	costUSD  float64
+}
+
+type scoreboard struct {
+	cutoff time.Time
+	scores map[string]*score
+	// queries by the model clai reports, which may be a dated version of the
+	// model the classifier was configured with
+	queries map[string]*score
+}
+
+func newScoreboard(cutoff time.Time) *scoreboard {
+	return &scoreboard{
+		cutoff:  cutoff,
+		scores:  make(map[string]*score),
+		queries: make(map[string]*score),
+	}
+}
+
+// readOutcomes of the classifier at p, returning how many lines couldn't be
+// read. A missing file has no outcomes.
+func (s *scoreboard) readOutcomes(p string) (int, error) {
+	f, err := os.Open(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to open classifier outcomes: %w", err)
+	}
+	defer f.Close()
+	skipped := 0
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var o model.ClassificationOutcome
+		if err := json.Unmarshal(sc.Bytes(), &o); err != nil || o.Model == "" {
+			skipped++
+			continue
+		}
+		s.addOutcome(o)
+	}
+	if err := sc.Err(); err != nil {
+		return skipped, fmt.Errorf("failed to read classifier outcomes: %w", err)
+	}
+	return skipped, nil
+}
+
+func (s *scoreboard) addOutcome(o model.ClassificationOutcome) {
+	if !s.cutoff.IsZero() && o.Time.Before(s.cutoff) {
+		return
+	}
+	sc := s.score(s.scores, o.Model)
+	sc.attempts++
+	switch o.Result {
+	case model.ResultAccepted:
+		sc.accepted++
+	case model.ResultUnsure:
+		sc.unsure++
+	case model.ResultFailed:
+		sc.failed++
+	}
+}
+
+func (s *scoreboard) addQueries(queries []queryRecord) {
+	for _, q := range queries {
+		if !s.cutoff.IsZero() && q.CreatedAt.Before(s.cutoff) {
+			continue
+		}
+		sc := s.score(s.queries, q.Model)
+		sc.queries++
+		sc.costUSD += q.CostUSD
+	}
+}
+
+func (s *scoreboard) score(m map[string]*score, key string) *score {
+	sc := m[key]
+	if sc == nil {
+		sc = &score{}
+		m[key] = sc
+	}
+	return sc
+}
+
+// modelOf the query model: the scored model it is or, failing that, the
+// longest one it starts with. Itself if none.
+func (s *scoreboard) modelOf(queryModel string) string {
+	if _, ok := s.scores[queryModel]; ok {
+		return queryModel
+	}
+	ret := queryModel
+	best := 0
+	for m := range s.scores {
+		if len(m) > best && strings.HasPrefix(queryModel, m) {
+			ret, best = m, len(m)
+		}
+	}
+	return ret
+}
+
+type scoreRow struct {
+	Model    string  `json:"model"`
+	Attempts int     `json:"attempts"`
+	Accepted int     `json:"accepted"`
+	Unsure   int     `json:"unsure"`
+	Failed   int     `json:"failed"`
+	Success  float64 `json:"success_rate"`
+	Queries  int     `json:"queries"`
+	CostUSD  float64 `json:"cost_usd_clai_reported"`
+	// CostPerAccepted is the cost of each accepted classification, all
+	// attempts included. 0 when none was accepted.
+	CostPerAccepted float64 `json:"cost_usd_per_accepted"`
+}
+
+// rows of the scoreboard, highest success rate first. Models with queries
+// but no outcomes, such as those classifying before the outcomes were
+// recorded, are listed without attempts.
+func (s *scoreboard) rows() []scoreRow {
+	joined := make(map[string]*score, len(s.scores))
+	for m, sc := range s.scores {
+		c := *sc
+		joined[m] = &c
+	}
+	for qm, q := range s.queries {
+		sc := s.score(joined, s.modelOf(qm))
+		sc.queries += q.queries
+		sc.costUSD += q.costUSD
+	}
+
+	rows := make([]scoreRow, 0, len(joined))
+	for m, sc := range joined {
+		r := scoreRow{
+			Model:    m,
+			Attempts: sc.attempts,
+			Accepted: sc.accepted,
+			Unsure:   sc.unsure,
+			Failed:   sc.failed,
+			Queries:  sc.queries,
+			CostUSD:  sc.costUSD,
+		}
+		if sc.attempts > 0 {
+			r.Success = float64(sc.accepted) / float64(sc.attempts) * 100
+		}
+		if sc.accepted > 0 {
+			r.CostPerAccepted = sc.costUSD / float64(sc.accepted)
+		}
+		rows = append(rows, r)
+	}
+	slices.SortFunc(rows, func(a, b scoreRow) int {
+		switch {
+		case a.Success != b.Success:
+			if a.Success > b.Success {
+				return -1
+			}
+			return 1
+		case a.Attempts != b.Attempts:
+			return b.Attempts - a.Attempts
+		default:
+			return strings.Compare(a.Model, b.Model)
+		}
+	})
+	return rows
+}
+
+// ── table output ───────────────────────────────────────────────────────────
+
+func printScoreboard(rows []scoreRow) {
+	fmt.Printf("%-30s %8s %8s %7s %7s %8s %8s %12s %14s\n",
+		"MODEL", "ATTEMPTS", "ACCEPTED", "UNSURE", "FAILED", "SUCCESS", "QUERIES",
+		"COST_USD", "USD/ACCEPTED")
+	for _, r := range rows {
+		fmt.Printf("%-30s %8d %8d %7d %7d %7.1f%% %8d %12.4f %14.4f\n",
+			r.Model,
+			r.Attempts,
+			r.Accepted,
+			r.Unsure,
+			r.Failed,
+			r.Success,
+			r.Queries,
+			r.CostUSD,
+			r.CostPerAccepted,
+		)
+	}
+}
@@ -0,0 +1,103 @@
+package llm
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+func writeOutcomes(t *testing.T, p string, outcomes ...model.ClassificationOutcome) {
+	t.Helper()
+	f, err := os.Create(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	enc := json.NewEncoder(f)
+	for _, o := range outcomes {
+		if err := enc.Encode(o); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestScoreboard_Rows(t *testing.T) {
+	now := time.Now()
+	p := filepath.Join(t.TempDir(), "outcomes.jsonl")
+	writeOutcomes(t, p,
+		model.ClassificationOutcome{Time: now, Model: "gpt-4.1-mini", ItemID: "1", Result: model.ResultUnsure, Confidence: 0.3},
+		model.ClassificationOutcome{Time: now, Model: "gpt-5", ItemID: "1", Result: model.ResultAccepted, Confidence: 0.9},
+		model.ClassificationOutcome{Time: now, Model: "gpt-4.1-mini", ItemID: "2", Result: model.ResultAccepted, Confidence: 0.8},
+		model.ClassificationOutcome{Time: now, Model: "gpt-4.1-mini", ItemID: "3", Result: model.ResultFailed, Error: "boom"},
+		model.ClassificationOutcome{Time: now, Model: "gpt-4.1-mini", ItemID: "4", Result: model.ResultAccepted},
+	)
+	if err := os.WriteFile(p+".bad", []byte("{not json\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	sb := newScoreboard(time.Time{})
+	skipped, err := sb.readOutcomes(p)
+	if err != nil || skipped != 0 {
+		t.Fatalf("readOutcomes = %v, %v", skipped, err)
+	}
+	if skipped, err := sb.readOutcomes(p + ".bad"); err != nil || skipped != 1 {
+		t.Fatalf("readOutcomes of a corrupt file = %v, %v, want 1 skipped", skipped, err)
+	}
+	if skipped, err := sb.readOutcomes(p + ".missing"); err != nil || skipped != 0 {
+		t.Fatalf("readOutcomes of a missing file = %v, %v", skipped, err)
+	}
+
+	mini := sampleQuery(now, "gpt-4.1-mini-2025-04-14")
+	mini.CostUSD = 0.01
+	big := sampleQuery(now, "gpt-5")
+	big.CostUSD = 0.1
+	sb.addQueries([]queryRecord{mini, mini, mini, mini, big, sampleQuery(now, "gpt-4o")})
+
+	rows := sb.rows()
+	if len(rows) != 3 {
+		t.Fatalf("expected 3 rows, got %+v", rows)
+	}
+	byModel := make(map[string]scoreRow)
+	for _, r := range rows {
+		byModel[r.Model] = r
+	}
+
+	m := byModel["gpt-4.1-mini"]
+	if m.Attempts != 4 || m.Accepted != 2 || m.Unsure != 1 || m.Failed != 1 || m.Success != 50 {
+		t.Errorf("unexpected gpt-4.1-mini outcomes %+v", m)
+	}
+	if m.Queries != 4 || !nearly(m.CostUSD, 0.04) || !nearly(m.CostPerAccepted, 0.02) {
+		t.Errorf("expected the dated queries joined to gpt-4.1-mini, got %+v", m)
+	}
+	if g := byModel["gpt-5"]; g.Success != 100 || g.Queries != 1 || !nearly(g.CostPerAccepted, 0.1) {
+		t.Errorf("unexpected gpt-5 score %+v", g)
+	}
+	if o := byModel["gpt-4o"]; o.Attempts != 0 || o.Queries != 1 || o.CostPerAccepted != 0 {
+		t.Errorf("expected gpt-4o listed without attempts, got %+v", o)
+	}
+	if rows[0].Model != "gpt-5" || rows[2].Model != "gpt-4o" {
+		t.Errorf("expected the highest success rate first, got %+v", rows)
+	}
+}
+
+func TestScoreboard_Since(t *testing.T) {
+	now := time.Now()
+	sb := newScoreboard(now.Add(-time.Hour))
+	sb.addOutcome(model.ClassificationOutcome{Time: now.Add(-2 * time.Hour), Model: "old", Result: model.ResultAccepted})
+	sb.addOutcome(model.ClassificationOutcome{Time: now, Model: "new", Result: model.ResultAccepted})
+	sb.addQueries([]queryRecord{sampleQuery(now.Add(-2*time.Hour), "new"), sampleQuery(now, "new")})
+
+	rows := sb.rows()
+	if len(rows) != 1 || rows[0].Model != "new" || rows[0].Queries != 1 {
+		t.Errorf("expected only the recent outcome and query, got %+v", rows)
+	}
+}
+
+func nearly(a, b float64) bool {
+	d := a - b
+	return d < 1e-9 && d > -1e-9
+}
@@ -1,5 +1,6 @@
 // Package llm provides the `kinoview llm usage` subcommand for aggregating
-// per-query cost and token data from clai's persisted conversation files.
+// per-query cost and token data from clai's persisted conversation files,
+// and `kinoview llm scoreboard`, which scores the classifier models by it.
 //
 // Every conversation JSON under the clai conversations directory is streamed,
 // decoded one at a time, attributed to an agent via its system prompt, and
@@ -30,7 +31,8 @@ Commands:
 %v`
 
 var subcommands = map[string]cmd.Command{
-	"u|usage": usageCommand(),
+	"u|usage":      usageCommand(),
+	"s|scoreboard": scoreboardCommand(),
 }
 
 func run(ctx context.Context, args []string) int {
@@ -50,7 +52,7 @@ func (c *topCommand) Describe() string {
 }
 
 func (c *topCommand) Help() string {
-	return "Use 'llm usage' to aggregate and report LLM usage data."
+	return "Use 'llm usage' to aggregate and report LLM usage data, 'llm scoreboard' to score the classifier models."
 }
 
 func (c *topCommand) Setup(ctx context.Context) error {
@@ -16,7 +16,7 @@ import (
 )
 
 func TestDeriveClassificationState(t *testing.T) {
-	raw := json.RawMessage(`{"title":"X"}`)
+	raw := model.MediaMetadata{Name: "X"}
 	tests := []struct {
 		name        string
 		item        model.Item
@@ -66,7 +66,7 @@ func writeItemFile(t *testing.T, dir string, it model.Item) {
 
 func TestReadItemStates(t *testing.T) {
 	dir := t.TempDir()
-	raw := json.RawMessage(`{"title":"Done"}`)
+	raw := model.MediaMetadata{Name: "Done"}
 	writeItemFile(t, dir, model.Item{ID: "done", Name: "Done.mkv", MIMEType: "video/mp4", Metadata: &raw, ClassificationAttempts: 2})
 	writeItemFile(t, dir, model.Item{ID: "queued", Name: "Queued.mkv", MIMEType: "video/mp4"})
 
@@ -192,7 +192,7 @@ func runWatch(t *testing.T, ctx context.Context, dir, label string, items []mode
 
 func TestWatchClassificationProgress_FinishesWhenAllAttempted(t *testing.T) {
 	dir := t.TempDir()
-	raw := json.RawMessage(`{"title":"Done"}`)
+	raw := model.MediaMetadata{Name: "Done"}
 	a := model.Item{ID: "a", Name: "A.mkv", MIMEType: "video/mp4"}
 	b := model.Item{ID: "b", Name: "B.mkv", MIMEType: "video/mp4"}
 	writeItemFile(t, dir, a)
@@ -243,7 +243,7 @@ func TestWatchClassificationProgress_QuitStops(t *testing.T) {
 
 func TestWatchClassificationProgress_AlreadyDoneExitsImmediately(t *testing.T) {
 	dir := t.TempDir()
-	raw := json.RawMessage(`{"title":"Done"}`)
+	raw := model.MediaMetadata{Name: "Done"}
 	item := model.Item{ID: "a", Name: "A.mkv", MIMEType: "video/mp4", Metadata: &raw}
 	writeItemFile(t, dir, item)
 
@@ -456,7 +456,7 @@ func TestPrintFinalReport(t *testing.T) {
 }
 
 func TestPrintGroupSummary(t *testing.T) {
-	raw := json.RawMessage(`{"title":"X"}`)
+	raw := model.MediaMetadata{Name: "X"}
 	row := mediaRow{
 		kind:     rowGroup,
 		groupKey: "/media/Show/Season 1",
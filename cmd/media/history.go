@@ -0,0 +1,115 @@
+package media
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/baalimago/go_away_boilerplate/pkg/ancli"
+	"github.com/baalimago/go_away_boilerplate/pkg/table"
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+// itemHistory prints the metadata history of item. With args "revert
+// <version>" it reverts the metadata to that version instead, confirming
+// unless forced.
+func (lc *listController) itemHistory(out io.Writer, item model.Item, args []string) error {
+	if len(args) == 0 {
+		changes, err := lc.store.History(item.ID)
+		if err != nil {
+			return fmt.Errorf("history: %w", err)
+		}
+		printHistory(out, item, changes)
+		return nil
+	}
+	if strings.ToLower(args[0]) != "revert" || len(args) < 2 {
+		return fmt.Errorf("unknown history action: %q (valid: revert <version>)", strings.Join(args, " "))
+	}
+	version, err := strconv.Atoi(args[1])
+	if err != nil {
+		return fmt.Errorf("invalid version: %q", args[1])
+	}
+	return lc.revertTo(item, version)
+}
+
+// revertTo reverts the metadata of item to version, confirming unless forced.
+func (lc *listController) revertTo(item model.Item, version int) error {
+	if !lc.force && !readYesNo(fmt.Sprintf("Revert the metadata of '%v' to version %v? (y/N): ", item.Name, version)) {
+		ancli.Noticef("Revert cancelled.")
+		return nil
+	}
+	reverted, err := lc.store.RevertMetadata(item.ID, version)
+	if err != nil {
+		return fmt.Errorf("revert: %w", err)
+	}
+	if reverted.Metadata == nil {
+		ancli.Okf("Reverted %v to version %v, which has no metadata — the server will reclassify it on its next pass.", item.Name, version)
+		return nil
+	}
+	ancli.Okf("Reverted %v to version %v: %v", item.Name, version, formatMetadata(*reverted.Metadata))
+	return nil
+}
+
+// printHistory prints every change of the metadata of item, oldest first,
+// with what made it and the fields it changed.
+func printHistory(out io.Writer, item model.Item, changes []model.MetadataChange) {
+	if len(changes) == 0 {
+		fmt.Fprintf(out, "\nNo metadata history for %v.\n", item.Name)
+		return
+	}
+	fmt.Fprintf(out, "\nMetadata history of %v:\n", item.Name)
+	for _, c := range changes {
+		line := fmt.Sprintf("  v%-3v %v  %v", c.Version, c.Time.Local().Format("2006-01-02 15:04"), formatProvenance(c.By))
+		if c.Note != "" {
+			line += " — " + c.Note
+		}
+		fmt.Fprintln(out, line)
+		for _, d := range c.Diff {
+			switch {
+			case d.From == nil:
+				fmt.Fprintf(out, "        + %v: %v\n", d.Field, truncateTo(string(d.To), 80))
+			case d.To == nil:
+				fmt.Fprintf(out, "        - %v: %v\n", d.Field, truncateTo(string(d.From), 80))
+			default:
+				fmt.Fprintf(out, "        ~ %v: %v -> %v\n", d.Field, truncateTo(string(d.From), 40), truncateTo(string(d.To), 40))
+			}
+		}
+	}
+}
+
+// formatProvenance as e.g. "agent concierge (gpt-4.1)".
+func formatProvenance(by model.Provenance) string {
+	s := string(by.Source)
+	if by.Actor != "" {
+		s += " " + by.Actor
+	}
+	if by.Model != "" {
+		s += " (" + by.Model + ")"
+	}
+	return s
+}
+
+// interactiveHistory prints the metadata history of item and offers to
+// revert to one of its versions.
+func (lc *listController) interactiveHistory(item model.Item) (done bool, back bool, err error) {
+	changes, err := lc.store.History(item.ID)
+	if err != nil {
+		return false, false, fmt.Errorf("history: %w", err)
+	}
+	printHistory(os.Stdout, item, changes)
+	if len(changes) == 0 {
+		return true, false, nil
+	}
+	fmt.Printf("\nRevert to version (enter to keep the metadata as it is): ")
+	input, err := table.ReadUserInput()
+	if err != nil || strings.TrimSpace(input) == "" {
+		return true, false, nil
+	}
+	version, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(input), "v"))
+	if err != nil {
+		return false, false, fmt.Errorf("invalid version: %q", input)
+	}
+	return true, false, lc.revertTo(item, version)
+}
@@ -0,0 +1,79 @@
+package media
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+func historyFixture() []model.MetadataChange {
+	t0 := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	return []model.MetadataChange{
+		{
+			Version:  1,
+			Time:     t0,
+			By:       model.Provenance{Source: model.SourceClassifier, Model: "gpt-4.1"},
+			Metadata: &model.MediaMetadata{Name: "Heat", Year: 1995},
+			Diff: []model.FieldChange{
+				{Field: "name", To: json.RawMessage(`"Heat"`)},
+				{Field: "year", To: json.RawMessage(`1995`)},
+			},
+		},
+		{
+			Version:  2,
+			Time:     t0.Add(time.Hour),
+			By:       model.Provenance{Source: model.SourceAgent, Actor: "concierge", Model: "gpt-4.1"},
+			Metadata: &model.MediaMetadata{Name: "Heat", Year: 1994},
+			Diff:     []model.FieldChange{{Field: "year", From: json.RawMessage(`1995`), To: json.RawMessage(`1994`)}},
+		},
+	}
+}
+
+func TestPrintHistory(t *testing.T) {
+	var out bytes.Buffer
+	printHistory(&out, model.Item{Name: "heat.mkv"}, historyFixture())
+	for _, want := range []string{
+		"classifier (gpt-4.1)",
+		"+ year: 1995",
+		"agent concierge (gpt-4.1)",
+		"~ year: 1995 -> 1994",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected %q in:\n%v", want, out.String())
+		}
+	}
+
+	out.Reset()
+	printHistory(&out, model.Item{Name: "heat.mkv"}, nil)
+	if !strings.Contains(out.String(), "No metadata history") {
+		t.Errorf("unexpected output without history: %v", out.String())
+	}
+}
+
+func TestItemHistory_revert(t *testing.T) {
+	store := &fakeMediaStore{history: historyFixture()}
+	lc := &listController{store: store, force: true}
+	item := model.Item{ID: "h", Name: "heat.mkv"}
+
+	if err := lc.itemHistory(io.Discard, item, []string{"revert", "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.reverted) != 1 || store.reverted[0] != 1 {
+		t.Errorf("expected a revert to version 1, got %v", store.reverted)
+	}
+
+	if err := lc.itemHistory(io.Discard, item, []string{"revert", "7"}); !errors.Is(err, model.ErrNoVersion) {
+		t.Errorf("expected ErrNoVersion, got %v", err)
+	}
+	for _, args := range [][]string{{"revert", "latest"}, {"revert"}, {"undo", "1"}} {
+		if err := lc.itemHistory(io.Discard, item, args); err == nil {
+			t.Errorf("%q: expected an error", args)
+		}
+	}
+}
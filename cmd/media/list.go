@@ -17,6 +17,7 @@ import (
 	"github.com/baalimago/go_away_boilerplate/pkg/ancli"
 	"github.com/baalimago/go_away_boilerplate/pkg/table"
 	"github.com/baalimago/kinoview/internal/media/storage"
+	"github.com/baalimago/kinoview/internal/media/stream"
 	"github.com/baalimago/kinoview/internal/model"
 )
 
@@ -32,6 +33,16 @@ type mediaStore interface {
 	ClearClassificationStopLoss(id string) (bool, error)
 	// ClassificationMaxAttempts is the ceiling itself.
 	ClassificationMaxAttempts() int
+	// History of an item's metadata, oldest first.
+	History(id string) ([]model.MetadataChange, error)
+	// RevertMetadata of an item to a version of its history.
+	RevertMetadata(id string, version int) (model.Item, error)
+}
+
+// subtitleSyncer is the subset of the stream manager needed to sync subtitles.
+type subtitleSyncer interface {
+	Find(item model.Item) (model.MediaInfo, error)
+	SyncSubtitles(item model.Item, streamIndex string) (string, error)
 }
 
 type listCmd struct {
@@ -68,6 +79,11 @@ for example); selecting a group row drills into its members, where [R]eclassify
 all resets the whole group.
 Navigate with n/p, filter with /pattern, select by index number.
 After selection: [i]nspect JSON, [d]elete, [r]eclassify, [s]ubtitles, [b]ack to table.
+[i]nspect history lists every change of the item's metadata: when, what made it
+(the classifier, an agent such as the concierge, or a human) and the fields it
+changed, and offers to revert to one of the versions.
+In [s]ubtitles, [s]ync <stream-index> retimes a subtitle stream to the speech in
+the audio track and writes the result into the server's subtitle cache.
 
 Macro mode: Each argument after "list" is processed sequentially.
 Group rows support: 0 r (reclassify the group), 0 i (group summary).
@@ -78,6 +94,9 @@ Examples:
   kinoview media list 0 s            # select and show subtitle info
   kinoview media list 0 sa /path/sub.srt  # select and associate subtitle file
   kinoview media list 0 sr 0         # select and remove subtitle at index 0
+  kinoview media list 0 s sync -1    # sync subtitle stream -1 to the audio
+  kinoview media list 0 i history    # select and show the metadata history
+  kinoview media list 0 i history revert 2  # revert the metadata to version 2
   kinoview media list --force 0 d    # delete without confirmation
   kinoview media list /season 0 r    # reclassify the whole group (confirms unless --force)`
 }
@@ -146,6 +165,8 @@ type listController struct {
 	force       bool
 	storePath   string
 	maxAttempts int
+	// subs is created on first use, see syncer.
+	subs subtitleSyncer
 }
 
 // ── Location grouping ──
@@ -446,7 +467,7 @@ func (lc *listController) runInteractive(ctx context.Context) error {
 }
 
 func (lc *listController) interactivePostSelect(ctx context.Context, item model.Item) (done bool, back bool, err error) {
-	fmt.Printf("\n(press [d]elete, [r]eclassify, [i]nspect JSON, [s]ubtitles, [b]ack to list, [q]uit): ")
+	fmt.Printf("\n(press [d]elete, [r]eclassify, [i]nspect JSON, [i history], [s]ubtitles, [b]ack to list, [q]uit): ")
 	input, inputErr := table.ReadUserInput()
 	if inputErr != nil {
 		if errors.Is(inputErr, table.ErrUserInitiatedExit) {
@@ -455,7 +476,7 @@ func (lc *listController) interactivePostSelect(ctx context.Context, item model.
 		return false, false, inputErr
 	}
 
-	switch strings.ToLower(input) {
+	switch strings.Join(strings.Fields(strings.ToLower(input)), " ") {
 	case "d":
 		if !lc.confirmDelete(item) {
 			ancli.Noticef("Delete cancelled.")
@@ -466,6 +487,8 @@ func (lc *listController) interactivePostSelect(ctx context.Context, item model.
 		}
 		ancli.Okf("Deleted: %v", item.Name)
 		return true, false, nil
+	case "i history":
+		return lc.interactiveHistory(item)
 	case "i":
 		data, err := json.MarshalIndent(item, "", "  ")
 		if err != nil {
@@ -596,7 +619,7 @@ func (lc *listController) interactiveSubtitleManager(item model.Item) (done bool
 			}
 		}
 
-		fmt.Printf("\n(press [a]dd path, [r]emove <index>, [b]ack): ")
+		fmt.Printf("\n(press [a]dd path, [r]emove <index>, [s]ync [stream-index], [b]ack): ")
 		input, inputErr := table.ReadUserInput()
 		if inputErr != nil {
 			if errors.Is(inputErr, table.ErrUserInitiatedExit) {
@@ -646,10 +669,74 @@ func (lc *listController) interactiveSubtitleManager(item model.Item) (done bool
 				continue
 			}
 			ancli.Okf("Removed subtitle: %v", removed)
+		case "s", "sync":
+			if err := lc.syncSubtitles(item, parts[1:]); err != nil {
+				ancli.Errf("Failed to sync: %v", err)
+			}
 		default:
-			ancli.Warnf("Unknown action: %q (valid: a, r, b)", parts[0])
+			ancli.Warnf("Unknown action: %q (valid: a, r, s, b)", parts[0])
+		}
+	}
+}
+
+// syncer returns the stream manager used to sync subtitles, set up against
+// the same directories as the server so that it serves the result.
+func (lc *listController) syncer() (subtitleSyncer, error) {
+	if lc.subs != nil {
+		return lc.subs, nil
+	}
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find cache dir: %w", err)
+	}
+	m, err := stream.NewManager(
+		stream.WithStoragePath(path.Join(path.Dir(lc.storePath), "subtitles")),
+		stream.WithSubtitleCachePath(path.Join(cacheDir, "kinoview")),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stream manager: %w", err)
+	}
+	lc.subs = m
+	return m, nil
+}
+
+// syncSubtitles retimes a subtitle stream of item to its audio. args may hold
+// the stream index; without it the first external subtitle is synced, as
+// fetched and sidecar files are the ones which tend to be off.
+func (lc *listController) syncSubtitles(item model.Item, args []string) error {
+	subs, err := lc.syncer()
+	if err != nil {
+		return err
+	}
+	var streamIdx string
+	if len(args) > 0 {
+		streamIdx = args[0]
+	} else {
+		info, err := subs.Find(item)
+		if err != nil {
+			return fmt.Errorf("find subtitle streams: %w", err)
 		}
+		for _, st := range info.Streams {
+			if st.CodecType == "subtitle" && st.ExternalPath != "" {
+				streamIdx = strconv.Itoa(st.Index)
+				break
+			}
+		}
+		if streamIdx == "" {
+			return errors.New("no external subtitles found, pass the index of the stream to sync")
+		}
+	}
+	if _, err := strconv.Atoi(streamIdx); err != nil {
+		return fmt.Errorf("invalid stream index: %q", streamIdx)
 	}
+
+	ancli.Noticef("Syncing subtitle stream %v of %v to its audio, this may take a minute...", streamIdx, item.Name)
+	p, err := subs.SyncSubtitles(item, streamIdx)
+	if err != nil {
+		return fmt.Errorf("sync subtitles: %w", err)
+	}
+	ancli.Okf("Synced subtitles written to: %v", p)
+	return nil
 }
 
 func (lc *listController) confirmDelete(item model.Item) bool {
@@ -824,6 +911,9 @@ func (lc *listController) runMacro(tokens []string) error {
 
 		switch strings.ToLower(action) {
 		case "i":
+			if len(tokens) > 0 && strings.ToLower(tokens[0]) == "history" {
+				return lc.itemHistory(os.Stdout, item, tokens[1:])
+			}
 			data, err := json.MarshalIndent(item, "", "  ")
 			if err != nil {
 				return fmt.Errorf("marshal item: %w", err)
@@ -847,6 +937,9 @@ func (lc *listController) runMacro(tokens []string) error {
 			ancli.Okf("Classification reset for %v — the server will reclassify it on its next pass.", item.Name)
 			return nil
 		case "s":
+			if len(tokens) > 0 && strings.ToLower(tokens[0]) == "sync" {
+				return lc.syncSubtitles(item, tokens[1:])
+			}
 			// Just print subtitle info and exit (summary already printed)
 			return nil
 		case "sa":
@@ -992,12 +1085,8 @@ func printItemSummary(item model.Item) {
 	}
 }
 
-func formatMetadata(raw json.RawMessage) string {
-	var m map[string]any
-	if err := json.Unmarshal(raw, &m); err != nil {
-		return string(raw)
-	}
-	compact, _ := json.Marshal(m)
+func formatMetadata(md model.MediaMetadata) string {
+	compact, _ := json.Marshal(md)
 	s := string(compact)
 	if len(s) > 120 {
 		s = s[:117] + "..."
@@ -223,7 +223,7 @@ func TestRowFormatter(t *testing.T) {
 	}
 
 	// With metadata.
-	raw := json.RawMessage(`{"title":"Test"}`)
+	raw := model.MediaMetadata{Name: "Test"}
 	item.Metadata = &raw
 	got = lc.itemRowFormatter(6, 0, item)
 	if len(got) == 0 {
@@ -268,7 +268,7 @@ func TestMaxIndexWidth(t *testing.T) {
 }
 
 func TestGroupRowFormatter(t *testing.T) {
-	raw := json.RawMessage(`{"title":"Ep"}`)
+	raw := model.MediaMetadata{Name: "Ep"}
 	members := []model.Item{
 		{Name: "Show.S01E01.mkv", MIMEType: "video/mp4", Path: "/media/Show/Season 1/Show.S01E01.mkv"},
 		{Name: "Show.S01E02.mkv", MIMEType: "video/mp4", Path: "/media/Show/Season 1/Show.S01E02.mkv", Metadata: &raw},
@@ -429,6 +429,8 @@ func TestResetItemsKeepsGoingOnError(t *testing.T) {
 type fakeMediaStore struct {
 	resetCalls []string
 	failIDs    map[string]bool
+	history    []model.MetadataChange
+	reverted   []int
 }
 
 func (f *fakeMediaStore) Snapshot() []model.Item                           { return nil }
@@ -436,6 +438,20 @@ func (f *fakeMediaStore) UpdateItem(model.Item) error                      { ret
 func (f *fakeMediaStore) DeleteItem(id string) error                       { return nil }
 func (f *fakeMediaStore) ClearClassificationStopLoss(string) (bool, error) { return false, nil }
 func (f *fakeMediaStore) ClassificationMaxAttempts() int                   { return 5 }
+func (f *fakeMediaStore) History(string) ([]model.MetadataChange, error) {
+	return f.history, nil
+}
+
+func (f *fakeMediaStore) RevertMetadata(id string, version int) (model.Item, error) {
+	for _, c := range f.history {
+		if c.Version == version {
+			f.reverted = append(f.reverted, version)
+			return model.Item{ID: id, Metadata: c.Metadata}, nil
+		}
+	}
+	return model.Item{}, model.ErrNoVersion
+}
+
 func (f *fakeMediaStore) ResetClassification(id string) (bool, error) {
 	f.resetCalls = append(f.resetCalls, id)
 	if f.failIDs != nil && f.failIDs[id] {
@@ -446,29 +462,29 @@ func (f *fakeMediaStore) ResetClassification(id string) (bool, error) {
 
 func TestFormatMetadata(t *testing.T) {
 	// Valid JSON.
-	raw := json.RawMessage(`{"title":"Inception","year":2010}`)
+	raw := model.MediaMetadata{Name: "Inception", Year: 2010}
 	got := formatMetadata(raw)
-	if len(got) == 0 {
-		t.Error("expected non-empty metadata")
+	if got != `{"name":"Inception","year":2010}` {
+		t.Errorf("unexpected metadata: %q", got)
 	}
 
 	// Long JSON gets truncated.
-	long := make(map[string]string)
+	raw = model.MediaMetadata{Extra: map[string]json.RawMessage{}}
 	for i := range 20 {
-		long[fmt.Sprintf("key%d", i)] = "value"
+		raw.Extra[fmt.Sprintf("key%d", i)] = json.RawMessage(`"value"`)
 	}
-	b, _ := json.Marshal(long)
-	raw = json.RawMessage(b)
 	got = formatMetadata(raw)
 	if len(got) > 123 { // 120 + "..."
 		t.Errorf("expected truncated metadata, got len=%d", len(got))
 	}
 
-	// Invalid JSON.
-	raw = json.RawMessage(`not json`)
+	// Not an object.
+	if err := json.Unmarshal([]byte(`"not an object"`), &raw); err != nil {
+		t.Fatal(err)
+	}
 	got = formatMetadata(raw)
-	if got != "not json" {
-		t.Errorf("expected raw string for invalid JSON, got %q", got)
+	if got != `"not an object"` {
+		t.Errorf("expected raw string for non-object metadata, got %q", got)
 	}
 }
 
@@ -482,7 +498,7 @@ func TestPrintItemSummary(t *testing.T) {
 	// Just verify it doesn't panic.
 	printItemSummary(item)
 
-	raw := json.RawMessage(`{"key":"val"}`)
+	raw := model.MediaMetadata{Extra: map[string]json.RawMessage{"key": json.RawMessage(`"val"`)}}
 	item.Metadata = &raw
 	item.ClassificationAttempts = 3
 	item.ClassificationError = "some error"
@@ -639,3 +655,63 @@ func TestSmartSplit(t *testing.T) {
 		})
 	}
 }
+
+// fakeSyncer implements subtitleSyncer.
+type fakeSyncer struct {
+	info   model.MediaInfo
+	synced []string
+}
+
+func (f *fakeSyncer) Find(model.Item) (model.MediaInfo, error) { return f.info, nil }
+func (f *fakeSyncer) SyncSubtitles(_ model.Item, streamIndex string) (string, error) {
+	f.synced = append(f.synced, streamIndex)
+	return "/tmp/" + streamIndex + ".synced.vtt", nil
+}
+
+func TestSyncSubtitles(t *testing.T) {
+	item := model.Item{ID: "abc", Name: "Movie.mkv"}
+
+	t.Run("explicit stream index", func(t *testing.T) {
+		subs := &fakeSyncer{}
+		lc := &listController{subs: subs}
+		if err := lc.syncSubtitles(item, []string{"3"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := strings.Join(subs.synced, ","); got != "3" {
+			t.Errorf("synced = %q, want %q", got, "3")
+		}
+	})
+
+	t.Run("defaults to first external subtitle", func(t *testing.T) {
+		subs := &fakeSyncer{info: model.MediaInfo{Streams: []model.Stream{
+			{Index: 1, CodecType: "audio"},
+			{Index: 2, CodecType: "subtitle"},
+			{Index: -1, CodecType: "subtitle", ExternalPath: "/media/Movie.en.srt"},
+		}}}
+		lc := &listController{subs: subs}
+		if err := lc.syncSubtitles(item, nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := strings.Join(subs.synced, ","); got != "-1" {
+			t.Errorf("synced = %q, want %q", got, "-1")
+		}
+	})
+
+	t.Run("no external subtitle needs an index", func(t *testing.T) {
+		subs := &fakeSyncer{info: model.MediaInfo{Streams: []model.Stream{{Index: 2, CodecType: "subtitle"}}}}
+		lc := &listController{subs: subs}
+		if err := lc.syncSubtitles(item, nil); err == nil {
+			t.Fatal("expected error without external subtitles")
+		}
+		if len(subs.synced) != 0 {
+			t.Fatalf("expected no sync, got: %v", subs.synced)
+		}
+	})
+
+	t.Run("invalid stream index", func(t *testing.T) {
+		lc := &listController{subs: &fakeSyncer{}}
+		if err := lc.syncSubtitles(item, []string{"first"}); err == nil {
+			t.Fatal("expected error for non-numeric index")
+		}
+	})
+}
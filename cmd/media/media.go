@@ -21,12 +21,22 @@ without starting the full server.
 Items which failed classification too many times are permanently skipped;
 'reclassify-stale' resets that stop-loss so the server retries them.
 
+'review' goes through the classifications flagged as doubtful, to accept,
+edit or reject them.
+
+'thumbs' generates missing thumbnails, or all of them with -rebuild.
+
+'subs gc' cleans up the cache of extracted subtitles.
+
 Commands:
 %v`
 
 var subcommands = map[string]cmd.Command{
 	"l|list":           listCommand(),
 	"reclassify-stale": reclassifyStaleCommand(),
+	"review":           reviewCommand(),
+	"thumbs":           thumbsCommand(),
+	"subs":             subsCommand(),
 }
 
 func run(ctx context.Context, args []string) int {
@@ -43,7 +53,7 @@ func Command() *command {
 }
 
 func (c *command) Describe() string {
-	return "Interact with the media store from the CLI — list, inspect, delete, reclassify."
+	return "Interact with the media store from the CLI — list, inspect, delete, reclassify, review, thumbnails, subtitles."
 }
 
 func (c *command) Help() string {
@@ -0,0 +1,220 @@
+package media
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/baalimago/go_away_boilerplate/pkg/ancli"
+	"github.com/baalimago/go_away_boilerplate/pkg/table"
+	"github.com/baalimago/kinoview/internal/media/storage"
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+// reviewStore is the slice of the store this command needs.
+type reviewStore interface {
+	Reviews() []model.Item
+	ResolveReview(id string, d model.ReviewDecision) (model.Item, error)
+}
+
+type reviewCmd struct {
+	storePath string
+	flagset   *flag.FlagSet
+
+	store reviewStore
+	// readInput reads one line of the user's answer, table.ReadUserInput
+	// unless testing.
+	readInput func() (string, error)
+	out       io.Writer
+}
+
+func reviewCommand() *reviewCmd {
+	cfgDir, err := os.UserConfigDir()
+	storePath := ""
+	if err == nil {
+		storePath = path.Join(cfgDir, "kinoview", "store")
+	}
+	return &reviewCmd{
+		storePath: storePath,
+		readInput: table.ReadUserInput,
+		out:       os.Stdout,
+	}
+}
+
+func (c *reviewCmd) Describe() string {
+	return "Go through the classifications flagged for review, accepting, editing or rejecting them."
+}
+
+func (c *reviewCmd) Help() string {
+	return `= media review =
+
+Classifications the server doesn't trust are flagged for review: the classifier
+wasn't confident in some field, it contradicted the file name or the sidecars,
+or the duration it came up with isn't the one of the file. The metadata is kept
+meanwhile, so flagged items are browsable as usual.
+
+This goes through the flagged items, the longest waiting first. For each:
+  [a]ccept       keep the metadata as it is
+  [e]dit <json>  patch the metadata, e.g. e {"year": 1995}, and keep it
+  [r]eject       discard the metadata so the server classifies the item anew
+  [s]kip         leave it for later
+  [q]uit
+
+Flags:
+  -store-path   Path to the kinoview store directory
+
+The server re-reads items as it goes, so this can be run while kinoview is up.
+The same queue is served at /gallery/review.`
+}
+
+func (c *reviewCmd) Flagset() *flag.FlagSet {
+	fs := flag.NewFlagSet("review", flag.ExitOnError)
+	fs.StringVar(&c.storePath, "store-path", c.storePath, "Path to kinoview store directory")
+	c.flagset = fs
+	return fs
+}
+
+func (c *reviewCmd) Setup(ctx context.Context) error {
+	if c.flagset == nil {
+		return errors.New("flagset can't be nil")
+	}
+	return nil
+}
+
+func (c *reviewCmd) Run(ctx context.Context) error {
+	if c.store == nil {
+		if _, err := os.Stat(c.storePath); os.IsNotExist(err) {
+			return fmt.Errorf("store path does not exist: %v", c.storePath)
+		}
+		// Classifier is nil on purpose: rejected items are classified anew
+		// by the running server, never by this command.
+		s := storage.NewStore(
+			storage.WithStorePath(c.storePath),
+			storage.WithClassifier(nil),
+		)
+		if _, err := s.Setup(ctx); err != nil {
+			return fmt.Errorf("failed to setup store: %w", err)
+		}
+		c.store = s
+	}
+
+	items := c.store.Reviews()
+	if len(items) == 0 {
+		ancli.Okf("Nothing to review.")
+		return nil
+	}
+	ancli.Noticef("%v item(s) flagged for review.", len(items))
+
+	var resolved int
+	for n, item := range items {
+		fmt.Fprintf(c.out, "\n(%v/%v)", n+1, len(items))
+		printReview(c.out, item)
+		done, err := c.reviewItem(item)
+		if errors.Is(err, errQuitReview) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if done {
+			resolved++
+		}
+	}
+	ancli.Okf("Reviewed %v of %v item(s).", resolved, len(items))
+	return nil
+}
+
+// errQuitReview stops the review, whatever is left is left for later.
+var errQuitReview = errors.New("quit review")
+
+// reviewItem prompts for the decision on item until one has been carried
+// out, or the user skips it. Reports whether the review was resolved.
+func (c *reviewCmd) reviewItem(item model.Item) (bool, error) {
+	for {
+		fmt.Fprintf(c.out, "\n(press [a]ccept, [e]dit <json>, [r]eject, [s]kip, [q]uit): ")
+		input, err := c.readInput()
+		if err != nil {
+			if errors.Is(err, table.ErrUserInitiatedExit) || errors.Is(err, io.EOF) {
+				return false, errQuitReview
+			}
+			return false, err
+		}
+		action, arg, _ := strings.Cut(strings.TrimSpace(input), " ")
+		var d model.ReviewDecision
+		switch strings.ToLower(action) {
+		case "a", "accept":
+			d.Action = model.ReviewAccept
+		case "e", "edit":
+			arg = strings.TrimSpace(arg)
+			if arg == "" {
+				fmt.Fprintf(c.out, "Metadata patch (JSON): ")
+				if arg, err = c.readInput(); err != nil {
+					return false, err
+				}
+			}
+			d.Action = model.ReviewEdit
+			d.Metadata = json.RawMessage(arg)
+		case "r", "reject":
+			d.Action = model.ReviewReject
+		case "s", "skip", "":
+			return false, nil
+		case "q", "quit":
+			return false, errQuitReview
+		default:
+			ancli.Warnf("Unknown action: %q", input)
+			continue
+		}
+
+		if _, err := c.store.ResolveReview(item.ID, d); err != nil {
+			// An edit which doesn't validate deserves another go
+			ancli.Errf("%v", err)
+			continue
+		}
+		switch d.Action {
+		case model.ReviewAccept:
+			ancli.Okf("Accepted: %v", item.Name)
+		case model.ReviewEdit:
+			ancli.Okf("Edited: %v", item.Name)
+		case model.ReviewReject:
+			ancli.Okf("Rejected: %v, the server will classify it anew.", item.Name)
+		}
+		return true, nil
+	}
+}
+
+// printReview prints why item was flagged, and its metadata field by field
+// with the classifier's confidence in each.
+func printReview(out io.Writer, item model.Item) {
+	fmt.Fprintf(out, " %v\n", item.Name)
+	fmt.Fprintf(out, "Path:      %v\n", item.Path)
+	fmt.Fprintln(out, "Flagged:")
+	for _, r := range item.Review.Reasons {
+		fmt.Fprintf(out, "  - %v\n", r)
+	}
+	if item.Metadata == nil {
+		fmt.Fprintln(out, "Metadata:  (none)")
+		return
+	}
+	var fields map[string]json.RawMessage
+	b, _ := json.Marshal(item.Metadata)
+	if err := json.Unmarshal(b, &fields); err != nil {
+		fmt.Fprintf(out, "Metadata:  %s\n", b)
+		return
+	}
+	fmt.Fprintln(out, "Metadata:")
+	for _, key := range slices.Sorted(maps.Keys(fields)) {
+		line := fmt.Sprintf("  %-14v %v", key+":", truncateTo(string(fields[key]), 100))
+		if c, ok := item.Confidence[key]; ok {
+			line += fmt.Sprintf(" (%.2f)", c)
+		}
+		fmt.Fprintln(out, line)
+	}
+}
@@ -0,0 +1,108 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+type fakeReviewStore struct {
+	items    []model.Item
+	resolved map[string]model.ReviewDecision
+	// invalid edits are refused, as the store refuses metadata which doesn't
+	// validate
+	invalid string
+}
+
+func (f *fakeReviewStore) Reviews() []model.Item { return f.items }
+
+func (f *fakeReviewStore) ResolveReview(id string, d model.ReviewDecision) (model.Item, error) {
+	if d.Action == model.ReviewEdit && string(d.Metadata) == f.invalid {
+		return model.Item{}, model.ErrInvalidDecision
+	}
+	if f.resolved == nil {
+		f.resolved = map[string]model.ReviewDecision{}
+	}
+	f.resolved[id] = d
+	return model.Item{ID: id}, nil
+}
+
+// scriptedInput answers the prompts with lines, then EOF.
+func scriptedInput(lines ...string) func() (string, error) {
+	return func() (string, error) {
+		if len(lines) == 0 {
+			return "", io.EOF
+		}
+		l := lines[0]
+		lines = lines[1:]
+		return l, nil
+	}
+}
+
+func reviewFixture() []model.Item {
+	review := &model.Review{Reasons: []string{"low confidence in year (0.40)"}}
+	md := &model.MediaMetadata{Name: "Heat", Year: 1995}
+	return []model.Item{
+		{ID: "a", Name: "heat.mkv", Metadata: md, Confidence: model.Confidence{"year": 0.4}, Review: review},
+		{ID: "b", Name: "ronin.mkv", Metadata: md, Review: review},
+		{ID: "c", Name: "thief.mkv", Metadata: md, Review: review},
+	}
+}
+
+func TestReviewCmd_Run(t *testing.T) {
+	store := &fakeReviewStore{items: reviewFixture(), invalid: `{"year":"soon"}`}
+	var out bytes.Buffer
+	c := &reviewCmd{
+		store: store,
+		out:   &out,
+		readInput: scriptedInput(
+			`e {"year":"soon"}`, // refused, asked again
+			`e {"year":1994}`,
+			"s",
+			"r",
+		),
+	}
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if d := store.resolved["a"]; d.Action != model.ReviewEdit || string(d.Metadata) != `{"year":1994}` {
+		t.Errorf("a: unexpected decision %+v", d)
+	}
+	if _, ok := store.resolved["b"]; ok {
+		t.Error("b: expected the skipped item to be left alone")
+	}
+	if d := store.resolved["c"]; d.Action != model.ReviewReject {
+		t.Errorf("c: unexpected decision %+v", d)
+	}
+	if !strings.Contains(out.String(), "year:          1995 (0.40)") {
+		t.Errorf("expected the confidence next to the field, got:\n%v", out.String())
+	}
+}
+
+func TestReviewCmd_Run_quit(t *testing.T) {
+	store := &fakeReviewStore{items: reviewFixture()}
+	c := &reviewCmd{store: store, out: io.Discard, readInput: scriptedInput("a", "q")}
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.resolved) != 1 || store.resolved["a"].Action != model.ReviewAccept {
+		t.Errorf("expected only the first item accepted, got %+v", store.resolved)
+	}
+}
+
+func TestReviewCmd_Run_inputFails(t *testing.T) {
+	c := &reviewCmd{
+		store:     &fakeReviewStore{items: reviewFixture()},
+		out:       io.Discard,
+		readInput: func() (string, error) { return "", errors.New("tty gone") },
+	}
+	if err := c.Run(context.Background()); err == nil {
+		t.Fatal("expected the input error")
+	}
+}
@@ -0,0 +1,179 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/baalimago/go_away_boilerplate/pkg/ancli"
+	"github.com/baalimago/go_away_boilerplate/pkg/cmd"
+	"github.com/baalimago/kinoview/internal/media/storage"
+	"github.com/baalimago/kinoview/internal/media/stream"
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+const subsUsage = `= Media subs =
+
+Manage the subtitles, fonts and synced subtitles the server extracts from
+the media and keeps in '<config>/kinoview/subtitles'.
+
+Commands:
+%v`
+
+var subsSubcommands = map[string]cmd.Command{
+	"gc": subsGCCommand(),
+}
+
+type subsCmd struct {
+	flagset *flag.FlagSet
+}
+
+func subsCommand() *subsCmd {
+	return &subsCmd{}
+}
+
+func (c *subsCmd) Describe() string {
+	return "Manage the cache of extracted subtitles."
+}
+
+func (c *subsCmd) Help() string {
+	return "Use 'media subs gc' to clean up the subtitle cache. See subcommand help for details."
+}
+
+func (c *subsCmd) Setup(ctx context.Context) error {
+	if c.flagset == nil {
+		return errors.New("flagset can't be nil")
+	}
+	return nil
+}
+
+func (c *subsCmd) Run(ctx context.Context) error {
+	args := append([]string{os.Args[0]}, c.flagset.Args()...)
+	if exitCode := cmd.Run(ctx, args, subsSubcommands, subsUsage); exitCode > 0 {
+		return fmt.Errorf("subs subcommand exited with code %v", exitCode)
+	}
+	return nil
+}
+
+func (c *subsCmd) Flagset() *flag.FlagSet {
+	fs := flag.NewFlagSet("subs", flag.ContinueOnError)
+	c.flagset = fs
+	return fs
+}
+
+// subtitleGC is the slice of the stream manager this command needs.
+type subtitleGC interface {
+	GC(items []model.Item) (stream.GCStats, error)
+}
+
+type subsGCCmd struct {
+	storePath string
+	subsPath  string
+	// maxSize of the cache in MiB
+	maxSize int64
+	flagset *flag.FlagSet
+
+	store interface{ Snapshot() []model.Item }
+	gc    subtitleGC
+}
+
+func subsGCCommand() *subsGCCmd {
+	cfgDir, err := os.UserConfigDir()
+	storePath, subsPath := "", ""
+	if err == nil {
+		storePath = path.Join(cfgDir, "kinoview", "store")
+		subsPath = path.Join(cfgDir, "kinoview", "subtitles")
+	}
+	return &subsGCCmd{storePath: storePath, subsPath: subsPath, maxSize: stream.DefaultCacheLimit >> 20}
+}
+
+func (c *subsGCCmd) Describe() string {
+	return "Remove broken, outdated and orphaned subtitles from the cache, and trim it to size."
+}
+
+func (c *subsGCCmd) Help() string {
+	return `= media subs gc =
+
+Removes from the subtitle cache:
+  - leftovers of extractions which crashed
+  - subtitles which are empty or hold no cues
+  - subtitles extracted before their media file last changed
+  - subtitles of media no longer in the store
+Then the least recently used ones are removed until the cache fits -max-size.
+
+The server does all of this as it goes, except for the orphans, so there's
+no need to stop it first.
+
+Flags:
+  -store-path   Path to the kinoview store directory
+  -subs-path    Path to the subtitle cache directory
+  -max-size     Size of the cache in MiB, 0 for no limit`
+}
+
+func (c *subsGCCmd) Flagset() *flag.FlagSet {
+	fs := flag.NewFlagSet("gc", flag.ExitOnError)
+	fs.StringVar(&c.storePath, "store-path", c.storePath, "Path to kinoview store directory")
+	fs.StringVar(&c.subsPath, "subs-path", c.subsPath, "Path to the subtitle cache directory")
+	fs.Int64Var(&c.maxSize, "max-size", c.maxSize, "Size of the cache in MiB, 0 for no limit")
+	c.flagset = fs
+	return fs
+}
+
+func (c *subsGCCmd) Setup(ctx context.Context) error {
+	if c.flagset == nil {
+		return errors.New("flagset can't be nil")
+	}
+	return nil
+}
+
+func (c *subsGCCmd) Run(ctx context.Context) error {
+	if _, err := os.Stat(c.subsPath); os.IsNotExist(err) {
+		ancli.Okf("No subtitle cache at: '%v'", c.subsPath)
+		return nil
+	}
+	if c.gc == nil {
+		m, err := stream.NewManager(
+			stream.WithStoragePath(c.subsPath),
+			stream.WithCacheLimit(c.maxSize<<20),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create stream manager: %w", err)
+		}
+		c.gc = m
+	}
+
+	// Without a store there's no telling which media are gone, so orphans
+	// are kept.
+	var items []model.Item
+	if c.store == nil {
+		if _, err := os.Stat(c.storePath); err == nil {
+			s := storage.NewStore(
+				storage.WithStorePath(c.storePath),
+				storage.WithClassifier(nil),
+			)
+			if _, err := s.Setup(ctx); err != nil {
+				return fmt.Errorf("failed to setup store: %w", err)
+			}
+			c.store = s
+		} else {
+			ancli.Noticef("No store at: '%v', keeping subtitles of unknown media.", c.storePath)
+		}
+	}
+	if c.store != nil {
+		// An empty store still tells that every subtitle is an orphan.
+		if items = c.store.Snapshot(); items == nil {
+			items = []model.Item{}
+		}
+	}
+
+	stats, err := c.gc.GC(items)
+	if err != nil {
+		return fmt.Errorf("failed to clean up subtitle cache: %w", err)
+	}
+	ancli.Okf("Removed %v file(s), freeing %.1f MiB. The subtitle cache is now %.1f MiB.",
+		stats.Removed, float64(stats.Freed)/(1<<20), float64(stats.Size)/(1<<20))
+	return nil
+}
@@ -0,0 +1,68 @@
+package media
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/baalimago/kinoview/internal/media/stream"
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+type fakeSnapshotter []model.Item
+
+func (f fakeSnapshotter) Snapshot() []model.Item { return f }
+
+type fakeSubtitleGC struct {
+	called bool
+	items  []model.Item
+}
+
+func (f *fakeSubtitleGC) GC(items []model.Item) (stream.GCStats, error) {
+	f.called = true
+	f.items = items
+	return stream.GCStats{Removed: 1}, nil
+}
+
+func TestSubsGC_EmptyStoreOrphansAll(t *testing.T) {
+	gc := &fakeSubtitleGC{}
+	c := &subsGCCmd{subsPath: t.TempDir(), store: fakeSnapshotter(nil), gc: gc}
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if !gc.called || gc.items == nil {
+		t.Fatalf("expected GC against an empty, not missing, store: %+v", gc)
+	}
+}
+
+func TestSubsGC_NoStoreKeepsOrphans(t *testing.T) {
+	gc := &fakeSubtitleGC{}
+	c := &subsGCCmd{subsPath: t.TempDir(), storePath: filepath.Join(t.TempDir(), "missing"), gc: gc}
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if !gc.called || gc.items != nil {
+		t.Fatalf("expected GC without items: %+v", gc)
+	}
+}
+
+func TestSubsGC_RemovesOrphans(t *testing.T) {
+	subs := t.TempDir()
+	vtt := "WEBVTT\n\n00:00:01.000 --> 00:00:02.000\nHello\n"
+	for _, name := range []string{"kept_2.vtt", "gone_2.vtt"} {
+		if err := os.WriteFile(filepath.Join(subs, name), []byte(vtt), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := &subsGCCmd{subsPath: subs, store: fakeSnapshotter{{ID: "kept"}}}
+	if err := c.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(subs, "kept_2.vtt")); err != nil {
+		t.Errorf("expected kept_2.vtt to stay: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(subs, "gone_2.vtt")); !os.IsNotExist(err) {
+		t.Errorf("expected gone_2.vtt to be removed: %v", err)
+	}
+}
@@ -0,0 +1,226 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/baalimago/go_away_boilerplate/pkg/ancli"
+	"github.com/baalimago/kinoview/internal/media/storage"
+	"github.com/baalimago/kinoview/internal/media/thumbnail"
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+// thumbsStore is the slice of the store this command needs.
+type thumbsStore interface {
+	Snapshot() []model.Item
+	RegenerateThumbnails(id string) (bool, error)
+	DeleteItem(id string) error
+}
+
+type thumbsCmd struct {
+	storePath   string
+	cacheDir    string
+	rebuild     bool
+	pruneLegacy bool
+	force       bool
+	flagset     *flag.FlagSet
+
+	store thumbsStore
+	cache *thumbnail.Cache
+}
+
+func thumbsCommand() *thumbsCmd {
+	cfgDir, err := os.UserConfigDir()
+	storePath := ""
+	if err == nil {
+		storePath = path.Join(cfgDir, "kinoview", "store")
+	}
+	return &thumbsCmd{storePath: storePath, cacheDir: thumbnail.DefaultCacheDir()}
+}
+
+func (c *thumbsCmd) Describe() string {
+	return "Generate the thumbnails of images and music which don't have any yet."
+}
+
+func (c *thumbsCmd) Help() string {
+	return `= media thumbs =
+
+Thumbnails are kept in the kinoview cache dir, keyed by item ID, in a grid,
+card and hero size. The server generates them as media is indexed; this
+generates any which are missing, for instance after clearing the cache.
+
+Older versions wrote thumbnails next to the media as '<name>_thumb<ext>'.
+Those are no longer used, and can be removed with -prune-legacy.
+
+Flags:
+  -store-path     Path to the kinoview store directory
+  -cache-dir      Path to the thumbnail cache directory
+  -rebuild        Regenerate all thumbnails, not only the missing ones
+  -prune-legacy   Delete thumbnails written next to the media by older versions
+  -force          Skip the confirmation prompt of -prune-legacy
+
+Stop the server first when using -prune-legacy, it removes items from the store.`
+}
+
+func (c *thumbsCmd) Flagset() *flag.FlagSet {
+	fs := flag.NewFlagSet("thumbs", flag.ExitOnError)
+	fs.StringVar(&c.storePath, "store-path", c.storePath, "Path to kinoview store directory")
+	fs.StringVar(&c.cacheDir, "cache-dir", c.cacheDir, "Path to the thumbnail cache directory")
+	fs.BoolVar(&c.rebuild, "rebuild", false, "Regenerate all thumbnails, not only the missing ones")
+	fs.BoolVar(&c.pruneLegacy, "prune-legacy", false, "Delete thumbnails written next to the media by older versions")
+	fs.BoolVar(&c.force, "force", false, "Skip the confirmation prompt")
+	c.flagset = fs
+	return fs
+}
+
+func (c *thumbsCmd) Setup(ctx context.Context) error {
+	if c.flagset == nil {
+		return errors.New("flagset can't be nil")
+	}
+	return nil
+}
+
+func (c *thumbsCmd) Run(ctx context.Context) error {
+	if _, err := os.Stat(c.storePath); os.IsNotExist(err) {
+		return fmt.Errorf("store path does not exist: %v", c.storePath)
+	}
+	if c.cache == nil {
+		c.cache = thumbnail.NewCache(c.cacheDir)
+	}
+	if c.store == nil {
+		// Classifier is nil on purpose: this command only generates
+		// thumbnails, it never classifies anything itself.
+		s := storage.NewStore(
+			storage.WithStorePath(c.storePath),
+			storage.WithClassifier(nil),
+			storage.WithThumbnailCache(c.cache),
+		)
+		if _, err := s.Setup(ctx); err != nil {
+			return fmt.Errorf("failed to setup store: %w", err)
+		}
+		c.store = s
+	}
+
+	items := c.store.Snapshot()
+	if c.pruneLegacy {
+		pruned, err := c.prune(items)
+		if err != nil {
+			return err
+		}
+		items = withoutIDs(items, pruned)
+	}
+
+	var generated, skipped int
+	var failed []error
+	for _, i := range thumbnailable(items) {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !c.rebuild && c.cache.Has(i.ID) {
+			continue
+		}
+		ok, err := c.store.RegenerateThumbnails(i.ID)
+		if err != nil {
+			// Keep going: one unreadable file should not strand the rest.
+			failed = append(failed, err)
+			continue
+		}
+		if ok {
+			generated++
+		} else {
+			skipped++
+		}
+	}
+
+	ancli.Okf("Generated thumbnails for %v item(s) in: '%v'", generated, c.cache.Dir())
+	if skipped > 0 {
+		ancli.Noticef("%v item(s) have no artwork to create thumbnails from.", skipped)
+	}
+	for _, err := range failed {
+		ancli.Errf("%v", err)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%v item(s) failed", len(failed))
+	}
+	return nil
+}
+
+// prune legacy thumbnails, and the items they were indexed as. Returns the
+// IDs of the removed items.
+func (c *thumbsCmd) prune(items []model.Item) (map[string]bool, error) {
+	legacy := legacyThumbnails(items)
+	if len(legacy) == 0 {
+		ancli.Okf("No legacy thumbnails found.")
+		return nil, nil
+	}
+	ancli.Noticef("%v legacy thumbnail(s) found next to the media:", len(legacy))
+	for _, i := range legacy {
+		fmt.Printf("  %v\n", i.Path)
+	}
+	if !c.force && !readYesNo(fmt.Sprintf("Delete %v file(s)? (y/N): ", len(legacy))) {
+		ancli.Noticef("Cancelled.")
+		return nil, nil
+	}
+
+	pruned := make(map[string]bool)
+	for _, i := range legacy {
+		if err := os.Remove(i.Path); err != nil && !os.IsNotExist(err) {
+			ancli.Errf("failed to delete: '%v', err: %v", i.Path, err)
+			continue
+		}
+		if err := c.store.DeleteItem(i.ID); err != nil {
+			ancli.Errf("failed to delete item: '%v', err: %v", i.Name, err)
+			continue
+		}
+		pruned[i.ID] = true
+	}
+	ancli.Okf("Deleted %v legacy thumbnail(s).", len(pruned))
+	return pruned, nil
+}
+
+// legacyThumbnails returns the items which are thumbnails written next to the
+// media by older versions. Only those whose original is still there count, so
+// that images which just happen to end with _thumb are left alone.
+func legacyThumbnails(items []model.Item) []model.Item {
+	var out []model.Item
+	for _, i := range items {
+		if !strings.HasPrefix(i.MIMEType, "image") || !thumbnail.IsThumbnail(i.Path) {
+			continue
+		}
+		ext := path.Ext(i.Path)
+		original := strings.TrimSuffix(i.Path, "_"+thumbnail.ThumbnailSuffix+ext) + ext
+		if _, err := os.Stat(original); err == nil {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+// thumbnailable returns the items which may have thumbnails.
+func thumbnailable(items []model.Item) []model.Item {
+	var out []model.Item
+	for _, i := range items {
+		if strings.HasPrefix(i.MIMEType, "image") || strings.HasPrefix(i.MIMEType, "audio") {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+func withoutIDs(items []model.Item, ids map[string]bool) []model.Item {
+	if len(ids) == 0 {
+		return items
+	}
+	var out []model.Item
+	for _, i := range items {
+		if !ids[i.ID] {
+			out = append(out, i)
+		}
+	}
+	return out
+}
@@ -0,0 +1,133 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/baalimago/kinoview/internal/media/thumbnail"
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+type fakeThumbsStore struct {
+	items       []model.Item
+	regenerated []string
+	deleted     []string
+	failOn      map[string]bool
+}
+
+func (f *fakeThumbsStore) Snapshot() []model.Item { return f.items }
+
+func (f *fakeThumbsStore) RegenerateThumbnails(id string) (bool, error) {
+	if f.failOn[id] {
+		return false, fmt.Errorf("cannot read %v", id)
+	}
+	f.regenerated = append(f.regenerated, id)
+	return true, nil
+}
+
+func (f *fakeThumbsStore) DeleteItem(id string) error {
+	f.deleted = append(f.deleted, id)
+	return nil
+}
+
+func runThumbs(t *testing.T, c *thumbsCmd) error {
+	t.Helper()
+	c.storePath = t.TempDir()
+	return c.Run(context.Background())
+}
+
+func thumbsFixture() []model.Item {
+	return []model.Item{
+		{ID: "img", Name: "a.jpg", MIMEType: "image/jpeg"},
+		{ID: "cached", Name: "b.jpg", MIMEType: "image/jpeg"},
+		{ID: "song", Name: "c.mp3", MIMEType: "audio/mpeg"},
+		{ID: "movie", Name: "d.mkv", MIMEType: "video/x-matroska"},
+	}
+}
+
+func cacheWith(t *testing.T, ids ...string) *thumbnail.Cache {
+	t.Helper()
+	c := thumbnail.NewCache(t.TempDir())
+	for _, id := range ids {
+		if _, err := c.Generate(id, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return c
+}
+
+func TestThumbs_OnlyMissing(t *testing.T) {
+	fake := &fakeThumbsStore{items: thumbsFixture()}
+	c := &thumbsCmd{store: fake, cache: cacheWith(t, "cached")}
+	if err := runThumbs(t, c); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	want := []string{"img", "song"}
+	if !slices.Equal(fake.regenerated, want) {
+		t.Fatalf("regenerated %v, want %v", fake.regenerated, want)
+	}
+}
+
+func TestThumbs_Rebuild(t *testing.T) {
+	fake := &fakeThumbsStore{items: thumbsFixture()}
+	c := &thumbsCmd{store: fake, cache: cacheWith(t, "cached"), rebuild: true}
+	if err := runThumbs(t, c); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	want := []string{"img", "cached", "song"}
+	if !slices.Equal(fake.regenerated, want) {
+		t.Fatalf("regenerated %v, want %v", fake.regenerated, want)
+	}
+}
+
+func TestThumbs_FailuresDontStopTheRest(t *testing.T) {
+	fake := &fakeThumbsStore{items: thumbsFixture(), failOn: map[string]bool{"img": true}}
+	c := &thumbsCmd{store: fake, cache: cacheWith(t)}
+	if err := runThumbs(t, c); err == nil {
+		t.Fatal("expected error reporting the failure")
+	}
+	if !slices.Contains(fake.regenerated, "song") {
+		t.Fatalf("expected remaining items to be processed, got %v", fake.regenerated)
+	}
+}
+
+func TestThumbs_PruneLegacy(t *testing.T) {
+	dir := t.TempDir()
+	touch := func(name string) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	orig := touch("a.jpg")
+	legacy := touch("a_thumb.jpg")
+	orphan := touch("lonely_thumb.jpg")
+
+	fake := &fakeThumbsStore{items: []model.Item{
+		{ID: "orig", Path: orig, MIMEType: "image/jpeg"},
+		{ID: "legacy", Path: legacy, MIMEType: "image/jpeg"},
+		{ID: "orphan", Path: orphan, MIMEType: "image/jpeg"},
+	}}
+	c := &thumbsCmd{store: fake, cache: cacheWith(t), pruneLegacy: true, force: true}
+	if err := runThumbs(t, c); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if !slices.Equal(fake.deleted, []string{"legacy"}) {
+		t.Fatalf("deleted %v, want only the legacy thumbnail", fake.deleted)
+	}
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Fatal("expected legacy thumbnail to be removed from disk")
+	}
+	if _, err := os.Stat(orphan); err != nil {
+		t.Fatal("expected image without original to be kept")
+	}
+	if slices.Contains(fake.regenerated, "legacy") {
+		t.Fatal("pruned item should not get new thumbnails")
+	}
+}
@@ -171,15 +171,18 @@ let searchDebounceTimer = null;
 const SEARCH_DEBOUNCE_MS = 250;
 const MAX_SEARCH_RESULTS = 5;
 
-// Auto-play an episode when navigated from shows page via ?play=ID
+// Auto-play an episode when navigated from shows page via ?play=ID, from
+// ?t=<seconds> if set, as quote links are
 function autoPlayFromQuery() {
   const params = new URLSearchParams(window.location.search);
   const playID = params.get('play');
   if (playID && media[playID]) {
-    selectMedia(playID);
+    const at = parseFloat(params.get('t'));
+    selectMedia(playID, isFinite(at) ? at : 0);
     // Clean URL without reload
     const url = new URL(window.location);
     url.searchParams.delete('play');
+    url.searchParams.delete('t');
     window.history.replaceState({}, '', url);
   }
 }
@@ -297,11 +300,11 @@ function generateUUID() {
 // selectMedia loads a media item into the custom player. The heavy lifting
 // (resume, transcode-aware seeking, autoplay) lives in the Player module below;
 // this thin wrapper keeps the many existing call sites working.
-function selectMedia(id) {
+function selectMedia(id, at) {
   mostRecentID = id;
   loadStreams(id);
   if (window.Player) {
-    window.Player.load(id);
+    window.Player.load(id, at);
   }
 }
 
@@ -317,9 +320,12 @@ function constuctClientContext() {
       }
     }
   )
-  return {
+  const ctx = {
     "viewingHistory": viewingHistory,
   }
+  const langs = deviceSubtitleLanguages()
+  if (langs.length) ctx.subtitleLanguages = langs
+  return ctx
 }
 
 function requestRecommendation() {
@@ -361,6 +367,106 @@ function requestRecommendation() {
     });
 }
 
+// ── Subtitle language preferences ──
+// The server's preferred subtitle languages, overridden per device by the
+// "subtitleLanguages" localStorage key (a list of ISO 639-1 codes).
+let serverSubtitleLanguages = ["en"];
+
+fetch("/gallery/preferences")
+  .then(r => r.ok ? r.json() : null)
+  .then(prefs => {
+    if (prefs && Array.isArray(prefs.subtitleLanguages) && prefs.subtitleLanguages.length) {
+      serverSubtitleLanguages = prefs.subtitleLanguages;
+    }
+  })
+  .catch(err => console.error("failed to load preferences: " + err));
+
+// normaliseLang maps tags such as "swe", "sv-SE" or "SV" to "sv". Relies on
+// the CLDR aliases of Intl for the ISO 639-2 codes; "" for undetermined tags.
+function normaliseLang(tag) {
+  if (!tag) return "";
+  let l = String(tag).trim().toLowerCase();
+  try {
+    l = Intl.getCanonicalLocales(l)[0].split("-")[0].toLowerCase();
+  } catch (e) {
+    l = l.split(/[-_]/)[0];
+  }
+  return (l === "und" || l === "mul" || l === "zxx") ? "" : l;
+}
+
+function parseLangs(s) {
+  const out = [];
+  String(s || "").split(/[\s,;]+/).forEach(t => {
+    const l = normaliseLang(t);
+    if (l && !out.includes(l)) out.push(l);
+  });
+  return out;
+}
+
+// deviceSubtitleLanguages are the languages set on this device, or [] if
+// it follows the server.
+function deviceSubtitleLanguages() {
+  try {
+    const l = JSON.parse(localStorage.getItem("subtitleLanguages") || "[]");
+    return Array.isArray(l) ? l : [];
+  } catch (e) {
+    return [];
+  }
+}
+
+function subtitleLanguages() {
+  const device = deviceSubtitleLanguages();
+  return device.length ? device : serverSubtitleLanguages;
+}
+
+function editSubtitleLanguages() {
+  const current = deviceSubtitleLanguages().join(", ");
+  const input = prompt(
+    "Subtitle languages for this device, most preferred first (e.g. sv, ja, en).\n" +
+    "Leave empty to use the server default: " + serverSubtitleLanguages.join(", "),
+    current);
+  if (input === null) return;
+  const langs = parseLangs(input);
+  if (langs.length) {
+    localStorage.setItem("subtitleLanguages", JSON.stringify(langs));
+  } else {
+    localStorage.removeItem("subtitleLanguages");
+  }
+  if (mostRecentID) {
+    // Re-pick the default track in the new languages.
+    selectSubtitle("off");
+    loadStreams(mostRecentID);
+  }
+}
+
+// preferredSubtitle picks the default subtitle stream: the most preferred
+// language, avoiding commentary, forced, sign/song and bitmap tracks, the
+// latter less so when the server reads them by OCR. Returns null if no stream
+// is in a preferred language.
+function preferredSubtitle(streams, langs) {
+  let best = null;
+  let bestScore = -1;
+  for (const s of streams) {
+    if (s.codec_type !== "subtitle") continue;
+    const rank = langs.indexOf(normaliseLang(s.tags && s.tags.language));
+    if (rank < 0) continue;
+    const title = ((s.tags && s.tags.title) || "").toLowerCase();
+    const d = s.disposition || {};
+    if (d.comment || title.includes("commentary")) continue;
+    let score = 100 * (langs.length - rank);
+    if (d.default) score += 20;
+    if (d.forced) score -= 40;
+    if (/sign|song|lyric|karaoke/.test(title)) score -= 60;
+    if (s.ocr) score -= 5;
+    else if (/^(hdmv_pgs|dvd|dvb)_subtitle$/.test(s.codec_name)) score -= 50;
+    if (score > bestScore) {
+      best = s;
+      bestScore = score;
+    }
+  }
+  return best;
+}
+
 function loadStreams(id) {
   fetch(`/gallery/streams/${id}`)
     .then(response => response.json())
@@ -385,6 +491,13 @@ function loadStreams(id) {
       let hasAudio = false;
       let audioTrackIndex = 0;
 
+      // Default to the preferred subtitle language, unless a track was
+      // already chosen, e.g. by a suggestion.
+      const subsTrack = document.getElementById("subs");
+      const preferred = (mostRecentID === id && subsTrack && !subsTrack.getAttribute("src"))
+        ? preferredSubtitle(data.streams || [], subtitleLanguages())
+        : null;
+
       // Check if streams is array, sometimes it might be null if find returned empty
       if (data.streams) {
         for (const i of data.streams) {
@@ -410,7 +523,8 @@ function loadStreams(id) {
           if (i.codec_type === 'subtitle') {
             // Relaxed check: include even if no language tag
             const lang = i.tags && i.tags.language ? i.tags.language : `Track ${i.index}`;
-            const title = i.tags && i.tags.title ? `${i.tags.title} (${lang})` : lang;
+            let title = i.tags && i.tags.title ? `${i.tags.title} (${lang})` : lang;
+            if (i.ocr) title += " [OCR]";
 
             if (subMenu) {
               const btn = createDropdownItem(title, () => {
@@ -418,11 +532,41 @@ function loadStreams(id) {
                 updateActiveItem(subMenu, btn);
               });
               subMenu.appendChild(btn);
+              if (preferred === i) {
+                selectSubtitle(i.index);
+                updateActiveItem(subMenu, btn);
+              }
             }
           }
         }
       }
 
+      // Two languages at once, for language learners
+      if (subMenu && data.dualPairs) {
+        for (const p of data.dualPairs) {
+          const btn = createDropdownItem(`${p.primaryLanguage} + ${p.secondaryLanguage}`, () => {
+            selectDualSubtitle(p.primary, p.secondary);
+            updateActiveItem(subMenu, btn);
+          });
+          subMenu.appendChild(btn);
+        }
+      }
+
+      if (subMenu) {
+        const syncBtn = createDropdownItem("Sync to audio", () => {
+          syncSubtitle();
+          subMenu.classList.add('hidden');
+        });
+        subMenu.appendChild(syncBtn);
+        const offsetBtn = createDropdownItem("Offset…", () => {
+          editSubtitleOffset();
+          subMenu.classList.add('hidden');
+        });
+        subMenu.appendChild(offsetBtn);
+        const langsBtn = createDropdownItem("Languages…", editSubtitleLanguages);
+        subMenu.appendChild(langsBtn);
+      }
+
       if (!hasAudio && audioMenu) {
         const btn = createDropdownItem("Default Audio", () => { }, true);
         audioMenu.appendChild(btn);
@@ -477,21 +621,119 @@ function selectAudio(index) {
   console.log(`Selected audio stream: ${index}`);
 }
 
+// currentSubtitle is the subtitle stream shown, null when they're off.
+let currentSubtitle = null;
+
 function selectSubtitle(id) {
   const track = document.getElementById("subs");
 
   if (id === 'off' || id === "") {
     console.log("Disabling subtitles");
+    currentSubtitle = null;
     track.src = "";
     track.removeAttribute("src");
     if (track.track) track.track.mode = "disabled";
   } else {
-    console.log(`Attempting to set subs to: /gallery/streams/${mostRecentID}/stream/${id}`)
-    track.src = `/gallery/streams/${mostRecentID}/stream/${id}`;
-    if (track.track) track.track.mode = "showing";
+    currentSubtitle = { id: id, sync: false };
+    loadSubtitle();
   }
 }
 
+// selectDualSubtitle shows the primary subtitle stream over the secondary,
+// merged by the server into one track.
+function selectDualSubtitle(primary, secondary) {
+  currentSubtitle = { id: primary, secondary: secondary, sync: false };
+  loadSubtitle();
+}
+
+// loadSubtitle points the track at the current subtitle stream. The server
+// applies the offset kept for it, unless a new one is passed. Merged streams
+// get the offsets kept for each of them.
+function loadSubtitle(offsetMs) {
+  if (!currentSubtitle) return;
+  const track = document.getElementById("subs");
+  const params = new URLSearchParams();
+  if (currentSubtitle.sync) params.set("sync", "auto");
+  const dual = currentSubtitle.secondary !== undefined;
+  if (offsetMs !== undefined && !dual) params.set("offset", String(offsetMs));
+  const query = params.toString();
+  const path = dual
+    ? `dual/${currentSubtitle.id}/${currentSubtitle.secondary}`
+    : `stream/${currentSubtitle.id}`;
+  const src = `/gallery/streams/${mostRecentID}/${path}` + (query ? `?${query}` : "");
+  console.log(`Attempting to set subs to: ${src}`);
+  track.src = src;
+  if (track.track) track.track.mode = "showing";
+}
+
+// syncSubtitle reloads the current subtitle track retimed to the speech in
+// the audio. The server decodes the whole audio track the first time, so the
+// track may take a while to show up.
+function syncSubtitle() {
+  if (!currentSubtitle || currentSubtitle.sync) return;
+  currentSubtitle.sync = true;
+  loadSubtitle();
+}
+
+// subtitleOffset of the current subtitle stream in milliseconds, as kept by
+// the server for the item.
+function subtitleOffset() {
+  if (!currentSubtitle) return 0;
+  const item = media[mostRecentID] || {};
+  const offsets = item.subtitleOffsets || {};
+  return offsets[String(currentSubtitle.id)] || 0;
+}
+
+// setSubtitleOffset shifts the current subtitles by offsetMs, positive being
+// later. The server keeps it, so other devices get the same timing.
+function setSubtitleOffset(offsetMs) {
+  if (!currentSubtitle) return;
+  const item = media[mostRecentID];
+  if (item) {
+    item.subtitleOffsets = Object.assign({}, item.subtitleOffsets);
+    if (offsetMs === 0) {
+      delete item.subtitleOffsets[String(currentSubtitle.id)];
+    } else {
+      item.subtitleOffsets[String(currentSubtitle.id)] = offsetMs;
+    }
+  }
+  loadSubtitle(offsetMs);
+}
+
+function editSubtitleOffset() {
+  if (!currentSubtitle) {
+    alert("Pick a subtitle track first.");
+    return;
+  }
+  if (currentSubtitle.secondary !== undefined) {
+    alert("Pick a single subtitle track to adjust its offset.");
+    return;
+  }
+  const input = prompt(
+    "Subtitle offset in milliseconds, positive shows them later.\n" +
+    "Use g and h during playback to nudge by 100 ms.",
+    String(subtitleOffset()));
+  if (input === null) return;
+  const ms = parseInt(input.trim() || "0", 10);
+  if (Number.isNaN(ms)) return;
+  setSubtitleOffset(ms);
+}
+
+// nudgeSubtitleOffset shifts the subtitles by deltaMs. Reloading the track is
+// held back until the nudging stops, so a few presses make one request.
+let nudgeTimer = null;
+let nudgedOffset = null;
+function nudgeSubtitleOffset(deltaMs) {
+  if (!currentSubtitle || currentSubtitle.secondary !== undefined) return;
+  nudgedOffset = (nudgedOffset === null ? subtitleOffset() : nudgedOffset) + deltaMs;
+  console.log(`Subtitle offset: ${nudgedOffset} ms`);
+  clearTimeout(nudgeTimer);
+  nudgeTimer = setTimeout(() => {
+    setSubtitleOffset(nudgedOffset);
+    nudgedOffset = null;
+  }, 400);
+}
+
 // Integrate events.js
 (function () {
   const script = document.createElement("script");
@@ -1062,6 +1304,7 @@ function renderSuggestionsFromPayload(payload) {
 
   const SKIP_INTRO_SEC = 85; // typical TV intro length
   const NUDGE_SEC = 10;
+  const SUBTITLE_NUDGE_MS = 100;
 
   const state = {
     id: "",
@@ -1108,6 +1351,7 @@ function renderSuggestionsFromPayload(payload) {
   }
 
   function resetSubtitles() {
+    currentSubtitle = null;
     if (subsTrack) {
       subsTrack.src = "";
       subsTrack.removeAttribute("src");
@@ -1115,13 +1359,15 @@ function renderSuggestionsFromPayload(payload) {
     }
   }
 
-  function load(id) {
+  // load the media of id, playing from at seconds if set, or else from
+  // where it was left.
+  function load(id, at) {
     if (!id) return;
     state.id = id;
     mostRecentID = id;
     const it = media[id] || {};
     state.duration = itemDurationSec(id);
-    const resume = getResume(id, state.duration);
+    const resume = at > 0 ? at : getResume(id, state.duration);
 
     titleEl.textContent = prettyMediaName(it);
     if (hero) hero.classList.add("hidden");
@@ -1301,6 +1547,8 @@ function renderSuggestionsFromPayload(payload) {
       case "ArrowRight": e.preventDefault(); nudge(NUDGE_SEC); showUI(); break;
       case "f": el.requestFullscreen ? (document.fullscreenElement ? document.exitFullscreen() : el.requestFullscreen()) : null; break;
       case "m": video.muted = !video.muted; break;
+      case "g": nudgeSubtitleOffset(-SUBTITLE_NUDGE_MS); break;
+      case "h": nudgeSubtitleOffset(SUBTITLE_NUDGE_MS); break;
     }
   });
 
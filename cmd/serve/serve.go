@@ -14,7 +14,10 @@ import (
 
 	"github.com/baalimago/clai/pkg/text/models"
 	"github.com/baalimago/go_away_boilerplate/pkg/ancli"
+	"github.com/baalimago/kinoview/internal/agents/classifier"
 	"github.com/baalimago/kinoview/internal/agents/theatre"
+	"github.com/baalimago/kinoview/internal/lang"
+	"github.com/baalimago/kinoview/internal/media/stream"
 	"github.com/baalimago/kinoview/internal/s3embed"
 )
 
@@ -62,6 +65,12 @@ type command struct {
 	classificationBurst           *int
 	classificationStartupCooldown *time.Duration
 	classificationTimeout         *time.Duration
+	classificationBatchSize       *int
+	reviewThreshold               *float64
+	escalateBelow                 *float64
+	titlesPath                    *string
+	parentalPath                  *string
+	tagBackfill                   *bool
 	pprof                         *bool
 	butlerModel                   *string
 	recommenderModel              *string
@@ -78,6 +87,10 @@ type command struct {
 	pongGrace                     *time.Duration
 	conciergeInterval             *time.Duration
 	conciergeTimeout              *time.Duration
+	ffprobeMediaTypes             *bool
+	subtitleLanguages             *string
+	subtitleCacheSize             *int64
+	ocrEngine                     *string
 	// S3 backend for the shared agent notebook: the supervised SeaweedFS child.
 	s3ServerPath *string
 	s3ServerPort *int
@@ -114,6 +127,12 @@ func Command() *command {
 	*ret.classificationRate = 0.2
 	*ret.classificationBurst = 3
 	*ret.classificationTimeout = 5 * time.Minute
+	ret.classificationBatchSize = new(int)
+	*ret.classificationBatchSize = 24
+	ret.reviewThreshold = new(float64)
+	*ret.reviewThreshold = 0.6
+	ret.escalateBelow = new(float64)
+	*ret.escalateBelow = classifier.DefaultEscalationThreshold
 	*ret.conciergeStartupDelay = 60 * time.Second
 	ret.theatreCooldown = new(time.Duration)
 	*ret.theatreCooldown = theatre.DefaultCooldown
@@ -310,13 +329,19 @@ func (c *command) Flagset() *flag.FlagSet {
 	c.tlsCertPath = fs.String("tlsCertPath", "", "set to a path to a cert, requires tlsKeyPath to be set")
 	c.tlsKeyPath = fs.String("tlsKeyPath", "", "set to a path to a key, requires tlsCertPath to be set")
 
-	c.classificationModel = fs.String("classifier", "", "set to LLM text model you'd like to use for the classifier. Supports multiple vendors automatically via clai. If unset, feature will be disabled.")
+	c.classificationModel = fs.String("classifier", "", "set to LLM text model you'd like to use for the classifier. Supports multiple vendors automatically via clai. Several models separated by ',' are tried cheapest first, the next one asked whenever one fails or is unsure, and routes separated by ';' pick the models by mime type, such as 'gpt-4.1-mini,gpt-5;image=gpt-4o-mini'. If unset, feature will be disabled.")
 	c.classificationWorkers = fs.Int("classifierWorkers", 2, "set amount of workers used for classification")
 	c.pprof = fs.Bool("pprof", false, "enable /debug/pprof/ endpoints for memory profiling")
 	c.classificationRate = fs.Float64("classificationRate", 0.2, "classifications per second (1 every 5s default)")
 	c.classificationBurst = fs.Int("classificationBurst", 3, "max burst before rate limit kicks in")
 	c.classificationStartupCooldown = fs.Duration("classificationStartupCooldown", 10*time.Second, "delay before first classification is admitted")
 	c.classificationTimeout = fs.Duration("classifierTimeout", 5*time.Minute, "wall-clock cap for one classification call; a classifier stuck on a looping model is aborted after this and the attempt counts against the item's max-attempts budget")
+	c.classificationBatchSize = fs.Int("classifierBatch", 24, "most videos of one folder, such as the episodes of a season, classified in one LLM call, 1 to classify every video on its own")
+	c.reviewThreshold = fs.Float64("reviewThreshold", 0.6, "confidence, from 0 to 1, below which a classified field flags the item for review, 0 to not flag by confidence")
+	c.escalateBelow = fs.Float64("escalateBelow", classifier.DefaultEscalationThreshold, "confidence, from 0 to 1, below which a classification is handed on to the next model of the -classifier route, 0 to escalate on failures only")
+	c.tagBackfill = fs.Bool("tagBackfill", false, "have the classifier tag videos classified before there were genre, mood, age rating and content warning tags, one at a time while it's idle")
+	c.titlesPath = fs.String("titles", "", "path to a TSV dataset of titles, such as IMDb's title.basics.tsv, to look metadata up in before asking the classifier. If unset, feature will be disabled.")
+	c.parentalPath = fs.String("parental", "", "path to a JSON file of parental controls: the most an age rating may be per device, and the sha256 of the PIN which overrides it. Overrides are audited in <configDir>/parental_audit.jsonl. If unset, feature will be disabled.")
 	c.recommenderModel = fs.String("recommender", "", "set to LLM text model you'd like to use for the classifier. Supports multiple vendors automatically via clai. If unset, feature will be disabled.")
 	c.butlerModel = fs.String("butler", "", "set to LLM text model you'd like to use for the butler. Supports multiple vendors automatically via clai. If unset, feature will be disabled.")
 	c.conciergeModel = fs.String("concierge", "", "set to LLM text model you'd like to use for the concierge. Supports multiple vendors automatically via clai. If unset, feature will be disabled.")
@@ -332,6 +357,10 @@ func (c *command) Flagset() *flag.FlagSet {
 	c.pongGrace = fs.Duration("pongGrace", 10*time.Second, "grace period after a pong timeout before a disconnect cascade fires; 0 disables")
 	c.conciergeInterval = fs.Duration("conciergeInterval", 6*time.Hour, "interval between concierge runs")
 	c.conciergeTimeout = fs.Duration("conciergeTimeout", 10*time.Minute, "wall-clock cap for a single concierge run; a run stuck on a looping model is aborted after this and the next run happens at the next interval")
+	c.subtitleLanguages = fs.String("subtitleLanguages", lang.FromEnv().String(), "preferred subtitle languages, most preferred first, as ISO 639-1/639-2 codes or BCP-47 tags. Clients may override it per device. Defaults to KINOVIEW_SUBTITLE_LANGUAGES, or en")
+	c.ocrEngine = fs.String("ocr", stream.OCRAuto, "tesseract binary, by name or path, used to read bitmap (PGS, VobSub, DVB) subtitles by OCR. 'auto' uses tesseract on PATH if there is one, 'off' disables OCR. Serve won't start if a binary set here can't be run")
+	c.subtitleCacheSize = fs.Int64("subtitleCacheSize", stream.DefaultCacheLimit>>20, "size in MiB the extracted subtitles may take up before the least recently used are evicted, 0 for no limit")
+	c.ffprobeMediaTypes = fs.Bool("ffprobeMediaTypes", false, "confirm media types with ffprobe when a file is only recognised by its extension")
 
 	// The shared agent notebook: a supervised SeaweedFS child (the S3 backend)
 	// and the slivingdoc MCP callsign over it. The feature is on when both
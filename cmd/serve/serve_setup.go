@@ -21,12 +21,18 @@ import (
 	"github.com/baalimago/kinoview/internal/agents/slivingdoc"
 	"github.com/baalimago/kinoview/internal/agents/theatre"
 	"github.com/baalimago/kinoview/internal/agents/tools"
+	"github.com/baalimago/kinoview/internal/lang"
 	"github.com/baalimago/kinoview/internal/loghandler"
 	"github.com/baalimago/kinoview/internal/media"
 	"github.com/baalimago/kinoview/internal/media/clientcontext"
+	"github.com/baalimago/kinoview/internal/media/parental"
+	"github.com/baalimago/kinoview/internal/media/quotes"
 	"github.com/baalimago/kinoview/internal/media/storage"
 	"github.com/baalimago/kinoview/internal/media/stream"
 	"github.com/baalimago/kinoview/internal/media/suggestions"
+	"github.com/baalimago/kinoview/internal/media/thumbnail"
+	"github.com/baalimago/kinoview/internal/media/titles"
+	"github.com/baalimago/kinoview/internal/media/watcher"
 	"github.com/baalimago/kinoview/internal/s3embed"
 	wd41serve "github.com/baalimago/wd-41/cmd/serve"
 )
@@ -67,10 +73,24 @@ func (c *command) Setup(ctx context.Context) error {
 	////////////
 	// Subtitle stream manager setup
 	////////////
-	subsManager, err := stream.NewManager(
+	subsOpts := []stream.Option{
 		stream.WithStoragePath(subsPath),
 		stream.WithSubtitleCachePath(*c.cacheDir),
-	)
+	}
+	if c.subtitleCacheSize != nil {
+		subsOpts = append(subsOpts, stream.WithCacheLimit(*c.subtitleCacheSize<<20))
+	}
+	if c.ocrEngine != nil {
+		ocrEngine, err := stream.ResolveOCREngine(ctx, *c.ocrEngine)
+		if err != nil {
+			return fmt.Errorf("-ocr: %w", err)
+		}
+		if ocrEngine == "" && *c.ocrEngine != stream.OCROff {
+			ancli.Warnf("tesseract not found on PATH, bitmap (PGS, VobSub, DVB) subtitles can't be read by OCR. Install tesseract, or set -ocr off to silence this")
+		}
+		subsOpts = append(subsOpts, stream.WithOCREngine(ocrEngine))
+	}
+	subsManager, err := stream.NewManager(subsOpts...)
 	if err != nil {
 		ancli.Warnf("failed to create subtitle stream manager, some features may not work: %v", err)
 		subsManager = nil
@@ -81,19 +101,56 @@ func (c *command) Setup(ctx context.Context) error {
 		return fmt.Errorf("failed to create suggestions manager: %w", err)
 	}
 
+	////////////
+	// Title dataset setup
+	////////////
+	var titleDataset *titles.Dataset
+	if c.titlesPath != nil && *c.titlesPath != "" {
+		titleDataset, err = titles.Open(*c.titlesPath)
+		if err != nil {
+			return fmt.Errorf("-titles: %w", err)
+		}
+		// Up front, rather than stalling the first classification
+		if err := titleDataset.Index(); err != nil {
+			return fmt.Errorf("-titles: %w", err)
+		}
+	}
+
+	////////////
+	// Parental controls setup
+	////////////
+	var parentalControls *parental.Controls
+	if c.parentalPath != nil && *c.parentalPath != "" {
+		parentalControls, err = parental.Load(*c.parentalPath,
+			parental.WithAuditLog(path.Join(*c.configDir, "parental_audit.jsonl")),
+			parental.WithKeyFile(path.Join(*c.configDir, "parental_device_key")))
+		if err != nil {
+			return fmt.Errorf("-parental: %w", err)
+		}
+	}
+
 	////////////
 	// Storage setup (early, without classifier for circular dep resolution)
 	////////////
-	store := storage.NewStore(
+	storeOpts := []storage.StoreOption{
 		storage.WithStorePath(storePath),
 		storage.WithSubtitlesManager(subsManager),
+		storage.WithThumbnailCache(thumbnail.NewCache(path.Join(*c.cacheDir, "thumbnails"))),
 		storage.WithClassificationWorkers(*c.classificationWorkers),
 		storage.WithClassificationRate(*c.classificationRate),
 		storage.WithClassificationBurst(*c.classificationBurst),
 		storage.WithClassificationStartupCooldown(*c.classificationStartupCooldown),
 		storage.WithClassificationTimeout(*c.classificationTimeout),
+		storage.WithClassificationBatchSize(*c.classificationBatchSize),
+		storage.WithReviewThreshold(*c.reviewThreshold),
+		storage.WithTagBackfill(c.tagBackfill != nil && *c.tagBackfill),
 		storage.WithStartupWriteDelay(*c.startupWriteDelay),
-	)
+		storage.WithParentalControls(parentalControls),
+	}
+	if titleDataset != nil {
+		storeOpts = append(storeOpts, storage.WithMetadataProvider(titleDataset))
+	}
+	store := storage.NewStore(storeOpts...)
 	// Give shutdown a wait point: cancelling the context stops the store's
 	// goroutines, and Wait guarantees deferred writes flush before exit.
 	c.storeWait = store.Wait
@@ -170,6 +227,15 @@ func (c *command) Setup(ctx context.Context) error {
 		}
 	}
 
+	subtitleLanguages := lang.Default
+	if c.subtitleLanguages != nil {
+		subtitleLanguages = lang.Parse(*c.subtitleLanguages).Or(lang.Default)
+	}
+	ancli.Noticef("preferred subtitle languages: %v", subtitleLanguages)
+	// One chain for every tool fetching subtitles, so that they count
+	// against the same quota
+	subtitleProvider := tools.DefaultSubtitleProviders(*c.cacheDir)
+
 	////////////
 	// Classifier setup
 	////////////
@@ -177,7 +243,6 @@ func (c *command) Setup(ctx context.Context) error {
 	if *c.classificationModel != "" {
 		ancli.Noticef("creating new classifier")
 		classifierConf := models.Configurations{
-			Model:     *c.classificationModel,
 			ConfigDir: *c.configDir,
 			InternalTools: []models.ToolName{
 				models.CatTool,
@@ -187,17 +252,45 @@ func (c *command) Setup(ctx context.Context) error {
 				models.RipGrepTool,
 			},
 		}
+		var classifierTools []models.LLMTool
 		// Fetch subtitles tool (if OpenSubtitles API key is configured)
-		fetchTool := tools.NewFetchSubtitlesTool(store, subsManager, *c.cacheDir)
+		fetchTool := tools.NewFetchSubtitlesToolWithProvider(store, subsManager, *c.cacheDir, subtitleLanguages, subtitleProvider)
 		if fetchTool != nil {
-			clifier = classifier.NewWithTools(classifierConf, []models.LLMTool{fetchTool})
+			classifierTools = append(classifierTools, fetchTool)
 		} else {
-			ancli.Warnf("OPENSUBTITLES_API_KEY not set — fetch_subtitles tool will not be available")
-			clifier = classifier.New(classifierConf)
+			ancli.Warnf("neither OPENSUBTITLES_API_KEY nor KINOVIEW_SUBTITLE_DIR set — fetch_subtitles tool will not be available")
+		}
+		if titleDataset != nil {
+			lookupTool, err := tools.NewLookupTitleTool(titleDataset)
+			if err != nil {
+				return fmt.Errorf("failed to create lookup_title tool: %w", err)
+			}
+			classifierTools = append(classifierTools, lookupTool)
+		}
+		escalateBelow := classifier.DefaultEscalationThreshold
+		if c.escalateBelow != nil {
+			escalateBelow = *c.escalateBelow
+		}
+		clifier, err = classifier.NewRouted(*c.classificationModel, func(m string) agents.Classifier {
+			conf := classifierConf
+			conf.Model = m
+			if len(classifierTools) > 0 {
+				return classifier.NewWithTools(conf, classifierTools)
+			}
+			return classifier.New(conf)
+		},
+			classifier.WithEscalationThreshold(escalateBelow),
+			classifier.WithOutcomeLog(path.Join(*c.configDir, "classifier_outcomes.jsonl")),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to create classifier: %w", err)
 		}
 		store.SetClassifier(clifier)
 	}
 
+	// Dialogue of the library, as extracted to subsPath
+	quoteIndex := quotes.New(subsPath, store)
+
 	////////////
 	// Recommender setup
 	////////////
@@ -207,7 +300,7 @@ func (c *command) Setup(ctx context.Context) error {
 			Model:         *c.recommenderModel,
 			ConfigDir:     *c.configDir,
 			InternalTools: []models.ToolName{},
-		})
+		}, recommender.WithQuoteSearcher(quoteIndex))
 	}
 
 	////////////
@@ -224,6 +317,7 @@ func (c *command) Setup(ctx context.Context) error {
 					ConfigDir:     *c.configDir,
 					InternalTools: []models.ToolName{},
 				}, subsManager,
+				butler.WithSubtitleLanguages(subtitleLanguages),
 			)
 		}
 	}
@@ -251,6 +345,9 @@ func (c *command) Setup(ctx context.Context) error {
 			concierge.WithCacheDir(*c.cacheDir),
 			concierge.WithUserContextManager(userContextMgr),
 			concierge.WithModel(*c.conciergeModel),
+			concierge.WithSubtitleLanguages(subtitleLanguages),
+			concierge.WithSubtitleProvider(subtitleProvider),
+			concierge.WithQuoteSearcher(quoteIndex),
 			// The shared agent notebook: a zero callsign (no S3 backend or no
 			// slivingdoc command) keeps the concierge running exactly as before.
 			concierge.WithSlivingdocServer(c.slivingdocServer),
@@ -327,6 +424,16 @@ func (c *command) Setup(ctx context.Context) error {
 		media.WithConciergeInterval(*c.conciergeInterval),
 		media.WithConciergeTimeout(*c.conciergeTimeout),
 		media.WithConciergeCacheDir(*c.cacheDir),
+		media.WithSubtitleLanguages(subtitleLanguages),
+		media.WithQuoteSearcher(quoteIndex),
+		media.WithReviewQueue(store),
+		media.WithMetadataHistory(store),
+		media.WithClassificationQueue(store),
+		media.WithParentalControls(parentalControls),
+		media.WithWatcherOptions(
+			watcher.WithGlobalIgnoreFile(path.Join(*c.configDir, watcher.IgnoreFileName)),
+			watcher.WithFFProbe(c.ffprobeMediaTypes != nil && *c.ffprobeMediaTypes),
+		),
 	)
 	if err != nil {
 		return fmt.Errorf("c.indexer.Setup failed to create Indexer, err: %v", err)
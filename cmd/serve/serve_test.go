@@ -3,11 +3,17 @@ package serve
 import (
 	"context"
 	"flag"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"path"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/baalimago/kinoview/internal/media/quotes"
 )
 
 func TestSetup(t *testing.T) {
@@ -184,3 +190,32 @@ func TestRun(t *testing.T) {
 		}
 	})
 }
+
+// TestQuoteLink_playsInFrontend checks that a quote link, to a native MP4
+// which is served as is and so never seeks server side, opens the player,
+// and that the player seeks to the quote.
+func TestQuoteLink_playsInFrontend(t *testing.T) {
+	link, err := url.Parse(quotes.Link("mp4-movie", 90*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link.Path != "/" || link.Query().Get("play") != "mp4-movie" || link.Query().Get("t") != "90" {
+		t.Fatalf("expected a link to the player, got %v", link)
+	}
+	subFs, err := fs.Sub(frontendFiles, "frontend")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	http.FileServer(http.FS(subFs)).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, link.String(), nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "index.js") {
+		t.Fatalf("expected the player to be served at %v, got %d", link, rec.Code)
+	}
+	js, err := fs.ReadFile(subFs, "index.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(js), "params.get('t')") {
+		t.Error("expected the player to read the position to play from")
+	}
+}
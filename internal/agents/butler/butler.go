@@ -17,6 +17,7 @@ import (
 	"github.com/baalimago/go_away_boilerplate/pkg/debug"
 	"github.com/baalimago/go_away_boilerplate/pkg/misc"
 	"github.com/baalimago/kinoview/internal/agents"
+	"github.com/baalimago/kinoview/internal/lang"
 	"github.com/baalimago/kinoview/internal/model"
 )
 
@@ -24,12 +25,27 @@ type butler struct {
 	llm      text.FullResponse
 	subs     agents.StreamManager
 	selector agents.SubtitleSelector
+	// langs are the subtitle languages preferred when the client doesn't
+	// state its own.
+	langs lang.Preferences
+}
+
+type ButlerOption func(*butler)
+
+// WithSubtitleLanguages sets the subtitle languages preloaded for clients
+// which haven't configured any, most preferred first. Defaults to
+// lang.Default.
+func WithSubtitleLanguages(langs lang.Preferences) ButlerOption {
+	return func(b *butler) {
+		b.langs = langs
+	}
 }
 
 // SuggestionFingerprintVersion is bumped whenever the picker system prompt,
 // the response schema, or butlerItemView changes. Phase 2 (index), Phase 4
-// (payload diet) and the suggestion-view upgrade (showName) each bumped it.
-const SuggestionFingerprintVersion = 4
+// (payload diet), the suggestion-view upgrade (showName) and the tags (mood,
+// age rating, warnings) each bumped it.
+const SuggestionFingerprintVersion = 5
 
 const pickerSystemPrompt = `You are a media Butler. Your goal is to anticipate what the user wants to watch next.
 You will be given the user's context (viewing history, time of day etc) and a list of available media.
@@ -39,7 +55,7 @@ Do not suggest items that are clearly not in the provided media list.
 Be concise.
 Add a posh style to your replies as it will be user facing.
 
-Item key legend: i=index n=filename t=title y=year s=season e=episode g=genre r=runtimeMinutes sn=seriesName (for episodes)
+Item key legend: i=index n=filename t=title y=year s=season e=episode g=genre r=runtimeMinutes sn=seriesName (for episodes) m=mood ar=ageRating w=contentWarnings
 
 Hints, in order of importance:
 	1. Users prefer to watch series sequentially. If previous episode was 3, the next should be 4, of the same season.
@@ -87,6 +103,11 @@ type butlerItemView struct {
 	Runtime int    `json:"r,omitempty"`
 	// ShowName is the series name for episodes, when known.
 	ShowName string `json:"sn,omitempty"`
+	// Mood, AgeRating and Warnings are a few words at most, and spare the
+	// butler guessing them from the name alone.
+	Mood      []string `json:"m,omitempty"`
+	AgeRating string   `json:"ar,omitempty"`
+	Warnings  []string `json:"w,omitempty"`
 }
 
 // butlerContextView is the subset of ClientContext the butler receives.
@@ -101,26 +122,19 @@ type viewMetadata struct {
 	PlayedForSec string `json:"playedFor"`
 }
 
-// butlerMetadata is the subset of classifier metadata relevant to the butler.
-type butlerMetadata struct {
-	Name        string `json:"name"`
-	ShowName    string `json:"showName"`
-	AltName     string `json:"alt_name"`
-	Year        int    `json:"year"`
-	Season      int    `json:"season"`
-	Episode     int    `json:"episode"`
-	Genre       string `json:"genre"`
-	DurationMin int    `json:"duration_min"`
-}
-
 // New configured by models.Configurations and a Subtitler
-func New(c models.Configurations, subs agents.StreamManager) agents.Butler {
+func New(c models.Configurations, subs agents.StreamManager, opts ...ButlerOption) agents.Butler {
 	c.SystemPrompt = pickerSystemPrompt
-	return &butler{
+	b := &butler{
 		llm:      text.NewFullResponseQuerier(c),
 		subs:     subs,
 		selector: NewSelector(c),
+		langs:    lang.Default,
 	}
+	for _, o := range opts {
+		o(b)
+	}
+	return b
 }
 
 func (b *butler) Setup(ctx context.Context) error {
@@ -175,6 +189,7 @@ func (b *butler) PrepSuggestions(ctx context.Context, clientCtx model.ClientCont
 		return nil, fmt.Errorf("failed to parse suggestions: %w", err)
 	}
 
+	langs := clientCtx.SubtitleLanguages.Or(b.langs)
 	var recommendations []model.Suggestion
 	var wg sync.WaitGroup
 	var mu sync.Mutex
@@ -185,7 +200,7 @@ func (b *butler) PrepSuggestions(ctx context.Context, clientCtx model.ClientCont
 		go func(suggestion suggestionResponse) {
 			defer wg.Done()
 			rec, err := b.prepSuggestion(ctx, suggestion,
-				items)
+				items, langs)
 			if err != nil {
 				var psErr *PreloadSubsError
 				if errors.As(err, &psErr) {
@@ -225,20 +240,20 @@ func ProjectItems(items []model.Item) []butlerItemView {
 			Index: idx,
 			Name:  it.Name,
 		}
-		if it.Metadata != nil {
-			var meta butlerMetadata
-			if err := json.Unmarshal(*it.Metadata, &meta); err == nil {
-				v.Year = meta.Year
-				v.Season = meta.Season
-				v.Episode = meta.Episode
-				v.Genre = meta.Genre
-				v.Runtime = meta.DurationMin
-				v.ShowName = meta.ShowName
-				if meta.Name != "" && meta.Name != it.Name {
-					v.Title = meta.Name
-				} else if meta.AltName != "" && meta.AltName != it.Name {
-					v.Title = meta.AltName
-				}
+		if meta := it.Metadata; meta != nil {
+			v.Year = meta.Year
+			v.Season = meta.Season
+			v.Episode = meta.Episode
+			v.Genre = meta.Genre
+			v.Runtime = meta.DurationMin
+			v.ShowName = meta.ShowName
+			v.Mood = meta.Mood
+			v.AgeRating = meta.AgeRating
+			v.Warnings = meta.ContentWarnings
+			if meta.Name != "" && meta.Name != it.Name {
+				v.Title = meta.Name
+			} else if meta.AltName != "" && meta.AltName != it.Name {
+				v.Title = meta.AltName
 			}
 		}
 		views[idx] = v
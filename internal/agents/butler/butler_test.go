@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/baalimago/clai/pkg/text/models"
+	"github.com/baalimago/kinoview/internal/lang"
 	"github.com/baalimago/kinoview/internal/model"
 )
 
@@ -61,6 +62,10 @@ func (m *MockSubtitler) ExtractSubtitles(item model.Item, streamIndex string) (s
 	return "", nil
 }
 
+func (m *MockSubtitler) SyncSubtitles(item model.Item, streamIndex string) (string, error) {
+	return "", nil
+}
+
 func (m *MockSubtitler) Associate(item model.Item, path string) error {
 	return nil
 }
@@ -68,9 +73,12 @@ func (m *MockSubtitler) Associate(item model.Item, path string) error {
 // MockSubtitleSelector mocks the SubtitleSelector interface
 type MockSubtitleSelector struct {
 	SelectEnglishFunc func(ctx context.Context, streams []model.Stream) (int, error)
+	// GotLangs are the languages of the last call to Select.
+	GotLangs lang.Preferences
 }
 
-func (m *MockSubtitleSelector) Select(ctx context.Context, streams []model.Stream) (int, error) {
+func (m *MockSubtitleSelector) Select(ctx context.Context, streams []model.Stream, langs lang.Preferences) (int, error) {
+	m.GotLangs = langs
 	if m.SelectEnglishFunc != nil {
 		return m.SelectEnglishFunc(ctx, streams)
 	}
@@ -194,6 +202,48 @@ func TestButler_PrepSuggestions(t *testing.T) {
 	}
 }
 
+func TestButler_PrepSuggestions_SubtitleLanguages(t *testing.T) {
+	mockLLM := &MockFullResponse{
+		QueryFunc: func(ctx context.Context, chat models.Chat) (models.Chat, error) {
+			content := `{"index": 0}`
+			if strings.Contains(chat.Messages[0].Content, "You are a media Butler") {
+				content = `[{"description": "Movie A", "motivation": "m"}]`
+			}
+			return models.Chat{Messages: []models.Message{{Role: "assistant", Content: content}}}, nil
+		},
+	}
+	mockSubs := &MockSubtitler{
+		FindFunc: func(item model.Item) (model.MediaInfo, error) {
+			return model.MediaInfo{Streams: []model.Stream{{Index: 1, CodecType: "subtitle"}}}, nil
+		},
+		ExtractFunc: func(item model.Item, streamIndex string) (string, error) {
+			return "/tmp/subs.vtt", nil
+		},
+	}
+	items := []model.Item{{Name: "Movie A", MIMEType: "video/mp4"}}
+
+	for _, tc := range []struct {
+		name   string
+		client lang.Preferences
+		want   string
+	}{
+		{name: "server default", want: "sv"},
+		{name: "client overrides", client: lang.Preferences{"ja", "en"}, want: "ja,en"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sel := &MockSubtitleSelector{}
+			b := &butler{llm: mockLLM, subs: mockSubs, selector: sel, langs: lang.Preferences{"sv"}}
+			_, err := b.PrepSuggestions(context.Background(), model.ClientContext{SubtitleLanguages: tc.client}, items)
+			if err != nil {
+				t.Fatalf("PrepSuggestions failed: %v", err)
+			}
+			if got := sel.GotLangs.String(); got != tc.want {
+				t.Errorf("selector got languages %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
 func TestButler_PrepSuggestions_LLMErrors(t *testing.T) {
 	ctx := context.Background()
 	mockLLM := &MockFullResponse{
@@ -253,7 +303,7 @@ func TestButler_prepSuggestion_SubsErrors(t *testing.T) {
 		},
 	}
 	b := &butler{llm: mockLLM, subs: mockSubs}
-	_, err := b.prepSuggestion(ctx, suggestionResponse{Description: "Movie A"}, items)
+	_, err := b.prepSuggestion(ctx, suggestionResponse{Description: "Movie A"}, items, nil)
 	if err == nil {
 		t.Fatal("Expected error when subs.Find fails")
 	}
@@ -272,7 +322,7 @@ func TestButler_prepSuggestion_SubsErrors(t *testing.T) {
 		},
 	}
 	b.selector = mockSelector
-	_, err = b.prepSuggestion(ctx, suggestionResponse{Description: "Movie A"}, items)
+	_, err = b.prepSuggestion(ctx, suggestionResponse{Description: "Movie A"}, items, nil)
 	if err == nil {
 		t.Fatal("Expected error when selector fails")
 	}
@@ -287,7 +337,7 @@ func TestButler_prepSuggestion_SubsErrors(t *testing.T) {
 	mockSubs.ExtractFunc = func(item model.Item, streamIndex string) (string, error) {
 		return "", errors.New("extract error")
 	}
-	_, err = b.prepSuggestion(ctx, suggestionResponse{Description: "Movie A"}, items)
+	_, err = b.prepSuggestion(ctx, suggestionResponse{Description: "Movie A"}, items, nil)
 	if err == nil {
 		t.Fatal("Expected error when extract fails")
 	}
@@ -314,7 +364,7 @@ func TestSelector_SelectEnglish(t *testing.T) {
 		{Index: 1, CodecType: "subtitle", Tags: model.Tags{Language: "eng", Title: "English"}, CodecName: "subrip"},
 	}
 
-	idx, err := s.Select(ctx, streams)
+	idx, err := s.Select(ctx, streams, nil)
 	if err != nil {
 		t.Fatalf("Select failed: %v", err)
 	}
@@ -326,7 +376,7 @@ func TestSelector_SelectEnglish(t *testing.T) {
 	}
 
 	// No subtitles at all → error before LLM
-	_, err = s.Select(ctx, []model.Stream{{Index: 0, CodecType: "video"}})
+	_, err = s.Select(ctx, []model.Stream{{Index: 0, CodecType: "video"}}, nil)
 	if err == nil {
 		t.Error("Expected error when no subtitles found")
 	}
@@ -342,7 +392,7 @@ func TestSelector_SelectEnglish(t *testing.T) {
 	sSwedish := &selector{llm: llmForSwedish}
 	idx, err = sSwedish.Select(ctx, []model.Stream{
 		{Index: 1, CodecType: "subtitle", Tags: model.Tags{Language: "swe", Title: "Swedish"}},
-	})
+	}, nil)
 	if err != nil {
 		t.Fatalf("Select with Swedish fallback failed: %v", err)
 	}
@@ -362,7 +412,7 @@ func TestSelector_SelectEnglish(t *testing.T) {
 	sErr := &selector{llm: llmError}
 	_, err = sErr.Select(ctx, []model.Stream{
 		{Index: 0, CodecType: "subtitle", Tags: model.Tags{Language: "swe"}},
-	})
+	}, nil)
 	if err == nil {
 		t.Error("Expected error when LLM fails on fallback")
 	}
@@ -378,7 +428,7 @@ func TestSelector_SelectEnglish(t *testing.T) {
 	sErrJSON := &selector{llm: llmErrJSON}
 	_, err = sErrJSON.Select(ctx, []model.Stream{
 		{Index: 0, CodecType: "subtitle", Tags: model.Tags{Language: "swe"}},
-	})
+	}, nil)
 	if err == nil {
 		t.Error("Expected error when LLM returns error info")
 	}
@@ -389,7 +439,7 @@ func TestButler_MetadataAndDebug(t *testing.T) {
 	defer os.Unsetenv("DEBUG")
 
 	ctx := context.Background()
-	rawMeta := json.RawMessage(`{"year": 2023, "season": 1, "episode": 1, "alt_name": "Alt", "name": "Name"}`)
+	rawMeta := model.MediaMetadata{Year: 2023, Season: 1, Episode: 1, AltName: "Alt", Name: "Name"}
 	items := []model.Item{
 		{Name: "Item 1", Metadata: &rawMeta},
 	}
@@ -427,7 +477,7 @@ func TestButler_MetadataAndDebug(t *testing.T) {
 
 	b.selector.Select(ctx, []model.Stream{
 		{Index: 0, CodecType: "subtitle"},
-	})
+	}, nil)
 }
 
 func TestUnwrap(t *testing.T) {
@@ -503,7 +553,7 @@ func TestSelector_Fallback(t *testing.T) {
 	// Use Swedish so the deterministic path falls through to LLM
 	idx, err := s.Select(ctx, []model.Stream{
 		{Index: 0, CodecType: "subtitle", Tags: model.Tags{Language: "swe"}},
-	})
+	}, nil)
 	if err != nil {
 		t.Fatalf("Fallback failed: %v", err)
 	}
@@ -564,7 +614,7 @@ func TestSelector_EmptyResponse(t *testing.T) {
 	}
 	s := &selector{llm: mockLLM}
 	streams := []model.Stream{{CodecType: "subtitle", Index: 0, Tags: model.Tags{Language: "swe"}}}
-	_, err := s.Select(ctx, streams)
+	_, err := s.Select(ctx, streams, nil)
 	if err == nil {
 		t.Fatal("Expected error on empty response")
 	}
@@ -997,7 +1047,7 @@ func TestRankSubtitle_Table(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			score := rankSubtitle(tt.stream)
+			score := rankSubtitle(tt.stream, lang.Default)
 			isUsable := score >= 0
 			if isUsable != tt.wantPos {
 				t.Errorf("rankSubtitle score=%d, usable=%v, want usable=%v", score, isUsable, tt.wantPos)
@@ -1023,7 +1073,7 @@ func TestSelect_EnglishDispositionPriority(t *testing.T) {
 	}
 
 	s := &selector{llm: mockLLM}
-	idx, err := s.Select(ctx, streams)
+	idx, err := s.Select(ctx, streams, nil)
 	if err != nil {
 		t.Fatalf("Select failed: %v", err)
 	}
@@ -1049,7 +1099,7 @@ func TestSelect_FallsBackWhenNoUsableCandidate(t *testing.T) {
 		s := &selector{llm: mockLLM}
 		idx, err := s.Select(ctx, []model.Stream{
 			{Index: 0, CodecType: "subtitle", CodecName: "subrip", Tags: model.Tags{Language: "eng"}, Disposition: model.Disposition{Comment: 1}},
-		})
+		}, nil)
 		if err != nil {
 			t.Fatalf("Select should fall back to LLM, got error: %v", err)
 		}
@@ -1072,7 +1122,7 @@ func TestSelect_FallsBackWhenNoUsableCandidate(t *testing.T) {
 		s := &selector{llm: mockLLM}
 		idx, err := s.Select(ctx, []model.Stream{
 			{Index: 1, CodecType: "subtitle", CodecName: "subrip", Tags: model.Tags{Language: "swe"}},
-		})
+		}, nil)
 		if err != nil {
 			t.Fatalf("Select should fall back to LLM, got error: %v", err)
 		}
@@ -1099,7 +1149,7 @@ func TestSelect_BitmapEnglishBeatsForeignText(t *testing.T) {
 	idx, err := s.Select(ctx, []model.Stream{
 		{Index: 0, CodecType: "subtitle", CodecName: "subrip", Tags: model.Tags{Language: "swe"}},
 		{Index: 1, CodecType: "subtitle", CodecName: "hdmv_pgs_subtitle", Tags: model.Tags{Language: "eng"}},
-	})
+	}, nil)
 	if err != nil {
 		t.Fatalf("Select failed: %v", err)
 	}
@@ -1126,7 +1176,7 @@ func TestRankBest_Deterministic(t *testing.T) {
 			shifted[i] = fixture[(i+1)%len(fixture)]
 		}
 
-		idx, ok := rankBest(shifted)
+		idx, ok := rankBest(shifted, lang.Default)
 		if !ok {
 			t.Fatal("rankBest returned no usable candidate")
 		}
@@ -1140,7 +1190,7 @@ func TestRankSubtitle_RegionalLanguageTag(t *testing.T) {
 	score := rankSubtitle(model.Stream{
 		CodecType: "subtitle", CodecName: "subrip",
 		Tags: model.Tags{Language: "eng-US"},
-	})
+	}, lang.Default)
 	if score < 0 {
 		t.Errorf("eng-US should be treated as English, got score=%d", score)
 	}
@@ -1148,17 +1198,41 @@ func TestRankSubtitle_RegionalLanguageTag(t *testing.T) {
 	score = rankSubtitle(model.Stream{
 		CodecType: "subtitle", CodecName: "subrip",
 		Tags: model.Tags{Language: "en-GB"},
-	})
+	}, lang.Default)
 	if score < 0 {
 		t.Errorf("en-GB should be treated as English, got score=%d", score)
 	}
 }
 
+func TestRankSubtitle_LanguagePreferences(t *testing.T) {
+	langs := lang.Parse("sv,ja")
+	stream := func(tag string) model.Stream {
+		return model.Stream{CodecType: "subtitle", CodecName: "subrip", Tags: model.Tags{Language: tag}}
+	}
+	swe := rankSubtitle(stream("swe"), langs)
+	jpn := rankSubtitle(stream("jpn"), langs)
+	if swe <= jpn || jpn < 0 {
+		t.Errorf("expected swe > jpn >= 0, got swe=%d jpn=%d", swe, jpn)
+	}
+	if score := rankSubtitle(stream("eng"), langs); score >= 0 {
+		t.Errorf("English isn't preferred and should be unusable, got score=%d", score)
+	}
+
+	idx, ok := rankBest([]model.Stream{
+		{Index: 2, CodecType: "subtitle", CodecName: "subrip", Tags: model.Tags{Language: "eng"}, Disposition: model.Disposition{Default: 1}},
+		{Index: 3, CodecType: "subtitle", CodecName: "subrip", Tags: model.Tags{Language: "ja"}},
+		{Index: 4, CodecType: "subtitle", CodecName: "subrip", Tags: model.Tags{Language: "Swedish"}},
+	}, langs)
+	if !ok || idx != 4 {
+		t.Errorf("expected the Swedish track, got idx=%d ok=%v", idx, ok)
+	}
+}
+
 func TestRankSubtitle_UndefinedLanguage(t *testing.T) {
 	score := rankSubtitle(model.Stream{
 		CodecType: "subtitle", CodecName: "subrip",
 		Tags: model.Tags{Language: "und"},
-	})
+	}, lang.Default)
 	if score < 0 {
 		t.Errorf("und should be treated as unknown (usable), got score=%d", score)
 	}
@@ -1171,7 +1245,7 @@ func TestRankSubtitle_TitleOnlyCommentary(t *testing.T) {
 	score := rankSubtitle(model.Stream{
 		CodecType: "subtitle", CodecName: "subrip",
 		Tags: model.Tags{Title: "Director Commentary"},
-	})
+	}, lang.Default)
 	if score >= 0 {
 		t.Errorf("title 'commentary' should be unusable, got score=%d", score)
 	}
@@ -1184,7 +1258,7 @@ func TestRankBest_TieBreakOnIndex(t *testing.T) {
 		{Index: 8, CodecType: "subtitle", CodecName: "subrip", Tags: model.Tags{Language: "eng"}},
 	}
 
-	idx, ok := rankBest(streams)
+	idx, ok := rankBest(streams, lang.Default)
 	if !ok {
 		t.Fatal("rankBest returned no usable candidate")
 	}
@@ -1199,7 +1273,7 @@ func TestRankBest_AllUnusable(t *testing.T) {
 		{Index: 1, CodecType: "subtitle", CodecName: "subrip", Tags: model.Tags{Language: "spa"}},
 	}
 
-	_, ok := rankBest(streams)
+	_, ok := rankBest(streams, lang.Default)
 	if ok {
 		t.Error("rankBest should return false when all candidates are unusable")
 	}
@@ -1228,7 +1302,7 @@ func TestRankSubtitle_CodecPenalties(t *testing.T) {
 			score := rankSubtitle(model.Stream{
 				CodecType: "subtitle", CodecName: codec,
 				Tags: model.Tags{Language: "eng"},
-			})
+			}, lang.Default)
 			if score < 115 {
 				t.Errorf("Expected score >= 115 for text codec %s, got %d", codec, score)
 			}
@@ -1240,7 +1314,7 @@ func TestRankSubtitle_CodecPenalties(t *testing.T) {
 			score := rankSubtitle(model.Stream{
 				CodecType: "subtitle", CodecName: codec,
 				Tags: model.Tags{Language: "eng"},
-			})
+			}, lang.Default)
 			if score > 50 || score < 0 {
 				t.Errorf("Expected score around 50 for bitmap codec %s, got %d", codec, score)
 			}
@@ -1248,13 +1322,24 @@ func TestRankSubtitle_CodecPenalties(t *testing.T) {
 	}
 }
 
+func TestRankSubtitle_OCR(t *testing.T) {
+	text := rankSubtitle(model.Stream{CodecType: "subtitle", CodecName: "subrip", Tags: model.Tags{Language: "eng"}}, lang.Default)
+	bitmap := model.Stream{CodecType: "subtitle", CodecName: "hdmv_pgs_subtitle", Tags: model.Tags{Language: "eng"}}
+	raw := rankSubtitle(bitmap, lang.Default)
+	bitmap.OCR = true
+	ocr := rankSubtitle(bitmap, lang.Default)
+	if !(text > ocr && ocr > raw) {
+		t.Errorf("expected text (%d) > OCR (%d) > bitmap (%d)", text, ocr, raw)
+	}
+}
+
 func TestRankSubtitle_KaraokeSongPenalty(t *testing.T) {
 	for _, word := range []string{"sign", "song", "lyric", "karaoke"} {
 		t.Run(word, func(t *testing.T) {
 			score := rankSubtitle(model.Stream{
 				CodecType: "subtitle", CodecName: "subrip",
 				Tags: model.Tags{Language: "eng", Title: "English " + word + " track"},
-			})
+			}, lang.Default)
 			if score > 60 || score < 0 {
 				t.Errorf("Expected score around 55 for %s title, got %d", word, score)
 			}
@@ -1274,7 +1359,7 @@ func TestSelect_ViaLLMParsePaths(t *testing.T) {
 			},
 		}
 		s := &selector{llm: mockLLM}
-		idx, err := s.selectViaLLM(ctx, []model.Stream{{CodecType: "subtitle", Index: 0}})
+		idx, err := s.selectViaLLM(ctx, []model.Stream{{CodecType: "subtitle", Index: 0}}, lang.Default)
 		if err != nil {
 			t.Fatalf("selectViaLLM failed: %v", err)
 		}
@@ -1292,7 +1377,7 @@ func TestSelect_ViaLLMParsePaths(t *testing.T) {
 			},
 		}
 		s := &selector{llm: mockLLM}
-		_, err := s.selectViaLLM(ctx, []model.Stream{{CodecType: "subtitle", Index: 0}})
+		_, err := s.selectViaLLM(ctx, []model.Stream{{CodecType: "subtitle", Index: 0}}, lang.Default)
 		if err == nil {
 			t.Error("Expected error when LLM returns error JSON")
 		}
@@ -1307,7 +1392,7 @@ func TestSelect_ViaLLMParsePaths(t *testing.T) {
 			},
 		}
 		s := &selector{llm: mockLLM}
-		_, err := s.selectViaLLM(ctx, []model.Stream{{CodecType: "subtitle", Index: 0}})
+		_, err := s.selectViaLLM(ctx, []model.Stream{{CodecType: "subtitle", Index: 0}}, lang.Default)
 		if err == nil {
 			t.Error("Expected error when no index in response")
 		}
@@ -1322,7 +1407,7 @@ func TestSelect_ViaLLMParsePaths(t *testing.T) {
 			},
 		}
 		s := &selector{llm: mockLLM}
-		_, err := s.selectViaLLM(ctx, []model.Stream{{CodecType: "subtitle", Index: 0}})
+		_, err := s.selectViaLLM(ctx, []model.Stream{{CodecType: "subtitle", Index: 0}}, lang.Default)
 		if err == nil {
 			t.Error("Expected error on malformed JSON")
 		}
@@ -1337,7 +1422,7 @@ func TestSelect_ViaLLMParsePaths(t *testing.T) {
 			},
 		}
 		s := &selector{llm: mockLLM}
-		idx, err := s.selectViaLLM(ctx, []model.Stream{{CodecType: "subtitle", Index: 0}, {CodecType: "subtitle", Index: 1}})
+		idx, err := s.selectViaLLM(ctx, []model.Stream{{CodecType: "subtitle", Index: 0}, {CodecType: "subtitle", Index: 1}}, lang.Default)
 		if err != nil {
 			t.Fatalf("selectViaLLM should handle JSON in markdown: %v", err)
 		}
@@ -1359,7 +1444,7 @@ func TestSelect_NoSubtitleStreams(t *testing.T) {
 	_, err := s.Select(ctx, []model.Stream{
 		{Index: 0, CodecType: "video"},
 		{Index: 1, CodecType: "audio"},
-	})
+	}, nil)
 	if err == nil {
 		t.Fatal("Expected error for no subtitle streams")
 	}
@@ -1371,7 +1456,7 @@ func TestSelect_NoSubtitleStreams(t *testing.T) {
 // Phase 4 tests — Butler Payload Diet
 
 func TestProjectItems_FieldSet(t *testing.T) {
-	rawMeta := json.RawMessage(`{"name": "The Movie", "showName": "The Show", "year": 2023, "season": 1, "episode": 4, "genre": "Action", "duration_min": 120}`)
+	rawMeta := model.MediaMetadata{Name: "The Movie", ShowName: "The Show", Year: 2023, Season: 1, Episode: 4, Genre: "Action", DurationMin: 120, Mood: []string{"tense"}, AgeRating: "PG-13", ContentWarnings: []string{"violence"}}
 	items := []model.Item{
 		{Name: "Movie.mp4", Metadata: &rawMeta},
 	}
@@ -1388,7 +1473,7 @@ func TestProjectItems_FieldSet(t *testing.T) {
 	}
 
 	// Every key must be one of the allowed fields.
-	allowed := map[string]bool{"i": true, "n": true, "t": true, "y": true, "s": true, "e": true, "g": true, "r": true, "sn": true}
+	allowed := map[string]bool{"i": true, "n": true, "t": true, "y": true, "s": true, "e": true, "g": true, "r": true, "sn": true, "m": true, "ar": true, "w": true}
 	for k := range out {
 		if !allowed[k] {
 			t.Errorf("Unexpected key %q in butlerItemView JSON — field not in the projection", k)
@@ -1397,10 +1482,13 @@ func TestProjectItems_FieldSet(t *testing.T) {
 	if out["sn"] != "The Show" {
 		t.Errorf("showName not projected, got %v", out["sn"])
 	}
+	if out["ar"] != "PG-13" || out["m"] == nil || out["w"] == nil {
+		t.Errorf("tags not projected, got %v", out)
+	}
 }
 
 func TestFormatItems_NoProseMetadata(t *testing.T) {
-	rawMeta := json.RawMessage(`{"name": "The Movie", "description": "A long plot summary", "actors": ["Actor 1", "Actor 2"], "year": 2023, "plot": "The story begins...", "director": "Someone", "rating": 8.5}`)
+	rawMeta := model.MediaMetadata{Name: "The Movie", Description: "A long plot summary", Actors: []string{"Actor 1", "Actor 2"}, Year: 2023, Extra: map[string]json.RawMessage{"plot": json.RawMessage(`"The story begins..."`), "director": json.RawMessage(`"Someone"`), "rating": json.RawMessage(`8.5`)}}
 	items := []model.Item{
 		{Name: "Movie.mp4", Metadata: &rawMeta},
 	}
@@ -1422,7 +1510,16 @@ func TestFormatItems_SizeBudget(t *testing.T) {
 	// Generate items with rich metadata to simulate real data.
 	var items []model.Item
 	for i := range 434 {
-		rawMeta := json.RawMessage(fmt.Sprintf(`{"name":"Movie %d","alt_name":"Alt %d","year":%d,"season":%d,"episode":%d,"description":"A long description for movie %d with lots of text that would bulk up the payload","actors":["Actor A","Actor B","Actor C","Actor D"],"duration_min":%d}`, i, i, 2020+(i%5), (i%3)+1, (i%12)+1, i, 90+(i%60)))
+		rawMeta := model.MediaMetadata{
+			Name:        fmt.Sprintf("Movie %d", i),
+			AltName:     fmt.Sprintf("Alt %d", i),
+			Year:        2020 + (i % 5),
+			Season:      (i % 3) + 1,
+			Episode:     (i % 12) + 1,
+			Description: fmt.Sprintf("A long description for movie %d with lots of text that would bulk up the payload", i),
+			Actors:      []string{"Actor A", "Actor B", "Actor C", "Actor D"},
+			DurationMin: 90 + (i % 60),
+		}
 		items = append(items, model.Item{
 			Name:     fmt.Sprintf("Movie_%d.mp4", i),
 			MIMEType: "video/mp4",
@@ -1451,11 +1548,7 @@ func oldFormatItems(items []model.Item) string {
 			"type":  it.MIMEType,
 		}
 		if it.Metadata != nil {
-			var metadata map[string]any
-			err := json.Unmarshal(*it.Metadata, &metadata)
-			if err == nil {
-				item["metadata"] = metadata
-			}
+			item["metadata"] = it.Metadata
 		}
 		result = append(result, item)
 	}
@@ -1464,7 +1557,7 @@ func oldFormatItems(items []model.Item) string {
 }
 
 func TestProjectItems_IndexAlignment(t *testing.T) {
-	rawMeta := json.RawMessage(`{"name": "Item 0"}`)
+	rawMeta := model.MediaMetadata{Name: "Item 0"}
 	items := []model.Item{
 		{Name: "file0.mp4", Metadata: &rawMeta},
 		{Name: "file1.mp4"},
@@ -1508,7 +1601,10 @@ func TestProjectItems_NilMetadata(t *testing.T) {
 }
 
 func TestProjectItems_MalformedMetadata(t *testing.T) {
-	badMeta := json.RawMessage(`not valid json`)
+	var badMeta model.MediaMetadata
+	if err := json.Unmarshal([]byte(`"not an object"`), &badMeta); err != nil {
+		t.Fatal(err)
+	}
 	items := []model.Item{
 		{Name: "video.mp4", Metadata: &badMeta},
 	}
@@ -1527,7 +1623,7 @@ func TestProjectItems_MalformedMetadata(t *testing.T) {
 }
 
 func TestFormatItems_Deterministic(t *testing.T) {
-	rawMeta := json.RawMessage(`{"name": "Test", "year": 2023}`)
+	rawMeta := model.MediaMetadata{Name: "Test", Year: 2023}
 	items := []model.Item{
 		{Name: "a.mp4", Metadata: &rawMeta},
 		{Name: "b.mp4", Metadata: &rawMeta},
@@ -1560,7 +1656,7 @@ func TestFormatItems_EmptyItems(t *testing.T) {
 }
 
 func TestProjectItems_AllZeroMetadata(t *testing.T) {
-	rawMeta := json.RawMessage(`{"name": "", "year": 0, "season": 0, "episode": 0}`)
+	rawMeta := model.MediaMetadata{}
 	items := []model.Item{
 		{Name: "video.mp4", Metadata: &rawMeta},
 	}
@@ -1576,7 +1672,7 @@ func TestProjectItems_AllZeroMetadata(t *testing.T) {
 }
 
 func TestProjectItems_TitleEqualsFilename(t *testing.T) {
-	rawMeta := json.RawMessage(`{"name": "SameAsFile"}`)
+	rawMeta := model.MediaMetadata{Name: "SameAsFile"}
 	items := []model.Item{
 		{Name: "SameAsFile", Metadata: &rawMeta},
 	}
@@ -1588,7 +1684,7 @@ func TestProjectItems_TitleEqualsFilename(t *testing.T) {
 	}
 
 	// But alt_name that differs should become the title
-	rawMetaWithAlt := json.RawMessage(`{"name": "SameAsFile", "alt_name": "Different"}`)
+	rawMetaWithAlt := model.MediaMetadata{Name: "SameAsFile", AltName: "Different"}
 	items[0].Metadata = &rawMetaWithAlt
 	views = ProjectItems(items)
 	b, _ = json.Marshal(views[0])
@@ -1599,7 +1695,7 @@ func TestProjectItems_TitleEqualsFilename(t *testing.T) {
 
 func TestProjectItems_MovieWithSeason(t *testing.T) {
 	// Classifier may tag movies with season/episode — pass through as-is.
-	rawMeta := json.RawMessage(`{"name": "Film", "season": 1, "episode": 0}`)
+	rawMeta := model.MediaMetadata{Name: "Film", Season: 1}
 	items := []model.Item{
 		{Name: "Film.mp4", Metadata: &rawMeta},
 	}
@@ -1681,7 +1777,7 @@ func TestProjectItems_StableOrdering(t *testing.T) {
 	// Build a fixture and shuffle it 100 times; the sorted projection
 	// (ignoring the Index field, which is input-order-dependent) must be
 	// identical every time.
-	rawMeta := json.RawMessage(`{"name":"Item"}`)
+	rawMeta := model.MediaMetadata{Name: "Item"}
 	items := []model.Item{
 		{Path: "/z.mp4", Name: "z.mp4", Metadata: &rawMeta},
 		{Path: "/a.mp4", Name: "a.mp4", Metadata: &rawMeta},
@@ -1715,7 +1811,7 @@ func TestProjectItems_StableOrdering(t *testing.T) {
 
 func TestPrepSuggestions_UsesCompactFormat(t *testing.T) {
 	ctx := context.Background()
-	rawMeta := json.RawMessage(`{"name":"Test Movie","year":2023,"description":"A long plot that should not appear"}`)
+	rawMeta := model.MediaMetadata{Name: "Test Movie", Year: 2023, Description: "A long plot that should not appear"}
 	items := []model.Item{
 		{Name: "test.mp4", Metadata: &rawMeta},
 	}
@@ -7,6 +7,7 @@ import (
 	"github.com/baalimago/clai/pkg/text"
 	"github.com/baalimago/clai/pkg/text/models"
 	"github.com/baalimago/kinoview/internal/agents"
+	"github.com/baalimago/kinoview/internal/lang"
 	"github.com/baalimago/kinoview/internal/model"
 )
 
@@ -14,20 +15,20 @@ type selector struct {
 	llm text.FullResponse
 }
 
-const selectorSystemPrompt = `You are a media stream analyzer. Your task is to select the most appropriate English subtitle stream from a list of streams.
+const selectorSystemPrompt = `You are a media stream analyzer. Your task is to select the most appropriate subtitle stream from a list of streams, in one of the user's preferred languages.
 
 Priorities:
-1. Standard English subtitles (often tagged "eng", "en", "English").
-2. English SDH (Subtitles for the Deaf and Hard-of-hearing) if no standard English is available.
-3. Forced English subtitles (only if strictly necessary or no others exist, though these are usually for foreign parts).
+1. Standard subtitles in the most preferred language available. Languages may be tagged by ISO 639-1 or 639-2 code or by name (for example "sv", "swe", "Swedish").
+2. SDH (Subtitles for the Deaf and Hard-of-hearing) in that language if no standard subtitles are available.
+3. Forced subtitles (only if strictly necessary or no others exist, though these are usually for foreign parts).
 
 Avoid:
 - Commentary tracks.
-- Non-English languages.
+- Languages which are not preferred.
 
-Input: A list of streams with their metadata.
+Input: The preferred languages, followed by a list of streams with their metadata.
 Output: A JSON object containing the index of the best match. 
-If no suitable English subtitle is found, return "error" in the JSON.
+If no suitable subtitle is found, return "error" in the JSON.
 
 Format:
 {
@@ -35,7 +36,7 @@ Format:
 }
 OR
 {
-  "error": "no english subtitles found"
+  "error": "no subtitles in a preferred language found"
 }
 `
 
@@ -51,7 +52,9 @@ type selectorResponse struct {
 	Error *string `json:"error,omitempty"`
 }
 
-func (s *selector) Select(ctx context.Context, streams []model.Stream) (int, error) {
+func (s *selector) Select(ctx context.Context, streams []model.Stream, langs lang.Preferences) (int, error) {
+	langs = langs.Or(lang.Default)
+
 	// Filter for subtitle streams only
 	subs := filterSubtitleStreams(streams)
 
@@ -60,10 +63,10 @@ func (s *selector) Select(ctx context.Context, streams []model.Stream) (int, err
 	}
 
 	// Deterministic fast path: if we have at least one usable candidate, pick the best
-	if idx, ok := rankBest(subs); ok {
+	if idx, ok := rankBest(subs, langs); ok {
 		return idx, nil
 	}
 
-	// Fallback to LLM for ambiguous cases (non-English only, commentary only, etc.)
-	return s.selectViaLLM(ctx, subs)
+	// Fallback to LLM for ambiguous cases (no preferred language, commentary only, etc.)
+	return s.selectViaLLM(ctx, subs, langs)
 }
@@ -71,25 +71,6 @@ type semanticIdexerResponse struct {
 	Index int `json:"index"`
 }
 
-type itemMetadata struct {
-	Name    string `json:"name"`
-	AltName string `json:"alt_name"`
-	Year    int    `json:"year"`
-	Season  int    `json:"season"`
-	Episode int    `json:"episode"`
-}
-
-func unmarshalMetadata(data *json.RawMessage) (
-	itemMetadata, error,
-) {
-	var meta itemMetadata
-	if data == nil {
-		return meta, nil
-	}
-	err := json.Unmarshal(*data, &meta)
-	return meta, err
-}
-
 func extractJSONBytes(content string) []byte {
 	lastMsgStr := []byte(content)
 	open := bytes.IndexByte(lastMsgStr, '{')
@@ -134,17 +115,12 @@ func (b *butler) semanticIndexerSelect(ctx context.Context,
 			Index:    idx,
 			FileName: item.Name,
 		}
-		if item.Metadata != nil {
-			meta, err := unmarshalMetadata(
-				item.Metadata,
-			)
-			if err == nil {
-				formatted.Name = meta.Name
-				formatted.AltName = meta.AltName
-				formatted.Year = meta.Year
-				formatted.Season = meta.Season
-				formatted.Episode = meta.Episode
-			}
+		if meta := item.Metadata; meta != nil {
+			formatted.Name = meta.Name
+			formatted.AltName = meta.AltName
+			formatted.Year = meta.Year
+			formatted.Season = meta.Season
+			formatted.Episode = meta.Episode
 		}
 		formattedItems = append(formattedItems,
 			formatted)
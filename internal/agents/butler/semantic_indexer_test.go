@@ -217,10 +217,13 @@ func TestSemanticIndexerSelect_JSONExtraction(t *testing.T) {
 
 func TestSemanticIndexerSelect_MetadataFormatting(t *testing.T) {
 	ctx := context.Background()
-	rawMeta := json.RawMessage(
-		`{"name":"Show Name","alt_name":"Alt Name",
-		"year":2024,"season":2,"episode":6}`,
-	)
+	rawMeta := model.MediaMetadata{
+		Name:    "Show Name",
+		AltName: "Alt Name",
+		Year:    2024,
+		Season:  2,
+		Episode: 6,
+	}
 
 	items := []model.Item{
 		{Name: "file1.mkv", Metadata: &rawMeta},
@@ -266,7 +269,10 @@ func TestSemanticIndexerSelect_MetadataFormatting(t *testing.T) {
 
 func TestSemanticIndexerSelect_InvalidMetadata(t *testing.T) {
 	ctx := context.Background()
-	badMeta := json.RawMessage(`{"invalid": json}`)
+	var badMeta model.MediaMetadata
+	if err := json.Unmarshal([]byte(`["not", "an", "object"]`), &badMeta); err != nil {
+		t.Fatal(err)
+	}
 	items := []model.Item{
 		{Name: "file.mkv", Metadata: &badMeta},
 	}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/baalimago/kinoview/internal/lang"
 	"github.com/baalimago/kinoview/internal/model"
 )
 
@@ -22,7 +23,7 @@ func (e *PreloadSubsError) Unwrap() error {
 }
 
 func (b *butler) preloadSubs(ctx context.Context,
-	item model.Item, rec *model.Suggestion,
+	item model.Item, rec *model.Suggestion, langs lang.Preferences,
 ) error {
 	info, err := b.subs.Find(item)
 	if err != nil {
@@ -34,12 +35,12 @@ func (b *butler) preloadSubs(ctx context.Context,
 	var selectedIdx string
 
 	if b.selector != nil {
-		idx, selEnglishErr := b.selector.Select(ctx, info.Streams)
-		if selEnglishErr != nil {
+		idx, selErr := b.selector.Select(ctx, info.Streams, langs)
+		if selErr != nil {
 			return &PreloadSubsError{
 				ItemName: item.Name,
 				Err: fmt.Errorf(
-					"failed to select english: %w", selEnglishErr,
+					"failed to select subtitles in %v: %w", langs, selErr,
 				),
 			}
 		}
@@ -56,7 +57,7 @@ func (b *butler) preloadSubs(ctx context.Context,
 }
 
 func (b *butler) prepSuggestion(ctx context.Context,
-	sug suggestionResponse, items []model.Item) (
+	sug suggestionResponse, items []model.Item, langs lang.Preferences) (
 	model.Suggestion, error,
 ) {
 	item, err := b.resolveItem(ctx, sug, items)
@@ -72,7 +73,7 @@ func (b *butler) prepSuggestion(ctx context.Context,
 	if b.subs == nil {
 		return rec, nil
 	}
-	err = b.preloadSubs(ctx, item, &rec)
+	err = b.preloadSubs(ctx, item, &rec, langs)
 	if err != nil {
 		return rec, fmt.Errorf("failed to preloadSubs: %w", err)
 	}
@@ -11,6 +11,7 @@ import (
 	"github.com/baalimago/go_away_boilerplate/pkg/ancli"
 	"github.com/baalimago/go_away_boilerplate/pkg/debug"
 	"github.com/baalimago/go_away_boilerplate/pkg/misc"
+	"github.com/baalimago/kinoview/internal/lang"
 	"github.com/baalimago/kinoview/internal/model"
 )
 
@@ -34,29 +35,17 @@ var textCodecs = map[string]bool{
 	"mov_text": true,
 }
 
-// isEnglish returns true if the language tag represents English.
-func isEnglish(lang string) bool {
-	lower := strings.ToLower(lang)
-	switch lower {
-	case "eng", "en", "english":
-		return true
-	}
-	// Handle regional tags like "eng-US", "en-GB"
-	if strings.HasPrefix(lower, "eng-") || strings.HasPrefix(lower, "en-") {
-		return true
-	}
-	return false
-}
-
-// rankSubtitle scores a subtitle stream; higher is better, negative means unusable.
-func rankSubtitle(st model.Stream) int {
-	lang := strings.ToLower(st.Tags.Language)
+// rankSubtitle scores a subtitle stream for a viewer preferring langs; higher
+// is better, negative means unusable.
+func rankSubtitle(st model.Stream, langs lang.Preferences) int {
+	tag := lang.Normalize(st.Tags.Language)
 	title := strings.ToLower(st.Tags.Title)
 
 	// --- Unusable checks (return negative immediately) ---
 
-	// Non-English language is unusable (but "und" = undefined/unknown counts as empty)
-	if lang != "" && lang != "und" && !isEnglish(lang) {
+	// Languages not preferred are unusable (but undetermined tags count as empty)
+	rank := langs.Rank(tag)
+	if tag != "" && rank < 0 {
 		return -1
 	}
 
@@ -72,11 +61,12 @@ func rankSubtitle(st model.Stream) int {
 
 	score := 0
 
-	// Language signal
-	if lang == "" || lang == "und" {
+	// Language signal: the last preferred language scores as English used
+	// to, and each step up the preference list adds as much again.
+	if tag == "" {
 		score += 10 // unknown/untagged is weakly acceptable
 	} else {
-		score += 100 // confirmed English
+		score += 100 * (len(langs) - rank)
 	}
 
 	// Sign/song/lyric/karaoke tracks are not dialogue
@@ -102,10 +92,14 @@ func rankSubtitle(st model.Stream) int {
 
 	// Codec type
 	codec := strings.ToLower(st.CodecName)
-	if textCodecs[codec] {
+	if textCodecs[codec] || st.OCR {
 		score += 15
 	}
-	if codec == "hdmv_pgs_subtitle" || codec == "dvd_subtitle" {
+	switch {
+	case st.OCR:
+		// Bitmap subtitles read by OCR, usable but with the odd misread
+		score -= 20
+	case codec == "hdmv_pgs_subtitle" || codec == "dvd_subtitle" || codec == "dvb_subtitle":
 		score -= 50
 	}
 
@@ -125,12 +119,12 @@ func matchAny(s string, substrs ...string) bool {
 // rankBest returns the index of the highest-scoring usable subtitle stream.
 // The second return value is false when every candidate scored negative (unusable).
 // Ties are broken on lowest Index.
-func rankBest(subs []model.Stream) (int, bool) {
+func rankBest(subs []model.Stream, langs lang.Preferences) (int, bool) {
 	bestIdx := -1
 	bestScore := -1
 
 	for _, st := range subs {
-		score := rankSubtitle(st)
+		score := rankSubtitle(st, langs)
 		if score < 0 {
 			continue
 		}
@@ -145,8 +139,9 @@ func rankBest(subs []model.Stream) (int, bool) {
 
 // selectViaLLM is the LLM-based fallback for subtitle selection. It sends the
 // subtitle streams to the LLM and parses the JSON response.
-func (s *selector) selectViaLLM(ctx context.Context, subs []model.Stream) (int, error) {
+func (s *selector) selectViaLLM(ctx context.Context, subs []model.Stream, langs lang.Preferences) (int, error) {
 	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Preferred languages, most preferred first: %s\n\n", strings.Join(langs, ", ")))
 	for _, st := range subs {
 		title := st.Tags.Title
 		lang := st.Tags.Language
@@ -0,0 +1,156 @@
+package classifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+
+	"github.com/baalimago/clai/pkg/text/models"
+	"github.com/baalimago/go_away_boilerplate/pkg/ancli"
+	"github.com/baalimago/kinoview/internal/agents"
+	"github.com/baalimago/kinoview/internal/media/constants"
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+const batchSystemPrompt = `You are a media classifier. Your job is to fill in the metadata for several pieces of media which belong together, such as the episodes of a season.
+You may need to use tools to find information about the media, do so at will.
+
+The metadata of each piece of media follows the format below. The parentheses describe the fields to you, the media classifier:
+%s
+
+Fields which are the same for every piece of media, such as the series name, actors or year, go once in "series". The rest goes in "items", under the ID of the piece of media it is for.
+
+Also add a "confidence" object to "series" and to each item, holding how sure you are of each field you filled in, from 0 (a guess) to 1 (certain). For instance: "confidence": {"name": 0.9, "year": 0.4}
+
+OUTPUT ONLY IN THE FOLLOWING FORMAT:
+{
+	"series": { <FIELDS SHARED BY ALL>, "confidence": { ... } },
+	"items": {
+		"<ID>": { <FIELDS OF THIS PIECE OF MEDIA>, "confidence": { ... } },
+		...
+	}
+}`
+
+const batchUserPrompt = `Information about the media to classify, one per line:
+%s`
+
+const batchKnownPrompt = ` — already known from the file name and sidecar files, keep as they are: %s`
+
+var _ agents.BatchClassifier = (*classifier)(nil)
+
+// batchResponse is what the classifier is asked to answer a batch with.
+type batchResponse struct {
+	Series map[string]json.RawMessage            `json:"series"`
+	Items  map[string]map[string]json.RawMessage `json:"items"`
+}
+
+// ClassifyBatch of items in one LLM call, the fields they share being
+// classified once. Items the answer leaves out, or gives no valid metadata
+// for, are left out of the result.
+func (c *classifier) ClassifyBatch(ctx context.Context, items []model.Item) ([]model.Item, error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+	t0 := time.Now()
+	respChat, err := c.llm.Query(ctx, buildBatchChat(items, t0))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query llm: %v", err)
+	}
+	lastMsg, err := extractLastMessage(respChat)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateBraces(lastMsg.Content); err != nil {
+		return nil, err
+	}
+	var resp batchResponse
+	if err := json.Unmarshal(extractJSONBytes(lastMsg.Content), &resp); err != nil {
+		return nil, fmt.Errorf("lastMsg is not a batch of metadata: %w", err)
+	}
+
+	seriesConfidence := model.ParseConfidence(resp.Series["confidence"])
+	ret := make([]model.Item, 0, len(items))
+	for _, i := range items {
+		fields, ok := resp.Items[i.ID]
+		if !ok {
+			continue
+		}
+		md, confidence, err := mergeBatchFields(resp.Series, fields)
+		if err != nil {
+			ancli.Warnf("batch classification of %v: %v", i.Name, err)
+			continue
+		}
+		i.Metadata = md
+		i.Confidence = mergeConfidence(seriesConfidence, confidence)
+		i.ClassificationModel = c.model
+		ret = append(ret, i)
+	}
+	return ret, nil
+}
+
+// mergeBatchFields overlays the fields of an item on those of the series,
+// returning the metadata and the confidence of the item.
+func mergeBatchFields(series, item map[string]json.RawMessage) (*model.MediaMetadata, model.Confidence, error) {
+	fields := maps.Clone(series)
+	if fields == nil {
+		fields = map[string]json.RawMessage{}
+	}
+	delete(fields, "confidence")
+	maps.Copy(fields, item)
+	delete(fields, "confidence")
+	b, err := json.Marshal(fields)
+	if err != nil {
+		return nil, nil, err
+	}
+	md, err := model.ParseMetadata(b)
+	if err != nil {
+		return nil, nil, fmt.Errorf("not valid metadata: %w", err)
+	}
+	if md.IsEmpty() {
+		return nil, nil, fmt.Errorf("no metadata")
+	}
+	return md, model.ParseConfidence(item["confidence"]), nil
+}
+
+// mergeConfidence of the series and an item, the item's winning.
+func mergeConfidence(series, item model.Confidence) model.Confidence {
+	if series == nil && item == nil {
+		return nil
+	}
+	ret := maps.Clone(series)
+	if ret == nil {
+		ret = model.Confidence{}
+	}
+	maps.Copy(ret, item)
+	return ret
+}
+
+func buildBatchChat(items []model.Item, t0 time.Time) models.Chat {
+	var lines strings.Builder
+	for _, i := range items {
+		fmt.Fprintf(&lines, "- ID %v: %v", i.ID, i)
+		if i.Metadata != nil {
+			if known, err := json.Marshal(i.Metadata); err == nil {
+				fmt.Fprintf(&lines, batchKnownPrompt, known)
+			}
+		}
+		lines.WriteString("\n")
+	}
+	return models.Chat{
+		Created: t0,
+		ID:      fmt.Sprintf("classify_batch_%v_%v", items[0].ID, t0.Format("25-01-01T00:00Z00")),
+		Messages: []models.Message{
+			{
+				Role:    "system",
+				Content: fmt.Sprintf(batchSystemPrompt, constants.MetadataFormat),
+			},
+			{
+				Role:    "user",
+				Content: fmt.Sprintf(batchUserPrompt, lines.String()),
+			},
+		},
+	}
+}
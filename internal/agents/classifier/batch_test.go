@@ -0,0 +1,88 @@
+package classifier
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/baalimago/clai/pkg/text/models"
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+func TestClassifyBatch(t *testing.T) {
+	var gotUser string
+	mockLLM := &mockLLM{
+		queryFunc: func(ctx context.Context, c models.Chat) (models.Chat, error) {
+			gotUser = c.Messages[1].Content
+			return models.Chat{
+				Messages: []models.Message{
+					{Role: "assistant", Content: `Here you go: {
+						"series": {"showName": "Show", "year": 2001, "actors": ["A"], "confidence": {"showName": 1, "year": 0.5}},
+						"items": {
+							"e1": {"name": "Pilot", "season": 1, "episode": 1, "confidence": {"name": 0.9, "year": 0.8}},
+							"e2": {"name": "Second", "season": 1, "episode": 2, "year": 2002},
+							"e3": {"season": "first"},
+							"invented": {"name": "Not asked for"}
+						}
+					}`},
+				},
+			}, nil
+		},
+	}
+	c := &classifier{llm: mockLLM}
+
+	known := model.MediaMetadata{ShowName: "Show", Season: 1, Episode: 1}
+	items := []model.Item{
+		{ID: "e1", Name: "e1.mkv", MIMEType: "video/mp4", Metadata: &known},
+		{ID: "e2", Name: "e2.mkv", MIMEType: "video/mp4"},
+		{ID: "e3", Name: "e3.mkv", MIMEType: "video/mp4"},
+		{ID: "e4", Name: "e4.mkv", MIMEType: "video/mp4"},
+	}
+	got, err := c.ClassifyBatch(context.Background(), items)
+	if err != nil {
+		t.Fatalf("didnt expect error: %v", err)
+	}
+
+	for _, want := range []string{"- ID e1:", "- ID e4:", `keep as they are: {"episode":1,"season":1,"showName":"Show"}`} {
+		if !strings.Contains(gotUser, want) {
+			t.Errorf("expected %q in the user prompt, got: %v", want, gotUser)
+		}
+	}
+
+	// e3 has invalid metadata and e4 was left out, to be classified one by one
+	if len(got) != 2 || got[0].ID != "e1" || got[1].ID != "e2" {
+		t.Fatalf("expected e1 and e2, got %+v", got)
+	}
+	e1, e2 := got[0], got[1]
+	if e1.Metadata.ShowName != "Show" || e1.Metadata.Name != "Pilot" || e1.Metadata.Year != 2001 || len(e1.Metadata.Actors) != 1 {
+		t.Errorf("expected e1 to have the series fields, got %+v", e1.Metadata)
+	}
+	if e2.Metadata.Year != 2002 || e2.Metadata.Episode != 2 {
+		t.Errorf("expected the fields of e2 to win over the series', got %+v", e2.Metadata)
+	}
+	if e1.Confidence["showName"] != 1 || e1.Confidence["year"] != 0.8 || e1.Confidence["name"] != 0.9 {
+		t.Errorf("expected the confidence of the series and e1 merged, got %v", e1.Confidence)
+	}
+	if e1.Metadata.Extra != nil {
+		t.Errorf("expected the confidence to be taken out of the metadata, got extra: %v", e1.Metadata.Extra)
+	}
+}
+
+func TestClassifyBatch_invalid(t *testing.T) {
+	for _, content := range []string{
+		`no json here`,
+		`{"items": ["e1"]}`,
+	} {
+		mockLLM := &mockLLM{
+			queryFunc: func(ctx context.Context, c models.Chat) (models.Chat, error) {
+				return models.Chat{
+					Messages: []models.Message{{Role: "assistant", Content: content}},
+				}, nil
+			},
+		}
+		c := &classifier{llm: mockLLM}
+		if _, err := c.ClassifyBatch(context.Background(), []model.Item{{ID: "e1"}, {ID: "e2"}}); err == nil {
+			t.Errorf("expected error classifying as %v", content)
+		}
+	}
+}
@@ -25,11 +25,16 @@ The following format will have parenthases. These are to describe the fields to
 
 Some of the fields may be omitted if they aren't relevant for the media. "season" is for instance not relevant for a movie. 
 
+Also add a "confidence" object to the output, holding how sure you are of each field you filled in, from 0 (a guess) to 1 (certain). For instance: "confidence": {"name": 0.9, "year": 0.4}
+
 OUTPUT ONLY IN THE FOLLOWING FORMAT:
 %s`
 
 const userPrompt = `Information about the media to classify: %v`
 
+const knownPrompt = `
+These fields are already known from the file name and sidecar files, keep them as they are and fill in the rest: %s`
+
 type classifier struct {
 	model     string
 	configDir string
@@ -84,6 +89,11 @@ func (c *classifier) buildAgent(tools []models.LLMTool, internalTools []models.T
 	c.llm = &a
 }
 
+// Model the classifier runs on.
+func (c *classifier) Model() string {
+	return c.model
+}
+
 func (c *classifier) Clone() agents.Classifier {
 	clone := &classifier{
 		model:     c.model,
@@ -155,31 +165,65 @@ func (c *classifier) Classify(ctx context.Context, i model.Item) (model.Item, er
 		return model.Item{}, err
 	}
 	lastMsgStr := extractJSONBytes(lastMsg.Content)
-	var js json.RawMessage
-	if err := json.Unmarshal(lastMsgStr, &js); err != nil {
-		return model.Item{}, fmt.Errorf("lastMsg is not valid json: %w", err)
+	md, err := model.ParseMetadata(lastMsgStr)
+	if err != nil {
+		return model.Item{}, fmt.Errorf("lastMsg is not valid metadata: %w", err)
+	}
+	i.Confidence = takeConfidence(md)
+	if md.IsEmpty() {
+		return model.Item{}, errors.New("lastMsg holds no metadata besides the confidence")
 	}
-	i.Metadata = &js
+	i.Metadata = md
+	i.ClassificationModel = c.model
 	return i, nil
 }
 
+// takeConfidence removes the confidence the classifier was asked for from
+// the metadata and returns it.
+func takeConfidence(md *model.MediaMetadata) model.Confidence {
+	raw, ok := md.Extra["confidence"]
+	if !ok {
+		return nil
+	}
+	delete(md.Extra, "confidence")
+	if len(md.Extra) == 0 {
+		md.Extra = nil
+	}
+	return model.ParseConfidence(raw)
+}
+
 func buildChat(i model.Item, t0 time.Time) models.Chat {
+	user := fmt.Sprintf(userPrompt, i)
+	if i.Metadata != nil {
+		if known, err := json.Marshal(i.Metadata); err == nil {
+			user += fmt.Sprintf(knownPrompt, known)
+		}
+	}
 	return models.Chat{
 		Created: t0,
 		ID:      fmt.Sprintf("classify_%v_%v", i.ID, t0.Format("25-01-01T00:00Z00")),
 		Messages: []models.Message{
 			{
 				Role:    "system",
-				Content: fmt.Sprintf(systemPrompt, constants.MetadataFormat),
+				Content: fmt.Sprintf(systemPrompt, metadataFormat(i)),
 			},
 			{
 				Role:    "user",
-				Content: fmt.Sprintf(userPrompt, i),
+				Content: user,
 			},
 		},
 	}
 }
 
+// metadataFormat returns the format to classify the item by. Music has
+// albums and tracks instead of seasons and episodes.
+func metadataFormat(i model.Item) string {
+	if strings.HasPrefix(i.MIMEType, "audio") {
+		return constants.MusicMetadataFormat
+	}
+	return constants.MetadataFormat
+}
+
 func extractLastMessage(respChat models.Chat) (models.Message, error) {
 	// clai >= v1.10.16 appends the model reply with role "assistant"; older
 	// versions used "system". Grabbing "system" now returns the prompt itself,
@@ -3,12 +3,15 @@ package classifier
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"strings"
 	"testing"
 
 	"github.com/baalimago/clai/pkg/text/models"
 	"github.com/baalimago/go_away_boilerplate/pkg/testboil"
 	"github.com/baalimago/kinoview/internal/agents"
+	"github.com/baalimago/kinoview/internal/media/constants"
 	"github.com/baalimago/kinoview/internal/model"
 )
 
@@ -198,8 +201,11 @@ func TestClassify(t *testing.T) {
 		testboil.FailTestIfDiff(t, input.Path, result.Path)
 		testboil.FailTestIfDiff(t, input.Name, result.Name)
 
-		metadata := result.Metadata
-		testboil.FailTestIfDiff(t, expectedJSON, string(*metadata))
+		metadata, err := json.Marshal(result.Metadata)
+		if err != nil {
+			t.Fatalf("didnt expect error: %v", err)
+		}
+		testboil.FailTestIfDiff(t, `{"actors":["Actor One"],"name":"Test Movie","year":2023}`, string(metadata))
 	})
 
 	t.Run("LLM query error", func(t *testing.T) {
@@ -319,8 +325,120 @@ func TestClassify(t *testing.T) {
 			t.Fatalf("expected no error, got: %v", err)
 		}
 
-		metadata := result.Metadata
+		metadata, err := json.Marshal(result.Metadata)
+		if err != nil {
+			t.Fatalf("didnt expect error: %v", err)
+		}
 		expectedJSON := `{"name":"Complex Movie","year":2024}`
-		testboil.FailTestIfDiff(t, expectedJSON, string(*metadata))
+		testboil.FailTestIfDiff(t, expectedJSON, string(metadata))
 	})
 }
+
+func TestClassify_musicFormat(t *testing.T) {
+	var gotSystem string
+	mockLLM := &mockLLM{
+		queryFunc: func(ctx context.Context, c models.Chat) (models.Chat, error) {
+			gotSystem = c.Messages[0].Content
+			return models.Chat{
+				Messages: []models.Message{
+					{Role: "assistant", Content: `{"name":"Song","artist":"Band"}`},
+				},
+			}, nil
+		},
+	}
+	c := &classifier{llm: mockLLM}
+
+	_, err := c.Classify(context.Background(), model.Item{ID: "a", MIMEType: "audio/mpeg"})
+	if err != nil {
+		t.Fatalf("didnt expect error: %v", err)
+	}
+	if !strings.Contains(gotSystem, constants.MusicMetadataFormat) {
+		t.Fatalf("expected music format in system prompt, got: %v", gotSystem)
+	}
+
+	_, err = c.Classify(context.Background(), model.Item{ID: "v", MIMEType: "video/mp4"})
+	if err != nil {
+		t.Fatalf("didnt expect error: %v", err)
+	}
+	if !strings.Contains(gotSystem, constants.MetadataFormat) {
+		t.Fatalf("expected video format in system prompt, got: %v", gotSystem)
+	}
+}
+
+func TestClassify_knownMetadata(t *testing.T) {
+	var gotUser string
+	mockLLM := &mockLLM{
+		queryFunc: func(ctx context.Context, c models.Chat) (models.Chat, error) {
+			gotUser = c.Messages[1].Content
+			return models.Chat{
+				Messages: []models.Message{
+					{Role: "assistant", Content: `{"name":"Pilot","showName":"Show","season":1,"episode":1}`},
+				},
+			}, nil
+		},
+	}
+	c := &classifier{llm: mockLLM}
+
+	known := model.MediaMetadata{ShowName: "Show", Season: 1, Episode: 1}
+	_, err := c.Classify(context.Background(), model.Item{ID: "v", MIMEType: "video/mp4", Metadata: &known})
+	if err != nil {
+		t.Fatalf("didnt expect error: %v", err)
+	}
+	if !strings.Contains(gotUser, `{"episode":1,"season":1,"showName":"Show"}`) {
+		t.Fatalf("expected known metadata in user prompt, got: %v", gotUser)
+	}
+
+	_, err = c.Classify(context.Background(), model.Item{ID: "v", MIMEType: "video/mp4"})
+	if err != nil {
+		t.Fatalf("didnt expect error: %v", err)
+	}
+	if strings.Contains(gotUser, "already known") {
+		t.Fatalf("expected no known metadata in user prompt, got: %v", gotUser)
+	}
+}
+
+func TestClassify_invalidMetadata(t *testing.T) {
+	for _, content := range []string{
+		`{"name":"Movie","year":"sometime"}`,
+		`{"name":"Movie","season":-1}`,
+		`{}`,
+		`{"confidence":{"name":1}}`,
+	} {
+		mockLLM := &mockLLM{
+			queryFunc: func(ctx context.Context, c models.Chat) (models.Chat, error) {
+				return models.Chat{
+					Messages: []models.Message{{Role: "assistant", Content: content}},
+				}, nil
+			},
+		}
+		c := &classifier{llm: mockLLM}
+		if _, err := c.Classify(context.Background(), model.Item{ID: "v", MIMEType: "video/mp4"}); err == nil {
+			t.Errorf("expected error classifying as %v", content)
+		}
+	}
+}
+
+func TestClassify_confidence(t *testing.T) {
+	mockLLM := &mockLLM{
+		queryFunc: func(ctx context.Context, c models.Chat) (models.Chat, error) {
+			return models.Chat{
+				Messages: []models.Message{
+					{Role: "assistant", Content: `{"name":"Heat","year":1995,"confidence":{"name":0.9,"year":"40%","actors":"unsure"}}`},
+				},
+			}, nil
+		},
+	}
+	c := &classifier{llm: mockLLM}
+
+	got, err := c.Classify(context.Background(), model.Item{ID: "v", MIMEType: "video/mp4"})
+	if err != nil {
+		t.Fatalf("didnt expect error: %v", err)
+	}
+	want := model.Confidence{"name": 0.9, "year": 0.4}
+	if len(got.Confidence) != len(want) || got.Confidence["name"] != want["name"] || got.Confidence["year"] != want["year"] {
+		t.Errorf("confidence = %v, want %v", got.Confidence, want)
+	}
+	if got.Metadata.Extra != nil {
+		t.Errorf("expected the confidence to be taken out of the metadata, got extra: %v", got.Metadata.Extra)
+	}
+}
@@ -0,0 +1,414 @@
+package classifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/baalimago/go_away_boilerplate/pkg/ancli"
+	"github.com/baalimago/kinoview/internal/agents"
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+// DefaultEscalationThreshold is the confidence below which a classification
+// is handed on to the next model of its route.
+const DefaultEscalationThreshold = 0.5
+
+// Route of the items whose MIME type starts with MIMEPrefix, such as
+// "image" or "video", to a chain of classifiers. Each item is classified by
+// the first, and handed on to the next whenever one fails or isn't
+// confident enough. An empty MIMEPrefix routes every item.
+type Route struct {
+	MIMEPrefix string
+	Chain      []agents.Classifier
+}
+
+// RouteSpec is a route by the names of the models of its chain.
+type RouteSpec struct {
+	MIMEPrefix string
+	Models     []string
+}
+
+// ParseRoutes of routes separated by ";", each an optional MIME type prefix
+// and "=", then its models separated by ",", cheapest first. For instance
+// "gpt-4.1-mini,gpt-5;image=gpt-4o-mini" classifies images with gpt-4o-mini
+// and everything else with gpt-4.1-mini, escalating to gpt-5.
+func ParseRoutes(spec string) ([]RouteSpec, error) {
+	var ret []RouteSpec
+	for r := range strings.SplitSeq(spec, ";") {
+		if strings.TrimSpace(r) == "" {
+			continue
+		}
+		var rs RouteSpec
+		prefix, chain, ok := strings.Cut(r, "=")
+		if ok {
+			rs.MIMEPrefix = strings.ToLower(strings.TrimSpace(prefix))
+			if rs.MIMEPrefix == "" {
+				return nil, fmt.Errorf("route %q: empty mime type", r)
+			}
+		} else {
+			chain = prefix
+		}
+		for m := range strings.SplitSeq(chain, ",") {
+			if m = strings.TrimSpace(m); m != "" {
+				rs.Models = append(rs.Models, m)
+			}
+		}
+		if len(rs.Models) == 0 {
+			return nil, fmt.Errorf("route %q: no models", r)
+		}
+		if slices.ContainsFunc(ret, func(o RouteSpec) bool { return o.MIMEPrefix == rs.MIMEPrefix }) {
+			return nil, fmt.Errorf("route %q: mime type routed twice", r)
+		}
+		ret = append(ret, rs)
+	}
+	if len(ret) == 0 {
+		return nil, errors.New("no routes")
+	}
+	return ret, nil
+}
+
+// outcomeLog appends the outcomes of the classifications to a file, as
+// JSON lines. It's shared by the clones of a router.
+type outcomeLog struct {
+	mu   sync.Mutex
+	path string
+}
+
+func (l *outcomeLog) write(o model.ClassificationOutcome) {
+	if l == nil || l.path == "" {
+		return
+	}
+	b, err := json.Marshal(o)
+	if err != nil {
+		ancli.Errf("failed to marshal classification outcome: %v", err)
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		ancli.Errf("failed to open classification outcomes: %v", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		ancli.Errf("failed to write classification outcome: %v", err)
+	}
+}
+
+type router struct {
+	routes    []Route
+	threshold float64
+	outcomes  *outcomeLog
+}
+
+type RouterOption func(*router)
+
+// WithEscalationThreshold sets the confidence below which a classification
+// is handed on to the next model. Defaults to DefaultEscalationThreshold,
+// 0 escalates on failure only.
+func WithEscalationThreshold(t float64) RouterOption {
+	return func(r *router) {
+		r.threshold = t
+	}
+}
+
+// WithOutcomeLog sets the file every attempt of every model is appended to,
+// see model.ClassificationOutcome. Without it, outcomes aren't kept.
+func WithOutcomeLog(p string) RouterOption {
+	return func(r *router) {
+		r.outcomes = &outcomeLog{path: p}
+	}
+}
+
+var (
+	_ agents.BatchClassifier = (*router)(nil)
+	_ agents.TagClassifier   = (*router)(nil)
+	_ agents.OutputSetter    = (*router)(nil)
+)
+
+// NewRouter of items to the classifiers of routes, by their MIME type. The
+// longest matching prefix wins.
+func NewRouter(routes []Route, opts ...RouterOption) (agents.Classifier, error) {
+	for _, rt := range routes {
+		if len(rt.Chain) == 0 {
+			return nil, fmt.Errorf("route %q has no classifiers", rt.MIMEPrefix)
+		}
+	}
+	r := &router{
+		routes:    slices.Clone(routes),
+		threshold: DefaultEscalationThreshold,
+	}
+	slices.SortStableFunc(r.routes, func(a, b Route) int { return len(b.MIMEPrefix) - len(a.MIMEPrefix) })
+	for _, o := range opts {
+		o(r)
+	}
+	return r, nil
+}
+
+// NewRouted classifier of the routes of spec, see ParseRoutes, newClassifier
+// creating the classifier of each model.
+func NewRouted(spec string, newClassifier func(model string) agents.Classifier, opts ...RouterOption) (agents.Classifier, error) {
+	specs, err := ParseRoutes(spec)
+	if err != nil {
+		return nil, err
+	}
+	routes := make([]Route, 0, len(specs))
+	for _, rs := range specs {
+		rt := Route{MIMEPrefix: rs.MIMEPrefix}
+		for _, m := range rs.Models {
+			rt.Chain = append(rt.Chain, newClassifier(m))
+		}
+		routes = append(routes, rt)
+	}
+	return NewRouter(routes, opts...)
+}
+
+func (r *router) Setup(ctx context.Context) error {
+	for _, rt := range r.routes {
+		for _, c := range rt.Chain {
+			if err := c.Setup(ctx); err != nil {
+				return fmt.Errorf("failed to setup classifier %v: %w", modelOf(c), err)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *router) Clone() agents.Classifier {
+	clone := &router{
+		routes:    make([]Route, len(r.routes)),
+		threshold: r.threshold,
+		outcomes:  r.outcomes,
+	}
+	for idx, rt := range r.routes {
+		chain := make([]agents.Classifier, len(rt.Chain))
+		for j, c := range rt.Chain {
+			chain[j] = c.Clone()
+		}
+		clone.routes[idx] = Route{MIMEPrefix: rt.MIMEPrefix, Chain: chain}
+	}
+	return clone
+}
+
+// SetOutput of every classifier which has one.
+func (r *router) SetOutput(w io.Writer) error {
+	for _, rt := range r.routes {
+		for _, c := range rt.Chain {
+			setter, ok := c.(agents.OutputSetter)
+			if !ok {
+				continue
+			}
+			if err := setter.SetOutput(w); err != nil {
+				return fmt.Errorf("failed to set output of %v: %w", modelOf(c), err)
+			}
+		}
+	}
+	return nil
+}
+
+// route of the item, false if none matches it.
+func (r *router) route(i model.Item) (Route, bool) {
+	mime := strings.ToLower(i.MIMEType)
+	for _, rt := range r.routes {
+		if strings.HasPrefix(mime, rt.MIMEPrefix) {
+			return rt, true
+		}
+	}
+	return Route{}, false
+}
+
+// Classify the item with the first classifier of its route which succeeds
+// and is confident, or whichever the last one answers.
+func (r *router) Classify(ctx context.Context, i model.Item) (model.Item, error) {
+	return r.escalate(ctx, i, nil, func(c agents.Classifier) (model.Item, error) {
+		return c.Classify(ctx, i)
+	})
+}
+
+// ClassifyTags like Classify, with the classifiers of the route which can.
+func (r *router) ClassifyTags(ctx context.Context, i model.Item) (model.Item, error) {
+	return r.escalate(ctx, i, model.TagKeys, func(c agents.Classifier) (model.Item, error) {
+		tc, ok := c.(agents.TagClassifier)
+		if !ok {
+			return model.Item{}, errors.New("classifier can't classify tags alone")
+		}
+		return tc.ClassifyTags(ctx, i)
+	})
+}
+
+// unsure classification, kept in case the models it's escalated to fail.
+type unsure struct {
+	itemID     string
+	item       model.Item
+	confidence float64
+	by         agents.Classifier
+}
+
+// keepBest of u and the unsure classification of the same item in best.
+func keepBest(best map[string]unsure, u unsure) {
+	if b, ok := best[u.itemID]; !ok || u.confidence > b.confidence {
+		best[u.itemID] = u
+	}
+}
+
+// accept the unsure classification, the models after it having failed.
+func (r *router) accept(u unsure) model.Item {
+	r.record(u.by, u.itemID, model.ResultAccepted, u.confidence, nil)
+	ancli.Noticef("escalating %v failed, keeping the classification of %v (%.2f)", u.item.Name, modelOf(u.by), u.confidence)
+	return u.item
+}
+
+// escalate classify along the route of i. Only the confidence in keys is
+// weighed, all of it if keys is nil. If the models escalated to fail, the
+// most confident of the unsure classifications is accepted.
+func (r *router) escalate(ctx context.Context, i model.Item, keys []string, classify func(agents.Classifier) (model.Item, error)) (model.Item, error) {
+	rt, ok := r.route(i)
+	if !ok {
+		return model.Item{}, fmt.Errorf("no classifier is routed %v", i.MIMEType)
+	}
+	var errs []error
+	best := make(map[string]unsure, 1)
+	for idx, c := range rt.Chain {
+		last := idx == len(rt.Chain)-1
+		classified, err := classify(c)
+		if err != nil {
+			r.record(c, i.ID, model.ResultFailed, 0, err)
+			errs = append(errs, fmt.Errorf("%v: %w", modelOf(c), err))
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		confidence, known := lowestConfidence(classified.Confidence, keys)
+		if !last && known && confidence < r.threshold {
+			r.record(c, i.ID, model.ResultUnsure, confidence, nil)
+			ancli.Noticef("%v is unsure of %v (%.2f), escalating", modelOf(c), i.Name, confidence)
+			keepBest(best, unsure{itemID: i.ID, item: classified, confidence: confidence, by: c})
+			continue
+		}
+		r.record(c, i.ID, model.ResultAccepted, confidence, nil)
+		return classified, nil
+	}
+	if u, ok := best[i.ID]; ok {
+		return r.accept(u), nil
+	}
+	return model.Item{}, errors.Join(errs...)
+}
+
+// ClassifyBatch with the classifiers of the route of the items, those the
+// first isn't confident in, or fails, handed on to the next, and so on.
+// The items must share a route. Items left out of a batch are left out of
+// the result, to be classified one by one. If the batch fails past the
+// first model, the items it was escalated for keep their most confident
+// unsure classification.
+func (r *router) ClassifyBatch(ctx context.Context, items []model.Item) ([]model.Item, error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+	rt, ok := r.route(items[0])
+	if !ok {
+		return nil, fmt.Errorf("no classifier is routed %v", items[0].MIMEType)
+	}
+	var ret []model.Item
+	var failed error
+	best := make(map[string]unsure)
+	ask := items
+	for idx, c := range rt.Chain {
+		bc, ok := c.(agents.BatchClassifier)
+		if !ok || len(ask) == 0 {
+			break
+		}
+		last := idx == len(rt.Chain)-1
+		classified, err := bc.ClassifyBatch(ctx, ask)
+		if err != nil {
+			for _, i := range ask {
+				r.record(c, i.ID, model.ResultFailed, 0, err)
+			}
+			if last || ctx.Err() != nil {
+				failed = err
+				break
+			}
+			continue
+		}
+		answered := make(map[string]model.Item, len(classified))
+		for _, i := range classified {
+			answered[i.ID] = i
+		}
+		var escalated []model.Item
+		for _, i := range ask {
+			classified, ok := answered[i.ID]
+			if !ok {
+				r.record(c, i.ID, model.ResultFailed, 0, errors.New("left out of the batch"))
+				continue
+			}
+			confidence, known := lowestConfidence(classified.Confidence, nil)
+			if !last && known && confidence < r.threshold {
+				r.record(c, i.ID, model.ResultUnsure, confidence, nil)
+				keepBest(best, unsure{itemID: i.ID, item: classified, confidence: confidence, by: c})
+				escalated = append(escalated, i)
+				continue
+			}
+			r.record(c, i.ID, model.ResultAccepted, confidence, nil)
+			ret = append(ret, classified)
+		}
+		ask = escalated
+	}
+	if failed != nil {
+		for _, i := range ask {
+			if u, ok := best[i.ID]; ok {
+				ret = append(ret, r.accept(u))
+			}
+		}
+		if len(ret) == 0 {
+			return nil, failed
+		}
+	}
+	return ret, nil
+}
+
+func (r *router) record(c agents.Classifier, itemID string, result model.ClassificationResult, confidence float64, err error) {
+	o := model.ClassificationOutcome{
+		Time:       time.Now(),
+		Model:      modelOf(c),
+		ItemID:     itemID,
+		Result:     result,
+		Confidence: confidence,
+	}
+	if err != nil {
+		o.Error = err.Error()
+	}
+	r.outcomes.write(o)
+}
+
+// lowestConfidence in the fields of keys, all of them if keys is nil. 0 and
+// false if there is none.
+func lowestConfidence(c model.Confidence, keys []string) (float64, bool) {
+	lowest, known := 1.0, false
+	for key, v := range c {
+		if keys != nil && !slices.Contains(keys, key) {
+			continue
+		}
+		lowest, known = min(lowest, v), true
+	}
+	if !known {
+		return 0, false
+	}
+	return lowest, true
+}
+
+// modelOf c, if it tells.
+func modelOf(c agents.Classifier) string {
+	if m, ok := c.(agents.ModelNamer); ok {
+		return m.Model()
+	}
+	return "unknown"
+}
@@ -0,0 +1,308 @@
+package classifier
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/baalimago/kinoview/internal/agents"
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+// fakeModel answers with confidence by item ID, failing the items in fail,
+// and every batch if failBatch.
+type fakeModel struct {
+	name       string
+	confidence map[string]float64
+	fail       map[string]bool
+	failBatch  bool
+	calls      int
+}
+
+func (f *fakeModel) Setup(context.Context) error { return nil }
+func (f *fakeModel) Clone() agents.Classifier    { c := *f; return &c }
+func (f *fakeModel) Model() string               { return f.name }
+func (f *fakeModel) answer(i model.Item) (model.Item, error) {
+	f.calls++
+	if f.fail[i.ID] {
+		return model.Item{}, errors.New(f.name + " failed")
+	}
+	i.Metadata = &model.MediaMetadata{Name: i.Name}
+	i.ClassificationModel = f.name
+	if c, ok := f.confidence[i.ID]; ok {
+		i.Confidence = model.Confidence{"name": c, "genre": 1}
+	}
+	return i, nil
+}
+
+func (f *fakeModel) Classify(_ context.Context, i model.Item) (model.Item, error) {
+	return f.answer(i)
+}
+
+func (f *fakeModel) ClassifyTags(_ context.Context, i model.Item) (model.Item, error) {
+	return f.answer(i)
+}
+
+func (f *fakeModel) ClassifyBatch(_ context.Context, items []model.Item) ([]model.Item, error) {
+	if f.failBatch {
+		return nil, errors.New(f.name + " failed the batch")
+	}
+	var ret []model.Item
+	for _, i := range items {
+		if f.fail[i.ID] {
+			continue
+		}
+		classified, _ := f.answer(i)
+		ret = append(ret, classified)
+	}
+	return ret, nil
+}
+
+func readOutcomes(t *testing.T, p string) []model.ClassificationOutcome {
+	t.Helper()
+	f, err := os.Open(p)
+	if err != nil {
+		t.Fatalf("open outcomes: %v", err)
+	}
+	defer f.Close()
+	var ret []model.ClassificationOutcome
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var o model.ClassificationOutcome
+		if err := json.Unmarshal(sc.Bytes(), &o); err != nil {
+			t.Fatalf("unmarshal outcome: %v", err)
+		}
+		ret = append(ret, o)
+	}
+	return ret
+}
+
+func TestParseRoutes(t *testing.T) {
+	got, err := ParseRoutes(" gpt-4.1-mini, gpt-5 ; Image = gpt-4o-mini ;")
+	if err != nil {
+		t.Fatalf("ParseRoutes: %v", err)
+	}
+	want := []RouteSpec{
+		{Models: []string{"gpt-4.1-mini", "gpt-5"}},
+		{MIMEPrefix: "image", Models: []string{"gpt-4o-mini"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseRoutes = %+v, want %+v", got, want)
+	}
+
+	for _, spec := range []string{"", ";", "=gpt-5", "image=", "gpt-5;gpt-4o"} {
+		if _, err := ParseRoutes(spec); err == nil {
+			t.Errorf("ParseRoutes(%q): expected an error", spec)
+		}
+	}
+}
+
+func TestRouter_Classify(t *testing.T) {
+	outcomes := filepath.Join(t.TempDir(), "outcomes.jsonl")
+	cheap := &fakeModel{name: "cheap", confidence: map[string]float64{"unsure": 0.2, "sure": 0.9}, fail: map[string]bool{"broken": true}}
+	big := &fakeModel{name: "big", confidence: map[string]float64{"unsure": 0.3}}
+	eye := &fakeModel{name: "eye"}
+	r, err := NewRouter([]Route{
+		{Chain: []agents.Classifier{cheap, big}},
+		{MIMEPrefix: "image", Chain: []agents.Classifier{eye}},
+	}, WithOutcomeLog(outcomes))
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+
+	for id, want := range map[string]string{"sure": "cheap", "unsure": "big", "broken": "big"} {
+		got, err := r.Classify(context.Background(), model.Item{ID: id, MIMEType: "video/mp4"})
+		if err != nil {
+			t.Fatalf("Classify(%v): %v", id, err)
+		}
+		if got.ClassificationModel != want {
+			t.Errorf("Classify(%v) by %v, want %v", id, got.ClassificationModel, want)
+		}
+	}
+	got, err := r.Classify(context.Background(), model.Item{ID: "photo", MIMEType: "image/jpeg"})
+	if err != nil || got.ClassificationModel != "eye" {
+		t.Errorf("expected images routed to eye, got %v, %v", got.ClassificationModel, err)
+	}
+
+	results := make(map[string][]model.ClassificationResult)
+	for _, o := range readOutcomes(t, outcomes) {
+		results[o.ItemID] = append(results[o.ItemID], o.Result)
+	}
+	want := map[string][]model.ClassificationResult{
+		"sure":   {model.ResultAccepted},
+		"unsure": {model.ResultUnsure, model.ResultAccepted},
+		"broken": {model.ResultFailed, model.ResultAccepted},
+		"photo":  {model.ResultAccepted},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("recorded %v, want %v", results, want)
+	}
+}
+
+func TestRouter_Classify_allFail(t *testing.T) {
+	fail := map[string]bool{"1": true}
+	r, err := NewRouter([]Route{{MIMEPrefix: "video", Chain: []agents.Classifier{
+		&fakeModel{name: "a", fail: fail},
+		&fakeModel{name: "b", fail: fail},
+	}}})
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+	if _, err := r.Classify(context.Background(), model.Item{ID: "1", MIMEType: "video/mp4"}); err == nil {
+		t.Error("expected an error when every model fails")
+	}
+	if _, err := r.Classify(context.Background(), model.Item{ID: "2", MIMEType: "audio/mpeg"}); err == nil {
+		t.Error("expected an error for an item no route matches")
+	}
+}
+
+func TestRouter_Classify_keepsUnsure(t *testing.T) {
+	outcomes := filepath.Join(t.TempDir(), "outcomes.jsonl")
+	fail := map[string]bool{"1": true}
+	r, err := NewRouter([]Route{{Chain: []agents.Classifier{
+		&fakeModel{name: "cheap", confidence: map[string]float64{"1": 0.2}},
+		&fakeModel{name: "mid", confidence: map[string]float64{"1": 0.3}},
+		&fakeModel{name: "big", fail: fail},
+	}}}, WithOutcomeLog(outcomes))
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+	got, err := r.Classify(context.Background(), model.Item{ID: "1"})
+	if err != nil || got.ClassificationModel != "mid" {
+		t.Fatalf("expected the most confident unsure classification kept, got %v, %v", got.ClassificationModel, err)
+	}
+	var results []string
+	for _, o := range readOutcomes(t, outcomes) {
+		results = append(results, o.Model+":"+string(o.Result))
+	}
+	want := []string{"cheap:" + string(model.ResultUnsure), "mid:" + string(model.ResultUnsure), "big:" + string(model.ResultFailed), "mid:" + string(model.ResultAccepted)}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("recorded %v, want %v", results, want)
+	}
+}
+
+func TestRouter_threshold(t *testing.T) {
+	cheap := &fakeModel{name: "cheap", confidence: map[string]float64{"1": 0.2}}
+	big := &fakeModel{name: "big"}
+	r, err := NewRouter([]Route{{Chain: []agents.Classifier{cheap, big}}}, WithEscalationThreshold(0))
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+	got, err := r.Classify(context.Background(), model.Item{ID: "1"})
+	if err != nil || got.ClassificationModel != "cheap" {
+		t.Errorf("expected no escalation at threshold 0, got %v, %v", got.ClassificationModel, err)
+	}
+}
+
+func TestRouter_ClassifyTags(t *testing.T) {
+	// Only the confidence in the tags is weighed
+	cheap := &fakeModel{name: "cheap", confidence: map[string]float64{"1": 0.1}}
+	big := &fakeModel{name: "big"}
+	r, err := NewRouter([]Route{{Chain: []agents.Classifier{cheap, big}}})
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+	got, err := r.(agents.TagClassifier).ClassifyTags(context.Background(), model.Item{ID: "1"})
+	if err != nil || got.ClassificationModel != "cheap" {
+		t.Errorf("expected the confident genre kept, got %v, %v", got.ClassificationModel, err)
+	}
+}
+
+func TestRouter_ClassifyBatch(t *testing.T) {
+	outcomes := filepath.Join(t.TempDir(), "outcomes.jsonl")
+	cheap := &fakeModel{name: "cheap", confidence: map[string]float64{"2": 0.1}, fail: map[string]bool{"3": true}}
+	big := &fakeModel{name: "big", fail: map[string]bool{"3": true}}
+	r, err := NewRouter([]Route{{Chain: []agents.Classifier{cheap, big}}}, WithOutcomeLog(outcomes))
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+	items := []model.Item{{ID: "1"}, {ID: "2"}, {ID: "3"}}
+	got, err := r.(agents.BatchClassifier).ClassifyBatch(context.Background(), items)
+	if err != nil {
+		t.Fatalf("ClassifyBatch: %v", err)
+	}
+	by := make(map[string]string)
+	for _, i := range got {
+		by[i.ID] = i.ClassificationModel
+	}
+	if want := map[string]string{"1": "cheap", "2": "big"}; !reflect.DeepEqual(by, want) {
+		t.Errorf("classified by %v, want %v", by, want)
+	}
+	// Those left out are classified one by one, escalating on their own
+	if big.calls != 1 {
+		t.Errorf("expected big asked of the unsure item only, got %d calls", big.calls)
+	}
+	if n := len(readOutcomes(t, outcomes)); n != 4 {
+		t.Errorf("expected 4 outcomes, got %d", n)
+	}
+}
+
+func TestRouter_ClassifyBatch_keepsUnsure(t *testing.T) {
+	cheap := &fakeModel{name: "cheap", confidence: map[string]float64{"2": 0.1}}
+	big := &fakeModel{name: "big", failBatch: true}
+	r, err := NewRouter([]Route{{Chain: []agents.Classifier{cheap, big}}})
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+	got, err := r.(agents.BatchClassifier).ClassifyBatch(context.Background(), []model.Item{{ID: "1"}, {ID: "2"}})
+	if err != nil {
+		t.Fatalf("ClassifyBatch: %v", err)
+	}
+	by := make(map[string]string)
+	for _, i := range got {
+		by[i.ID] = i.ClassificationModel
+	}
+	if want := map[string]string{"1": "cheap", "2": "cheap"}; !reflect.DeepEqual(by, want) {
+		t.Errorf("classified by %v, want %v", by, want)
+	}
+
+	// Nothing to keep, the failure is returned
+	r, err = NewRouter([]Route{{Chain: []agents.Classifier{&fakeModel{name: "a", failBatch: true}, &fakeModel{name: "b", failBatch: true}}}})
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+	if _, err := r.(agents.BatchClassifier).ClassifyBatch(context.Background(), []model.Item{{ID: "1"}}); err == nil {
+		t.Error("expected an error when every model fails the batch")
+	}
+}
+
+func TestRouter_Clone(t *testing.T) {
+	cheap := &fakeModel{name: "cheap"}
+	r, err := NewRouter([]Route{{Chain: []agents.Classifier{cheap}}})
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+	clone := r.Clone()
+	if _, err := clone.Classify(context.Background(), model.Item{ID: "1"}); err != nil {
+		t.Fatalf("Classify: %v", err)
+	}
+	if cheap.calls != 0 {
+		t.Error("expected the clone to classify with clones of the chain")
+	}
+}
+
+func TestNewRouted(t *testing.T) {
+	var created []string
+	r, err := NewRouted("a,b;video=c", func(m string) agents.Classifier {
+		created = append(created, m)
+		return &fakeModel{name: m}
+	})
+	if err != nil {
+		t.Fatalf("NewRouted: %v", err)
+	}
+	if !reflect.DeepEqual(created, []string{"a", "b", "c"}) {
+		t.Errorf("created %v", created)
+	}
+	got, err := r.Classify(context.Background(), model.Item{ID: "1", MIMEType: "video/mp4"})
+	if err != nil || got.ClassificationModel != "c" {
+		t.Errorf("expected the longest prefix to win, got %v, %v", got.ClassificationModel, err)
+	}
+	if _, err := NewRouted("", nil); err == nil {
+		t.Error("expected an empty spec to fail")
+	}
+}
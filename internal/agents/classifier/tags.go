@@ -0,0 +1,102 @@
+package classifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/baalimago/clai/pkg/text/models"
+	"github.com/baalimago/kinoview/internal/agents"
+	"github.com/baalimago/kinoview/internal/media/constants"
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+const tagsSystemPrompt = `You are a media classifier. The media below has already been classified, your job is only to tag it.
+You may need to use tools to find information about the media, do so at will.
+
+The following format will have parenthases. These are to describe the fields to you, the media classifier.
+
+Also add a "confidence" object to the output, holding how sure you are of each field you filled in, from 0 (a guess) to 1 (certain). For instance: "confidence": {"genre": 0.9, "age_rating": 0.4}
+
+OUTPUT ONLY IN THE FOLLOWING FORMAT:
+%s`
+
+const tagsUserPrompt = `Information about the media to tag: %v
+Its metadata: %s`
+
+var _ agents.TagClassifier = (*classifier)(nil)
+
+// ClassifyTags of an item, asking the LLM for the tags alone. Whatever else
+// the answer holds is ignored.
+func (c *classifier) ClassifyTags(ctx context.Context, i model.Item) (model.Item, error) {
+	if i.Metadata == nil {
+		return model.Item{}, errors.New("item has no metadata to tag")
+	}
+	t0 := time.Now()
+	chat, err := buildTagsChat(i, t0)
+	if err != nil {
+		return model.Item{}, err
+	}
+	respChat, err := c.llm.Query(ctx, chat)
+	if err != nil {
+		return model.Item{}, fmt.Errorf("failed to query llm: %v", err)
+	}
+	lastMsg, err := extractLastMessage(respChat)
+	if err != nil {
+		return model.Item{}, err
+	}
+	if err := validateBraces(lastMsg.Content); err != nil {
+		return model.Item{}, err
+	}
+	var tags model.MediaMetadata
+	if err := json.Unmarshal(extractJSONBytes(lastMsg.Content), &tags); err != nil {
+		return model.Item{}, fmt.Errorf("lastMsg is not valid metadata: %w", err)
+	}
+	confidence := takeConfidence(&tags)
+	if !tags.HasTags() {
+		return model.Item{}, errors.New("lastMsg holds no tags")
+	}
+
+	md := *i.Metadata
+	md.SetTags(tags)
+	i.Metadata = &md
+	if confidence != nil {
+		merged := maps.Clone(i.Confidence)
+		if merged == nil {
+			merged = model.Confidence{}
+		}
+		for key, v := range confidence {
+			if slices.Contains(model.TagKeys, key) {
+				merged[key] = v
+			}
+		}
+		i.Confidence = merged
+	}
+	i.ClassificationModel = c.model
+	return i, nil
+}
+
+func buildTagsChat(i model.Item, t0 time.Time) (models.Chat, error) {
+	known, err := json.Marshal(i.Metadata)
+	if err != nil {
+		return models.Chat{}, fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+	return models.Chat{
+		Created: t0,
+		ID:      fmt.Sprintf("classify_tags_%v_%v", i.ID, t0.Format("25-01-01T00:00Z00")),
+		Messages: []models.Message{
+			{
+				Role:    "system",
+				Content: fmt.Sprintf(tagsSystemPrompt, constants.TagsFormat),
+			},
+			{
+				Role:    "user",
+				Content: fmt.Sprintf(tagsUserPrompt, i, known),
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,66 @@
+package classifier
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/baalimago/clai/pkg/text/models"
+	"github.com/baalimago/kinoview/internal/media/constants"
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+func TestClassifyTags(t *testing.T) {
+	var gotSystem, gotUser string
+	mockLLM := &mockLLM{
+		queryFunc: func(ctx context.Context, c models.Chat) (models.Chat, error) {
+			gotSystem = c.Messages[0].Content
+			gotUser = c.Messages[1].Content
+			return models.Chat{
+				Messages: []models.Message{
+					{Role: "assistant", Content: `{"name": "Renamed", "genre": "Crime, Thriller", "mood": ["tense"], "age_rating": "R", "content_warnings": ["violence"], "confidence": {"genre": 0.9, "name": 0.1}}`},
+				},
+			}, nil
+		},
+	}
+	c := &classifier{llm: mockLLM}
+
+	md := model.MediaMetadata{Name: "Heat", Year: 1995}
+	got, err := c.ClassifyTags(context.Background(), model.Item{ID: "heat", Name: "heat.mkv", Metadata: &md, Confidence: model.Confidence{"name": 1}})
+	if err != nil {
+		t.Fatalf("didnt expect error: %v", err)
+	}
+	if !strings.Contains(gotSystem, constants.TagsFormat) || strings.Contains(gotSystem, `"season"`) {
+		t.Errorf("expected only the tags to be asked for, got: %v", gotSystem)
+	}
+	if !strings.Contains(gotUser, `"name":"Heat"`) {
+		t.Errorf("expected the metadata in the user prompt, got: %v", gotUser)
+	}
+	if got.Metadata.Name != "Heat" || got.Metadata.Year != 1995 {
+		t.Errorf("expected the rest of the metadata untouched, got %+v", got.Metadata)
+	}
+	if got.Metadata.Genre != "Crime, Thriller" || got.Metadata.AgeRating != "R" || len(got.Metadata.Mood) != 1 || len(got.Metadata.ContentWarnings) != 1 {
+		t.Errorf("expected the tags to be set, got %+v", got.Metadata)
+	}
+	if got.Confidence["name"] != 1 || got.Confidence["genre"] != 0.9 {
+		t.Errorf("expected only the confidence of the tags to change, got %v", got.Confidence)
+	}
+	if md.Genre != "" {
+		t.Error("expected the metadata of the item passed in to be left alone")
+	}
+
+	t.Run("no tags", func(t *testing.T) {
+		mockLLM.queryFunc = func(ctx context.Context, c models.Chat) (models.Chat, error) {
+			return models.Chat{Messages: []models.Message{{Role: "assistant", Content: `{"name": "Heat"}`}}}, nil
+		}
+		if _, err := c.ClassifyTags(context.Background(), model.Item{Metadata: &md}); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+
+	t.Run("no metadata", func(t *testing.T) {
+		if _, err := c.ClassifyTags(context.Background(), model.Item{}); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
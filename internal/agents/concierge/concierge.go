@@ -2,7 +2,9 @@ package concierge
 
 import (
 	"errors"
+	"fmt"
 	"path"
+	"strings"
 
 	"github.com/baalimago/clai/pkg/agent"
 	"github.com/baalimago/clai/pkg/text/models"
@@ -11,6 +13,8 @@ import (
 	"github.com/baalimago/kinoview/internal/agents"
 	"github.com/baalimago/kinoview/internal/agents/slivingdoc"
 	"github.com/baalimago/kinoview/internal/agents/tools"
+	"github.com/baalimago/kinoview/internal/lang"
+	"github.com/baalimago/kinoview/internal/model"
 )
 
 type ConciergeOption func(*concierge)
@@ -29,7 +33,7 @@ Goal: Every active suggestion must have valid, working subtitles.
    c. If no candidates exist and fetch_subtitles is unavailable:
       - Note the item has no subtitles and cannot fetch them. Move to next suggestion.
    d. If candidates exist:
-      - Select the best candidate: prefer default, English, non-forced, non-commentary.
+      - Select the best candidate: prefer a preferred language (see SUBTITLE LANGUAGES), then default, non-forced, non-commentary.
       - If not already extracted, call extract_subtitle with the chosen subtitleID.
       - Call rows_between on the extracted file (path from extract_subtitle result, or call extract_subtitle again — it is idempotent). Read at least 100-200 lines to sample the dialogue.
       - VALIDATE: Compare the subtitle dialogue against the item's metadata:
@@ -37,6 +41,7 @@ Goal: Every active suggestion must have valid, working subtitles.
         * Do character names, places, or plot elements in the dialogue match the item's description?
         * Is there actual spoken dialogue (not just timing cues or empty blocks)?
         * Does the subtitle appear to be for the correct media (not a different movie/episode)?
+      - If valid and the candidate is a fetched or sidecar file (negative subtitleID), call sync_subtitle on it: such files are often offset or drifting relative to the video. It is cached, so it only takes a while once.
       - If valid: note it and move to the next suggestion.
       - If invalid or empty: attempt another candidate if available, otherwise note the failure.
 3. Only after ALL suggestions have been processed, proceed to Phase 2.
@@ -63,9 +68,19 @@ GENERAL RULES:
 - You will be called periodically; note the current date and adjust suggestions accordingly.
 `
 
+// subtitleLanguagesSection is formatted with the server's preferred subtitle
+// languages.
+const subtitleLanguagesSection = `
+SUBTITLE LANGUAGES:
+- Preferred subtitle languages, most preferred first: %s.
+- A client context may list its own under "subtitleLanguages"; prefer those for media suggested to that client.
+- Pass such client languages to list_subtitle_candidates and fetch_subtitles with their "languages" input.
+- Validate that extracted subtitles are written in the language they are tagged with.
+`
+
 const openSubtitlesAddendum = `
 OPENSUBTITLES FALLBACK:
-- If no subtitle candidates exist for a suggested item, call fetch_subtitles to search OpenSubtitles.
+- If no subtitle candidates exist for a suggested item, call fetch_subtitles to search the subtitle providers (a local subtitle folder, OpenSubtitles).
 - If extracted subtitles fail validation, call fetch_subtitles as a fallback.
 - fetch_subtitles only works for movies (video/* MIME types).
 `
@@ -77,6 +92,7 @@ type concierge struct {
 	suggestionMgr  agents.SuggestionManager
 	subtitlesMgr   agents.StreamManager
 	userContextMgr agents.ClientContextManager
+	quotes         agents.QuoteSearcher
 
 	storeDir string
 
@@ -84,6 +100,12 @@ type concierge struct {
 	configDir string
 	cacheDir  string
 
+	// subtitleLanguages preferred when a client doesn't state its own.
+	subtitleLanguages lang.Preferences
+	// subtitleProvider fetch_subtitles fetches from, nil for the providers
+	// configured by environment.
+	subtitleProvider tools.SubtitleProvider
+
 	// slivingdocServer is the MCP callsign for the shared agent notebook. A
 	// zero server disables the notebook: the concierge runs exactly as today,
 	// without the callsign, the file-tool globs or the NOTES prompt section.
@@ -131,6 +153,14 @@ func WithItemLister(il agents.ItemLister) ConciergeOption {
 	}
 }
 
+// WithQuoteSearcher gives the concierge the search_quotes tool, to find
+// media by what's said in it.
+func WithQuoteSearcher(q agents.QuoteSearcher) ConciergeOption {
+	return func(c *concierge) {
+		c.quotes = q
+	}
+}
+
 func WithStoreDir(dir string) ConciergeOption {
 	return func(c *concierge) {
 		c.storeDir = dir
@@ -155,6 +185,24 @@ func WithUserContextManager(ucm agents.ClientContextManager) ConciergeOption {
 	}
 }
 
+// WithSubtitleProvider sets the provider fetch_subtitles fetches from, so
+// that it can be shared with other tools and count against one quota.
+// Defaults to the providers configured by environment, see
+// tools.DefaultSubtitleProviders.
+func WithSubtitleProvider(p tools.SubtitleProvider) ConciergeOption {
+	return func(c *concierge) {
+		c.subtitleProvider = p
+	}
+}
+
+// WithSubtitleLanguages sets the preferred subtitle languages, most
+// preferred first. Defaults to lang.Default.
+func WithSubtitleLanguages(langs lang.Preferences) ConciergeOption {
+	return func(c *concierge) {
+		c.subtitleLanguages = langs
+	}
+}
+
 func WithModel(m string) ConciergeOption {
 	return func(c *concierge) {
 		c.model = m
@@ -206,11 +254,12 @@ func (c *concierge) notebookGlobs() []string {
 	return slivingdoc.ToolGlobs()
 }
 
-// buildPrompt assembles the system prompt: the base workflow, the
-// OpenSubtitles addendum when the fetch tool is live, and the shared NOTES
-// partial when the notebook is enabled.
+// buildPrompt assembles the system prompt: the base workflow, the preferred
+// subtitle languages, the OpenSubtitles addendum when the fetch tool is live,
+// and the shared NOTES partial when the notebook is enabled.
 func (c *concierge) buildPrompt(hasOpenSubtitles bool) string {
 	prompt := baseSystemPrompt
+	prompt += fmt.Sprintf(subtitleLanguagesSection, strings.Join(c.subtitleLanguages.Or(lang.Default), ", "))
 	if hasOpenSubtitles {
 		prompt += openSubtitlesAddendum
 	}
@@ -226,19 +275,21 @@ func (c *concierge) buildPrompt(hasOpenSubtitles bool) string {
 // 3.  UpdateMetadata
 // 4.  list_subtitle_candidates
 // 5.  extract_subtitle
-// 6.  fetch_subtitles (conditional — only when OPENSUBTITLES_API_KEY is set)
-// 7.  check_suggestions
-// 8.  remove_suggestion
-// 9.  add_suggestion
-// 10. get_user_context
-// 11. media_get_item
-// 12. media_list (conditional — only when item lister is available)
-// 13. media_stats (conditional — only when item lister is available)
-// 14. website_text
-// 15. date
-// 16. ffprobe
-// 17. cat
-// 18. rows_between
+// 6.  sync_subtitle
+// 7.  fetch_subtitles (conditional — only when a subtitle provider is configured)
+// 8.  check_suggestions
+// 9.  remove_suggestion
+// 10. add_suggestion
+// 11. get_user_context
+// 12. media_get_item
+// 13. media_list (conditional — only when item lister is available)
+// 14. media_stats (conditional — only when item lister is available)
+// 15. search_quotes (conditional — only when a quote searcher is available)
+// 16. website_text
+// 17. date
+// 18. ffprobe
+// 19. cat
+// 20. rows_between
 // With the slivingdoc callsign configured, the file tools (cat, rows_between,
 // ls, rg, write_file, apply_patch, mkdir) and the notebook tools
 // (mcp_slivingdoc_notes_pull, mcp_slivingdoc_notes_commit) arrive through the
@@ -249,6 +300,7 @@ func New(opts ...ConciergeOption) (agents.Concierge, error) {
 	for _, o := range opts {
 		o(&c)
 	}
+	c.subtitleLanguages = c.subtitleLanguages.Or(lang.Default)
 
 	if c.itemStore == nil {
 		return nil, errors.New("item store can't be nil")
@@ -282,7 +334,11 @@ func New(opts ...ConciergeOption) (agents.Concierge, error) {
 		llmTools = append(llmTools, ccp)
 	}
 
-	umt, err := tools.NewUpdateMetadataTool(c.metadataMgr, c.itemStore)
+	umt, err := tools.NewUpdateMetadataTool(c.metadataMgr, c.itemStore, model.Provenance{
+		Source: model.SourceAgent,
+		Actor:  "concierge",
+		Model:  c.model,
+	})
 	if err != nil {
 		ancli.Errf("concierge failed to setup updateMetadataTool: %v", err)
 	} else {
@@ -290,7 +346,7 @@ func New(opts ...ConciergeOption) (agents.Concierge, error) {
 	}
 
 	subsPath := path.Join(c.configDir, "subtitles")
-	lsc, err := tools.NewListSubtitleCandidatesTool(c.itemStore, c.subtitlesMgr, subsPath)
+	lsc, err := tools.NewListSubtitleCandidatesTool(c.itemStore, c.subtitlesMgr, subsPath, c.subtitleLanguages)
 	if err != nil {
 		ancli.Errf("concierge failed to setup listSubtitleCandidatesTool: %v", err)
 	} else {
@@ -304,6 +360,13 @@ func New(opts ...ConciergeOption) (agents.Concierge, error) {
 		llmTools = append(llmTools, esc)
 	}
 
+	sst, err := tools.NewSyncSubtitleTool(c.itemStore, c.subtitlesMgr)
+	if err != nil {
+		ancli.Errf("concierge failed to setup syncSubtitleTool: %v", err)
+	} else {
+		llmTools = append(llmTools, sst)
+	}
+
 	lst, err := tools.NewCheckSuggestionsTool(c.suggestionMgr)
 	if err != nil {
 		ancli.Errf("concierge failed to setup checkSuggestionsTool: %v", err)
@@ -311,9 +374,14 @@ func New(opts ...ConciergeOption) (agents.Concierge, error) {
 		llmTools = append(llmTools, lst)
 	}
 
-	// Fetch subtitles from OpenSubtitles for movies without embedded subtitles.
-	// Returns nil if OPENSUBTITLES_API_KEY is not configured (tool silently omitted).
-	fst := tools.NewFetchSubtitlesTool(c.itemStore, c.subtitlesMgr, c.cacheDir)
+	// Fetch subtitles for movies without embedded subtitles. Returns nil if
+	// no subtitle provider is configured (tool silently omitted).
+	var fst models.LLMTool
+	if c.subtitleProvider != nil {
+		fst = tools.NewFetchSubtitlesToolWithProvider(c.itemStore, c.subtitlesMgr, c.cacheDir, c.subtitleLanguages, c.subtitleProvider)
+	} else if t := tools.NewFetchSubtitlesTool(c.itemStore, c.subtitlesMgr, c.cacheDir, c.subtitleLanguages); t != nil {
+		fst = t
+	}
 	if fst != nil {
 		llmTools = append(llmTools, fst)
 	}
@@ -362,6 +430,16 @@ func New(opts ...ConciergeOption) (agents.Concierge, error) {
 		}
 	}
 
+	if c.quotes != nil {
+		gate, _ := c.itemLister.(agents.ContentGate)
+		sqt, err := tools.NewSearchQuotesTool(c.quotes, gate)
+		if err != nil {
+			ancli.Errf("concierge failed to setup searchQuotesTool: %v", err)
+		} else {
+			llmTools = append(llmTools, sqt)
+		}
+	}
+
 	llmTools = append(
 		llmTools,
 		clai_tools.WebsiteText,
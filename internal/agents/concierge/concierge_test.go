@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/baalimago/kinoview/internal/agents/slivingdoc"
+	"github.com/baalimago/kinoview/internal/lang"
 	"github.com/baalimago/kinoview/internal/model"
 )
 
@@ -22,7 +23,9 @@ func (m *mockItemGetterLister) Snapshot() []model.Item { return nil }
 
 type mockMetadataManager struct{}
 
-func (m *mockMetadataManager) UpdateMetadata(item model.Item, metadata string) error { return nil }
+func (m *mockMetadataManager) UpdateMetadata(item model.Item, metadata string, by model.Provenance) error {
+	return nil
+}
 
 type mockSuggestionManager struct{}
 
@@ -39,6 +42,9 @@ func (m *mockSubtitleManager) Find(item model.Item) (model.MediaInfo, error) {
 func (m *mockSubtitleManager) ExtractSubtitles(item model.Item, streamIndex string) (string, error) {
 	return "", nil
 }
+func (m *mockSubtitleManager) SyncSubtitles(item model.Item, streamIndex string) (string, error) {
+	return "", nil
+}
 func (m *mockSubtitleManager) Associate(item model.Item, path string) error { return nil }
 
 type mockUserContextManager struct{}
@@ -260,6 +266,21 @@ func TestConcierge_NoServerOmitsSlivingdoc(t *testing.T) {
 	}
 }
 
+func TestConcierge_PromptListsSubtitleLanguages(t *testing.T) {
+	c := concierge{}
+	if !strings.Contains(c.buildPrompt(false), "most preferred first: en.") {
+		t.Error("expected the default subtitle language in the prompt")
+	}
+	WithSubtitleLanguages(lang.Parse("swe,jpn"))(&c)
+	prompt := c.buildPrompt(false)
+	if !strings.Contains(prompt, "most preferred first: sv, ja.") {
+		t.Errorf("expected the configured subtitle languages in the prompt:\n%s", prompt)
+	}
+	if strings.Contains(prompt, "English") {
+		t.Error("prompt must not hard-code English")
+	}
+}
+
 // The NOTES prompt section names the exact workspace path — from the explicit
 // option, or read back from the callsign args when the option is empty — so
 // the model is never asked to guess where the notebook lives.
@@ -4,6 +4,7 @@ import (
 	"context"
 	"io"
 
+	"github.com/baalimago/kinoview/internal/lang"
 	"github.com/baalimago/kinoview/internal/model"
 )
 
@@ -71,8 +72,74 @@ type ItemLister interface {
 	Snapshot() []model.Item
 }
 
+// QuoteSearcher finds lines of dialogue in the subtitles of the library.
+type QuoteSearcher interface {
+	// SearchQuotes returns the lines in which query is said, at most limit of
+	// them, and the total number of matches. Only lines of the items allowed
+	// reports true for are searched, those of every item if it's nil.
+	SearchQuotes(query string, limit int, allowed func(model.Item) bool) ([]model.Quote, int, error)
+}
+
+// ReviewQueue holds the items whose classification wasn't trusted, until
+// someone has looked at them.
+type ReviewQueue interface {
+	// Reviews lists the items waiting for review, the longest waiting first.
+	Reviews() []model.Item
+	// ResolveReview of the item with the given ID, returning the item as it
+	// is after. Errors wrap model.ErrNoReview if the item isn't in review,
+	// and model.ErrInvalidDecision if the decision can't be carried out.
+	ResolveReview(id string, d model.ReviewDecision) (model.Item, error)
+}
+
+// MetadataHistory keeps every version of the metadata of the items, and who
+// made it.
+type MetadataHistory interface {
+	// History of the metadata of the item with the given ID, oldest first.
+	// Errors wrap model.ErrNoItem if there's no such item.
+	History(id string) ([]model.MetadataChange, error)
+	// RevertMetadata of the item with the given ID to what it was at
+	// version, returning the item as it is after. Errors wrap
+	// model.ErrNoVersion if the item never had that version.
+	RevertMetadata(id string, version int) (model.Item, error)
+}
+
+// ClassificationPrioritizer moves items ahead in the classification queue.
+// Optional, like OutputSetter: an ItemGetter which also implements it gets
+// the items the concierge suggests classified first.
+type ClassificationPrioritizer interface {
+	// PrioritizeClassification of the item with the given ID, if p is more
+	// urgent than what it was queued with. Reports whether it's queued.
+	PrioritizeClassification(id string, p model.ClassificationPriority) bool
+}
+
+// ContentGate keeps the agents from what they may not suggest, by its age
+// rating. Optional, like ClassificationPrioritizer: the tools of an
+// ItemLister or ItemGetter which also implements it leave those items out.
+type ContentGate interface {
+	// AllowedForAgents reports whether the agents may list and suggest it.
+	AllowedForAgents(model.Item) bool
+}
+
+// ClassificationQueue tells what waits for classification.
+type ClassificationQueue interface {
+	ClassificationQueue() model.ClassificationQueue
+}
+
+// MetadataProvider looks up the metadata of titles in a dataset, such as a
+// dump of IMDb, without an LLM or the network. Lookups are reproducible:
+// the same dataset always answers the same.
+type MetadataProvider interface {
+	// LookupTitle by its name, and its year unless 0. Every match is
+	// returned, oldest first, none being no error.
+	LookupTitle(title string, year int) ([]model.MediaMetadata, error)
+	// LookupID by the ID the dataset has for the title, such as the IMDb
+	// ID. Errors wrap model.ErrNoTitle if there's no such title.
+	LookupID(id string) (model.MediaMetadata, error)
+}
+
+// MetadataManager patches the metadata of items, recording by whom.
 type MetadataManager interface {
-	UpdateMetadata(model.Item, string) error
+	UpdateMetadata(item model.Item, metadata string, by model.Provenance) error
 }
 
 // SuggestionManager manages suggestions. Stores them for whoever wants some suggestions
@@ -93,11 +160,17 @@ type StreamManager interface {
 	// ExtractSubtitles the subtitles, return string to path to the file where the subtitles are extracted. On subsequent
 	// calls, the subtitles will be checked from fle. As such, it's possible to preload subs
 	ExtractSubtitles(item model.Item, streamIndex string) (string, error)
+
+	// SyncSubtitles retimes the subtitles at streamIndex to match the speech
+	// in the audio track, return string to path of the corrected file. Like
+	// ExtractSubtitles, the result is kept on file.
+	SyncSubtitles(item model.Item, streamIndex string) (string, error)
 }
 
 type SubtitleSelector interface {
-	// Select returns the index of the best english subtitle stream, or error if none found
-	Select(ctx context.Context, streams []model.Stream) (int, error)
+	// Select returns the index of the best subtitle stream in one of langs,
+	// most preferred first, or error if none found
+	Select(ctx context.Context, streams []model.Stream, langs lang.Preferences) (int, error)
 }
 
 // ClientContextManager manages the user context and allows
@@ -116,6 +189,33 @@ type OutputSetter interface {
 	SetOutput(io.Writer) error
 }
 
+// BatchClassifier classifies several items in one go, such as the episodes
+// of a season, which share most of what there is to know. Optional, like
+// OutputSetter: the classification station batches items for classifiers
+// which implement it.
+type BatchClassifier interface {
+	// ClassifyBatch of items in a blocking manner, returning a copy of those
+	// it classified. Items missing from the result are to be classified one
+	// by one.
+	ClassifyBatch(context.Context, []model.Item) ([]model.Item, error)
+}
+
+// TagClassifier classifies the tags of an item alone: its genre, mood, age
+// rating and content warnings, see model.TagKeys. Optional, like
+// BatchClassifier: items classified before there were tags are backfilled
+// with it, without classifying all of them again.
+type TagClassifier interface {
+	// ClassifyTags of an item which has metadata, returning a copy with the
+	// tags set and the rest of the metadata as it was.
+	ClassifyTags(context.Context, model.Item) (model.Item, error)
+}
+
+// ModelNamer tells which LLM model a module runs on. Optional, like
+// OutputSetter: it's used to record the provenance of what the module wrote.
+type ModelNamer interface {
+	Model() string
+}
+
 // Teller prepares a short story for the next visit and hands out the current
 // one. The house contract of the intro splash (phase 9 — moved here from the
 // old intro-story package, which the theatre replaced): the media index wires
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"regexp"
 	"strings"
 
 	"github.com/baalimago/clai/pkg/text"
@@ -16,9 +17,27 @@ import (
 )
 
 type recommender struct {
-	llm text.FullResponse
+	llm    text.FullResponse
+	quotes agents.QuoteSearcher
 }
 
+type Option func(*recommender)
+
+// WithQuoteSearcher lets the recommender look up lines quoted in a request,
+// such as: the movie where someone says "I'll be back".
+func WithQuoteSearcher(q agents.QuoteSearcher) Option {
+	return func(r *recommender) {
+		r.quotes = q
+	}
+}
+
+// quotedRe matches the phrases quoted in a request. Single quotes must stand
+// apart from the words around them, so that apostrophes don't count.
+var quotedRe = regexp.MustCompile(`"([^"]+)"|“([^”]+)”|(?:^|\s)'(.+?)'(?:[\s.,!?]|$)`)
+
+// maxQuoteMatches per quoted phrase shown to the llm.
+const maxQuoteMatches = 10
+
 const systemPrompt = `You are a media picker. You will be given request by a user, user context and a list of media. Your task is to determine the right piece of media for the user, based on the request and context.
 
 Be smart. Avoid picking media which has already been seen. When queried to continue something, doublecheck the existing duration since there may be some minutes left.
@@ -36,10 +55,14 @@ Media:
 %v`
 
 // New configured by models.Configurations
-func New(c models.Configurations) agents.Recommender {
-	return &recommender{
+func New(c models.Configurations, opts ...Option) agents.Recommender {
+	r := &recommender{
 		llm: text.NewFullResponseQuerier(c),
 	}
+	for _, o := range opts {
+		o(r)
+	}
+	return r
 }
 
 func (r *recommender) Setup(ctx context.Context) error {
@@ -76,15 +99,19 @@ func (r *recommender) Recommend(
 			metadataJSONStr,
 		))
 	}
+	prompt := fmt.Sprintf(
+		systemPrompt,
+		request,
+		itemsStr.String(),
+	)
+	if dialogue := r.dialogue(request, items); dialogue != "" {
+		prompt += "\nDialogue quoted in the request is said in:\n" + dialogue
+	}
 	chat := models.Chat{
 		Messages: []models.Message{
 			{
-				Role: "system",
-				Content: fmt.Sprintf(
-					systemPrompt,
-					request,
-					itemsStr.String(),
-				),
+				Role:    "system",
+				Content: prompt,
 			},
 		},
 	}
@@ -123,6 +150,36 @@ func (r *recommender) Recommend(
 	)
 }
 
+// dialogue lists where the phrases quoted in request are said in items, one
+// line per match. Empty if there's no quote searcher, or nothing quoted is
+// found.
+func (r *recommender) dialogue(request string, items []model.Item) string {
+	if r.quotes == nil {
+		return ""
+	}
+	ids := make(map[string]bool, len(items))
+	for _, it := range items {
+		ids[it.ID] = true
+	}
+	among := func(it model.Item) bool { return ids[it.ID] }
+	var b strings.Builder
+	for _, m := range quotedRe.FindAllStringSubmatch(request, -1) {
+		phrase := strings.TrimSpace(m[1] + m[2] + m[3])
+		if phrase == "" {
+			continue
+		}
+		found, _, err := r.quotes.SearchQuotes(phrase, maxQuoteMatches, among)
+		if err != nil {
+			ancli.Warnf("failed to search quotes for %q: %v", phrase, err)
+			continue
+		}
+		for _, q := range found {
+			fmt.Fprintf(&b, "- id: %s, name: %s, at: %.0fs, line: %q\n", q.ItemID, q.Name, q.Start, q.Text)
+		}
+	}
+	return b.String()
+}
+
 // extractMediaID by using some unholy concoction an LLM conjured up. It seems to work though, see tests
 func extractMediaID(s string) (string, error) {
 	key := `"mediaId"`
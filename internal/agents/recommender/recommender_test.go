@@ -198,3 +198,52 @@ func TestExtractMediaID(t *testing.T) {
 		})
 	}
 }
+
+type fakeQuoteSearcher struct {
+	queries []string
+}
+
+func (f *fakeQuoteSearcher) SearchQuotes(query string, limit int, allowed func(model.Item) bool) ([]model.Quote, int, error) {
+	f.queries = append(f.queries, query)
+	if query != "I'll be back" || (allowed != nil && !allowed(model.Item{ID: "2"})) {
+		return nil, 0, nil
+	}
+	return []model.Quote{{ItemID: "2", Name: "Two", Start: 3723, Text: "I'll be back."}}, 1, nil
+}
+
+func TestRecommend_QuotedDialogue(t *testing.T) {
+	f := &fakeLLM{
+		resp: models.Chat{Messages: []models.Message{{Role: "assistant", Content: `{"mediaId":"2"}`}}},
+	}
+	qs := &fakeQuoteSearcher{}
+	r := &recommender{llm: f, quotes: qs}
+	items := []model.Item{{ID: "1", Name: "One"}, {ID: "2", Name: "Two"}}
+
+	if _, err := r.Recommend(context.Background(), `the movie where he says 'I'll be back', or “nope”`, items); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(qs.queries) != 2 || qs.queries[0] != "I'll be back" || qs.queries[1] != "nope" {
+		t.Fatalf("unexpected quote searches: %q", qs.queries)
+	}
+	sys := f.got.Messages[0].Content
+	if !strings.Contains(sys, `- id: 2, name: Two, at: 3723s, line: "I'll be back."`) {
+		t.Fatalf("prompt missing dialogue: %q", sys)
+	}
+
+	// Only the lines of the items to recommend from
+	f.resp = models.Chat{Messages: []models.Message{{Role: "assistant", Content: `{"mediaId":"1"}`}}}
+	if _, err := r.Recommend(context.Background(), `he says 'I'll be back'`, items[:1]); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if strings.Contains(f.got.Messages[0].Content, "Dialogue") {
+		t.Fatalf("expected no dialogue of items not recommended from, got: %q", f.got.Messages[0].Content)
+	}
+
+	qs.queries = nil
+	if _, err := r.Recommend(context.Background(), "something I'd like", items); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(qs.queries) != 0 || strings.Contains(f.got.Messages[0].Content, "Dialogue") {
+		t.Fatalf("expected no quote search without quotes, got: %q", qs.queries)
+	}
+}
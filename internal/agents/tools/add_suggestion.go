@@ -42,6 +42,13 @@ func (ast *addSuggestionTool) Call(input models.Input) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("failed to get item: %w", err)
 	}
+	if g, ok := ast.itemGetter.(agents.ContentGate); ok && !g.AllowedForAgents(item) {
+		return "", fmt.Errorf("item '%v' is rated above what may be suggested", item.Name)
+	}
+	// A suggestion without metadata is a poor one, see it classified first
+	if p, ok := ast.itemGetter.(agents.ClassificationPrioritizer); ok && item.Metadata == nil {
+		p.PrioritizeClassification(item.ID, model.PrioritySuggested)
+	}
 
 	suggestion := model.Suggestion{
 		Item:       item,
@@ -177,3 +177,67 @@ func TestAddSuggestionTool_Call_Success(t *testing.T) {
 		t.Fatalf("unexpected item in suggestion: %+v", sm.addCalls[0].Item)
 	}
 }
+
+type prioritizingItemGetter struct {
+	fakeItemGetter
+	prioritized map[string]model.ClassificationPriority
+}
+
+func (p *prioritizingItemGetter) PrioritizeClassification(id string, prio model.ClassificationPriority) bool {
+	p.prioritized[id] = prio
+	return true
+}
+
+func TestAddSuggestionTool_Call_PrioritizesClassification(t *testing.T) {
+	t.Parallel()
+
+	md := model.MediaMetadata{}
+	for _, tc := range []struct {
+		name string
+		item model.Item
+		want bool
+	}{
+		{"unclassified", model.Item{ID: "a", Name: "A"}, true},
+		{"classified", model.Item{ID: "a", Name: "A", Metadata: &md}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ig := &prioritizingItemGetter{fakeItemGetter: fakeItemGetter{item: tc.item}, prioritized: map[string]model.ClassificationPriority{}}
+			ast, err := NewAddSuggestionTool(&fakeSuggestionManager{}, ig)
+			if err != nil {
+				t.Fatalf("NewAddSuggestionTool: %v", err)
+			}
+			if _, err := ast.Call(models.Input{"mediaID": "a", "motivation": "x"}); err != nil {
+				t.Fatalf("Call: %v", err)
+			}
+			p, ok := ig.prioritized["a"]
+			if ok != tc.want || (ok && p != model.PrioritySuggested) {
+				t.Fatalf("prioritized = %v (%v), want %v", ok, p, tc.want)
+			}
+		})
+	}
+}
+
+type gatedItemGetter struct {
+	fakeItemGetter
+}
+
+func (g *gatedItemGetter) AllowedForAgents(it model.Item) bool {
+	return it.Metadata == nil || it.Metadata.AgeRating != "R"
+}
+
+func TestAddSuggestionTool_Call_RefusesGatedItems(t *testing.T) {
+	t.Parallel()
+
+	sm := &fakeSuggestionManager{}
+	ig := &gatedItemGetter{fakeItemGetter{item: model.Item{ID: "a", Name: "Heat", Metadata: &model.MediaMetadata{AgeRating: "R"}}}}
+	ast, err := NewAddSuggestionTool(sm, ig)
+	if err != nil {
+		t.Fatalf("NewAddSuggestionTool: %v", err)
+	}
+	if _, err := ast.Call(models.Input{"mediaID": "a", "motivation": "x"}); err == nil {
+		t.Fatal("expected an item above the agents' limit to be refused")
+	}
+	if len(sm.addCalls) != 0 {
+		t.Fatalf("expected no suggestion, got %v", sm.addCalls)
+	}
+}
@@ -1,43 +1,70 @@
 package tools
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/baalimago/clai/pkg/text/models"
+	"github.com/baalimago/go_away_boilerplate/pkg/ancli"
 	"github.com/baalimago/kinoview/internal/agents"
+	"github.com/baalimago/kinoview/internal/lang"
+	"github.com/baalimago/kinoview/internal/media/moviehash"
+	"github.com/baalimago/kinoview/internal/media/stream"
 	kinomodel "github.com/baalimago/kinoview/internal/model"
 )
 
 type fetchSubtitlesTool struct {
 	itemGetter agents.ItemGetter
 	subMgr     agents.StreamManager
-	osClient   *OpenSubtitlesClient
+	provider   SubtitleProvider
 	cacheDir   string
+	langs      lang.Preferences
 	debug      bool
 }
 
-// NewFetchSubtitlesTool creates the OpenSubtitles-based subtitle fetch tool.
-// Returns nil if the OpenSubtitles API key is not configured, signalling
-// that the tool should not be registered.
+// NewFetchSubtitlesTool creates the subtitle fetch tool, using the providers
+// configured by environment, see DefaultSubtitleProviders. Processes with
+// more than one such tool should share a chain between them instead, see
+// NewFetchSubtitlesToolWithProvider. It searches for
+// subtitles in langs unless the call states its own languages. Empty langs
+// fall back to KINOVIEW_SUBTITLE_LANGUAGES, see lang.FromEnv.
+// Returns nil if no provider is configured, signalling that the tool should
+// not be registered.
 func NewFetchSubtitlesTool(
 	ig agents.ItemGetter,
 	sm agents.StreamManager,
 	cacheDir string,
+	langs lang.Preferences,
 ) *fetchSubtitlesTool {
-	client := NewOpenSubtitlesClient()
-	if client == nil {
+	provider := DefaultSubtitleProviders(cacheDir)
+	if provider == nil {
+		return nil
+	}
+	return NewFetchSubtitlesToolWithProvider(ig, sm, cacheDir, langs, provider)
+}
+
+// NewFetchSubtitlesToolWithProvider creates the subtitle fetch tool, fetching
+// from provider. Returns nil if provider is nil, like NewFetchSubtitlesTool.
+func NewFetchSubtitlesToolWithProvider(
+	ig agents.ItemGetter,
+	sm agents.StreamManager,
+	cacheDir string,
+	langs lang.Preferences,
+	provider SubtitleProvider,
+) *fetchSubtitlesTool {
+	if provider == nil {
 		return nil
 	}
 	return &fetchSubtitlesTool{
 		itemGetter: ig,
 		subMgr:     sm,
-		osClient:   client,
+		provider:   provider,
 		cacheDir:   cacheDir,
+		langs:      langs,
 		debug:      os.Getenv("DEBUG") != "" || os.Getenv("DEBUG_SUBS") != "",
 	}
 }
@@ -79,42 +106,52 @@ func (t *fetchSubtitlesTool) Call(input models.Input) (string, error) {
 	}
 
 	// Search and download
-	langs := SubtitleLanguages()
-	result, err := t.searchItem(item, langs)
+	langs := t.langs.Or(lang.FromEnv())
+	if l, ok := input["languages"].(string); ok {
+		langs = lang.Parse(l).Or(langs)
+	}
+	if t.provider == nil {
+		return "", errors.New("no subtitle provider configured for fetch_subtitles tool")
+	}
+	q := t.query(item, info, langs)
+	found, err := t.provider.Search(q)
+	if errors.Is(err, ErrQuotaExhausted) {
+		return "subtitle download quota is used up, try again later", nil
+	}
 	if err != nil {
 		return "", fmt.Errorf("subtitle search failed: %w", err)
 	}
 
-	if len(result.Data) == 0 {
+	if len(found) == 0 {
 		return fmt.Sprintf("no subtitles found for '%s'", item.Name), nil
 	}
 
-	file := BestFile(result.Data, firstLang(langs))
-	if file == nil {
-		return fmt.Sprintf("no matching subtitle file found for '%s' (wanted lang: %s)", item.Name, firstLang(langs)), nil
+	best := BestCandidate(found, q)
+	if best == nil {
+		return fmt.Sprintf("no matching subtitle file found for '%s' (wanted langs: %s)", item.Name, langs), nil
 	}
 
-	dl, err := t.osClient.Download(file.FileID)
+	fileName, content, err := t.provider.Download(*best)
 	if err != nil {
-		return "", fmt.Errorf("failed to get download link: %w", err)
+		return "", fmt.Errorf("failed to download subtitle from %s: %w", best.Provider, err)
 	}
 
-	content, err := t.osClient.DownloadFile(dl.Link)
+	outPath, err := t.saveSubtitle(item, fileName, content)
 	if err != nil {
-		return "", fmt.Errorf("failed to download subtitle file: %w", err)
-	}
-
-	if err := t.saveSubtitle(item, dl.FileName, content); err != nil {
 		return "", fmt.Errorf("failed to save subtitle: %w", err)
 	}
+	fetched := stream.Fetched{Provider: best.Provider, MatchedBy: best.MatchedBy, Release: best.Release}
+	if err := stream.RecordFetched(filepath.Dir(outPath), outPath, fetched); err != nil {
+		ancli.Warnf("failed to record origin of %v: %v", outPath, err)
+	}
 
-	return fmt.Sprintf("downloaded subtitle for '%s': %s", item.Name, dl.FileName), nil
+	return fmt.Sprintf("downloaded subtitle for '%s': %s", item.Name, fileName), nil
 }
 
 func (t *fetchSubtitlesTool) Specification() models.Specification {
 	return models.Specification{
 		Name:        "fetch_subtitles",
-		Description: "Search OpenSubtitles for subtitles for a media item and download the best match. Only works for movies without existing subtitles. Automatically saves to the configured subtitle cache.",
+		Description: "Search the configured subtitle providers (a local subtitle folder, OpenSubtitles) for subtitles for a media item and download the best match. Only works for movies without existing subtitles. Automatically saves to the configured subtitle cache.",
 		Inputs: &models.InputSchema{
 			Type: "object",
 			Properties: map[string]models.ParameterObject{
@@ -126,35 +163,65 @@ func (t *fetchSubtitlesTool) Specification() models.Specification {
 					Type:        "string",
 					Description: "Alias for ID",
 				},
+				"languages": {
+					Type:        "string",
+					Description: "Optional comma separated subtitle languages, most preferred first, such as a client's subtitleLanguages. Defaults to the server's preferences.",
+				},
 			},
 			Required: []string{},
 		},
 	}
 }
 
-func (t *fetchSubtitlesTool) searchItem(item kinomodel.Item, langs string) (*OpenSubtitlesSearchResponse, error) {
+// query describes item, with streams info, to the subtitle providers.
+func (t *fetchSubtitlesTool) query(item kinomodel.Item, info kinomodel.MediaInfo, langs lang.Preferences) SubtitleQuery {
 	imdbID, tmdbID := extractIDs(item.Metadata)
-
-	// Try IDs first
-	if imdbID != "" || tmdbID != "" {
-		resp, err := t.osClient.Search(imdbID, tmdbID, "", langs, "movie")
-		if err == nil && len(resp.Data) > 0 {
-			return resp, nil
-		}
+	hash := item.MovieHash
+	if hash == "" {
+		// Stored before moviehashes were, or too small to have one.
+		hash, _ = moviehash.Compute(item.Path)
+	}
+	return SubtitleQuery{
+		MovieHash: hash,
+		IMDBID:    imdbID,
+		TMDBID:    tmdbID,
+		Query:     cleanQuery(item.Name),
+		Languages: langs,
+		MediaType: "movie",
+		Release:   strings.TrimSuffix(item.Name, filepath.Ext(item.Name)),
+		FPS:       videoFPS(info),
 	}
+}
 
-	// Fall back to filename-based query
-	query := cleanQuery(item.Name)
-	if query == "" {
-		return nil, fmt.Errorf("no searchable identifiers for item '%s'", item.Name)
+// videoFPS is the frame rate of the first video stream, 0 if unknown.
+func videoFPS(info kinomodel.MediaInfo) float64 {
+	for _, s := range info.Streams {
+		if s.CodecType != "video" {
+			continue
+		}
+		num, den, ok := strings.Cut(s.RFrameRate, "/")
+		n, err := strconv.ParseFloat(num, 64)
+		if err != nil {
+			return 0
+		}
+		if !ok {
+			return n
+		}
+		d, err := strconv.ParseFloat(den, 64)
+		if err != nil || d == 0 {
+			return 0
+		}
+		return n / d
 	}
-	return t.osClient.Search("", "", query, langs, "movie")
+	return 0
 }
 
-func (t *fetchSubtitlesTool) saveSubtitle(item kinomodel.Item, filename string, content []byte) error {
+// saveSubtitle writes the subtitle where findExternal picks it up, returning
+// its path.
+func (t *fetchSubtitlesTool) saveSubtitle(item kinomodel.Item, filename string, content []byte) (string, error) {
 	dir := filepath.Join(t.cacheDir, "subtitles", item.ID)
 	if err := os.MkdirAll(dir, 0o755); err != nil {
-		return fmt.Errorf("failed to create subtitle dir: %w", err)
+		return "", fmt.Errorf("failed to create subtitle dir: %w", err)
 	}
 
 	// Determine extension: prefer .srt for easier discovery by findExternal
@@ -168,35 +235,24 @@ func (t *fetchSubtitlesTool) saveSubtitle(item kinomodel.Item, filename string,
 	outPath := filepath.Join(dir, base+ext)
 
 	if err := os.WriteFile(outPath, content, 0o644); err != nil {
-		return fmt.Errorf("failed to write subtitle file: %w", err)
+		return "", fmt.Errorf("failed to write subtitle file: %w", err)
 	}
 	if t.debug {
 		fmt.Fprintf(os.Stderr, "DEBUG: saved subtitle to %s\n", outPath)
 	}
-	return nil
+	return outPath, nil
 }
 
 // extractIDs attempts to find IMDB/TMDB IDs from item metadata.
-func extractIDs(metadata *json.RawMessage) (imdbID, tmdbID string) {
+func extractIDs(metadata *kinomodel.MediaMetadata) (imdbID, tmdbID string) {
 	if metadata == nil {
 		return "", ""
 	}
-
-	var m map[string]any
-	if err := json.Unmarshal(*metadata, &m); err != nil {
-		return "", ""
-	}
-
-	if id, ok := stringField(m, "imdb_id"); ok {
-		imdbID = id
-	}
-	if id, ok := stringField(m, "tmdb_id"); ok {
-		tmdbID = id
-	}
+	imdbID, tmdbID = metadata.IMDbID, metadata.TMDbID
 
 	// Some classifiers put IMDb ID under "id" with "tt" prefix
 	if imdbID == "" {
-		if id, ok := stringField(m, "id"); ok && strings.HasPrefix(id, "tt") {
+		if id := metadata.ExtraString("id"); strings.HasPrefix(id, "tt") {
 			imdbID = id
 		}
 	}
@@ -204,15 +260,6 @@ func extractIDs(metadata *json.RawMessage) (imdbID, tmdbID string) {
 	return imdbID, tmdbID
 }
 
-func stringField(m map[string]any, key string) (string, bool) {
-	v, ok := m[key]
-	if !ok {
-		return "", false
-	}
-	s, ok := v.(string)
-	return s, ok
-}
-
 // hasSubs checks if any subtitle-type streams exist in the media info.
 func hasSubs(info kinomodel.MediaInfo) bool {
 	for _, s := range info.Streams {
@@ -287,15 +334,3 @@ func cleanQuery(name string) string {
 	result = strings.TrimSpace(result)
 	return result
 }
-
-func firstLang(langs string) string {
-	langs = strings.TrimSpace(langs)
-	if langs == "" {
-		return "en"
-	}
-	parts := strings.Split(langs, ",")
-	if len(parts) > 0 {
-		return strings.TrimSpace(parts[0])
-	}
-	return "en"
-}
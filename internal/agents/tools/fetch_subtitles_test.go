@@ -10,6 +10,8 @@ import (
 	"testing"
 
 	"github.com/baalimago/clai/pkg/text/models"
+	"github.com/baalimago/kinoview/internal/lang"
+	"github.com/baalimago/kinoview/internal/media/stream"
 	kinomodel "github.com/baalimago/kinoview/internal/model"
 )
 
@@ -21,7 +23,7 @@ func TestNewFetchSubtitlesTool(t *testing.T) {
 
 	t.Run("returns nil when API key not set", func(t *testing.T) {
 		os.Unsetenv("OPENSUBTITLES_API_KEY")
-		tool := NewFetchSubtitlesTool(nil, nil, "/tmp")
+		tool := NewFetchSubtitlesTool(nil, nil, "/tmp", nil)
 		if tool != nil {
 			t.Fatal("expected nil when API key is not set")
 		}
@@ -31,7 +33,7 @@ func TestNewFetchSubtitlesTool(t *testing.T) {
 		os.Setenv("OPENSUBTITLES_API_KEY", "key123")
 		ig := &mockItemGetter{}
 		sm := &mockSubtitleManager{}
-		tool := NewFetchSubtitlesTool(ig, sm, "/tmp/cache")
+		tool := NewFetchSubtitlesTool(ig, sm, "/tmp/cache", nil)
 		if tool == nil {
 			t.Fatal("expected non-nil when API key is set")
 		}
@@ -176,7 +178,7 @@ func TestExtractIDs(t *testing.T) {
 	})
 
 	t.Run("imdb_id field", func(t *testing.T) {
-		meta := json.RawMessage(`{"imdb_id":"tt1234567","tmdb_id":"7654321"}`)
+		meta := kinomodel.MediaMetadata{IMDbID: "tt1234567", TMDbID: "7654321"}
 		imdb, tmdb := extractIDs(&meta)
 		if imdb != "tt1234567" {
 			t.Fatalf("imdb: got %q, want 'tt1234567'", imdb)
@@ -187,7 +189,7 @@ func TestExtractIDs(t *testing.T) {
 	})
 
 	t.Run("id field with tt prefix", func(t *testing.T) {
-		meta := json.RawMessage(`{"id":"tt9876543"}`)
+		meta := kinomodel.MediaMetadata{Extra: map[string]json.RawMessage{"id": json.RawMessage(`"tt9876543"`)}}
 		imdb, tmdb := extractIDs(&meta)
 		if imdb != "tt9876543" {
 			t.Fatalf("imdb: got %q, want 'tt9876543'", imdb)
@@ -198,20 +200,12 @@ func TestExtractIDs(t *testing.T) {
 	})
 
 	t.Run("no IDs present", func(t *testing.T) {
-		meta := json.RawMessage(`{"name":"Some Movie","year":2020}`)
+		meta := kinomodel.MediaMetadata{Name: "Some Movie", Year: 2020}
 		imdb, tmdb := extractIDs(&meta)
 		if imdb != "" || tmdb != "" {
 			t.Fatal("expected empty IDs")
 		}
 	})
-
-	t.Run("invalid JSON", func(t *testing.T) {
-		meta := json.RawMessage(`{invalid}`)
-		imdb, tmdb := extractIDs(&meta)
-		if imdb != "" || tmdb != "" {
-			t.Fatal("expected empty IDs for invalid JSON")
-		}
-	})
 }
 
 func TestHasSubs(t *testing.T) {
@@ -289,24 +283,6 @@ func TestCleanQuery(t *testing.T) {
 	}
 }
 
-func TestFirstLang(t *testing.T) {
-	tests := []struct {
-		input    string
-		expected string
-	}{
-		{"en", "en"},
-		{"en,sv", "en"},
-		{"sv,en", "sv"},
-		{"  de , fr  ", "de"},
-		{"", "en"},
-	}
-	for _, tt := range tests {
-		if got := firstLang(tt.input); got != tt.expected {
-			t.Errorf("firstLang(%q) = %q, want %q", tt.input, got, tt.expected)
-		}
-	}
-}
-
 func TestSaveSubtitle(t *testing.T) {
 	tmpDir := t.TempDir()
 	cacheDir := filepath.Join(tmpDir, "cache")
@@ -320,7 +296,7 @@ func TestSaveSubtitle(t *testing.T) {
 	item := kinomodel.Item{ID: "abc123", Name: "movie.mkv"}
 
 	t.Run("saves .srt file", func(t *testing.T) {
-		err := tool.saveSubtitle(item, "movie.srt", []byte("test subtitle"))
+		_, err := tool.saveSubtitle(item, "movie.srt", []byte("test subtitle"))
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -335,7 +311,7 @@ func TestSaveSubtitle(t *testing.T) {
 	})
 
 	t.Run("saves with .srt even when no extension", func(t *testing.T) {
-		err := tool.saveSubtitle(item, "subfile", []byte("test"))
+		_, err := tool.saveSubtitle(item, "subfile", []byte("test"))
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -346,7 +322,7 @@ func TestSaveSubtitle(t *testing.T) {
 	})
 
 	t.Run("preserves .vtt extension", func(t *testing.T) {
-		err := tool.saveSubtitle(item, "sub.vtt", []byte("WEBVTT\n\n"))
+		_, err := tool.saveSubtitle(item, "sub.vtt", []byte("WEBVTT\n\n"))
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -438,7 +414,7 @@ func TestFetchSubtitlesCall_Success(t *testing.T) {
 		subMgr: &mockSubtitleManager{
 			mediaInfo: kinomodel.MediaInfo{}, // No existing subs
 		},
-		osClient: client,
+		provider: NewOpenSubtitlesProvider(client),
 		cacheDir: cacheDir,
 	}
 
@@ -461,3 +437,57 @@ func TestFetchSubtitlesCall_Success(t *testing.T) {
 		t.Fatalf("content mismatch: got %q, want %q", string(data), expectedContent)
 	}
 }
+
+func TestFetchSubtitlesCall_LocalProvider(t *testing.T) {
+	pack := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(pack, "The Matrix (1999)"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	sub := "1\n00:01:00,000 --> 00:02:00,000\nHej\n"
+	if err := os.WriteFile(filepath.Join(pack, "The Matrix (1999)", "matrix.sv.srt"), []byte(sub), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cacheDir := t.TempDir()
+	tool := NewFetchSubtitlesToolWithProvider(
+		&mockItemGetter{item: kinomodel.Item{ID: "movie-1", Name: "The.Matrix.1999.1080p.mkv", MIMEType: "video/x-matroska"}},
+		&mockSubtitleManager{},
+		cacheDir,
+		lang.Preferences{"sv"},
+		NewLocalSubtitleProvider(pack),
+	)
+
+	result, err := tool.Call(models.Input{"ID": "movie-1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != "downloaded subtitle for 'The.Matrix.1999.1080p.mkv': matrix.sv.srt" {
+		t.Fatalf("unexpected result: %q", result)
+	}
+	data, err := os.ReadFile(filepath.Join(cacheDir, "subtitles", "movie-1", "matrix.sv.srt"))
+	if err != nil || string(data) != sub {
+		t.Fatalf("expected the subtitle to be saved, got %q, err: %v", data, err)
+	}
+	data, err = os.ReadFile(filepath.Join(cacheDir, "subtitles", "movie-1", "fetched.json"))
+	if err != nil {
+		t.Fatalf("expected the origin to be recorded: %v", err)
+	}
+	var fetched map[string]stream.Fetched
+	if err := json.Unmarshal(data, &fetched); err != nil {
+		t.Fatal(err)
+	}
+	if f := fetched["matrix.sv.srt"]; f.Provider != "local" || f.MatchedBy != MatchTitle {
+		t.Errorf("unexpected origin: %+v", f)
+	}
+}
+
+func TestVideoFPS(t *testing.T) {
+	for rate, want := range map[string]float64{"24000/1001": 24000.0 / 1001, "25/1": 25, "30": 30, "0/0": 0, "": 0} {
+		info := kinomodel.MediaInfo{Streams: []kinomodel.Stream{
+			{CodecType: "audio", RFrameRate: "0/0"},
+			{CodecType: "video", RFrameRate: rate},
+		}}
+		if got := videoFPS(info); got != want {
+			t.Errorf("videoFPS(%q) = %v, want %v", rate, got, want)
+		}
+	}
+}
@@ -8,15 +8,19 @@ import (
 
 	"github.com/baalimago/clai/pkg/text/models"
 	"github.com/baalimago/kinoview/internal/agents"
+	"github.com/baalimago/kinoview/internal/lang"
 )
 
 type listSubtitleCandidatesTool struct {
 	itemGetter   agents.ItemGetter
 	subMgr       agents.StreamManager
 	subStorePath string
+	langs        lang.Preferences
 }
 
-func NewListSubtitleCandidatesTool(ig agents.ItemGetter, sm agents.StreamManager, subStorePath string) (*listSubtitleCandidatesTool, error) {
+// NewListSubtitleCandidatesTool ranks the candidates by langs, unless the
+// call states its own languages. Defaults to lang.Default.
+func NewListSubtitleCandidatesTool(ig agents.ItemGetter, sm agents.StreamManager, subStorePath string, langs lang.Preferences) (*listSubtitleCandidatesTool, error) {
 	if ig == nil {
 		return nil, fmt.Errorf("item getter can't be nil")
 	}
@@ -27,6 +31,7 @@ func NewListSubtitleCandidatesTool(ig agents.ItemGetter, sm agents.StreamManager
 		itemGetter:   ig,
 		subMgr:       sm,
 		subStorePath: subStorePath,
+		langs:        langs.Or(lang.Default),
 	}, nil
 }
 
@@ -41,20 +46,33 @@ func (t *listSubtitleCandidatesTool) Call(input models.Input) (string, error) {
 		return "", fmt.Errorf("failed to get item: %w", err)
 	}
 
+	langs := t.langs
+	if l, ok := input["languages"].(string); ok {
+		langs = lang.Parse(l).Or(langs)
+	}
+
 	mediaInfo, err := t.subMgr.Find(item)
 	if err != nil {
 		return "", fmt.Errorf("failed to find subtitle info: %w", err)
 	}
 
 	type candidate struct {
-		Index            int    `json:"index"`
-		Codec            string `json:"codec"`
-		Language         string `json:"language"`
-		Title            string `json:"title"`
-		Default          bool   `json:"default"`
-		Forced           bool   `json:"forced"`
-		Comment          bool   `json:"comment"`
-		Source           string `json:"source"`
+		Index    int    `json:"index"`
+		Codec    string `json:"codec"`
+		OCR      bool   `json:"ocr,omitempty"`
+		Language string `json:"language"`
+		// PreferenceRank is the position of Language among the preferred
+		// languages, 0 being the most preferred. Omitted if not preferred.
+		PreferenceRank *int   `json:"preferenceRank,omitempty"`
+		Title          string `json:"title"`
+		Default        bool   `json:"default"`
+		Forced         bool   `json:"forced"`
+		Comment        bool   `json:"comment"`
+		Source         string `json:"source"`
+		// Provider and MatchedBy tell where a fetched subtitle came from, and
+		// if it was matched by moviehash, so made for this very file.
+		Provider         string `json:"provider,omitempty"`
+		MatchedBy        string `json:"matchedBy,omitempty"`
 		AlreadyExtracted bool   `json:"alreadyExtracted"`
 		ExtractedPath    string `json:"extractedPath,omitempty"`
 	}
@@ -64,9 +82,9 @@ func (t *listSubtitleCandidatesTool) Call(input models.Input) (string, error) {
 		if s.CodecType != "subtitle" {
 			continue
 		}
-		lang := s.Tags.Language
-		if lang == "" {
-			lang = "und"
+		tag := s.Tags.Language
+		if tag == "" {
+			tag = "und"
 		}
 		title := s.Tags.Title
 		if title == "" {
@@ -76,6 +94,10 @@ func (t *listSubtitleCandidatesTool) Call(input models.Input) (string, error) {
 		if s.ExternalPath != "" {
 			source = "external"
 		}
+		var rank *int
+		if r := langs.Rank(tag); r >= 0 {
+			rank = &r
+		}
 		extracted := t.isExtracted(item.ID, s.Index)
 		var extractedPath string
 		if extracted {
@@ -84,12 +106,16 @@ func (t *listSubtitleCandidatesTool) Call(input models.Input) (string, error) {
 		candidates = append(candidates, candidate{
 			Index:            s.Index,
 			Codec:            s.CodecName,
-			Language:         lang,
+			OCR:              s.OCR,
+			Language:         tag,
+			PreferenceRank:   rank,
 			Title:            title,
 			Default:          s.Disposition.Default == 1,
 			Forced:           s.Disposition.Forced == 1,
 			Comment:          s.Disposition.Comment == 1,
 			Source:           source,
+			Provider:         s.Provider,
+			MatchedBy:        s.MatchedBy,
 			AlreadyExtracted: extracted,
 			ExtractedPath:    extractedPath,
 		})
@@ -104,7 +130,7 @@ func (t *listSubtitleCandidatesTool) Call(input models.Input) (string, error) {
 		return "", fmt.Errorf("failed to marshal candidates: %w", err)
 	}
 
-	return fmt.Sprintf("subtitle candidates for '%s' (%d found):\n%s", item.Name, len(candidates), string(result)), nil
+	return fmt.Sprintf("subtitle candidates for '%s' (%d found, preferred languages: %s):\n%s", item.Name, len(candidates), langs, string(result)), nil
 }
 
 func (t *listSubtitleCandidatesTool) isExtracted(itemID string, streamIndex int) bool {
@@ -116,7 +142,7 @@ func (t *listSubtitleCandidatesTool) isExtracted(itemID string, streamIndex int)
 func (t *listSubtitleCandidatesTool) Specification() models.Specification {
 	return models.Specification{
 		Name:        "list_subtitle_candidates",
-		Description: "List all subtitle streams (embedded and external) for a media item with metadata and extraction status. Use to discover available subtitles before selecting one to extract.",
+		Description: "List all subtitle streams (embedded and external) for a media item with metadata and extraction status. Use to discover available subtitles before selecting one to extract. Bitmap subtitles marked \"ocr\" are extracted as text read by OCR, which may contain misread characters; prefer text subtitles when there are any.",
 		Inputs: &models.InputSchema{
 			Type: "object",
 			Properties: map[string]models.ParameterObject{
@@ -124,6 +150,10 @@ func (t *listSubtitleCandidatesTool) Specification() models.Specification {
 					Type:        "string",
 					Description: "ID of the media item to list subtitle candidates for",
 				},
+				"languages": {
+					Type:        "string",
+					Description: "Optional comma separated subtitle languages, most preferred first, such as a client's subtitleLanguages. Defaults to the server's preferences.",
+				},
 			},
 			Required: []string{"ID"},
 		},
@@ -8,26 +8,27 @@ import (
 	"testing"
 
 	"github.com/baalimago/clai/pkg/text/models"
+	"github.com/baalimago/kinoview/internal/lang"
 	kinomodel "github.com/baalimago/kinoview/internal/model"
 )
 
 func TestNewListSubtitleCandidatesTool(t *testing.T) {
 	t.Run("nil item getter", func(t *testing.T) {
-		_, err := NewListSubtitleCandidatesTool(nil, &mockSubtitleManager{}, "/tmp")
+		_, err := NewListSubtitleCandidatesTool(nil, &mockSubtitleManager{}, "/tmp", nil)
 		if err == nil {
 			t.Fatal("expected error for nil item getter")
 		}
 	})
 
 	t.Run("nil subtitle manager", func(t *testing.T) {
-		_, err := NewListSubtitleCandidatesTool(&mockItemGetter{}, nil, "/tmp")
+		_, err := NewListSubtitleCandidatesTool(&mockItemGetter{}, nil, "/tmp", nil)
 		if err == nil {
 			t.Fatal("expected error for nil subtitle manager")
 		}
 	})
 
 	t.Run("valid construction", func(t *testing.T) {
-		tool, err := NewListSubtitleCandidatesTool(&mockItemGetter{}, &mockSubtitleManager{}, "/tmp/subs")
+		tool, err := NewListSubtitleCandidatesTool(&mockItemGetter{}, &mockSubtitleManager{}, "/tmp/subs", nil)
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -51,7 +52,7 @@ func TestListSubtitleCandidatesCall(t *testing.T) {
 				},
 			},
 		}
-		tool, _ := NewListSubtitleCandidatesTool(ig, sm, "/tmp")
+		tool, _ := NewListSubtitleCandidatesTool(ig, sm, "/tmp", nil)
 		resp, err := tool.Call(models.Input{"ID": "test-id"})
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
@@ -109,7 +110,7 @@ func TestListSubtitleCandidatesCall(t *testing.T) {
 			},
 		}
 
-		tool, _ := NewListSubtitleCandidatesTool(ig, sm, subStorePath)
+		tool, _ := NewListSubtitleCandidatesTool(ig, sm, subStorePath, nil)
 		resp, err := tool.Call(models.Input{"ID": "test-id"})
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
@@ -191,7 +192,7 @@ func TestListSubtitleCandidatesCall(t *testing.T) {
 
 	t.Run("missing ID", func(t *testing.T) {
 		sm := &mockSubtitleManager{}
-		tool, _ := NewListSubtitleCandidatesTool(ig, sm, "/tmp")
+		tool, _ := NewListSubtitleCandidatesTool(ig, sm, "/tmp", nil)
 		_, err := tool.Call(models.Input{})
 		if err == nil {
 			t.Fatal("expected error for missing ID")
@@ -204,7 +205,7 @@ func TestListSubtitleCandidatesCall(t *testing.T) {
 
 	t.Run("item not found", func(t *testing.T) {
 		sm := &mockSubtitleManager{}
-		tool, _ := NewListSubtitleCandidatesTool(ig, sm, "/tmp")
+		tool, _ := NewListSubtitleCandidatesTool(ig, sm, "/tmp", nil)
 		_, err := tool.Call(models.Input{"ID": "nonexistent"})
 		if err == nil {
 			t.Fatal("expected error when item not found")
@@ -213,7 +214,7 @@ func TestListSubtitleCandidatesCall(t *testing.T) {
 
 	t.Run("stream manager error", func(t *testing.T) {
 		sm := &mockSubtitleManager{err: errSentinel}
-		tool, _ := NewListSubtitleCandidatesTool(ig, sm, "/tmp")
+		tool, _ := NewListSubtitleCandidatesTool(ig, sm, "/tmp", nil)
 		_, err := tool.Call(models.Input{"ID": "test-id"})
 		if err == nil {
 			t.Fatal("expected error when Find fails")
@@ -233,7 +234,7 @@ func TestListSubtitleCandidatesCall(t *testing.T) {
 				},
 			},
 		}
-		tool, _ := NewListSubtitleCandidatesTool(ig, sm, t.TempDir())
+		tool, _ := NewListSubtitleCandidatesTool(ig, sm, t.TempDir(), nil)
 		resp, _ := tool.Call(models.Input{"ID": "test-id"})
 
 		jsonStart := strings.Index(resp, "[")
@@ -289,3 +290,51 @@ func TestListSubtitleCandidatesIsExtracted(t *testing.T) {
 		t.Error("different stream index should not be extracted")
 	}
 }
+
+func TestListSubtitleCandidatesCall_PreferenceRank(t *testing.T) {
+	ig := &mockItemGetter{item: kinomodel.Item{ID: "test-id", Name: "Test Movie"}}
+	sm := &mockSubtitleManager{
+		mediaInfo: kinomodel.MediaInfo{
+			Streams: []kinomodel.Stream{
+				{Index: 2, CodecType: "subtitle", Tags: kinomodel.Tags{Language: "eng"}},
+				{Index: 3, CodecType: "subtitle", Tags: kinomodel.Tags{Language: "swe"}},
+				{Index: 4, CodecType: "subtitle", Tags: kinomodel.Tags{Language: "fre"}},
+			},
+		},
+	}
+	tool, _ := NewListSubtitleCandidatesTool(ig, sm, t.TempDir(), lang.Preferences{"en"})
+
+	ranks := func(t *testing.T, input models.Input) map[int]*int {
+		t.Helper()
+		resp, err := tool.Call(input)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var candidates []struct {
+			Index          int  `json:"index"`
+			PreferenceRank *int `json:"preferenceRank"`
+		}
+		if err := json.Unmarshal([]byte(resp[strings.Index(resp, "["):]), &candidates); err != nil {
+			t.Fatalf("failed to unmarshal candidates JSON: %v", err)
+		}
+		got := make(map[int]*int)
+		for _, c := range candidates {
+			got[c.Index] = c.PreferenceRank
+		}
+		return got
+	}
+
+	t.Run("server preferences", func(t *testing.T) {
+		got := ranks(t, models.Input{"ID": "test-id"})
+		if got[2] == nil || *got[2] != 0 || got[3] != nil || got[4] != nil {
+			t.Fatalf("unexpected ranks: %v", got)
+		}
+	})
+
+	t.Run("languages input overrides", func(t *testing.T) {
+		got := ranks(t, models.Input{"ID": "test-id", "languages": "sv, en"})
+		if got[3] == nil || *got[3] != 0 || got[2] == nil || *got[2] != 1 || got[4] != nil {
+			t.Fatalf("unexpected ranks: %v", got)
+		}
+	})
+}
@@ -0,0 +1,128 @@
+package tools
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/baalimago/kinoview/internal/lang"
+)
+
+// localSubtitleProvider finds subtitles in a folder, such as a shared folder
+// of subtitle packs. Files match by IMDb ID or by title anywhere in their
+// path, so both 'tt0133093/en.srt' and 'The Matrix (1999)/English.srt' do.
+type localSubtitleProvider struct {
+	root string
+}
+
+// NewLocalSubtitleProvider creates a SubtitleProvider of the .srt and .vtt
+// files below root. It's never rate limited.
+func NewLocalSubtitleProvider(root string) SubtitleProvider {
+	return &localSubtitleProvider{root: root}
+}
+
+func (p *localSubtitleProvider) Name() string {
+	return "local"
+}
+
+func (p *localSubtitleProvider) Search(q SubtitleQuery) ([]SubtitleCandidate, error) {
+	imdbID := strings.ToLower(q.IMDBID)
+	queryWords := words(q.Query)
+	if imdbID == "" && len(queryWords) == 0 {
+		return nil, fmt.Errorf("no searchable identifiers")
+	}
+
+	var candidates []SubtitleCandidate
+	err := filepath.WalkDir(p.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if d.IsDir() || (ext != ".srt" && ext != ".vtt") {
+			return nil
+		}
+		rel, err := filepath.Rel(p.root, path)
+		if err != nil {
+			return nil
+		}
+		relWords := words(strings.TrimSuffix(rel, filepath.Ext(rel)))
+		matched := imdbID != "" && slices.Contains(relWords, imdbID)
+		matchedBy := MatchID
+		if !matched && len(queryWords) > 0 {
+			matched, matchedBy = true, MatchTitle
+			for _, w := range queryWords {
+				if !slices.Contains(relWords, w) {
+					matched = false
+					break
+				}
+			}
+		}
+		if matched {
+			candidates = append(candidates, SubtitleCandidate{
+				Provider: p.Name(),
+				ID:       filepath.ToSlash(rel),
+				FileName: filepath.Base(rel),
+				Language: fileLanguage(rel),
+				// The folder names the release in packs such as
+				// 'The.Matrix.1999.1080p.BluRay/English.srt'.
+				Release:   strings.TrimSuffix(rel, filepath.Ext(rel)),
+				MatchedBy: matchedBy,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search '%s': %w", p.root, err)
+	}
+	return candidates, nil
+}
+
+func (p *localSubtitleProvider) Download(c SubtitleCandidate) (string, []byte, error) {
+	rel := filepath.FromSlash(c.ID)
+	if !filepath.IsLocal(rel) {
+		return "", nil, fmt.Errorf("subtitle outside of '%s': %q", p.root, c.ID)
+	}
+	content, err := os.ReadFile(filepath.Join(p.root, rel))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read subtitle: %w", err)
+	}
+	return filepath.Base(rel), content, nil
+}
+
+func (p *localSubtitleProvider) RateLimit() RateLimit {
+	return RateLimit{}
+}
+
+// words splits s into its lowercased words, so that "The.Matrix (1999)"
+// and "the matrix 1999" compare equal.
+func words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// fileLanguage guesses the language of a subtitle from its path, such as
+// "movie.sv.srt" or "English/movie.srt". Returns "" if there's no telling.
+func fileLanguage(rel string) string {
+	name := strings.TrimSuffix(filepath.Base(rel), filepath.Ext(rel))
+	nameWords := words(name)
+	for i := len(nameWords) - 1; i >= 0; i-- {
+		w := nameWords[i]
+		// Two letter codes are too ambiguous ("it", "no") unless they end
+		// the name.
+		if len(w) == 2 && i < len(nameWords)-1 {
+			continue
+		}
+		if lang.Known(w) {
+			return lang.Normalize(w)
+		}
+	}
+	if dir := filepath.Base(filepath.Dir(rel)); lang.Known(dir) {
+		return lang.Normalize(dir)
+	}
+	return ""
+}
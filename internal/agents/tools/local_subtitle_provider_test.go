@@ -0,0 +1,98 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/baalimago/kinoview/internal/lang"
+)
+
+func localFixture(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	for _, p := range []string{
+		"The Matrix (1999)/The.Matrix.1999.sv.srt",
+		"The Matrix (1999)/English/matrix.srt",
+		"The Matrix (1999)/poster.jpg",
+		"tt0133093/subs.vtt",
+		"Inception (2010)/Inception.en.srt",
+	} {
+		full := filepath.Join(root, p)
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(p), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestLocalSubtitleProvider_Search(t *testing.T) {
+	p := NewLocalSubtitleProvider(localFixture(t))
+
+	found, err := p.Search(SubtitleQuery{IMDBID: "tt0133093", Query: "The Matrix 1999"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids, langs []string
+	for _, c := range found {
+		ids = append(ids, c.ID)
+		langs = append(langs, c.Language)
+		wantMatch := MatchTitle
+		if c.ID == "tt0133093/subs.vtt" {
+			wantMatch = MatchID
+		}
+		if c.MatchedBy != wantMatch {
+			t.Errorf("%s: matched by %q, want %q", c.ID, c.MatchedBy, wantMatch)
+		}
+	}
+	slices.Sort(ids)
+	slices.Sort(langs)
+	wantIDs := []string{"The Matrix (1999)/English/matrix.srt", "The Matrix (1999)/The.Matrix.1999.sv.srt", "tt0133093/subs.vtt"}
+	if !slices.Equal(ids, wantIDs) {
+		t.Errorf("ids = %v, want %v", ids, wantIDs)
+	}
+	if want := []string{"", "en", "sv"}; !slices.Equal(langs, want) {
+		t.Errorf("languages = %v, want %v", langs, want)
+	}
+
+	if _, err := p.Search(SubtitleQuery{}); err == nil {
+		t.Error("expected an error without identifiers")
+	}
+}
+
+func TestLocalSubtitleProvider_Download(t *testing.T) {
+	p := NewLocalSubtitleProvider(localFixture(t))
+	found, err := p.Search(SubtitleQuery{Query: "inception", Languages: lang.Preferences{"en"}})
+	if err != nil || len(found) != 1 {
+		t.Fatalf("expected one match, got %+v, err: %v", found, err)
+	}
+	name, content, err := p.Download(found[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "Inception.en.srt" || string(content) != "Inception (2010)/Inception.en.srt" {
+		t.Errorf("unexpected download: %q, %q", name, content)
+	}
+
+	if _, _, err := p.Download(SubtitleCandidate{ID: "../secret.srt"}); err == nil {
+		t.Error("expected paths outside the root to be refused")
+	}
+}
+
+func TestFileLanguage(t *testing.T) {
+	for path, want := range map[string]string{
+		"movie.sv.srt":         "sv",
+		"5_English.srt":        "en",
+		"It.Follows.2014.srt":  "",
+		"Svenska/movie.srt":    "sv",
+		"movie/Movie.2000.srt": "",
+	} {
+		if got := fileLanguage(path); got != want {
+			t.Errorf("fileLanguage(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
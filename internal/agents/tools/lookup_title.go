@@ -0,0 +1,89 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/baalimago/clai/pkg/text/models"
+	"github.com/baalimago/kinoview/internal/agents"
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+// maxTitleMatches bounds the answer to a lookup by title, common titles
+// have dozens of matches.
+const maxTitleMatches = 10
+
+type lookupTitleTool struct {
+	provider agents.MetadataProvider
+}
+
+func NewLookupTitleTool(p agents.MetadataProvider) (*lookupTitleTool, error) {
+	if p == nil {
+		return nil, errors.New("metadata provider can't be nil")
+	}
+	return &lookupTitleTool{provider: p}, nil
+}
+
+func (t *lookupTitleTool) Call(input models.Input) (string, error) {
+	if id, _ := input["id"].(string); id != "" {
+		md, err := t.provider.LookupID(id)
+		if errors.Is(err, model.ErrNoTitle) {
+			return fmt.Sprintf("no title with ID %q in the dataset", id), nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to look up %q: %w", id, err)
+		}
+		return marshalMatches([]model.MediaMetadata{md})
+	}
+
+	title, _ := input["title"].(string)
+	if title == "" {
+		return "", fmt.Errorf("title or id must be a non-empty string")
+	}
+	year := 0
+	if y, ok := input["year"].(float64); ok {
+		year = int(y)
+	}
+	matches, err := t.provider.LookupTitle(title, year)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up %q: %w", title, err)
+	}
+	if len(matches) == 0 {
+		return fmt.Sprintf("no title named %q in the dataset", title), nil
+	}
+	return marshalMatches(matches[:min(len(matches), maxTitleMatches)])
+}
+
+func marshalMatches(matches []model.MediaMetadata) (string, error) {
+	b, err := json.Marshal(matches)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (t *lookupTitleTool) Specification() models.Specification {
+	return models.Specification{
+		Name:        "lookup_title",
+		Description: "Look up a movie or show in the local title dataset, by its title and optionally year, or by its ID (such as the IMDb ID). Answers the metadata of every match, which is reliable: prefer it over searching the web.",
+		Inputs: &models.InputSchema{
+			Type: "object",
+			Properties: map[string]models.ParameterObject{
+				"title": {
+					Type:        "string",
+					Description: "Title of the movie or show, case and punctuation don't matter.",
+				},
+				"year": {
+					Type:        "integer",
+					Description: "Release year, to tell titles of the same name apart.",
+				},
+				"id": {
+					Type:        "string",
+					Description: "ID of the title, such as tt0113277. Used instead of the title if given.",
+				},
+			},
+			Required: []string{},
+		},
+	}
+}
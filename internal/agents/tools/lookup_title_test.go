@@ -0,0 +1,84 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/baalimago/clai/pkg/text/models"
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+type fakeMetadataProvider struct {
+	gotTitle string
+	gotYear  int
+	matches  []model.MediaMetadata
+}
+
+func (f *fakeMetadataProvider) LookupTitle(title string, year int) ([]model.MediaMetadata, error) {
+	f.gotTitle, f.gotYear = title, year
+	return f.matches, nil
+}
+
+func (f *fakeMetadataProvider) LookupID(id string) (model.MediaMetadata, error) {
+	for _, md := range f.matches {
+		if md.IMDbID == id {
+			return md, nil
+		}
+	}
+	return model.MediaMetadata{}, fmt.Errorf("%w: %q", model.ErrNoTitle, id)
+}
+
+func TestNewLookupTitleTool_NilProvider(t *testing.T) {
+	if _, err := NewLookupTitleTool(nil); err == nil {
+		t.Fatal("expected error for nil provider")
+	}
+}
+
+func TestLookupTitleTool_Call(t *testing.T) {
+	p := &fakeMetadataProvider{matches: []model.MediaMetadata{{Name: "Heat", Year: 1995, IMDbID: "tt0113277"}}}
+	tool, err := NewLookupTitleTool(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := tool.Call(models.Input{"title": "Heat", "year": float64(1995)})
+	if err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+	if p.gotTitle != "Heat" || p.gotYear != 1995 {
+		t.Errorf("looked up %q %v", p.gotTitle, p.gotYear)
+	}
+	if !strings.Contains(got, `"imdb_id":"tt0113277"`) {
+		t.Errorf("expected the match, got %v", got)
+	}
+
+	got, err = tool.Call(models.Input{"id": "tt0113277"})
+	if err != nil || !strings.Contains(got, `"name":"Heat"`) {
+		t.Errorf("expected the match by ID, got %v, %v", got, err)
+	}
+
+	got, err = tool.Call(models.Input{"id": "tt0000000"})
+	if err != nil || !strings.Contains(got, "no title with ID") {
+		t.Errorf("expected no match to be an answer, got %v, %v", got, err)
+	}
+
+	if _, err := tool.Call(models.Input{}); err == nil {
+		t.Error("expected error without title or id")
+	}
+}
+
+func TestLookupTitleTool_Call_capped(t *testing.T) {
+	p := &fakeMetadataProvider{}
+	for n := range maxTitleMatches + 5 {
+		p.matches = append(p.matches, model.MediaMetadata{Name: "Heat", Year: 1900 + n})
+	}
+	tool, _ := NewLookupTitleTool(p)
+	got, err := tool.Call(models.Input{"title": "Heat"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(got, `"name":"Heat"`); n != maxTitleMatches {
+		t.Errorf("expected %v matches, got %v", maxTitleMatches, n)
+	}
+}
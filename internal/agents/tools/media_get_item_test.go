@@ -9,7 +9,7 @@ import (
 )
 
 func TestMediaGetItemTool_SummaryMode(t *testing.T) {
-	metadata := json.RawMessage(`{"title":"Test Movie","year":2024}`)
+	metadata := model.MediaMetadata{Name: "Test Movie", Year: 2024}
 	item := model.Item{
 		ID:       "test-id-123",
 		Name:     "test_movie.mp4",
@@ -60,7 +60,7 @@ func TestMediaGetItemTool_SummaryMode(t *testing.T) {
 }
 
 func TestMediaGetItemTool_FullMode(t *testing.T) {
-	metadata := json.RawMessage(`{"title":"Full Test","duration":7200}`)
+	metadata := model.MediaMetadata{Name: "Full Test", Extra: map[string]json.RawMessage{"duration": json.RawMessage(`7200`)}}
 	item := model.Item{
 		ID:       "full-test-id",
 		Name:     "full_test.mkv",
@@ -168,7 +168,7 @@ func TestMediaGetItemTool_InvalidMode(t *testing.T) {
 }
 
 func TestMediaGetItemTool_DefaultModeSummary(t *testing.T) {
-	metadata := json.RawMessage(`{"test":"data"}`)
+	metadata := model.MediaMetadata{Extra: map[string]json.RawMessage{"test": json.RawMessage(`"data"`)}}
 	item := model.Item{
 		ID:       "default-mode-id",
 		Name:     "default.mp4",
@@ -243,7 +243,7 @@ func TestMediaStatsTool_EmptyLibrary(t *testing.T) {
 }
 
 func TestMediaStatsTool_BasicCounts(t *testing.T) {
-	metadata := json.RawMessage(`{"title":"test"}`)
+	metadata := model.MediaMetadata{Name: "test"}
 	items := []model.Item{
 		{ID: "1", Name: "video1.mp4", MIMEType: "video/mp4", Metadata: &metadata},
 		{ID: "2", Name: "video2.mkv", MIMEType: "video/x-matroska", Metadata: &metadata},
@@ -378,7 +378,7 @@ func TestMediaStatsTool_UnknownMIMEType(t *testing.T) {
 }
 
 func TestMediaStatsTool_AllWithMetadata(t *testing.T) {
-	metadata := json.RawMessage(`{"title":"test"}`)
+	metadata := model.MediaMetadata{Name: "test"}
 	items := []model.Item{
 		{ID: "1", Name: "item1.mp4", MIMEType: "video/mp4", Metadata: &metadata},
 		{ID: "2", Name: "item2.jpg", MIMEType: "image/jpeg", Metadata: &metadata},
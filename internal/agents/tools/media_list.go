@@ -55,6 +55,13 @@ func (t *mediaListTool) Call(input models.Input) (string, error) {
 	mimeTypeFilter, _ := input["mimeType"].(string)
 	mimeTypeFilter = strings.TrimSpace(strings.ToLower(mimeTypeFilter))
 
+	tags := model.TagFilter{
+		Genres:          stringListInput(input, "genre"),
+		Moods:           stringListInput(input, "mood"),
+		AgeRatings:      stringListInput(input, "ageRating"),
+		WithoutWarnings: stringListInput(input, "withoutWarnings"),
+	}
+
 	hasMetadata := ""
 	if v, ok := input["hasMetadata"].(bool); ok {
 		if v {
@@ -64,6 +71,7 @@ func (t *mediaListTool) Call(input models.Input) (string, error) {
 		}
 	}
 
+	gate, _ := t.lister.(agents.ContentGate)
 	items := t.lister.Snapshot()
 	filtered := make([]model.Item, 0, len(items))
 	for _, it := range items {
@@ -81,6 +89,13 @@ func (t *mediaListTool) Call(input models.Input) (string, error) {
 			}
 		}
 
+		if !tags.Matches(it) {
+			continue
+		}
+		if gate != nil && !gate.AllowedForAgents(it) {
+			continue
+		}
+
 		switch hasMetadata {
 		case "true":
 			if it.Metadata == nil {
@@ -134,7 +149,7 @@ func (t *mediaListTool) Call(input models.Input) (string, error) {
 func (t *mediaListTool) Specification() models.Specification {
 	return models.Specification{
 		Name:        "media_list",
-		Description: "List media library items with pagination, optional global search and mime filters.",
+		Description: "List media library items with pagination, optional global search, mime and tag filters.",
 		Inputs: &models.InputSchema{
 			Type: "object",
 			Properties: map[string]models.ParameterObject{
@@ -150,6 +165,22 @@ func (t *mediaListTool) Specification() models.Specification {
 					Type:        "string",
 					Description: "Optional mime prefix filter (e.g. 'video', 'image').",
 				},
+				"genre": {
+					Type:        "string",
+					Description: "Optional comma separated genres, items of any of them are listed (e.g. 'comedy, family').",
+				},
+				"mood": {
+					Type:        "string",
+					Description: "Optional comma separated moods, items of any of them are listed (e.g. 'uplifting').",
+				},
+				"ageRating": {
+					Type:        "string",
+					Description: "Optional comma separated age ratings, items of any of them are listed (e.g. 'G, PG').",
+				},
+				"withoutWarnings": {
+					Type:        "string",
+					Description: "Optional comma separated content warnings, items with any of them are left out (e.g. 'violence').",
+				},
 				"hasMetadata": {
 					Type:        "boolean",
 					Description: "Optional filter on metadata presence.",
@@ -174,3 +205,9 @@ func (t *mediaListTool) Specification() models.Specification {
 
 // sanity compile-time guard; unused in current implementation but kept for future richer filters.
 var _ = fmt.Sprintf
+
+// stringListInput is the comma separated input of key, nil if there's none.
+func stringListInput(input models.Input, key string) []string {
+	s, _ := input[key].(string)
+	return model.ParseTagList(s)
+}
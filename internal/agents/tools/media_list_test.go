@@ -72,9 +72,9 @@ func TestMediaListTool_BasicPaginationAndFilters(t *testing.T) {
 }
 
 func TestMediaListTool_GlobalSearchWithMetadata(t *testing.T) {
-	metadata1 := json.RawMessage(`{"title":"Inception","director":"Christopher Nolan","year":2010}`)
-	metadata2 := json.RawMessage(`{"title":"The Matrix","director":"Wachowski","year":1999}`)
-	metadata3 := json.RawMessage(`{"tags":["action","sci-fi"],"description":"A great action movie"}`)
+	metadata1 := model.MediaMetadata{Name: "Inception", Year: 2010, Extra: map[string]json.RawMessage{"director": json.RawMessage(`"Christopher Nolan"`)}}
+	metadata2 := model.MediaMetadata{Name: "The Matrix", Year: 1999, Extra: map[string]json.RawMessage{"director": json.RawMessage(`"Wachowski"`)}}
+	metadata3 := model.MediaMetadata{Description: "A great action movie", Extra: map[string]json.RawMessage{"tags": json.RawMessage(`["action","sci-fi"]`)}}
 
 	l := &mockItemLister{items: []model.Item{
 		{ID: "1", Name: "inception.mp4", Path: "/movies/inception.mp4", MIMEType: "video/mp4", Metadata: &metadata1},
@@ -154,3 +154,72 @@ func TestMediaListTool_GlobalSearchWithMetadata(t *testing.T) {
 		t.Fatalf("expected only item 1 (inception); got total=%d items=%v", resp.Total, resp.Items)
 	}
 }
+
+func TestMediaListTool_TagFilters(t *testing.T) {
+	l := &mockItemLister{items: []model.Item{
+		{ID: "1", Name: "Heat", MIMEType: "video/mp4", Metadata: &model.MediaMetadata{Genre: "Crime", AgeRating: "R", ContentWarnings: []string{"violence"}}},
+		{ID: "2", Name: "Paddington", MIMEType: "video/mp4", Metadata: &model.MediaMetadata{Genre: "Family", Mood: []string{"uplifting"}, AgeRating: "PG"}},
+	}}
+	tool, err := NewMediaListTool(l)
+	if err != nil {
+		t.Fatalf("NewMediaListTool: %v", err)
+	}
+
+	for _, in := range []models.Input{
+		{"genre": "family, comedy"},
+		{"mood": "uplifting"},
+		{"ageRating": "G,PG"},
+		{"withoutWarnings": "violence"},
+	} {
+		respStr, err := tool.Call(in)
+		if err != nil {
+			t.Fatalf("Call %v: %v", in, err)
+		}
+		var resp struct {
+			Items []struct {
+				ID string `json:"id"`
+			} `json:"items"`
+		}
+		if err := json.Unmarshal([]byte(respStr), &resp); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		if len(resp.Items) != 1 || resp.Items[0].ID != "2" {
+			t.Errorf("%v: expected only item 2, got %v", in, resp.Items)
+		}
+	}
+}
+
+type gatedItemLister struct {
+	mockItemLister
+}
+
+func (g *gatedItemLister) AllowedForAgents(it model.Item) bool {
+	return it.Metadata != nil && it.Metadata.AgeRating != "R"
+}
+
+func TestMediaListTool_ContentGate(t *testing.T) {
+	l := &gatedItemLister{mockItemLister{items: []model.Item{
+		{ID: "1", Name: "Heat", MIMEType: "video/mp4", Metadata: &model.MediaMetadata{AgeRating: "R"}},
+		{ID: "2", Name: "Paddington", MIMEType: "video/mp4", Metadata: &model.MediaMetadata{AgeRating: "PG"}},
+	}}}
+	tool, err := NewMediaListTool(l)
+	if err != nil {
+		t.Fatalf("NewMediaListTool: %v", err)
+	}
+	respStr, err := tool.Call(models.Input{})
+	if err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+	var resp struct {
+		Total int `json:"total"`
+		Items []struct {
+			ID string `json:"id"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(respStr), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if resp.Total != 1 || len(resp.Items) != 1 || resp.Items[0].ID != "2" {
+		t.Errorf("expected only item 2, got %+v", resp)
+	}
+}
@@ -47,12 +47,12 @@ func TestMediaStatsTool_Call_Empty(t *testing.T) {
 func TestMediaStatsTool_Call_Counts(t *testing.T) {
 	t.Parallel()
 
-	meta := json.RawMessage(`{"title":"x"}`)
+	meta := model.MediaMetadata{Name: "x"}
 	items := []model.Item{
-		{ID: "1", Name: "V", MIMEType: "video/mp4", Metadata: (*json.RawMessage)(&meta)},
+		{ID: "1", Name: "V", MIMEType: "video/mp4", Metadata: &meta},
 		{ID: "2", Name: "I", MIMEType: "image/jpeg", Metadata: nil},
 		{ID: "3", Name: "U", MIMEType: "", Metadata: nil},
-		{ID: "4", Name: "O", MIMEType: "application/pdf", Metadata: (*json.RawMessage)(&meta)},
+		{ID: "4", Name: "O", MIMEType: "application/pdf", Metadata: &meta},
 	}
 
 	tool, err := NewMediaStatsTool(&mockItemLister{items: items})
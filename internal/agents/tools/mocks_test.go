@@ -9,12 +9,14 @@ import (
 type mockMetadataManager struct {
 	updatedItem     model.Item
 	updatedMetadata string
+	updatedBy       model.Provenance
 	err             error
 }
 
-func (m *mockMetadataManager) UpdateMetadata(item model.Item, metadata string) error {
+func (m *mockMetadataManager) UpdateMetadata(item model.Item, metadata string, by model.Provenance) error {
 	m.updatedItem = item
 	m.updatedMetadata = metadata
+	m.updatedBy = by
 	return m.err
 }
 
@@ -45,6 +47,7 @@ func (m *mockItemLister) Snapshot() []model.Item {
 type mockSubtitleManager struct {
 	mediaInfo     model.MediaInfo
 	extractedPath string
+	syncedPath    string
 	err           error
 }
 
@@ -56,6 +59,10 @@ func (m *mockSubtitleManager) ExtractSubtitles(item model.Item, streamIndex stri
 	return m.extractedPath, m.err
 }
 
+func (m *mockSubtitleManager) SyncSubtitles(item model.Item, streamIndex string) (string, error) {
+	return m.syncedPath, m.err
+}
+
 type mockSuggestionManager struct {
 	suggestions []model.Suggestion
 	removedID   string
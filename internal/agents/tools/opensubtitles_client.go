@@ -3,14 +3,17 @@ package tools
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
 	"strconv"
-	"strings"
+	"sync"
 	"time"
+
+	"github.com/baalimago/kinoview/internal/lang"
 )
 
 // OpenSubtitlesClient wraps the OpenSubtitles.com REST API.
@@ -32,10 +35,14 @@ type OpenSubtitlesFile struct {
 
 // OpenSubtitlesAttributes holds metadata about a subtitle entry.
 type OpenSubtitlesAttributes struct {
-	Language      string              `json:"language"`
-	DownloadCount int                 `json:"download_count"`
-	Release       string              `json:"release"`
-	Files         []OpenSubtitlesFile `json:"files"`
+	Language      string  `json:"language"`
+	DownloadCount int     `json:"download_count"`
+	Release       string  `json:"release"`
+	FPS           float64 `json:"fps"`
+	// MovieHashMatch is set when searching by moviehash, on the results
+	// uploaded for the very same video file.
+	MovieHashMatch bool                `json:"moviehash_match"`
+	Files          []OpenSubtitlesFile `json:"files"`
 }
 
 // OpenSubtitlesData is a single search result.
@@ -58,6 +65,8 @@ type OpenSubtitlesDownloadResponse struct {
 	FileName  string `json:"file_name"`
 	FileSize  int    `json:"file_size"`
 	Remaining int    `json:"remaining"`
+	// ResetTimeUTC is when Remaining is replenished, in RFC 3339.
+	ResetTimeUTC string `json:"reset_time_utc"`
 }
 
 // NewOpenSubtitlesClient creates a client from environment variables.
@@ -98,6 +107,22 @@ func (c *OpenSubtitlesClient) Search(imdbID, tmdbID, query string, languages str
 	if mediaType != "" {
 		params.Set("type", mediaType)
 	}
+	return c.search(params)
+}
+
+// SearchMovieHash searches subtitles by the moviehash of a video file, see
+// package moviehash. Subtitles synced to that very file have MovieHashMatch
+// set, the others are for the same title.
+func (c *OpenSubtitlesClient) SearchMovieHash(hash, languages string) (*OpenSubtitlesSearchResponse, error) {
+	params := url.Values{}
+	params.Set("moviehash", hash)
+	if languages != "" {
+		params.Set("languages", languages)
+	}
+	return c.search(params)
+}
+
+func (c *OpenSubtitlesClient) search(params url.Values) (*OpenSubtitlesSearchResponse, error) {
 	params.Set("page", "1")
 
 	reqURL := c.baseURL + "/subtitles?" + params.Encode()
@@ -183,19 +208,23 @@ func (c *OpenSubtitlesClient) DownloadFile(link string) ([]byte, error) {
 	return io.ReadAll(resp.Body)
 }
 
-// BestFile selects the most appropriate subtitle file from search results.
-// Currently picks the first result with most downloads, preferring exact
-// language match.
-func BestFile(data []OpenSubtitlesData, preferredLang string) *OpenSubtitlesFile {
+// BestFile selects the most appropriate subtitle file from search results:
+// the one with most downloads, in the most preferred language available.
+func BestFile(data []OpenSubtitlesData, langs lang.Preferences) *OpenSubtitlesFile {
 	var best *OpenSubtitlesFile
+	bestRank := len(langs)
 	bestDownloads := -1
 
 	for i := range data {
 		d := &data[i]
-		lang := strings.ToLower(d.Attributes.Language)
-		if !strings.HasPrefix(lang, strings.ToLower(preferredLang)) {
+		rank := langs.Rank(d.Attributes.Language)
+		if rank < 0 || rank > bestRank {
 			continue
 		}
+		if rank < bestRank {
+			bestRank = rank
+			bestDownloads = -1
+		}
 		for j := range d.Attributes.Files {
 			f := &d.Attributes.Files[j]
 			if d.Attributes.DownloadCount > bestDownloads {
@@ -207,12 +236,103 @@ func BestFile(data []OpenSubtitlesData, preferredLang string) *OpenSubtitlesFile
 	return best
 }
 
-// SubtitleLanguages returns the configured languages from env.
-// Defaults to "en".
-func SubtitleLanguages() string {
-	langs := os.Getenv("KINOVIEW_SUBTITLE_LANGUAGES")
-	if langs == "" {
-		return "en"
+// openSubtitlesProvider is the SubtitleProvider of OpenSubtitles.com.
+type openSubtitlesProvider struct {
+	client *OpenSubtitlesClient
+
+	mu        sync.Mutex
+	rateLimit RateLimit
+}
+
+// NewOpenSubtitlesProvider creates a SubtitleProvider searching and
+// downloading through client.
+func NewOpenSubtitlesProvider(client *OpenSubtitlesClient) SubtitleProvider {
+	return &openSubtitlesProvider{client: client}
+}
+
+func (p *openSubtitlesProvider) Name() string {
+	return "opensubtitles"
+}
+
+// Search by moviehash if known, then by IMDb or TMDB ID, falling back to
+// the query if those yield nothing.
+func (p *openSubtitlesProvider) Search(q SubtitleQuery) ([]SubtitleCandidate, error) {
+	langs := q.Languages.String()
+	var resp *OpenSubtitlesSearchResponse
+	matchedBy := MatchID
+	if q.MovieHash != "" {
+		r, err := p.client.SearchMovieHash(q.MovieHash, langs)
+		if err == nil && len(r.Data) > 0 {
+			resp = r
+		}
+	}
+	if resp == nil && (q.IMDBID != "" || q.TMDBID != "") {
+		r, err := p.client.Search(q.IMDBID, q.TMDBID, "", langs, q.MediaType)
+		if err == nil && len(r.Data) > 0 {
+			resp = r
+		}
 	}
-	return strings.TrimSpace(langs)
+	if resp == nil {
+		if q.Query == "" {
+			return nil, errors.New("no searchable identifiers")
+		}
+		r, err := p.client.Search("", "", q.Query, langs, q.MediaType)
+		if err != nil {
+			return nil, err
+		}
+		resp, matchedBy = r, MatchTitle
+	}
+
+	var candidates []SubtitleCandidate
+	for _, d := range resp.Data {
+		by := matchedBy
+		if d.Attributes.MovieHashMatch {
+			by = MatchMovieHash
+		}
+		for _, f := range d.Attributes.Files {
+			candidates = append(candidates, SubtitleCandidate{
+				Provider:  p.Name(),
+				ID:        strconv.Itoa(f.FileID),
+				FileName:  f.FileName,
+				Language:  d.Attributes.Language,
+				Release:   d.Attributes.Release,
+				FPS:       d.Attributes.FPS,
+				Downloads: d.Attributes.DownloadCount,
+				MatchedBy: by,
+			})
+		}
+	}
+	return candidates, nil
+}
+
+// Download the candidate, keeping track of the remaining downloads the API
+// reports.
+func (p *openSubtitlesProvider) Download(c SubtitleCandidate) (string, []byte, error) {
+	fileID, err := strconv.Atoi(c.ID)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid OpenSubtitles file ID: %q", c.ID)
+	}
+	dl, err := p.client.Download(fileID)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get download link: %w", err)
+	}
+	rl := RateLimit{Limited: true, Remaining: dl.Remaining}
+	if t, err := time.Parse(time.RFC3339, dl.ResetTimeUTC); err == nil {
+		rl.ResetAt = t
+	}
+	p.mu.Lock()
+	p.rateLimit = rl
+	p.mu.Unlock()
+
+	content, err := p.client.DownloadFile(dl.Link)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to download subtitle file: %w", err)
+	}
+	return dl.FileName, content, nil
+}
+
+func (p *openSubtitlesProvider) RateLimit() RateLimit {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.rateLimit
 }
@@ -6,6 +6,9 @@ import (
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
+
+	"github.com/baalimago/kinoview/internal/lang"
 )
 
 func TestNewOpenSubtitlesClient(t *testing.T) {
@@ -299,7 +302,7 @@ func TestBestFile(t *testing.T) {
 	}
 
 	t.Run("picks highest downloads for matching language", func(t *testing.T) {
-		best := BestFile(data, "en")
+		best := BestFile(data, lang.Preferences{"en"})
 		if best == nil {
 			t.Fatal("expected non-nil result")
 		}
@@ -308,22 +311,33 @@ func TestBestFile(t *testing.T) {
 		}
 	})
 
+	t.Run("prefers language order over downloads", func(t *testing.T) {
+		best := BestFile(data, lang.Parse("swe,en"))
+		if best == nil || best.FileID != 3 {
+			t.Fatalf("expected file_id 3 (sv), got %+v", best)
+		}
+		best = BestFile(data, lang.Parse("ja,en-GB"))
+		if best == nil || best.FileID != 2 {
+			t.Fatalf("expected file_id 2 (en fallback), got %+v", best)
+		}
+	})
+
 	t.Run("returns nil when no language match", func(t *testing.T) {
-		best := BestFile(data, "fr")
+		best := BestFile(data, lang.Preferences{"fr"})
 		if best != nil {
 			t.Fatal("expected nil when no matching language")
 		}
 	})
 
 	t.Run("returns nil for empty data", func(t *testing.T) {
-		best := BestFile(nil, "en")
+		best := BestFile(nil, lang.Preferences{"en"})
 		if best != nil {
 			t.Fatal("expected nil for empty data")
 		}
 	})
 
 	t.Run("case insensitive language matching", func(t *testing.T) {
-		best := BestFile(data, "EN")
+		best := BestFile(data, lang.Parse("EN"))
 		if best == nil {
 			t.Fatal("expected non-nil for uppercase language")
 		}
@@ -333,28 +347,108 @@ func TestBestFile(t *testing.T) {
 	})
 }
 
-func TestSubtitleLanguages(t *testing.T) {
-	orig := os.Getenv("KINOVIEW_SUBTITLE_LANGUAGES")
-	defer os.Setenv("KINOVIEW_SUBTITLE_LANGUAGES", orig)
-
-	t.Run("defaults to en", func(t *testing.T) {
-		os.Unsetenv("KINOVIEW_SUBTITLE_LANGUAGES")
-		if got := SubtitleLanguages(); got != "en" {
-			t.Fatalf("expected 'en', got %q", got)
+func TestOpenSubtitlesProvider(t *testing.T) {
+	var searches []string
+	var serverURL string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/subtitles":
+			searches = append(searches, r.URL.RawQuery)
+			resp := OpenSubtitlesSearchResponse{}
+			// Nothing by ID, something by query
+			if r.URL.Query().Get("query") != "" {
+				resp.Data = []OpenSubtitlesData{{
+					Attributes: OpenSubtitlesAttributes{
+						Language:      "en",
+						DownloadCount: 7,
+						Files:         []OpenSubtitlesFile{{FileID: 42, FileName: "movie.en.srt"}},
+					},
+				}}
+			}
+			json.NewEncoder(w).Encode(resp)
+		case "/download":
+			json.NewEncoder(w).Encode(OpenSubtitlesDownloadResponse{
+				Link:         serverURL + "/file",
+				FileName:     "movie.en.srt",
+				Remaining:    0,
+				ResetTimeUTC: "2099-01-01T00:00:00Z",
+			})
+		case "/file":
+			w.Write([]byte("subs"))
 		}
-	})
+	}))
+	defer server.Close()
+	serverURL = server.URL
 
-	t.Run("reads from env", func(t *testing.T) {
-		os.Setenv("KINOVIEW_SUBTITLE_LANGUAGES", "en,sv")
-		if got := SubtitleLanguages(); got != "en,sv" {
-			t.Fatalf("expected 'en,sv', got %q", got)
-		}
-	})
+	p := NewOpenSubtitlesProvider(&OpenSubtitlesClient{apiKey: "key", baseURL: server.URL, client: server.Client()})
+	found, err := p.Search(SubtitleQuery{IMDBID: "tt0133093", Query: "The Matrix", Languages: lang.Preferences{"en"}, MediaType: "movie"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(searches) != 2 {
+		t.Fatalf("expected a search by ID, then by query, got: %v", searches)
+	}
+	if len(found) != 1 || found[0].ID != "42" || found[0].Provider != "opensubtitles" || found[0].Downloads != 7 {
+		t.Fatalf("unexpected candidates: %+v", found)
+	}
+
+	if p.RateLimit().Limited {
+		t.Error("expected the rate limit to be unknown before the first download")
+	}
+	name, content, err := p.Download(found[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "movie.en.srt" || string(content) != "subs" {
+		t.Errorf("unexpected download: %q, %q", name, content)
+	}
+	if !p.RateLimit().Exhausted(time.Now()) {
+		t.Errorf("expected the reported quota to be used up, got %+v", p.RateLimit())
+	}
+}
 
-	t.Run("trims whitespace", func(t *testing.T) {
-		os.Setenv("KINOVIEW_SUBTITLE_LANGUAGES", "  en , de  ")
-		if got := SubtitleLanguages(); got != "en , de" {
-			t.Fatalf("expected 'en , de', got %q", got)
+func TestOpenSubtitlesProvider_MovieHash(t *testing.T) {
+	var searches []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		searches = append(searches, r.URL.RawQuery)
+		resp := OpenSubtitlesSearchResponse{}
+		if r.URL.Query().Get("moviehash") == "8e245d9679d31e12" {
+			resp.Data = []OpenSubtitlesData{
+				{Attributes: OpenSubtitlesAttributes{
+					Language:       "en",
+					MovieHashMatch: true,
+					FPS:            23.976,
+					Files:          []OpenSubtitlesFile{{FileID: 1, FileName: "synced.srt"}},
+				}},
+				{Attributes: OpenSubtitlesAttributes{
+					Language: "en",
+					Files:    []OpenSubtitlesFile{{FileID: 2, FileName: "other.srt"}},
+				}},
+			}
 		}
-	})
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	p := NewOpenSubtitlesProvider(&OpenSubtitlesClient{apiKey: "key", baseURL: server.URL, client: server.Client()})
+	found, err := p.Search(SubtitleQuery{MovieHash: "8e245d9679d31e12", IMDBID: "tt0133093", Languages: lang.Preferences{"en"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(searches) != 1 {
+		t.Fatalf("expected only the moviehash search, got: %v", searches)
+	}
+	if len(found) != 2 || found[0].MatchedBy != MatchMovieHash || found[0].FPS != 23.976 || found[1].MatchedBy != MatchID {
+		t.Fatalf("unexpected candidates: %+v", found)
+	}
+
+	// No luck by hash, falling back to the ID
+	searches = nil
+	found, err = p.Search(SubtitleQuery{MovieHash: "0000000000000000", IMDBID: "tt0133093", Query: "The Matrix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(searches) != 3 || len(found) != 0 {
+		t.Fatalf("expected searches by hash, ID and query, got: %v", searches)
+	}
 }
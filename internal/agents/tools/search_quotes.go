@@ -0,0 +1,81 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/baalimago/clai/pkg/text/models"
+	"github.com/baalimago/kinoview/internal/agents"
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+type searchQuotesTool struct {
+	searcher agents.QuoteSearcher
+	gate     agents.ContentGate
+}
+
+// NewSearchQuotesTool searching s, leaving out the lines of the items gate
+// doesn't allow the agents. A nil gate allows every item.
+func NewSearchQuotesTool(s agents.QuoteSearcher, gate agents.ContentGate) (*searchQuotesTool, error) {
+	if s == nil {
+		return nil, errors.New("quote searcher can't be nil")
+	}
+	return &searchQuotesTool{searcher: s, gate: gate}, nil
+}
+
+type searchQuotesResponse struct {
+	Total  int           `json:"total"`
+	Quotes []model.Quote `json:"quotes"`
+}
+
+func (t *searchQuotesTool) Call(input models.Input) (string, error) {
+	q, _ := input["q"].(string)
+	q = strings.TrimSpace(q)
+	if q == "" {
+		return "", errors.New("q must be a non-empty string")
+	}
+	limit, _, err := parseLimitOffset(input, 20)
+	if err != nil {
+		return "", err
+	}
+
+	var allowed func(model.Item) bool
+	if t.gate != nil {
+		allowed = t.gate.AllowedForAgents
+	}
+	found, total, err := t.searcher.SearchQuotes(q, limit, allowed)
+	if err != nil {
+		return "", fmt.Errorf("failed to search quotes: %w", err)
+	}
+	if total == 0 {
+		return fmt.Sprintf("no extracted subtitles say '%s'", q), nil
+	}
+	b, err := json.Marshal(searchQuotesResponse{Total: total, Quotes: found})
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (t *searchQuotesTool) Specification() models.Specification {
+	return models.Specification{
+		Name:        "search_quotes",
+		Description: "Search the dialogue of the library for a line, such as \"I'll be back\", to find which media it's said in, and when. Matches whole words in order, ignoring case and punctuation. Only subtitles which have been extracted are searched. Each match links to the video at that moment.",
+		Inputs: &models.InputSchema{
+			Type: "object",
+			Properties: map[string]models.ParameterObject{
+				"q": {
+					Type:        "string",
+					Description: "The line, or part of it, to search for.",
+				},
+				"limit": {
+					Type:        "integer",
+					Description: "Max number of matches to return (default 20).",
+				},
+			},
+			Required: []string{"q"},
+		},
+	}
+}
@@ -0,0 +1,89 @@
+package tools
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/baalimago/clai/pkg/text/models"
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+type fakeQuoteSearcher struct {
+	gotQuery string
+	gotLimit int
+	quotes   []model.Quote
+}
+
+func (f *fakeQuoteSearcher) SearchQuotes(query string, limit int, allowed func(model.Item) bool) ([]model.Quote, int, error) {
+	f.gotQuery, f.gotLimit = query, limit
+	var ret []model.Quote
+	for _, q := range f.quotes {
+		if allowed == nil || allowed(model.Item{ID: q.ItemID}) {
+			ret = append(ret, q)
+		}
+	}
+	return ret, len(ret), nil
+}
+
+// idGate allows the agents the items by their ID.
+type idGate map[string]bool
+
+func (g idGate) AllowedForAgents(it model.Item) bool { return g[it.ID] }
+
+func TestNewSearchQuotesTool_NilSearcher(t *testing.T) {
+	if _, err := NewSearchQuotesTool(nil, nil); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestSearchQuotesTool_Call(t *testing.T) {
+	s := &fakeQuoteSearcher{quotes: []model.Quote{{ItemID: "t1", Name: "The Terminator.mkv", Start: 3723, Text: "I'll be back."}}}
+	tool, err := NewSearchQuotesTool(s, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tool.Call(models.Input{"q": "  "}); err == nil {
+		t.Error("expected an error without a query")
+	}
+
+	got, err := tool.Call(models.Input{"q": " I'll be back ", "limit": float64(5)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.gotQuery != "I'll be back" || s.gotLimit != 5 {
+		t.Errorf("unexpected search: %q, limit %d", s.gotQuery, s.gotLimit)
+	}
+	var resp searchQuotesResponse
+	if err := json.Unmarshal([]byte(got), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if resp.Total != 1 || resp.Quotes[0].ItemID != "t1" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+
+	s.quotes = nil
+	got, err = tool.Call(models.Input{"q": "hasta la vista"})
+	if err != nil || !strings.Contains(got, "no extracted subtitles say") {
+		t.Errorf("expected a no-match message, got %q, err: %v", got, err)
+	}
+}
+
+func TestSearchQuotesTool_Call_gated(t *testing.T) {
+	s := &fakeQuoteSearcher{quotes: []model.Quote{
+		{ItemID: "t1", Name: "The Terminator.mkv", Text: "I'll be back."},
+		{ItemID: "r1", Name: "Rated R.mkv", Text: "I'll be back."},
+	}}
+	tool, err := NewSearchQuotesTool(s, idGate{"t1": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := tool.Call(models.Input{"q": "I'll be back"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(got, "r1") || !strings.Contains(got, "t1") {
+		t.Errorf("expected only the allowed item's lines, got %s", got)
+	}
+}
@@ -0,0 +1,405 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/baalimago/go_away_boilerplate/pkg/ancli"
+	"github.com/baalimago/kinoview/internal/lang"
+)
+
+// SubtitleQuery describes the media to find subtitles for. Providers use
+// whichever identifiers they understand, preferring MovieHash, then the IDs,
+// over Query.
+type SubtitleQuery struct {
+	// MovieHash of the video file, see package moviehash.
+	MovieHash string
+	IMDBID    string
+	TMDBID    string
+	// Query is the title, as cleaned up from the file name.
+	Query     string
+	Languages lang.Preferences
+	// MediaType is "movie" or "episode".
+	MediaType string
+	// Release is the file name of the video, without extension, to tell
+	// releases of the same title apart.
+	Release string
+	// FPS of the video, 0 if unknown.
+	FPS float64
+}
+
+// How a subtitle was matched to the media, see SubtitleCandidate.MatchedBy.
+const (
+	// MatchMovieHash is a subtitle made for the very same video file.
+	MatchMovieHash = "moviehash"
+	// MatchID is a subtitle for the same IMDb or TMDB ID.
+	MatchID = "id"
+	// MatchTitle is a subtitle found by searching for the title.
+	MatchTitle = "title"
+)
+
+// SubtitleCandidate is a subtitle file offered by a provider.
+type SubtitleCandidate struct {
+	// Provider is the Name of the provider offering it.
+	Provider string
+	// ID identifies the file to the provider, see SubtitleProvider.Download.
+	ID       string
+	FileName string
+	Language string
+	Release  string
+	// FPS the subtitle was timed for, 0 if unknown.
+	FPS       float64
+	Downloads int
+	// MatchedBy is how the provider found it: MatchMovieHash, MatchID or
+	// MatchTitle.
+	MatchedBy string
+}
+
+// RateLimit is what a provider knows of its own quota. The zero value is
+// unlimited.
+type RateLimit struct {
+	// Limited is false if there's no limit, or none is known yet.
+	Limited bool
+	// Remaining downloads, if Limited.
+	Remaining int
+	// ResetAt is when Remaining is replenished, zero if unknown.
+	ResetAt time.Time
+}
+
+// Exhausted reports if no downloads remain until ResetAt.
+func (r RateLimit) Exhausted(now time.Time) bool {
+	return r.Limited && r.Remaining <= 0 && (r.ResetAt.IsZero() || now.Before(r.ResetAt))
+}
+
+// SubtitleProvider is a source of subtitles, such as OpenSubtitles.
+type SubtitleProvider interface {
+	// Name of the provider, unique within a chain.
+	Name() string
+	// Search for subtitles matching q.
+	Search(q SubtitleQuery) ([]SubtitleCandidate, error)
+	// Download a candidate returned by Search, returning its file name and
+	// content.
+	Download(c SubtitleCandidate) (string, []byte, error)
+	// RateLimit reports the quota of the provider.
+	RateLimit() RateLimit
+}
+
+// ErrQuotaExhausted is returned when a provider has no downloads left.
+var ErrQuotaExhausted = errors.New("subtitle provider quota exhausted")
+
+// QuotaFile is the file under the cache dir in which the downloads counted
+// against the quotas of the providers are kept, see
+// SubtitleProviderChain.PersistQuota.
+const QuotaFile = "subtitle_quota.json"
+
+// DefaultSubtitleProviders returns the providers configured by environment:
+// the local folder at KINOVIEW_SUBTITLE_DIR, tried first, and OpenSubtitles
+// if OPENSUBTITLES_API_KEY is set, limited to OPENSUBTITLES_DAILY_QUOTA
+// downloads a day if set. The downloads counted against the quota are kept
+// in QuotaFile under cacheDir, unless it's empty. Returns nil if neither
+// provider is configured.
+//
+// Every tool fetching subtitles in a process should share the one chain,
+// as each chain counts its downloads on its own.
+func DefaultSubtitleProviders(cacheDir string) SubtitleProvider {
+	var links []ChainLink
+	if dir := os.Getenv("KINOVIEW_SUBTITLE_DIR"); dir != "" {
+		links = append(links, ChainLink{Provider: NewLocalSubtitleProvider(dir)})
+	}
+	if client := NewOpenSubtitlesClient(); client != nil {
+		link := ChainLink{Provider: NewOpenSubtitlesProvider(client)}
+		if q := os.Getenv("OPENSUBTITLES_DAILY_QUOTA"); q != "" {
+			n, err := strconv.Atoi(q)
+			if err != nil {
+				ancli.Warnf("ignoring invalid OPENSUBTITLES_DAILY_QUOTA: %q", q)
+			}
+			link.Quota = ProviderQuota{Downloads: n, Period: 24 * time.Hour}
+		}
+		links = append(links, link)
+	}
+	if len(links) == 0 {
+		return nil
+	}
+	chain := NewSubtitleProviderChain(links...)
+	if cacheDir != "" {
+		if err := chain.PersistQuota(filepath.Join(cacheDir, QuotaFile)); err != nil {
+			ancli.Warnf("subtitle quota starts afresh: %v", err)
+		}
+	}
+	return chain
+}
+
+// BestCandidate selects the most appropriate subtitle in the most preferred
+// language available of q.Languages. Among those, one made for the very same
+// video file wins, then one timed for the same frame rate, then the one
+// whose release name is most alike q.Release, then the one with most
+// downloads. Returns nil if none are in a preferred language.
+func BestCandidate(candidates []SubtitleCandidate, q SubtitleQuery) *SubtitleCandidate {
+	release := words(q.Release)
+	score := func(c *SubtitleCandidate) [4]int {
+		var s [4]int
+		if c.MatchedBy == MatchMovieHash {
+			s[0] = 1
+		}
+		s[1] = fpsMatch(c.FPS, q.FPS)
+		if len(release) > 0 {
+			for _, w := range words(c.Release) {
+				if slices.Contains(release, w) {
+					s[2]++
+				}
+			}
+		}
+		s[3] = c.Downloads
+		return s
+	}
+
+	var best *SubtitleCandidate
+	var bestScore [4]int
+	bestRank := len(q.Languages)
+	for i := range candidates {
+		c := &candidates[i]
+		rank := q.Languages.Rank(c.Language)
+		if rank < 0 || rank > bestRank {
+			continue
+		}
+		s := score(c)
+		if best == nil || rank < bestRank || slices.Compare(s[:], bestScore[:]) > 0 {
+			best, bestScore, bestRank = c, s, rank
+		}
+	}
+	return best
+}
+
+// fpsMatch is 1 if the frame rates are known and alike, -1 if they are known
+// and differ, so that the subtitle drifts, and 0 otherwise.
+func fpsMatch(a, b float64) int {
+	if a <= 0 || b <= 0 {
+		return 0
+	}
+	if math.Abs(a-b) < 0.01 {
+		return 1
+	}
+	return -1
+}
+
+// ProviderQuota limits the downloads from a provider to Downloads per
+// Period. Zero Downloads is unlimited.
+type ProviderQuota struct {
+	Downloads int
+	Period    time.Duration
+}
+
+// ChainLink is a provider of a SubtitleProviderChain, and its quota.
+type ChainLink struct {
+	Provider SubtitleProvider
+	Quota    ProviderQuota
+}
+
+type chainLink struct {
+	ChainLink
+	used  int
+	since time.Time
+}
+
+// quotaUse is the persisted use of the quota of a provider.
+type quotaUse struct {
+	Used  int       `json:"used"`
+	Since time.Time `json:"since"`
+}
+
+// SubtitleProviderChain tries its providers in order, skipping those whose
+// quota is used up, so that a local folder can be preferred over an online
+// service, and an online service be kept within its limits.
+type SubtitleProviderChain struct {
+	mu    sync.Mutex
+	links []*chainLink
+	now   func() time.Time
+	// quotaPath is where the use of the quotas is kept, empty to keep it
+	// in memory only.
+	quotaPath string
+}
+
+// NewSubtitleProviderChain creates a chain of links, most preferred first.
+func NewSubtitleProviderChain(links ...ChainLink) *SubtitleProviderChain {
+	c := &SubtitleProviderChain{now: time.Now}
+	for _, l := range links {
+		c.links = append(c.links, &chainLink{ChainLink: l})
+	}
+	return c
+}
+
+func (c *SubtitleProviderChain) Name() string {
+	return "chain"
+}
+
+// PersistQuota keeps the downloads counted against the quotas of the chain
+// in the file at p, so that they survive restarts. The use already recorded
+// in p is picked up. Should p not be readable, the quotas start afresh and
+// are still written to it.
+func (c *SubtitleProviderChain) PersistQuota(p string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.quotaPath = p
+	b, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read subtitle quota: %w", err)
+	}
+	var uses map[string]quotaUse
+	if err := json.Unmarshal(b, &uses); err != nil {
+		return fmt.Errorf("failed to unmarshal subtitle quota: %w", err)
+	}
+	for _, l := range c.links {
+		if u, ok := uses[l.Provider.Name()]; ok {
+			l.used, l.since = u.Used, u.Since
+		}
+	}
+	return nil
+}
+
+// saveQuota of the links to quotaPath, if set. Callers hold c.mu.
+func (c *SubtitleProviderChain) saveQuota() {
+	if c.quotaPath == "" {
+		return
+	}
+	uses := make(map[string]quotaUse, len(c.links))
+	for _, l := range c.links {
+		if l.Quota.Downloads > 0 {
+			uses[l.Provider.Name()] = quotaUse{Used: l.used, Since: l.since}
+		}
+	}
+	b, err := json.Marshal(uses)
+	if err != nil {
+		ancli.Errf("failed to marshal subtitle quota: %v", err)
+		return
+	}
+	// Written aside and moved into place, so a crash can't leave half of it
+	tmp := c.quotaPath + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		ancli.Errf("failed to write subtitle quota: %v", err)
+		return
+	}
+	if err := os.Rename(tmp, c.quotaPath); err != nil {
+		ancli.Errf("failed to write subtitle quota: %v", err)
+	}
+}
+
+// remaining downloads of l within its quota, -1 if unlimited. Resets the
+// quota when its period has passed. Callers hold c.mu.
+func (c *SubtitleProviderChain) remaining(l *chainLink) int {
+	if l.Quota.Downloads <= 0 {
+		return -1
+	}
+	if now := c.now(); l.since.IsZero() || (l.Quota.Period > 0 && now.Sub(l.since) >= l.Quota.Period) {
+		l.used, l.since = 0, now
+		c.saveQuota()
+	}
+	return max(l.Quota.Downloads-l.used, 0)
+}
+
+// available reports if l may be asked for more downloads. Callers hold c.mu.
+func (c *SubtitleProviderChain) available(l *chainLink) bool {
+	return c.remaining(l) != 0 && !l.Provider.RateLimit().Exhausted(c.now())
+}
+
+// Search the providers in order, returning the results of the first one
+// offering a subtitle in a preferred language. If none do, all results are
+// returned. Providers failing to search are skipped.
+func (c *SubtitleProviderChain) Search(q SubtitleQuery) ([]SubtitleCandidate, error) {
+	c.mu.Lock()
+	var links []*chainLink
+	for _, l := range c.links {
+		if c.available(l) {
+			links = append(links, l)
+		}
+	}
+	c.mu.Unlock()
+	if len(links) == 0 {
+		return nil, ErrQuotaExhausted
+	}
+
+	var all []SubtitleCandidate
+	var errs []error
+	for _, l := range links {
+		found, err := l.Provider.Search(q)
+		if err != nil {
+			ancli.Warnf("subtitle provider %v failed to search: %v", l.Provider.Name(), err)
+			errs = append(errs, fmt.Errorf("%v: %w", l.Provider.Name(), err))
+			continue
+		}
+		if BestCandidate(found, q) != nil {
+			return found, nil
+		}
+		all = append(all, found...)
+	}
+	if len(errs) == len(links) {
+		return nil, errors.Join(errs...)
+	}
+	return all, nil
+}
+
+// Download the candidate from the provider which offered it, counting it
+// against the quota of that provider.
+func (c *SubtitleProviderChain) Download(cand SubtitleCandidate) (string, []byte, error) {
+	c.mu.Lock()
+	var link *chainLink
+	for _, l := range c.links {
+		if l.Provider.Name() == cand.Provider {
+			link = l
+			break
+		}
+	}
+	if link == nil {
+		c.mu.Unlock()
+		return "", nil, fmt.Errorf("unknown subtitle provider: %q", cand.Provider)
+	}
+	if !c.available(link) {
+		c.mu.Unlock()
+		return "", nil, fmt.Errorf("%v: %w", cand.Provider, ErrQuotaExhausted)
+	}
+	// Count it up front, so that concurrent downloads can't overrun it.
+	link.used++
+	c.saveQuota()
+	c.mu.Unlock()
+
+	name, content, err := link.Provider.Download(cand)
+	if err != nil {
+		c.mu.Lock()
+		link.used--
+		c.saveQuota()
+		c.mu.Unlock()
+		return "", nil, err
+	}
+	return name, content, nil
+}
+
+// RateLimit sums up the downloads remaining in the chain, which is only
+// limited if all its providers are.
+func (c *SubtitleProviderChain) RateLimit() RateLimit {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	total := 0
+	for _, l := range c.links {
+		rem := c.remaining(l)
+		if pr := l.Provider.RateLimit(); pr.Exhausted(c.now()) {
+			rem = 0
+		} else if pr.Limited && (rem < 0 || pr.Remaining < rem) {
+			rem = pr.Remaining
+		}
+		if rem < 0 {
+			return RateLimit{}
+		}
+		total += rem
+	}
+	return RateLimit{Limited: true, Remaining: total}
+}
@@ -0,0 +1,217 @@
+package tools
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/baalimago/kinoview/internal/lang"
+)
+
+type fakeProvider struct {
+	name       string
+	candidates []SubtitleCandidate
+	searchErr  error
+	rateLimit  RateLimit
+	searches   int
+	downloads  int
+}
+
+func (f *fakeProvider) Name() string { return f.name }
+
+func (f *fakeProvider) Search(q SubtitleQuery) ([]SubtitleCandidate, error) {
+	f.searches++
+	return f.candidates, f.searchErr
+}
+
+func (f *fakeProvider) Download(c SubtitleCandidate) (string, []byte, error) {
+	f.downloads++
+	return c.FileName, []byte("subs"), nil
+}
+
+func (f *fakeProvider) RateLimit() RateLimit { return f.rateLimit }
+
+func TestBestCandidate(t *testing.T) {
+	candidates := []SubtitleCandidate{
+		{ID: "1", Language: "en", Downloads: 100},
+		{ID: "2", Language: "eng", Downloads: 500},
+		{ID: "3", Language: "sv", Downloads: 9},
+		{ID: "4", Language: "", Downloads: 999},
+	}
+	if best := BestCandidate(candidates, SubtitleQuery{Languages: lang.Preferences{"en"}}); best == nil || best.ID != "2" {
+		t.Fatalf("expected the most downloaded english subtitle, got %+v", best)
+	}
+	if best := BestCandidate(candidates, SubtitleQuery{Languages: lang.Parse("sv,en")}); best == nil || best.ID != "3" {
+		t.Fatalf("expected language order over downloads, got %+v", best)
+	}
+	if best := BestCandidate(candidates, SubtitleQuery{Languages: lang.Preferences{"fr"}}); best != nil {
+		t.Fatalf("expected nil without a language match, got %+v", best)
+	}
+}
+
+func TestBestCandidate_Tiebreaks(t *testing.T) {
+	q := SubtitleQuery{
+		Languages: lang.Preferences{"en"},
+		Release:   "The.Matrix.1999.1080p.BluRay.x264-GROUP",
+		FPS:       23.976,
+	}
+	candidates := []SubtitleCandidate{
+		{ID: "popular", Language: "en", Downloads: 900, Release: "The.Matrix.1999.DVDRip"},
+		{ID: "same-release", Language: "en", Downloads: 10, Release: "The Matrix 1999 1080p BluRay x264-GROUP"},
+		{ID: "wrong-fps", Language: "en", Downloads: 5, Release: "The.Matrix.1999.1080p.BluRay.x264-GROUP", FPS: 25},
+		{ID: "hashed", Language: "en", Downloads: 1, MatchedBy: MatchMovieHash, FPS: 25},
+		{ID: "hashed-sv", Language: "sv", Downloads: 1, MatchedBy: MatchMovieHash},
+	}
+	if best := BestCandidate(candidates, q); best == nil || best.ID != "hashed" {
+		t.Fatalf("expected the moviehash match, got %+v", best)
+	}
+	if best := BestCandidate(candidates[:3], q); best == nil || best.ID != "same-release" {
+		t.Fatalf("expected the release match at the right frame rate, got %+v", best)
+	}
+	q.Release = ""
+	if best := BestCandidate(candidates[:3], q); best == nil || best.ID != "popular" {
+		t.Fatalf("expected downloads to decide without a release, got %+v", best)
+	}
+}
+
+func TestSubtitleProviderChain_Search(t *testing.T) {
+	q := SubtitleQuery{Query: "movie", Languages: lang.Preferences{"sv"}}
+
+	t.Run("first provider with a preferred language wins", func(t *testing.T) {
+		local := &fakeProvider{name: "local", candidates: []SubtitleCandidate{{Provider: "local", Language: "en"}}}
+		broken := &fakeProvider{name: "broken", searchErr: errors.New("offline")}
+		remote := &fakeProvider{name: "remote", candidates: []SubtitleCandidate{{Provider: "remote", Language: "sv"}}}
+		last := &fakeProvider{name: "last"}
+		chain := NewSubtitleProviderChain(ChainLink{Provider: local}, ChainLink{Provider: broken}, ChainLink{Provider: remote}, ChainLink{Provider: last})
+
+		found, err := chain.Search(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(found) != 1 || found[0].Provider != "remote" {
+			t.Fatalf("expected the remote result, got %+v", found)
+		}
+		if last.searches != 0 {
+			t.Error("expected the chain to stop at the first match")
+		}
+	})
+
+	t.Run("without a match, all results are returned", func(t *testing.T) {
+		a := &fakeProvider{name: "a", candidates: []SubtitleCandidate{{Provider: "a", Language: "en"}}}
+		b := &fakeProvider{name: "b", candidates: []SubtitleCandidate{{Provider: "b", Language: "ja"}}}
+		found, err := NewSubtitleProviderChain(ChainLink{Provider: a}, ChainLink{Provider: b}).Search(q)
+		if err != nil || len(found) != 2 {
+			t.Fatalf("expected both results, got %+v, err: %v", found, err)
+		}
+	})
+
+	t.Run("all failing is an error", func(t *testing.T) {
+		a := &fakeProvider{name: "a", searchErr: errors.New("offline")}
+		if _, err := NewSubtitleProviderChain(ChainLink{Provider: a}).Search(q); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+
+	t.Run("rate limited providers are skipped", func(t *testing.T) {
+		limited := &fakeProvider{name: "limited", rateLimit: RateLimit{Limited: true, ResetAt: time.Now().Add(time.Hour)}}
+		_, err := NewSubtitleProviderChain(ChainLink{Provider: limited}).Search(q)
+		if !errors.Is(err, ErrQuotaExhausted) || limited.searches != 0 {
+			t.Fatalf("expected ErrQuotaExhausted without searching, got: %v", err)
+		}
+	})
+}
+
+func TestSubtitleProviderChain_Quota(t *testing.T) {
+	remote := &fakeProvider{name: "remote"}
+	chain := NewSubtitleProviderChain(ChainLink{Provider: remote, Quota: ProviderQuota{Downloads: 2, Period: 24 * time.Hour}})
+	now := time.Now()
+	chain.now = func() time.Time { return now }
+	cand := SubtitleCandidate{Provider: "remote", FileName: "movie.srt"}
+
+	for range 2 {
+		if _, _, err := chain.Download(cand); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if rl := chain.RateLimit(); !rl.Limited || rl.Remaining != 0 {
+		t.Errorf("expected no downloads remaining, got %+v", rl)
+	}
+	if _, _, err := chain.Download(cand); !errors.Is(err, ErrQuotaExhausted) {
+		t.Fatalf("expected ErrQuotaExhausted, got: %v", err)
+	}
+	if _, err := chain.Search(SubtitleQuery{}); !errors.Is(err, ErrQuotaExhausted) {
+		t.Fatalf("expected an exhausted provider to be skipped, got: %v", err)
+	}
+
+	now = now.Add(25 * time.Hour)
+	if _, _, err := chain.Download(cand); err != nil {
+		t.Fatalf("expected the quota to reset, got: %v", err)
+	}
+	if remote.downloads != 3 {
+		t.Errorf("expected 3 downloads, got %d", remote.downloads)
+	}
+
+	if _, _, err := chain.Download(SubtitleCandidate{Provider: "nope"}); err == nil {
+		t.Error("expected an error for an unknown provider")
+	}
+}
+
+func TestSubtitleProviderChain_PersistQuota(t *testing.T) {
+	p := filepath.Join(t.TempDir(), QuotaFile)
+	quota := ProviderQuota{Downloads: 2, Period: 24 * time.Hour}
+	cand := SubtitleCandidate{Provider: "remote", FileName: "movie.srt"}
+
+	chain := NewSubtitleProviderChain(ChainLink{Provider: &fakeProvider{name: "remote"}, Quota: quota})
+	if err := chain.PersistQuota(p); err != nil {
+		t.Fatalf("PersistQuota: %v", err)
+	}
+	if _, _, err := chain.Download(cand); err != nil {
+		t.Fatal(err)
+	}
+
+	// As after a restart
+	restarted := NewSubtitleProviderChain(ChainLink{Provider: &fakeProvider{name: "remote"}, Quota: quota})
+	if err := restarted.PersistQuota(p); err != nil {
+		t.Fatalf("PersistQuota: %v", err)
+	}
+	if rl := restarted.RateLimit(); rl.Remaining != 1 {
+		t.Errorf("expected the download before the restart counted, got %+v", rl)
+	}
+	if _, _, err := restarted.Download(cand); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := restarted.Download(cand); !errors.Is(err, ErrQuotaExhausted) {
+		t.Fatalf("expected ErrQuotaExhausted across restarts, got: %v", err)
+	}
+
+	if err := os.WriteFile(p, []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewSubtitleProviderChain().PersistQuota(p); err == nil {
+		t.Error("expected a corrupt quota file to be reported")
+	}
+}
+
+func TestDefaultSubtitleProviders(t *testing.T) {
+	t.Setenv("OPENSUBTITLES_API_KEY", "")
+	t.Setenv("KINOVIEW_SUBTITLE_DIR", "")
+	if p := DefaultSubtitleProviders(""); p != nil {
+		t.Fatalf("expected nil without configuration, got %v", p)
+	}
+
+	t.Setenv("OPENSUBTITLES_API_KEY", "key")
+	t.Setenv("OPENSUBTITLES_DAILY_QUOTA", "5")
+	t.Setenv("KINOVIEW_SUBTITLE_DIR", t.TempDir())
+	chain, ok := DefaultSubtitleProviders("").(*SubtitleProviderChain)
+	if !ok || len(chain.links) != 2 {
+		t.Fatalf("expected a chain of two providers, got %+v", chain)
+	}
+	if chain.links[0].Provider.Name() != "local" || chain.links[1].Provider.Name() != "opensubtitles" {
+		t.Errorf("expected the local folder to be tried first")
+	}
+	if q := chain.links[1].Quota; q.Downloads != 5 || q.Period != 24*time.Hour {
+		t.Errorf("unexpected quota: %+v", q)
+	}
+}
@@ -0,0 +1,71 @@
+package tools
+
+import (
+	"fmt"
+
+	"github.com/baalimago/clai/pkg/text/models"
+	"github.com/baalimago/kinoview/internal/agents"
+)
+
+type syncSubtitleTool struct {
+	itemGetter agents.ItemGetter
+	subMgr     agents.StreamManager
+}
+
+func NewSyncSubtitleTool(ig agents.ItemGetter, sm agents.StreamManager) (*syncSubtitleTool, error) {
+	if ig == nil {
+		return nil, fmt.Errorf("item getter can't be nil")
+	}
+	if sm == nil {
+		return nil, fmt.Errorf("subtitle manager can't be nil")
+	}
+	return &syncSubtitleTool{
+		itemGetter: ig,
+		subMgr:     sm,
+	}, nil
+}
+
+func (t *syncSubtitleTool) Call(input models.Input) (string, error) {
+	id, ok := input["ID"].(string)
+	if !ok || id == "" {
+		return "", fmt.Errorf("ID must be a non-empty string")
+	}
+
+	subtitleID, ok := input["subtitleID"].(string)
+	if !ok || subtitleID == "" {
+		return "", fmt.Errorf("subtitleID must be a non-empty string")
+	}
+
+	item, err := t.itemGetter.GetItemByID(id)
+	if err != nil {
+		return "", fmt.Errorf("failed to get item: %w", err)
+	}
+
+	path, err := t.subMgr.SyncSubtitles(item, subtitleID)
+	if err != nil {
+		return "", fmt.Errorf("failed to sync subtitles: %w", err)
+	}
+
+	return fmt.Sprintf("subtitles synced to the audio of '%s' (subtitleID=%s): %s. The client loads them with ?sync=auto", item.Name, subtitleID, path), nil
+}
+
+func (t *syncSubtitleTool) Specification() models.Specification {
+	return models.Specification{
+		Name:        "sync_subtitle",
+		Description: "Retime a subtitle stream of a media item to match the speech in its audio track, correcting a constant offset and a slow drift. Use this when subtitles are reported to be early, late or drifting, typically for fetched or sidecar files. Takes a while on the first call, the result is cached.",
+		Inputs: &models.InputSchema{
+			Type: "object",
+			Properties: map[string]models.ParameterObject{
+				"ID": {
+					Type:        "string",
+					Description: "ID of the media item to sync subtitles for",
+				},
+				"subtitleID": {
+					Type:        "string",
+					Description: "The subtitle stream index/ID to sync (from list_subtitle_candidates)",
+				},
+			},
+			Required: []string{"ID", "subtitleID"},
+		},
+	}
+}
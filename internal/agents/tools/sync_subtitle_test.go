@@ -0,0 +1,52 @@
+package tools
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/baalimago/clai/pkg/text/models"
+	kinomodel "github.com/baalimago/kinoview/internal/model"
+)
+
+func TestNewSyncSubtitleTool(t *testing.T) {
+	if _, err := NewSyncSubtitleTool(nil, &mockSubtitleManager{}); err == nil {
+		t.Fatal("expected error for nil item getter")
+	}
+	if _, err := NewSyncSubtitleTool(&mockItemGetter{}, nil); err == nil {
+		t.Fatal("expected error for nil subtitle manager")
+	}
+}
+
+func TestSyncSubtitleCall(t *testing.T) {
+	ig := &mockItemGetter{
+		item: kinomodel.Item{ID: "item-1", Name: "Test Movie"},
+	}
+
+	t.Run("successful sync", func(t *testing.T) {
+		sm := &mockSubtitleManager{syncedPath: "/tmp/subs/item-1_-1.synced.vtt"}
+		tool, _ := NewSyncSubtitleTool(ig, sm)
+		resp, err := tool.Call(models.Input{"ID": "item-1", "subtitleID": "-1"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !strings.Contains(resp, "'Test Movie' (subtitleID=-1): /tmp/subs/item-1_-1.synced.vtt") {
+			t.Fatalf("unexpected response: %q", resp)
+		}
+	})
+
+	t.Run("missing subtitleID", func(t *testing.T) {
+		tool, _ := NewSyncSubtitleTool(ig, &mockSubtitleManager{})
+		if _, err := tool.Call(models.Input{"ID": "item-1"}); err == nil {
+			t.Fatal("expected error for missing subtitleID")
+		}
+	})
+
+	t.Run("sync failure", func(t *testing.T) {
+		tool, _ := NewSyncSubtitleTool(ig, &mockSubtitleManager{err: errors.New("no speech")})
+		_, err := tool.Call(models.Input{"ID": "item-1", "subtitleID": "-1"})
+		if err == nil || !strings.Contains(err.Error(), "no speech") {
+			t.Fatalf("expected wrapped sync error, got: %v", err)
+		}
+	})
+}
@@ -7,16 +7,21 @@ import (
 	"github.com/baalimago/clai/pkg/text/models"
 	"github.com/baalimago/kinoview/internal/agents"
 	"github.com/baalimago/kinoview/internal/media/constants"
+	"github.com/baalimago/kinoview/internal/model"
 )
 
 type updateMetadataTool struct {
 	metadataMgr agents.MetadataManager
 	itemsGetter agents.ItemGetter
+	// by is recorded as the provenance of the updates.
+	by model.Provenance
 }
 
 const metadataPrompt = `Metadata which should be in the following format: %s`
 
-func NewUpdateMetadataTool(mm agents.MetadataManager, ig agents.ItemGetter) (*updateMetadataTool, error) {
+// NewUpdateMetadataTool updating metadata on behalf of by, which is what the
+// metadata history says made the change.
+func NewUpdateMetadataTool(mm agents.MetadataManager, ig agents.ItemGetter, by model.Provenance) (*updateMetadataTool, error) {
 	if mm == nil {
 		return nil, errors.New("metadata manager can't be nil")
 	}
@@ -27,6 +32,7 @@ func NewUpdateMetadataTool(mm agents.MetadataManager, ig agents.ItemGetter) (*up
 	return &updateMetadataTool{
 		metadataMgr: mm,
 		itemsGetter: ig,
+		by:          by,
 	}, nil
 }
 
@@ -44,7 +50,7 @@ func (umt *updateMetadataTool) Call(input models.Input) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("update metadata tool failed to get item: %v", err)
 	}
-	err = umt.metadataMgr.UpdateMetadata(item, metadata)
+	err = umt.metadataMgr.UpdateMetadata(item, metadata, umt.by)
 	if err != nil {
 		return "", fmt.Errorf("failed to update metadata for item: '%v', error: %w", item.Name, err)
 	}
@@ -12,7 +12,8 @@ func TestUpdateMetadataTool_Call(t *testing.T) {
 	mm := &mockMetadataManager{}
 	ig := &mockItemGetter{item: item}
 
-	tool, err := NewUpdateMetadataTool(mm, ig)
+	by := model.Provenance{Source: model.SourceAgent, Actor: "concierge", Model: "gpt-test"}
+	tool, err := NewUpdateMetadataTool(mm, ig, by)
 	if err != nil {
 		t.Fatalf("failed to create tool: %v", err)
 	}
@@ -39,13 +40,17 @@ func TestUpdateMetadataTool_Call(t *testing.T) {
 	if mm.updatedMetadata != `{"name": "Updated Name"}` {
 		t.Errorf("expected updated metadata %q, got %q", `{"name": "Updated Name"}`, mm.updatedMetadata)
 	}
+
+	if mm.updatedBy != by {
+		t.Errorf("expected the update made by %+v, got %+v", by, mm.updatedBy)
+	}
 }
 
 func TestUpdateMetadataTool_Call_NotFound(t *testing.T) {
 	mm := &mockMetadataManager{}
 	ig := &mockItemGetter{item: model.Item{ID: "other-id"}}
 
-	tool, err := NewUpdateMetadataTool(mm, ig)
+	tool, err := NewUpdateMetadataTool(mm, ig, model.Provenance{Source: model.SourceAgent})
 	if err != nil {
 		t.Fatalf("failed to create tool: %v", err)
 	}
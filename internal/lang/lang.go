@@ -0,0 +1,191 @@
+// Package lang normalises the language tags found on subtitle and audio
+// streams, in file names and in user configuration, so that "sv", "swe",
+// "sv-SE" and "Swedish" all compare equal.
+package lang
+
+import (
+	"os"
+	"strings"
+)
+
+type language struct {
+	// alpha2 is the ISO 639-1 code, which is the normalised form.
+	alpha2 string
+	// alpha3 holds the ISO 639-2 codes, bibliographic first.
+	alpha3 []string
+	// names in English, and natively where it differs.
+	names []string
+}
+
+var languages = []language{
+	{"ar", []string{"ara"}, []string{"arabic"}},
+	{"bg", []string{"bul"}, []string{"bulgarian"}},
+	{"ca", []string{"cat"}, []string{"catalan"}},
+	{"cs", []string{"cze", "ces"}, []string{"czech", "čeština"}},
+	{"da", []string{"dan"}, []string{"danish", "dansk"}},
+	{"de", []string{"ger", "deu"}, []string{"german", "deutsch"}},
+	{"el", []string{"gre", "ell"}, []string{"greek"}},
+	{"en", []string{"eng"}, []string{"english"}},
+	{"es", []string{"spa"}, []string{"spanish", "español", "espanol"}},
+	{"et", []string{"est"}, []string{"estonian", "eesti"}},
+	{"fa", []string{"per", "fas"}, []string{"persian", "farsi"}},
+	{"fi", []string{"fin"}, []string{"finnish", "suomi"}},
+	{"fr", []string{"fre", "fra"}, []string{"french", "français", "francais"}},
+	{"he", []string{"heb"}, []string{"hebrew"}},
+	{"hi", []string{"hin"}, []string{"hindi"}},
+	{"hr", []string{"hrv"}, []string{"croatian", "hrvatski"}},
+	{"hu", []string{"hun"}, []string{"hungarian", "magyar"}},
+	{"id", []string{"ind"}, []string{"indonesian"}},
+	{"is", []string{"ice", "isl"}, []string{"icelandic", "íslenska"}},
+	{"it", []string{"ita"}, []string{"italian", "italiano"}},
+	{"ja", []string{"jpn"}, []string{"japanese", "日本語"}},
+	{"ko", []string{"kor"}, []string{"korean", "한국어"}},
+	{"lt", []string{"lit"}, []string{"lithuanian"}},
+	{"lv", []string{"lav"}, []string{"latvian"}},
+	{"ms", []string{"may", "msa"}, []string{"malay"}},
+	{"nb", []string{"nob"}, []string{"norwegian bokmål", "bokmål", "bokmal"}},
+	{"nl", []string{"dut", "nld"}, []string{"dutch", "nederlands"}},
+	{"no", []string{"nor"}, []string{"norwegian", "norsk"}},
+	{"pl", []string{"pol"}, []string{"polish", "polski"}},
+	{"pt", []string{"por"}, []string{"portuguese", "português", "portugues"}},
+	{"ro", []string{"rum", "ron"}, []string{"romanian", "română"}},
+	{"ru", []string{"rus"}, []string{"russian", "русский"}},
+	{"sk", []string{"slo", "slk"}, []string{"slovak"}},
+	{"sl", []string{"slv"}, []string{"slovenian", "slovene"}},
+	{"sr", []string{"srp"}, []string{"serbian"}},
+	{"sv", []string{"swe"}, []string{"swedish", "svenska"}},
+	{"th", []string{"tha"}, []string{"thai"}},
+	{"tr", []string{"tur"}, []string{"turkish", "türkçe"}},
+	{"uk", []string{"ukr"}, []string{"ukrainian"}},
+	{"vi", []string{"vie"}, []string{"vietnamese"}},
+	{"zh", []string{"chi", "zho"}, []string{"chinese", "中文", "mandarin", "cantonese"}},
+}
+
+// undetermined tags say nothing about the language of a stream.
+var undetermined = map[string]bool{
+	"":    true,
+	"und": true,
+	"mul": true,
+	"mis": true,
+	"zxx": true,
+}
+
+var byTag = func() map[string]*language {
+	m := make(map[string]*language)
+	for i := range languages {
+		l := &languages[i]
+		m[l.alpha2] = l
+		for _, t := range l.alpha3 {
+			m[t] = l
+		}
+		for _, n := range l.names {
+			m[n] = l
+		}
+	}
+	return m
+}()
+
+// Normalize returns the ISO 639-1 code of tag, which may be an ISO 639-1 or
+// 639-2 code, a BCP-47 tag such as "pt-BR", or a language name. Regions and
+// scripts are dropped. Unknown codes are returned lowercased, so that they
+// still match themselves, and undetermined tags such as "und" return "".
+func Normalize(tag string) string {
+	t := strings.ToLower(strings.TrimSpace(tag))
+	if l, ok := byTag[t]; ok {
+		return l.alpha2
+	}
+	base, _, _ := strings.Cut(strings.ReplaceAll(t, "_", "-"), "-")
+	if undetermined[base] {
+		return ""
+	}
+	if l, ok := byTag[base]; ok {
+		return l.alpha2
+	}
+	return base
+}
+
+// Known reports if tag is a language code or name of a known language, as
+// opposed to something which merely looks like one.
+func Known(tag string) bool {
+	_, ok := byTag[strings.ToLower(strings.TrimSpace(tag))]
+	return ok
+}
+
+// Alpha3 returns the ISO 639-2/B code of tag, as used in Matroska files, or
+// "und" if the language is unknown.
+func Alpha3(tag string) string {
+	if l, ok := byTag[Normalize(tag)]; ok {
+		return l.alpha3[0]
+	}
+	return "und"
+}
+
+// Alpha3T returns the ISO 639-2/T code of tag, which differs from the
+// bibliographic one for a handful of languages, such as "deu" for German.
+// Returns "und" if the language is unknown.
+func Alpha3T(tag string) string {
+	if l, ok := byTag[Normalize(tag)]; ok {
+		return l.alpha3[len(l.alpha3)-1]
+	}
+	return "und"
+}
+
+// Preferences lists languages in order of preference, as ISO 639-1 codes.
+type Preferences []string
+
+// Default preferences, used when nothing is configured.
+var Default = Preferences{"en"}
+
+// Parse a comma or whitespace separated list of language tags. Duplicates
+// and undetermined tags are dropped.
+func Parse(s string) Preferences {
+	return FromTags(strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ';' || r == ' ' || r == '\t'
+	}))
+}
+
+// FromTags normalises tags into Preferences, keeping their order.
+func FromTags(tags []string) Preferences {
+	var p Preferences
+	for _, t := range tags {
+		n := Normalize(t)
+		if n == "" || p.Rank(n) >= 0 {
+			continue
+		}
+		p = append(p, n)
+	}
+	return p
+}
+
+// FromEnv reads KINOVIEW_SUBTITLE_LANGUAGES, falling back to Default.
+func FromEnv() Preferences {
+	return Parse(os.Getenv("KINOVIEW_SUBTITLE_LANGUAGES")).Or(Default)
+}
+
+// Rank of tag within p, 0 being the most preferred. Returns -1 if tag isn't
+// preferred, or is undetermined.
+func (p Preferences) Rank(tag string) int {
+	n := Normalize(tag)
+	if n == "" {
+		return -1
+	}
+	for i, l := range p {
+		if l == n {
+			return i
+		}
+	}
+	return -1
+}
+
+// Or returns p, or fallback if p is empty.
+func (p Preferences) Or(fallback Preferences) Preferences {
+	if len(p) == 0 {
+		return fallback
+	}
+	return p
+}
+
+// String formats p as a comma separated list, as accepted by Parse.
+func (p Preferences) String() string {
+	return strings.Join(p, ",")
+}
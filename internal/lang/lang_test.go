@@ -0,0 +1,74 @@
+package lang
+
+import (
+	"testing"
+
+	"github.com/baalimago/go_away_boilerplate/pkg/testboil"
+)
+
+func TestNormalize(t *testing.T) {
+	for tag, want := range map[string]string{
+		"en":       "en",
+		"ENG":      "en",
+		"English":  "en",
+		"en-GB":    "en",
+		"eng-US":   "en",
+		"swe":      "sv",
+		"sv_SE":    "sv",
+		"Svenska":  "sv",
+		"jpn":      "ja",
+		"ja-JP":    "ja",
+		"ger":      "de",
+		"deu":      "de",
+		"pt-BR":    "pt",
+		"zh-Hant":  "zh",
+		"und":      "",
+		"":         "",
+		"  mul ":   "",
+		"klingon":  "klingon",
+		"tlh":      "tlh",
+		"fre":      "fr",
+		"français": "fr",
+	} {
+		testboil.FailTestIfDiff(t, Normalize(tag), want)
+	}
+}
+
+func TestAlpha3(t *testing.T) {
+	testboil.FailTestIfDiff(t, Alpha3("sv"), "swe")
+	testboil.FailTestIfDiff(t, Alpha3("German"), "ger")
+	testboil.FailTestIfDiff(t, Alpha3("und"), "und")
+	testboil.FailTestIfDiff(t, Alpha3("tlh"), "und")
+}
+
+func TestAlpha3T(t *testing.T) {
+	testboil.FailTestIfDiff(t, Alpha3T("German"), "deu")
+	testboil.FailTestIfDiff(t, Alpha3T("fre"), "fra")
+	testboil.FailTestIfDiff(t, Alpha3T("sv"), "swe")
+	testboil.FailTestIfDiff(t, Alpha3T("tlh"), "und")
+}
+
+func TestKnown(t *testing.T) {
+	testboil.FailTestIfDiff(t, Known("Swedish"), true)
+	testboil.FailTestIfDiff(t, Known("JPN"), true)
+	testboil.FailTestIfDiff(t, Known("hint"), false)
+	testboil.FailTestIfDiff(t, Known("und"), false)
+}
+
+func TestParse(t *testing.T) {
+	p := Parse(" swe, Japanese;en-GB sv und")
+	testboil.FailTestIfDiff(t, p.String(), "sv,ja,en")
+	testboil.FailTestIfDiff(t, p.Rank("jpn"), 1)
+	testboil.FailTestIfDiff(t, p.Rank("eng"), 2)
+	testboil.FailTestIfDiff(t, p.Rank("fre"), -1)
+	testboil.FailTestIfDiff(t, p.Rank("und"), -1)
+
+	testboil.FailTestIfDiff(t, Parse("").Or(Default).String(), "en")
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("KINOVIEW_SUBTITLE_LANGUAGES", "")
+	testboil.FailTestIfDiff(t, FromEnv().String(), "en")
+	t.Setenv("KINOVIEW_SUBTITLE_LANGUAGES", "sv,ja")
+	testboil.FailTestIfDiff(t, FromEnv().String(), "sv,ja")
+}
@@ -0,0 +1,164 @@
+// Package audiotags reads tags and embedded cover art from audio files. It
+// understands ID3v1, ID3v2.2-2.4 (mp3), FLAC metadata blocks and Vorbis
+// comments in Ogg Vorbis/Opus.
+package audiotags
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+// ErrNoTags is returned when the file has no tags which could be read.
+var ErrNoTags = errors.New("no tags found")
+
+// maxTagSize caps how much of a file is read when looking for tags. Cover art
+// is the bulk of it, and nobody embeds 64MB covers.
+const maxTagSize = 64 << 20
+
+// Picture is an embedded cover image.
+type Picture struct {
+	MIMEType string
+	Data     []byte
+}
+
+// Read the tags and the front cover, if there is one, of the audio file at p.
+func Read(p string) (model.AudioTags, *Picture, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return model.AudioTags{}, nil, err
+	}
+	defer f.Close()
+	return read(f)
+}
+
+func read(r io.ReadSeeker) (model.AudioTags, *Picture, error) {
+	head := make([]byte, 10)
+	n, err := io.ReadFull(r, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return model.AudioTags{}, nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	head = head[:n]
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return model.AudioTags{}, nil, err
+	}
+
+	var tags model.AudioTags
+	var pic *Picture
+	switch {
+	case bytes.HasPrefix(head, []byte("ID3")):
+		tags, pic, err = readID3v2(r)
+		if err != nil {
+			return model.AudioTags{}, nil, err
+		}
+		// FLAC files may be prefixed by an ID3 tag
+		flacTags, flacPic, flacErr := readFLAC(r)
+		if flacErr == nil {
+			tags = merge(tags, flacTags)
+			if pic == nil {
+				pic = flacPic
+			}
+		}
+	case bytes.HasPrefix(head, []byte("fLaC")):
+		tags, pic, err = readFLAC(r)
+	case bytes.HasPrefix(head, []byte("OggS")):
+		tags, pic, err = readOgg(r)
+	}
+	if err != nil {
+		return model.AudioTags{}, nil, err
+	}
+	if v1, err := readID3v1(r); err == nil {
+		tags = merge(tags, v1)
+	}
+	if tags.IsEmpty() && pic == nil {
+		return model.AudioTags{}, nil, ErrNoTags
+	}
+	return tags, pic, nil
+}
+
+// merge fills in the zero fields of a with the values of b.
+func merge(a, b model.AudioTags) model.AudioTags {
+	fill := func(dst *string, src string) {
+		if *dst == "" {
+			*dst = src
+		}
+	}
+	fillInt := func(dst *int, src int) {
+		if *dst == 0 {
+			*dst = src
+		}
+	}
+	fill(&a.Title, b.Title)
+	fill(&a.Artist, b.Artist)
+	fill(&a.AlbumArtist, b.AlbumArtist)
+	fill(&a.Album, b.Album)
+	fill(&a.Genre, b.Genre)
+	fillInt(&a.Year, b.Year)
+	fillInt(&a.Track, b.Track)
+	fillInt(&a.TrackTotal, b.TrackTotal)
+	fillInt(&a.Disc, b.Disc)
+	return a
+}
+
+// parseNumberPair parses "3" or "3/12" into (3, 12).
+func parseNumberPair(s string) (int, int) {
+	num, total, _ := strings.Cut(strings.TrimSpace(s), "/")
+	n, _ := strconv.Atoi(strings.TrimSpace(num))
+	t, _ := strconv.Atoi(strings.TrimSpace(total))
+	return n, t
+}
+
+// parseYear picks the year out of dates such as "1999", "1999-03-01" or
+// "1999-03-01T10:00".
+func parseYear(s string) int {
+	s = strings.TrimSpace(s)
+	if len(s) < 4 {
+		return 0
+	}
+	y, err := strconv.Atoi(s[:4])
+	if err != nil {
+		return 0
+	}
+	return y
+}
+
+// setField sets the tag field identified by the normalised key, shared by
+// ID3 frames and Vorbis comments.
+func setField(t *model.AudioTags, key, val string) {
+	val = strings.TrimSpace(val)
+	if val == "" {
+		return
+	}
+	switch key {
+	case "title":
+		t.Title = val
+	case "artist":
+		t.Artist = val
+	case "albumartist":
+		t.AlbumArtist = val
+	case "album":
+		t.Album = val
+	case "genre":
+		t.Genre = val
+	case "year":
+		if y := parseYear(val); y != 0 {
+			t.Year = y
+		}
+	case "track":
+		n, total := parseNumberPair(val)
+		t.Track = n
+		if total != 0 {
+			t.TrackTotal = total
+		}
+	case "tracktotal":
+		t.TrackTotal, _ = parseNumberPair(val)
+	case "disc":
+		t.Disc, _ = parseNumberPair(val)
+	}
+}
@@ -0,0 +1,232 @@
+package audiotags
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+var jpegData = []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x10, 'J', 'F', 'I', 'F'}
+
+func syncsafeBytes(n int) []byte {
+	return []byte{byte(n >> 21 & 0x7f), byte(n >> 14 & 0x7f), byte(n >> 7 & 0x7f), byte(n & 0x7f)}
+}
+
+func id3Frame(version byte, id string, data []byte) []byte {
+	var b bytes.Buffer
+	b.WriteString(id)
+	if version == 4 {
+		b.Write(syncsafeBytes(len(data)))
+	} else {
+		_ = binary.Write(&b, binary.BigEndian, uint32(len(data)))
+	}
+	b.Write([]byte{0, 0})
+	b.Write(data)
+	return b.Bytes()
+}
+
+func id3Tag(version byte, frames ...[]byte) []byte {
+	body := bytes.Join(frames, nil)
+	var b bytes.Buffer
+	b.WriteString("ID3")
+	b.Write([]byte{version, 0, 0})
+	b.Write(syncsafeBytes(len(body)))
+	b.Write(body)
+	return b.Bytes()
+}
+
+func textFrame(enc byte, s string) []byte {
+	if enc == 1 {
+		b := []byte{1, 0xFF, 0xFE}
+		for _, r := range s {
+			b = append(b, byte(r), 0)
+		}
+		return b
+	}
+	return append([]byte{enc}, []byte(s)...)
+}
+
+func vorbisComment(comments ...string) []byte {
+	var b bytes.Buffer
+	_ = binary.Write(&b, binary.LittleEndian, uint32(len("kinoview")))
+	b.WriteString("kinoview")
+	_ = binary.Write(&b, binary.LittleEndian, uint32(len(comments)))
+	for _, c := range comments {
+		_ = binary.Write(&b, binary.LittleEndian, uint32(len(c)))
+		b.WriteString(c)
+	}
+	return b.Bytes()
+}
+
+func flacPicture(picType uint32, mime string, data []byte) []byte {
+	var b bytes.Buffer
+	_ = binary.Write(&b, binary.BigEndian, picType)
+	_ = binary.Write(&b, binary.BigEndian, uint32(len(mime)))
+	b.WriteString(mime)
+	_ = binary.Write(&b, binary.BigEndian, uint32(0))
+	b.Write(make([]byte, 16))
+	_ = binary.Write(&b, binary.BigEndian, uint32(len(data)))
+	b.Write(data)
+	return b.Bytes()
+}
+
+func flacBlock(blockType byte, last bool, data []byte) []byte {
+	h := blockType
+	if last {
+		h |= 0x80
+	}
+	return append([]byte{h, byte(len(data) >> 16), byte(len(data) >> 8), byte(len(data))}, data...)
+}
+
+func oggPage(serial uint32, packets ...[]byte) []byte {
+	var segTable []byte
+	var body []byte
+	for _, p := range packets {
+		l := len(p)
+		for l >= 255 {
+			segTable = append(segTable, 255)
+			l -= 255
+		}
+		segTable = append(segTable, byte(l))
+		body = append(body, p...)
+	}
+	h := make([]byte, 27)
+	copy(h, "OggS")
+	binary.LittleEndian.PutUint32(h[14:18], serial)
+	h[26] = byte(len(segTable))
+	return append(append(h, segTable...), body...)
+}
+
+func writeTemp(t *testing.T, name string, b []byte) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(p, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestRead(t *testing.T) {
+	apic := append([]byte{0}, []byte("image/jpeg\x00\x03cover\x00")...)
+	apic = append(apic, jpegData...)
+
+	tests := []struct {
+		name    string
+		content []byte
+		want    model.AudioTags
+		wantPic bool
+	}{
+		{
+			name: "id3v2.3 with utf-16 and cover",
+			content: append(id3Tag(3,
+				id3Frame(3, "TIT2", textFrame(1, "Blåbär")),
+				id3Frame(3, "TPE1", textFrame(0, "Artist")),
+				id3Frame(3, "TALB", textFrame(0, "Album")),
+				id3Frame(3, "TRCK", textFrame(0, "3/12")),
+				id3Frame(3, "TPOS", textFrame(0, "2")),
+				id3Frame(3, "TYER", textFrame(0, "1999")),
+				id3Frame(3, "TCON", textFrame(0, "(17)")),
+				id3Frame(3, "APIC", apic),
+			), 0xFF, 0xFB, 0x90, 0x64),
+			want:    model.AudioTags{Title: "Blåbär", Artist: "Artist", Album: "Album", Track: 3, TrackTotal: 12, Disc: 2, Year: 1999, Genre: "Rock"},
+			wantPic: true,
+		},
+		{
+			name: "id3v2.4 utf-8 with recording date",
+			content: id3Tag(4,
+				id3Frame(4, "TIT2", textFrame(3, "Title")),
+				id3Frame(4, "TPE2", textFrame(3, "Various")),
+				id3Frame(4, "TDRC", textFrame(3, "2004-05-06")),
+			),
+			want: model.AudioTags{Title: "Title", AlbumArtist: "Various", Year: 2004},
+		},
+		{
+			name: "flac with vorbis comment and picture",
+			content: bytes.Join([][]byte{
+				[]byte("fLaC"),
+				flacBlock(0, false, make([]byte, 34)),
+				flacBlock(4, false, vorbisComment("TITLE=Song", "artist=Band", "ALBUMARTIST=Band", "ALBUM=Record", "TRACKNUMBER=7", "TRACKTOTAL=9", "DATE=2011-01-01", "GENRE=Jazz")),
+				flacBlock(6, true, flacPicture(3, "image/jpeg", jpegData)),
+			}, nil),
+			want:    model.AudioTags{Title: "Song", Artist: "Band", AlbumArtist: "Band", Album: "Record", Track: 7, TrackTotal: 9, Year: 2011, Genre: "Jazz"},
+			wantPic: true,
+		},
+		{
+			name: "ogg vorbis with embedded picture",
+			content: append(
+				oggPage(1, []byte("\x01vorbis-identification")),
+				oggPage(1, append(append([]byte("\x03vorbis"), vorbisComment(
+					"TITLE=Ogg Song",
+					"ARTIST=Ogg Band",
+					"METADATA_BLOCK_PICTURE="+base64.StdEncoding.EncodeToString(flacPicture(3, "image/jpeg", jpegData)),
+				)...), 1))...),
+			want:    model.AudioTags{Title: "Ogg Song", Artist: "Ogg Band"},
+			wantPic: true,
+		},
+		{
+			name: "opus",
+			content: append(
+				oggPage(5, []byte("OpusHead-identification")),
+				oggPage(5, append([]byte("OpusTags"), vorbisComment("TITLE=Opus Song")...))...),
+			want: model.AudioTags{Title: "Opus Song"},
+		},
+		{
+			name: "id3v1 only",
+			content: func() []byte {
+				b := make([]byte, 200)
+				tag := b[len(b)-128:]
+				copy(tag, "TAG")
+				copy(tag[3:], "V1 Title")
+				copy(tag[33:], "V1 Artist")
+				copy(tag[63:], "V1 Album")
+				copy(tag[93:], "1987")
+				tag[126] = 4
+				tag[127] = 8
+				return b
+			}(),
+			want: model.AudioTags{Title: "V1 Title", Artist: "V1 Artist", Album: "V1 Album", Year: 1987, Track: 4, Genre: "Jazz"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, pic, err := Read(writeTemp(t, "audio", tc.content))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("got %+v, want %+v", got, tc.want)
+			}
+			if tc.wantPic {
+				if pic == nil {
+					t.Fatal("expected a picture")
+				}
+				if pic.MIMEType != "image/jpeg" || !bytes.Equal(pic.Data, jpegData) {
+					t.Fatalf("unexpected picture: %v, %v", pic.MIMEType, pic.Data)
+				}
+			} else if pic != nil {
+				t.Fatalf("expected no picture, got: %v", pic.MIMEType)
+			}
+		})
+	}
+
+	t.Run("no tags", func(t *testing.T) {
+		_, _, err := Read(writeTemp(t, "audio", bytes.Repeat([]byte{0}, 300)))
+		if !errors.Is(err, ErrNoTags) {
+			t.Fatalf("expected ErrNoTags, got: %v", err)
+		}
+	})
+
+	t.Run("truncated tag errors", func(t *testing.T) {
+		tag := id3Tag(3, id3Frame(3, "TIT2", textFrame(0, "Title")))
+		_, _, err := Read(writeTemp(t, "audio", tag[:len(tag)-3]))
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
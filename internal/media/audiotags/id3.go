@@ -0,0 +1,327 @@
+package audiotags
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+var id3Frames = map[string]string{
+	"TIT2": "title", "TT2": "title",
+	"TPE1": "artist", "TP1": "artist",
+	"TPE2": "albumartist", "TP2": "albumartist",
+	"TALB": "album", "TAL": "album",
+	"TCON": "genre", "TCO": "genre",
+	"TRCK": "track", "TRK": "track",
+	"TPOS": "disc", "TPA": "disc",
+	"TYER": "year", "TYE": "year",
+	"TDRC": "year", "TDOR": "year", "TORY": "year",
+}
+
+// id3v1Genres is the original genre list which ID3v1 and numeric ID3v2 TCON
+// values refer to.
+var id3v1Genres = []string{
+	"Blues", "Classic Rock", "Country", "Dance", "Disco", "Funk", "Grunge",
+	"Hip-Hop", "Jazz", "Metal", "New Age", "Oldies", "Other", "Pop", "R&B",
+	"Rap", "Reggae", "Rock", "Techno", "Industrial", "Alternative", "Ska",
+	"Death Metal", "Pranks", "Soundtrack", "Euro-Techno", "Ambient",
+	"Trip-Hop", "Vocal", "Jazz+Funk", "Fusion", "Trance", "Classical",
+	"Instrumental", "Acid", "House", "Game", "Sound Clip", "Gospel", "Noise",
+	"AlternRock", "Bass", "Soul", "Punk", "Space", "Meditative",
+	"Instrumental Pop", "Instrumental Rock", "Ethnic", "Gothic", "Darkwave",
+	"Techno-Industrial", "Electronic", "Pop-Folk", "Eurodance", "Dream",
+	"Southern Rock", "Comedy", "Cult", "Gangsta", "Top 40", "Christian Rap",
+	"Pop/Funk", "Jungle", "Native American", "Cabaret", "New Wave",
+	"Psychadelic", "Rave", "Showtunes", "Trailer", "Lo-Fi", "Tribal",
+	"Acid Punk", "Acid Jazz", "Polka", "Retro", "Musical", "Rock & Roll",
+	"Hard Rock",
+}
+
+var numericGenreRE = regexp.MustCompile(`^\((\d+)\)(.*)$`)
+
+func genreName(s string) string {
+	s = strings.TrimSpace(s)
+	if m := numericGenreRE.FindStringSubmatch(s); m != nil {
+		if rest := strings.TrimSpace(m[2]); rest != "" {
+			return rest
+		}
+		s = m[1]
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		if n >= 0 && n < len(id3v1Genres) {
+			return id3v1Genres[n]
+		}
+		return ""
+	}
+	return s
+}
+
+func syncsafe(b []byte) int {
+	return int(b[0]&0x7f)<<21 | int(b[1]&0x7f)<<14 | int(b[2]&0x7f)<<7 | int(b[3]&0x7f)
+}
+
+// removeUnsync reverses the ID3 unsynchronisation scheme, which inserts a
+// 0x00 after every 0xFF.
+func removeUnsync(b []byte) []byte {
+	return bytes.ReplaceAll(b, []byte{0xFF, 0x00}, []byte{0xFF})
+}
+
+// readID3v2 reads the ID3v2 tag at the start of r and leaves r positioned
+// right after it.
+func readID3v2(r io.ReadSeeker) (model.AudioTags, *Picture, error) {
+	header := make([]byte, 10)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return model.AudioTags{}, nil, fmt.Errorf("failed to read id3 header: %w", err)
+	}
+	version := header[3]
+	flags := header[5]
+	size := syncsafe(header[6:10])
+	if size > maxTagSize {
+		return model.AudioTags{}, nil, fmt.Errorf("id3 tag too large: %v bytes", size)
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return model.AudioTags{}, nil, fmt.Errorf("failed to read id3 tag: %w", err)
+	}
+	if flags&0x10 != 0 {
+		// Footer present
+		if _, err := r.Seek(10, io.SeekCurrent); err != nil {
+			return model.AudioTags{}, nil, err
+		}
+	}
+	if version < 4 && flags&0x80 != 0 {
+		body = removeUnsync(body)
+	}
+	if flags&0x40 != 0 && version >= 3 && len(body) >= 4 {
+		var extSize int
+		if version == 4 {
+			extSize = syncsafe(body[:4])
+		} else {
+			extSize = int(binary.BigEndian.Uint32(body[:4])) + 4
+		}
+		if extSize > len(body) {
+			return model.AudioTags{}, nil, errors.New("invalid id3 extended header")
+		}
+		body = body[extSize:]
+	}
+
+	var tags model.AudioTags
+	var pic *Picture
+	idLen, headerLen := 4, 10
+	if version == 2 {
+		idLen, headerLen = 3, 6
+	}
+	for len(body) >= headerLen && body[0] != 0 {
+		id := string(body[:idLen])
+		var frameSize int
+		var frameFlags uint16
+		switch version {
+		case 2:
+			frameSize = int(body[3])<<16 | int(body[4])<<8 | int(body[5])
+		case 3:
+			frameSize = int(binary.BigEndian.Uint32(body[4:8]))
+			frameFlags = binary.BigEndian.Uint16(body[8:10])
+		default:
+			frameSize = syncsafe(body[4:8])
+			frameFlags = binary.BigEndian.Uint16(body[8:10])
+		}
+		if frameSize <= 0 || headerLen+frameSize > len(body) {
+			break
+		}
+		data := body[headerLen : headerLen+frameSize]
+		body = body[headerLen+frameSize:]
+
+		if version == 4 {
+			if frameFlags&0x0001 != 0 && len(data) >= 4 {
+				// Data length indicator
+				data = data[4:]
+			}
+			if frameFlags&0x0002 != 0 {
+				data = removeUnsync(data)
+			}
+		}
+		// Compressed or encrypted frames aren't worth the trouble
+		if version == 3 && frameFlags&0x00C0 != 0 || version == 4 && frameFlags&0x000C != 0 {
+			continue
+		}
+
+		switch {
+		case id == "APIC" || id == "PIC":
+			p, picType := parseAPIC(data, version == 2)
+			if p != nil && (pic == nil || picType == 3) {
+				pic = p
+			}
+		case strings.HasPrefix(id, "T"):
+			key, ok := id3Frames[id]
+			if !ok {
+				continue
+			}
+			val := firstValue(decodeID3Text(data))
+			if key == "genre" {
+				val = genreName(val)
+			}
+			// TYER is more specific than the recording/original dates
+			if key == "year" && tags.Year != 0 && id != "TYER" && id != "TYE" {
+				continue
+			}
+			setField(&tags, key, val)
+		}
+	}
+	return tags, pic, nil
+}
+
+func firstValue(s string) string {
+	v, _, _ := strings.Cut(s, "\x00")
+	return v
+}
+
+// decodeID3Text decodes a text frame, whose first byte is the encoding.
+func decodeID3Text(data []byte) string {
+	if len(data) == 0 {
+		return ""
+	}
+	return decodeID3String(data[0], data[1:])
+}
+
+func decodeID3String(enc byte, b []byte) string {
+	switch enc {
+	case 1, 2:
+		return decodeUTF16(b, enc == 2)
+	case 3:
+		return strings.TrimRight(string(b), "\x00")
+	default:
+		runes := make([]rune, len(b))
+		for i, c := range b {
+			runes[i] = rune(c)
+		}
+		return strings.TrimRight(string(runes), "\x00")
+	}
+}
+
+func decodeUTF16(b []byte, bigEndian bool) string {
+	var order binary.ByteOrder = binary.LittleEndian
+	if bigEndian {
+		order = binary.BigEndian
+	}
+	if len(b) >= 2 {
+		switch {
+		case b[0] == 0xFE && b[1] == 0xFF:
+			order = binary.BigEndian
+			b = b[2:]
+		case b[0] == 0xFF && b[1] == 0xFE:
+			order = binary.LittleEndian
+			b = b[2:]
+		}
+	}
+	u := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		u = append(u, order.Uint16(b[i:]))
+	}
+	return strings.TrimRight(string(utf16.Decode(u)), "\x00")
+}
+
+// splitTerminated splits b at the first string terminator of the encoding,
+// which is two null bytes for UTF-16.
+func splitTerminated(enc byte, b []byte) ([]byte, []byte) {
+	if enc == 1 || enc == 2 {
+		for i := 0; i+1 < len(b); i += 2 {
+			if b[i] == 0 && b[i+1] == 0 {
+				return b[:i], b[i+2:]
+			}
+		}
+		return b, nil
+	}
+	i := bytes.IndexByte(b, 0)
+	if i < 0 {
+		return b, nil
+	}
+	return b[:i], b[i+1:]
+}
+
+// parseAPIC parses an attached picture frame and returns the picture and
+// its type, where 3 is the front cover.
+func parseAPIC(data []byte, v22 bool) (*Picture, byte) {
+	if len(data) < 2 {
+		return nil, 0
+	}
+	enc := data[0]
+	data = data[1:]
+	var mime string
+	if v22 {
+		if len(data) < 3 {
+			return nil, 0
+		}
+		switch strings.ToUpper(string(data[:3])) {
+		case "PNG":
+			mime = "image/png"
+		default:
+			mime = "image/jpeg"
+		}
+		data = data[3:]
+	} else {
+		i := bytes.IndexByte(data, 0)
+		if i < 0 {
+			return nil, 0
+		}
+		mime = string(data[:i])
+		data = data[i+1:]
+	}
+	if len(data) < 1 {
+		return nil, 0
+	}
+	picType := data[0]
+	_, data = splitTerminated(enc, data[1:])
+	if len(data) == 0 {
+		return nil, 0
+	}
+	return &Picture{MIMEType: normaliseImageMIME(mime), Data: data}, picType
+}
+
+func normaliseImageMIME(m string) string {
+	m = strings.ToLower(strings.TrimSpace(m))
+	switch m {
+	case "", "jpg", "image/jpg":
+		return "image/jpeg"
+	case "png":
+		return "image/png"
+	}
+	return m
+}
+
+// readID3v1 reads the 128 byte ID3v1 tag at the end of the file.
+func readID3v1(r io.ReadSeeker) (model.AudioTags, error) {
+	if _, err := r.Seek(-128, io.SeekEnd); err != nil {
+		return model.AudioTags{}, err
+	}
+	b := make([]byte, 128)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return model.AudioTags{}, err
+	}
+	if string(b[:3]) != "TAG" {
+		return model.AudioTags{}, ErrNoTags
+	}
+	field := func(f []byte) string {
+		return strings.TrimSpace(decodeID3String(0, bytes.TrimRight(f, "\x00 ")))
+	}
+	var tags model.AudioTags
+	setField(&tags, "title", field(b[3:33]))
+	setField(&tags, "artist", field(b[33:63]))
+	setField(&tags, "album", field(b[63:93]))
+	setField(&tags, "year", field(b[93:97]))
+	// ID3v1.1 stores the track in the last byte of the comment
+	if b[125] == 0 && b[126] != 0 {
+		tags.Track = int(b[126])
+	}
+	if int(b[127]) < len(id3v1Genres) {
+		tags.Genre = id3v1Genres[b[127]]
+	}
+	return tags, nil
+}
@@ -0,0 +1,255 @@
+package audiotags
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+var vorbisKeys = map[string]string{
+	"TITLE":        "title",
+	"ARTIST":       "artist",
+	"ALBUMARTIST":  "albumartist",
+	"ALBUM ARTIST": "albumartist",
+	"ALBUM":        "album",
+	"GENRE":        "genre",
+	"DATE":         "year",
+	"YEAR":         "year",
+	"TRACKNUMBER":  "track",
+	"TRACKTOTAL":   "tracktotal",
+	"TOTALTRACKS":  "tracktotal",
+	"DISCNUMBER":   "disc",
+}
+
+// readFLAC reads the metadata blocks of a FLAC stream starting at the current
+// position of r.
+func readFLAC(r io.Reader) (model.AudioTags, *Picture, error) {
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return model.AudioTags{}, nil, fmt.Errorf("failed to read flac marker: %w", err)
+	}
+	if string(magic) != "fLaC" {
+		return model.AudioTags{}, nil, errors.New("not a flac stream")
+	}
+	var tags model.AudioTags
+	var pic *Picture
+	header := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return model.AudioTags{}, nil, fmt.Errorf("failed to read flac block header: %w", err)
+		}
+		last := header[0]&0x80 != 0
+		blockType := header[0] & 0x7f
+		size := int(header[1])<<16 | int(header[2])<<8 | int(header[3])
+		block := make([]byte, size)
+		if _, err := io.ReadFull(r, block); err != nil {
+			return model.AudioTags{}, nil, fmt.Errorf("failed to read flac block: %w", err)
+		}
+		switch blockType {
+		case 4:
+			t, p, err := parseVorbisComment(block)
+			if err != nil {
+				return model.AudioTags{}, nil, err
+			}
+			tags = merge(tags, t)
+			if pic == nil {
+				pic = p
+			}
+		case 6:
+			p, picType, err := parseFLACPicture(block)
+			if err == nil && (pic == nil || picType == 3) {
+				pic = p
+			}
+		}
+		if last {
+			break
+		}
+	}
+	return tags, pic, nil
+}
+
+// parseVorbisComment parses a Vorbis comment block, without the framing bit
+// or packet type prefix. Cover art may be embedded as a base64 encoded FLAC
+// picture block.
+func parseVorbisComment(b []byte) (model.AudioTags, *Picture, error) {
+	var tags model.AudioTags
+	var pic *Picture
+	rd := bytes.NewReader(b)
+	readString := func() (string, error) {
+		var l uint32
+		if err := binary.Read(rd, binary.LittleEndian, &l); err != nil {
+			return "", err
+		}
+		if int(l) > rd.Len() {
+			return "", errors.New("vorbis comment length out of bounds")
+		}
+		s := make([]byte, l)
+		_, err := io.ReadFull(rd, s)
+		return string(s), err
+	}
+	if _, err := readString(); err != nil {
+		return tags, nil, fmt.Errorf("failed to read vorbis vendor: %w", err)
+	}
+	var count uint32
+	if err := binary.Read(rd, binary.LittleEndian, &count); err != nil {
+		return tags, nil, fmt.Errorf("failed to read vorbis comment count: %w", err)
+	}
+	for range count {
+		c, err := readString()
+		if err != nil {
+			return tags, nil, fmt.Errorf("failed to read vorbis comment: %w", err)
+		}
+		k, v, ok := strings.Cut(c, "=")
+		if !ok {
+			continue
+		}
+		k = strings.ToUpper(k)
+		if k == "METADATA_BLOCK_PICTURE" {
+			raw, err := base64.StdEncoding.DecodeString(v)
+			if err != nil {
+				continue
+			}
+			p, picType, err := parseFLACPicture(raw)
+			if err == nil && (pic == nil || picType == 3) {
+				pic = p
+			}
+			continue
+		}
+		key, ok := vorbisKeys[k]
+		if !ok {
+			continue
+		}
+		// Multi-valued fields repeat the key, keep the first one
+		if key != "tracktotal" && hasField(tags, key) {
+			continue
+		}
+		setField(&tags, key, v)
+	}
+	return tags, pic, nil
+}
+
+func hasField(t model.AudioTags, key string) bool {
+	switch key {
+	case "title":
+		return t.Title != ""
+	case "artist":
+		return t.Artist != ""
+	case "albumartist":
+		return t.AlbumArtist != ""
+	case "album":
+		return t.Album != ""
+	case "genre":
+		return t.Genre != ""
+	case "year":
+		return t.Year != 0
+	case "track":
+		return t.Track != 0
+	case "disc":
+		return t.Disc != 0
+	}
+	return false
+}
+
+// parseFLACPicture parses a FLAC PICTURE block.
+func parseFLACPicture(b []byte) (*Picture, byte, error) {
+	rd := bytes.NewReader(b)
+	var picType, mimeLen uint32
+	if err := binary.Read(rd, binary.BigEndian, &picType); err != nil {
+		return nil, 0, err
+	}
+	if err := binary.Read(rd, binary.BigEndian, &mimeLen); err != nil {
+		return nil, 0, err
+	}
+	if int(mimeLen) > rd.Len() {
+		return nil, 0, errors.New("picture mime length out of bounds")
+	}
+	mime := make([]byte, mimeLen)
+	if _, err := io.ReadFull(rd, mime); err != nil {
+		return nil, 0, err
+	}
+	var descLen uint32
+	if err := binary.Read(rd, binary.BigEndian, &descLen); err != nil {
+		return nil, 0, err
+	}
+	if int(descLen)+16 > rd.Len() {
+		return nil, 0, errors.New("picture description length out of bounds")
+	}
+	// description, width, height, depth, colors
+	if _, err := rd.Seek(int64(descLen)+16, io.SeekCurrent); err != nil {
+		return nil, 0, err
+	}
+	var dataLen uint32
+	if err := binary.Read(rd, binary.BigEndian, &dataLen); err != nil {
+		return nil, 0, err
+	}
+	if int(dataLen) > rd.Len() {
+		return nil, 0, errors.New("picture data length out of bounds")
+	}
+	data := make([]byte, dataLen)
+	if _, err := io.ReadFull(rd, data); err != nil {
+		return nil, 0, err
+	}
+	return &Picture{MIMEType: normaliseImageMIME(string(mime)), Data: data}, byte(picType), nil
+}
+
+// readOgg reads the comment header, the second packet of the first logical
+// stream, of an Ogg Vorbis or Opus file.
+func readOgg(r io.Reader) (model.AudioTags, *Picture, error) {
+	var packets [][]byte
+	var cur []byte
+	var serial uint32
+	first := true
+	read := 0
+	header := make([]byte, 27)
+	for len(packets) < 2 {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return model.AudioTags{}, nil, fmt.Errorf("failed to read ogg page: %w", err)
+		}
+		if string(header[:4]) != "OggS" {
+			return model.AudioTags{}, nil, errors.New("invalid ogg page")
+		}
+		pageSerial := binary.LittleEndian.Uint32(header[14:18])
+		if first {
+			serial = pageSerial
+			first = false
+		}
+		segTable := make([]byte, header[26])
+		if _, err := io.ReadFull(r, segTable); err != nil {
+			return model.AudioTags{}, nil, fmt.Errorf("failed to read ogg segment table: %w", err)
+		}
+		for _, l := range segTable {
+			seg := make([]byte, l)
+			if _, err := io.ReadFull(r, seg); err != nil {
+				return model.AudioTags{}, nil, fmt.Errorf("failed to read ogg segment: %w", err)
+			}
+			read += int(l)
+			if read > maxTagSize {
+				return model.AudioTags{}, nil, errors.New("ogg comment header too large")
+			}
+			if pageSerial != serial {
+				continue
+			}
+			cur = append(cur, seg...)
+			if l < 255 {
+				packets = append(packets, cur)
+				cur = nil
+			}
+		}
+	}
+	comment := packets[1]
+	switch {
+	case bytes.HasPrefix(comment, []byte("\x03vorbis")):
+		comment = comment[7:]
+	case bytes.HasPrefix(comment, []byte("OpusTags")):
+		comment = comment[8:]
+	default:
+		return model.AudioTags{}, nil, errors.New("unknown ogg comment header")
+	}
+	return parseVorbisComment(comment)
+}
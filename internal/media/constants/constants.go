@@ -1,5 +1,11 @@
 package constants
 
+// tagFields of MetadataFormat, also asked for on their own, see TagsFormat.
+const tagFields = `	"genre": "<GENRES, comma separated (e.g. Crime, Thriller)>" (string),
+	"mood": [ "MOOD 0 (e.g. tense, uplifting, melancholic)" (string), "MOOD 1" (string), ... ],
+	"age_rating": "<AGE RATING (MPA rating for films, e.g. PG-13, TV Parental Guidelines rating for series, e.g. TV-MA)>" (string),
+	"content_warnings": [ "CONTENT WARNING 0 (e.g. violence, drug use, sexual content, only those which apply)" (string), ... ]`
+
 const MetadataFormat = `{
 	"name": "<NAME>",
 	"showName": "<SERIES NAME (if the media is part of a series, e.g. episodes or extras)>" (string),
@@ -11,5 +17,25 @@ const MetadataFormat = `{
 	"duration_min": <DURATION OF MEDIA IN MINUTES> (int),
 	"season": <SEASON (if series)> (int),
 	"episode": <EPISODE NUMBER (if series)> (int),
-	"extra_to": "<MAIN MEDIA NAME (if extras, such as behind the scenes)>" (string)
+	"extra_to": "<MAIN MEDIA NAME (if extras, such as behind the scenes)>" (string),
+` + tagFields + `
+}`
+
+// TagsFormat is the tags of MetadataFormat alone, to fill in the tags of
+// media classified before they were asked for.
+const TagsFormat = `{
+` + tagFields + `
+}`
+
+const MusicMetadataFormat = `{
+	"name": "<TRACK TITLE>" (string),
+	"artist": "<PERFORMING ARTIST>" (string),
+	"album_artist": "<ALBUM ARTIST (if different from the performing artist, e.g. compilations)>" (string),
+	"album": "<ALBUM NAME>" (string),
+	"year": <RELEASE YEAR OF ALBUM> (int),
+	"track": <TRACK NUMBER ON ALBUM> (int),
+	"disc": <DISC NUMBER (if the album spans multiple discs)> (int),
+	"genre": "<GENRE>" (string),
+	"duration_min": <DURATION OF TRACK IN MINUTES> (int),
+	"description": "<DESCRIPTION OF TRACK (max 50 words)>" (string)
 }`
@@ -0,0 +1,377 @@
+// Package exif reads the subset of EXIF metadata which kinoview cares about
+// from JPEG, PNG, WebP and TIFF images.
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+// ErrNoExif is returned when the image carries no EXIF metadata.
+var ErrNoExif = errors.New("no exif metadata found")
+
+// maxExifSize bounds the amount of memory spent on a single EXIF block.
+const maxExifSize = 1 << 20
+
+const (
+	tagMake             = 0x010F
+	tagModel            = 0x0110
+	tagOrientation      = 0x0112
+	tagDateTime         = 0x0132
+	tagExifIFD          = 0x8769
+	tagGPSIFD           = 0x8825
+	tagDateTimeOriginal = 0x9003
+	tagDateTimeDigitize = 0x9004
+	tagOffsetOriginal   = 0x9011
+	tagLensModel        = 0xA434
+
+	tagGPSLatRef = 0x0001
+	tagGPSLat    = 0x0002
+	tagGPSLonRef = 0x0003
+	tagGPSLon    = 0x0004
+)
+
+// Read the EXIF metadata of the image at p.
+func Read(p string) (model.PhotoMetadata, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return model.PhotoMetadata{}, err
+	}
+	defer f.Close()
+	raw, err := find(f)
+	if err != nil {
+		return model.PhotoMetadata{}, err
+	}
+	return Parse(raw)
+}
+
+// find the raw TIFF structure holding the EXIF metadata within the image.
+func find(r io.ReadSeeker) ([]byte, error) {
+	head := make([]byte, 12)
+	n, err := io.ReadFull(r, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	head = head[:n]
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	switch {
+	case bytes.HasPrefix(head, []byte{0xFF, 0xD8}):
+		return findJPEG(r)
+	case bytes.HasPrefix(head, []byte("\x89PNG\r\n\x1a\n")):
+		return findPNG(r)
+	case len(head) >= 12 && string(head[:4]) == "RIFF" && string(head[8:12]) == "WEBP":
+		return findWebP(r)
+	case bytes.HasPrefix(head, []byte("II*\x00")) || bytes.HasPrefix(head, []byte("MM\x00*")):
+		return readLimited(r, maxExifSize)
+	}
+	return nil, ErrNoExif
+}
+
+func readLimited(r io.Reader, n int) ([]byte, error) {
+	b, err := io.ReadAll(io.LimitReader(r, int64(n)))
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// findJPEG walks the JPEG markers up until the start of scan, looking for an
+// APP1 segment with the Exif identifier.
+func findJPEG(r io.ReadSeeker) ([]byte, error) {
+	if _, err := r.Seek(2, io.SeekStart); err != nil {
+		return nil, err
+	}
+	marker := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(r, marker); err != nil {
+			return nil, ErrNoExif
+		}
+		if marker[0] != 0xFF {
+			return nil, errors.New("invalid jpeg marker")
+		}
+		// Start of scan, or end of image. No metadata after this.
+		if marker[1] == 0xDA || marker[1] == 0xD9 {
+			return nil, ErrNoExif
+		}
+		size := int(binary.BigEndian.Uint16(marker[2:])) - 2
+		if size < 0 {
+			return nil, errors.New("invalid jpeg segment size")
+		}
+		if marker[1] != 0xE1 {
+			if _, err := r.Seek(int64(size), io.SeekCurrent); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		seg := make([]byte, size)
+		if _, err := io.ReadFull(r, seg); err != nil {
+			return nil, fmt.Errorf("failed to read app1 segment: %w", err)
+		}
+		if bytes.HasPrefix(seg, []byte("Exif\x00\x00")) {
+			return seg[6:], nil
+		}
+	}
+}
+
+// findPNG looks for the eXIf chunk, which has to come before the image data.
+func findPNG(r io.ReadSeeker) ([]byte, error) {
+	if _, err := r.Seek(8, io.SeekStart); err != nil {
+		return nil, err
+	}
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return nil, ErrNoExif
+		}
+		size := int64(binary.BigEndian.Uint32(header[:4]))
+		switch string(header[4:]) {
+		case "eXIf":
+			if size > maxExifSize {
+				return nil, fmt.Errorf("exif chunk too large: %v bytes", size)
+			}
+			b := make([]byte, size)
+			if _, err := io.ReadFull(r, b); err != nil {
+				return nil, fmt.Errorf("failed to read exif chunk: %w", err)
+			}
+			return b, nil
+		case "IDAT", "IEND":
+			return nil, ErrNoExif
+		}
+		// Data and crc
+		if _, err := r.Seek(size+4, io.SeekCurrent); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// findWebP looks for the EXIF chunk of an extended WebP file.
+func findWebP(r io.ReadSeeker) ([]byte, error) {
+	if _, err := r.Seek(12, io.SeekStart); err != nil {
+		return nil, err
+	}
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return nil, ErrNoExif
+		}
+		size := int64(binary.LittleEndian.Uint32(header[4:]))
+		if string(header[:4]) == "EXIF" {
+			if size > maxExifSize {
+				return nil, fmt.Errorf("exif chunk too large: %v bytes", size)
+			}
+			b := make([]byte, size)
+			if _, err := io.ReadFull(r, b); err != nil {
+				return nil, fmt.Errorf("failed to read exif chunk: %w", err)
+			}
+			// Some encoders keep the jpeg identifier
+			return bytes.TrimPrefix(b, []byte("Exif\x00\x00")), nil
+		}
+		// Chunks are padded to an even size
+		if _, err := r.Seek(size+size%2, io.SeekCurrent); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Parse the EXIF metadata from a raw TIFF structure.
+func Parse(b []byte) (model.PhotoMetadata, error) {
+	t, err := newTIFF(b)
+	if err != nil {
+		return model.PhotoMetadata{}, err
+	}
+	ifd0, err := t.ifd(t.order.Uint32(b[4:8]))
+	if err != nil {
+		return model.PhotoMetadata{}, fmt.Errorf("failed to read ifd0: %w", err)
+	}
+
+	var meta model.PhotoMetadata
+	meta.CameraMake = t.ascii(ifd0[tagMake])
+	meta.CameraModel = t.ascii(ifd0[tagModel])
+	meta.Orientation = int(t.uint(ifd0[tagOrientation]))
+	if meta.Orientation > 8 {
+		meta.Orientation = 0
+	}
+	takenAt := t.ascii(ifd0[tagDateTime])
+	var offset string
+
+	if e, ok := ifd0[tagExifIFD]; ok {
+		// A broken sub-IFD shouldn't hide what's already been found
+		if exifIFD, err := t.ifd(t.uint(e)); err == nil {
+			if s := t.ascii(exifIFD[tagDateTimeOriginal]); s != "" {
+				takenAt = s
+			} else if s := t.ascii(exifIFD[tagDateTimeDigitize]); s != "" {
+				takenAt = s
+			}
+			offset = t.ascii(exifIFD[tagOffsetOriginal])
+			meta.LensModel = t.ascii(exifIFD[tagLensModel])
+		}
+	}
+	meta.TakenAt = parseDateTime(takenAt, offset)
+
+	if e, ok := ifd0[tagGPSIFD]; ok {
+		if gps, err := t.ifd(t.uint(e)); err == nil {
+			meta.GPS = t.coordinates(gps)
+		}
+	}
+	return meta, nil
+}
+
+// parseDateTime parses an EXIF timestamp, "2006:01:02 15:04:05". Without an
+// offset the wall clock time is kept as UTC.
+func parseDateTime(s, offset string) time.Time {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.HasPrefix(s, "0000") {
+		return time.Time{}
+	}
+	loc := time.UTC
+	if o, err := time.Parse("-07:00", strings.TrimSpace(offset)); err == nil {
+		_, secs := o.Zone()
+		loc = time.FixedZone("", secs)
+	}
+	t, err := time.ParseInLocation("2006:01:02 15:04:05", s, loc)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+type entry struct {
+	typ   uint16
+	count uint32
+	// value is the inline value, or the offset to it when larger than 4 bytes
+	value []byte
+}
+
+type tiff struct {
+	b     []byte
+	order binary.ByteOrder
+}
+
+func newTIFF(b []byte) (*tiff, error) {
+	if len(b) < 8 {
+		return nil, ErrNoExif
+	}
+	var order binary.ByteOrder
+	switch string(b[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, errors.New("invalid tiff byte order")
+	}
+	if order.Uint16(b[2:4]) != 42 {
+		return nil, errors.New("invalid tiff magic")
+	}
+	return &tiff{b: b, order: order}, nil
+}
+
+func (t *tiff) ifd(offset uint32) (map[uint16]entry, error) {
+	if int(offset)+2 > len(t.b) {
+		return nil, errors.New("ifd offset out of bounds")
+	}
+	count := int(t.order.Uint16(t.b[offset:]))
+	start := int(offset) + 2
+	if start+count*12 > len(t.b) {
+		return nil, errors.New("ifd entries out of bounds")
+	}
+	entries := make(map[uint16]entry, count)
+	for i := range count {
+		e := t.b[start+i*12 : start+(i+1)*12]
+		entries[t.order.Uint16(e)] = entry{
+			typ:   t.order.Uint16(e[2:]),
+			count: t.order.Uint32(e[4:]),
+			value: e[8:12],
+		}
+	}
+	return entries, nil
+}
+
+var typeSizes = map[uint16]int{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 7: 1, 9: 4, 10: 8}
+
+// data returns the bytes of the entry value, or nil if they're out of bounds.
+func (t *tiff) data(e entry) []byte {
+	size, ok := typeSizes[e.typ]
+	if !ok || e.count == 0 {
+		return nil
+	}
+	n := size * int(e.count)
+	if n <= 4 {
+		return e.value[:n]
+	}
+	off := int(t.order.Uint32(e.value))
+	if off < 0 || off+n > len(t.b) || n > maxExifSize {
+		return nil
+	}
+	return t.b[off : off+n]
+}
+
+func (t *tiff) ascii(e entry) string {
+	if e.typ != 2 {
+		return ""
+	}
+	s, _, _ := strings.Cut(string(t.data(e)), "\x00")
+	return strings.TrimSpace(s)
+}
+
+func (t *tiff) uint(e entry) uint32 {
+	d := t.data(e)
+	switch {
+	case e.typ == 3 && len(d) >= 2:
+		return uint32(t.order.Uint16(d))
+	case (e.typ == 4 || e.typ == 9) && len(d) >= 4:
+		return t.order.Uint32(d)
+	}
+	return 0
+}
+
+func (t *tiff) rationals(e entry) []float64 {
+	if e.typ != 5 {
+		return nil
+	}
+	d := t.data(e)
+	ret := make([]float64, 0, len(d)/8)
+	for i := 0; i+8 <= len(d); i += 8 {
+		num, den := t.order.Uint32(d[i:]), t.order.Uint32(d[i+4:])
+		if den == 0 {
+			return nil
+		}
+		ret = append(ret, float64(num)/float64(den))
+	}
+	return ret
+}
+
+// coordinates from the GPS IFD, nil if they're missing or invalid.
+func (t *tiff) coordinates(gps map[uint16]entry) *model.Coordinates {
+	lat := degrees(t.rationals(gps[tagGPSLat]))
+	lon := degrees(t.rationals(gps[tagGPSLon]))
+	if math.IsNaN(lat) || math.IsNaN(lon) || math.Abs(lat) > 90 || math.Abs(lon) > 180 {
+		return nil
+	}
+	if strings.EqualFold(t.ascii(gps[tagGPSLatRef]), "S") {
+		lat = -lat
+	}
+	if strings.EqualFold(t.ascii(gps[tagGPSLonRef]), "W") {
+		lon = -lon
+	}
+	return &model.Coordinates{Latitude: lat, Longitude: lon}
+}
+
+// degrees from degrees, minutes and seconds.
+func degrees(dms []float64) float64 {
+	if len(dms) != 3 {
+		return math.NaN()
+	}
+	return dms[0] + dms[1]/60 + dms[2]/3600
+}
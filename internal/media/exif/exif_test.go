@@ -0,0 +1,282 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/baalimago/go_away_boilerplate/pkg/testboil"
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+type field struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	data  []byte
+	// sub is a nested ifd which the field points to
+	sub []field
+}
+
+func ascii(tag uint16, s string) field {
+	return field{tag: tag, typ: 2, count: uint32(len(s) + 1), data: append([]byte(s), 0)}
+}
+
+func short(order binary.ByteOrder, tag uint16, v uint16) field {
+	d := make([]byte, 2)
+	order.PutUint16(d, v)
+	return field{tag: tag, typ: 3, count: 1, data: d}
+}
+
+func rationals(order binary.ByteOrder, tag uint16, vals ...[2]uint32) field {
+	d := make([]byte, 8*len(vals))
+	for i, v := range vals {
+		order.PutUint32(d[i*8:], v[0])
+		order.PutUint32(d[i*8+4:], v[1])
+	}
+	return field{tag: tag, typ: 5, count: uint32(len(vals)), data: d}
+}
+
+// writeIFD appends the ifd, and everything it refers to, to buf.
+func writeIFD(buf *bytes.Buffer, order binary.ByteOrder, fields []field) {
+	start := buf.Len()
+	dataStart := start + 2 + 12*len(fields) + 4
+	var data bytes.Buffer
+	var subs []int
+	entries := make([]byte, 0, 12*len(fields))
+	for i, f := range fields {
+		e := make([]byte, 12)
+		order.PutUint16(e, f.tag)
+		typ, count, d := f.typ, f.count, f.data
+		if f.sub != nil {
+			typ, count, d = 4, 1, make([]byte, 4)
+			subs = append(subs, i)
+		}
+		order.PutUint16(e[2:], typ)
+		order.PutUint32(e[4:], count)
+		if len(d) <= 4 {
+			copy(e[8:], d)
+		} else {
+			order.PutUint32(e[8:], uint32(dataStart+data.Len()))
+			data.Write(d)
+		}
+		entries = append(entries, e...)
+	}
+	count := make([]byte, 2)
+	order.PutUint16(count, uint16(len(fields)))
+	buf.Write(count)
+	buf.Write(entries)
+	buf.Write([]byte{0, 0, 0, 0})
+	buf.Write(data.Bytes())
+	for _, i := range subs {
+		order.PutUint32(buf.Bytes()[start+2+i*12+8:], uint32(buf.Len()))
+		writeIFD(buf, order, fields[i].sub)
+	}
+}
+
+func buildTIFF(order binary.ByteOrder, ifd0 []field) []byte {
+	var buf bytes.Buffer
+	if order == binary.LittleEndian {
+		buf.WriteString("II")
+	} else {
+		buf.WriteString("MM")
+	}
+	h := make([]byte, 6)
+	order.PutUint16(h, 42)
+	order.PutUint32(h[2:], 8)
+	buf.Write(h)
+	writeIFD(&buf, order, ifd0)
+	return buf.Bytes()
+}
+
+func fullTIFF(order binary.ByteOrder) []byte {
+	return buildTIFF(order, []field{
+		ascii(tagMake, "Canon"),
+		ascii(tagModel, "Canon EOS 5D"),
+		short(order, tagOrientation, 6),
+		ascii(tagDateTime, "2020:01:01 00:00:00"),
+		{tag: tagExifIFD, sub: []field{
+			ascii(tagDateTimeOriginal, "2019:07:14 18:30:05"),
+			ascii(tagOffsetOriginal, "+02:00"),
+			ascii(tagLensModel, "EF 50mm"),
+		}},
+		{tag: tagGPSIFD, sub: []field{
+			ascii(tagGPSLatRef, "N"),
+			rationals(order, tagGPSLat, [2]uint32{59, 1}, [2]uint32{19, 1}, [2]uint32{4680, 100}),
+			ascii(tagGPSLonRef, "W"),
+			rationals(order, tagGPSLon, [2]uint32{18, 1}, [2]uint32{4, 1}, [2]uint32{0, 1}),
+		}},
+	})
+}
+
+func wantFull() model.PhotoMetadata {
+	return model.PhotoMetadata{
+		TakenAt:     time.Date(2019, 7, 14, 18, 30, 5, 0, time.FixedZone("", 2*3600)),
+		CameraMake:  "Canon",
+		CameraModel: "Canon EOS 5D",
+		LensModel:   "EF 50mm",
+		Orientation: 6,
+		GPS:         &model.Coordinates{Latitude: 59 + 19.0/60 + 46.8/3600, Longitude: -(18 + 4.0/60)},
+	}
+}
+
+func checkMeta(t *testing.T, got, want model.PhotoMetadata) {
+	t.Helper()
+	if !got.TakenAt.Equal(want.TakenAt) {
+		t.Fatalf("takenAt: got %v, want %v", got.TakenAt, want.TakenAt)
+	}
+	testboil.FailTestIfDiff(t, got.CameraMake, want.CameraMake)
+	testboil.FailTestIfDiff(t, got.CameraModel, want.CameraModel)
+	testboil.FailTestIfDiff(t, got.LensModel, want.LensModel)
+	testboil.FailTestIfDiff(t, got.Orientation, want.Orientation)
+	if (got.GPS == nil) != (want.GPS == nil) {
+		t.Fatalf("gps: got %v, want %v", got.GPS, want.GPS)
+	}
+	if want.GPS != nil {
+		const eps = 1e-9
+		if d := got.GPS.Latitude - want.GPS.Latitude; d > eps || d < -eps {
+			t.Fatalf("latitude: got %v, want %v", got.GPS.Latitude, want.GPS.Latitude)
+		}
+		if d := got.GPS.Longitude - want.GPS.Longitude; d > eps || d < -eps {
+			t.Fatalf("longitude: got %v, want %v", got.GPS.Longitude, want.GPS.Longitude)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	t.Run("little endian", func(t *testing.T) {
+		got, err := Parse(fullTIFF(binary.LittleEndian))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		checkMeta(t, got, wantFull())
+	})
+
+	t.Run("big endian", func(t *testing.T) {
+		got, err := Parse(fullTIFF(binary.BigEndian))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		checkMeta(t, got, wantFull())
+	})
+
+	t.Run("falls back to ifd0 date time", func(t *testing.T) {
+		got, err := Parse(buildTIFF(binary.LittleEndian, []field{ascii(tagDateTime, "2020:01:02 03:04:05")}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		checkMeta(t, got, model.PhotoMetadata{TakenAt: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)})
+	})
+
+	t.Run("broken sub ifd keeps ifd0", func(t *testing.T) {
+		b := buildTIFF(binary.LittleEndian, []field{
+			ascii(tagMake, "Nikon"),
+			{tag: tagExifIFD, typ: 4, count: 1, data: []byte{0xff, 0xff, 0, 0}},
+		})
+		got, err := Parse(b)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		testboil.FailTestIfDiff(t, got.CameraMake, "Nikon")
+	})
+
+	t.Run("invalid header", func(t *testing.T) {
+		if _, err := Parse([]byte("XX\x2a\x00\x08\x00\x00\x00")); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
+func writeFile(t *testing.T, name string, b []byte) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(p, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func encodedImage(t *testing.T, format string) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	var buf bytes.Buffer
+	var err error
+	if format == "png" {
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, nil)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestRead(t *testing.T) {
+	raw := fullTIFF(binary.BigEndian)
+
+	t.Run("jpeg app1", func(t *testing.T) {
+		j := encodedImage(t, "jpeg")
+		app1 := []byte{0xFF, 0xE1, 0, 0}
+		binary.BigEndian.PutUint16(app1[2:], uint16(2+6+len(raw)))
+		app1 = append(append(app1, "Exif\x00\x00"...), raw...)
+		b := append(append(append([]byte{}, j[:2]...), app1...), j[2:]...)
+		got, err := Read(writeFile(t, "a.jpg", b))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		checkMeta(t, got, wantFull())
+	})
+
+	t.Run("png exif chunk", func(t *testing.T) {
+		p := encodedImage(t, "png")
+		// Insert after the IHDR chunk: signature (8) + length, type, data (13), crc
+		ihdrEnd := 8 + 8 + 13 + 4
+		chunk := make([]byte, 4)
+		binary.BigEndian.PutUint32(chunk, uint32(len(raw)))
+		chunk = append(append(append(chunk, "eXIf"...), raw...), 0, 0, 0, 0)
+		b := append(append(append([]byte{}, p[:ihdrEnd]...), chunk...), p[ihdrEnd:]...)
+		got, err := Read(writeFile(t, "a.png", b))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		checkMeta(t, got, wantFull())
+	})
+
+	t.Run("webp exif chunk", func(t *testing.T) {
+		var b bytes.Buffer
+		b.WriteString("RIFF\x00\x00\x00\x00WEBP")
+		b.WriteString("VP8X")
+		_ = binary.Write(&b, binary.LittleEndian, uint32(10))
+		b.Write(make([]byte, 10))
+		b.WriteString("EXIF")
+		_ = binary.Write(&b, binary.LittleEndian, uint32(len(raw)))
+		b.Write(raw)
+		got, err := Read(writeFile(t, "a.webp", b.Bytes()))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		checkMeta(t, got, wantFull())
+	})
+
+	t.Run("jpeg without exif", func(t *testing.T) {
+		_, err := Read(writeFile(t, "b.jpg", encodedImage(t, "jpeg")))
+		if !errors.Is(err, ErrNoExif) {
+			t.Fatalf("expected ErrNoExif, got: %v", err)
+		}
+	})
+
+	t.Run("png without exif", func(t *testing.T) {
+		_, err := Read(writeFile(t, "b.png", encodedImage(t, "png")))
+		if !errors.Is(err, ErrNoExif) {
+			t.Fatalf("expected ErrNoExif, got: %v", err)
+		}
+	})
+}
@@ -49,6 +49,9 @@ func computeContextFingerprint(clientCtx model.ClientContext, now time.Time) str
 		fmt.Fprintf(h, "vh:%s:%d|", vh.Name, bucket)
 	}
 
+	// Subtitle languages decide which subtitles are preloaded.
+	fmt.Fprintf(h, "sl:%s|", clientCtx.SubtitleLanguages)
+
 	// Day-of-week and part-of-day.
 	fmt.Fprintf(h, "dow:%s|", now.Weekday().String())
 	fmt.Fprintf(h, "pod:%s|", partOfDay(now))
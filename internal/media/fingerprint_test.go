@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/baalimago/kinoview/internal/lang"
 	"github.com/baalimago/kinoview/internal/model"
 )
 
@@ -126,6 +127,17 @@ func TestComputeContextFingerprint_ContextChange(t *testing.T) {
 	}
 }
 
+func TestComputeContextFingerprint_SubtitleLanguages(t *testing.T) {
+	now := time.Date(2026, 7, 25, 14, 0, 0, 0, time.UTC)
+
+	c1 := model.ClientContext{LastPlayedName: "Movie A"}
+	c2 := model.ClientContext{LastPlayedName: "Movie A", SubtitleLanguages: lang.Preferences{"sv"}}
+
+	if computeContextFingerprint(c1, now) == computeContextFingerprint(c2, now) {
+		t.Fatal("different subtitle languages must produce different fingerprints")
+	}
+}
+
 func TestComputeContextFingerprint_DayOfWeek(t *testing.T) {
 	c := model.ClientContext{LastPlayedName: "Movie A"}
 
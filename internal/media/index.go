@@ -16,7 +16,9 @@ import (
 	"github.com/baalimago/go_away_boilerplate/pkg/misc"
 	"github.com/baalimago/kinoview/internal/agents"
 	"github.com/baalimago/kinoview/internal/agents/recommender"
+	"github.com/baalimago/kinoview/internal/lang"
 	"github.com/baalimago/kinoview/internal/loghandler"
+	"github.com/baalimago/kinoview/internal/media/parental"
 	"github.com/baalimago/kinoview/internal/media/suggestions"
 	int_watcher "github.com/baalimago/kinoview/internal/media/watcher"
 	"github.com/baalimago/kinoview/internal/model"
@@ -34,9 +36,13 @@ type Storage interface {
 	Snapshot() []model.Item
 	ListHandlerFunc() http.HandlerFunc
 	VideoHandlerFunc() http.HandlerFunc
+	AudioHandlerFunc() http.HandlerFunc
+	ThumbnailHandlerFunc() http.HandlerFunc
 	ImageHandlerFunc() http.HandlerFunc
 	StreamListHandlerFunc() http.HandlerFunc
 	StreamHandlerFunc() http.HandlerFunc
+	DualStreamHandlerFunc() http.HandlerFunc
+	AttachmentHandlerFunc() http.HandlerFunc
 }
 
 type watcher interface {
@@ -86,9 +92,10 @@ const (
 )
 
 type Indexer struct {
-	watchPath string
-	watcher   watcher
-	store     Storage
+	watchPath   string
+	watcher     watcher
+	watcherOpts []int_watcher.RecursiveWatcherOption
+	store       Storage
 
 	// Agents
 	recommender           agents.Recommender
@@ -104,6 +111,26 @@ type Indexer struct {
 	// agents.Feedbacker contract). Nil when the notebook is disabled; the
 	// feedback handler then answers 501.
 	feedback agents.Feedbacker
+	// quotes searches the dialogue of the library. Nil when not configured;
+	// the quotes handler then answers 501.
+	quotes agents.QuoteSearcher
+	// reviews holds the items whose classification waits for review. Nil
+	// when not configured; the review handlers then answer 501.
+	reviews agents.ReviewQueue
+	// history keeps the metadata history of the items. Nil when not
+	// configured; the history handler then answers 501.
+	history agents.MetadataHistory
+	// classification tells what waits for classification. Nil when not
+	// configured; the queue handler then answers 501.
+	classification agents.ClassificationQueue
+	// parental limits what each device sees by its age rating. Nil when
+	// not configured; everything is then shown and the parental handlers
+	// answer 501.
+	parental *parental.Controls
+
+	// subtitleLanguages are the server's preferred subtitle languages, which
+	// clients fall back to.
+	subtitleLanguages lang.Preferences
 
 	// Agent support managers
 	clientContextMgr agents.ClientContextManager
@@ -150,6 +177,44 @@ func WithStorage(s Storage) IndexerOption {
 	}
 }
 
+// WithQuoteSearcher sets the index searched by GET /gallery/quotes.
+func WithQuoteSearcher(q agents.QuoteSearcher) IndexerOption {
+	return func(i *Indexer) {
+		i.quotes = q
+	}
+}
+
+// WithReviewQueue sets the queue served by /gallery/review.
+func WithReviewQueue(q agents.ReviewQueue) IndexerOption {
+	return func(i *Indexer) {
+		i.reviews = q
+	}
+}
+
+// WithMetadataHistory sets the history served by
+// /gallery/items/{id}/history.
+func WithMetadataHistory(h agents.MetadataHistory) IndexerOption {
+	return func(i *Indexer) {
+		i.history = h
+	}
+}
+
+// WithClassificationQueue sets the queue served by
+// /gallery/classification/queue.
+func WithClassificationQueue(q agents.ClassificationQueue) IndexerOption {
+	return func(i *Indexer) {
+		i.classification = q
+	}
+}
+
+// WithParentalControls limits the shows, suggestions and recommendations
+// each device gets by their age rating, and serves /gallery/parental.
+func WithParentalControls(c *parental.Controls) IndexerOption {
+	return func(i *Indexer) {
+		i.parental = c
+	}
+}
+
 func WithWatchPath(watchPath string) IndexerOption {
 	return func(i *Indexer) {
 		i.watchPath = watchPath
@@ -202,6 +267,14 @@ func WithConciergeInterval(d time.Duration) IndexerOption {
 	}
 }
 
+// WithSubtitleLanguages sets the preferred subtitle languages served to
+// clients which haven't configured their own. Defaults to lang.Default.
+func WithSubtitleLanguages(langs lang.Preferences) IndexerOption {
+	return func(i *Indexer) {
+		i.subtitleLanguages = langs
+	}
+}
+
 // WithConciergeCacheDir sets the directory where the concierge last-run
 // timestamp is persisted.
 func WithConciergeCacheDir(dir string) IndexerOption {
@@ -268,6 +341,14 @@ func WithPongGrace(d time.Duration) IndexerOption {
 	}
 }
 
+// WithWatcherOptions passes options on to the recursive file watcher. They're
+// applied after the defaults, so they may override the global ignore file.
+func WithWatcherOptions(opts ...int_watcher.RecursiveWatcherOption) IndexerOption {
+	return func(i *Indexer) {
+		i.watcherOpts = append(i.watcherOpts, opts...)
+	}
+}
+
 // withClock sets the time source for debounce checks. Only exported for
 // tests; production code uses time.Now.
 func withClock(fn func() time.Time) IndexerOption {
@@ -277,10 +358,6 @@ func withClock(fn func() time.Time) IndexerOption {
 }
 
 func NewIndexer(opts ...IndexerOption) (*Indexer, error) {
-	w, err := int_watcher.NewRecursiveWatcher()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create recursive watcher: %w", err)
-	}
 	cfgDir, err := os.UserConfigDir()
 	if err != nil {
 		ancli.Warnf("failed to find user config dir: %v", err)
@@ -288,7 +365,6 @@ func NewIndexer(opts ...IndexerOption) (*Indexer, error) {
 	claiPath := path.Join(cfgDir, "kinoview", "clai")
 
 	i := &Indexer{
-		watcher:           w,
 		clock:             time.Now,
 		pongGrace:         defaultPongGrace,
 		conciergeInterval: 6 * time.Hour,
@@ -298,14 +374,25 @@ func NewIndexer(opts ...IndexerOption) (*Indexer, error) {
 			ConfigDir:     claiPath,
 			InternalTools: []models.ToolName{},
 		}),
-		errorChannels: make(map[string]errorListener),
-		errorUpdates:  make(chan error, 1000),
+		errorChannels:     make(map[string]errorListener),
+		errorUpdates:      make(chan error, 1000),
+		subtitleLanguages: lang.Default,
+	}
+
+	if cfgDir != "" {
+		i.watcherOpts = append(i.watcherOpts, int_watcher.WithGlobalIgnoreFile(path.Join(cfgDir, "kinoview", int_watcher.IgnoreFileName)))
 	}
 
 	for _, opt := range opts {
 		opt(i)
 	}
 
+	w, err := int_watcher.NewRecursiveWatcher(i.watcherOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recursive watcher: %w", err)
+	}
+	i.watcher = w
+
 	if i.suggestions == nil {
 		sm, err := suggestions.NewManager("")
 		if err != nil {
@@ -655,10 +742,25 @@ func (i *Indexer) Handler() http.Handler {
 	mux.HandleFunc("/video/{id}", i.store.VideoHandlerFunc())
 	mux.HandleFunc("/streams/{vid}", i.store.StreamListHandlerFunc())
 	mux.HandleFunc("/streams/{vid}/stream/{stream_idx}", i.store.StreamHandlerFunc())
+	mux.HandleFunc("/streams/{vid}/dual/{primary_idx}/{secondary_idx}", i.store.DualStreamHandlerFunc())
+	mux.HandleFunc("/streams/{vid}/attachment/{stream_idx}", i.store.AttachmentHandlerFunc())
+	mux.HandleFunc("/audio/{id}", i.store.AudioHandlerFunc())
 	mux.HandleFunc("/image/{id}", i.store.ImageHandlerFunc())
+	mux.HandleFunc("/thumb/{id}", i.store.ThumbnailHandlerFunc())
 	mux.HandleFunc("/recommend", i.recomendHandler())
 	mux.HandleFunc("/suggestions", i.suggestionsHandler())
 	mux.HandleFunc("/shows", i.showsHandler())
+	mux.HandleFunc("/music", i.musicHandler())
+	mux.HandleFunc("/photos", i.photosHandler())
+	mux.HandleFunc("/quotes", i.quotesHandler())
+	mux.HandleFunc("/review", i.reviewsHandler())
+	mux.HandleFunc("/review/{id}", i.resolveReviewHandler())
+	mux.HandleFunc("/items/{id}/history", i.historyHandler())
+	mux.HandleFunc("/classification/queue", i.classificationQueueHandler())
+	mux.HandleFunc("/preferences", i.preferencesHandler())
+	mux.HandleFunc("/parental/override", i.parentalOverrideHandler())
+	mux.HandleFunc("/parental/device", i.parentalDeviceHandler())
+	mux.HandleFunc("/parental/audit", i.parentalAuditHandler())
 	mux.HandleFunc("/intro/story", i.introStoryHandler())
 	mux.HandleFunc("/intro/session-end", i.introSessionEndHandler())
 	mux.HandleFunc("/intro/feedback", i.introFeedbackHandler())
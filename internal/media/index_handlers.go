@@ -13,6 +13,7 @@ import (
 	"github.com/baalimago/go_away_boilerplate/pkg/ancli"
 	"github.com/baalimago/go_away_boilerplate/pkg/debug"
 	"github.com/baalimago/kinoview/internal/agents/butler"
+	"github.com/baalimago/kinoview/internal/lang"
 	"github.com/baalimago/kinoview/internal/model"
 	"golang.org/x/net/websocket"
 )
@@ -43,7 +44,7 @@ func (i *Indexer) recomendHandler() http.HandlerFunc {
 			return
 		}
 		goCtx := r.Context()
-		items := i.store.Snapshot()
+		items := i.allowedItems(r, i.store.Snapshot())
 		it, err := i.recommender.Recommend(goCtx, debug.IndentedJsonFmt(req), items)
 		if err != nil {
 			ancli.Errf("recommender failed: %v", err)
@@ -209,7 +210,7 @@ func (i *Indexer) runCascade(reason disconnectReason, clientCtx model.ClientCont
 	allItems := i.store.Snapshot()
 	var videos []model.Item
 	for _, it := range allItems {
-		if strings.Contains(it.MIMEType, "video") {
+		if strings.Contains(it.MIMEType, "video") && i.parental.AllowedForAgents(it) {
 			videos = append(videos, it)
 		}
 	}
@@ -285,7 +286,7 @@ func (i *Indexer) suggestionsHandler() http.HandlerFunc {
 		computing := i.butlerInFlight
 		i.butlerMu.Unlock()
 
-		recs := i.suggestions.Get()
+		recs := i.allowedSuggestions(r, i.suggestions.Get())
 		if recs == nil {
 			recs = []model.Suggestion{}
 		}
@@ -432,3 +433,24 @@ func (i *Indexer) introFeedbackHandler() http.HandlerFunc {
 func (i *Indexer) prepareNextStory(reason string) {
 	go i.theatre.Prepare(context.Background(), reason)
 }
+
+// preferencesHandler serves the server-side defaults which clients build
+// their own preferences upon, such as the preferred subtitle languages.
+func (i *Indexer) preferencesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		err := json.NewEncoder(w).Encode(struct {
+			SubtitleLanguages lang.Preferences `json:"subtitleLanguages"`
+		}{
+			SubtitleLanguages: i.subtitleLanguages.Or(lang.Default),
+		})
+		if err != nil {
+			http.Error(w, "failed to encode preferences", http.StatusInternalServerError)
+		}
+	}
+}
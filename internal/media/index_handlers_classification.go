@@ -0,0 +1,41 @@
+package media
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+// classificationQueueHandler lists what waits for classification, in the
+// order it will be classified, and what the workers are busy with. A nil
+// queue answers 501.
+func (i *Indexer) classificationQueueHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if i.classification == nil {
+			http.Error(w, "classification queue not configured", http.StatusNotImplemented)
+			return
+		}
+		q := i.classification.ClassificationQueue()
+		if refused := i.refusedIDs(r); len(refused) > 0 {
+			q.Queued = slices.DeleteFunc(q.Queued, func(c model.QueuedClassification) bool { return refused[c.ID] })
+			q.Workers = slices.DeleteFunc(q.Workers, func(a model.ClassificationAssignment) bool { return refused[a.ID] })
+		}
+		if q.Queued == nil {
+			q.Queued = []model.QueuedClassification{}
+		}
+		if q.Workers == nil {
+			q.Workers = []model.ClassificationAssignment{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(q); err != nil {
+			http.Error(w, "failed to encode classification queue", http.StatusInternalServerError)
+		}
+	}
+}
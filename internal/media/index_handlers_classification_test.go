@@ -0,0 +1,74 @@
+package media
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+type fakeClassificationQueue struct {
+	q model.ClassificationQueue
+}
+
+func (f *fakeClassificationQueue) ClassificationQueue() model.ClassificationQueue {
+	return f.q
+}
+
+func Test_classificationQueueHandler(t *testing.T) {
+	t.Parallel()
+
+	t.Run("lists", func(t *testing.T) {
+		eta := time.Now().Add(time.Minute).Truncate(time.Second)
+		i := &Indexer{classification: &fakeClassificationQueue{q: model.ClassificationQueue{
+			Queued:         []model.QueuedClassification{{ID: "a", Priority: model.PriorityBrowsed, ETA: eta}},
+			Workers:        []model.ClassificationAssignment{{Worker: 1, ID: "b"}},
+			AverageSeconds: 60,
+		}}}
+		rec := httptest.NewRecorder()
+		i.classificationQueueHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/classification/queue", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("code = %d: %s", rec.Code, rec.Body.String())
+		}
+		if !strings.Contains(rec.Body.String(), `"priority":"browsed"`) {
+			t.Errorf("expected the priority by name, got %v", rec.Body.String())
+		}
+		var resp model.ClassificationQueue
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Queued) != 1 || !resp.Queued[0].ETA.Equal(eta) || len(resp.Workers) != 1 || resp.Workers[0].ID != "b" {
+			t.Errorf("unexpected response: %+v", resp)
+		}
+	})
+
+	t.Run("empty is a list", func(t *testing.T) {
+		i := &Indexer{classification: &fakeClassificationQueue{}}
+		rec := httptest.NewRecorder()
+		i.classificationQueueHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/classification/queue", nil))
+		if got := strings.TrimSpace(rec.Body.String()); got != `{"queued":[],"workers":[],"average_seconds":0}` {
+			t.Errorf("body = %v", got)
+		}
+	})
+
+	t.Run("not configured", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		(&Indexer{}).classificationQueueHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/classification/queue", nil))
+		if rec.Code != http.StatusNotImplemented {
+			t.Errorf("code = %d", rec.Code)
+		}
+	})
+
+	t.Run("get only", func(t *testing.T) {
+		i := &Indexer{classification: &fakeClassificationQueue{}}
+		rec := httptest.NewRecorder()
+		i.classificationQueueHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/classification/queue", nil))
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("code = %d", rec.Code)
+		}
+	})
+}
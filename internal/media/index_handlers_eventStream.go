@@ -49,6 +49,10 @@ func (i *Indexer) broadcastToClient(ws *websocket.Conn, suggestionsCh <-chan mod
 			if !ok {
 				return
 			}
+			payload.Suggestions = i.allowedSuggestions(ws.Request(), payload.Suggestions)
+			if len(payload.Suggestions) == 0 {
+				payload.State = "empty"
+			}
 			event := model.Event[model.SuggestionsPayload]{
 				Type:    model.SuggestionsEvent,
 				Created: time.Now(),
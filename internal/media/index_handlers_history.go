@@ -0,0 +1,80 @@
+package media
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/baalimago/go_away_boilerplate/pkg/ancli"
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+// historyHandler serves the metadata history of the item at PathValue id on
+// GET, and reverts its metadata to the version in the model.RevertRequest
+// body on POST, answering with the item as it is after. A nil history
+// answers 501.
+func (i *Indexer) historyHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodGet+", "+http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if i.history == nil {
+			http.Error(w, "metadata history not configured", http.StatusNotImplemented)
+			return
+		}
+		id := r.PathValue("id")
+		if i.refusedID(w, r, id) {
+			return
+		}
+
+		var body any
+		if r.Method == http.MethodGet {
+			changes, err := i.history.History(id)
+			if !historyError(w, err) {
+				return
+			}
+			if changes == nil {
+				changes = []model.MetadataChange{}
+			}
+			body = model.HistoryResponse{ID: id, Changes: changes}
+		} else {
+			defer r.Body.Close()
+			dec := json.NewDecoder(io.LimitReader(r.Body, 1<<20))
+			dec.DisallowUnknownFields()
+			var req model.RevertRequest
+			if err := dec.Decode(&req); err != nil {
+				http.Error(w, "malformed revert request", http.StatusBadRequest)
+				return
+			}
+			item, err := i.history.RevertMetadata(id, req.Version)
+			if !historyError(w, err) {
+				return
+			}
+			body = item
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(body); err != nil {
+			http.Error(w, "failed to encode history", http.StatusInternalServerError)
+		}
+	}
+}
+
+// historyError answers err, if any, and reports whether there was none.
+func historyError(w http.ResponseWriter, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, model.ErrNoItem):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, model.ErrNoVersion):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		ancli.Errf("metadata history: %v", err)
+		http.Error(w, "failed to handle metadata history", http.StatusInternalServerError)
+	}
+	return false
+}
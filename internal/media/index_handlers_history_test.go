@@ -0,0 +1,99 @@
+package media
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+type fakeMetadataHistory struct {
+	changes    []model.MetadataChange
+	err        error
+	gotVersion int
+	reverted   model.Item
+}
+
+func (f *fakeMetadataHistory) History(id string) ([]model.MetadataChange, error) {
+	return f.changes, f.err
+}
+
+func (f *fakeMetadataHistory) RevertMetadata(id string, version int) (model.Item, error) {
+	f.gotVersion = version
+	return f.reverted, f.err
+}
+
+func Test_historyHandler(t *testing.T) {
+	t.Parallel()
+
+	t.Run("lists", func(t *testing.T) {
+		i := &Indexer{history: &fakeMetadataHistory{changes: []model.MetadataChange{
+			{Version: 1, By: model.Provenance{Source: model.SourceClassifier}},
+		}}}
+		req := httptest.NewRequest(http.MethodGet, "/items/a/history", nil)
+		req.SetPathValue("id", "a")
+		rec := httptest.NewRecorder()
+		i.historyHandler().ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("code = %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp model.HistoryResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.ID != "a" || len(resp.Changes) != 1 || resp.Changes[0].By.Source != model.SourceClassifier {
+			t.Errorf("unexpected response: %+v", resp)
+		}
+	})
+
+	t.Run("empty is a list", func(t *testing.T) {
+		i := &Indexer{history: &fakeMetadataHistory{}}
+		req := httptest.NewRequest(http.MethodGet, "/items/a/history", nil)
+		req.SetPathValue("id", "a")
+		rec := httptest.NewRecorder()
+		i.historyHandler().ServeHTTP(rec, req)
+		if got := strings.TrimSpace(rec.Body.String()); got != `{"id":"a","changes":[]}` {
+			t.Errorf("body = %v", got)
+		}
+	})
+
+	tests := []struct {
+		name     string
+		history  *fakeMetadataHistory
+		method   string
+		body     string
+		wantCode int
+	}{
+		{"revert", &fakeMetadataHistory{reverted: model.Item{ID: "a"}}, http.MethodPost, `{"version":2}`, http.StatusOK},
+		{"no such item", &fakeMetadataHistory{err: fmt.Errorf("%w: a", model.ErrNoItem)}, http.MethodGet, ``, http.StatusNotFound},
+		{"no such version", &fakeMetadataHistory{err: fmt.Errorf("%w: 9", model.ErrNoVersion)}, http.MethodPost, `{"version":9}`, http.StatusBadRequest},
+		{"malformed body", &fakeMetadataHistory{}, http.MethodPost, `{"version":`, http.StatusBadRequest},
+		{"unknown field", &fakeMetadataHistory{}, http.MethodPost, `{"to":2}`, http.StatusBadRequest},
+		{"store fails", &fakeMetadataHistory{err: errors.New("disk full")}, http.MethodGet, ``, http.StatusInternalServerError},
+		{"wrong method", &fakeMetadataHistory{}, http.MethodDelete, ``, http.StatusMethodNotAllowed},
+		{"not configured", nil, http.MethodGet, ``, http.StatusNotImplemented},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := &Indexer{}
+			if tt.history != nil {
+				i.history = tt.history
+			}
+			req := httptest.NewRequest(tt.method, "/items/a/history", strings.NewReader(tt.body))
+			req.SetPathValue("id", "a")
+			rec := httptest.NewRecorder()
+			i.historyHandler().ServeHTTP(rec, req)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("code = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body.String())
+			}
+			if rec.Code == http.StatusOK && tt.history.gotVersion != 2 {
+				t.Errorf("reverted to %v, want 2", tt.history.gotVersion)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package media
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+const (
+	unknownArtist = "Unknown Artist"
+	unknownAlbum  = "Unknown Album"
+)
+
+var (
+	// discDirRE matches directories splitting an album into discs, such as
+	// "CD1" or "Disc 2".
+	discDirRE = regexp.MustCompile(`(?i)^(?:cd|disc|disk)[\s._-]*(\d{1,2})$`)
+	// trackPrefixRE matches leading track numbers such as "03 - ", "3. " or
+	// "1-03 " (disc-track).
+	trackPrefixRE = regexp.MustCompile(`^(?:(\d{1,2})-)?(\d{1,3})[\s._-]+(.+)$`)
+)
+
+// musicTrackInfo is the resolved placement of one audio item.
+type musicTrackInfo struct {
+	artist string
+	album  string
+	title  string
+	year   int
+	track  int
+	disc   int
+}
+
+// extractMusicInfo resolves where an audio item belongs.
+// Strategy, per field:
+//  1. Tags read from the file
+//  2. Classifier metadata
+//  3. The path, assuming <Artist>/<Album>/[<Disc>/]<NN - Title>.ext
+func extractMusicInfo(it model.Item) musicTrackInfo {
+	var info musicTrackInfo
+	if a := it.Audio; a != nil {
+		info.artist = firstNonEmpty(a.AlbumArtist, a.Artist)
+		info.album = a.Album
+		info.title = a.Title
+		info.year = a.Year
+		info.track = a.Track
+		info.disc = a.Disc
+	}
+
+	if md := it.Metadata; md != nil {
+		if info.artist == "" {
+			info.artist = firstNonEmpty(md.AlbumArtist, md.Artist)
+		}
+		if info.album == "" {
+			info.album = md.Album
+		}
+		if info.title == "" {
+			info.title = md.Name
+		}
+		if info.year == 0 {
+			info.year = md.Year
+		}
+		if info.track == 0 {
+			info.track = md.Track
+		}
+		if info.disc == 0 {
+			info.disc = md.Disc
+		}
+	}
+
+	pathArtist, pathAlbum, pathDisc, pathTrack, pathTitle := parseMusicPath(it.Path)
+	if info.artist == "" {
+		info.artist = pathArtist
+	}
+	if info.album == "" {
+		info.album = pathAlbum
+	}
+	if info.title == "" {
+		info.title = pathTitle
+	}
+	if info.track == 0 {
+		info.track = pathTrack
+	}
+	if info.disc == 0 {
+		info.disc = pathDisc
+	}
+
+	if info.artist == "" {
+		info.artist = unknownArtist
+	}
+	if info.album == "" {
+		info.album = unknownAlbum
+	}
+	return info
+}
+
+// parseMusicPath derives artist, album, disc, track and title from a path
+// laid out as <Artist>/<Album>/[<Disc>/]<NN - Title>.ext.
+func parseMusicPath(p string) (artist, album string, disc, track int, title string) {
+	base := filepath.Base(p)
+	stem := strings.TrimSuffix(base, filepath.Ext(base))
+	title = strings.Join(strings.Fields(strings.ReplaceAll(stem, "_", " ")), " ")
+	if m := trackPrefixRE.FindStringSubmatch(title); m != nil {
+		disc, _ = strconv.Atoi(m[1])
+		track, _ = strconv.Atoi(m[2])
+		title = strings.TrimSpace(m[3])
+	}
+
+	dir := filepath.Dir(p)
+	if m := discDirRE.FindStringSubmatch(filepath.Base(dir)); m != nil {
+		disc, _ = strconv.Atoi(m[1])
+		dir = filepath.Dir(dir)
+	}
+	if dir == "." || dir == string(filepath.Separator) {
+		return "", "", disc, track, title
+	}
+	album = filepath.Base(dir)
+	parent := filepath.Dir(dir)
+	if parent != "." && parent != string(filepath.Separator) {
+		artist = filepath.Base(parent)
+	}
+	return artist, album, disc, track, title
+}
+
+// musicHandler groups all audio items into artists → albums → tracks.
+func (i *Indexer) musicHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(groupMusic(i.store.Snapshot())); err != nil {
+			http.Error(w, "failed to encode music", http.StatusInternalServerError)
+		}
+	}
+}
+
+func groupMusic(items []model.Item) model.MusicResponse {
+	artists := make(map[string]*model.MusicArtist)
+	albums := make(map[string]map[string]*model.MusicAlbum)
+
+	for _, item := range items {
+		if !strings.HasPrefix(item.MIMEType, "audio") {
+			continue
+		}
+		info := extractMusicInfo(item)
+
+		artistKey := normalizeShowName(info.artist)
+		artist, exists := artists[artistKey]
+		if !exists {
+			artist = &model.MusicArtist{Name: info.artist}
+			artists[artistKey] = artist
+			albums[artistKey] = make(map[string]*model.MusicAlbum)
+		}
+
+		albumKey := normalizeShowName(info.album)
+		album, exists := albums[artistKey][albumKey]
+		if !exists {
+			album = &model.MusicAlbum{Title: info.album, Tracks: []model.MusicTrack{}}
+			albums[artistKey][albumKey] = album
+		}
+		if album.Year == 0 {
+			album.Year = info.year
+		}
+		if album.CoverID == "" {
+			album.CoverID = item.Thumbnail.ID
+		}
+		album.Tracks = append(album.Tracks, model.MusicTrack{
+			Item:  item,
+			Title: info.title,
+			Track: info.track,
+			Disc:  info.disc,
+		})
+	}
+
+	resp := model.MusicResponse{Artists: make([]model.MusicArtist, 0, len(artists))}
+	for artistKey, artist := range artists {
+		for _, album := range albums[artistKey] {
+			sort.Slice(album.Tracks, func(a, b int) bool {
+				ta, tb := album.Tracks[a], album.Tracks[b]
+				if ta.Disc != tb.Disc {
+					return ta.Disc < tb.Disc
+				}
+				if ta.Track != tb.Track {
+					return ta.Track < tb.Track
+				}
+				return strings.ToLower(ta.Title) < strings.ToLower(tb.Title)
+			})
+			artist.Albums = append(artist.Albums, *album)
+		}
+		sort.Slice(artist.Albums, func(a, b int) bool {
+			aa, ab := artist.Albums[a], artist.Albums[b]
+			if aa.Year != ab.Year {
+				return aa.Year < ab.Year
+			}
+			return strings.ToLower(aa.Title) < strings.ToLower(ab.Title)
+		})
+		resp.Artists = append(resp.Artists, *artist)
+	}
+	sort.Slice(resp.Artists, func(a, b int) bool {
+		return strings.ToLower(resp.Artists[a].Name) < strings.ToLower(resp.Artists[b].Name)
+	})
+	return resp
+}
@@ -0,0 +1,111 @@
+package media
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+func Test_parseMusicPath(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		path                  string
+		wantArtist, wantAlbum string
+		wantDisc, wantTrack   int
+		wantTitle             string
+	}{
+		{"/music/Band/Record/03 - Song.mp3", "Band", "Record", 0, 3, "Song"},
+		{"/music/Band/Record/CD2/1-07 Other_Song.flac", "Band", "Record", 2, 7, "Other Song"},
+		{"/music/Band/Record/Disc 1/12. Closer.ogg", "Band", "Record", 1, 12, "Closer"},
+		{"Loose Track.mp3", "", "", 0, 0, "Loose Track"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.path, func(t *testing.T) {
+			artist, album, disc, track, title := parseMusicPath(tc.path)
+			if artist != tc.wantArtist || album != tc.wantAlbum || disc != tc.wantDisc ||
+				track != tc.wantTrack || title != tc.wantTitle {
+				t.Fatalf("got (%q, %q, %d, %d, %q)", artist, album, disc, track, title)
+			}
+		})
+	}
+}
+
+func Test_musicHandler(t *testing.T) {
+	t.Parallel()
+
+	t.Run("groups by artist, album and track", func(t *testing.T) {
+		classified := model.MediaMetadata{Name: "Classified", Artist: "beta", Album: "Meta", Year: 2001, Track: 1}
+		store := &mockStore{
+			items: []model.Item{
+				{
+					ID:        "t2",
+					Path:      "/music/whatever/02 - second.mp3",
+					MIMEType:  "audio/mpeg",
+					Audio:     &model.AudioTags{Title: "Second", Artist: "Alpha", Album: "Later", Year: 2010, Track: 2},
+					Thumbnail: model.Image{ID: "cover"},
+				},
+				{
+					ID:       "t1",
+					Path:     "/music/whatever/01 - first.mp3",
+					MIMEType: "audio/mpeg",
+					Audio:    &model.AudioTags{Title: "First", Artist: "alpha", AlbumArtist: "Alpha", Album: "later", Year: 2010, Track: 1},
+				},
+				{
+					ID:       "t3",
+					Path:     "/music/Alpha/Earlier/05 Path Only.flac",
+					MIMEType: "audio/flac",
+				},
+				{ID: "c", Path: "/music/Beta/Meta/x.mp3", MIMEType: "audio/mpeg", Metadata: &classified},
+				{ID: "v", Path: "/music/Alpha/Later/video.mp4", MIMEType: "video/mp4"},
+			},
+		}
+		rec := httptest.NewRecorder()
+		(&Indexer{store: store}).musicHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/music", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+
+		var resp model.MusicResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		if len(resp.Artists) != 2 {
+			t.Fatalf("expected 2 artists, got %d: %+v", len(resp.Artists), resp.Artists)
+		}
+		alpha := resp.Artists[0]
+		if alpha.Name != "Alpha" || len(alpha.Albums) != 2 {
+			t.Fatalf("unexpected first artist: %+v", alpha)
+		}
+		// Albums without a year sort first
+		if alpha.Albums[0].Title != "Earlier" || alpha.Albums[1].Title != "Later" {
+			t.Fatalf("unexpected album order: %q, %q", alpha.Albums[0].Title, alpha.Albums[1].Title)
+		}
+		later := alpha.Albums[1]
+		if later.Year != 2010 || later.CoverID != "cover" {
+			t.Fatalf("unexpected album: %+v", later)
+		}
+		if len(later.Tracks) != 2 || later.Tracks[0].ID != "t1" || later.Tracks[1].ID != "t2" {
+			t.Fatalf("unexpected track order: %+v", later.Tracks)
+		}
+		earlier := alpha.Albums[0]
+		if earlier.Tracks[0].Title != "Path Only" || earlier.Tracks[0].Track != 5 {
+			t.Fatalf("unexpected path derived track: %+v", earlier.Tracks[0])
+		}
+
+		beta := resp.Artists[1]
+		if beta.Name != "beta" || beta.Albums[0].Title != "Meta" || beta.Albums[0].Tracks[0].Title != "Classified" {
+			t.Fatalf("unexpected classified artist: %+v", beta)
+		}
+	})
+
+	t.Run("rejects non-GET", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		(&Indexer{store: &mockStore{}}).musicHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/music", nil))
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Fatalf("expected 405, got %d", rec.Code)
+		}
+	})
+}
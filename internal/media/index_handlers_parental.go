@@ -0,0 +1,202 @@
+package media
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/baalimago/go_away_boilerplate/pkg/ancli"
+	"github.com/baalimago/kinoview/internal/media/parental"
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+// pinHeader carries the PIN of requests without a body.
+const pinHeader = "X-Kinoview-Pin"
+
+// deviceCookieAge is how long a client keeps the name of its device.
+const deviceCookieAge = 10 * 365 * 24 * time.Hour
+
+// allowedSuggestions of recs, those the device of r may see.
+func (i *Indexer) allowedSuggestions(r *http.Request, recs []model.Suggestion) []model.Suggestion {
+	if i.parental == nil {
+		return recs
+	}
+	ret := make([]model.Suggestion, 0, len(recs))
+	for _, rec := range recs {
+		if i.parental.Allowed(r, rec.Item) {
+			ret = append(ret, rec)
+		}
+	}
+	return ret
+}
+
+// allowedItems of items, those the device of r may see.
+func (i *Indexer) allowedItems(r *http.Request, items []model.Item) []model.Item {
+	if i.parental == nil {
+		return items
+	}
+	ret := make([]model.Item, 0, len(items))
+	for _, it := range items {
+		if i.parental.Allowed(r, it) {
+			ret = append(ret, it)
+		}
+	}
+	return ret
+}
+
+// refusedIDs are the IDs of the items the device of r may not see, nil
+// without parental controls.
+func (i *Indexer) refusedIDs(r *http.Request) map[string]bool {
+	if i.parental == nil {
+		return nil
+	}
+	ret := map[string]bool{}
+	for _, it := range i.store.Snapshot() {
+		if !i.parental.Allowed(r, it) {
+			ret[it.ID] = true
+		}
+	}
+	return ret
+}
+
+// refusedID answers 403 if the device of r may not see the item with the
+// given ID, reporting whether it did.
+func (i *Indexer) refusedID(w http.ResponseWriter, r *http.Request, id string) bool {
+	if !i.refusedIDs(r)[id] {
+		return false
+	}
+	http.Error(w, "rated above the limit of this device", http.StatusForbidden)
+	return true
+}
+
+// pinError answers the error of a PIN check.
+func pinError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, parental.ErrNoPIN):
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+	case errors.Is(err, parental.ErrLockedOut):
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+	default:
+		http.Error(w, err.Error(), http.StatusForbidden)
+	}
+}
+
+// parentalOverrideHandler lifts the limit of the device of the request for
+// a while on POST, given the PIN in a parental.OverrideRequest, and ends
+// the override on DELETE. Nil parental controls answer 501.
+func (i *Indexer) parentalOverrideHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+			w.Header().Set("Allow", "POST, DELETE")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if i.parental == nil {
+			http.Error(w, "parental controls not configured", http.StatusNotImplemented)
+			return
+		}
+		// Unnamed devices share the empty name, overriding one would
+		// override them all
+		device := i.parental.Device(r)
+		if device == "" {
+			http.Error(w, "name the device first, see /parental/device", http.StatusBadRequest)
+			return
+		}
+		if r.Method == http.MethodDelete {
+			i.parental.EndOverride(device, r.RemoteAddr)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		defer r.Body.Close()
+		dec := json.NewDecoder(io.LimitReader(r.Body, 1<<10))
+		dec.DisallowUnknownFields()
+		var req parental.OverrideRequest
+		if err := dec.Decode(&req); err != nil || req.Minutes < 0 {
+			http.Error(w, "malformed override request", http.StatusBadRequest)
+			return
+		}
+		until, err := i.parental.Override(device, r.RemoteAddr, req.PIN, time.Duration(req.Minutes)*time.Minute)
+		if err != nil {
+			pinError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(parental.OverrideResponse{Device: device, Until: until}); err != nil {
+			ancli.Errf("failed to encode override: %v", err)
+		}
+	}
+}
+
+// parentalDeviceHandler names the device of the client by a cookie, given
+// the PIN in a parental.DeviceRequest, since the name decides its limit.
+// Nil parental controls answer 501.
+func (i *Indexer) parentalDeviceHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if i.parental == nil {
+			http.Error(w, "parental controls not configured", http.StatusNotImplemented)
+			return
+		}
+		defer r.Body.Close()
+		dec := json.NewDecoder(io.LimitReader(r.Body, 1<<10))
+		dec.DisallowUnknownFields()
+		var req parental.DeviceRequest
+		if err := dec.Decode(&req); err != nil || strings.TrimSpace(req.Device) == "" {
+			http.Error(w, "malformed device request", http.StatusBadRequest)
+			return
+		}
+		device := strings.TrimSpace(req.Device)
+		if err := i.parental.CheckPIN(parental.ActionSetDevice, device, r.RemoteAddr, req.PIN); err != nil {
+			pinError(w, err)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{
+			Name:     parental.DeviceCookie,
+			Value:    i.parental.SignDevice(device),
+			Path:     "/",
+			MaxAge:   int(deviceCookieAge.Seconds()),
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// parentalAuditHandler lists the attempts to get past the parental
+// controls, oldest first, given the PIN in the X-Kinoview-Pin header. Nil
+// parental controls answer 501.
+func (i *Indexer) parentalAuditHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if i.parental == nil {
+			http.Error(w, "parental controls not configured", http.StatusNotImplemented)
+			return
+		}
+		if err := i.parental.CheckPIN(parental.ActionReadAudit, i.parental.Device(r), r.RemoteAddr, r.Header.Get(pinHeader)); err != nil {
+			pinError(w, err)
+			return
+		}
+		entries, err := i.parental.Audit()
+		if err != nil {
+			ancli.Errf("parental audit: %v", err)
+			http.Error(w, "failed to read audit log", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(entries); err != nil {
+			http.Error(w, "failed to encode audit log", http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package media
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/baalimago/kinoview/internal/media/parental"
+	"github.com/baalimago/kinoview/internal/media/quotes"
+	"github.com/baalimago/kinoview/internal/media/suggestions"
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+func newTestParental(t *testing.T) *parental.Controls {
+	t.Helper()
+	sum := sha256.Sum256([]byte("1234"))
+	c, err := parental.New(parental.Config{
+		PINSHA256:        hex.EncodeToString(sum[:]),
+		DefaultMaxRating: "PG",
+		Devices:          map[string]string{"living-room": ""},
+	}, parental.WithAuditLog(filepath.Join(t.TempDir(), "audit.jsonl")))
+	if err != nil {
+		t.Fatalf("parental.New: %v", err)
+	}
+	return c
+}
+
+// fromDevice names the device of r by a cookie c signed.
+func fromDevice(c *parental.Controls, r *http.Request, device string) *http.Request {
+	if device != "" {
+		r.AddCookie(&http.Cookie{Name: parental.DeviceCookie, Value: c.SignDevice(device)})
+	}
+	return r
+}
+
+func Test_showsHandler_parental(t *testing.T) {
+	t.Parallel()
+	store := &mockStore{items: []model.Item{
+		{ID: "1", Path: "/media/Bluey.S01E01.mkv", MIMEType: "video/x-matroska", Metadata: &model.MediaMetadata{AgeRating: "TV-Y"}},
+		{ID: "2", Path: "/media/The.Wire.S01E01.mkv", MIMEType: "video/x-matroska", Metadata: &model.MediaMetadata{AgeRating: "TV-MA"}},
+	}}
+	i := &Indexer{store: store, parental: newTestParental(t)}
+
+	for device, want := range map[string]string{"": "Bluey", "living-room": "Bluey,The Wire"} {
+		rec := httptest.NewRecorder()
+		i.showsHandler().ServeHTTP(rec, fromDevice(i.parental, httptest.NewRequest(http.MethodGet, "/shows", nil), device))
+		var resp model.ShowsResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		var names []string
+		for _, s := range resp.Shows {
+			names = append(names, s.Name)
+		}
+		if got := strings.Join(names, ","); got != want {
+			t.Errorf("device %q: shows = %v, want %v", device, got, want)
+		}
+	}
+
+	// Naming the device is up to whoever knows the PIN
+	req := httptest.NewRequest(http.MethodGet, "/shows?device=living-room", nil)
+	req.Header.Set("X-Kinoview-Device", "living-room")
+	rec := httptest.NewRecorder()
+	i.showsHandler().ServeHTTP(rec, req)
+	if strings.Contains(rec.Body.String(), "The Wire") {
+		t.Errorf("expected the query and header not to name the device, got %s", rec.Body.String())
+	}
+}
+
+func Test_suggestionsHandler_parental(t *testing.T) {
+	t.Parallel()
+	sm, err := suggestions.NewManager(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create suggestions manager: %v", err)
+	}
+	if err := sm.Update([]model.Suggestion{
+		{Item: model.Item{ID: "1", Name: "Heat", MIMEType: "video/mp4", Metadata: &model.MediaMetadata{AgeRating: "R"}}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	i := &Indexer{suggestions: sm, parental: newTestParental(t)}
+
+	rec := httptest.NewRecorder()
+	i.suggestionsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/suggestions", nil))
+	var payload model.SuggestionsPayload
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(payload.Suggestions) != 0 || payload.State != "empty" {
+		t.Errorf("expected the R rated suggestion hidden, got %+v", payload)
+	}
+}
+
+func Test_parentalHandlers(t *testing.T) {
+	t.Parallel()
+	c := newTestParental(t)
+	i := &Indexer{parental: c}
+	heat := model.Item{MIMEType: "video/mp4", Metadata: &model.MediaMetadata{AgeRating: "R"}}
+
+	override := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		i.parentalOverrideHandler().ServeHTTP(rec, fromDevice(i.parental, httptest.NewRequest(http.MethodPost, "/parental/override", strings.NewReader(body)), "tablet"))
+		return rec
+	}
+	if rec := override(`{"pin": "0000"}`); rec.Code != http.StatusForbidden {
+		t.Fatalf("wrong pin: code = %d: %s", rec.Code, rec.Body.String())
+	}
+	rec := override(`{"pin": "1234", "minutes": 10}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("override: code = %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp parental.OverrideResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp.Device != "tablet" || resp.Until.IsZero() {
+		t.Fatalf("unexpected override response %s: %v", rec.Body.String(), err)
+	}
+	if !c.Allowed(fromDevice(i.parental, httptest.NewRequest(http.MethodGet, "/", nil), "tablet"), heat) {
+		t.Error("expected the tablet to be overridden")
+	}
+
+	t.Run("unnamed devices can't be overridden", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		i.parentalOverrideHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/parental/override", strings.NewReader(`{"pin": "1234"}`)))
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("override without a device: code = %d", rec.Code)
+		}
+		if c.Allowed(httptest.NewRequest(http.MethodGet, "/", nil), heat) {
+			t.Error("expected another unnamed device to keep its limit")
+		}
+		rec = httptest.NewRecorder()
+		i.parentalOverrideHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/parental/override", nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("end override without a device: code = %d", rec.Code)
+		}
+	})
+
+	rec = httptest.NewRecorder()
+	i.parentalOverrideHandler().ServeHTTP(rec, fromDevice(i.parental, httptest.NewRequest(http.MethodDelete, "/parental/override", nil), "tablet"))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("end override: code = %d", rec.Code)
+	}
+	if c.Allowed(fromDevice(i.parental, httptest.NewRequest(http.MethodGet, "/", nil), "tablet"), heat) {
+		t.Error("expected the override to have ended")
+	}
+
+	rec = httptest.NewRecorder()
+	i.parentalDeviceHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/parental/device", strings.NewReader(`{"device": "living-room", "pin": "1234"}`)))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("set device: code = %d: %s", rec.Code, rec.Body.String())
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != parental.DeviceCookie {
+		t.Fatalf("unexpected cookies %v", cookies)
+	}
+	named := httptest.NewRequest(http.MethodGet, "/", nil)
+	named.AddCookie(cookies[0])
+	if got := c.Device(named); got != "living-room" {
+		t.Errorf("expected the cookie to name the device, got %q", got)
+	}
+
+	rec = httptest.NewRecorder()
+	i.parentalAuditHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/parental/audit", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("audit without pin: code = %d", rec.Code)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/parental/audit", nil)
+	req.Header.Set(pinHeader, "1234")
+	rec = httptest.NewRecorder()
+	i.parentalAuditHandler().ServeHTTP(rec, req)
+	var entries []parental.AuditEntry
+	if err := json.Unmarshal(rec.Body.Bytes(), &entries); err != nil {
+		t.Fatalf("unmarshal audit: %v", err)
+	}
+	var actions []string
+	for _, e := range entries {
+		actions = append(actions, e.Action)
+	}
+	want := "override,override,end_override,set_device,read_audit,read_audit"
+	if got := strings.Join(actions, ","); got != want {
+		t.Errorf("audited %v, want %v", got, want)
+	}
+}
+
+func Test_parentalHandlers_notConfigured(t *testing.T) {
+	t.Parallel()
+	i := &Indexer{}
+	rec := httptest.NewRecorder()
+	i.parentalOverrideHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/parental/override", strings.NewReader(`{}`)))
+	if rec.Code != http.StatusNotImplemented {
+		t.Errorf("code = %d, want 501", rec.Code)
+	}
+}
+
+func Test_limitedHandlers_parental(t *testing.T) {
+	t.Parallel()
+	heat := model.Item{ID: "r1", Name: "Heat.mkv", MIMEType: "video/x-matroska", Metadata: &model.MediaMetadata{AgeRating: "R"}}
+	paddington := model.Item{ID: "pg1", Name: "Paddington.mkv", MIMEType: "video/x-matroska", Metadata: &model.MediaMetadata{AgeRating: "PG"}}
+	store := &mockStore{items: []model.Item{heat, paddington}}
+	dir := t.TempDir()
+	for name, text := range map[string]string{
+		"r1_2.vtt":  "WEBVTT\n\n00:00:01.000 --> 00:00:02.000\nI'll be back.\n",
+		"pg1_2.vtt": "WEBVTT\n\n00:00:01.000 --> 00:00:02.000\nI'll be back with marmalade.\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o644); err != nil {
+			t.Fatalf("write subtitle: %v", err)
+		}
+	}
+	i := &Indexer{
+		store:    store,
+		parental: newTestParental(t),
+		quotes:   quotes.New(dir, store),
+		history:  &fakeMetadataHistory{},
+		reviews:  &fakeReviewQueue{items: []model.Item{heat, paddington}},
+		classification: &fakeClassificationQueue{q: model.ClassificationQueue{
+			Queued:  []model.QueuedClassification{{ID: "r1"}, {ID: "pg1"}},
+			Workers: []model.ClassificationAssignment{{ID: "r1"}},
+		}},
+	}
+
+	t.Run("quotes", func(t *testing.T) {
+		for device, want := range map[string]string{"": "pg1", "living-room": "pg1,r1"} {
+			rec := httptest.NewRecorder()
+			i.quotesHandler().ServeHTTP(rec, fromDevice(i.parental, httptest.NewRequest(http.MethodGet, "/quotes?q=i%27ll+be+back", nil), device))
+			var resp model.QuotesResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			var ids []string
+			for _, q := range resp.Quotes {
+				ids = append(ids, q.ItemID)
+			}
+			slices.Sort(ids)
+			if got := strings.Join(ids, ","); got != want {
+				t.Errorf("device %q: quotes of %v, want %v", device, got, want)
+			}
+		}
+	})
+
+	t.Run("history", func(t *testing.T) {
+		for device, want := range map[string]int{"": http.StatusForbidden, "living-room": http.StatusOK} {
+			req := fromDevice(i.parental, httptest.NewRequest(http.MethodGet, "/items/r1/history", nil), device)
+			req.SetPathValue("id", "r1")
+			rec := httptest.NewRecorder()
+			i.historyHandler().ServeHTTP(rec, req)
+			if rec.Code != want {
+				t.Errorf("device %q: code = %d, want %d", device, rec.Code, want)
+			}
+		}
+	})
+
+	t.Run("reviews", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		i.reviewsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/review", nil))
+		if strings.Contains(rec.Body.String(), "r1") || !strings.Contains(rec.Body.String(), "pg1") {
+			t.Errorf("expected only the reviews of allowed items, got %s", rec.Body.String())
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/review/r1", strings.NewReader(`{}`))
+		req.SetPathValue("id", "r1")
+		rec = httptest.NewRecorder()
+		i.resolveReviewHandler().ServeHTTP(rec, req)
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("resolving above the limit: code = %d, want 403", rec.Code)
+		}
+	})
+
+	t.Run("classification queue", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		i.classificationQueueHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/classification/queue", nil))
+		var q model.ClassificationQueue
+		if err := json.Unmarshal(rec.Body.Bytes(), &q); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		if len(q.Queued) != 1 || q.Queued[0].ID != "pg1" || len(q.Workers) != 0 {
+			t.Errorf("expected only the allowed item queued, got %+v", q)
+		}
+	})
+}
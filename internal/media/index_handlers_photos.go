@@ -0,0 +1,97 @@
+package media
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/baalimago/kinoview/internal/media/thumbnail"
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+const undatedAlbum = "Undated"
+
+// albumLayouts maps the supported album groupings to the layout of their
+// titles.
+var albumLayouts = map[string]string{
+	"year":  "2006",
+	"month": "2006-01",
+	"day":   "2006-01-02",
+}
+
+// photosHandler groups all photos into albums by the date they were taken.
+// The period is set by the "group" query parameter: year, month (default)
+// or day.
+func (i *Indexer) photosHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		group := r.URL.Query().Get("group")
+		if group == "" {
+			group = "month"
+		}
+		layout, ok := albumLayouts[group]
+		if !ok {
+			http.Error(w, fmt.Sprintf("invalid group: '%v', expected year, month or day", group), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(groupPhotos(i.store.Snapshot(), layout)); err != nil {
+			http.Error(w, "failed to encode albums", http.StatusInternalServerError)
+		}
+	}
+}
+
+func groupPhotos(items []model.Item, layout string) model.PhotoAlbumsResponse {
+	albums := make(map[string]*model.PhotoAlbum)
+	for _, item := range items {
+		if !strings.HasPrefix(item.MIMEType, "image") || thumbnail.IsThumbnail(item.Path) {
+			continue
+		}
+		title := undatedAlbum
+		if item.Photo != nil && !item.Photo.TakenAt.IsZero() {
+			title = item.Photo.TakenAt.Format(layout)
+		}
+		album, exists := albums[title]
+		if !exists {
+			album = &model.PhotoAlbum{Title: title, Photos: []model.Item{}}
+			albums[title] = album
+		}
+		album.Photos = append(album.Photos, item)
+	}
+
+	resp := model.PhotoAlbumsResponse{Albums: make([]model.PhotoAlbum, 0, len(albums))}
+	for _, album := range albums {
+		sort.Slice(album.Photos, func(a, b int) bool {
+			ta, tb := takenAt(album.Photos[a]), takenAt(album.Photos[b])
+			if !ta.Equal(tb) {
+				return ta.Before(tb)
+			}
+			return album.Photos[a].Path < album.Photos[b].Path
+		})
+		album.CoverID = album.Photos[0].Thumbnail.ID
+		resp.Albums = append(resp.Albums, *album)
+	}
+	// Newest first, undated last. The titles sort chronologically.
+	sort.Slice(resp.Albums, func(a, b int) bool {
+		ta, tb := resp.Albums[a].Title, resp.Albums[b].Title
+		if ta == undatedAlbum || tb == undatedAlbum {
+			return tb == undatedAlbum && ta != undatedAlbum
+		}
+		return ta > tb
+	})
+	return resp
+}
+
+func takenAt(it model.Item) time.Time {
+	if it.Photo == nil {
+		return time.Time{}
+	}
+	return it.Photo.TakenAt
+}
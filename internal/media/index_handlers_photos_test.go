@@ -0,0 +1,81 @@
+package media
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+func Test_photosHandler(t *testing.T) {
+	t.Parallel()
+
+	taken := func(y int, m time.Month, d int) *model.PhotoMetadata {
+		return &model.PhotoMetadata{TakenAt: time.Date(y, m, d, 12, 0, 0, 0, time.UTC)}
+	}
+	store := &mockStore{
+		items: []model.Item{
+			{ID: "b", Path: "/p/b.jpg", MIMEType: "image/jpeg", Photo: taken(2024, 5, 20), Thumbnail: model.Image{ID: "b_thumb"}},
+			{ID: "a", Path: "/p/a.jpg", MIMEType: "image/jpeg", Photo: taken(2024, 5, 2), Thumbnail: model.Image{ID: "a_thumb"}},
+			{ID: "c", Path: "/p/c.jpg", MIMEType: "image/jpeg", Photo: taken(2023, 1, 1)},
+			{ID: "u", Path: "/p/u.png", MIMEType: "image/png"},
+			{ID: "t", Path: "/p/a_thumb.jpg", MIMEType: "image/jpeg"},
+			{ID: "v", Path: "/p/v.mp4", MIMEType: "video/mp4"},
+		},
+	}
+	handler := (&Indexer{store: store}).photosHandler()
+
+	get := func(t *testing.T, url string) (int, model.PhotoAlbumsResponse) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		var resp model.PhotoAlbumsResponse
+		if rec.Code == http.StatusOK {
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+		}
+		return rec.Code, resp
+	}
+
+	t.Run("groups by month, newest first", func(t *testing.T) {
+		code, resp := get(t, "/photos")
+		if code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", code)
+		}
+		var titles []string
+		for _, a := range resp.Albums {
+			titles = append(titles, a.Title)
+		}
+		want := []string{"2024-05", "2023-01", undatedAlbum}
+		if len(titles) != len(want) {
+			t.Fatalf("expected albums %v, got %v", want, titles)
+		}
+		for i := range want {
+			if titles[i] != want[i] {
+				t.Fatalf("expected albums %v, got %v", want, titles)
+			}
+		}
+		may := resp.Albums[0]
+		if len(may.Photos) != 2 || may.Photos[0].ID != "a" || may.CoverID != "a_thumb" {
+			t.Fatalf("unexpected album: %+v", may)
+		}
+	})
+
+	t.Run("groups by year", func(t *testing.T) {
+		_, resp := get(t, "/photos?group=year")
+		if len(resp.Albums) != 3 || resp.Albums[0].Title != "2024" {
+			t.Fatalf("unexpected albums: %+v", resp.Albums)
+		}
+	})
+
+	t.Run("invalid group", func(t *testing.T) {
+		code, _ := get(t, "/photos?group=week")
+		if code != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d", code)
+		}
+	})
+}
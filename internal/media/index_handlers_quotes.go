@@ -0,0 +1,64 @@
+package media
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/baalimago/go_away_boilerplate/pkg/ancli"
+	"github.com/baalimago/kinoview/internal/media/quotes"
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+const (
+	defaultQuotesLimit = 50
+	maxQuotesLimit     = 500
+)
+
+// quotesHandler searches the subtitles of the library for the line in the
+// "q" query parameter. At most "limit" matches are returned, 50 by default.
+// A nil searcher answers 501.
+func (i *Indexer) quotesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if i.quotes == nil {
+			http.Error(w, "quote search not configured", http.StatusNotImplemented)
+			return
+		}
+		q := r.URL.Query().Get("q")
+		limit := defaultQuotesLimit
+		if l := r.URL.Query().Get("limit"); l != "" {
+			n, err := strconv.Atoi(l)
+			if err != nil || n <= 0 {
+				http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+				return
+			}
+			limit = min(n, maxQuotesLimit)
+		}
+
+		found, total, err := i.quotes.SearchQuotes(q, limit, func(it model.Item) bool {
+			return i.parental.Allowed(r, it)
+		})
+		if errors.Is(err, quotes.ErrEmptyQuery) {
+			http.Error(w, "missing query parameter: 'q'", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			ancli.Errf("quote search: %v", err)
+			http.Error(w, "failed to search quotes", http.StatusInternalServerError)
+			return
+		}
+		if found == nil {
+			found = []model.Quote{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(model.QuotesResponse{Query: q, Quotes: found, Total: total}); err != nil {
+			http.Error(w, "failed to encode quotes", http.StatusInternalServerError)
+		}
+	}
+}
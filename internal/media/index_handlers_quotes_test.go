@@ -0,0 +1,78 @@
+package media
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/baalimago/kinoview/internal/media/quotes"
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+type fakeQuoteSearcher struct {
+	gotLimit int
+	quotes   []model.Quote
+	err      error
+}
+
+func (f *fakeQuoteSearcher) SearchQuotes(query string, limit int, allowed func(model.Item) bool) ([]model.Quote, int, error) {
+	f.gotLimit = limit
+	if f.err != nil {
+		return nil, 0, f.err
+	}
+	if query == "" {
+		return nil, 0, quotes.ErrEmptyQuery
+	}
+	return f.quotes, len(f.quotes), nil
+}
+
+func Test_quotesHandler(t *testing.T) {
+	t.Parallel()
+
+	q := model.Quote{ItemID: "t1", Name: "The Terminator.mkv", Start: 3723, Text: "I'll be back.", Link: "/gallery/video/t1?t=3723"}
+	tests := []struct {
+		name      string
+		searcher  *fakeQuoteSearcher
+		method    string
+		url       string
+		wantCode  int
+		wantLimit int
+	}{
+		{"found", &fakeQuoteSearcher{quotes: []model.Quote{q}}, http.MethodGet, "/quotes?q=i%27ll+be+back", http.StatusOK, 50},
+		{"nothing found", &fakeQuoteSearcher{}, http.MethodGet, "/quotes?q=nope", http.StatusOK, 50},
+		{"limit capped", &fakeQuoteSearcher{}, http.MethodGet, "/quotes?q=back&limit=9999", http.StatusOK, 500},
+		{"invalid limit", &fakeQuoteSearcher{}, http.MethodGet, "/quotes?q=back&limit=-1", http.StatusBadRequest, 0},
+		{"missing query", &fakeQuoteSearcher{}, http.MethodGet, "/quotes", http.StatusBadRequest, 50},
+		{"search fails", &fakeQuoteSearcher{err: errors.New("boom")}, http.MethodGet, "/quotes?q=back", http.StatusInternalServerError, 50},
+		{"wrong method", &fakeQuoteSearcher{}, http.MethodPost, "/quotes?q=back", http.StatusMethodNotAllowed, 0},
+		{"not configured", nil, http.MethodGet, "/quotes?q=back", http.StatusNotImplemented, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := &Indexer{}
+			if tt.searcher != nil {
+				i.quotes = tt.searcher
+			}
+			rec := httptest.NewRecorder()
+			i.quotesHandler().ServeHTTP(rec, httptest.NewRequest(tt.method, tt.url, nil))
+			if rec.Code != tt.wantCode {
+				t.Fatalf("code = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body.String())
+			}
+			if tt.searcher != nil && tt.searcher.gotLimit != tt.wantLimit {
+				t.Errorf("limit = %d, want %d", tt.searcher.gotLimit, tt.wantLimit)
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+			var resp model.QuotesResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			if resp.Quotes == nil || resp.Total != len(tt.searcher.quotes) {
+				t.Errorf("unexpected response: %+v", resp)
+			}
+		})
+	}
+}
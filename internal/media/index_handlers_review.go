@@ -0,0 +1,82 @@
+package media
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/baalimago/go_away_boilerplate/pkg/ancli"
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+// reviewsHandler lists the items whose classification waits for review. A
+// nil review queue answers 501.
+func (i *Indexer) reviewsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if i.reviews == nil {
+			http.Error(w, "review not configured", http.StatusNotImplemented)
+			return
+		}
+		items := i.allowedItems(r, i.reviews.Reviews())
+		if len(items) == 0 {
+			items = []model.Item{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(model.ReviewsResponse{Items: items}); err != nil {
+			http.Error(w, "failed to encode reviews", http.StatusInternalServerError)
+		}
+	}
+}
+
+// resolveReviewHandler accepts, edits or rejects the metadata of the item at
+// PathValue id, as told by the model.ReviewDecision in the body. Answers with
+// the item as it is after.
+func (i *Indexer) resolveReviewHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if i.reviews == nil {
+			http.Error(w, "review not configured", http.StatusNotImplemented)
+			return
+		}
+		defer r.Body.Close()
+		dec := json.NewDecoder(io.LimitReader(r.Body, 1<<20))
+		dec.DisallowUnknownFields()
+		var d model.ReviewDecision
+		if err := dec.Decode(&d); err != nil {
+			http.Error(w, "malformed review decision", http.StatusBadRequest)
+			return
+		}
+		if i.refusedID(w, r, r.PathValue("id")) {
+			return
+		}
+
+		item, err := i.reviews.ResolveReview(r.PathValue("id"), d)
+		switch {
+		case errors.Is(err, model.ErrNoReview):
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		case errors.Is(err, model.ErrInvalidDecision):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case err != nil:
+			ancli.Errf("resolve review: %v", err)
+			http.Error(w, "failed to resolve review", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(item); err != nil {
+			http.Error(w, "failed to encode item", http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package media
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+type fakeReviewQueue struct {
+	items    []model.Item
+	gotID    string
+	gotDec   model.ReviewDecision
+	resolved model.Item
+	err      error
+}
+
+func (f *fakeReviewQueue) Reviews() []model.Item {
+	return f.items
+}
+
+func (f *fakeReviewQueue) ResolveReview(id string, d model.ReviewDecision) (model.Item, error) {
+	f.gotID, f.gotDec = id, d
+	return f.resolved, f.err
+}
+
+func Test_reviewsHandler(t *testing.T) {
+	t.Parallel()
+
+	t.Run("lists", func(t *testing.T) {
+		i := &Indexer{reviews: &fakeReviewQueue{items: []model.Item{{ID: "a", Review: &model.Review{Reasons: []string{"low confidence in year (0.40)"}}}}}}
+		rec := httptest.NewRecorder()
+		i.reviewsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/review", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("code = %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp model.ReviewsResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Items) != 1 || resp.Items[0].Review == nil {
+			t.Errorf("unexpected response: %+v", resp)
+		}
+	})
+
+	t.Run("empty is a list", func(t *testing.T) {
+		i := &Indexer{reviews: &fakeReviewQueue{}}
+		rec := httptest.NewRecorder()
+		i.reviewsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/review", nil))
+		if got := strings.TrimSpace(rec.Body.String()); got != `{"items":[]}` {
+			t.Errorf("body = %v", got)
+		}
+	})
+
+	t.Run("not configured", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		(&Indexer{}).reviewsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/review", nil))
+		if rec.Code != http.StatusNotImplemented {
+			t.Errorf("code = %d, want %d", rec.Code, http.StatusNotImplemented)
+		}
+	})
+}
+
+func Test_resolveReviewHandler(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		queue    *fakeReviewQueue
+		method   string
+		body     string
+		wantCode int
+	}{
+		{"accept", &fakeReviewQueue{resolved: model.Item{ID: "a"}}, http.MethodPost, `{"action":"accept"}`, http.StatusOK},
+		{"edit", &fakeReviewQueue{resolved: model.Item{ID: "a"}}, http.MethodPost, `{"action":"edit","metadata":{"year":1995}}`, http.StatusOK},
+		{"not in review", &fakeReviewQueue{err: fmt.Errorf("%w: a", model.ErrNoReview)}, http.MethodPost, `{"action":"accept"}`, http.StatusNotFound},
+		{"invalid decision", &fakeReviewQueue{err: fmt.Errorf("%w: nope", model.ErrInvalidDecision)}, http.MethodPost, `{"action":"shrug"}`, http.StatusBadRequest},
+		{"malformed body", &fakeReviewQueue{}, http.MethodPost, `{"action":`, http.StatusBadRequest},
+		{"unknown field", &fakeReviewQueue{}, http.MethodPost, `{"verdict":"accept"}`, http.StatusBadRequest},
+		{"store fails", &fakeReviewQueue{err: errors.New("disk full")}, http.MethodPost, `{"action":"accept"}`, http.StatusInternalServerError},
+		{"wrong method", &fakeReviewQueue{}, http.MethodGet, ``, http.StatusMethodNotAllowed},
+		{"not configured", nil, http.MethodPost, `{"action":"accept"}`, http.StatusNotImplemented},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := &Indexer{}
+			if tt.queue != nil {
+				i.reviews = tt.queue
+			}
+			req := httptest.NewRequest(tt.method, "/review/a", strings.NewReader(tt.body))
+			req.SetPathValue("id", "a")
+			rec := httptest.NewRecorder()
+			i.resolveReviewHandler().ServeHTTP(rec, req)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("code = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body.String())
+			}
+			if rec.Code == http.StatusOK && tt.queue.gotID != "a" {
+				t.Errorf("resolved %q, want %q", tt.queue.gotID, "a")
+			}
+		})
+	}
+}
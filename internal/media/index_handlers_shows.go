@@ -46,33 +46,13 @@ func extractShowNameFromPath(path string) string {
 	return ""
 }
 
-// parseSeasonEpisodeFromMetadata extracts season and episode from Metadata JSON.
+// parseSeasonEpisodeFromMetadata extracts season and episode from Metadata.
 func parseSeasonEpisodeFromMetadata(it model.Item) (season int, episode int, ok bool) {
 	if it.Metadata == nil {
 		return 0, 0, false
 	}
-	var md map[string]any
-	if err := json.Unmarshal(*it.Metadata, &md); err != nil {
-		return 0, 0, false
-	}
-	if s, found := md["season"]; found {
-		switch v := s.(type) {
-		case float64:
-			season = int(v)
-		case string:
-			season, _ = strconv.Atoi(v)
-		}
-	}
-	if e, found := md["episode"]; found {
-		switch v := e.(type) {
-		case float64:
-			episode = int(v)
-		case string:
-			episode, _ = strconv.Atoi(v)
-		}
-	}
-	if season > 0 && episode > 0 {
-		return season, episode, true
+	if it.Metadata.Season > 0 && it.Metadata.Episode > 0 {
+		return it.Metadata.Season, it.Metadata.Episode, true
 	}
 	return 0, 0, false
 }
@@ -142,7 +122,7 @@ func (i *Indexer) showsHandler() http.HandlerFunc {
 			return
 		}
 
-		allItems := i.store.Snapshot()
+		allItems := i.allowedItems(r, i.store.Snapshot())
 		showMap := make(map[string]*model.ShowSeries)
 		showOrder := []string{}
 
@@ -13,7 +13,7 @@ func Test_extractShowMetadata(t *testing.T) {
 	t.Parallel()
 
 	t.Run("metadata season+episode, show name from filename", func(t *testing.T) {
-		raw := json.RawMessage(`{"name":"Ethon","season":9,"episode":15}`)
+		raw := model.MediaMetadata{Name: "Ethon", Season: 9, Episode: 15}
 		it := model.Item{
 			Name:     "Stargate.SG-1.S09E15.Ethon.mkv",
 			Path:     "/media/Stargate.SG-1.S09E15.Ethon.mkv",
@@ -36,7 +36,7 @@ func Test_extractShowMetadata(t *testing.T) {
 	})
 
 	t.Run("metadata from From series example", func(t *testing.T) {
-		raw := json.RawMessage(`{"name":"From (2022) - S02E06 - Pas de Deux","season":2,"episode":6}`)
+		raw := model.MediaMetadata{Name: "From (2022) - S02E06 - Pas de Deux", Season: 2, Episode: 6}
 		it := model.Item{
 			Name:     "From (2022) - S02E06 - Pas de Deux (1080p AMZN WEB-DL x265 t3nzin).mkv",
 			Path:     "/media/From (2022) - S02E06 - Pas de Deux (1080p AMZN WEB-DL x265 t3nzin).mkv",
@@ -155,7 +155,10 @@ func Test_extractShowMetadata(t *testing.T) {
 	})
 
 	t.Run("metadata floats as ints", func(t *testing.T) {
-		raw := json.RawMessage(`{"name":"Irrelevant Episode Title","season":4.0,"episode":5.0}`)
+		var raw model.MediaMetadata
+		if err := json.Unmarshal([]byte(`{"name":"Irrelevant Episode Title","season":4.0,"episode":5.0}`), &raw); err != nil {
+			t.Fatal(err)
+		}
 		it := model.Item{
 			Name:     "Test.Show.S04E05.mkv",
 			Path:     "/media/Test.Show.S04E05.mkv",
@@ -178,7 +181,10 @@ func Test_extractShowMetadata(t *testing.T) {
 	})
 
 	t.Run("metadata string season/episode", func(t *testing.T) {
-		raw := json.RawMessage(`{"name":"Ep Title","season":"3","episode":"7"}`)
+		var raw model.MediaMetadata
+		if err := json.Unmarshal([]byte(`{"name":"Ep Title","season":"3","episode":"7"}`), &raw); err != nil {
+			t.Fatal(err)
+		}
 		it := model.Item{
 			Name:     "Another.Show.S03E07.mkv",
 			Path:     "/media/Another.Show.S03E07.mkv",
@@ -201,7 +207,7 @@ func Test_extractShowMetadata(t *testing.T) {
 	})
 
 	t.Run("metadata with season but no show name in path", func(t *testing.T) {
-		raw := json.RawMessage(`{"season":1,"episode":5}`)
+		raw := model.MediaMetadata{Season: 1, Episode: 5}
 		it := model.Item{
 			Name:     "00105.mkv",
 			Path:     "/media/00105.mkv",
@@ -216,7 +222,7 @@ func Test_extractShowMetadata(t *testing.T) {
 
 	t.Run("metadata with name but no season/episode in filename, fallback to metadata name as show", func(t *testing.T) {
 		// Has metadata name, but no season/episode in metadata
-		raw := json.RawMessage(`{"name":"Breaking Bad","season":1,"episode":1}`)
+		raw := model.MediaMetadata{Name: "Breaking Bad", Season: 1, Episode: 1}
 		it := model.Item{
 			Name:     "Breaking.Bad.S01E01.Pilot.mkv",
 			Path:     "/media/Breaking.Bad.S01E01.Pilot.mkv",
@@ -240,7 +246,7 @@ func Test_extractShowMetadata(t *testing.T) {
 	})
 
 	t.Run("space race episode with metadata", func(t *testing.T) {
-		raw := json.RawMessage(`{"name":"Space Race","season":7,"episode":8}`)
+		raw := model.MediaMetadata{Name: "Space Race", Season: 7, Episode: 8}
 		it := model.Item{
 			Name:     "Stargate.SG-1.S07E08.Space.Race.1080p.BluRay.mkv",
 			Path:     "/media/Stargate.SG-1.S07E08.Space.Race.1080p.BluRay.mkv",
@@ -267,9 +273,9 @@ func Test_showsHandler(t *testing.T) {
 	t.Parallel()
 
 	t.Run("groups shows correctly with metadata", func(t *testing.T) {
-		raw1 := json.RawMessage(`{"name":"Ethon","season":9,"episode":15}`)
-		raw2 := json.RawMessage(`{"name":"Space Race","season":7,"episode":8}`)
-		raw3 := json.RawMessage(`{"name":"Another S9 Episode","season":9,"episode":16}`)
+		raw1 := model.MediaMetadata{Name: "Ethon", Season: 9, Episode: 15}
+		raw2 := model.MediaMetadata{Name: "Space Race", Season: 7, Episode: 8}
+		raw3 := model.MediaMetadata{Name: "Another S9 Episode", Season: 9, Episode: 16}
 
 		store := &mockStore{
 			items: []model.Item{
@@ -365,7 +371,7 @@ func Test_showsHandler(t *testing.T) {
 	})
 
 	t.Run("non-video items skipped", func(t *testing.T) {
-		raw := json.RawMessage(`{"name":"Show","season":1,"episode":1}`)
+		raw := model.MediaMetadata{Name: "Show", Season: 1, Episode: 1}
 		store := &mockStore{
 			items: []model.Item{
 				{
@@ -447,8 +453,8 @@ func Test_showsHandler(t *testing.T) {
 	})
 
 	t.Run("multiple shows from From and Stargate", func(t *testing.T) {
-		rawFrom := json.RawMessage(`{"name":"Pas de Deux","season":2,"episode":6}`)
-		rawSG := json.RawMessage(`{"name":"Ethon","season":9,"episode":15}`)
+		rawFrom := model.MediaMetadata{Name: "Pas de Deux", Season: 2, Episode: 6}
+		rawSG := model.MediaMetadata{Name: "Ethon", Season: 9, Episode: 15}
 		store := &mockStore{
 			items: []model.Item{
 				{
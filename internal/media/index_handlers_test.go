@@ -9,6 +9,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/baalimago/kinoview/internal/lang"
 	"github.com/baalimago/kinoview/internal/model"
 )
 
@@ -143,3 +144,30 @@ func TestIntroFeedbackHandler_WriteFailureIs500(t *testing.T) {
 		t.Errorf("status = %d, want 500", rr.Code)
 	}
 }
+
+func TestPreferencesHandler(t *testing.T) {
+	get := func(t *testing.T, i *Indexer) string {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		i.preferencesHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/preferences", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", rec.Code)
+		}
+		return strings.TrimSpace(rec.Body.String())
+	}
+
+	if got := get(t, &Indexer{}); got != `{"subtitleLanguages":["en"]}` {
+		t.Errorf("unexpected default preferences: %s", got)
+	}
+	i := &Indexer{}
+	WithSubtitleLanguages(lang.Parse("swe,jpn"))(i)
+	if got := get(t, i); got != `{"subtitleLanguages":["sv","ja"]}` {
+		t.Errorf("unexpected preferences: %s", got)
+	}
+
+	rec := httptest.NewRecorder()
+	i.preferencesHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/preferences", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", rec.Code)
+	}
+}
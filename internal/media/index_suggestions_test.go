@@ -202,7 +202,7 @@ func TestSuggestionsHandler_AttachesView(t *testing.T) {
 		t.Fatalf("failed to create suggestions manager: %v", err)
 	}
 
-	rawMeta := json.RawMessage(`{"name":"Endgame","season":8,"episode":10,"year":2004}`)
+	rawMeta := model.MediaMetadata{Name: "Endgame", Season: 8, Episode: 10, Year: 2004}
 	sm.Update([]model.Suggestion{
 		{
 			Item: model.Item{
@@ -38,10 +38,26 @@ func (m *mockStore) VideoHandlerFunc() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {}
 }
 
+func (m *mockStore) AudioHandlerFunc() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {}
+}
+
+func (m *mockStore) ThumbnailHandlerFunc() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {}
+}
+
 func (m *mockStore) StreamHandlerFunc() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {}
 }
 
+func (m *mockStore) AttachmentHandlerFunc() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {}
+}
+
+func (m *mockStore) DualStreamHandlerFunc() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {}
+}
+
 func (m *mockStore) StreamListHandlerFunc() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {}
 }
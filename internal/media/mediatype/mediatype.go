@@ -0,0 +1,309 @@
+// Package mediatype figures out the MIME type of media files. The standard
+// library sniffer only knows a handful of web formats, so Matroska, MPEG-TS,
+// AVI, FLAC and friends are recognised here by their magic numbers, with the
+// file extension as fallback and ffprobe as optional confirmation.
+package mediatype
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SniffLen is the amount of bytes Detect wants to see, same as
+// http.DetectContentType.
+const SniffLen = 512
+
+const octetStream = "application/octet-stream"
+
+// This is to allow for testing
+var ffprobeLookPath = "ffprobe"
+
+var extensions = map[string]string{
+	".mkv":  "video/x-matroska",
+	".mk3d": "video/x-matroska",
+	".mka":  "audio/x-matroska",
+	".webm": "video/webm",
+	".mp4":  "video/mp4",
+	".m4v":  "video/mp4",
+	".mov":  "video/quicktime",
+	".avi":  "video/x-msvideo",
+	".ts":   "video/mp2t",
+	".m2ts": "video/mp2t",
+	".mts":  "video/mp2t",
+	".mpg":  "video/mpeg",
+	".mpeg": "video/mpeg",
+	".vob":  "video/mpeg",
+	".wmv":  "video/x-ms-wmv",
+	".asf":  "video/x-ms-asf",
+	".flv":  "video/x-flv",
+	".ogv":  "video/ogg",
+	".3gp":  "video/3gpp",
+	".mp3":  "audio/mpeg",
+	".flac": "audio/flac",
+	".m4a":  "audio/mp4",
+	".m4b":  "audio/mp4",
+	".aac":  "audio/aac",
+	".ogg":  "audio/ogg",
+	".oga":  "audio/ogg",
+	".opus": "audio/opus",
+	".wav":  "audio/wav",
+	".wma":  "audio/x-ms-wma",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+	".bmp":  "image/bmp",
+	".tif":  "image/tiff",
+	".tiff": "image/tiff",
+	".heic": "image/heic",
+	".avif": "image/avif",
+}
+
+// transcoded lists containers which browsers can't play natively, so they
+// are remuxed/transcoded to mp4 when streamed.
+var transcoded = map[string]bool{
+	"video/x-matroska": true,
+	"video/x-msvideo":  true,
+	"video/mp2t":       true,
+	"video/mpeg":       true,
+	"video/x-ms-wmv":   true,
+	"video/x-ms-asf":   true,
+	"video/x-flv":      true,
+}
+
+// NeedsTranscode reports if media of mimeType has to be converted before a
+// browser can play it.
+func NeedsTranscode(mimeType string) bool {
+	mt, _, _ := strings.Cut(mimeType, ";")
+	return transcoded[strings.TrimSpace(mt)]
+}
+
+// IsMedia reports if mimeType is a video, audio or image type.
+func IsMedia(mimeType string) bool {
+	return strings.HasPrefix(mimeType, "video/") ||
+		strings.HasPrefix(mimeType, "audio/") ||
+		strings.HasPrefix(mimeType, "image/")
+}
+
+// FromExtension returns the MIME type registered for the extension of name,
+// or an empty string if it's unknown.
+func FromExtension(name string) string {
+	return extensions[strings.ToLower(filepath.Ext(name))]
+}
+
+// Detect the MIME type of the file called name with the content head, which
+// should be the first SniffLen bytes of the file. Magic numbers are checked
+// first, then http.DetectContentType. The extension is only consulted when
+// the content is unrecognised binary. sure is false when the result is based
+// on the extension alone.
+func Detect(name string, head []byte) (mimeType string, sure bool) {
+	if mt := Sniff(head); mt != "" {
+		return refineByExtension(mt, name), true
+	}
+	mt := http.DetectContentType(head)
+	if mt != octetStream {
+		return mt, true
+	}
+	if ext := FromExtension(name); ext != "" {
+		return ext, false
+	}
+	return mt, false
+}
+
+// refineByExtension settles ambiguities the magic numbers can't, such as an
+// audio-only Matroska file.
+func refineByExtension(mt, name string) string {
+	ext := FromExtension(name)
+	switch mt {
+	case "video/x-matroska", "video/mp4", "audio/ogg":
+		if strings.HasPrefix(ext, "audio/") {
+			return ext
+		}
+	}
+	return mt
+}
+
+// Sniff checks head against the magic numbers of common media containers.
+// Returns an empty string if there's no match.
+func Sniff(head []byte) string {
+	switch {
+	case len(head) < 4:
+		return ""
+	case bytes.HasPrefix(head, []byte{0x1A, 0x45, 0xDF, 0xA3}):
+		// EBML header, the DocType tells webm apart from matroska
+		if bytes.Contains(head[:min(len(head), 64)], []byte("webm")) {
+			return "video/webm"
+		}
+		return "video/x-matroska"
+	case isMPEGTS(head, 0, 188), isMPEGTS(head, 4, 192):
+		return "video/mp2t"
+	case bytes.HasPrefix(head, []byte{0x00, 0x00, 0x01, 0xBA}), bytes.HasPrefix(head, []byte{0x00, 0x00, 0x01, 0xB3}):
+		return "video/mpeg"
+	case len(head) >= 12 && bytes.HasPrefix(head, []byte("RIFF")):
+		switch string(head[8:12]) {
+		case "AVI ":
+			return "video/x-msvideo"
+		case "WAVE":
+			return "audio/wav"
+		case "WEBP":
+			return "image/webp"
+		}
+		return ""
+	case len(head) >= 12 && string(head[4:8]) == "ftyp":
+		return ftypBrand(string(head[8:12]))
+	case bytes.HasPrefix(head, []byte("FLV\x01")):
+		return "video/x-flv"
+	case bytes.HasPrefix(head, []byte{0x30, 0x26, 0xB2, 0x75, 0x8E, 0x66, 0xCF, 0x11}):
+		return "video/x-ms-asf"
+	case bytes.HasPrefix(head, []byte("fLaC")):
+		return "audio/flac"
+	case bytes.HasPrefix(head, []byte("OggS")):
+		switch {
+		case bytes.Contains(head, []byte("OpusHead")):
+			return "audio/opus"
+		case bytes.Contains(head, []byte("\x80theora")):
+			return "video/ogg"
+		}
+		return "audio/ogg"
+	case bytes.HasPrefix(head, []byte("ID3")):
+		return "audio/mpeg"
+	case bytes.HasPrefix(head, []byte{0xFF, 0xD8, 0xFF}):
+		return "image/jpeg"
+	case head[0] == 0xFF && head[1]&0xE0 == 0xE0 && head[2]&0xF0 != 0xF0 && head[2]&0x0C != 0x0C:
+		// MPEG audio frame sync, with valid bitrate and sample rate. Layer
+		// bits 00 is reserved for mp3, but used by ADTS framed AAC.
+		if head[1]&0x06 == 0 {
+			return "audio/aac"
+		}
+		return "audio/mpeg"
+	}
+	return ""
+}
+
+// isMPEGTS checks for the 0x47 sync byte at the start of consecutive
+// transport stream packets. offset is 4 for the 192 byte m2ts packets.
+func isMPEGTS(head []byte, offset, packetLen int) bool {
+	if len(head) < offset+packetLen+1 {
+		return false
+	}
+	for i := offset; i < len(head); i += packetLen {
+		if head[i] != 0x47 {
+			return false
+		}
+	}
+	return true
+}
+
+func ftypBrand(brand string) string {
+	switch {
+	case brand == "qt  ":
+		return "video/quicktime"
+	case brand == "M4A " || brand == "M4B " || brand == "M4P ":
+		return "audio/mp4"
+	case strings.HasPrefix(brand, "3g"):
+		return "video/3gpp"
+	case brand == "avif" || brand == "avis":
+		return "image/avif"
+	case brand == "heic" || brand == "heix" || brand == "mif1" || brand == "msf1":
+		return "image/heic"
+	}
+	return "video/mp4"
+}
+
+type probeOutput struct {
+	Streams []struct {
+		CodecType string `json:"codec_type"`
+	} `json:"streams"`
+	Format struct {
+		FormatName string `json:"format_name"`
+	} `json:"format"`
+}
+
+// Probe asks ffprobe for the container format of the file at p. It's slow
+// compared to Detect, so only use it to confirm guesses which aren't sure.
+func Probe(ctx context.Context, p string) (string, error) {
+	if _, err := exec.LookPath(ffprobeLookPath); err != nil {
+		return "", fmt.Errorf("ffprobe not found: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, ffprobeLookPath,
+		"-v", "quiet",
+		"-print_format", "json",
+		"-show_entries", "format=format_name:stream=codec_type",
+		p,
+	).Output()
+	if err != nil {
+		return "", fmt.Errorf("ffprobe failed: %w", err)
+	}
+	var po probeOutput
+	if err := json.Unmarshal(out, &po); err != nil {
+		return "", fmt.Errorf("failed to unmarshal ffprobe output: %w", err)
+	}
+	hasVideo, hasAudio := false, false
+	for _, s := range po.Streams {
+		switch s.CodecType {
+		case "video":
+			hasVideo = true
+		case "audio":
+			hasAudio = true
+		}
+	}
+	mt := fromFormatName(po.Format.FormatName, hasVideo, hasAudio)
+	if mt == "" {
+		return "", fmt.Errorf("unknown format: '%v'", po.Format.FormatName)
+	}
+	return mt, nil
+}
+
+func fromFormatName(formatName string, hasVideo, hasAudio bool) string {
+	audioOnly := hasAudio && !hasVideo
+	first, _, _ := strings.Cut(formatName, ",")
+	switch first {
+	case "matroska":
+		if audioOnly {
+			return "audio/x-matroska"
+		}
+		return "video/x-matroska"
+	case "mov":
+		if audioOnly {
+			return "audio/mp4"
+		}
+		return "video/mp4"
+	case "ogg":
+		if hasVideo {
+			return "video/ogg"
+		}
+		return "audio/ogg"
+	case "mpegts":
+		return "video/mp2t"
+	case "mpeg":
+		return "video/mpeg"
+	case "avi":
+		return "video/x-msvideo"
+	case "asf":
+		if audioOnly {
+			return "audio/x-ms-wma"
+		}
+		return "video/x-ms-asf"
+	case "flv":
+		return "video/x-flv"
+	case "flac":
+		return "audio/flac"
+	case "mp3":
+		return "audio/mpeg"
+	case "aac":
+		return "audio/aac"
+	case "wav":
+		return "audio/wav"
+	}
+	return ""
+}
@@ -0,0 +1,137 @@
+package mediatype
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func tsPackets(packetLen, offset, n int) []byte {
+	b := make([]byte, packetLen*n)
+	for i := 0; i < n; i++ {
+		b[i*packetLen+offset] = 0x47
+	}
+	return b
+}
+
+func TestDetect(t *testing.T) {
+	mp4 := []byte{0x00, 0x00, 0x00, 0x18, 'f', 't', 'y', 'p', 'm', 'p', '4', '2', 0x00, 0x00, 0x00, 0x00}
+	tests := []struct {
+		name     string
+		file     string
+		head     []byte
+		want     string
+		wantSure bool
+	}{
+		{"matroska", "movie.bin", []byte("\x1a\x45\xdf\xa3\x93\x42\x82\x88matroska\x42\x87\x81\x04"), "video/x-matroska", true},
+		{"webm", "clip.webm", []byte("\x1a\x45\xdf\xa3\x9f\x42\x86\x81\x01\x42\x82\x84webm"), "video/webm", true},
+		{"audio-only matroska by extension", "album.mka", []byte("\x1a\x45\xdf\xa3\x93\x42\x82\x88matroska"), "audio/x-matroska", true},
+		{"mpeg-ts", "show.ts", tsPackets(188, 0, 3), "video/mp2t", true},
+		{"m2ts", "disc.m2ts", tsPackets(192, 4, 3), "video/mp2t", true},
+		{"mpeg-ps", "old.mpg", []byte{0x00, 0x00, 0x01, 0xBA, 0x44, 0x00}, "video/mpeg", true},
+		{"avi", "movie.avi", []byte("RIFF\x00\x00\x00\x00AVI LIST"), "video/x-msvideo", true},
+		{"wav", "sound.wav", []byte("RIFF\x00\x00\x00\x00WAVEfmt "), "audio/wav", true},
+		{"mp4", "movie.mp4", mp4, "video/mp4", true},
+		{"m4a", "song.m4a", []byte("\x00\x00\x00\x20ftypM4A \x00\x00\x00\x00"), "audio/mp4", true},
+		{"quicktime", "clip.mov", []byte("\x00\x00\x00\x14ftypqt  \x00\x00\x00\x00"), "video/quicktime", true},
+		{"flv", "clip.flv", []byte("FLV\x01\x05\x00\x00\x00\x09"), "video/x-flv", true},
+		{"flac", "song.flac", []byte("fLaC\x00\x00\x00\x22"), "audio/flac", true},
+		{"mp3 with id3", "song.mp3", []byte("ID3\x03\x00\x00\x00\x00\x00\x00"), "audio/mpeg", true},
+		{"mp3 frame sync", "song.mp3", []byte{0xFF, 0xFB, 0x90, 0x64, 0x00}, "audio/mpeg", true},
+		{"aac adts", "song.aac", []byte{0xFF, 0xF1, 0x50, 0x80, 0x00}, "audio/aac", true},
+		{"opus", "song.opus", []byte("OggS\x00\x02\x00\x00\x00\x00\x00\x00\x00\x00OpusHead"), "audio/opus", true},
+		{"jpeg", "photo.jpg", []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x10, 'J', 'F', 'I', 'F'}, "image/jpeg", true},
+		{"png via stdlib", "photo.png", []byte("\x89PNG\x0D\x0A\x1A\x0A\x00\x00\x00\x0DIHDR"), "image/png", true},
+		{"unknown binary falls back to extension", "movie.mkv", []byte{0x00, 0x01, 0x02, 0x03, 0x04}, "video/x-matroska", false},
+		{"unknown binary without known extension", "blob.bin", []byte{0x00, 0x01, 0x02, 0x03, 0x04}, "application/octet-stream", false},
+		{"text is never media by extension", "script.ts", []byte("export const a = 1;\n"), "text/plain; charset=utf-8", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, sure := Detect(tc.file, tc.head)
+			if got != tc.want {
+				t.Fatalf("Detect(%q) = %q, want %q", tc.file, got, tc.want)
+			}
+			if sure != tc.wantSure {
+				t.Fatalf("Detect(%q) sure = %v, want %v", tc.file, sure, tc.wantSure)
+			}
+		})
+	}
+}
+
+func TestNeedsTranscode(t *testing.T) {
+	for mt, want := range map[string]bool{
+		"video/x-matroska":         true,
+		"video/x-msvideo":          true,
+		"video/mp2t":               true,
+		"video/x-matroska; foo=ba": true,
+		"video/mp4":                false,
+		"video/webm":               false,
+		"image/png":                false,
+	} {
+		if got := NeedsTranscode(mt); got != want {
+			t.Errorf("NeedsTranscode(%q) = %v, want %v", mt, got, want)
+		}
+	}
+}
+
+func TestFromFormatName(t *testing.T) {
+	tests := []struct {
+		format             string
+		hasVideo, hasAudio bool
+		want               string
+	}{
+		{"matroska,webm", true, true, "video/x-matroska"},
+		{"matroska,webm", false, true, "audio/x-matroska"},
+		{"mov,mp4,m4a,3gp,3g2,mj2", true, true, "video/mp4"},
+		{"mov,mp4,m4a,3gp,3g2,mj2", false, true, "audio/mp4"},
+		{"mpegts", true, true, "video/mp2t"},
+		{"ogg", false, true, "audio/ogg"},
+		{"flac", false, true, "audio/flac"},
+		{"tty", false, false, ""},
+	}
+	for _, tc := range tests {
+		if got := fromFormatName(tc.format, tc.hasVideo, tc.hasAudio); got != tc.want {
+			t.Errorf("fromFormatName(%q, %v, %v) = %q, want %q", tc.format, tc.hasVideo, tc.hasAudio, got, tc.want)
+		}
+	}
+}
+
+func TestProbe(t *testing.T) {
+	t.Run("uses ffprobe output", func(t *testing.T) {
+		dir := t.TempDir()
+		fake := filepath.Join(dir, "ffprobe")
+		script := "#!/bin/sh\necho '{\"streams\":[{\"codec_type\":\"audio\"}],\"format\":{\"format_name\":\"matroska,webm\"}}'\n"
+		if err := os.WriteFile(fake, []byte(script), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		orig := ffprobeLookPath
+		ffprobeLookPath = fake
+		t.Cleanup(func() { ffprobeLookPath = orig })
+
+		got, err := Probe(context.Background(), filepath.Join(dir, "whatever.mkv"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != "audio/x-matroska" {
+			t.Fatalf("got %q, want audio/x-matroska", got)
+		}
+	})
+
+	t.Run("errors when ffprobe is missing", func(t *testing.T) {
+		orig := ffprobeLookPath
+		ffprobeLookPath = "/no/such/ffprobe"
+		t.Cleanup(func() { ffprobeLookPath = orig })
+		if _, err := Probe(context.Background(), "x.mkv"); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
+func TestSniff_shortInput(t *testing.T) {
+	if got := Sniff(bytes.Repeat([]byte{0x47}, 3)); got != "" {
+		t.Fatalf("expected no match on short input, got %q", got)
+	}
+}
@@ -0,0 +1,51 @@
+// Package moviehash computes the OpenSubtitles hash of video files, which
+// identifies a particular release of a movie rather than the movie itself.
+package moviehash
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// chunkSize is how much of the head and of the tail of a file is hashed.
+const chunkSize = 64 << 10
+
+// ErrTooSmall is returned for files too small to be hashed.
+var ErrTooSmall = errors.New("file too small to hash")
+
+// Compute the hash of the file at path: its size plus the sum of the 64-bit
+// little-endian words of its first and last 64 KiB, overflowing, formatted as
+// 16 hex digits.
+func Compute(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open: %w", err)
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to stat: %w", err)
+	}
+	return Read(f, fi.Size())
+}
+
+// Read computes the hash of r, which is size bytes large.
+func Read(r io.ReaderAt, size int64) (string, error) {
+	if size < chunkSize*2 {
+		return "", ErrTooSmall
+	}
+	sum := uint64(size)
+	buf := make([]byte, chunkSize)
+	for _, off := range []int64{0, size - chunkSize} {
+		if _, err := r.ReadAt(buf, off); err != nil {
+			return "", fmt.Errorf("failed to read: %w", err)
+		}
+		for i := 0; i < chunkSize; i += 8 {
+			sum += binary.LittleEndian.Uint64(buf[i:])
+		}
+	}
+	return fmt.Sprintf("%016x", sum), nil
+}
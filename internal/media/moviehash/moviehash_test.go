@@ -0,0 +1,60 @@
+package moviehash
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRead(t *testing.T) {
+	data := make([]byte, 3*chunkSize)
+	binary.LittleEndian.PutUint64(data, 1)
+	// Outside of both the head and the tail, so not hashed
+	binary.LittleEndian.PutUint64(data[chunkSize+8:], 100)
+	binary.LittleEndian.PutUint64(data[len(data)-8:], 2)
+
+	got, err := Read(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "0000000000030003"; got != want {
+		t.Errorf("Read = %v, want %v", got, want)
+	}
+}
+
+func TestRead_Overflow(t *testing.T) {
+	data := make([]byte, 2*chunkSize)
+	for i := 0; i < len(data); i += 8 {
+		binary.LittleEndian.PutUint64(data[i:], ^uint64(0))
+	}
+	got, err := Read(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 16384 words of -1, plus the size
+	if want := "000000000001c000"; got != want {
+		t.Errorf("Read = %v, want %v", got, want)
+	}
+}
+
+func TestCompute(t *testing.T) {
+	dir := t.TempDir()
+	small := filepath.Join(dir, "small.mkv")
+	if err := os.WriteFile(small, []byte("tiny"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Compute(small); !errors.Is(err, ErrTooSmall) {
+		t.Errorf("expected ErrTooSmall, got: %v", err)
+	}
+
+	big := filepath.Join(dir, "big.mkv")
+	if err := os.WriteFile(big, make([]byte, 2*chunkSize), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := Compute(big); err != nil || got != "0000000000020000" {
+		t.Errorf("Compute = %v, %v", got, err)
+	}
+}
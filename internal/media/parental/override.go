@@ -0,0 +1,208 @@
+package parental
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/baalimago/go_away_boilerplate/pkg/ancli"
+)
+
+var (
+	// ErrNoPIN is returned when no PIN is configured, so nothing can be
+	// overridden.
+	ErrNoPIN = errors.New("no pin configured")
+	// ErrWrongPIN is returned for a PIN which isn't the configured one.
+	ErrWrongPIN = errors.New("wrong pin")
+	// ErrLockedOut is returned for any PIN after too many wrong ones.
+	ErrLockedOut = errors.New("too many wrong pins, try again later")
+	// ErrNoDevice is returned when overriding a device without a name,
+	// which would override every unnamed device.
+	ErrNoDevice = errors.New("no device named")
+)
+
+// Audit actions.
+const (
+	ActionOverride    = "override"
+	ActionEndOverride = "end_override"
+	ActionSetDevice   = "set_device"
+	ActionReadAudit   = "read_audit"
+)
+
+// AuditEntry is an attempt to get past the parental controls, granted or
+// not.
+type AuditEntry struct {
+	Time       time.Time `json:"time"`
+	Action     string    `json:"action"`
+	Device     string    `json:"device"`
+	RemoteAddr string    `json:"remoteAddr,omitempty"`
+	Granted    bool      `json:"granted"`
+	// Until is when a granted override ends.
+	Until time.Time `json:"until,omitzero"`
+	// Reason a request wasn't granted.
+	Reason string `json:"reason,omitempty"`
+}
+
+// checkPIN, counting the wrong ones. c.mu must be held.
+func (c *Controls) checkPIN(pin string) error {
+	if len(c.pinHash) == 0 {
+		return ErrNoPIN
+	}
+	now := c.now()
+	if now.Before(c.lockedUntil) {
+		return ErrLockedOut
+	}
+	sum := sha256.Sum256([]byte(pin))
+	if subtle.ConstantTimeCompare(sum[:], c.pinHash) != 1 {
+		c.failures++
+		if c.failures >= maxFailures {
+			c.failures = 0
+			c.lockedUntil = now.Add(lockout)
+		}
+		return ErrWrongPIN
+	}
+	c.failures = 0
+	return nil
+}
+
+// Override the limit of device until the returned time, if pin is right.
+// The override lasts d, at most and by default as long as configured.
+func (c *Controls) Override(device, remoteAddr, pin string, d time.Duration) (time.Time, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := AuditEntry{Time: c.now(), Action: ActionOverride, Device: device, RemoteAddr: remoteAddr}
+	if device == "" {
+		entry.Reason = ErrNoDevice.Error()
+		c.audit(entry)
+		return time.Time{}, ErrNoDevice
+	}
+	if err := c.checkPIN(pin); err != nil {
+		entry.Reason = err.Error()
+		c.audit(entry)
+		return time.Time{}, err
+	}
+	if d <= 0 || d > c.overrideFor {
+		d = c.overrideFor
+	}
+	entry.Granted = true
+	entry.Until = entry.Time.Add(d)
+	c.overrides[device] = entry.Until
+	c.audit(entry)
+	return entry.Until, nil
+}
+
+// EndOverride of device, if there is one. Reports whether there was.
+func (c *Controls) EndOverride(device, remoteAddr string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	until, ok := c.overrides[device]
+	delete(c.overrides, device)
+	if !ok || !c.now().Before(until) {
+		return false
+	}
+	c.audit(AuditEntry{Time: c.now(), Action: ActionEndOverride, Device: device, RemoteAddr: remoteAddr, Granted: true})
+	return true
+}
+
+// CheckPIN for an action other than overriding, such as naming a device or
+// reading the audit log, writing the attempt to the audit log.
+func (c *Controls) CheckPIN(action, device, remoteAddr, pin string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := AuditEntry{Time: c.now(), Action: action, Device: device, RemoteAddr: remoteAddr}
+	err := c.checkPIN(pin)
+	if err != nil {
+		entry.Reason = err.Error()
+	}
+	entry.Granted = err == nil
+	c.audit(entry)
+	return err
+}
+
+// overridden reports whether the limit of device is overridden now.
+func (c *Controls) overridden(device string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	until, ok := c.overrides[device]
+	if ok && !c.now().Before(until) {
+		delete(c.overrides, device)
+		return false
+	}
+	return ok
+}
+
+// audit the entry. c.mu must be held.
+func (c *Controls) audit(e AuditEntry) {
+	ancli.Noticef("parental controls: %v of device %q from %v, granted: %v %v", e.Action, e.Device, e.RemoteAddr, e.Granted, e.Reason)
+	if c.auditPath == "" {
+		return
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		ancli.Errf("failed to marshal parental audit entry: %v", err)
+		return
+	}
+	f, err := os.OpenFile(c.auditPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		ancli.Errf("failed to open parental audit log: %v", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		ancli.Errf("failed to write parental audit log: %v", err)
+	}
+}
+
+// Audit log, oldest first. Lines which can't be read are skipped.
+func (c *Controls) Audit() ([]AuditEntry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ret := []AuditEntry{}
+	if c.auditPath == "" {
+		return ret, nil
+	}
+	f, err := os.Open(c.auditPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return ret, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open parental audit log: %w", err)
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var e AuditEntry
+		if json.Unmarshal(sc.Bytes(), &e) == nil {
+			ret = append(ret, e)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read parental audit log: %w", err)
+	}
+	return ret, nil
+}
+
+// OverrideRequest is the body of POST /gallery/parental/override.
+type OverrideRequest struct {
+	PIN string `json:"pin"`
+	// Minutes the override lasts, as long as configured if 0.
+	Minutes int `json:"minutes,omitempty"`
+}
+
+// OverrideResponse tells until when the device is overridden.
+type OverrideResponse struct {
+	Device string    `json:"device"`
+	Until  time.Time `json:"until"`
+}
+
+// DeviceRequest is the body of POST /gallery/parental/device, which names
+// the device of the client.
+type DeviceRequest struct {
+	Device string `json:"device"`
+	PIN    string `json:"pin"`
+}
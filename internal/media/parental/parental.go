@@ -0,0 +1,292 @@
+// Package parental limits the age rating of what each device may see and
+// play, and lets whoever knows the PIN lift the limit for a while.
+package parental
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+const (
+	// DeviceCookie names the device of a request, signed by the server so
+	// that only whoever knows the PIN names a device.
+	DeviceCookie = "kinoview_device"
+
+	// defaultOverride is how long an override lasts unless configured.
+	defaultOverride = time.Hour
+	// maxFailures wrong PINs in a row lock overrides for lockout.
+	maxFailures = 5
+	lockout     = time.Minute
+)
+
+// Config of the parental controls, as written in their JSON file. Ratings
+// are written as items are rated, such as "PG-13", "TV-14" or "12", and an
+// empty rating is no limit.
+type Config struct {
+	// PINSHA256 is the hex SHA-256 of the PIN which overrides the limits.
+	// Without it there are no overrides.
+	PINSHA256 string `json:"pinSha256"`
+	// DefaultMaxRating limits the devices which aren't in Devices.
+	DefaultMaxRating string `json:"defaultMaxRating"`
+	// Devices limits each device by its name.
+	Devices map[string]string `json:"devices"`
+	// AgentMaxRating limits what the agents may list and suggest.
+	AgentMaxRating string `json:"agentMaxRating"`
+	// AllowUnrated lets limited devices see videos whose rating isn't
+	// known, which they otherwise don't.
+	AllowUnrated bool `json:"allowUnrated"`
+	// Folders rates every video under a folder, over what the video is
+	// rated itself. The deepest folder wins.
+	Folders map[string]string `json:"folders"`
+	// OverrideMinutes is how long an override lasts, an hour by default.
+	OverrideMinutes int `json:"overrideMinutes"`
+}
+
+// folderRule rates the videos under dir.
+type folderRule struct {
+	dir    string
+	rating Rating
+}
+
+// Controls enforce a Config. A nil Controls allows everything.
+type Controls struct {
+	pinHash      []byte
+	defaultLimit *Rating
+	devices      map[string]*Rating
+	agentLimit   *Rating
+	allowUnrated bool
+	folders      []folderRule
+	overrideFor  time.Duration
+	auditPath    string
+	keyPath      string
+	key          []byte
+	now          func() time.Time
+
+	mu          sync.Mutex
+	overrides   map[string]time.Time
+	failures    int
+	lockedUntil time.Time
+}
+
+type Option func(*Controls)
+
+// WithAuditLog sets the file the override attempts are appended to. Without
+// it, they are only logged.
+func WithAuditLog(p string) Option {
+	return func(c *Controls) {
+		c.auditPath = p
+	}
+}
+
+// WithKeyFile sets the file the key signing the device cookies is kept in,
+// created if missing. Without it the key is made anew on every start, and
+// every device has to be named again.
+func WithKeyFile(p string) Option {
+	return func(c *Controls) {
+		c.keyPath = p
+	}
+}
+
+// Load the Config at p.
+func Load(p string, opts ...Option) (*Controls, error) {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read parental controls: %w", err)
+	}
+	var cfg Config
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal parental controls: %w", err)
+	}
+	return New(cfg, opts...)
+}
+
+// New Controls of cfg, erroring on ratings which aren't understood.
+func New(cfg Config, opts ...Option) (*Controls, error) {
+	c := &Controls{
+		devices:      map[string]*Rating{},
+		allowUnrated: cfg.AllowUnrated,
+		overrideFor:  defaultOverride,
+		now:          time.Now,
+		overrides:    map[string]time.Time{},
+	}
+	var err error
+	if cfg.PINSHA256 != "" {
+		c.pinHash, err = hex.DecodeString(cfg.PINSHA256)
+		if err != nil || len(c.pinHash) != sha256.Size {
+			return nil, errors.New("pinSha256: not a hex SHA-256")
+		}
+	}
+	if c.defaultLimit, err = parseLimit(cfg.DefaultMaxRating); err != nil {
+		return nil, fmt.Errorf("defaultMaxRating: %w", err)
+	}
+	if c.agentLimit, err = parseLimit(cfg.AgentMaxRating); err != nil {
+		return nil, fmt.Errorf("agentMaxRating: %w", err)
+	}
+	for device, rating := range cfg.Devices {
+		if c.devices[device], err = parseLimit(rating); err != nil {
+			return nil, fmt.Errorf("devices: %v: %w", device, err)
+		}
+	}
+	for dir, rating := range cfg.Folders {
+		r, ok := ParseRating(rating)
+		if !ok {
+			return nil, fmt.Errorf("folders: %v: unknown rating %q", dir, rating)
+		}
+		c.folders = append(c.folders, folderRule{dir: filepath.Clean(dir), rating: r})
+	}
+	slices.SortFunc(c.folders, func(a, b folderRule) int { return len(b.dir) - len(a.dir) })
+	if cfg.OverrideMinutes > 0 {
+		c.overrideFor = time.Duration(cfg.OverrideMinutes) * time.Minute
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	if c.key, err = loadKey(c.keyPath); err != nil {
+		return nil, fmt.Errorf("device key: %w", err)
+	}
+	return c, nil
+}
+
+// loadKey at p, creating it if it's missing. A new key which isn't kept if
+// p is empty.
+func loadKey(p string) ([]byte, error) {
+	if p != "" {
+		key, err := os.ReadFile(p)
+		if err == nil && len(key) >= sha256.Size {
+			return key, nil
+		}
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	key := make([]byte, sha256.Size)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if p == "" {
+		return key, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(p, key, 0o600); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func parseLimit(s string) (*Rating, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	r, ok := ParseRating(s)
+	if !ok {
+		return nil, fmt.Errorf("unknown rating %q", s)
+	}
+	return &r, nil
+}
+
+// Device of the request, named by the cookie SignDevice signed. Empty if
+// there's no such cookie, or it isn't signed by c.
+func (c *Controls) Device(r *http.Request) string {
+	if c == nil {
+		return ""
+	}
+	ck, err := r.Cookie(DeviceCookie)
+	if err != nil {
+		return ""
+	}
+	name, sig, ok := strings.Cut(ck.Value, ".")
+	if !ok {
+		return ""
+	}
+	device, err := base64.RawURLEncoding.DecodeString(name)
+	if err != nil {
+		return ""
+	}
+	want, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(c.sign(device), want) {
+		return ""
+	}
+	return string(device)
+}
+
+// SignDevice, for the value of the DeviceCookie naming it. Only to be set
+// for whoever knows the PIN.
+func (c *Controls) SignDevice(device string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(device)) + "." +
+		base64.RawURLEncoding.EncodeToString(c.sign([]byte(device)))
+}
+
+func (c *Controls) sign(device []byte) []byte {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write(device)
+	return mac.Sum(nil)
+}
+
+// Rating of the item, the folder rules winning over its metadata. False if
+// it isn't known.
+func (c *Controls) Rating(it model.Item) (Rating, bool) {
+	p := filepath.Clean(it.Path)
+	for _, f := range c.folders {
+		if p == f.dir || strings.HasPrefix(p, f.dir+string(filepath.Separator)) {
+			return f.rating, true
+		}
+	}
+	if it.Metadata == nil {
+		return 0, false
+	}
+	return ParseRating(it.Metadata.AgeRating)
+}
+
+// Allowed reports whether the device of r may see and play the item.
+func (c *Controls) Allowed(r *http.Request, it model.Item) bool {
+	if c == nil {
+		return true
+	}
+	device := c.Device(r)
+	if c.overridden(device) {
+		return true
+	}
+	limit, ok := c.devices[device]
+	if !ok {
+		limit = c.defaultLimit
+	}
+	return c.within(limit, it)
+}
+
+// AllowedForAgents reports whether the agents may list and suggest the item.
+func (c *Controls) AllowedForAgents(it model.Item) bool {
+	if c == nil {
+		return true
+	}
+	return c.within(c.agentLimit, it)
+}
+
+// within reports whether the item is rated within limit, nil being no
+// limit. Only videos are rated.
+func (c *Controls) within(limit *Rating, it model.Item) bool {
+	if limit == nil || !strings.Contains(it.MIMEType, "video") {
+		return true
+	}
+	r, ok := c.Rating(it)
+	if !ok {
+		return c.allowUnrated
+	}
+	return r <= *limit
+}
@@ -0,0 +1,292 @@
+package parental
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+func TestParseRating(t *testing.T) {
+	for in, want := range map[string]Rating{
+		"G":           0,
+		"tv-y7":       7,
+		"PG":          10,
+		"Rated PG-13": 13,
+		"TV-14":       14,
+		"R":           17,
+		"NC-17":       18,
+		"12A":         12,
+		"FSK 16":      16,
+		"15+":         15,
+	} {
+		got, ok := ParseRating(in)
+		if !ok || got != want {
+			t.Errorf("ParseRating(%q) = %v, %v, want %v", in, got, ok, want)
+		}
+	}
+	for _, in := range []string{"", "Not Rated", "NR", "1999"} {
+		if got, ok := ParseRating(in); ok {
+			t.Errorf("ParseRating(%q) = %v, want no rating", in, got)
+		}
+	}
+}
+
+func TestNew_unknownRating(t *testing.T) {
+	if _, err := New(Config{DefaultMaxRating: "mild"}); err == nil {
+		t.Error("expected an unknown rating to fail")
+	}
+	if _, err := New(Config{PINSHA256: "1234"}); err == nil {
+		t.Error("expected a pin which isn't a sha256 to fail")
+	}
+}
+
+func video(path, rating string) model.Item {
+	it := model.Item{Path: path, MIMEType: "video/mp4"}
+	if rating != "" {
+		it.Metadata = &model.MediaMetadata{AgeRating: rating}
+	}
+	return it
+}
+
+// requestFrom the device, named by a cookie c signed.
+func requestFrom(c *Controls, device string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if device != "" {
+		r.AddCookie(&http.Cookie{Name: DeviceCookie, Value: c.SignDevice(device)})
+	}
+	return r
+}
+
+func TestControls_Allowed(t *testing.T) {
+	c, err := New(Config{
+		DefaultMaxRating: "PG",
+		Devices:          map[string]string{"living-room": "", "teen": "PG-13"},
+		Folders:          map[string]string{"/media/kids": "G", "/media/kids/scary": "R"},
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		device string
+		item   model.Item
+		want   bool
+	}{
+		{"within the default", "", video("/media/paddington.mkv", "PG"), true},
+		{"above the default", "", video("/media/heat.mkv", "R"), false},
+		{"unknown device has the default", "tablet", video("/media/heat.mkv", "R"), false},
+		{"unrestricted device", "living-room", video("/media/heat.mkv", "R"), true},
+		{"device limit", "teen", video("/media/spiderman.mkv", "PG-13"), true},
+		{"unrated is hidden", "", video("/media/holiday.mkv", ""), false},
+		{"unrated on unrestricted device", "living-room", video("/media/holiday.mkv", ""), true},
+		{"folder rates over metadata", "", video("/media/kids/bluey.mkv", "R"), true},
+		{"deepest folder wins", "", video("/media/kids/scary/it.mkv", "PG"), false},
+		{"folder prefix is a whole folder", "", video("/media/kidsplus/heat.mkv", "R"), false},
+		{"only videos are rated", "", model.Item{Path: "/media/song.mp3", MIMEType: "audio/mpeg"}, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := c.Allowed(requestFrom(c, tc.device), tc.item); got != tc.want {
+				t.Errorf("Allowed = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestControls_AllowUnrated(t *testing.T) {
+	c, err := New(Config{DefaultMaxRating: "PG", AllowUnrated: true})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if !c.Allowed(requestFrom(c, ""), video("/media/holiday.mkv", "")) {
+		t.Error("expected unrated video to be allowed")
+	}
+}
+
+func TestControls_AllowedForAgents(t *testing.T) {
+	c, err := New(Config{AgentMaxRating: "PG-13"})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if !c.AllowedForAgents(video("/a.mkv", "PG")) || c.AllowedForAgents(video("/b.mkv", "R")) {
+		t.Error("expected the agents to be limited to PG-13")
+	}
+	var nilControls *Controls
+	if !nilControls.AllowedForAgents(video("/b.mkv", "R")) || !nilControls.Allowed(requestFrom(nilControls, ""), video("/b.mkv", "R")) {
+		t.Error("expected nil controls to allow everything")
+	}
+}
+
+func TestControls_Device(t *testing.T) {
+	c, err := New(Config{}, WithKeyFile(filepath.Join(t.TempDir(), "key")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Device(requestFrom(c, "tablet")); got != "tablet" {
+		t.Errorf("Device = %q, want tablet", got)
+	}
+
+	t.Run("only a signed cookie names the device", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/?device=tablet", nil)
+		r.Header.Set("X-Kinoview-Device", "tablet")
+		if got := c.Device(r); got != "" {
+			t.Errorf("expected the query and header ignored, got %q", got)
+		}
+		r.AddCookie(&http.Cookie{Name: DeviceCookie, Value: "tablet"})
+		if got := c.Device(r); got != "" {
+			t.Errorf("expected an unsigned cookie ignored, got %q", got)
+		}
+		other, err := New(Config{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := c.Device(requestFrom(other, "tablet")); got != "" {
+			t.Errorf("expected a cookie signed by another key ignored, got %q", got)
+		}
+	})
+
+	t.Run("the key is kept", func(t *testing.T) {
+		again, err := New(Config{}, WithKeyFile(c.keyPath))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := again.Device(requestFrom(c, "tablet")); got != "tablet" {
+			t.Errorf("expected the cookie to survive a restart, got %q", got)
+		}
+	})
+}
+
+func pinHash(pin string) string {
+	sum := sha256.Sum256([]byte(pin))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestControls_Override(t *testing.T) {
+	auditPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	c, err := New(Config{PINSHA256: pinHash("1234"), DefaultMaxRating: "G", OverrideMinutes: 30}, WithAuditLog(auditPath))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	now := time.Date(2026, 1, 2, 20, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+	heat := video("/media/heat.mkv", "R")
+
+	if _, err := c.Override("tablet", "10.0.0.2", "0000", 0); !errors.Is(err, ErrWrongPIN) {
+		t.Fatalf("Override with wrong pin = %v, want ErrWrongPIN", err)
+	}
+	if c.Allowed(requestFrom(c, "tablet"), heat) {
+		t.Fatal("expected a wrong pin to override nothing")
+	}
+	if _, err := c.Override("", "10.0.0.2", "1234", 0); !errors.Is(err, ErrNoDevice) {
+		t.Fatalf("Override of an unnamed device = %v, want ErrNoDevice", err)
+	}
+	if c.Allowed(requestFrom(c, ""), heat) {
+		t.Fatal("expected the unnamed devices to keep their limit")
+	}
+
+	until, err := c.Override("tablet", "10.0.0.2", "1234", 2*time.Hour)
+	if err != nil {
+		t.Fatalf("Override: %v", err)
+	}
+	if want := now.Add(30 * time.Minute); !until.Equal(want) {
+		t.Errorf("until = %v, want it capped at %v", until, want)
+	}
+	if !c.Allowed(requestFrom(c, "tablet"), heat) {
+		t.Error("expected the override to allow the device everything")
+	}
+	if c.Allowed(requestFrom(c, "phone"), heat) {
+		t.Error("expected the override to be of the one device only")
+	}
+
+	now = now.Add(31 * time.Minute)
+	if c.Allowed(requestFrom(c, "tablet"), heat) {
+		t.Error("expected the override to have ended")
+	}
+
+	entries, err := c.Audit()
+	if err != nil {
+		t.Fatalf("Audit: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 audit entries, got %+v", entries)
+	}
+	if entries[0].Granted || entries[0].Reason == "" || entries[0].RemoteAddr != "10.0.0.2" {
+		t.Errorf("unexpected failed attempt: %+v", entries[0])
+	}
+	if entries[1].Granted || entries[1].Reason != ErrNoDevice.Error() {
+		t.Errorf("unexpected attempt without a device: %+v", entries[1])
+	}
+	if !entries[2].Granted || entries[2].Device != "tablet" || !entries[2].Until.Equal(until) {
+		t.Errorf("unexpected granted override: %+v", entries[2])
+	}
+}
+
+func TestControls_EndOverride(t *testing.T) {
+	c, err := New(Config{PINSHA256: pinHash("1234"), DefaultMaxRating: "G"})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if _, err := c.Override("tablet", "", "1234", 0); err != nil {
+		t.Fatalf("Override: %v", err)
+	}
+	if !c.EndOverride("tablet", "") {
+		t.Error("expected an override to end")
+	}
+	if c.Allowed(requestFrom(c, "tablet"), video("/media/heat.mkv", "R")) {
+		t.Error("expected the limit back after the override ended")
+	}
+}
+
+func TestControls_lockout(t *testing.T) {
+	c, err := New(Config{PINSHA256: pinHash("1234")})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	for range maxFailures {
+		if err := c.CheckPIN(ActionReadAudit, "", "", "0000"); !errors.Is(err, ErrWrongPIN) {
+			t.Fatalf("CheckPIN = %v, want ErrWrongPIN", err)
+		}
+	}
+	if err := c.CheckPIN(ActionReadAudit, "", "", "1234"); !errors.Is(err, ErrLockedOut) {
+		t.Fatalf("CheckPIN after too many wrong pins = %v, want ErrLockedOut", err)
+	}
+	now = now.Add(lockout)
+	if err := c.CheckPIN(ActionReadAudit, "", "", "1234"); err != nil {
+		t.Fatalf("CheckPIN after the lockout = %v", err)
+	}
+}
+
+func TestControls_noPIN(t *testing.T) {
+	c, err := New(Config{DefaultMaxRating: "G"})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if _, err := c.Override("tablet", "", "", 0); !errors.Is(err, ErrNoPIN) {
+		t.Fatalf("Override = %v, want ErrNoPIN", err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "parental.json")
+	if err := os.WriteFile(p, []byte(`{"defaultMaxRating": "PG", "devices": {"living-room": ""}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Load(p)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if c.Allowed(requestFrom(c, ""), video("/heat.mkv", "R")) || !c.Allowed(requestFrom(c, "living-room"), video("/heat.mkv", "R")) {
+		t.Error("expected the loaded limits to apply")
+	}
+}
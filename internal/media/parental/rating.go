@@ -0,0 +1,56 @@
+package parental
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Rating is the youngest age an item is suitable for, such as 13 for PG-13.
+type Rating int
+
+// ratings by the labels of the US film and TV ratings, and the British
+// ones which aren't an age. Other systems mostly rate by age, "FSK 16",
+// "12A" or "15+", which ParseRating reads as the number.
+var ratings = map[string]Rating{
+	"G":     0,
+	"TV-Y":  0,
+	"TV-G":  0,
+	"U":     0,
+	"TV-Y7": 7,
+	"PG":    10,
+	"TV-PG": 10,
+	"PG-13": 13,
+	"TV-14": 14,
+	"R":     17,
+	"TV-MA": 17,
+	"NC-17": 18,
+	"X":     18,
+}
+
+// ParseRating of an age rating as the classifier or a sidecar writes it,
+// false if it doesn't say an age, such as "Not Rated".
+func ParseRating(s string) (Rating, bool) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	s = strings.TrimSpace(strings.TrimPrefix(s, "RATED "))
+	if r, ok := ratings[s]; ok {
+		return r, true
+	}
+	start := strings.IndexFunc(s, isDigit)
+	if start == -1 {
+		return 0, false
+	}
+	end := strings.IndexFunc(s[start:], func(r rune) bool { return !isDigit(r) })
+	if end == -1 {
+		end = len(s) - start
+	}
+	age, err := strconv.Atoi(s[start : start+end])
+	// Larger numbers are years, runtimes or whatever else, not ages
+	if err != nil || age > 21 {
+		return 0, false
+	}
+	return Rating(age), true
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
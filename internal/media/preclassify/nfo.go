@@ -0,0 +1,216 @@
+package preclassify
+
+import (
+	"encoding/xml"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// maxNFOSize bounds how much of a sidecar is read. Kodi's are a few KB, the
+// odd one embedding fanart as base64 isn't worth the memory.
+const maxNFOSize = 1 << 20
+
+// nfo holds the fields of Kodi's <movie>, <episodedetails> and <tvshow>
+// documents which kinoview cares about, as well as those of the <Title>
+// documents in MediaBrowser's movie.xml.
+type nfo struct {
+	XMLName       xml.Name
+	Title         string     `xml:"title"`
+	OriginalTitle string     `xml:"originaltitle"`
+	ShowTitle     string     `xml:"showtitle"`
+	Year          string     `xml:"year"`
+	Premiered     string     `xml:"premiered"`
+	Aired         string     `xml:"aired"`
+	Plot          string     `xml:"plot"`
+	Outline       string     `xml:"outline"`
+	Runtime       string     `xml:"runtime"`
+	Season        string     `xml:"season"`
+	Episode       string     `xml:"episode"`
+	Actors        []nfoActor `xml:"actor"`
+	UniqueIDs     []nfoID    `xml:"uniqueid"`
+	IMDbID        string     `xml:"imdbid"`
+	MPAA          string     `xml:"mpaa"`
+	Certification string     `xml:"certification"`
+
+	LocalTitle     string     `xml:"LocalTitle"`
+	ProductionYear string     `xml:"ProductionYear"`
+	Overview       string     `xml:"Overview"`
+	RunningTime    string     `xml:"RunningTime"`
+	Persons        []nfoActor `xml:"Persons>Person"`
+	IMDB           string     `xml:"IMDB"`
+	ContentRating  string     `xml:"ContentRating"`
+	MPAARating     string     `xml:"MPAARating"`
+}
+
+// nfoID is one of Kodi's <uniqueid type="imdb">tt0113277</uniqueid>.
+type nfoID struct {
+	Type string `xml:"type,attr"`
+	ID   string `xml:",chardata"`
+}
+
+type nfoActor struct {
+	Name string `xml:"name"`
+	// MediaBrowser's persons are capitalized and typed, the crew included
+	PersonName string `xml:"Name"`
+	Type       string `xml:"Type"`
+}
+
+// fromNFO reads the sidecars of the video at p: <name>.nfo or movie.xml
+// next to it, and tvshow.nfo in the show folder for episodes.
+func fromNFO(p string) Metadata {
+	dir := filepath.Dir(p)
+	base := strings.TrimSuffix(filepath.Base(p), filepath.Ext(p))
+	var m Metadata
+	for _, sidecar := range []string{
+		filepath.Join(dir, base+".nfo"),
+		filepath.Join(dir, base+".xml"),
+		filepath.Join(dir, "movie.nfo"),
+		filepath.Join(dir, "movie.xml"),
+	} {
+		n, ok := readNFO(sidecar)
+		if !ok {
+			continue
+		}
+		m = n.metadata()
+		break
+	}
+	if m.Name != "" && m.ShowName == "" && m.Season == 0 && m.Episode == 0 {
+		return m
+	}
+	// Episodes name their show in its own folder, the parent of the season
+	// folder if there is one.
+	for _, showDir := range []string{dir, filepath.Dir(dir)} {
+		n, ok := readNFO(filepath.Join(showDir, "tvshow.nfo"))
+		if !ok || n.XMLName.Local != "tvshow" {
+			continue
+		}
+		if m.ShowName == "" {
+			m.ShowName = strings.TrimSpace(n.Title)
+		}
+		if len(m.Actors) == 0 {
+			m.Actors = n.actors()
+		}
+		if m.AgeRating == "" {
+			m.AgeRating = ageRating(first(n.MPAA, n.Certification))
+		}
+		break
+	}
+	return m
+}
+
+// readNFO at p, false if there is none or it isn't XML. Some .nfo files
+// only hold a link to a database entry, those say nothing we can use.
+func readNFO(p string) (nfo, bool) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nfo{}, false
+	}
+	defer f.Close()
+	var n nfo
+	dec := xml.NewDecoder(io.LimitReader(f, maxNFOSize))
+	dec.Strict = false
+	if err := dec.Decode(&n); err != nil {
+		return nfo{}, false
+	}
+	switch n.XMLName.Local {
+	case "movie", "episodedetails", "tvshow", "Title":
+		return n, true
+	}
+	return nfo{}, false
+}
+
+func (n nfo) metadata() Metadata {
+	var m Metadata
+	m.Name = strings.TrimSpace(first(n.Title, n.LocalTitle))
+	if alt := strings.TrimSpace(n.OriginalTitle); alt != m.Name {
+		m.AltName = alt
+	}
+	m.Year = atoi(first(n.Year, n.ProductionYear))
+	if m.Year == 0 {
+		for _, date := range []string{n.Premiered, n.Aired} {
+			if y, _, ok := strings.Cut(strings.TrimSpace(date), "-"); ok {
+				m.Year = atoi(y)
+				break
+			}
+		}
+	}
+	m.Description = strings.TrimSpace(first(n.Plot, n.Outline, n.Overview))
+	m.DurationMin = atoi(first(n.Runtime, n.RunningTime))
+	m.Actors = n.actors()
+	m.IMDbID = n.imdbID()
+	m.AgeRating = ageRating(first(n.MPAA, n.Certification, n.ContentRating, n.MPAARating))
+	if n.XMLName.Local == "episodedetails" {
+		m.ShowName = strings.TrimSpace(n.ShowTitle)
+		m.Season = atoi(n.Season)
+		m.Episode = atoi(n.Episode)
+	}
+	return m
+}
+
+// imdbID of the title, which Kodi has as a <uniqueid>, or in <imdbid> in
+// older sidecars, and MediaBrowser in <IMDB>.
+func (n nfo) imdbID() string {
+	for _, id := range n.UniqueIDs {
+		if strings.EqualFold(id.Type, "imdb") {
+			return strings.TrimSpace(id.ID)
+		}
+	}
+	return strings.TrimSpace(first(n.IMDbID, n.IMDB))
+}
+
+func (n nfo) actors() []string {
+	var ret []string
+	for _, a := range n.Actors {
+		if name := strings.TrimSpace(a.Name); name != "" {
+			ret = append(ret, name)
+		}
+	}
+	for _, p := range n.Persons {
+		if !strings.EqualFold(p.Type, "actor") {
+			continue
+		}
+		if name := strings.TrimSpace(p.PersonName); name != "" {
+			ret = append(ret, name)
+		}
+	}
+	return ret
+}
+
+// first non-blank of ss.
+func first(ss ...string) string {
+	for _, s := range ss {
+		if strings.TrimSpace(s) != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// atoi the leading number of s, 0 if there is none. Runtimes are sometimes
+// written "142 min".
+func atoi(s string) int {
+	s = strings.TrimSpace(s)
+	end := strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' })
+	if end == -1 {
+		end = len(s)
+	}
+	n, _ := strconv.Atoi(s[:end])
+	return n
+}
+
+// ageRating as written in a sidecar, such as "Rated PG-13", "US:PG-13" or
+// Kodi's "US:PG-13 / GB:12A", of which the first is taken.
+func ageRating(s string) string {
+	s, _, _ = strings.Cut(s, "/")
+	s = strings.TrimSpace(s)
+	if _, rating, ok := strings.Cut(s, ":"); ok {
+		s = strings.TrimSpace(rating)
+	}
+	if len(s) > len("rated ") && strings.EqualFold(s[:len("rated ")], "rated ") {
+		s = strings.TrimSpace(s[len("rated "):])
+	}
+	return s
+}
@@ -0,0 +1,355 @@
+// Package preclassify guesses the metadata of videos without an LLM, from
+// scene-release file names, the folders they're kept in and Kodi-style
+// .nfo/movie.xml sidecars. Only what these state outright is filled in, the
+// classifier is left to figure out the rest.
+package preclassify
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+// Metadata is the subset of constants.MetadataFormat which may be known
+// before classification. Zero values are unknown.
+type Metadata struct {
+	Name        string
+	ShowName    string
+	AltName     string
+	Actors      []string
+	Year        int
+	Description string
+	DurationMin int
+	Season      int
+	Episode     int
+	IMDbID      string
+	AgeRating   string
+}
+
+// Guess the metadata of the video at p. Sidecars are trusted over the file
+// name, which is trusted over the folders.
+func Guess(p string) Metadata {
+	m := fromFolders(p).overlay(fromName(filepath.Base(p)))
+	return m.overlay(fromNFO(p))
+}
+
+// Empty reports whether nothing at all is known.
+func (m Metadata) Empty() bool {
+	return m.Name == "" && m.ShowName == "" && m.AltName == "" &&
+		len(m.Actors) == 0 && m.Year == 0 && m.Description == "" &&
+		m.DurationMin == 0 && m.Season == 0 && m.Episode == 0 && m.IMDbID == "" &&
+		m.AgeRating == ""
+}
+
+// IsEpisode reports whether the video is known to be part of a series.
+func (m Metadata) IsEpisode() bool {
+	return m.ShowName != "" && m.Season > 0 && m.Episode > 0
+}
+
+// Complete reports whether enough is known to skip the classifier: what the
+// video is, a name and a description.
+func (m Metadata) Complete() bool {
+	if m.Name == "" || m.Description == "" {
+		return false
+	}
+	if m.IsEpisode() {
+		return true
+	}
+	return m.ShowName == "" && m.Season == 0 && m.Episode == 0 && m.Year > 0
+}
+
+// MediaMetadata is the guess as classified metadata.
+func (m Metadata) MediaMetadata() *model.MediaMetadata {
+	return &model.MediaMetadata{
+		Name:        m.Name,
+		ShowName:    m.ShowName,
+		AltName:     m.AltName,
+		Actors:      m.Actors,
+		Year:        m.Year,
+		Description: m.Description,
+		DurationMin: m.DurationMin,
+		Season:      m.Season,
+		Episode:     m.Episode,
+		IMDbID:      m.IMDbID,
+		AgeRating:   m.AgeRating,
+	}
+}
+
+// FromMediaMetadata takes the fields of md which Metadata has.
+func FromMediaMetadata(md model.MediaMetadata) Metadata {
+	return Metadata{
+		Name:        md.Name,
+		ShowName:    md.ShowName,
+		AltName:     md.AltName,
+		Actors:      md.Actors,
+		Year:        md.Year,
+		Description: md.Description,
+		DurationMin: md.DurationMin,
+		Season:      md.Season,
+		Episode:     md.Episode,
+		IMDbID:      md.IMDbID,
+		AgeRating:   md.AgeRating,
+	}
+}
+
+// Fill in what m doesn't know from o. What m knows is kept.
+func (m Metadata) Fill(o Metadata) Metadata {
+	return o.overlay(m)
+}
+
+// Merge the known fields into metadata produced by the classifier. Known
+// fields win, anything else the classifier came up with is kept.
+func (m Metadata) Merge(classified model.MediaMetadata) model.MediaMetadata {
+	if m.Name != "" {
+		classified.Name = m.Name
+	}
+	if m.ShowName != "" {
+		classified.ShowName = m.ShowName
+	}
+	if m.AltName != "" {
+		classified.AltName = m.AltName
+	}
+	if len(m.Actors) > 0 {
+		classified.Actors = m.Actors
+	}
+	if m.Year > 0 {
+		classified.Year = m.Year
+	}
+	if m.Description != "" {
+		classified.Description = m.Description
+	}
+	if m.DurationMin > 0 {
+		classified.DurationMin = m.DurationMin
+	}
+	if m.Season > 0 {
+		classified.Season = m.Season
+	}
+	if m.Episode > 0 {
+		classified.Episode = m.Episode
+	}
+	if m.IMDbID != "" {
+		classified.IMDbID = m.IMDbID
+	}
+	if m.AgeRating != "" {
+		classified.AgeRating = m.AgeRating
+	}
+	return classified
+}
+
+// Keys are the JSON keys of the metadata fields which are known.
+func (m Metadata) Keys() []string {
+	var keys []string
+	for _, f := range []struct {
+		key   string
+		known bool
+	}{
+		{"name", m.Name != ""},
+		{"showName", m.ShowName != ""},
+		{"alt_name", m.AltName != ""},
+		{"actors", len(m.Actors) > 0},
+		{"year", m.Year > 0},
+		{"description", m.Description != ""},
+		{"duration_min", m.DurationMin > 0},
+		{"season", m.Season > 0},
+		{"episode", m.Episode > 0},
+		{"imdb_id", m.IMDbID != ""},
+		{"age_rating", m.AgeRating != ""},
+	} {
+		if f.known {
+			keys = append(keys, f.key)
+		}
+	}
+	return keys
+}
+
+// Disagreements lists where the classifier contradicts what's known. The
+// known fields win either way, but a classifier which got these wrong has
+// likely mistaken the video for another.
+func (m Metadata) Disagreements(classified model.MediaMetadata) []string {
+	var out []string
+	texts := []struct{ key, known, classified string }{
+		{"name", m.Name, classified.Name},
+		{"showName", m.ShowName, classified.ShowName},
+	}
+	for _, t := range texts {
+		if t.known != "" && t.classified != "" && comparable(t.known) != comparable(t.classified) {
+			out = append(out, fmt.Sprintf("%v is %q, the classifier said %q", t.key, t.known, t.classified))
+		}
+	}
+	nums := []struct {
+		key               string
+		known, classified int
+	}{
+		{"year", m.Year, classified.Year},
+		{"season", m.Season, classified.Season},
+		{"episode", m.Episode, classified.Episode},
+	}
+	for _, n := range nums {
+		if n.known > 0 && n.classified > 0 && n.known != n.classified {
+			out = append(out, fmt.Sprintf("%v is %v, the classifier said %v", n.key, n.known, n.classified))
+		}
+	}
+	return out
+}
+
+// comparable strips what tells titles apart only in writing, so that
+// "2001: A Space Odyssey" is "2001 A Space Odyssey".
+func comparable(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// overlay o on m, o's known fields replacing m's.
+func (m Metadata) overlay(o Metadata) Metadata {
+	if o.Name != "" {
+		m.Name = o.Name
+	}
+	if o.ShowName != "" {
+		m.ShowName = o.ShowName
+	}
+	if o.AltName != "" {
+		m.AltName = o.AltName
+	}
+	if len(o.Actors) > 0 {
+		m.Actors = o.Actors
+	}
+	if o.Year > 0 {
+		m.Year = o.Year
+	}
+	if o.Description != "" {
+		m.Description = o.Description
+	}
+	if o.DurationMin > 0 {
+		m.DurationMin = o.DurationMin
+	}
+	if o.Season > 0 {
+		m.Season = o.Season
+	}
+	if o.Episode > 0 {
+		m.Episode = o.Episode
+	}
+	if o.IMDbID != "" {
+		m.IMDbID = o.IMDbID
+	}
+	if o.AgeRating != "" {
+		m.AgeRating = o.AgeRating
+	}
+	return m
+}
+
+var (
+	// episodeRe matches S02E06, s2e6, S02.E06 and multi-episode S02E06E07.
+	episodeRe = regexp.MustCompile(`(?i)\bS(\d{1,2})[ ._-]?E(\d{1,3})(?:[-E]+\d{1,3})*\b`)
+	// crossEpisodeRe matches the older 2x06 style.
+	crossEpisodeRe = regexp.MustCompile(`(?i)\b(\d{1,2})x(\d{2,3})\b`)
+	// bareEpisodeRe matches episode numbers in files already placed in a
+	// season folder: "E06", "Episode 6" or a leading "06 - ".
+	bareEpisodeRe = regexp.MustCompile(`(?i)(?:^|\b)(?:E|Ep|Episode)[ ._-]?(\d{1,3})\b|^(\d{1,3})\b`)
+	seasonDirRe   = regexp.MustCompile(`(?i)^(?:season|series|s)[ ._-]?(\d{1,2})$`)
+	yearRe        = regexp.MustCompile(`^[(\[]?((?:19|20)\d{2})[)\]]?$`)
+	// titledYearRe matches "Name (1999)" folders and files.
+	titledYearRe = regexp.MustCompile(`^(.+?)[ ._-]*[(\[]((?:19|20)\d{2})[)\]]`)
+	// releaseTagRe matches the tokens of a release name which say how the
+	// video was released, rather than what it is.
+	releaseTagRe = regexp.MustCompile(`(?i)^(?:\d{3,4}[pi]|4k|uhd|hdr\d*|dv|10bit|bluray|blu-ray|bdrip|brrip|remux|web|web-?dl|webrip|hdtv|hdrip|dvdrip|dvd|x26[45]|h26[45]|hevc|avc|xvid|aac\d?|ac3|dts|ddp?\d*|eac3|atmos|truehd|amzn|nf|dsnp|hmax|atvp|proper|repack|extended|unrated|internal|multi|subbed|dubbed)$`)
+)
+
+// fromName parses a scene-release style file name, e.g.
+// Show.Name.S02E06.Episode.Title.1080p.WEB-DL.mkv or
+// The.Matrix.1999.1080p.BluRay.x264-GROUP.mkv.
+func fromName(name string) Metadata {
+	base := spaced(strings.TrimSuffix(name, filepath.Ext(name)))
+	if loc := episodeRe.FindStringSubmatchIndex(base); loc != nil {
+		return episodeFromName(base, loc)
+	}
+	if loc := crossEpisodeRe.FindStringSubmatchIndex(base); loc != nil && loc[0] > 0 {
+		return episodeFromName(base, loc)
+	}
+	return movieFromName(base)
+}
+
+func episodeFromName(base string, loc []int) Metadata {
+	var m Metadata
+	m.Season, _ = strconv.Atoi(base[loc[2]:loc[3]])
+	m.Episode, _ = strconv.Atoi(base[loc[4]:loc[5]])
+	show := strings.Fields(trimSeparators(base[:loc[0]]))
+	// A year right before the episode tells shows of the same name apart,
+	// "Doctor Who 2005 S01E01", it's not part of the name.
+	if n := len(show); n > 1 && yearRe.MatchString(show[n-1]) {
+		show = show[:n-1]
+	}
+	m.ShowName = strings.Join(show, " ")
+	m.Name = strings.Join(untilTags(strings.Fields(trimSeparators(base[loc[1]:]))), " ")
+	return m
+}
+
+func movieFromName(base string) Metadata {
+	tokens := untilTags(strings.Fields(base))
+	// The year is the last one, "2001 A Space Odyssey 1968" is from 1968. The
+	// first token is never the year, it'd leave the movie without a name.
+	for i := len(tokens) - 1; i > 0; i-- {
+		sm := yearRe.FindStringSubmatch(tokens[i])
+		if sm == nil {
+			continue
+		}
+		name := trimSeparators(strings.Join(tokens[:i], " "))
+		if name == "" {
+			return Metadata{}
+		}
+		year, _ := strconv.Atoi(sm[1])
+		return Metadata{Name: name, Year: year}
+	}
+	return Metadata{}
+}
+
+// fromFolders guesses from the library layout, Show Name/Season 2/E06.mkv
+// or Movie Name (1999)/movie.mkv.
+func fromFolders(p string) Metadata {
+	dir := filepath.Base(filepath.Dir(p))
+	if sm := seasonDirRe.FindStringSubmatch(spaced(dir)); sm != nil {
+		var m Metadata
+		m.Season, _ = strconv.Atoi(sm[1])
+		show := spaced(filepath.Base(filepath.Dir(filepath.Dir(p))))
+		if tm := titledYearRe.FindStringSubmatch(show); tm != nil {
+			show = tm[1]
+		}
+		m.ShowName = trimSeparators(show)
+		base := spaced(strings.TrimSuffix(filepath.Base(p), filepath.Ext(p)))
+		if em := bareEpisodeRe.FindStringSubmatch(base); em != nil {
+			m.Episode, _ = strconv.Atoi(em[1] + em[2])
+		}
+		return m
+	}
+	if tm := titledYearRe.FindStringSubmatch(spaced(dir)); tm != nil {
+		year, _ := strconv.Atoi(tm[2])
+		return Metadata{Name: trimSeparators(tm[1]), Year: year}
+	}
+	return Metadata{}
+}
+
+// spaced replaces the dots and underscores release names use as spaces.
+func spaced(s string) string {
+	return strings.Join(strings.Fields(strings.NewReplacer(".", " ", "_", " ").Replace(s)), " ")
+}
+
+func trimSeparators(s string) string {
+	return strings.Trim(s, " -_.[]")
+}
+
+// untilTags returns the tokens before the first release tag.
+func untilTags(tokens []string) []string {
+	for i, t := range tokens {
+		// The release group is dash-appended to the last tag, x264-GROUP
+		tag, _, _ := strings.Cut(t, "-")
+		if releaseTagRe.MatchString(t) || releaseTagRe.MatchString(tag) {
+			return tokens[:i]
+		}
+	}
+	return tokens
+}
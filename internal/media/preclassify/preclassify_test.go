@@ -0,0 +1,298 @@
+package preclassify
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+func TestFromName(t *testing.T) {
+	tests := []struct {
+		name string
+		want Metadata
+	}{
+		{
+			name: "Show.Name.S02E06.1080p.mkv",
+			want: Metadata{ShowName: "Show Name", Season: 2, Episode: 6},
+		},
+		{
+			name: "The.Expanse.S01E03.Remember.the.Cant.1080p.WEB-DL.DD5.1.H264-GROUP.mkv",
+			want: Metadata{ShowName: "The Expanse", Name: "Remember the Cant", Season: 1, Episode: 3},
+		},
+		{
+			name: "Doctor.Who.2005.s04e12.720p.HDTV.mkv",
+			want: Metadata{ShowName: "Doctor Who", Season: 4, Episode: 12},
+		},
+		{
+			name: "Frasier - 3x14 - Death and the Dog.avi",
+			want: Metadata{ShowName: "Frasier", Name: "Death and the Dog", Season: 3, Episode: 14},
+		},
+		{
+			name: "The.Matrix.1999.1080p.BluRay.x264-GROUP.mkv",
+			want: Metadata{Name: "The Matrix", Year: 1999},
+		},
+		{
+			name: "2001.A.Space.Odyssey.1968.REMASTERED.mkv",
+			want: Metadata{Name: "2001 A Space Odyssey", Year: 1968},
+		},
+		{
+			name: "Blade Runner (1982).mp4",
+			want: Metadata{Name: "Blade Runner", Year: 1982},
+		},
+		{
+			name: "holiday_video.mp4",
+			want: Metadata{},
+		},
+		{
+			name: "1917.mkv",
+			want: Metadata{},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := fromName(tc.name)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("fromName(%q) = %+v, want %+v", tc.name, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFromFolders(t *testing.T) {
+	tests := []struct {
+		path string
+		want Metadata
+	}{
+		{
+			path: "/media/Show Name (2019)/Season 02/E06.mkv",
+			want: Metadata{ShowName: "Show Name", Season: 2, Episode: 6},
+		},
+		{
+			path: "/media/Show Name/S3/04 - Some Title.mkv",
+			want: Metadata{ShowName: "Show Name", Season: 3, Episode: 4},
+		},
+		{
+			path: "/media/Heat (1995)/heat.mkv",
+			want: Metadata{Name: "Heat", Year: 1995},
+		},
+		{
+			path: "/media/misc/clip.mkv",
+			want: Metadata{},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.path, func(t *testing.T) {
+			got := fromFolders(tc.path)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("fromFolders(%q) = %+v, want %+v", tc.path, got, tc.want)
+			}
+		})
+	}
+}
+
+func writeFile(t *testing.T, p, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGuess_MovieNFO(t *testing.T) {
+	dir := t.TempDir()
+	video := filepath.Join(dir, "matrix.mkv")
+	writeFile(t, video, "")
+	writeFile(t, filepath.Join(dir, "matrix.nfo"), `<?xml version="1.0" encoding="UTF-8" standalone="yes" ?>
+<movie>
+  <title>The Matrix</title>
+  <originaltitle>The Matrix</originaltitle>
+  <year>1999</year>
+  <plot>A hacker learns the truth about his reality.</plot>
+  <runtime>136</runtime>
+  <mpaa>Rated R</mpaa>
+  <actor><name>Keanu Reeves</name><role>Neo</role></actor>
+  <actor><name>Carrie-Anne Moss</name></actor>
+  <uniqueid type="tmdb">603</uniqueid>
+  <uniqueid type="imdb" default="true">tt0133093</uniqueid>
+</movie>`)
+
+	got := Guess(video)
+	want := Metadata{
+		Name:        "The Matrix",
+		Year:        1999,
+		Description: "A hacker learns the truth about his reality.",
+		DurationMin: 136,
+		Actors:      []string{"Keanu Reeves", "Carrie-Anne Moss"},
+		IMDbID:      "tt0133093",
+		AgeRating:   "R",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Guess = %+v, want %+v", got, want)
+	}
+	if !got.Complete() {
+		t.Error("expected movie nfo to be complete")
+	}
+}
+
+func TestGuess_MovieXML(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "Heat (1995)")
+	video := filepath.Join(dir, "heat.mkv")
+	writeFile(t, video, "")
+	writeFile(t, filepath.Join(dir, "movie.xml"), `<?xml version="1.0" encoding="utf-8"?>
+<Title>
+  <LocalTitle>Heat</LocalTitle>
+  <ProductionYear>1995</ProductionYear>
+  <Overview>A group of professional bank robbers start to feel the heat.</Overview>
+  <RunningTime>170</RunningTime>
+  <Persons>
+    <Person><Name>Al Pacino</Name><Type>Actor</Type></Person>
+    <Person><Name>Michael Mann</Name><Type>Director</Type></Person>
+  </Persons>
+</Title>`)
+
+	got := Guess(video)
+	if got.Name != "Heat" || got.Year != 1995 || got.DurationMin != 170 {
+		t.Errorf("Guess = %+v", got)
+	}
+	if !reflect.DeepEqual(got.Actors, []string{"Al Pacino"}) {
+		t.Errorf("Actors = %v, want only the actor", got.Actors)
+	}
+}
+
+func TestGuess_EpisodeWithShowNFO(t *testing.T) {
+	show := filepath.Join(t.TempDir(), "the_expanse")
+	video := filepath.Join(show, "Season 1", "The.Expanse.S01E03.1080p.mkv")
+	writeFile(t, video, "")
+	writeFile(t, filepath.Join(show, "tvshow.nfo"), `<tvshow>
+  <title>The Expanse</title>
+  <certification>US:TV-14 / GB:15</certification>
+  <actor><name>Steven Strait</name></actor>
+</tvshow>`)
+	writeFile(t, filepath.Join(show, "Season 1", "The.Expanse.S01E03.1080p.nfo"), `<episodedetails>
+  <title>Remember the Cant</title>
+  <season>1</season>
+  <episode>3</episode>
+  <aired>2015-12-22</aired>
+  <plot>Holden and the crew search for answers.</plot>
+</episodedetails>`)
+
+	got := Guess(video)
+	want := Metadata{
+		Name:        "Remember the Cant",
+		ShowName:    "The Expanse",
+		Year:        2015,
+		Description: "Holden and the crew search for answers.",
+		Season:      1,
+		Episode:     3,
+		Actors:      []string{"Steven Strait"},
+		AgeRating:   "TV-14",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Guess = %+v, want %+v", got, want)
+	}
+	if !got.Complete() {
+		t.Error("expected episode with nfo to be complete")
+	}
+}
+
+func Test_ageRating(t *testing.T) {
+	for in, want := range map[string]string{
+		"":                 "",
+		"PG-13":            "PG-13",
+		"Rated PG-13":      "PG-13",
+		"US:PG-13":         "PG-13",
+		"US:TV-MA / GB:18": "TV-MA",
+		" rated r ":        "r",
+		"FSK 16":           "FSK 16",
+	} {
+		if got := ageRating(in); got != want {
+			t.Errorf("ageRating(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestGuess_LinkOnlyNFO(t *testing.T) {
+	dir := t.TempDir()
+	video := filepath.Join(dir, "The.Matrix.1999.1080p.mkv")
+	writeFile(t, video, "")
+	writeFile(t, filepath.Join(dir, "The.Matrix.1999.1080p.nfo"), "https://www.imdb.com/title/tt0133093/\n")
+
+	got := Guess(video)
+	if want := (Metadata{Name: "The Matrix", Year: 1999}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Guess = %+v, want %+v", got, want)
+	}
+	if got.Complete() {
+		t.Error("expected no description to leave the guess incomplete")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	known := Metadata{ShowName: "Show Name", Season: 2, Episode: 6}
+	classified := model.MediaMetadata{Name: "Pilot", ShowName: "Show name!", Season: 1, Episode: 6, Description: "Things happen.", Genre: "Drama"}
+
+	got := known.Merge(classified)
+	want := model.MediaMetadata{Name: "Pilot", ShowName: "Show Name", Season: 2, Episode: 6, Description: "Things happen.", Genre: "Drama"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Merge = %+v, want %+v", got, want)
+	}
+}
+
+func TestFill(t *testing.T) {
+	known := Metadata{Name: "Heat", Year: 1995}
+	got := known.Fill(Metadata{Name: "Heat!", Year: 1996, Description: "Robbers.", IMDbID: "tt0113277"})
+	want := Metadata{Name: "Heat", Year: 1995, Description: "Robbers.", IMDbID: "tt0113277"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Fill = %+v, want %+v", got, want)
+	}
+}
+
+func TestComplete(t *testing.T) {
+	tests := []struct {
+		name string
+		m    Metadata
+		want bool
+	}{
+		{"empty", Metadata{}, false},
+		{"movie without description", Metadata{Name: "Heat", Year: 1995}, false},
+		{"movie", Metadata{Name: "Heat", Year: 1995, Description: "Robbers."}, true},
+		{"episode without name", Metadata{ShowName: "S", Season: 1, Episode: 1, Description: "d"}, false},
+		{"episode", Metadata{Name: "Pilot", ShowName: "S", Season: 1, Episode: 1, Description: "d"}, true},
+		{"partial episode", Metadata{Name: "Pilot", ShowName: "S", Season: 1, Description: "d", Year: 2000}, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.m.Complete(); got != tc.want {
+				t.Errorf("Complete() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDisagreements(t *testing.T) {
+	known := Metadata{Name: "2001 A Space Odyssey", Year: 1968}
+	if got := known.Disagreements(model.MediaMetadata{Name: "2001: A Space Odyssey", Year: 1968, Description: "Apes."}); len(got) != 0 {
+		t.Errorf("expected no disagreements, got %v", got)
+	}
+
+	known = Metadata{ShowName: "Show Name", Season: 2, Episode: 6}
+	got := known.Disagreements(model.MediaMetadata{ShowName: "Other Show", Season: 2, Episode: 7})
+	want := []string{
+		`showName is "Show Name", the classifier said "Other Show"`,
+		"episode is 6, the classifier said 7",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Disagreements = %q, want %q", got, want)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	got := Metadata{ShowName: "Show Name", Season: 2, Episode: 6}.Keys()
+	if want := []string{"showName", "season", "episode"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Keys = %v, want %v", got, want)
+	}
+}
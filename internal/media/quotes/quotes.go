@@ -0,0 +1,261 @@
+// Package quotes indexes the dialogue in the subtitles extracted by the
+// stream manager, so that the library can be searched for what's said in it.
+package quotes
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/baalimago/kinoview/internal/agents"
+	"github.com/baalimago/kinoview/internal/media/stream"
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+// ErrEmptyQuery is returned when a query holds no words.
+var ErrEmptyQuery = errors.New("query holds no words")
+
+// line is a cue of an indexed subtitle.
+type line struct {
+	start, end time.Duration
+	text       string
+	// norm is the words of text, lowercased, each followed by a space, see
+	// normalize.
+	norm string
+}
+
+// file is an indexed subtitle of a stream.
+type file struct {
+	itemID      string
+	streamIndex string
+	modTime     time.Time
+	lines       []line
+	// words maps each word to the lines it's in.
+	words map[string][]int
+}
+
+// Index is a full-text index of the subtitles in the cache of a stream
+// manager. It's kept up to date as it's searched: subtitles extracted or
+// synced since the last search are read, and those removed are dropped.
+type Index struct {
+	dir   string
+	items agents.ItemLister
+
+	mu    sync.Mutex
+	files map[string]*file
+}
+
+// New creates an index of the subtitles in dir, the storage path of the
+// stream manager, belonging to the items listed by items.
+func New(dir string, items agents.ItemLister) *Index {
+	return &Index{dir: dir, items: items, files: make(map[string]*file)}
+}
+
+// subtitleName parses the name of an extracted subtitle, "<id>_<idx>.vtt",
+// or of a synced one, "<id>_<idx>.synced.vtt".
+func subtitleName(name string) (itemID, streamIndex string, synced, ok bool) {
+	base, found := strings.CutSuffix(name, ".vtt")
+	if !found {
+		return "", "", false, false
+	}
+	base, synced = strings.CutSuffix(base, ".synced")
+	itemID, streamIndex, found = strings.Cut(base, "_")
+	if !found || itemID == "" {
+		return "", "", false, false
+	}
+	if _, err := strconv.Atoi(streamIndex); err != nil {
+		return "", "", false, false
+	}
+	return itemID, streamIndex, synced, true
+}
+
+// refresh reads the subtitles which changed since last time. A synced
+// subtitle is indexed in place of the one it was synced from, since its
+// timing matches the video. Callers hold i.mu.
+func (i *Index) refresh() error {
+	entries, err := os.ReadDir(i.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		clear(i.files)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read subtitle cache: %w", err)
+	}
+
+	synced := make(map[string]bool)
+	for _, e := range entries {
+		if id, idx, s, ok := subtitleName(e.Name()); ok && s {
+			synced[id+"_"+idx] = true
+		}
+	}
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		id, idx, s, ok := subtitleName(e.Name())
+		if !ok || e.IsDir() || (!s && synced[id+"_"+idx]) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		p := filepath.Join(i.dir, e.Name())
+		seen[p] = true
+		if f, ok := i.files[p]; ok && f.modTime.Equal(info.ModTime()) {
+			continue
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			continue
+		}
+		i.files[p] = indexFile(id, idx, info.ModTime(), stream.ReadCues(data))
+	}
+	for p := range i.files {
+		if !seen[p] {
+			delete(i.files, p)
+		}
+	}
+	return nil
+}
+
+func indexFile(itemID, streamIndex string, modTime time.Time, cues []stream.Cue) *file {
+	f := &file{
+		itemID:      itemID,
+		streamIndex: streamIndex,
+		modTime:     modTime,
+		words:       make(map[string][]int),
+	}
+	for _, c := range cues {
+		text := strings.ReplaceAll(c.Text, "\n", " ")
+		ws := words(text)
+		n := len(f.lines)
+		f.lines = append(f.lines, line{start: c.Start, end: c.End, text: text, norm: normalize(ws)})
+		for _, w := range ws {
+			if l := f.words[w]; len(l) == 0 || l[len(l)-1] != n {
+				f.words[w] = append(l, n)
+			}
+		}
+	}
+	return f
+}
+
+// SearchQuotes returns the lines in which the words of query are said, in
+// that order, at most limit of them, and the total number of such lines.
+// Matches are ordered by item name, then by time. The same line in several
+// subtitles of an item, such as a regular and an SDH track, is only
+// returned once, from the first. Only the items allowed reports true for are
+// searched, every item if it's nil.
+func (i *Index) SearchQuotes(query string, limit int, allowed func(model.Item) bool) ([]model.Quote, int, error) {
+	qWords := words(query)
+	if len(qWords) == 0 {
+		return nil, 0, ErrEmptyQuery
+	}
+	phrase := normalize(qWords)
+
+	items := make(map[string]model.Item)
+	if i.items != nil {
+		for _, it := range i.items.Snapshot() {
+			items[it.ID] = it
+		}
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if err := i.refresh(); err != nil {
+		return nil, 0, err
+	}
+
+	var quotes []model.Quote
+	for _, f := range i.files {
+		item, ok := items[f.itemID]
+		if !ok || (allowed != nil && !allowed(item)) {
+			continue
+		}
+		offset := item.SubtitleOffset(f.streamIndex)
+		streamIndex, _ := strconv.Atoi(f.streamIndex)
+		for _, n := range f.candidates(qWords) {
+			l := f.lines[n]
+			if !strings.Contains(" "+l.norm, " "+phrase) {
+				continue
+			}
+			start := max(l.start+offset, 0)
+			quotes = append(quotes, model.Quote{
+				ItemID:      item.ID,
+				Name:        item.Name,
+				StreamIndex: streamIndex,
+				Start:       start.Seconds(),
+				End:         max(l.end+offset, 0).Seconds(),
+				Text:        l.text,
+				Link:        Link(item.ID, start),
+			})
+		}
+	}
+	sort.Slice(quotes, func(a, b int) bool {
+		if quotes[a].Name != quotes[b].Name {
+			return quotes[a].Name < quotes[b].Name
+		}
+		if quotes[a].Start != quotes[b].Start {
+			return quotes[a].Start < quotes[b].Start
+		}
+		return quotes[a].StreamIndex < quotes[b].StreamIndex
+	})
+	// The same line, said within the same second
+	quotes = slices.CompactFunc(quotes, func(a, b model.Quote) bool {
+		return a.ItemID == b.ItemID && int64(a.Start) == int64(b.Start)
+	})
+	total := len(quotes)
+	if limit > 0 && total > limit {
+		quotes = quotes[:limit]
+	}
+	return quotes, total, nil
+}
+
+// candidates returns the lines holding every word of qWords, by way of the
+// rarest of them.
+func (f *file) candidates(qWords []string) []int {
+	var rarest []int
+	for n, w := range qWords {
+		l, ok := f.words[w]
+		if !ok {
+			return nil
+		}
+		if n == 0 || len(l) < len(rarest) {
+			rarest = l
+		}
+	}
+	return rarest
+}
+
+// Link to the player playing the video of itemID from at, in whole seconds.
+// The player seeks there itself, whether the video is served as is or
+// transcoded.
+func Link(itemID string, at time.Duration) string {
+	return fmt.Sprintf("/?play=%s&t=%d", url.QueryEscape(itemID), int64(at/time.Second))
+}
+
+// words splits s into its lowercased words, so that "I'll be back!" is
+// "i", "ll", "be", "back".
+func words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// normalize joins ws, each followed by a space, so that a phrase matches
+// whole words only: " "+phrase is in " "+line.
+func normalize(ws []string) string {
+	var b strings.Builder
+	for _, w := range ws {
+		b.WriteString(w)
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
@@ -0,0 +1,107 @@
+package quotes
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+type lister []model.Item
+
+func (l lister) Snapshot() []model.Item { return l }
+
+func writeVTT(t *testing.T, dir, name string, cues ...string) {
+	t.Helper()
+	data := "WEBVTT\n"
+	for _, c := range cues {
+		data += "\n" + c + "\n"
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSearchQuotes(t *testing.T) {
+	dir := t.TempDir()
+	items := lister{
+		{ID: "t2", Name: "Terminator 2.mkv", SubtitleOffsets: map[string]int64{"3": 1500}},
+		{ID: "t1", Name: "The Terminator.mkv"},
+	}
+	writeVTT(t, dir, "t1_2.vtt",
+		"01:02:03.000 --> 01:02:05.000\n<i>I'll be back.</i>",
+		"01:10:00.000 --> 01:10:02.000\nBack to the car!",
+	)
+	// The SDH track says the same
+	writeVTT(t, dir, "t1_4.vtt", "01:02:03.200 --> 01:02:05.000\n[GRUFF] I'll be\nback.")
+	writeVTT(t, dir, "t2_3.vtt", "00:00:10.000 --> 00:00:12.000\nStay here. I'll be back.")
+	writeVTT(t, dir, "gone_2.vtt", "00:00:01.000 --> 00:00:02.000\nI'll be back")
+	writeVTT(t, dir, "t2_3_font.vtt", "00:00:01.000 --> 00:00:02.000\nI'll be back")
+
+	idx := New(dir, items)
+	found, total, err := idx.SearchQuotes("i'll BE back", 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 || len(found) != 2 {
+		t.Fatalf("expected 2 matches, got %d: %+v", total, found)
+	}
+	if q := found[0]; q.ItemID != "t2" || q.Start != 11.5 || q.Link != "/?play=t2&t=11" {
+		t.Errorf("expected the offset to be applied, got: %+v", q)
+	}
+	if q := found[1]; q.ItemID != "t1" || q.StreamIndex != 2 || q.Text != "I'll be back." || q.Start != 3723 {
+		t.Errorf("unexpected match: %+v", q)
+	}
+	onlyT1 := func(it model.Item) bool { return it.ID == "t1" }
+	if found, total, _ := idx.SearchQuotes("i'll be back", 10, onlyT1); total != 1 || found[0].ItemID != "t1" {
+		t.Errorf("expected only the allowed item searched, got %d: %+v", total, found)
+	}
+
+	if found, _, _ := idx.SearchQuotes("be back to", 10, nil); len(found) != 0 {
+		t.Errorf("expected the words to match in order only, got: %+v", found)
+	}
+	if found, _, _ := idx.SearchQuotes("bac", 10, nil); len(found) != 0 {
+		t.Errorf("expected whole words only, got: %+v", found)
+	}
+	if _, total, _ := idx.SearchQuotes("back", 1, nil); total != 3 {
+		t.Errorf("expected the total beyond the limit, got: %d", total)
+	}
+	if _, _, err := idx.SearchQuotes("?!", 10, nil); !errors.Is(err, ErrEmptyQuery) {
+		t.Errorf("expected ErrEmptyQuery, got: %v", err)
+	}
+}
+
+func TestSearchQuotes_Refresh(t *testing.T) {
+	dir := t.TempDir()
+	idx := New(dir, lister{{ID: "vid", Name: "vid.mkv"}})
+	if found, _, err := idx.SearchQuotes("hello", 10, nil); err != nil || len(found) != 0 {
+		t.Fatalf("expected nothing before extraction, got: %+v, err: %v", found, err)
+	}
+
+	writeVTT(t, dir, "vid_2.vtt", "00:00:05.000 --> 00:00:06.000\nHello there")
+	if found, _, _ := idx.SearchQuotes("hello", 10, nil); len(found) != 1 || found[0].Start != 5 {
+		t.Fatalf("expected the new subtitle to be indexed, got: %+v", found)
+	}
+
+	// Synced, it's said later
+	writeVTT(t, dir, "vid_2.synced.vtt", "00:00:07.000 --> 00:00:08.000\nHello there")
+	if found, _, _ := idx.SearchQuotes("hello", 10, nil); len(found) != 1 || found[0].Start != 7 {
+		t.Fatalf("expected the synced subtitle to take over, got: %+v", found)
+	}
+
+	later := time.Now().Add(time.Minute)
+	writeVTT(t, dir, "vid_2.synced.vtt", "00:00:09.000 --> 00:00:10.000\nGoodbye")
+	os.Chtimes(filepath.Join(dir, "vid_2.synced.vtt"), later, later)
+	if found, _, _ := idx.SearchQuotes("hello", 10, nil); len(found) != 0 {
+		t.Fatalf("expected the changed subtitle to be read anew, got: %+v", found)
+	}
+
+	os.Remove(filepath.Join(dir, "vid_2.synced.vtt"))
+	os.Remove(filepath.Join(dir, "vid_2.vtt"))
+	if found, _, _ := idx.SearchQuotes("goodbye", 10, nil); len(found) != 0 {
+		t.Fatalf("expected removed subtitles to be dropped, got: %+v", found)
+	}
+}
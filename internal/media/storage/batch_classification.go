@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	"github.com/baalimago/go_away_boilerplate/pkg/ancli"
+	"github.com/baalimago/kinoview/internal/agents"
+	"github.com/baalimago/kinoview/internal/media/preclassify"
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+// batchGroup of an item: videos in the same directory, like the episodes of
+// a season, are classified together when the classifier can. The grouping
+// is that of `kinoview media list`. Items which are in no group are "".
+func batchGroup(i model.Item) string {
+	if !strings.Contains(i.MIMEType, "video") || i.Path == "" {
+		return ""
+	}
+	dir := path.Dir(i.Path)
+	if dir == "." || dir == "/" {
+		return ""
+	}
+	return dir
+}
+
+// batchSize the delegator hands to the workers: one unless the classifier
+// can classify several items in one go.
+func (s *store) batchSize() int {
+	s.classifierMu.RLock()
+	defer s.classifierMu.RUnlock()
+	if _, ok := s.classifier.(agents.BatchClassifier); !ok {
+		return 1
+	}
+	return max(s.classificationBatchSize, 1)
+}
+
+// classifyCandidates with c, as a batch if there are several and c is an
+// agents.BatchClassifier. Whatever the batch misses is classified one by one.
+func (s *store) classifyCandidates(ctx context.Context, c agents.Classifier, batch []classificationCandidate) []classificationResult {
+	var results []classificationResult
+	if bc, ok := c.(agents.BatchClassifier); ok && len(batch) > 1 {
+		results, batch = s.classifyAsBatch(ctx, c, bc, batch)
+	}
+	for _, cand := range batch {
+		classifyCtx, cancel := s.classificationContext(ctx)
+		i, by, err := s.classify(classifyCtx, c, cand.item)
+		cancel()
+		if err == nil {
+			s.checkClassification(&i)
+		}
+		results = append(results, classificationResult{
+			correlationID: cand.correlationID,
+			classifierErr: err,
+			item:          i,
+			by:            by,
+		})
+	}
+	return results
+}
+
+// classifyAsBatch asks bc to classify the candidates which aren't
+// pre-classified in one call, returning the results and the candidates left
+// to classify one by one.
+func (s *store) classifyAsBatch(ctx context.Context, c agents.Classifier, bc agents.BatchClassifier, batch []classificationCandidate) ([]classificationResult, []classificationCandidate) {
+	var results []classificationResult
+	var ask []model.Item
+	var rest []classificationCandidate
+	known := make(map[string]preclassify.Metadata, len(batch))
+	candidates := make(map[string]classificationCandidate, len(batch))
+	for _, cand := range batch {
+		i, k, done := s.prepareClassification(cand.item)
+		if done {
+			results = append(results, classificationResult{
+				correlationID: cand.correlationID,
+				item:          i,
+				by:            model.Provenance{Source: model.SourcePreclassify},
+			})
+			continue
+		}
+		ask = append(ask, i)
+		known[i.ID] = k
+		candidates[i.ID] = cand
+		rest = append(rest, cand)
+	}
+	if len(ask) < 2 {
+		return results, rest
+	}
+
+	classifyCtx, cancel := s.classificationContext(ctx)
+	classified, err := bc.ClassifyBatch(classifyCtx, ask)
+	cancel()
+	if err != nil {
+		ancli.Warnf("batch classification of %v items failed, classifying them one by one: %v", len(ask), err)
+		return results, rest
+	}
+	for _, i := range classified {
+		cand, ok := candidates[i.ID]
+		if !ok || i.Metadata == nil {
+			continue
+		}
+		i = finishClassification(known[i.ID], i)
+		s.checkClassification(&i)
+		results = append(results, classificationResult{
+			correlationID: cand.correlationID,
+			item:          i,
+			by:            classifiedProvenance(c, i),
+		})
+		delete(candidates, i.ID)
+	}
+	rest = rest[:0]
+	for _, cand := range batch {
+		if _, missed := candidates[cand.item.ID]; missed {
+			ancli.Noticef("[%v] batch classification missed %v, classifying it on its own", cand.correlationID, cand.item.Name)
+			rest = append(rest, cand)
+		}
+	}
+	return results, rest
+}
+
+// classificationContext bounds one call of the classifier by the
+// classification timeout, if there is one.
+func (s *store) classificationContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.classificationTimeout > 0 {
+		return context.WithTimeout(ctx, s.classificationTimeout)
+	}
+	return ctx, func() {}
+}
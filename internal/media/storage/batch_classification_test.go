@@ -0,0 +1,185 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+func episode(dir, id string) classificationCandidate {
+	return classificationCandidate{
+		correlationID: id,
+		item:          model.Item{ID: id, Name: id + ".mkv", MIMEType: "video/x-matroska", Path: path.Join(dir, id+".mkv")},
+	}
+}
+
+func Test_batchGroup(t *testing.T) {
+	for _, tc := range []struct {
+		item model.Item
+		want string
+	}{
+		{model.Item{MIMEType: "video/mp4", Path: "/tv/Show/Season 1/e1.mp4"}, "/tv/Show/Season 1"},
+		{model.Item{MIMEType: "image/jpeg", Path: "/tv/Show/Season 1/poster.jpg"}, ""},
+		{model.Item{MIMEType: "video/mp4", Path: "e1.mp4"}, ""},
+		{model.Item{MIMEType: "video/mp4"}, ""},
+	} {
+		if got := batchGroup(tc.item); got != tc.want {
+			t.Errorf("batchGroup(%v) = %q, want %q", tc.item.Path, got, tc.want)
+		}
+	}
+}
+
+func Test_classificationQueue_peekBatch(t *testing.T) {
+	q := newClassificationQueue("")
+	q.push(episode("/tv/a", "a1"), model.PriorityNew)
+	q.push(episode("/tv/b", "b1"), model.PriorityNew)
+	q.push(episode("/tv/a", "a2"), model.PriorityRetry)
+	q.push(episode("/tv/a", "a3"), model.PriorityNew)
+
+	batch := q.peekBatch(3)
+	ids := []string{}
+	for _, c := range batch {
+		ids = append(ids, c.item.ID)
+	}
+	if len(ids) != 3 || ids[0] != "a1" || ids[1] != "a3" || ids[2] != "a2" {
+		t.Fatalf("expected the group of the head, most urgent first, got %v", ids)
+	}
+	if got := q.peekBatch(2); len(got) != 2 {
+		t.Fatalf("expected the batch to be capped, got %v items", len(got))
+	}
+	if got := q.peekBatch(1); len(got) != 1 {
+		t.Fatalf("expected no batch, got %v items", len(got))
+	}
+}
+
+func Test_store_classifyCandidates(t *testing.T) {
+	classified := func(i model.Item) model.Item {
+		i.Metadata = &model.MediaMetadata{Name: "one by one " + i.ID}
+		return i
+	}
+
+	t.Run("misses are classified one by one", func(t *testing.T) {
+		s := NewStore(WithStorePath(t.TempDir()))
+		var single []string
+		c := &mockBatchClassifier{
+			mockClassifier: mockClassifier{ClassifyFunc: func(ctx context.Context, i model.Item) (model.Item, error) {
+				single = append(single, i.ID)
+				return classified(i), nil
+			}},
+			BatchFunc: func(ctx context.Context, items []model.Item) ([]model.Item, error) {
+				if len(items) != 3 {
+					t.Fatalf("expected a batch of 3, got %v", len(items))
+				}
+				i := items[0]
+				i.Metadata = &model.MediaMetadata{Name: "batched"}
+				return []model.Item{i}, nil
+			},
+		}
+		results := s.classifyCandidates(context.Background(), c, []classificationCandidate{
+			episode("/tv/a", "a1"), episode("/tv/a", "a2"), episode("/tv/a", "a3"),
+		})
+		if len(results) != 3 {
+			t.Fatalf("expected 3 results, got %v", len(results))
+		}
+		if results[0].item.ID != "a1" || results[0].item.Metadata.Name != "batched" || results[0].correlationID != "a1" {
+			t.Errorf("expected a1 from the batch, got %+v", results[0])
+		}
+		if results[0].by.Source != model.SourceClassifier {
+			t.Errorf("expected the classifier's provenance, got %+v", results[0].by)
+		}
+		if len(single) != 2 || single[0] != "a2" || single[1] != "a3" {
+			t.Errorf("expected a2 and a3 classified one by one, got %v", single)
+		}
+	})
+
+	t.Run("failed batch is classified one by one", func(t *testing.T) {
+		s := NewStore(WithStorePath(t.TempDir()))
+		var single int
+		c := &mockBatchClassifier{
+			mockClassifier: mockClassifier{ClassifyFunc: func(ctx context.Context, i model.Item) (model.Item, error) {
+				single++
+				return classified(i), nil
+			}},
+			BatchFunc: func(ctx context.Context, items []model.Item) ([]model.Item, error) {
+				return nil, errors.New("boom")
+			},
+		}
+		results := s.classifyCandidates(context.Background(), c, []classificationCandidate{
+			episode("/tv/a", "a1"), episode("/tv/a", "a2"),
+		})
+		if len(results) != 2 || single != 2 {
+			t.Fatalf("expected both classified one by one, got %v results, %v calls", len(results), single)
+		}
+		for _, r := range results {
+			if r.classifierErr != nil || r.item.Metadata == nil {
+				t.Errorf("unexpected result: %+v", r)
+			}
+		}
+	})
+}
+
+func Test_startClassificationStation_batches(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	s := NewStore(
+		WithStorePath(t.TempDir()),
+		WithClassificationWorkers(1),
+		WithClassificationRate(0),
+		WithClassificationStartupCooldown(0),
+	)
+	s.classifierErrors = make(chan error, 10)
+	var mu sync.Mutex
+	var batches [][]string
+	s.classifier = &mockBatchClassifier{
+		mockClassifier: mockClassifier{ClassifyFunc: func(ctx context.Context, i model.Item) (model.Item, error) {
+			mu.Lock()
+			batches = append(batches, []string{i.ID})
+			mu.Unlock()
+			i.Metadata = &model.MediaMetadata{Name: i.ID}
+			return i, nil
+		}},
+		BatchFunc: func(ctx context.Context, items []model.Item) ([]model.Item, error) {
+			var ids []string
+			for n := range items {
+				ids = append(ids, items[n].ID)
+				items[n].Metadata = &model.MediaMetadata{Name: items[n].ID}
+			}
+			mu.Lock()
+			batches = append(batches, ids)
+			mu.Unlock()
+			return items, nil
+		},
+	}
+	// Queued before the station starts, as if restored
+	for _, c := range []classificationCandidate{episode("/tv/a", "a1"), episode("/tv/b", "b1"), episode("/tv/a", "a2")} {
+		s.cache[c.item.ID] = c.item
+		s.queue.push(c, model.PriorityNew)
+	}
+	if err := s.StartClassificationStation(ctx); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	waitUntil(t, 2*time.Second, func() bool {
+		s.cacheMu.RLock()
+		defer s.cacheMu.RUnlock()
+		for _, id := range []string{"a1", "a2", "b1"} {
+			if s.cache[id].Metadata == nil {
+				return false
+			}
+		}
+		return true
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(batches) != 2 || len(batches[0]) != 2 || batches[0][0] != "a1" || batches[0][1] != "a2" || batches[1][0] != "b1" {
+		t.Fatalf("expected a1 and a2 in one batch, then b1, got %v", batches)
+	}
+	cancel()
+	s.Wait()
+}
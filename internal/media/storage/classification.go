@@ -27,12 +27,15 @@ type ClassificationStation interface {
 type classificationCandidate struct {
 	correlationID string
 	item          model.Item
+	priority      model.ClassificationPriority
 }
 
 type classificationResult struct {
 	correlationID string
 	classifierErr error
 	item          model.Item
+	// by made the metadata of item.
+	by model.Provenance
 }
 
 // randString for ID, deterministic length, not crypto-rand.
@@ -97,12 +100,19 @@ func (r *rateLimiter) peek() bool {
 	return r.tokens+earned > 0
 }
 
-// AddToClassificationQueue by pushing the item to the back of the queue.
+// AddToClassificationQueue by pushing the item onto the priority queue, behind
+// the items of the same priority. New items go before retries of failed ones,
+// see PrioritizeClassification to move an item further ahead.
 // Items may be dropped if: already in flight, startup cooldown is active,
-// rate limit is exceeded, or the downstream work queue is at capacity.
-// Items dropped by cooldown, rate limit, a full queue, or memory pressure are
-// marked for retry by the requeue loop, so "deferring" actually defers.
+// or rate limit is exceeded.
+// Items dropped by cooldown, rate limit, or memory pressure are marked for
+// retry by the requeue loop, so "deferring" actually defers.
 func (s *store) AddToClassificationQueue(i model.Item) {
+	s.enqueue(i, classificationPriority(i))
+}
+
+// enqueue the item with priority p, see AddToClassificationQueue.
+func (s *store) enqueue(i model.Item, p model.ClassificationPriority) {
 	// classificationRequest is unbuffered and only drained by the classification
 	// station, which Start spawns. Anything using the store WITHOUT starting it —
 	// the `kinoview media` CLI, for instance — would otherwise block here for
@@ -116,15 +126,13 @@ func (s *store) AddToClassificationQueue(i model.Item) {
 		ancli.Noticef("classification dedup: %v already in flight, skipping", i.Name)
 		return
 	}
-	if s.classificationStartupCooldown > 0 {
-		if time.Since(s.classificationStationStartTime) < s.classificationStartupCooldown {
-			ancli.Noticef("classification cooldown active (%v remaining), deferring: %v",
-				s.classificationStartupCooldown-time.Since(s.classificationStationStartTime),
-				i.Name)
-			s.inFlight.Delete(i.ID)
-			s.markPendingRequeue(i.ID)
-			return
-		}
+	if s.inCooldown() {
+		ancli.Noticef("classification cooldown active (%v remaining), deferring: %v",
+			s.classificationStartupCooldown-time.Since(s.classificationStationStartTime),
+			i.Name)
+		s.inFlight.Delete(i.ID)
+		s.markPendingRequeue(i.ID)
+		return
 	}
 	if s.rateLimiter != nil && !s.rateLimiter.allow() {
 		ancli.Warnf("classification rate limit reached, dropping: %v", i.Name)
@@ -135,6 +143,7 @@ func (s *store) AddToClassificationQueue(i model.Item) {
 	s.classificationRequest <- classificationCandidate{
 		correlationID: randString(10),
 		item:          i,
+		priority:      p,
 	}
 }
 
@@ -180,7 +189,7 @@ func (s *store) memoryHigh() bool {
 	return float64(m.Sys) > s.memoryThreshold*float64(total)
 }
 
-func (s *store) startClassificationRoutine(ctx context.Context, workerID int, workChan <-chan classificationCandidate, resChan chan<- classificationResult) {
+func (s *store) startClassificationRoutine(ctx context.Context, workerID int, workChan <-chan []classificationCandidate, resChan chan<- classificationResult) {
 	s.classifierMu.RLock()
 	workerClassifier := s.classifier.Clone()
 	s.classifierMu.RUnlock()
@@ -207,24 +216,22 @@ func (s *store) startClassificationRoutine(ctx context.Context, workerID int, wo
 		select {
 		case <-ctx.Done():
 			return
-		case c := <-workChan:
-			ancli.Noticef("[%v] - Worker %v, classifying: %v", c.correlationID, workerID, c.item.Name)
-			// Bound each call so a classifier stuck on a looping model (endless
-			// reasoning stream, the 2026-08-11 OOM root cause) cannot hold the
-			// worker forever. The timeout ctx derives from the station ctx, so
-			// shutdown still cancels promptly; a timeout surfaces as a
-			// classification error and counts as an attempt.
-			classifyCtx := ctx
-			cancel := func() {}
-			if s.classificationTimeout > 0 {
-				classifyCtx, cancel = context.WithTimeout(ctx, s.classificationTimeout)
+		case batch := <-workChan:
+			if len(batch) == 1 {
+				ancli.Noticef("[%v] - Worker %v, classifying: %v", batch[0].correlationID, workerID, batch[0].item.Name)
+			} else {
+				ancli.Noticef("[%v] - Worker %v, classifying %v items of: %v", batch[0].correlationID, workerID, len(batch), batchGroup(batch[0].item))
 			}
-			i, err := workerClassifier.Classify(classifyCtx, c.item)
-			cancel()
-			resChan <- classificationResult{
-				correlationID: c.correlationID,
-				classifierErr: err,
-				item:          i,
+			s.queue.assign(workerID, batch)
+			// Each call is bounded so a classifier stuck on a looping model
+			// (endless reasoning stream, the 2026-08-11 OOM root cause) cannot
+			// hold the worker forever. The timeout ctx derives from the
+			// station ctx, so shutdown still cancels promptly; a timeout
+			// surfaces as a classification error and counts as an attempt.
+			results := s.classifyCandidates(ctx, workerClassifier, batch)
+			s.queue.done(workerID)
+			for _, r := range results {
+				resChan <- r
 			}
 		}
 	}
@@ -257,24 +264,57 @@ func (s *store) StartClassificationStation(ctx context.Context) error {
 		ancli.Warnf("classification workers set to %v (>3); high worker counts increase memory pressure and may cause OOM", s.classificationWorkers)
 	}
 
-	queueCap := s.classificationWorkers * 2
-	resChan := make(chan classificationResult, queueCap)
-	workChan := make(chan classificationCandidate, queueCap)
+	// Restored before the workers start, so that what was queued when the
+	// server stopped goes first, in the order it was in.
+	s.restoreClassificationQueue()
+
+	// workChan is unbuffered: an item stays in the queue, where it can still
+	// be overtaken, until a worker is free to take it.
+	resChan := make(chan classificationResult, s.classificationWorkers)
+	workChan := make(chan []classificationCandidate)
+	batchSize := s.batchSize()
 	for i := range s.classificationWorkers {
 		s.wg.Go(func() {
 			s.startClassificationRoutine(ctx, i, workChan, resChan)
 		})
 	}
+	if s.tagBackfill {
+		s.wg.Go(func() {
+			s.backfillTags(ctx, tagBackfillInterval)
+		})
+	}
 	s.wg.Go(func() {
 		// Once the delegator stops there is no consumer, so enqueuing must go
 		// back to being a no-op rather than a deadlock.
 		defer s.started.Store(false)
-		ancli.Noticef("Starting classification delegator (queue cap: %v)", queueCap)
+		ancli.Noticef("Starting classification delegator (queued: %v)", s.queue.len())
+		// Wakes the delegator to dispatch restored items once the startup
+		// cooldown is over, nothing else would, and to save the queue.
+		tick := time.NewTicker(time.Second)
+		defer tick.Stop()
 		amToClassify := 0
 		for {
+			// A nil channel never sends, so nothing is dispatched while the
+			// queue is empty or the station is cooling down.
+			var dispatch chan<- []classificationCandidate
+			next := s.queue.peekBatch(batchSize)
+			if len(next) > 0 && !s.inCooldown() {
+				dispatch = workChan
+			}
 			select {
 			case <-ctx.Done():
+				// Callers need not Wait for the queue to be saved
+				s.queue.flush()
 				return
+			case <-s.queue.changed:
+			case <-tick.C:
+				s.queue.flush()
+			case dispatch <- next:
+				for _, c := range next {
+					s.queue.remove(c.item.ID)
+				}
+				amToClassify += len(next)
+				ancli.Noticef("[%v] Dispatched: %v (batch of %v), in progress: %v, queued: %v", next[0].correlationID, next[0].item.Name, len(next), amToClassify, s.queue.len())
 			case c := <-s.classificationRequest:
 				if s.memoryHigh() {
 					s.inFlight.Delete(c.item.ID)
@@ -282,24 +322,21 @@ func (s *store) StartClassificationStation(ctx context.Context) error {
 					s.markPendingRequeue(c.item.ID)
 					continue
 				}
-				select {
-				case workChan <- c:
-					amToClassify++
-					ancli.Noticef("[%v] New classification request: %v, total: %v", c.correlationID, c.item.Name, amToClassify)
-				default:
-					s.inFlight.Delete(c.item.ID)
-					ancli.Warnf("[%v] classification queue full, dropping: %v", c.correlationID, c.item.Name)
-					s.markPendingRequeue(c.item.ID)
-				}
+				s.queue.push(c, c.priority)
+				ancli.Noticef("[%v] New classification request: %v, queued: %v", c.correlationID, c.item.Name, s.queue.len())
 			case r := <-resChan:
 				amToClassify--
 				s.inFlight.Delete(r.item.ID)
+				s.queue.finish(r.item.ID)
 				ancli.Noticef("[%v] Work done, am in queue: %v", r.correlationID, amToClassify)
 				if r.classifierErr == nil {
 					r.item.ClassificationAttempts = 0
 					r.item.ClassificationLastTry = time.Time{}
 					r.item.ClassificationError = ""
-					s.store(r.item)
+					before := s.cachedMetadata(r.item.ID)
+					if err := s.store(r.item); err == nil {
+						s.recordMetadata(before, r.item, r.by, "")
+					}
 				} else {
 					r.item.ClassificationError = r.classifierErr.Error()
 					s.store(r.item)
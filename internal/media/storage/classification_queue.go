@@ -0,0 +1,415 @@
+package storage
+
+import (
+	"cmp"
+	"container/heap"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/baalimago/go_away_boilerplate/pkg/ancli"
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+// classificationQueueFile is where the queue is kept between restarts,
+// relative to the store directory. In a directory of its own, which the
+// store's load skips.
+const classificationQueueFile = ".queue/classification.json"
+
+// queueEntry is an item waiting for a classification worker, as persisted.
+type queueEntry struct {
+	ID       string                       `json:"id"`
+	Name     string                       `json:"name"`
+	Priority model.ClassificationPriority `json:"priority"`
+	// Seq orders entries of the same priority, first come first served.
+	Seq   uint64    `json:"seq"`
+	Since time.Time `json:"since"`
+
+	candidate classificationCandidate
+	// group of items which may be classified together, "" for none.
+	group string
+	index int
+}
+
+// queueHeap implements heap.Interface, the most urgent entry first.
+type queueHeap []*queueEntry
+
+func (h queueHeap) Len() int { return len(h) }
+
+func (h queueHeap) Less(i, j int) bool { return compareEntries(h[i], h[j]) < 0 }
+
+// compareEntries by priority, then by when they were queued.
+func compareEntries(a, b *queueEntry) int {
+	return cmp.Or(cmp.Compare(a.Priority, b.Priority), cmp.Compare(a.Seq, b.Seq))
+}
+
+func (h queueHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *queueHeap) Push(x any) {
+	e := x.(*queueEntry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *queueHeap) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return e
+}
+
+// classificationQueue holds the items waiting for a classification worker,
+// ordered by priority, and what the workers are busy with. It replaces the
+// bounded channel which used to drop items once full: nothing is dropped
+// for want of room, and the queue is persisted so that a restart doesn't
+// lose its place in line.
+//
+// The delegator is the only one popping; anyone may push or raise the
+// priority of an entry. changed wakes the delegator when the head may have
+// changed.
+type classificationQueue struct {
+	mu      sync.Mutex
+	entries queueHeap
+	byID    map[string]*queueEntry
+	seq     uint64
+	// dispatched are entries handed to a worker, persisted until the worker
+	// is done so that a restart mid-classification picks them up again.
+	dispatched map[string]*queueEntry
+	// persistPath is where the queue is saved when flushed, "" not to.
+	persistPath string
+	// dirty when the queue changed since it was last saved.
+	dirty bool
+
+	working map[int]model.ClassificationAssignment
+	// average duration of a classification, a moving average.
+	average time.Duration
+
+	changed chan struct{}
+}
+
+func newClassificationQueue(persistPath string) *classificationQueue {
+	return &classificationQueue{
+		byID:        map[string]*queueEntry{},
+		dispatched:  map[string]*queueEntry{},
+		persistPath: persistPath,
+		working:     map[int]model.ClassificationAssignment{},
+		changed:     make(chan struct{}, 1),
+	}
+}
+
+// classificationPriority of an item about to be queued: items which failed
+// before go after those which are yet to be tried. The attempt about to be
+// made is already counted.
+func classificationPriority(i model.Item) model.ClassificationPriority {
+	if i.ClassificationAttempts > 1 {
+		return model.PriorityRetry
+	}
+	return model.PriorityNew
+}
+
+// push c with the given priority. An item already queued keeps its place,
+// unless p is more urgent.
+func (q *classificationQueue) push(c classificationCandidate, p model.ClassificationPriority) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if e, ok := q.byID[c.item.ID]; ok {
+		e.candidate = c
+		q.raiseLocked(e, p)
+		return
+	}
+	q.seq++
+	e := &queueEntry{
+		ID:        c.item.ID,
+		Name:      c.item.Name,
+		Priority:  p,
+		Seq:       q.seq,
+		Since:     time.Now(),
+		candidate: c,
+		group:     batchGroup(c.item),
+	}
+	heap.Push(&q.entries, e)
+	q.byID[e.ID] = e
+	q.persistLocked()
+	q.notify()
+}
+
+// raise the priority of the queued item with the given ID to p, if p is
+// more urgent. Reports whether the item is queued.
+func (q *classificationQueue) raise(id string, p model.ClassificationPriority) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	e, ok := q.byID[id]
+	if ok {
+		q.raiseLocked(e, p)
+	}
+	return ok
+}
+
+func (q *classificationQueue) raiseLocked(e *queueEntry, p model.ClassificationPriority) {
+	if p >= e.Priority {
+		return
+	}
+	e.Priority = p
+	heap.Fix(&q.entries, e.index)
+	q.persistLocked()
+	q.notify()
+}
+
+// peekBatch returns the most urgent candidate, followed by up to max-1 others
+// of its group, most urgent first.
+func (q *classificationQueue) peekBatch(max int) []classificationCandidate {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.entries) == 0 {
+		return nil
+	}
+	head := q.entries[0]
+	batch := []classificationCandidate{head.candidate}
+	if head.group == "" || max <= 1 {
+		return batch
+	}
+	var mates []*queueEntry
+	for _, e := range q.entries[1:] {
+		if e.group == head.group {
+			mates = append(mates, e)
+		}
+	}
+	slices.SortFunc(mates, compareEntries)
+	for _, e := range mates[:min(len(mates), max-1)] {
+		batch = append(batch, e.candidate)
+	}
+	return batch
+}
+
+// remove the item with the given ID, once it has been handed to a worker.
+func (q *classificationQueue) remove(id string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	e, ok := q.byID[id]
+	if !ok {
+		return
+	}
+	heap.Remove(&q.entries, e.index)
+	delete(q.byID, id)
+	q.dispatched[id] = e
+	q.persistLocked()
+}
+
+// finish the item with the given ID, once its result is in. Called by the
+// delegator, after remove, the worker might be done before remove is called.
+func (q *classificationQueue) finish(id string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, ok := q.dispatched[id]; !ok {
+		return
+	}
+	delete(q.dispatched, id)
+	q.persistLocked()
+}
+
+func (q *classificationQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.entries)
+}
+
+// notify the delegator that the head of the queue may have changed.
+func (q *classificationQueue) notify() {
+	select {
+	case q.changed <- struct{}{}:
+	default:
+	}
+}
+
+// assign batch to worker, until done is called for it.
+func (q *classificationQueue) assign(worker int, batch []classificationCandidate) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	a := model.ClassificationAssignment{
+		Worker: worker,
+		ID:     batch[0].item.ID,
+		Name:   batch[0].item.Name,
+		Since:  time.Now(),
+	}
+	if len(batch) > 1 {
+		a.Batch = len(batch)
+	}
+	q.working[worker] = a
+}
+
+// done marks worker as free, timing the classification it was busy with. The
+// average is per item, a batch counting as many.
+func (q *classificationQueue) done(worker int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	a, ok := q.working[worker]
+	if !ok {
+		return
+	}
+	delete(q.working, worker)
+	took := time.Since(a.Since) / time.Duration(max(a.Batch, 1))
+	if q.average == 0 {
+		q.average = took
+	} else {
+		q.average = (q.average*4 + took) / 5
+	}
+}
+
+// snapshot of the queue, in the order the items will be classified. The ETA
+// assumes workers workers, each taking the average time per item.
+func (q *classificationQueue) snapshot(workers int) model.ClassificationQueue {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	entries := slices.Clone(q.entries)
+	slices.SortFunc(entries, compareEntries)
+	now := time.Now()
+	ret := model.ClassificationQueue{
+		Queued:         make([]model.QueuedClassification, 0, len(entries)),
+		Workers:        make([]model.ClassificationAssignment, 0, len(q.working)),
+		AverageSeconds: q.average.Seconds(),
+	}
+	workers = max(workers, 1)
+	for n, e := range entries {
+		qc := model.QueuedClassification{
+			ID:       e.ID,
+			Name:     e.Name,
+			Priority: e.Priority,
+			Position: n,
+			Since:    e.Since,
+		}
+		if q.average > 0 {
+			qc.ETA = now.Add(q.average * time.Duration(n/workers+1))
+		}
+		ret.Queued = append(ret.Queued, qc)
+	}
+	for _, a := range q.working {
+		ret.Workers = append(ret.Workers, a)
+	}
+	slices.SortFunc(ret.Workers, func(a, b model.ClassificationAssignment) int {
+		return a.Worker - b.Worker
+	})
+	return ret
+}
+
+// persistLocked marks the queue to be saved on the next flush, rewriting
+// it on every change would be quadratic in its length. Caller must hold q.mu.
+func (q *classificationQueue) persistLocked() {
+	q.dirty = q.persistPath != ""
+}
+
+// flush saves the queue if it changed since the last flush. Failing to is
+// logged and retried on the next flush, the queue in memory is still right.
+func (q *classificationQueue) flush() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.dirty {
+		return
+	}
+	if err := q.writeLocked(); err != nil {
+		ancli.Warnf("failed to persist the classification queue: %v", err)
+		return
+	}
+	q.dirty = false
+}
+
+func (q *classificationQueue) writeLocked() error {
+	all := slices.Concat(slices.Collect(maps.Values(q.dispatched)), q.entries)
+	b, err := json.Marshal(all)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(path.Dir(q.persistPath), 0o755); err != nil {
+		return err
+	}
+	tmp := q.persistPath + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, q.persistPath)
+}
+
+// readQueueEntries persisted at p, nil if there are none.
+func readQueueEntries(p string) ([]queueEntry, error) {
+	b, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []queueEntry
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return nil, fmt.Errorf("decode %v: %w", p, err)
+	}
+	return entries, nil
+}
+
+// restoreClassificationQueue puts back the items which were queued when the
+// server last stopped, in the order they were in. Items which have been
+// classified, or removed, since are left out.
+func (s *store) restoreClassificationQueue() {
+	entries, err := readQueueEntries(s.queue.persistPath)
+	if err != nil {
+		ancli.Warnf("failed to restore the classification queue: %v", err)
+		return
+	}
+	slices.SortFunc(entries, func(a, b queueEntry) int {
+		return cmp.Compare(a.Seq, b.Seq)
+	})
+	var restored int
+	for _, e := range entries {
+		s.cacheMu.RLock()
+		item, ok := s.cache[e.ID]
+		s.cacheMu.RUnlock()
+		if !ok || item.Metadata != nil || s.atMaxAttempts(item) {
+			continue
+		}
+		if _, loaded := s.inFlight.LoadOrStore(item.ID, struct{}{}); loaded {
+			continue
+		}
+		s.queue.push(classificationCandidate{correlationID: randString(10), item: item}, e.Priority)
+		restored++
+	}
+	if restored > 0 {
+		ancli.Noticef("restored %v item(s) to the classification queue", restored)
+	}
+}
+
+// PrioritizeClassification moves the item with the given ID ahead in the
+// classification queue, if p is more urgent than what it was queued with.
+// An item waiting to be queued, such as one deferred by the cooldown or the
+// rate limit, is queued with p now if the usual checks allow it. Reports
+// whether the item is queued.
+func (s *store) PrioritizeClassification(id string, p model.ClassificationPriority) bool {
+	if s.queue.raise(id, p) {
+		return true
+	}
+	// On its way to the queue, or being classified
+	if _, inFlight := s.inFlight.Load(id); inFlight {
+		return true
+	}
+	return s.requeue(id, p)
+}
+
+// ClassificationQueue is what waits for classification, and what the
+// workers are busy with.
+func (s *store) ClassificationQueue() model.ClassificationQueue {
+	return s.queue.snapshot(s.classificationWorkers)
+}
+
+// inCooldown reports whether the classification station is in its startup
+// cooldown.
+func (s *store) inCooldown() bool {
+	return s.classificationStartupCooldown > 0 && time.Since(s.classificationStationStartTime) < s.classificationStartupCooldown
+}
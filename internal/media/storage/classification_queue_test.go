@@ -0,0 +1,350 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+func queued(id string) classificationCandidate {
+	return classificationCandidate{correlationID: id, item: model.Item{ID: id, Name: id}}
+}
+
+func popAll(q *classificationQueue) []string {
+	var ids []string
+	for {
+		batch := q.peekBatch(1)
+		if len(batch) == 0 {
+			return ids
+		}
+		q.remove(batch[0].item.ID)
+		ids = append(ids, batch[0].item.ID)
+	}
+}
+
+func Test_classificationQueue_order(t *testing.T) {
+	q := newClassificationQueue("")
+	q.push(queued("retry"), model.PriorityRetry)
+	q.push(queued("new-1"), model.PriorityNew)
+	q.push(queued("new-2"), model.PriorityNew)
+	q.push(queued("browsed"), model.PriorityNew)
+	q.push(queued("suggested"), model.PrioritySuggested)
+
+	if !q.raise("browsed", model.PriorityBrowsed) {
+		t.Fatal("expected browsed to be queued")
+	}
+	// Lowering the priority is a no-op
+	q.raise("new-1", model.PriorityRetry)
+	if q.raise("missing", model.PriorityBrowsed) {
+		t.Fatal("expected raising an item which isn't queued to report false")
+	}
+
+	got := popAll(q)
+	want := []string{"browsed", "suggested", "new-1", "new-2", "retry"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for n := range want {
+		if got[n] != want[n] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func Test_classificationQueue_pushTwiceKeepsPlace(t *testing.T) {
+	q := newClassificationQueue("")
+	q.push(queued("a"), model.PriorityNew)
+	q.push(queued("b"), model.PriorityNew)
+	q.push(queued("a"), model.PriorityRetry)
+	if q.len() != 2 {
+		t.Fatalf("expected 2 queued, got %v", q.len())
+	}
+	if got := popAll(q); got[0] != "a" {
+		t.Fatalf("expected a to keep its place, got %v", got)
+	}
+}
+
+func Test_classificationQueue_snapshot(t *testing.T) {
+	q := newClassificationQueue("")
+	for _, id := range []string{"a", "b", "c"} {
+		q.push(queued(id), model.PriorityNew)
+	}
+	q.raise("c", model.PriorityBrowsed)
+
+	t.Run("no ETA before a classification is timed", func(t *testing.T) {
+		snap := q.snapshot(2)
+		if len(snap.Queued) != 3 || snap.Queued[0].ID != "c" || snap.Queued[0].Position != 0 {
+			t.Fatalf("unexpected queue: %+v", snap.Queued)
+		}
+		if !snap.Queued[0].ETA.IsZero() {
+			t.Fatalf("expected no ETA, got %v", snap.Queued[0].ETA)
+		}
+	})
+
+	t.Run("ETA by position and workers", func(t *testing.T) {
+		q.average = time.Minute
+		q.assign(0, []classificationCandidate{queued("x")})
+		snap := q.snapshot(2)
+		if len(snap.Workers) != 1 || snap.Workers[0].ID != "x" {
+			t.Fatalf("unexpected workers: %+v", snap.Workers)
+		}
+		if snap.AverageSeconds != 60 {
+			t.Fatalf("expected an average of 60s, got %v", snap.AverageSeconds)
+		}
+		// Two workers: positions 0 and 1 in one round, 2 in the next
+		first := time.Until(snap.Queued[0].ETA)
+		last := time.Until(snap.Queued[2].ETA)
+		if first > time.Minute || first < 59*time.Second {
+			t.Fatalf("expected the first ETA in a minute, got %v", first)
+		}
+		if last > 2*time.Minute || last < 119*time.Second {
+			t.Fatalf("expected the last ETA in two minutes, got %v", last)
+		}
+	})
+
+	t.Run("done frees the worker", func(t *testing.T) {
+		q.done(0)
+		if snap := q.snapshot(2); len(snap.Workers) != 0 {
+			t.Fatalf("expected no busy workers, got %+v", snap.Workers)
+		}
+	})
+}
+
+func Test_classificationQueue_persist(t *testing.T) {
+	p := path.Join(t.TempDir(), classificationQueueFile)
+	q := newClassificationQueue(p)
+	q.push(queued("a"), model.PriorityNew)
+	q.push(queued("b"), model.PriorityRetry)
+	q.push(queued("c"), model.PriorityNew)
+	// Handed to a worker but not done: kept, so a restart picks it up again
+	q.remove("a")
+	q.assign(0, []classificationCandidate{queued("a")})
+	q.remove("c")
+	q.assign(1, []classificationCandidate{queued("c")})
+	q.done(1)
+	q.finish("c")
+	if _, err := os.Stat(p); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected nothing written before a flush, got %v", err)
+	}
+	q.flush()
+
+	entries, err := readQueueEntries(p)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	ids := map[string]model.ClassificationPriority{}
+	for _, e := range entries {
+		ids[e.ID] = e.Priority
+	}
+	if len(ids) != 2 || ids["a"] != model.PriorityNew || ids["b"] != model.PriorityRetry {
+		t.Fatalf("unexpected persisted queue: %+v", entries)
+	}
+}
+
+func Test_store_restoreClassificationQueue(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	dir := t.TempDir()
+
+	prev := NewStore(WithStorePath(dir))
+	prev.queue.push(queued("first"), model.PriorityNew)
+	prev.queue.push(queued("browsed"), model.PriorityBrowsed)
+	prev.queue.push(queued("classified"), model.PriorityNew)
+	prev.queue.push(queued("removed"), model.PriorityNew)
+	prev.queue.flush()
+	if _, err := os.Stat(path.Join(dir, classificationQueueFile)); err != nil {
+		t.Fatalf("expected the queue to be persisted: %v", err)
+	}
+
+	s := NewStore(
+		WithStorePath(dir),
+		WithClassificationWorkers(1),
+		WithClassificationRate(0),
+		WithClassificationStartupCooldown(0),
+	)
+	s.classifierErrors = make(chan error, 10)
+	md := model.MediaMetadata{}
+	s.cache["first"] = model.Item{ID: "first", Name: "first"}
+	s.cache["browsed"] = model.Item{ID: "browsed", Name: "browsed"}
+	s.cache["classified"] = model.Item{ID: "classified", Name: "classified", Metadata: &md}
+
+	order := make(chan string, 10)
+	s.classifier = &mockClassifier{
+		SetupFunc: func(ctx context.Context) error { return nil },
+		ClassifyFunc: func(ctx context.Context, i model.Item) (model.Item, error) {
+			order <- i.ID
+			meta := model.MediaMetadata{}
+			i.Metadata = &meta
+			return i, nil
+		},
+	}
+	if err := s.StartClassificationStation(ctx); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+
+	for _, want := range []string{"browsed", "first"} {
+		select {
+		case got := <-order:
+			if got != want {
+				t.Fatalf("expected %v to be classified, got %v", want, got)
+			}
+		case <-ctx.Done():
+			t.Fatalf("timed out waiting for %v", want)
+		}
+	}
+	// Saved on the delegator's next tick
+	waitUntil(t, 3*time.Second, func() bool {
+		entries, err := readQueueEntries(path.Join(dir, classificationQueueFile))
+		return err == nil && len(entries) == 0
+	})
+	select {
+	case got := <-order:
+		t.Fatalf("unexpected classification of %v", got)
+	default:
+	}
+
+	cancel()
+	s.Wait()
+}
+
+func Test_store_VideoHandlerFunc_prioritizesClassification(t *testing.T) {
+	s := NewStore(WithStorePath(t.TempDir()))
+	s.cache["a"] = model.Item{ID: "a", Name: "a", MIMEType: "video/mp4", Path: "missing.mp4"}
+	s.cache["b"] = model.Item{ID: "b", Name: "b", MIMEType: "video/mp4", Path: "missing.mp4"}
+	s.queue.push(queued("a"), model.PriorityNew)
+	s.queue.push(queued("b"), model.PriorityNew)
+
+	req := httptest.NewRequest(http.MethodGet, "/video/b", nil)
+	req.SetPathValue("id", "b")
+	s.VideoHandlerFunc().ServeHTTP(httptest.NewRecorder(), req)
+
+	if got := popAll(s.queue); got[0] != "b" {
+		t.Fatalf("expected the browsed item first, got %v", got)
+	}
+}
+
+func Test_classificationPriority(t *testing.T) {
+	if got := classificationPriority(model.Item{ClassificationAttempts: 1}); got != model.PriorityNew {
+		t.Fatalf("expected a first attempt to be new, got %v", got)
+	}
+	if got := classificationPriority(model.Item{ClassificationAttempts: 2}); got != model.PriorityRetry {
+		t.Fatalf("expected a second attempt to be a retry, got %v", got)
+	}
+}
+
+func Test_store_PrioritizeClassification_queuesDeferred(t *testing.T) {
+	s := NewStore(
+		WithStorePath(t.TempDir()),
+		WithClassificationRate(0),
+		WithClassificationStartupCooldown(0),
+	)
+	s.started.Store(true)
+	s.classificationRequest = make(chan classificationCandidate, 1)
+	s.cache["a"] = model.Item{ID: "a", Name: "a", MIMEType: "video/mp4"}
+	s.markPendingRequeue("a")
+
+	if !s.PrioritizeClassification("a", model.PriorityBrowsed) {
+		t.Fatal("expected the deferred item to be queued")
+	}
+	c := <-s.classificationRequest
+	if c.item.ID != "a" || c.priority != model.PriorityBrowsed {
+		t.Fatalf("expected a queued as browsed, got %v as %v", c.item.ID, c.priority)
+	}
+	if len(s.pendingRequeueIDs()) != 0 {
+		t.Fatal("expected the item no longer pending")
+	}
+	if s.cache["a"].ClassificationAttempts != 1 {
+		t.Fatalf("expected the attempt counted, got %v", s.cache["a"].ClassificationAttempts)
+	}
+	// Being classified already, nothing more to do
+	if !s.PrioritizeClassification("a", model.PriorityBrowsed) || len(s.classificationRequest) != 0 {
+		t.Fatal("expected an item in flight to be left be")
+	}
+
+	t.Run("the usual checks apply", func(t *testing.T) {
+		s.cache["b"] = model.Item{ID: "b", Name: "b", MIMEType: "video/mp4", ClassificationAttempts: 1, ClassificationLastTry: time.Now()}
+		if s.PrioritizeClassification("b", model.PriorityBrowsed) {
+			t.Fatal("expected an item in backoff not to be queued")
+		}
+		if s.cache["b"].ClassificationAttempts != 1 {
+			t.Fatal("expected no attempt counted")
+		}
+	})
+}
+
+func Test_store_restoreClassificationQueue_afterCancel(t *testing.T) {
+	dir := t.TempDir()
+	newStore := func() *store {
+		s := NewStore(
+			WithStorePath(dir),
+			WithClassificationWorkers(1),
+			WithClassificationRate(0),
+			WithClassificationStartupCooldown(0),
+		)
+		s.classifierErrors = make(chan error, 10)
+		s.cache["a"] = model.Item{ID: "a", Name: "a"}
+		s.cache["b"] = model.Item{ID: "b", Name: "b"}
+		return s
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	prev := newStore()
+	t.Cleanup(prev.Wait)
+	busy := make(chan string, 2)
+	prev.classifier = &mockClassifier{
+		SetupFunc: func(ctx context.Context) error { return nil },
+		ClassifyFunc: func(ctx context.Context, i model.Item) (model.Item, error) {
+			busy <- i.ID
+			<-ctx.Done()
+			return i, ctx.Err()
+		},
+	}
+	if err := prev.StartClassificationStation(ctx); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	prev.AddToClassificationQueue(prev.cache["a"])
+	<-busy
+	prev.AddToClassificationQueue(prev.cache["b"])
+	waitUntil(t, time.Second, func() bool { return prev.queue.len() == 1 })
+	cancel()
+	// Saved by the delegator on its way out, before it stops
+	waitUntil(t, 3*time.Second, func() bool { return !prev.started.Load() })
+	entries, err := readQueueEntries(path.Join(dir, classificationQueueFile))
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("expected both items saved, got %+v, err: %v", entries, err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s := newStore()
+	classified := make(chan string, 2)
+	s.classifier = &mockClassifier{
+		SetupFunc: func(ctx context.Context) error { return nil },
+		ClassifyFunc: func(ctx context.Context, i model.Item) (model.Item, error) {
+			classified <- i.ID
+			i.Metadata = &model.MediaMetadata{}
+			return i, nil
+		},
+	}
+	if err := s.StartClassificationStation(ctx); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	got := map[string]bool{}
+	for len(got) < 2 {
+		select {
+		case id := <-classified:
+			got[id] = true
+		case <-ctx.Done():
+			t.Fatalf("timed out, classified only %v", got)
+		}
+	}
+	cancel()
+	s.Wait()
+}
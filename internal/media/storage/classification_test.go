@@ -2,7 +2,6 @@ package storage
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"strings"
 	"sync"
@@ -37,7 +36,7 @@ func Test_startClassificationStation_success(t *testing.T) {
 	s.classificationRequest = make(chan classificationCandidate, 100)
 	s.classifierErrors = make(chan error, 100)
 
-	wantMeta := json.RawMessage(`{"ok":true}`)
+	wantMeta := model.MediaMetadata{Name: "ok"}
 	s.classifier = &mockClassifier{
 		SetupFunc: func(ctx context.Context) error { return nil },
 		ClassifyFunc: func(ctx context.Context, i model.Item) (model.Item, error) {
@@ -46,6 +45,7 @@ func Test_startClassificationStation_success(t *testing.T) {
 		},
 	}
 
+	t.Cleanup(s.Wait)
 	if err := s.StartClassificationStation(ctx); err != nil {
 		t.Fatalf("start failed: %v", err)
 	}
@@ -78,7 +78,7 @@ func Test_startClassificationStation_success(t *testing.T) {
 		if v.Metadata == nil {
 			t.Fatalf("missing metadata on %s", v.ID)
 		}
-		if string(*v.Metadata) != string(wantMeta) {
+		if v.Metadata.Name != wantMeta.Name {
 			t.Fatalf("bad metadata for %s", v.ID)
 		}
 	}
@@ -101,12 +101,13 @@ func Test_startClassificationStation_error(t *testing.T) {
 			if len(i.Name) >= 3 && i.Name[:3] == "bad" {
 				return i, fmt.Errorf("boom on %s", i.ID)
 			}
-			meta := json.RawMessage(`{"ok":true}`)
+			meta := model.MediaMetadata{Name: "ok"}
 			i.Metadata = &meta
 			return i, nil
 		},
 	}
 
+	t.Cleanup(s.Wait)
 	if err := s.StartClassificationStation(ctx); err != nil {
 		t.Fatalf("start failed: %v", err)
 	}
@@ -220,12 +221,13 @@ func Test_startClassificationStation_concurrency(t *testing.T) {
 			}
 			time.Sleep(30 * time.Millisecond)
 			atomic.AddInt32(&active, -1)
-			meta := json.RawMessage(`{"ok":true}`)
+			meta := model.MediaMetadata{Name: "ok"}
 			i.Metadata = &meta
 			return i, nil
 		},
 	}
 
+	t.Cleanup(s.Wait)
 	if err := s.StartClassificationStation(ctx); err != nil {
 		t.Fatalf("start failed: %v", err)
 	}
@@ -273,7 +275,7 @@ func Test_startClassificationStation_context(t *testing.T) {
 		ClassifyFunc: func(c context.Context, i model.Item) (model.Item, error) {
 			gotCtx = c
 			ctxErrDuringCall = c.Err()
-			meta := json.RawMessage(`{"ok":true}`)
+			meta := model.MediaMetadata{Name: "ok"}
 			i.Metadata = &meta
 			return i, nil
 		},
@@ -334,12 +336,13 @@ func Test_startClassificationStation_cancel_shutdown(t *testing.T) {
 			case <-c.Done():
 			case <-block:
 			}
-			meta := json.RawMessage(`{"ok":true}`)
+			meta := model.MediaMetadata{Name: "ok"}
 			i.Metadata = &meta
 			return i, nil
 		},
 	}
 
+	t.Cleanup(s.Wait)
 	if err := s.StartClassificationStation(ctx); err != nil {
 		t.Fatalf("start failed: %v", err)
 	}
@@ -413,6 +416,7 @@ func Test_startClassificationStation_classifierTimeout(t *testing.T) {
 		},
 	}
 
+	t.Cleanup(s.Wait)
 	if err := s.StartClassificationStation(ctx); err != nil {
 		t.Fatalf("start failed: %v", err)
 	}
@@ -453,12 +457,13 @@ func Test_startClassificationStation_backpressure(t *testing.T) {
 		SetupFunc: func(ctx context.Context) error { return nil },
 		ClassifyFunc: func(ctx context.Context, i model.Item) (model.Item, error) {
 			time.Sleep(30 * time.Millisecond)
-			meta := json.RawMessage(`{"ok":true}`)
+			meta := model.MediaMetadata{Name: "ok"}
 			i.Metadata = &meta
 			return i, nil
 		},
 	}
 
+	t.Cleanup(s.Wait)
 	if err := s.StartClassificationStation(ctx); err != nil {
 		t.Fatalf("start failed: %v", err)
 	}
@@ -507,6 +512,7 @@ func Test_startClassificationStation_corr_id_in_error(t *testing.T) {
 		},
 	}
 
+	t.Cleanup(s.Wait)
 	if err := s.StartClassificationStation(ctx); err != nil {
 		t.Fatalf("start failed: %v", err)
 	}
@@ -551,12 +557,13 @@ func Test_startClassificationStation_large_volume(t *testing.T) {
 	s.classifier = &mockClassifier{
 		SetupFunc: func(ctx context.Context) error { return nil },
 		ClassifyFunc: func(ctx context.Context, i model.Item) (model.Item, error) {
-			meta := json.RawMessage(`{}`)
+			meta := model.MediaMetadata{}
 			i.Metadata = &meta
 			return i, nil
 		},
 	}
 
+	t.Cleanup(s.Wait)
 	if err := s.StartClassificationStation(ctx); err != nil {
 		t.Fatalf("start failed: %v", err)
 	}
@@ -731,12 +738,13 @@ func Test_AddToClassificationQueue_cooldown(t *testing.T) {
 	s.classifier = &mockClassifier{
 		SetupFunc: func(ctx context.Context) error { return nil },
 		ClassifyFunc: func(ctx context.Context, i model.Item) (model.Item, error) {
-			meta := json.RawMessage(`{}`)
+			meta := model.MediaMetadata{}
 			i.Metadata = &meta
 			return i, nil
 		},
 	}
 
+	t.Cleanup(s.Wait)
 	if err := s.StartClassificationStation(ctx); err != nil {
 		t.Fatalf("start failed: %v", err)
 	}
@@ -790,12 +798,13 @@ func Test_AddToClassificationQueue_rateLimit(t *testing.T) {
 		SetupFunc: func(ctx context.Context) error { return nil },
 		ClassifyFunc: func(ctx context.Context, i model.Item) (model.Item, error) {
 			stored.Add(1)
-			meta := json.RawMessage(`{}`)
+			meta := model.MediaMetadata{}
 			i.Metadata = &meta
 			return i, nil
 		},
 	}
 
+	t.Cleanup(s.Wait)
 	if err := s.StartClassificationStation(ctx); err != nil {
 		t.Fatalf("start failed: %v", err)
 	}
@@ -821,40 +830,33 @@ func Test_AddToClassificationQueue_rateLimit(t *testing.T) {
 	}
 }
 
-func Test_AddToClassificationQueue_queueCap(t *testing.T) {
+func Test_AddToClassificationQueue_noDropWhenBusy(t *testing.T) {
 	t.Parallel()
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	dir := t.TempDir()
-	// 1 worker → queue cap = 2
 	s := NewStore(
 		WithStorePath(dir),
 		WithClassificationWorkers(1),
 		WithClassificationRate(0),
 		WithClassificationStartupCooldown(0),
 	)
-	// Use unbuffered classificationRequest so sends block until delegator processes
-	s.classificationRequest = make(chan classificationCandidate)
 	s.classifierErrors = make(chan error, 100)
 
-	// Block the single worker so workChan fills up
+	// Block the single worker so everything else has to wait in the queue
 	workerBlock := make(chan struct{})
 	var processed atomic.Int32
 	s.classifier = &mockClassifier{
 		SetupFunc: func(ctx context.Context) error { return nil },
 		ClassifyFunc: func(ctx context.Context, i model.Item) (model.Item, error) {
-			// Unblock on close(workerBlock) or on ctx cancel, so a slow test
-			// (ctx expiring before the unblock) drains cleanly instead of
-			// leaving the worker stuck on a send to a gone delegator — which
-			// hangs the store's Wait() in teardown.
 			select {
 			case <-ctx.Done():
 				return model.Item{}, ctx.Err()
 			case <-workerBlock:
 			}
 			processed.Add(1)
-			meta := json.RawMessage(`{}`)
+			meta := model.MediaMetadata{}
 			i.Metadata = &meta
 			return i, nil
 		},
@@ -864,46 +866,31 @@ func Test_AddToClassificationQueue_queueCap(t *testing.T) {
 		t.Fatalf("start failed: %v", err)
 	}
 
-	// Send items from goroutine since classificationRequest is unbuffered
 	M := 10
-	go func() {
-		for i := range M {
-			it := model.Item{ID: fmt.Sprintf("qc-%d", i), Name: fmt.Sprintf("qc-%d", i), MIMEType: "video/mp4"}
-			s.AddToClassificationQueue(it)
-		}
-	}()
+	for i := range M {
+		s.AddToClassificationQueue(model.Item{ID: fmt.Sprintf("qc-%d", i), Name: fmt.Sprintf("qc-%d", i), MIMEType: "video/mp4"})
+	}
 
-	// Wait until the delegator has finished draining the queue. With the
-	// worker blocked and workChan (cap workers*2) full, every item beyond
-	// worker+cap is dropped and marked pending — so the drop count reaching
-	// M-(workers+cap) means the pipeline is quiescent. "All sends done" is
-	// not a barrier here: the unbuffered request sends complete via handoff
-	// before the delegator processes the item, so the last item can still be
-	// dispatched after the senders have finished.
+	// One with the worker, the rest queued, none dropped
 	waitUntil(t, 2*time.Second, func() bool {
-		s.pendingRequeueMu.Lock()
-		defer s.pendingRequeueMu.Unlock()
-		return len(s.pendingRequeue) == M-3
+		return s.queue.len() == M-1
+	})
+	s.pendingRequeueMu.Lock()
+	pending := len(s.pendingRequeue)
+	s.pendingRequeueMu.Unlock()
+	if pending != 0 {
+		t.Fatalf("expected nothing pending a requeue, got %v", pending)
+	}
+	waitUntil(t, 2*time.Second, func() bool {
+		w := s.ClassificationQueue().Workers
+		return len(w) == 1 && w[0].ID == "qc-0"
 	})
 
-	// Unblock worker
 	close(workerBlock)
-
-	// All items in workChan (cap=2) should be processed
 	waitUntil(t, 2*time.Second, func() bool {
-		return processed.Load() >= 1
+		return int(processed.Load()) == M
 	})
 
-	// At most 3 items processed: 1 in-progress + 2 in buffer (cap = workers*2 = 2)
-	// When worker picks up an item and blocks, workChan slot frees, delegator refills.
-	final := int(processed.Load())
-	if final > 3 {
-		t.Fatalf("expected at most 3 items (workers + cap), got %d", final)
-	}
-	if final == 0 {
-		t.Fatal("expected at least 1 item processed")
-	}
-
 	// Stop the station and drain its goroutines before the temp-dir cleanup
 	// runs: the delegator may still be writing a result to disk, and a write
 	// racing the RemoveAll fails the test in the cleanup phase.
@@ -930,12 +917,13 @@ func Test_AddToClassificationQueue_nilRateLimiter(t *testing.T) {
 	s.classifier = &mockClassifier{
 		SetupFunc: func(ctx context.Context) error { return nil },
 		ClassifyFunc: func(ctx context.Context, i model.Item) (model.Item, error) {
-			meta := json.RawMessage(`{}`)
+			meta := model.MediaMetadata{}
 			i.Metadata = &meta
 			return i, nil
 		},
 	}
 
+	t.Cleanup(s.Wait)
 	if err := s.StartClassificationStation(ctx); err != nil {
 		t.Fatalf("start failed: %v", err)
 	}
@@ -975,12 +963,13 @@ func Test_AddToClassificationQueue_dedup_sameItem(t *testing.T) {
 		ClassifyFunc: func(ctx context.Context, i model.Item) (model.Item, error) {
 			stored.Add(1)
 			time.Sleep(20 * time.Millisecond) // give the second queuer time to try
-			meta := json.RawMessage(`{}`)
+			meta := model.MediaMetadata{}
 			i.Metadata = &meta
 			return i, nil
 		},
 	}
 
+	t.Cleanup(s.Wait)
 	if err := s.StartClassificationStation(ctx); err != nil {
 		t.Fatalf("start failed: %v", err)
 	}
@@ -1020,12 +1009,13 @@ func Test_AddToClassificationQueue_dedup_cleanupOnSuccess(t *testing.T) {
 		SetupFunc: func(ctx context.Context) error { return nil },
 		ClassifyFunc: func(ctx context.Context, i model.Item) (model.Item, error) {
 			stored.Add(1)
-			meta := json.RawMessage(`{}`)
+			meta := model.MediaMetadata{}
 			i.Metadata = &meta
 			return i, nil
 		},
 	}
 
+	t.Cleanup(s.Wait)
 	if err := s.StartClassificationStation(ctx); err != nil {
 		t.Fatalf("start failed: %v", err)
 	}
@@ -1079,6 +1069,7 @@ func Test_AddToClassificationQueue_dedup_cleanupOnError(t *testing.T) {
 		},
 	}
 
+	t.Cleanup(s.Wait)
 	if err := s.StartClassificationStation(ctx); err != nil {
 		t.Fatalf("start failed: %v", err)
 	}
@@ -1135,7 +1126,7 @@ func Test_AddToClassificationQueue_dedup_concurrent(t *testing.T) {
 		ClassifyFunc: func(ctx context.Context, i model.Item) (model.Item, error) {
 			stored.Add(1)
 			<-blockClassify
-			meta := json.RawMessage(`{}`)
+			meta := model.MediaMetadata{}
 			i.Metadata = &meta
 			return i, nil
 		},
@@ -1201,12 +1192,13 @@ func Test_AddToClassificationQueue_dedup_differentItems(t *testing.T) {
 		SetupFunc: func(ctx context.Context) error { return nil },
 		ClassifyFunc: func(ctx context.Context, i model.Item) (model.Item, error) {
 			stored.Add(1)
-			meta := json.RawMessage(`{}`)
+			meta := model.MediaMetadata{}
 			i.Metadata = &meta
 			return i, nil
 		},
 	}
 
+	t.Cleanup(s.Wait)
 	if err := s.StartClassificationStation(ctx); err != nil {
 		t.Fatalf("start failed: %v", err)
 	}
@@ -1252,12 +1244,13 @@ func Test_AddToClassificationQueue_dedup_cooldownCleanup(t *testing.T) {
 		SetupFunc: func(ctx context.Context) error { return nil },
 		ClassifyFunc: func(ctx context.Context, i model.Item) (model.Item, error) {
 			stored.Add(1)
-			meta := json.RawMessage(`{}`)
+			meta := model.MediaMetadata{}
 			i.Metadata = &meta
 			return i, nil
 		},
 	}
 
+	t.Cleanup(s.Wait)
 	if err := s.StartClassificationStation(ctx); err != nil {
 		t.Fatalf("start failed: %v", err)
 	}
@@ -1303,12 +1296,13 @@ func Test_AddToClassificationQueue_dedup_limitCleanup(t *testing.T) {
 		SetupFunc: func(ctx context.Context) error { return nil },
 		ClassifyFunc: func(ctx context.Context, i model.Item) (model.Item, error) {
 			stored.Add(1)
-			meta := json.RawMessage(`{}`)
+			meta := model.MediaMetadata{}
 			i.Metadata = &meta
 			return i, nil
 		},
 	}
 
+	t.Cleanup(s.Wait)
 	if err := s.StartClassificationStation(ctx); err != nil {
 		t.Fatalf("start failed: %v", err)
 	}
@@ -1399,12 +1393,13 @@ func Test_StartClassificationStation_cooldownDefault(t *testing.T) {
 	s.classifier = &mockClassifier{
 		SetupFunc: func(ctx context.Context) error { return nil },
 		ClassifyFunc: func(ctx context.Context, i model.Item) (model.Item, error) {
-			meta := json.RawMessage(`{}`)
+			meta := model.MediaMetadata{}
 			i.Metadata = &meta
 			return i, nil
 		},
 	}
 
+	t.Cleanup(s.Wait)
 	if err := s.StartClassificationStation(ctx); err != nil {
 		t.Fatalf("start failed: %v", err)
 	}
@@ -1454,12 +1449,13 @@ func Test_StartClassificationStation_negativeCooldown(t *testing.T) {
 	s.classifier = &mockClassifier{
 		SetupFunc: func(ctx context.Context) error { return nil },
 		ClassifyFunc: func(ctx context.Context, i model.Item) (model.Item, error) {
-			meta := json.RawMessage(`{}`)
+			meta := model.MediaMetadata{}
 			i.Metadata = &meta
 			return i, nil
 		},
 	}
 
+	t.Cleanup(s.Wait)
 	if err := s.StartClassificationStation(ctx); err != nil {
 		t.Fatalf("start failed: %v", err)
 	}
@@ -1508,7 +1504,7 @@ func Test_startClassificationStation_workersUseClones(t *testing.T) {
 					activityMu.Lock()
 					workerActivity[id]++
 					activityMu.Unlock()
-					meta := json.RawMessage(`{}`)
+					meta := model.MediaMetadata{}
 					i.Metadata = &meta
 					return i, nil
 				},
@@ -1516,6 +1512,7 @@ func Test_startClassificationStation_workersUseClones(t *testing.T) {
 		},
 	}
 
+	t.Cleanup(s.Wait)
 	if err := s.StartClassificationStation(ctx); err != nil {
 		t.Fatalf("start failed: %v", err)
 	}
@@ -1632,12 +1629,13 @@ func Test_startClassificationStation_memoryGuard(t *testing.T) {
 		SetupFunc: func(ctx context.Context) error { return nil },
 		ClassifyFunc: func(ctx context.Context, i model.Item) (model.Item, error) {
 			atomic.AddInt32(&classified, 1)
-			meta := json.RawMessage(`{"ok":true}`)
+			meta := model.MediaMetadata{Name: "ok"}
 			i.Metadata = &meta
 			return i, nil
 		},
 	}
 
+	t.Cleanup(s.Wait)
 	if err := s.StartClassificationStation(ctx); err != nil {
 		t.Fatalf("start failed: %v", err)
 	}
@@ -1833,12 +1831,13 @@ func Test_handleVideoItem_backoffExpired(t *testing.T) {
 	s.classifier = &mockClassifier{
 		SetupFunc: func(ctx context.Context) error { return nil },
 		ClassifyFunc: func(ctx context.Context, i model.Item) (model.Item, error) {
-			meta := json.RawMessage(`{}`)
+			meta := model.MediaMetadata{}
 			i.Metadata = &meta
 			return i, nil
 		},
 	}
 
+	t.Cleanup(s.Wait)
 	if err := s.StartClassificationStation(ctx); err != nil {
 		t.Fatalf("start failed: %v", err)
 	}
@@ -1941,12 +1940,13 @@ func Test_startClassificationStation_successClearsAttempts(t *testing.T) {
 	s.classifier = &mockClassifier{
 		SetupFunc: func(ctx context.Context) error { return nil },
 		ClassifyFunc: func(ctx context.Context, i model.Item) (model.Item, error) {
-			meta := json.RawMessage(`{"ok":true}`)
+			meta := model.MediaMetadata{Name: "ok"}
 			i.Metadata = &meta
 			return i, nil
 		},
 	}
 
+	t.Cleanup(s.Wait)
 	if err := s.StartClassificationStation(ctx); err != nil {
 		t.Fatalf("start failed: %v", err)
 	}
@@ -2005,6 +2005,7 @@ func Test_startClassificationStation_errorPersistsAttempts(t *testing.T) {
 		},
 	}
 
+	t.Cleanup(s.Wait)
 	if err := s.StartClassificationStation(ctx); err != nil {
 		t.Fatalf("start failed: %v", err)
 	}
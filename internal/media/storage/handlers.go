@@ -1,27 +1,37 @@
 package storage
 
 import (
+	"bytes"
+	"cmp"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"image"
+	"mime"
 	"net/http"
 	"os"
+	"path"
 	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/baalimago/go_away_boilerplate/pkg/ancli"
+	"github.com/baalimago/kinoview/internal/media/audiotags"
+	"github.com/baalimago/kinoview/internal/media/exif"
+	"github.com/baalimago/kinoview/internal/media/mediatype"
+	"github.com/baalimago/kinoview/internal/media/stream"
 	"github.com/baalimago/kinoview/internal/media/thumbnail"
 	"github.com/baalimago/kinoview/internal/model"
 )
 
 // handleImageItem by:
-// 1. Checking if thumbnail exists
-// 2. Adding thumbnail if it does
-// 3. Creating thumbnail if it doesnt
+// 1. Reading the EXIF metadata, unless it's been read before
+// 2. Using the cached thumbnails if they exist
+// 3. Generating thumbnails if they dont, rotated by the EXIF orientation
 //
-// Exceptions: If the image is a thumbnail itself, then
-// set the thumbnail to itself and return
+// Exceptions: If the image is a thumbnail itself, one older versions wrote
+// next to the media, then set the thumbnail to itself and return
 func (s *store) handleImageItem(i *model.Item) error {
 	if thumbnail.IsThumbnail(i.Path) {
 		img, err := thumbnail.LoadImage(i.Path)
@@ -32,27 +42,106 @@ func (s *store) handleImageItem(i *model.Item) error {
 		return nil
 	}
 
-	thumbPath := thumbnail.GetThumbnailPath(i.Path)
-	if _, err := os.Stat(thumbPath); err == nil {
-		img, thumbErr := thumbnail.LoadImage(thumbPath)
-		if thumbErr != nil {
-			return fmt.Errorf("load existing thumb: %w", thumbErr)
+	if i.Photo == nil {
+		meta, err := exif.Read(i.Path)
+		if err == nil {
+			i.Photo = &meta
+		} else if !errors.Is(err, exif.ErrNoExif) {
+			ancli.Warnf("failed to read exif of: '%v', err: %v", i.Name, err)
 		}
-		i.Thumbnail = img
+	}
+
+	if s.thumbs.Has(i.ID) {
+		i.Thumbnail = s.thumbs.Image(i.ID)
 		return nil
-	} else if !os.IsNotExist(err) {
-		return fmt.Errorf("stat thumb: %w", err)
 	}
 
-	img, err := thumbnail.CreateThumbnail(*i)
+	src, err := thumbnail.Source(*i)
 	if err != nil {
 		return fmt.Errorf("create thumb: %w", err)
 	}
+	img, err := s.thumbs.Generate(i.ID, src)
+	if err != nil {
+		return fmt.Errorf("create thumb: %w", err)
+	}
+	ancli.Okf("thumbnails for: '%v' created in: '%v'", i.Name, path.Dir(img.Path))
 	i.Thumbnail = img
 	return nil
 }
 
+// handleAudioItem by:
+// 1. Reading the tags, unless they've been read before
+// 2. Using the cached thumbnails if they exist
+// 3. Generating thumbnails from album art next to the track, if there is one
+// 4. Generating thumbnails from the embedded cover art if there isn't
+func (s *store) handleAudioItem(i *model.Item) error {
+	var pic *audiotags.Picture
+	if i.Audio == nil {
+		tags, p, err := audiotags.Read(i.Path)
+		if err != nil && !errors.Is(err, audiotags.ErrNoTags) {
+			return fmt.Errorf("read tags: %w", err)
+		}
+		if !tags.IsEmpty() {
+			i.Audio = &tags
+		}
+		pic = p
+	}
+
+	if s.thumbs.Has(i.ID) {
+		i.Thumbnail = s.thumbs.Image(i.ID)
+		return nil
+	}
+	src, err := coverArt(i.Path, pic)
+	if err != nil {
+		return err
+	}
+	if src == nil {
+		return nil
+	}
+	img, err := s.thumbs.Generate(i.ID, src)
+	if err != nil {
+		return fmt.Errorf("cover thumb: %w", err)
+	}
+	i.Thumbnail = img
+	return nil
+}
+
+// coverArt of the track at p, either from an image next to it or embedded.
+// The embedded picture is read from the file unless pic is set. Returns nil if
+// there is none.
+func coverArt(p string, pic *audiotags.Picture) (image.Image, error) {
+	if cover := thumbnail.FindCoverArt(p); cover != "" {
+		img, err := thumbnail.LoadImage(cover)
+		if err != nil {
+			return nil, fmt.Errorf("load cover: %w", err)
+		}
+		return img.Raw, nil
+	}
+	if pic == nil {
+		_, pic, _ = audiotags.Read(p)
+	}
+	if pic == nil {
+		return nil, nil
+	}
+	img, _, err := image.Decode(bytes.NewReader(pic.Data))
+	if err != nil {
+		return nil, fmt.Errorf("decode embedded cover: %w", err)
+	}
+	return img, nil
+}
+
+// needsMusicClassification for audio items which lack the tags needed to
+// place them in the music library.
+func needsMusicClassification(i model.Item) bool {
+	return i.Audio == nil || i.Audio.Title == "" || (i.Audio.Artist == "" && i.Audio.AlbumArtist == "")
+}
+
 func (s *store) handleVideoItem(i *model.Item) error {
+	// Checked before the attempt budget, a sidecar added since the last
+	// attempt settles it all the same
+	if s.preclassified(i) {
+		return nil
+	}
 	if s.atMaxAttempts(*i) {
 		ancli.Warnf("classification permanently skipped for %v: max attempts (%v) reached", i.Name, s.classificationMaxAttempts)
 		return nil
@@ -103,15 +192,46 @@ func handlePaginatedRequest(
 	}
 	mime := r.URL.Query().Get("mime")
 	search := r.URL.Query().Get("search")
+	takenFrom, err := parseDateParam(r.URL.Query().Get("takenFrom"), false)
+	if err != nil {
+		return model.PaginatedRequest{}, fmt.Errorf("invalid takenFrom: %w", err)
+	}
+	takenTo, err := parseDateParam(r.URL.Query().Get("takenTo"), true)
+	if err != nil {
+		return model.PaginatedRequest{}, fmt.Errorf("invalid takenTo: %w", err)
+	}
 	retAm := min(start+am, totalAm)
 	return model.PaginatedRequest{
-		Start:    start,
-		Am:       retAm,
-		MIMEType: mime,
-		Search:   search,
+		Start:     start,
+		Am:        retAm,
+		MIMEType:  mime,
+		Search:    search,
+		TakenFrom: takenFrom,
+		TakenTo:   takenTo,
+		Tags: model.TagFilter{
+			Genres:          model.ParseTagList(r.URL.Query().Get("genre")),
+			Moods:           model.ParseTagList(r.URL.Query().Get("mood")),
+			AgeRatings:      model.ParseTagList(r.URL.Query().Get("ageRating")),
+			WithoutWarnings: model.ParseTagList(r.URL.Query().Get("withoutWarnings")),
+		},
 	}, nil
 }
 
+// parseDateParam parses either a date, 2006-01-02, or an RFC3339 timestamp.
+// Dates used as an upper bound include the whole day.
+func parseDateParam(s string, upper bool) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		if upper {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
 // ListHandlerFunc returns a list of all available items in the gallery
 func (s *store) ListHandlerFunc() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -134,6 +254,15 @@ func (s *store) ListHandlerFunc() http.HandlerFunc {
 			if paginatedRequest.Search != "" && !model.MatchesGlobalSearch(v, paginatedRequest.Search) {
 				continue
 			}
+			if !model.TakenWithin(v, paginatedRequest.TakenFrom, paginatedRequest.TakenTo) {
+				continue
+			}
+			if !paginatedRequest.Tags.Matches(v) {
+				continue
+			}
+			if !s.parental.Allowed(r, v) {
+				continue
+			}
 			keys = append(keys, key)
 		}
 		slices.Sort(keys)
@@ -177,6 +306,13 @@ func (s *store) VideoHandlerFunc() http.HandlerFunc {
 			http.Error(w, "media found, but its not a video", http.StatusNotFound)
 			return
 		}
+		if s.refused(w, r, item) {
+			return
+		}
+		// Someone is watching, classify it next if it's waiting for it
+		if item.Metadata == nil {
+			s.PrioritizeClassification(id, model.PriorityBrowsed)
+		}
 
 		w.Header().Set("Content-Type", mimeType)
 		file, err := os.Open(pathToMedia)
@@ -192,10 +328,11 @@ func (s *store) VideoHandlerFunc() http.HandlerFunc {
 			return
 		}
 
-		// For MKV files: always transcode on seek (?t= present), but only
-		// transcode on initial load for non-SmartTV (raw MKV may play natively
-		// on SmartTV but seeking requires the transcode path).
-		if strings.HasSuffix(strings.ToLower(item.Name), ".mkv") {
+		// For containers browsers can't play (MKV, AVI, TS...): always transcode
+		// on seek (?t= present), but only transcode on initial load for
+		// non-SmartTV (raw MKV may play natively on SmartTV but seeking
+		// requires the transcode path).
+		if mediatype.NeedsTranscode(mimeType) {
 			if !strings.Contains(r.UserAgent(), "SmartTV") || r.URL.Query().Get("t") != "" {
 				streamMkvToMp4(w, r, pathToMedia)
 				return
@@ -207,6 +344,46 @@ func (s *store) VideoHandlerFunc() http.HandlerFunc {
 	}
 }
 
+// AudioHandlerFunc returns a handler to get audio by ID, if the item is not
+// audio it will return 404. Range requests are supported, so clients can seek.
+func (s *store) AudioHandlerFunc() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		if id == "" {
+			http.Error(w, "missing id", http.StatusBadRequest)
+			return
+		}
+		s.cacheMu.RLock()
+		item, ok := s.cache[id]
+		s.cacheMu.RUnlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		if !strings.HasPrefix(item.MIMEType, "audio") {
+			http.Error(w, "media found, but its not audio", http.StatusNotFound)
+			return
+		}
+
+		file, err := os.Open(item.Path)
+		if err != nil {
+			http.Error(w, "media not found", http.StatusNotFound)
+			return
+		}
+		defer file.Close()
+
+		info, err := file.Stat()
+		if err != nil {
+			http.Error(w, "media not found", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", item.MIMEType)
+		http.ServeContent(w, r, item.Name, info.ModTime(), file)
+	}
+}
+
 // StreamHandlerFunc by stripping out the substitle streams using ffmpeg from video media found at
 // PathValue id. If there are multiple subtitle streams found, select one at random
 func (s *store) StreamListHandlerFunc() http.HandlerFunc {
@@ -227,6 +404,9 @@ func (s *store) StreamListHandlerFunc() http.HandlerFunc {
 			http.Error(w, "media found, but its not a video", http.StatusNotFound)
 			return
 		}
+		if s.refused(w, r, item) {
+			return
+		}
 
 		info, err := s.subtitleManager.Find(item)
 		if err != nil {
@@ -240,6 +420,20 @@ func (s *store) StreamListHandlerFunc() http.HandlerFunc {
 	}
 }
 
+// refused answers 403 if the device of r may not see the item, reporting
+// whether it did.
+func (s *store) refused(w http.ResponseWriter, r *http.Request, item model.Item) bool {
+	if s.parental.Allowed(r, item) {
+		return false
+	}
+	http.Error(w, "rated above the limit of this device", http.StatusForbidden)
+	return true
+}
+
+// maxSubtitleOffset bounds the manual subtitle offset, anything more is a
+// typo or the wrong subtitles altogether.
+const maxSubtitleOffset = 10 * time.Minute
+
 func (s *store) StreamHandlerFunc() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		vid := r.PathValue("vid")
@@ -264,17 +458,199 @@ func (s *store) StreamHandlerFunc() http.HandlerFunc {
 			http.Error(w, fmt.Sprintf("cache miss for: '%v'", vid), http.StatusNotFound)
 			return
 		}
+		if s.refused(w, r, cacheFile) {
+			return
+		}
+
+		// Styled subtitles are passed through as ASS for a client side
+		// renderer, everything else is converted to WebVTT.
+		query := r.URL.Query()
+		format := cmp.Or(query.Get("format"), model.FormatVTT)
+		var extract func(model.Item, string) (string, error)
+		contentType := "text/vtt; charset=utf-8"
+		shift := stream.ShiftVTT
+		switch mode := query.Get("sync"); {
+		case format != model.FormatVTT && format != model.FormatASS:
+			http.Error(w, fmt.Sprintf("unsupported format: '%v'", format), http.StatusBadRequest)
+			return
+		case mode != "" && mode != "auto":
+			http.Error(w, fmt.Sprintf("unsupported sync mode: '%v'", mode), http.StatusBadRequest)
+			return
+		case format == model.FormatASS && mode != "":
+			http.Error(w, "sync is only supported for vtt", http.StatusBadRequest)
+			return
+		case format == model.FormatASS:
+			ancli.Okf("attempting to extract ass subs for: %v, idx: %v", cacheFile.Name, streamIdx)
+			extract = s.subtitleManager.ExtractASS
+			contentType = "text/x-ssa; charset=utf-8"
+			shift = stream.ShiftASS
+		case mode == "auto":
+			ancli.Okf("attempting to sync subs for: %v, idx: %v", cacheFile.Name, streamIdx)
+			extract = s.subtitleManager.SyncSubtitles
+		default:
+			ancli.Okf("attempting to extract subs for: %v, idx: %v", cacheFile.Name, streamIdx)
+			extract = s.subtitleManager.ExtractSubtitles
+		}
+
+		// An offset in the query is the user nudging the timing, keep it
+		// for the next session. Without one, use the kept one.
+		offset := cacheFile.SubtitleOffset(streamIdx)
+		if q := query.Get("offset"); q != "" {
+			ms, err := strconv.ParseInt(q, 10, 64)
+			if err != nil || ms < -maxSubtitleOffset.Milliseconds() || ms > maxSubtitleOffset.Milliseconds() {
+				http.Error(w, fmt.Sprintf("offset must be milliseconds within ±%v", maxSubtitleOffset), http.StatusBadRequest)
+				return
+			}
+			offset = time.Duration(ms) * time.Millisecond
+			if err := s.SetSubtitleOffset(vid, streamIdx, offset); err != nil {
+				ancli.Errf("failed to persist subtitle offset: %v", err)
+			}
+		}
 
-		ancli.Okf("attempting to extract subs for: %v, idx: %v", cacheFile.Name, streamIdx)
-		streamData, err := s.subtitleManager.ExtractSubtitles(cacheFile, streamIdx)
+		streamData, err := extract(cacheFile, streamIdx)
+		if errors.Is(err, stream.ErrUnsupportedFormat) {
+			http.Error(w, fmt.Sprintf("stream can't be served as %v", format), http.StatusBadRequest)
+			return
+		}
 		if err != nil {
 			ancli.Errf("failed to extract stream: %v", err)
 			http.Error(w, "failed to extract stream", http.StatusInternalServerError)
 			return
 		}
 		ancli.Okf("serving file: %v", streamData)
+		w.Header().Set("Content-Type", contentType)
+		// The offset may change while the file doesn't, so don't let the
+		// client reuse a response without asking.
+		w.Header().Set("Cache-Control", "no-cache")
+		if offset == 0 {
+			http.ServeFile(w, r, streamData)
+			return
+		}
+		data, err := os.ReadFile(streamData)
+		if err != nil {
+			ancli.Errf("failed to read stream: %v", err)
+			http.Error(w, "failed to read stream", http.StatusInternalServerError)
+			return
+		}
+		http.ServeContent(w, r, path.Base(streamData), time.Time{}, bytes.NewReader(shift(data, offset)))
+	}
+}
+
+// DualStreamHandlerFunc serves the subtitle streams at PathValues
+// primary_idx and secondary_idx of the video at PathValue vid merged into one
+// WebVTT, see stream.MergeVTT. The kept offset of each is applied, and with
+// "sync=auto" both are synced to the audio first.
+func (s *store) DualStreamHandlerFunc() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vid := r.PathValue("vid")
+		primaryIdx, secondaryIdx := r.PathValue("primary_idx"), r.PathValue("secondary_idx")
+		if vid == "" || primaryIdx == "" || secondaryIdx == "" {
+			http.Error(w, "missing video id or stream indices", http.StatusBadRequest)
+			return
+		}
+		if primaryIdx == secondaryIdx {
+			http.Error(w, "primary and secondary must be different streams", http.StatusBadRequest)
+			return
+		}
+		s.cacheMu.RLock()
+		item, ok := s.cache[vid]
+		s.cacheMu.RUnlock()
+		if !ok {
+			http.Error(w, fmt.Sprintf("cache miss for: '%v'", vid), http.StatusNotFound)
+			return
+		}
+		if s.refused(w, r, item) {
+			return
+		}
+
+		extract := s.subtitleManager.ExtractSubtitles
+		switch mode := r.URL.Query().Get("sync"); mode {
+		case "":
+		case "auto":
+			extract = s.subtitleManager.SyncSubtitles
+		default:
+			http.Error(w, fmt.Sprintf("unsupported sync mode: '%v'", mode), http.StatusBadRequest)
+			return
+		}
+
+		var tracks [2][]byte
+		for i, idx := range []string{primaryIdx, secondaryIdx} {
+			p, err := extract(item, idx)
+			if errors.Is(err, stream.ErrUnsupportedFormat) {
+				http.Error(w, fmt.Sprintf("stream %v can't be served as vtt", idx), http.StatusBadRequest)
+				return
+			}
+			if err != nil {
+				ancli.Errf("failed to extract stream %v of %v: %v", idx, item.Name, err)
+				http.Error(w, "failed to extract stream", http.StatusInternalServerError)
+				return
+			}
+			data, err := os.ReadFile(p)
+			if err != nil {
+				ancli.Errf("failed to read stream: %v", err)
+				http.Error(w, "failed to read stream", http.StatusInternalServerError)
+				return
+			}
+			if offset := item.SubtitleOffset(idx); offset != 0 {
+				data = stream.ShiftVTT(data, offset)
+			}
+			tracks[i] = data
+		}
+
 		w.Header().Set("Content-Type", "text/vtt; charset=utf-8")
-		http.ServeFile(w, r, streamData)
+		w.Header().Set("Cache-Control", "no-cache")
+		name := fmt.Sprintf("%s_%s_%s.vtt", vid, primaryIdx, secondaryIdx)
+		http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(stream.MergeVTT(tracks[0], tracks[1])))
+	}
+}
+
+// attachmentTypes are the content types of the usual attachments of
+// Matroska files, which the mime package doesn't know of.
+var attachmentTypes = map[string]string{
+	".ttf":   "font/ttf",
+	".otf":   "font/otf",
+	".ttc":   "font/collection",
+	".woff":  "font/woff",
+	".woff2": "font/woff2",
+}
+
+// AttachmentHandlerFunc serves the attachment stream at PathValue stream_idx
+// of the video at PathValue vid, such as the fonts of its ASS subtitles.
+func (s *store) AttachmentHandlerFunc() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vid := r.PathValue("vid")
+		streamIdx := r.PathValue("stream_idx")
+		if vid == "" || streamIdx == "" {
+			http.Error(w, "missing video id or stream index", http.StatusBadRequest)
+			return
+		}
+		s.cacheMu.RLock()
+		item, ok := s.cache[vid]
+		s.cacheMu.RUnlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if s.refused(w, r, item) {
+			return
+		}
+
+		p, err := s.subtitleManager.ExtractAttachment(item, streamIdx)
+		if errors.Is(err, stream.ErrUnsupportedFormat) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			ancli.Errf("failed to extract attachment: %v", err)
+			http.Error(w, "failed to extract attachment", http.StatusInternalServerError)
+			return
+		}
+		ext := strings.ToLower(path.Ext(p))
+		contentType := cmp.Or(attachmentTypes[ext], mime.TypeByExtension(ext), "application/octet-stream")
+		w.Header().Set("Content-Type", contentType)
+		// Attachments never change, unlike the file they're in might.
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+		http.ServeFile(w, r, p)
 	}
 }
 
@@ -318,3 +694,56 @@ func (s *store) ImageHandlerFunc() http.HandlerFunc {
 		http.ServeContent(w, r, item.Name, modTime, file)
 	}
 }
+
+// ThumbnailHandlerFunc returns a handler to get the thumbnail of an item by ID.
+// The size is selected with the "size" query parameter: grid (default), card
+// or hero. WebP or AVIF is served to clients which accept it.
+func (s *store) ThumbnailHandlerFunc() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		if id == "" {
+			http.Error(w, "missing id", http.StatusBadRequest)
+			return
+		}
+		sizeName := r.URL.Query().Get("size")
+		if sizeName == "" {
+			sizeName = thumbnail.SizeGrid.Name
+		}
+		size, ok := thumbnail.SizeByName(sizeName)
+		if !ok {
+			http.Error(w, fmt.Sprintf("invalid size: '%v'", sizeName), http.StatusBadRequest)
+			return
+		}
+		s.cacheMu.RLock()
+		item, ok := s.cache[id]
+		s.cacheMu.RUnlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if s.refused(w, r, item) {
+			return
+		}
+
+		p, contentType, err := s.thumbs.Negotiate(r.Context(), id, size, r.Header.Get("Accept"))
+		if err != nil {
+			http.Error(w, "thumbnail not found", http.StatusNotFound)
+			return
+		}
+		file, err := os.Open(p)
+		if err != nil {
+			http.Error(w, "thumbnail not found", http.StatusNotFound)
+			return
+		}
+		defer file.Close()
+		info, err := file.Stat()
+		if err != nil {
+			http.Error(w, "thumbnail not found", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Add("Vary", "Accept")
+		http.ServeContent(w, r, path.Base(p), info.ModTime(), file)
+	}
+}
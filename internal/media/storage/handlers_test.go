@@ -12,8 +12,10 @@ import (
 	"path"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/baalimago/go_away_boilerplate/pkg/testboil"
+	"github.com/baalimago/kinoview/internal/media/parental"
 	"github.com/baalimago/kinoview/internal/media/thumbnail"
 	"github.com/baalimago/kinoview/internal/model"
 )
@@ -23,7 +25,7 @@ func Test_store_ListHandlerFunc_search(t *testing.T) {
 	s := newTestStore(t)
 	h := s.ListHandlerFunc()
 
-	md := json.RawMessage(`{"title":"The Matrix","director":"Wachowski"}`)
+	md := model.MediaMetadata{Name: "The Matrix", Extra: map[string]json.RawMessage{"director": json.RawMessage(`"Wachowski"`)}}
 	s.cacheMu.Lock()
 	s.cache = map[string]model.Item{
 		"1": {ID: "1", Name: "matrix.mp4", Path: "/movies/matrix.mp4", MIMEType: "video/mp4", Metadata: &md},
@@ -158,7 +160,7 @@ func TestStoreSearchE2E(t *testing.T) {
 		t.Fatalf("Setup failed: %v", err)
 	}
 
-	mdJSON := json.RawMessage(`{"director":"Christopher Nolan","year":2010}`)
+	mdJSON := model.MediaMetadata{Year: 2010, Extra: map[string]json.RawMessage{"director": json.RawMessage(`"Christopher Nolan"`)}}
 	s.cacheMu.Lock()
 	s.cache = map[string]model.Item{
 		"inception": {ID: "inception", Name: "Inception (2010).mp4", Path: "/films/scifi/inception.mp4", MIMEType: "video/mp4", Metadata: &mdJSON},
@@ -561,6 +563,210 @@ func Test_store_StreamHandlerFunc(t *testing.T) {
 			t.Errorf("expected 500 on extractor fail, got %d", rr.Result().StatusCode)
 		}
 	})
+
+	t.Run("sync=auto serves the synced subtitles", func(t *testing.T) {
+		synced := path.Join(t.TempDir(), "1_0.synced.vtt")
+		if err := os.WriteFile(synced, []byte("WEBVTT\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		s := NewStore(WithStorePath(t.TempDir()))
+		s.subtitleManager = &mockSubtitleManager{syncedPath: synced}
+		s.cache = map[string]model.Item{"1": {ID: "1", Path: "dummy"}}
+
+		req := httptest.NewRequest(http.MethodGet, "/subs/1/0?sync=auto", nil)
+		req.SetPathValue("vid", "1")
+		req.SetPathValue("stream_idx", "0")
+		rr := httptest.NewRecorder()
+		s.StreamHandlerFunc().ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", rr.Code)
+		}
+		if rr.Body.String() != "WEBVTT\n" {
+			t.Errorf("expected the synced file, got %q", rr.Body.String())
+		}
+	})
+
+	t.Run("offset is applied and kept for the next request", func(t *testing.T) {
+		extracted := path.Join(t.TempDir(), "1_2.vtt")
+		if err := os.WriteFile(extracted, []byte("WEBVTT\n\n00:00:10.000 --> 00:00:12.000\nHi\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		s := NewStore(WithStorePath(t.TempDir()))
+		s.subtitleManager = &mockSubtitleManager{extractedPath: extracted}
+		s.cache = map[string]model.Item{"1": {ID: "1", Name: "vid", Path: "dummy"}}
+
+		get := func(url string) string {
+			t.Helper()
+			req := httptest.NewRequest(http.MethodGet, url, nil)
+			req.SetPathValue("vid", "1")
+			req.SetPathValue("stream_idx", "2")
+			rr := httptest.NewRecorder()
+			s.StreamHandlerFunc().ServeHTTP(rr, req)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("expected 200 for %v, got %d", url, rr.Code)
+			}
+			return rr.Body.String()
+		}
+
+		want := "WEBVTT\n\n00:00:08.750 --> 00:00:10.750\nHi\n"
+		testboil.FailTestIfDiff(t, get("/subs/1/2?offset=-1250"), want)
+		testboil.FailTestIfDiff(t, s.cache["1"].SubtitleOffsets["2"], int64(-1250))
+		testboil.FailTestIfDiff(t, get("/subs/1/2"), want)
+
+		// Zero clears it.
+		get("/subs/1/2?offset=0")
+		testboil.FailTestIfDiff(t, len(s.cache["1"].SubtitleOffsets), 0)
+	})
+
+	t.Run("malformed offset is a bad request", func(t *testing.T) {
+		s := NewStore(WithStorePath(t.TempDir()))
+		s.subtitleManager = &mockSubtitleManager{}
+		s.cache = map[string]model.Item{"1": {ID: "1", Path: "dummy"}}
+		for _, q := range []string{"soon", "1.5", "3600000"} {
+			req := httptest.NewRequest(http.MethodGet, "/subs/1/0?offset="+q, nil)
+			req.SetPathValue("vid", "1")
+			req.SetPathValue("stream_idx", "0")
+			rr := httptest.NewRecorder()
+			s.StreamHandlerFunc().ServeHTTP(rr, req)
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("expected 400 for offset=%v, got %d", q, rr.Code)
+			}
+		}
+	})
+
+	t.Run("unknown sync mode is a bad request", func(t *testing.T) {
+		s := NewStore(WithStorePath(t.TempDir()))
+		s.subtitleManager = &mockSubtitleManager{}
+		s.cache = map[string]model.Item{"1": {ID: "1", Path: "dummy"}}
+
+		req := httptest.NewRequest(http.MethodGet, "/subs/1/0?sync=manual", nil)
+		req.SetPathValue("vid", "1")
+		req.SetPathValue("stream_idx", "0")
+		rr := httptest.NewRecorder()
+		s.StreamHandlerFunc().ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expected 400, got %d", rr.Code)
+		}
+	})
+
+	t.Run("format=ass serves the styled subtitles, shifted", func(t *testing.T) {
+		ass := path.Join(t.TempDir(), "1_3.ass")
+		data := "[Events]\nFormat: Layer, Start, End, Style, Text\nDialogue: 0,0:00:10.00,0:00:12.50,Default,{\\i1}Hi{\\i0}\n"
+		if err := os.WriteFile(ass, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		s := NewStore(WithStorePath(t.TempDir()))
+		s.subtitleManager = &mockSubtitleManager{assPath: ass}
+		s.cache = map[string]model.Item{"1": {ID: "1", Path: "dummy"}}
+
+		req := httptest.NewRequest(http.MethodGet, "/subs/1/3?format=ass&offset=1500", nil)
+		req.SetPathValue("vid", "1")
+		req.SetPathValue("stream_idx", "3")
+		rr := httptest.NewRecorder()
+		s.StreamHandlerFunc().ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", rr.Code)
+		}
+		testboil.FailTestIfDiff(t, rr.Header().Get("Content-Type"), "text/x-ssa; charset=utf-8")
+		want := "[Events]\nFormat: Layer, Start, End, Style, Text\nDialogue: 0,0:00:11.50,0:00:14.00,Default,{\\i1}Hi{\\i0}\n"
+		testboil.FailTestIfDiff(t, rr.Body.String(), want)
+	})
+
+	t.Run("unservable format is a bad request", func(t *testing.T) {
+		s := NewStore(WithStorePath(t.TempDir()))
+		s.subtitleManager = &mockSubtitleManager{}
+		s.cache = map[string]model.Item{"1": {ID: "1", Path: "dummy"}}
+		for _, q := range []string{"format=srt", "format=ass&sync=auto"} {
+			req := httptest.NewRequest(http.MethodGet, "/subs/1/0?"+q, nil)
+			req.SetPathValue("vid", "1")
+			req.SetPathValue("stream_idx", "0")
+			rr := httptest.NewRecorder()
+			s.StreamHandlerFunc().ServeHTTP(rr, req)
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("expected 400 for %v, got %d", q, rr.Code)
+			}
+		}
+	})
+}
+
+func Test_store_DualStreamHandlerFunc(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	ja, en := path.Join(dir, "1_2.vtt"), path.Join(dir, "1_3.vtt")
+	os.WriteFile(ja, []byte("WEBVTT\n\n00:00:01.000 --> 00:00:03.000\nこんにちは\n"), 0o644)
+	os.WriteFile(en, []byte("WEBVTT\n\n00:00:01.000 --> 00:00:02.000\nHello\n"), 0o644)
+	s := NewStore(WithStorePath(t.TempDir()))
+	s.subtitleManager = &mockSubtitleManager{extractedPaths: map[string]string{"2": ja, "3": en}}
+	s.cache = map[string]model.Item{"1": {ID: "1", Path: "dummy", SubtitleOffsets: map[string]int64{"3": 1000}}}
+
+	serve := func(vid, primary, secondary, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/streams/"+vid+"/dual/"+primary+"/"+secondary+query, nil)
+		req.SetPathValue("vid", vid)
+		req.SetPathValue("primary_idx", primary)
+		req.SetPathValue("secondary_idx", secondary)
+		rr := httptest.NewRecorder()
+		s.DualStreamHandlerFunc().ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := serve("1", "2", "3", "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	testboil.FailTestIfDiff(t, rr.Header().Get("Content-Type"), "text/vtt; charset=utf-8")
+	// The english subtitles are kept a second later
+	for _, want := range []string{
+		"00:00:01.000 --> 00:00:02.000\n<c.primary>こんにちは</c>\n\n",
+		"00:00:02.000 --> 00:00:03.000\n<c.primary>こんにちは</c>\n<c.secondary>Hello</c>\n",
+	} {
+		if !strings.Contains(rr.Body.String(), want) {
+			t.Errorf("expected %q in:\n%s", want, rr.Body.String())
+		}
+	}
+
+	for _, tc := range []struct {
+		vid, primary, secondary, query string
+		want                           int
+	}{
+		{"2", "2", "3", "", http.StatusNotFound},
+		{"1", "2", "2", "", http.StatusBadRequest},
+		{"1", "2", "3", "?sync=manual", http.StatusBadRequest},
+	} {
+		if rr := serve(tc.vid, tc.primary, tc.secondary, tc.query); rr.Code != tc.want {
+			t.Errorf("%+v: expected %d, got %d", tc, tc.want, rr.Code)
+		}
+	}
+}
+
+func Test_store_AttachmentHandlerFunc(t *testing.T) {
+	t.Parallel()
+	font := path.Join(t.TempDir(), "1_5_Font.TTF")
+	if err := os.WriteFile(font, []byte("font"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := NewStore(WithStorePath(t.TempDir()))
+	s.subtitleManager = &mockSubtitleManager{attachmentPath: font}
+	s.cache = map[string]model.Item{"1": {ID: "1", Path: "dummy"}}
+
+	serve := func(vid string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/streams/"+vid+"/attachment/5", nil)
+		req.SetPathValue("vid", vid)
+		req.SetPathValue("stream_idx", "5")
+		rr := httptest.NewRecorder()
+		s.AttachmentHandlerFunc().ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := serve("1")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	testboil.FailTestIfDiff(t, rr.Header().Get("Content-Type"), "font/ttf")
+	testboil.FailTestIfDiff(t, rr.Body.String(), "font")
+
+	if rr := serve("2"); rr.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown video, got %d", rr.Code)
+	}
 }
 
 func Test_store_StreamListHandlerFunc(t *testing.T) {
@@ -623,17 +829,23 @@ func writePNG(t *testing.T, p string, w, h int) {
 
 func Test_store_handleImageItem(t *testing.T) {
 	t.Parallel()
-	t.Run("uses existing thumb", func(t *testing.T) {
+	t.Run("uses cached thumbnails", func(t *testing.T) {
 		s := newTestStore(t)
 		dir := t.TempDir()
 
 		src := path.Join(dir, "a.png")
 		writePNG(t, src, 10, 10)
-
-		thumb := thumbnail.GetThumbnailPath(src)
-		writePNG(t, thumb, 5, 5)
+		if _, err := s.thumbs.Generate("a", image.NewRGBA(image.Rect(0, 0, 5, 5))); err != nil {
+			t.Fatal(err)
+		}
+		cached := s.thumbs.Path("a", thumbnail.SizeGrid, thumbnail.FormatJPEG)
+		before, err := os.Stat(cached)
+		if err != nil {
+			t.Fatal(err)
+		}
 
 		i := model.Item{
+			ID:       "a",
 			Name:     "a.png",
 			Path:     src,
 			MIMEType: "image/png",
@@ -643,15 +855,19 @@ func Test_store_handleImageItem(t *testing.T) {
 			t.Fatalf("handleImageItem: %v", err)
 		}
 
-		if i.Thumbnail.Path != thumb {
-			t.Fatalf("thumb path = %q, want %q", i.Thumbnail.Path, thumb)
+		if i.Thumbnail.Path != cached {
+			t.Fatalf("thumb path = %q, want %q", i.Thumbnail.Path, cached)
+		}
+		after, err := os.Stat(cached)
+		if err != nil {
+			t.Fatal(err)
 		}
-		if i.Thumbnail.Width == 0 || i.Thumbnail.Height == 0 {
-			t.Fatalf("thumb dims not set")
+		if !after.ModTime().Equal(before.ModTime()) {
+			t.Fatal("expected cached thumbnail to be reused")
 		}
 	})
 
-	t.Run("creates thumbnail when missing", func(t *testing.T) {
+	t.Run("creates thumbnails in the cache when missing", func(t *testing.T) {
 		s := newTestStore(t)
 		dir := t.TempDir()
 
@@ -659,6 +875,7 @@ func Test_store_handleImageItem(t *testing.T) {
 		writePNG(t, src, 16, 9)
 
 		i := model.Item{
+			ID:       "b",
 			Name:     "b.png",
 			Path:     src,
 			MIMEType: "image/png",
@@ -668,12 +885,13 @@ func Test_store_handleImageItem(t *testing.T) {
 			t.Fatalf("handleImageItem: %v", err)
 		}
 
-		want := thumbnail.GetThumbnailPath(src)
+		want := s.thumbs.Path("b", thumbnail.SizeGrid, thumbnail.FormatJPEG)
 		if i.Thumbnail.Path != want {
 			t.Fatalf("thumb path = %q, want %q", i.Thumbnail.Path, want)
 		}
-		if _, err := os.Stat(want); err != nil {
-			t.Fatalf("thumb not created: %v", err)
+		testboil.FailTestIfDiff(t, i.Thumbnail.ID, "b")
+		if !s.thumbs.Has("b") {
+			t.Fatal("thumbnails not created")
 		}
 		if i.Thumbnail.Width != thumbnail.ThumbnailWidth {
 			t.Fatalf("width = %d", i.Thumbnail.Width)
@@ -681,6 +899,9 @@ func Test_store_handleImageItem(t *testing.T) {
 		if i.Thumbnail.Height != thumbnail.ThumbnailHeight {
 			t.Fatalf("height = %d", i.Thumbnail.Height)
 		}
+		if _, err := os.Stat(thumbnail.GetThumbnailPath(src)); !os.IsNotExist(err) {
+			t.Fatalf("expected nothing written next to the media, stat err: %v", err)
+		}
 	})
 
 	t.Run("errors on thumbnail input", func(t *testing.T) {
@@ -691,13 +912,35 @@ func Test_store_handleImageItem(t *testing.T) {
 		// Do not create any files to force LoadImage fail
 
 		i := &model.Item{
+			ID:       "c",
 			Name:     "c_thumb.png",
 			Path:     thumbLike,
 			MIMEType: "image/png",
 		}
 
-		if err := s.handleImageItem(i); err == nil {
-			t.Fatal("expected error when input is a thumbnail")
+		err := s.handleImageItem(i)
+		if err == nil || !strings.Contains(err.Error(), "load existing thumb") {
+			t.Fatalf("expected the thumbnail itself to fail to load, got %v", err)
+		}
+	})
+
+	t.Run("thumbnail input is its own thumbnail", func(t *testing.T) {
+		s := newTestStore(t)
+		src := path.Join(t.TempDir(), "c_thumb.png")
+		writePNG(t, src, 20, 10)
+
+		i := &model.Item{
+			ID:       "c",
+			Name:     "c_thumb.png",
+			Path:     src,
+			MIMEType: "image/png",
+		}
+		if err := s.handleImageItem(i); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		testboil.FailTestIfDiff(t, i.Thumbnail.Path, src)
+		if s.thumbs.Has("c") {
+			t.Fatal("expected no thumbnails of a thumbnail")
 		}
 	})
 
@@ -876,3 +1119,379 @@ func Test_handlePaginatedRequest_ok(t *testing.T) {
 		}
 	})
 }
+
+func Test_store_AudioHandlerFunc(t *testing.T) {
+	t.Parallel()
+	s := newTestStore(t)
+	handler := s.AudioHandlerFunc()
+
+	dir := t.TempDir()
+	song := path.Join(dir, "song.mp3")
+	if err := os.WriteFile(song, []byte("0123456789"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s.cache = map[string]model.Item{
+		"song":  {Name: "song.mp3", Path: song, MIMEType: "audio/mpeg"},
+		"video": {Name: "song.mp4", Path: song, MIMEType: "video/mp4"},
+	}
+
+	t.Run("serves range requests", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/audio/song", nil)
+		req.SetPathValue("id", "song")
+		req.Header.Set("Range", "bytes=2-5")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusPartialContent {
+			t.Fatalf("want 206, got %d", rr.Code)
+		}
+		testboil.FailTestIfDiff(t, rr.Body.String(), "2345")
+		testboil.FailTestIfDiff(t, rr.Header().Get("Content-Type"), "audio/mpeg")
+	})
+
+	t.Run("404 if item is not audio", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/audio/video", nil)
+		req.SetPathValue("id", "video")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusNotFound {
+			t.Fatalf("want 404, got %d", rr.Code)
+		}
+	})
+
+	t.Run("404 on unknown id", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/audio/nope", nil)
+		req.SetPathValue("id", "nope")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusNotFound {
+			t.Fatalf("want 404, got %d", rr.Code)
+		}
+	})
+}
+
+// id3v23 builds a minimal ID3v2.3 tag with latin-1 text frames.
+func id3v23(frames map[string]string) []byte {
+	var body []byte
+	for id, v := range frames {
+		data := append([]byte{0}, v...)
+		body = append(body, id...)
+		body = append(body, byte(len(data)>>24), byte(len(data)>>16), byte(len(data)>>8), byte(len(data)), 0, 0)
+		body = append(body, data...)
+	}
+	n := len(body)
+	header := []byte{'I', 'D', '3', 3, 0, 0, byte(n >> 21 & 0x7f), byte(n >> 14 & 0x7f), byte(n >> 7 & 0x7f), byte(n & 0x7f)}
+	return append(header, body...)
+}
+
+func Test_store_handleAudioItem(t *testing.T) {
+	t.Parallel()
+	t.Run("reads tags and uses album art next to the track", func(t *testing.T) {
+		s := newTestStore(t)
+		dir := t.TempDir()
+		song := path.Join(dir, "01 - song.mp3")
+		if err := os.WriteFile(song, id3v23(map[string]string{"TIT2": "Song", "TPE1": "Band"}), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		cover := path.Join(dir, "cover.png")
+		writePNG(t, cover, 20, 20)
+
+		i := model.Item{ID: "song", Name: "01 - song.mp3", Path: song, MIMEType: "audio/mpeg"}
+		if err := s.handleAudioItem(&i); err != nil {
+			t.Fatalf("handleAudioItem: %v", err)
+		}
+		if i.Audio == nil {
+			t.Fatal("expected tags to be read")
+		}
+		testboil.FailTestIfDiff(t, i.Audio.Title, "Song")
+		testboil.FailTestIfDiff(t, i.Audio.Artist, "Band")
+		testboil.FailTestIfDiff(t, i.Thumbnail.Path, s.thumbs.Path("song", thumbnail.SizeGrid, thumbnail.FormatJPEG))
+		if needsMusicClassification(i) {
+			t.Fatal("tagged track should not need classification")
+		}
+	})
+
+	t.Run("untagged track without art", func(t *testing.T) {
+		s := newTestStore(t)
+		song := path.Join(t.TempDir(), "song.mp3")
+		if err := os.WriteFile(song, make([]byte, 256), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		i := model.Item{ID: "song", Name: "song.mp3", Path: song, MIMEType: "audio/mpeg"}
+		if err := s.handleAudioItem(&i); err != nil {
+			t.Fatalf("handleAudioItem: %v", err)
+		}
+		if i.Audio != nil {
+			t.Fatalf("expected no tags, got: %+v", i.Audio)
+		}
+		if i.Thumbnail.Path != "" {
+			t.Fatalf("expected no thumbnail, got: %v", i.Thumbnail.Path)
+		}
+		if !needsMusicClassification(i) {
+			t.Fatal("untagged track should need classification")
+		}
+	})
+}
+
+func Test_store_ListHandlerFunc_takenFilter(t *testing.T) {
+	t.Parallel()
+	s := newTestStore(t)
+	h := s.ListHandlerFunc()
+
+	taken := func(y int, m time.Month, d int) *model.PhotoMetadata {
+		return &model.PhotoMetadata{TakenAt: time.Date(y, m, d, 12, 0, 0, 0, time.UTC)}
+	}
+	s.cacheMu.Lock()
+	s.cache = map[string]model.Item{
+		"1": {ID: "1", Name: "old", MIMEType: "image/jpeg", Photo: taken(2019, 12, 31)},
+		"2": {ID: "2", Name: "new year", MIMEType: "image/jpeg", Photo: taken(2020, 1, 1)},
+		"3": {ID: "3", Name: "new years eve", MIMEType: "image/jpeg", Photo: taken(2020, 12, 31)},
+		"4": {ID: "4", Name: "undated", MIMEType: "image/jpeg"},
+	}
+	s.cacheMu.Unlock()
+
+	tests := []struct {
+		query   string
+		wantIDs []string
+	}{
+		{"", []string{"1", "2", "3", "4"}},
+		{"&takenFrom=2020-01-01", []string{"2", "3"}},
+		{"&takenTo=2020-12-30", []string{"1", "2"}},
+		{"&takenFrom=2020-01-01&takenTo=2020-12-31", []string{"2", "3"}},
+		{"&takenFrom=2020-01-01T13:00:00Z", []string{"3"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.query, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/list?start=0&am=10"+tc.query, nil)
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("want 200, got %d: %v", rr.Code, rr.Body.String())
+			}
+			var got model.PaginatedResponse[model.Item]
+			if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			ids := make([]string, 0, len(got.Items))
+			for _, it := range got.Items {
+				ids = append(ids, it.ID)
+			}
+			testboil.FailTestIfDiff(t, strings.Join(ids, ","), strings.Join(tc.wantIDs, ","))
+		})
+	}
+
+	t.Run("invalid date", func(t *testing.T) {
+		_, err := handlePaginatedRequest(4, httptest.NewRequest(http.MethodGet, "/list?start=0&am=1&takenFrom=yesterday", nil))
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
+func Test_store_ListHandlerFunc_tagFilter(t *testing.T) {
+	t.Parallel()
+	s := newTestStore(t)
+	h := s.ListHandlerFunc()
+
+	s.cacheMu.Lock()
+	s.cache = map[string]model.Item{
+		"1": {ID: "1", Name: "heat", MIMEType: "video/mp4", Metadata: &model.MediaMetadata{Genre: "Crime, Thriller", Mood: []string{"tense"}, AgeRating: "R", ContentWarnings: []string{"violence"}}},
+		"2": {ID: "2", Name: "paddington", MIMEType: "video/mp4", Metadata: &model.MediaMetadata{Genre: "Comedy, Family", Mood: []string{"uplifting"}, AgeRating: "PG"}},
+		"3": {ID: "3", Name: "unclassified", MIMEType: "video/mp4"},
+	}
+	s.cacheMu.Unlock()
+
+	tests := []struct {
+		query   string
+		wantIDs []string
+	}{
+		{"", []string{"1", "2", "3"}},
+		{"&genre=thriller", []string{"1"}},
+		{"&genre=family,crime", []string{"1", "2"}},
+		{"&mood=uplifting", []string{"2"}},
+		{"&ageRating=G,PG", []string{"2"}},
+		{"&withoutWarnings=violence", []string{"2", "3"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.query, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/list?start=0&am=10"+tc.query, nil)
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("want 200, got %d: %v", rr.Code, rr.Body.String())
+			}
+			var got model.PaginatedResponse[model.Item]
+			if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			ids := make([]string, 0, len(got.Items))
+			for _, it := range got.Items {
+				ids = append(ids, it.ID)
+			}
+			testboil.FailTestIfDiff(t, strings.Join(ids, ","), strings.Join(tc.wantIDs, ","))
+		})
+	}
+}
+
+func Test_store_ThumbnailHandlerFunc(t *testing.T) {
+	t.Parallel()
+	s := newTestStore(t)
+	handler := s.ThumbnailHandlerFunc()
+
+	s.cache = map[string]model.Item{
+		"img":   {ID: "img", MIMEType: "image/png"},
+		"video": {ID: "video", MIMEType: "video/mp4"},
+	}
+	if _, err := s.thumbs.Generate("img", image.NewRGBA(image.Rect(0, 0, 800, 400))); err != nil {
+		t.Fatal(err)
+	}
+
+	serve := func(id, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/thumb/"+id+query, nil)
+		req.SetPathValue("id", id)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("serves grid by default", func(t *testing.T) {
+		rr := serve("img", "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("want 200, got %d", rr.Code)
+		}
+		testboil.FailTestIfDiff(t, rr.Header().Get("Content-Type"), "image/jpeg")
+		testboil.FailTestIfDiff(t, rr.Header().Get("Vary"), "Accept")
+		cfg, _, err := image.DecodeConfig(rr.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testboil.FailTestIfDiff(t, cfg.Width, thumbnail.SizeGrid.Width)
+	})
+
+	t.Run("serves requested size", func(t *testing.T) {
+		rr := serve("img", "?size=card")
+		cfg, _, err := image.DecodeConfig(rr.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testboil.FailTestIfDiff(t, cfg.Width, thumbnail.SizeCard.Width)
+	})
+
+	t.Run("invalid size", func(t *testing.T) {
+		if rr := serve("img", "?size=huge"); rr.Code != http.StatusBadRequest {
+			t.Fatalf("want 400, got %d", rr.Code)
+		}
+	})
+
+	t.Run("404 without thumbnail", func(t *testing.T) {
+		if rr := serve("video", ""); rr.Code != http.StatusNotFound {
+			t.Fatalf("want 404, got %d", rr.Code)
+		}
+	})
+
+	t.Run("404 on unknown id", func(t *testing.T) {
+		if rr := serve("nope", ""); rr.Code != http.StatusNotFound {
+			t.Fatalf("want 404, got %d", rr.Code)
+		}
+	})
+}
+
+func Test_store_RegenerateThumbnails(t *testing.T) {
+	t.Parallel()
+	s := newTestStore(t)
+	src := path.Join(t.TempDir(), "a.png")
+	writePNG(t, src, 20, 20)
+	legacy := thumbnail.GetThumbnailPath(src)
+	s.cache = map[string]model.Item{
+		"a":     {ID: "a", Name: "a.png", Path: src, MIMEType: "image/png", Thumbnail: model.Image{ID: "legacy", Path: legacy}},
+		"video": {ID: "video", Name: "v.mp4", MIMEType: "video/mp4"},
+	}
+
+	regenerated, err := s.RegenerateThumbnails("a")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !regenerated {
+		t.Fatal("expected thumbnails to be regenerated")
+	}
+	got := s.cache["a"].Thumbnail
+	testboil.FailTestIfDiff(t, got.ID, "a")
+	testboil.FailTestIfDiff(t, got.Path, s.thumbs.Path("a", thumbnail.SizeGrid, thumbnail.FormatJPEG))
+	if _, err := os.Stat(path.Join(s.storePath, "a")); err != nil {
+		t.Fatalf("expected item to be persisted: %v", err)
+	}
+
+	regenerated, err = s.RegenerateThumbnails("video")
+	if err != nil || regenerated {
+		t.Fatalf("expected videos to be skipped, got: %v, %v", regenerated, err)
+	}
+	if _, err := s.RegenerateThumbnails("nope"); err == nil {
+		t.Fatal("expected error for unknown id")
+	}
+}
+
+func Test_store_parentalControls(t *testing.T) {
+	t.Parallel()
+	c, err := parental.New(parental.Config{DefaultMaxRating: "PG", Devices: map[string]string{"living-room": ""}})
+	if err != nil {
+		t.Fatalf("parental.New: %v", err)
+	}
+	s := newTestStore(t)
+	s.parental = c
+	s.cacheMu.Lock()
+	s.cache = map[string]model.Item{
+		"1": {ID: "1", Name: "heat", Path: "/media/heat.mkv", MIMEType: "video/mp4", Metadata: &model.MediaMetadata{AgeRating: "R"}},
+		"2": {ID: "2", Name: "paddington", Path: "/media/paddington.mkv", MIMEType: "video/mp4", Metadata: &model.MediaMetadata{AgeRating: "PG"}},
+	}
+	s.cacheMu.Unlock()
+
+	for device, want := range map[string]string{"": "2", "living-room": "1,2"} {
+		req := httptest.NewRequest(http.MethodGet, "/list?start=0&am=10", nil)
+		if device != "" {
+			req.AddCookie(&http.Cookie{Name: parental.DeviceCookie, Value: c.SignDevice(device)})
+		}
+		rr := httptest.NewRecorder()
+		s.ListHandlerFunc().ServeHTTP(rr, req)
+		var got model.PaginatedResponse[model.Item]
+		if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		ids := make([]string, 0, len(got.Items))
+		for _, it := range got.Items {
+			ids = append(ids, it.ID)
+		}
+		testboil.FailTestIfDiff(t, strings.Join(ids, ","), want)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/video/1", nil)
+	req.SetPathValue("id", "1")
+	rr := httptest.NewRecorder()
+	s.VideoHandlerFunc().ServeHTTP(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("playing above the limit: code = %d, want 403", rr.Code)
+	}
+
+	for name, tc := range map[string]struct {
+		h      http.HandlerFunc
+		values map[string]string
+	}{
+		"stream list": {s.StreamListHandlerFunc(), map[string]string{"vid": "1"}},
+		"stream":      {s.StreamHandlerFunc(), map[string]string{"vid": "1", "stream_idx": "2"}},
+		"dual stream": {s.DualStreamHandlerFunc(), map[string]string{"vid": "1", "primary_idx": "2", "secondary_idx": "3"}},
+		"attachment":  {s.AttachmentHandlerFunc(), map[string]string{"vid": "1", "stream_idx": "4"}},
+		"thumbnail":   {s.ThumbnailHandlerFunc(), map[string]string{"id": "1"}},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		for k, v := range tc.values {
+			req.SetPathValue(k, v)
+		}
+		rr := httptest.NewRecorder()
+		tc.h.ServeHTTP(rr, req)
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("%s above the limit: code = %d, want 403", name, rr.Code)
+		}
+	}
+
+	if !s.AllowedForAgents(model.Item{MIMEType: "video/mp4", Metadata: &model.MediaMetadata{AgeRating: "R"}}) {
+		t.Error("expected the agents unlimited without agentMaxRating")
+	}
+}
@@ -0,0 +1,193 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/baalimago/go_away_boilerplate/pkg/ancli"
+	"github.com/baalimago/kinoview/internal/agents"
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+// historyDir holds the metadata history of every item, one JSON line per
+// change in <id>.jsonl. Being a directory, the store's load skips it.
+//
+// The history is appended to by whichever process changes the metadata, the
+// server or the `kinoview media` CLI, so it's numbered when read rather than
+// when written.
+const historyDir = ".history"
+
+func historyPath(storePath, id string) string {
+	return path.Join(storePath, historyDir, id+".jsonl")
+}
+
+// classifierProvenance of metadata classified by c, with its model if it
+// tells.
+func classifierProvenance(c agents.Classifier) model.Provenance {
+	by := model.Provenance{Source: model.SourceClassifier}
+	if m, ok := c.(agents.ModelNamer); ok {
+		by.Model = m.Model()
+	}
+	return by
+}
+
+// classifiedProvenance of the item i as classified by c: the model is the
+// one which classified it, c possibly routing between several.
+func classifiedProvenance(c agents.Classifier, i model.Item) model.Provenance {
+	by := classifierProvenance(c)
+	if i.ClassificationModel != "" {
+		by.Model = i.ClassificationModel
+	}
+	return by
+}
+
+// cachedMetadata of the item with the given ID, nil if it has none or
+// there's no such item.
+func (s *store) cachedMetadata(id string) *model.MediaMetadata {
+	s.cacheMu.RLock()
+	defer s.cacheMu.RUnlock()
+	return s.cache[id].Metadata
+}
+
+// recordMetadata appends the change of the metadata of i from before to its
+// history, unless nothing changed. The history is a record of the metadata,
+// not the metadata itself, so failing to write it is logged rather than
+// failing the change.
+func (s *store) recordMetadata(before *model.MediaMetadata, i model.Item, by model.Provenance, note string) {
+	diff := model.DiffMetadata(before, i.Metadata)
+	if len(diff) == 0 {
+		return
+	}
+	c := model.MetadataChange{
+		Time:     time.Now(),
+		By:       by,
+		Note:     note,
+		Metadata: i.Metadata,
+		Diff:     diff,
+	}
+	if err := s.appendHistory(i.ID, c); err != nil {
+		ancli.Warnf("failed to record the metadata history of %v: %v", i.Name, err)
+	}
+}
+
+func (s *store) appendHistory(id string, c model.MetadataChange) error {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("marshal change: %w", err)
+	}
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+	if err := os.MkdirAll(path.Join(s.storePath, historyDir), 0o755); err != nil {
+		return fmt.Errorf("create history dir: %w", err)
+	}
+	f, err := os.OpenFile(historyPath(s.storePath, id), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("open history: %w", err)
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("append to history: %w", err)
+	}
+	return f.Close()
+}
+
+// readHistory of the item with the given ID, oldest first. Items whose
+// metadata never changed have none.
+func readHistory(storePath, id string) ([]model.MetadataChange, error) {
+	f, err := os.Open(historyPath(storePath, id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var changes []model.MetadataChange
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 4<<20)
+	version := 0
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		// Counted even if it can't be read, so versions don't shift
+		version++
+		var c model.MetadataChange
+		if err := json.Unmarshal(line, &c); err != nil {
+			ancli.Warnf("skipping unreadable version %v in the history of %v: %v", version, id, err)
+			continue
+		}
+		c.Version = version
+		changes = append(changes, c)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read history: %w", err)
+	}
+	return changes, nil
+}
+
+// History of the metadata of the item with the given ID, oldest first.
+func (s *store) History(id string) ([]model.MetadataChange, error) {
+	s.cacheMu.RLock()
+	_, ok := s.cache[id]
+	s.cacheMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", model.ErrNoItem, id)
+	}
+	return readHistory(s.storePath, id)
+}
+
+// RevertMetadata of the item with the given ID to what it was at version,
+// and return the item as it is after. The revert is itself a change, so
+// the history only ever grows. Reverting to a version without metadata
+// resets the classification, queueing the item for it anew.
+func (s *store) RevertMetadata(id string, version int) (model.Item, error) {
+	// Locked until stored, so nothing changes the item in between
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+	item, ok := s.cache[id]
+	if !ok {
+		return model.Item{}, fmt.Errorf("%w: %q", model.ErrNoItem, id)
+	}
+	changes, err := readHistory(s.storePath, id)
+	if err != nil {
+		return model.Item{}, fmt.Errorf("history of %q: %w", item.Name, err)
+	}
+	var target *model.MetadataChange
+	for n := range changes {
+		if changes[n].Version == version {
+			target = &changes[n]
+		}
+	}
+	if target == nil {
+		return model.Item{}, fmt.Errorf("%w: %v has no version %v", model.ErrNoVersion, item.Name, version)
+	}
+
+	before := item.Metadata
+	item.Metadata = target.Metadata
+	item.ClassificationModel = target.By.Model
+	// Whoever picked the version is sure of it
+	item.Confidence = nil
+	item.Review = nil
+	item.ClassificationAttempts = 0
+	item.ClassificationLastTry = time.Time{}
+	item.ClassificationError = ""
+	if err := s.storeLocked(item); err != nil {
+		return model.Item{}, fmt.Errorf("persist revert of %q: %w", item.Name, err)
+	}
+	// A running server takes reverts written by the CLI once this is
+	// recorded, see syncExternalRevert
+	s.recordMetadata(before, item, model.Provenance{Source: model.SourceHuman}, fmt.Sprintf("reverted to version %v", version))
+	if item.Metadata == nil && s.started.Load() {
+		s.markPendingRequeue(id)
+	}
+	return item, nil
+}
@@ -0,0 +1,266 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/baalimago/kinoview/internal/agents"
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+// namedClassifier is a classifier which tells its model.
+type namedClassifier struct {
+	mockClassifier
+}
+
+func (namedClassifier) Model() string { return "gpt-test" }
+
+func Test_classifierProvenance(t *testing.T) {
+	var c agents.Classifier = &namedClassifier{}
+	if by := classifierProvenance(c); by.Source != model.SourceClassifier || by.Model != "gpt-test" {
+		t.Errorf("unexpected provenance: %+v", by)
+	}
+	if by := classifierProvenance(&mockClassifier{}); by.Source != model.SourceClassifier || by.Model != "" {
+		t.Errorf("unexpected provenance: %+v", by)
+	}
+	if by := classifiedProvenance(c, model.Item{ClassificationModel: "gpt-routed"}); by.Model != "gpt-routed" {
+		t.Errorf("expected the model which classified the item, got %+v", by)
+	}
+}
+
+// storeHistory stores an item classified as Heat (1995), then has the
+// concierge change its year.
+func storeHistory(t *testing.T, s *store) model.Item {
+	t.Helper()
+	video := filepath.Join(t.TempDir(), "heat.mkv")
+	if err := os.WriteFile(video, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	i := model.Item{ID: "h", Name: "heat.mkv", Path: video, MIMEType: "video/x-matroska"}
+	if err := s.store(i); err != nil {
+		t.Fatal(err)
+	}
+	i.Metadata = &model.MediaMetadata{Name: "Heat", Year: 1995}
+	i.ClassificationModel = "gpt-test"
+	if err := s.store(i); err != nil {
+		t.Fatal(err)
+	}
+	s.recordMetadata(nil, i, model.Provenance{Source: model.SourceClassifier, Model: "gpt-test"}, "")
+	concierge := model.Provenance{Source: model.SourceAgent, Actor: "concierge"}
+	if err := s.UpdateMetadata(i, `{"year":1994}`, concierge); err != nil {
+		t.Fatal(err)
+	}
+	// Changing nothing isn't a change
+	if err := s.UpdateMetadata(i, `{"year":1994}`, concierge); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := s.GetItemByID("h")
+	return got
+}
+
+func Test_store_History(t *testing.T) {
+	s := newTestStore(t)
+	if got := storeHistory(t, s); got.ClassificationModel != "" {
+		t.Errorf("expected the concierge's change not to be the classifier's, got %q", got.ClassificationModel)
+	}
+
+	changes, err := s.History("h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %+v", changes)
+	}
+	if c := changes[0]; c.Version != 1 || c.By.Model != "gpt-test" || len(c.Diff) != 2 {
+		t.Errorf("unexpected first change: %+v", c)
+	}
+	c := changes[1]
+	if c.Version != 2 || c.By.Actor != "concierge" || c.Metadata.Year != 1994 {
+		t.Errorf("unexpected second change: %+v", c)
+	}
+	if len(c.Diff) != 1 || c.Diff[0].Field != "year" || string(c.Diff[0].From) != "1995" || string(c.Diff[0].To) != "1994" {
+		t.Errorf("unexpected diff: %+v", c.Diff)
+	}
+
+	if _, err := s.History("missing"); !errors.Is(err, model.ErrNoItem) {
+		t.Errorf("expected ErrNoItem, got %v", err)
+	}
+	if err := s.store(model.Item{ID: "new", Name: "new.mkv"}); err != nil {
+		t.Fatal(err)
+	}
+	if changes, err := s.History("new"); err != nil || changes != nil {
+		t.Errorf("expected no history, got %+v, %v", changes, err)
+	}
+}
+
+func Test_store_History_resetAndReview(t *testing.T) {
+	s := newTestStore(t)
+	storeReviewed(t, s, "r", time.Now())
+	if _, err := s.ResolveReview("r", model.ReviewDecision{Action: model.ReviewEdit, Metadata: json.RawMessage(`{"year":1994}`)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ResetClassification("r"); err != nil {
+		t.Fatal(err)
+	}
+	changes, err := s.History("r")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 || changes[0].Note != "review edited" || changes[1].Note != "classification reset" {
+		t.Fatalf("unexpected history: %+v", changes)
+	}
+	if changes[1].By.Source != model.SourceHuman || changes[1].Metadata != nil {
+		t.Errorf("unexpected reset: %+v", changes[1])
+	}
+}
+
+func Test_store_RevertMetadata(t *testing.T) {
+	t.Run("to an earlier version", func(t *testing.T) {
+		s := newTestStore(t)
+		storeHistory(t, s)
+		got, err := s.RevertMetadata("h", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Metadata.Year != 1995 || got.ClassificationModel != "gpt-test" {
+			t.Errorf("expected the year and model of version 1, got %+v, %q", got.Metadata, got.ClassificationModel)
+		}
+		disk, err := readStoreItem(s.storePath, "h")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if disk.Metadata.Year != 1995 {
+			t.Error("expected the revert to be persisted")
+		}
+		changes, _ := s.History("h")
+		if len(changes) != 3 || changes[2].Note != "reverted to version 1" || changes[2].By.Source != model.SourceHuman {
+			t.Errorf("expected the revert at the end of the history, got %+v", changes)
+		}
+	})
+
+	t.Run("to no metadata", func(t *testing.T) {
+		s := newTestStore(t)
+		s.started.Store(true)
+		i := storeHistory(t, s)
+		if _, err := s.ResetClassification(i.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.RevertMetadata("h", 2); err != nil {
+			t.Fatal(err)
+		}
+		got, err := s.RevertMetadata("h", 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Metadata != nil {
+			t.Errorf("expected the metadata cleared, got %+v", got.Metadata)
+		}
+		if ids := s.pendingRequeueIDs(); len(ids) != 1 || ids[0] != "h" {
+			t.Errorf("expected the item to be queued anew, pending: %v", ids)
+		}
+	})
+
+	t.Run("to a version it never had", func(t *testing.T) {
+		s := newTestStore(t)
+		storeHistory(t, s)
+		for _, v := range []int{0, 3, -1} {
+			if _, err := s.RevertMetadata("h", v); !errors.Is(err, model.ErrNoVersion) {
+				t.Errorf("%v: expected ErrNoVersion, got %v", v, err)
+			}
+		}
+		if _, err := s.RevertMetadata("missing", 1); !errors.Is(err, model.ErrNoItem) {
+			t.Errorf("expected ErrNoItem, got %v", err)
+		}
+	})
+}
+
+func Test_readHistory_skipsUnreadable(t *testing.T) {
+	s := newTestStore(t)
+	storeHistory(t, s)
+	f, err := os.OpenFile(historyPath(s.storePath, "h"), os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A write cut short, then one more change
+	f.WriteString(`{"time":"2026-` + "\n")
+	f.Close()
+	s.recordMetadata(nil, model.Item{ID: "h", Metadata: &model.MediaMetadata{Name: "Ronin"}}, model.Provenance{Source: model.SourceHuman}, "")
+
+	changes, err := readHistory(s.storePath, "h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 3 || changes[2].Version != 4 {
+		t.Errorf("expected the last change to keep version 4, got %+v", changes)
+	}
+}
+
+func Test_store_RevertMetadata_storeFails(t *testing.T) {
+	s := newTestStore(t)
+	storeHistory(t, s)
+	before, err := s.History("h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A directory where the item is stored can't be written over
+	p := filepath.Join(s.storePath, "h")
+	if err := os.Remove(p); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(p, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.RevertMetadata("h", 1); err == nil {
+		t.Fatal("expected the revert to fail")
+	}
+	after, err := s.History("h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(after) != len(before) {
+		t.Errorf("expected no history of a revert which wasn't stored, got %+v", after[len(before):])
+	}
+}
+
+func Test_store_syncExternalRevert(t *testing.T) {
+	s := newTestStore(t)
+	storeHistory(t, s)
+
+	// `kinoview media list` reverts with a store of its own
+	cli := NewStore(WithStorePath(s.storePath), WithClassifier(nil))
+	if _, err := cli.Setup(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cli.RevertMetadata("h", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if !s.syncExternalRevert("h") {
+		t.Fatal("expected the revert to be picked up")
+	}
+	if got, _ := s.GetItemByID("h"); got.Metadata.Year != 1995 {
+		t.Errorf("expected the cache to follow the disk, got %+v", got.Metadata)
+	}
+	if s.syncExternalRevert("h") {
+		t.Error("expected nothing to pick up the second time")
+	}
+
+	// Metadata on disk which the history doesn't have isn't a revert
+	i, _ := s.GetItemByID("h")
+	i.Metadata = &model.MediaMetadata{Name: "Heat", Year: 2001}
+	data, err := json.Marshal(i)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(s.storePath, "h"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if s.syncExternalRevert("h") {
+		t.Error("expected metadata missing from the history to be left alone")
+	}
+}
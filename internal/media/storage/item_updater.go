@@ -3,6 +3,8 @@ package storage
 import (
 	"context"
 	"fmt"
+	"maps"
+	"strings"
 	"time"
 
 	"github.com/baalimago/kinoview/internal/model"
@@ -33,7 +35,11 @@ func (s *store) ResetClassification(id string) (bool, error) {
 		return false, fmt.Errorf("no item with ID %q", id)
 	}
 
+	before := item.Metadata
 	item.Metadata = nil
+	item.Confidence = nil
+	item.ClassificationModel = ""
+	item.Review = nil
 	item.ClassificationAttempts = 0
 	item.ClassificationLastTry = time.Time{}
 	item.ClassificationError = ""
@@ -41,6 +47,7 @@ func (s *store) ResetClassification(id string) (bool, error) {
 	if err := s.store(item); err != nil {
 		return false, fmt.Errorf("persist reset for %q: %w", item.Name, err)
 	}
+	s.recordMetadata(before, item, model.Provenance{Source: model.SourceHuman}, "classification reset")
 	return true, nil
 }
 
@@ -70,8 +77,69 @@ func (s *store) ClearClassificationStopLoss(id string) (bool, error) {
 	return true, nil
 }
 
+// SetSubtitleOffset persists the timing correction of the subtitle stream at
+// streamIndex of an item. A zero offset clears it.
+func (s *store) SetSubtitleOffset(id, streamIndex string, offset time.Duration) error {
+	s.cacheMu.RLock()
+	item, ok := s.cache[id]
+	s.cacheMu.RUnlock()
+	if !ok {
+		return fmt.Errorf("no item with ID %q", id)
+	}
+
+	// Copy, as the cached item shares the map with anyone reading it.
+	offsets := make(map[string]int64, len(item.SubtitleOffsets)+1)
+	maps.Copy(offsets, item.SubtitleOffsets)
+	if offset == 0 {
+		delete(offsets, streamIndex)
+	} else {
+		offsets[streamIndex] = offset.Milliseconds()
+	}
+	if len(offsets) == 0 {
+		offsets = nil
+	}
+	item.SubtitleOffsets = offsets
+
+	if err := s.store(item); err != nil {
+		return fmt.Errorf("persist subtitle offset for %q: %w", item.Name, err)
+	}
+	return nil
+}
+
 // ClassificationMaxAttempts is the ceiling after which an item is permanently
 // skipped. Exposed so callers can report the threshold they are acting on.
 func (s *store) ClassificationMaxAttempts() int {
 	return s.classificationMaxAttempts
 }
+
+// RegenerateThumbnails discards the cached thumbnails of an item and
+// generates them anew. Items without thumbnails, such as videos, report false.
+func (s *store) RegenerateThumbnails(id string) (bool, error) {
+	s.cacheMu.RLock()
+	item, ok := s.cache[id]
+	s.cacheMu.RUnlock()
+	if !ok {
+		return false, fmt.Errorf("no item with ID %q", id)
+	}
+
+	var handle func(*model.Item) error
+	switch {
+	case strings.HasPrefix(item.MIMEType, "image"):
+		handle = s.handleImageItem
+	case strings.HasPrefix(item.MIMEType, "audio"):
+		handle = s.handleAudioItem
+	default:
+		return false, nil
+	}
+	if err := s.thumbs.Remove(id); err != nil {
+		return false, err
+	}
+	item.Thumbnail = model.Image{}
+	if err := handle(&item); err != nil {
+		return false, fmt.Errorf("regenerate thumbnails for %q: %w", item.Name, err)
+	}
+	if err := s.store(item); err != nil {
+		return false, fmt.Errorf("persist thumbnails for %q: %w", item.Name, err)
+	}
+	return item.Thumbnail.Path != "", nil
+}
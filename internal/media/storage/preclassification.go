@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"strings"
+
+	"github.com/baalimago/go_away_boilerplate/pkg/ancli"
+	"github.com/baalimago/kinoview/internal/agents"
+	"github.com/baalimago/kinoview/internal/media/preclassify"
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+// preclassified sets the metadata of videos whose file name, folders and
+// sidecars, and the title dataset, already say all the classifier would, and
+// reports whether it did. Those never need to cost an LLM call.
+func (s *store) preclassified(i *model.Item) bool {
+	if !strings.Contains(i.MIMEType, "video") {
+		return false
+	}
+	known := s.known(i.Path)
+	if !known.Complete() {
+		return false
+	}
+	i.Metadata = known.MediaMetadata()
+	i.ClassificationModel = ""
+	i.ClassificationAttempts = 0
+	i.ClassificationError = ""
+	ancli.Noticef("pre-classified %v without the classifier", i.Name)
+	return true
+}
+
+// known metadata of the video at p: what its file name, folders and
+// sidecars say, filled in from the metadata provider, if any. The provider
+// is asked by ID when one is known. Otherwise by the name and year of a
+// movie, or the name of a show. An answer is used only when it is the
+// only match. A show's entry says nothing of the episode, so only its
+// actors are taken.
+func (s *store) known(p string) preclassify.Metadata {
+	known := preclassify.Guess(p)
+	if s.metadataProvider == nil {
+		return known
+	}
+	if known.IMDbID != "" {
+		md, err := s.metadataProvider.LookupID(known.IMDbID)
+		if err != nil {
+			if !errors.Is(err, model.ErrNoTitle) {
+				ancli.Warnf("failed to look up %v: %v", known.IMDbID, err)
+			}
+			return known
+		}
+		return known.Fill(preclassify.FromMediaMetadata(md))
+	}
+	switch {
+	case known.IsEpisode():
+		if md, ok := s.lookupTitle(known.ShowName, 0); ok {
+			return known.Fill(preclassify.Metadata{Actors: md.Actors})
+		}
+	case known.Name != "" && known.ShowName == "":
+		if md, ok := s.lookupTitle(known.Name, known.Year); ok {
+			return known.Fill(preclassify.FromMediaMetadata(md))
+		}
+	}
+	return known
+}
+
+// lookupTitle with the metadata provider, reporting whether there was
+// exactly one match.
+func (s *store) lookupTitle(title string, year int) (model.MediaMetadata, bool) {
+	matches, err := s.metadataProvider.LookupTitle(title, year)
+	if err != nil {
+		ancli.Warnf("failed to look up %q: %v", title, err)
+		return model.MediaMetadata{}, false
+	}
+	if len(matches) != 1 {
+		return model.MediaMetadata{}, false
+	}
+	return matches[0], true
+}
+
+// classify the item, only asking the classifier for what couldn't be
+// worked out deterministically. What was is handed to the classifier as
+// the item's metadata, and wins over whatever the classifier answers. Where
+// the classifier disagrees, the item is flagged for review. Also returns
+// the provenance of the metadata, for its history.
+func (s *store) classify(ctx context.Context, c agents.Classifier, i model.Item) (model.Item, model.Provenance, error) {
+	i, known, done := s.prepareClassification(i)
+	if done {
+		return i, model.Provenance{Source: model.SourcePreclassify}, nil
+	}
+	classified, err := c.Classify(ctx, i)
+	if err != nil {
+		return classified, classifierProvenance(c), err
+	}
+	return finishClassification(known, classified), classifiedProvenance(c, classified), nil
+}
+
+// prepareClassification clears what a previous classification left and sets
+// what is known of i as its metadata. Reports done if that's all there is
+// to it, the item being pre-classified.
+func (s *store) prepareClassification(i model.Item) (model.Item, preclassify.Metadata, bool) {
+	i.Confidence = nil
+	i.Review = nil
+	i.ClassificationModel = ""
+	i.TagsVersion = 0
+	if s.preclassified(&i) {
+		return i, preclassify.Metadata{}, true
+	}
+	if !strings.Contains(i.MIMEType, "video") {
+		return i, preclassify.Metadata{}, false
+	}
+	known := s.known(i.Path)
+	if !known.Empty() {
+		i.Metadata = known.MediaMetadata()
+	}
+	return i, known, false
+}
+
+// finishClassification merges what was known into what the classifier
+// answered, flagging where they disagree.
+func finishClassification(known preclassify.Metadata, classified model.Item) model.Item {
+	classified.TagsVersion = model.TagsVersion
+	if known.Empty() || classified.Metadata == nil {
+		return classified
+	}
+	for _, d := range known.Disagreements(*classified.Metadata) {
+		flagForReview(&classified, "the classifier contradicts the file: "+d)
+	}
+	merged := known.Merge(*classified.Metadata)
+	classified.Metadata = &merged
+	if classified.Confidence != nil {
+		confidence := maps.Clone(classified.Confidence)
+		for _, key := range known.Keys() {
+			confidence[key] = 1
+		}
+		classified.Confidence = confidence
+	}
+	return classified
+}
@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+func Test_classify_skipsClassifierWithCompleteNFO(t *testing.T) {
+	dir := t.TempDir()
+	video := filepath.Join(dir, "heat.mkv")
+	if err := os.WriteFile(filepath.Join(dir, "heat.nfo"), []byte(`<movie>
+  <title>Heat</title>
+  <year>1995</year>
+  <plot>A group of professional bank robbers start to feel the heat.</plot>
+</movie>`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	called := false
+	c := &mockClassifier{
+		ClassifyFunc: func(ctx context.Context, i model.Item) (model.Item, error) {
+			called = true
+			return i, nil
+		},
+	}
+	got, by, err := newTestStore(t).classify(context.Background(), c, model.Item{Name: "heat.mkv", Path: video, MIMEType: "video/x-matroska"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if called {
+		t.Fatal("expected the classifier to be skipped")
+	}
+	if by.Source != model.SourcePreclassify {
+		t.Errorf("expected the metadata to be pre-classified, got %+v", by)
+	}
+	if got.Metadata == nil {
+		t.Fatal("expected metadata to be set")
+	}
+	if got.Metadata.Name != "Heat" || got.Metadata.Year != 1995 {
+		t.Errorf("unexpected metadata: %+v", got.Metadata)
+	}
+	if !needsTags(got) {
+		t.Error("expected the tags of a pre-classified video to be backfilled")
+	}
+}
+
+func Test_classify_mergesKnownFields(t *testing.T) {
+	video := filepath.Join(t.TempDir(), "Show.Name.S02E06.1080p.mkv")
+
+	var gotHint string
+	c := &mockClassifier{
+		ClassifyFunc: func(ctx context.Context, i model.Item) (model.Item, error) {
+			if i.Metadata != nil {
+				b, err := json.Marshal(i.Metadata)
+				if err != nil {
+					t.Error(err)
+				}
+				gotHint = string(b)
+			}
+			md := model.MediaMetadata{Name: "Episode Six", ShowName: "Wrong", Season: 1, Episode: 6, Description: "Stuff."}
+			i.Metadata = &md
+			return i, nil
+		},
+	}
+	got, _, err := newTestStore(t).classify(context.Background(), c, model.Item{Name: "Show.Name.S02E06.1080p.mkv", Path: video, MIMEType: "video/x-matroska"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"episode":6,"season":2,"showName":"Show Name"}`; gotHint != want {
+		t.Errorf("hint = %v, want %v", gotHint, want)
+	}
+	if md := got.Metadata; md.ShowName != "Show Name" || md.Season != 2 || md.Name != "Episode Six" {
+		t.Errorf("unexpected merged metadata: %+v", md)
+	}
+}
+
+func Test_classify_unknownGoesToClassifier(t *testing.T) {
+	want := model.MediaMetadata{Name: "Clip"}
+	c := &mockClassifier{
+		ClassifyFunc: func(ctx context.Context, i model.Item) (model.Item, error) {
+			if i.Metadata != nil {
+				t.Errorf("expected no hint, got %+v", i.Metadata)
+			}
+			i.Metadata = &want
+			return i, nil
+		},
+	}
+	got, _, err := newTestStore(t).classify(context.Background(), c, model.Item{Name: "clip.mp4", Path: filepath.Join(t.TempDir(), "clip.mp4"), MIMEType: "video/mp4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Metadata.Name != want.Name {
+		t.Errorf("metadata = %+v, want %+v", got.Metadata, want)
+	}
+	if needsTags(got) {
+		t.Error("expected the tags to count as asked for with the rest of the metadata")
+	}
+}
+
+func Test_handleVideoItem_preclassifiedNotQueued(t *testing.T) {
+	dir := t.TempDir()
+	video := filepath.Join(dir, "heat.mkv")
+	if err := os.WriteFile(filepath.Join(dir, "heat.nfo"), []byte(`<movie><title>Heat</title><year>1995</year><plot>Robbers.</plot></movie>`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := NewStore(WithStorePath(t.TempDir()))
+	s.started.Store(true)
+	s.classificationRequest = make(chan classificationCandidate, 1)
+
+	i := model.Item{ID: "heat", Name: "heat.mkv", Path: video, MIMEType: "video/x-matroska"}
+	if err := s.handleVideoItem(&i); err != nil {
+		t.Fatal(err)
+	}
+	if i.Metadata == nil {
+		t.Fatal("expected metadata to be set")
+	}
+	if i.ClassificationAttempts != 0 {
+		t.Errorf("expected no attempt to be counted, got %v", i.ClassificationAttempts)
+	}
+	select {
+	case c := <-s.classificationRequest:
+		t.Fatalf("expected nothing queued, got %v", c.item.Name)
+	default:
+	}
+}
+
+func Test_classify_metadataProvider(t *testing.T) {
+	provider := &mockMetadataProvider{titles: []model.MediaMetadata{
+		{Name: "Heat", Year: 1995, IMDbID: "tt0113277", Description: "A group of professional bank robbers start to feel the heat.", Actors: []string{"Al Pacino"}},
+		{Name: "Heat", Year: 1986, IMDbID: "tt0093164", Description: "A Las Vegas bodyguard."},
+		{Name: "Heat", Year: 1986, IMDbID: "tt0000002", Description: "Another heat."},
+		{ShowName: "Show Name", IMDbID: "tt0000001", Actors: []string{"Someone"}, Description: "About the show."},
+	}}
+	s := newTestStore(t)
+	s.metadataProvider = provider
+
+	t.Run("a unique match completes a movie", func(t *testing.T) {
+		c := &mockClassifier{
+			ClassifyFunc: func(ctx context.Context, i model.Item) (model.Item, error) {
+				t.Fatal("expected the classifier to be skipped")
+				return i, nil
+			},
+		}
+		video := filepath.Join(t.TempDir(), "Heat.1995.1080p.BluRay.mkv")
+		got, by, err := s.classify(context.Background(), c, model.Item{Name: "Heat.1995.1080p.BluRay.mkv", Path: video, MIMEType: "video/x-matroska"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if by.Source != model.SourcePreclassify {
+			t.Errorf("expected the metadata to be pre-classified, got %+v", by)
+		}
+		if md := got.Metadata; md == nil || md.IMDbID != "tt0113277" || len(md.Actors) != 1 {
+			t.Errorf("unexpected metadata: %+v", md)
+		}
+	})
+
+	t.Run("ambiguous matches are left to the classifier", func(t *testing.T) {
+		called := false
+		c := &mockClassifier{
+			ClassifyFunc: func(ctx context.Context, i model.Item) (model.Item, error) {
+				called = true
+				if i.Metadata != nil && i.Metadata.Description != "" {
+					t.Errorf("expected no description to be guessed, got %q", i.Metadata.Description)
+				}
+				md := model.MediaMetadata{Name: "Heat", Description: "Some heat."}
+				i.Metadata = &md
+				return i, nil
+			},
+		}
+		video := filepath.Join(t.TempDir(), "Heat.1986.mkv")
+		if _, _, err := s.classify(context.Background(), c, model.Item{Name: "Heat.1986.mkv", Path: video, MIMEType: "video/x-matroska"}); err != nil {
+			t.Fatal(err)
+		}
+		if !called {
+			t.Fatal("expected the classifier to be called")
+		}
+	})
+
+	t.Run("episodes take the actors of their show", func(t *testing.T) {
+		var hint model.MediaMetadata
+		c := &mockClassifier{
+			ClassifyFunc: func(ctx context.Context, i model.Item) (model.Item, error) {
+				hint = *i.Metadata
+				return i, nil
+			},
+		}
+		video := filepath.Join(t.TempDir(), "Show.Name.S02E06.mkv")
+		if _, _, err := s.classify(context.Background(), c, model.Item{Name: "Show.Name.S02E06.mkv", Path: video, MIMEType: "video/x-matroska"}); err != nil {
+			t.Fatal(err)
+		}
+		if len(hint.Actors) != 1 || hint.Description != "" {
+			t.Errorf("expected only the actors of the show, got %+v", hint)
+		}
+	})
+}
@@ -1,7 +1,6 @@
 package storage
 
 import (
-	"encoding/json"
 	"testing"
 	"time"
 
@@ -72,7 +71,7 @@ func seedItem(t *testing.T, s *store, i model.Item) {
 func TestResetClassification_ClearsMetadataAndAttempts(t *testing.T) {
 	t.Parallel()
 	s := NewStore(WithStorePath(t.TempDir()))
-	raw := json.RawMessage(`{"name":"Done","genre":"drama"}`)
+	raw := model.MediaMetadata{Name: "Done", Genre: "drama"}
 	seedItem(t, s, model.Item{
 		ID: "id1", Name: "done.mkv", Path: "/media/done.mkv",
 		MIMEType: "video/x-matroska", Metadata: &raw,
@@ -93,7 +92,7 @@ func TestResetClassification_ClearsMetadataAndAttempts(t *testing.T) {
 		t.Fatal(err)
 	}
 	if got.Metadata != nil {
-		t.Errorf("metadata survived the reset: %s", *got.Metadata)
+		t.Errorf("metadata survived the reset: %+v", *got.Metadata)
 	}
 	if got.ClassificationAttempts != 0 {
 		t.Errorf("attempts = %d, want 0", got.ClassificationAttempts)
@@ -119,7 +118,7 @@ func TestResetClassification_UnknownID(t *testing.T) {
 func TestClearClassificationStopLoss(t *testing.T) {
 	t.Parallel()
 	s := NewStore(WithStorePath(t.TempDir()), WithClassificationMaxAttempts(5))
-	raw := json.RawMessage(`{"name":"Partial"}`)
+	raw := model.MediaMetadata{Name: "Partial"}
 	seedItem(t, s, model.Item{ID: "blocked", Name: "blocked.mkv", ClassificationAttempts: 5, ClassificationError: "rate limited", Metadata: &raw})
 	seedItem(t, s, model.Item{ID: "over", Name: "over.mkv", ClassificationAttempts: 9, ClassificationError: "no key"})
 	seedItem(t, s, model.Item{ID: "retrying", Name: "retrying.mkv", ClassificationAttempts: 2, ClassificationError: "timeout"})
@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/baalimago/go_away_boilerplate/pkg/ancli"
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+// flagForReview adds reason to the review of i, starting one if there is none.
+func flagForReview(i *model.Item, reason string) {
+	if i.Review == nil {
+		i.Review = &model.Review{Since: time.Now()}
+	}
+	i.Review.Reasons = append(i.Review.Reasons, reason)
+}
+
+// checkClassification flags freshly classified items for review if the
+// classifier wasn't confident in some field, or if the duration it came up
+// with isn't the one of the file.
+func (s *store) checkClassification(i *model.Item) {
+	if i.Metadata == nil {
+		return
+	}
+	for _, key := range slices.Sorted(maps.Keys(i.Confidence)) {
+		if c := i.Confidence[key]; c < s.reviewThreshold && i.Metadata.Has(key) {
+			flagForReview(i, fmt.Sprintf("low confidence in %v (%.2f)", key, c))
+		}
+	}
+	if i.Metadata.DurationMin > 0 && s.subtitleManager != nil && strings.Contains(i.MIMEType, "video") {
+		info, err := s.subtitleManager.Find(*i)
+		if err != nil {
+			ancli.Warnf("failed to probe the duration of %v: %v", i.Name, err)
+		} else if actual := info.Duration(); durationMismatch(i.Metadata.DurationMin, actual) {
+			flagForReview(i, fmt.Sprintf("duration_min is %v, the file runs for %v min", i.Metadata.DurationMin, int(actual.Round(time.Minute).Minutes())))
+		}
+	}
+	if i.Review != nil {
+		ancli.Noticef("flagged %v for review: %v", i.Name, strings.Join(i.Review.Reasons, ", "))
+	}
+}
+
+// durationMismatch reports if the classified duration is off from the actual
+// one by more than 15%, or 5 minutes for short videos. Cuts and credits make
+// up the difference between releases, but not more than that.
+func durationMismatch(classifiedMin int, actual time.Duration) bool {
+	if actual <= 0 {
+		return false
+	}
+	diff := time.Duration(classifiedMin)*time.Minute - actual
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff > max(5*time.Minute, actual*15/100)
+}
+
+// Reviews lists the items waiting for review, the longest waiting first.
+func (s *store) Reviews() []model.Item {
+	var ret []model.Item
+	for _, i := range s.Snapshot() {
+		if i.Review != nil {
+			ret = append(ret, i)
+		}
+	}
+	slices.SortFunc(ret, func(a, b model.Item) int {
+		if c := a.Review.Since.Compare(b.Review.Since); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return ret
+}
+
+// ResolveReview of the item with the given ID and return it as it is after.
+// Rejected items are queued for classification anew.
+func (s *store) ResolveReview(id string, d model.ReviewDecision) (model.Item, error) {
+	// Locked until stored, so nothing changes the item in between
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+	item, ok := s.cache[id]
+	if !ok {
+		return model.Item{}, fmt.Errorf("%w: no item with ID %q", model.ErrNoReview, id)
+	}
+	if item.Review == nil {
+		return model.Item{}, fmt.Errorf("%w: %v", model.ErrNoReview, item.Name)
+	}
+
+	before := item.Metadata
+	var note string
+	switch d.Action {
+	case model.ReviewAccept:
+	case model.ReviewEdit:
+		note = "review edited"
+		merged, err := model.PatchMetadata(item.Metadata, d.Metadata)
+		if err != nil {
+			return model.Item{}, fmt.Errorf("%w: %w", model.ErrInvalidDecision, err)
+		}
+		item.Metadata = merged
+		item.ClassificationModel = ""
+		// Whoever edited the fields is sure of them
+		var patch map[string]json.RawMessage
+		_ = json.Unmarshal(d.Metadata, &patch)
+		confidence := make(model.Confidence, len(item.Confidence)+len(patch))
+		maps.Copy(confidence, item.Confidence)
+		for key := range patch {
+			confidence[key] = 1
+		}
+		item.Confidence = confidence
+	case model.ReviewReject:
+		note = "review rejected"
+		item.Metadata = nil
+		item.Confidence = nil
+		item.ClassificationModel = ""
+		item.ClassificationAttempts = 0
+		item.ClassificationLastTry = time.Time{}
+		item.ClassificationError = ""
+	default:
+		return model.Item{}, fmt.Errorf("%w: unknown action %q", model.ErrInvalidDecision, d.Action)
+	}
+	item.Review = nil
+
+	if err := s.storeLocked(item); err != nil {
+		return model.Item{}, fmt.Errorf("persist review of %q: %w", item.Name, err)
+	}
+	s.recordMetadata(before, item, model.Provenance{Source: model.SourceHuman}, note)
+	if d.Action == model.ReviewReject && s.started.Load() {
+		s.markPendingRequeue(id)
+	}
+	return item, nil
+}
@@ -0,0 +1,242 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+func Test_checkClassification(t *testing.T) {
+	s := newTestStore(t)
+	s.subtitleManager = &mockSubtitleManager{shouldReturn: model.MediaInfo{
+		Streams: []model.Stream{{CodecType: "video", Duration: "5400"}},
+	}}
+
+	t.Run("confident and right", func(t *testing.T) {
+		i := model.Item{
+			Name:       "heat.mkv",
+			MIMEType:   "video/x-matroska",
+			Metadata:   &model.MediaMetadata{Name: "Heat", Year: 1995, DurationMin: 88},
+			Confidence: model.Confidence{"name": 0.9, "year": 0.8},
+		}
+		s.checkClassification(&i)
+		if i.Review != nil {
+			t.Errorf("expected no review, got %+v", i.Review)
+		}
+	})
+
+	t.Run("low confidence and wrong duration", func(t *testing.T) {
+		i := model.Item{
+			Name:     "heat.mkv",
+			MIMEType: "video/x-matroska",
+			Metadata: &model.MediaMetadata{Name: "Heat", Year: 1995, DurationMin: 170},
+			// Confidence in fields which weren't filled in doesn't matter
+			Confidence: model.Confidence{"name": 0.9, "year": 0.4, "season": 0.1},
+		}
+		s.checkClassification(&i)
+		if i.Review == nil {
+			t.Fatal("expected the item to be flagged")
+		}
+		want := []string{
+			"low confidence in year (0.40)",
+			"duration_min is 170, the file runs for 90 min",
+		}
+		if !reflect.DeepEqual(i.Review.Reasons, want) {
+			t.Errorf("reasons = %q, want %q", i.Review.Reasons, want)
+		}
+	})
+}
+
+func Test_durationMismatch(t *testing.T) {
+	tests := []struct {
+		classified int
+		actual     time.Duration
+		want       bool
+	}{
+		{90, 92 * time.Minute, false},
+		{120, 95 * time.Minute, true},
+		{25, 22 * time.Minute, false},
+		{45, 22 * time.Minute, true},
+		{45, 0, false},
+	}
+	for _, tc := range tests {
+		if got := durationMismatch(tc.classified, tc.actual); got != tc.want {
+			t.Errorf("durationMismatch(%v, %v) = %v, want %v", tc.classified, tc.actual, got, tc.want)
+		}
+	}
+}
+
+func Test_classify_flagsDisagreement(t *testing.T) {
+	video := filepath.Join(t.TempDir(), "Show.Name.S02E06.1080p.mkv")
+	c := &mockClassifier{
+		ClassifyFunc: func(ctx context.Context, i model.Item) (model.Item, error) {
+			i.Metadata = &model.MediaMetadata{Name: "Pilot", ShowName: "Show Name", Season: 1, Episode: 6}
+			i.Confidence = model.Confidence{"name": 0.8, "season": 0.3}
+			return i, nil
+		},
+	}
+	got, _, err := newTestStore(t).classify(context.Background(), c, model.Item{Name: "Show.Name.S02E06.1080p.mkv", Path: video, MIMEType: "video/x-matroska"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Review == nil || len(got.Review.Reasons) != 1 || got.Review.Reasons[0] != "the classifier contradicts the file: season is 2, the classifier said 1" {
+		t.Errorf("unexpected review: %+v", got.Review)
+	}
+	if got.Confidence["season"] != 1 || got.Confidence["name"] != 0.8 {
+		t.Errorf("expected the known fields to be certain, got %v", got.Confidence)
+	}
+}
+
+// storeReviewed stores an item flagged for review since the given time.
+func storeReviewed(t *testing.T, s *store, id string, since time.Time) model.Item {
+	t.Helper()
+	i := model.Item{
+		ID:                  id,
+		Name:                id + ".mkv",
+		MIMEType:            "video/x-matroska",
+		Metadata:            &model.MediaMetadata{Name: "Heat", Year: 1995},
+		Confidence:          model.Confidence{"name": 0.9, "year": 0.4},
+		ClassificationModel: "gpt-test",
+		Review:              &model.Review{Reasons: []string{"low confidence in year (0.40)"}, Since: since},
+	}
+	if err := s.store(i); err != nil {
+		t.Fatal(err)
+	}
+	return i
+}
+
+func Test_store_Reviews(t *testing.T) {
+	s := newTestStore(t)
+	now := time.Now()
+	storeReviewed(t, s, "newer", now)
+	storeReviewed(t, s, "older", now.Add(-time.Hour))
+	if err := s.store(model.Item{ID: "fine", Name: "fine.mkv"}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := s.Reviews()
+	if len(got) != 2 || got[0].ID != "older" || got[1].ID != "newer" {
+		t.Errorf("expected the two reviews, oldest first, got %v", got)
+	}
+}
+
+func Test_store_ResolveReview(t *testing.T) {
+	t.Run("accept", func(t *testing.T) {
+		s := newTestStore(t)
+		storeReviewed(t, s, "a", time.Now())
+		got, err := s.ResolveReview("a", model.ReviewDecision{Action: model.ReviewAccept})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Review != nil || got.Metadata.Year != 1995 || got.ClassificationModel != "gpt-test" {
+			t.Errorf("expected the metadata kept and the review gone, got %+v", got)
+		}
+		disk, err := readStoreItem(s.storePath, "a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if disk.Review != nil {
+			t.Error("expected the resolved review to be persisted")
+		}
+	})
+
+	t.Run("edit", func(t *testing.T) {
+		s := newTestStore(t)
+		storeReviewed(t, s, "e", time.Now())
+		got, err := s.ResolveReview("e", model.ReviewDecision{Action: model.ReviewEdit, Metadata: json.RawMessage(`{"year":1994}`)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Review != nil || got.Metadata.Name != "Heat" || got.Metadata.Year != 1994 {
+			t.Errorf("expected the year patched, got %+v", got.Metadata)
+		}
+		if got.Confidence["year"] != 1 {
+			t.Errorf("expected the edited field to be certain, got %v", got.Confidence)
+		}
+		if got.ClassificationModel != "" {
+			t.Errorf("expected the edit not to be the classifier's, got %q", got.ClassificationModel)
+		}
+	})
+
+	t.Run("edit which doesn't validate", func(t *testing.T) {
+		s := newTestStore(t)
+		storeReviewed(t, s, "e", time.Now())
+		_, err := s.ResolveReview("e", model.ReviewDecision{Action: model.ReviewEdit, Metadata: json.RawMessage(`{"year":"soon"}`)})
+		if !errors.Is(err, model.ErrInvalidDecision) {
+			t.Fatalf("expected ErrInvalidDecision, got %v", err)
+		}
+		if got, _ := s.GetItemByID("e"); got.Review == nil {
+			t.Error("expected the item to stay in review")
+		}
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		s := newTestStore(t)
+		s.started.Store(true)
+		storeReviewed(t, s, "r", time.Now())
+		got, err := s.ResolveReview("r", model.ReviewDecision{Action: model.ReviewReject})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Review != nil || got.Metadata != nil || got.Confidence != nil || got.ClassificationModel != "" {
+			t.Errorf("expected the classification discarded, got %+v", got)
+		}
+		if ids := s.pendingRequeueIDs(); len(ids) != 1 || ids[0] != "r" {
+			t.Errorf("expected the item to be queued anew, pending: %v", ids)
+		}
+	})
+
+	t.Run("not in review", func(t *testing.T) {
+		s := newTestStore(t)
+		if err := s.store(model.Item{ID: "fine", Name: "fine.mkv"}); err != nil {
+			t.Fatal(err)
+		}
+		for _, id := range []string{"fine", "missing"} {
+			if _, err := s.ResolveReview(id, model.ReviewDecision{Action: model.ReviewAccept}); !errors.Is(err, model.ErrNoReview) {
+				t.Errorf("%v: expected ErrNoReview, got %v", id, err)
+			}
+		}
+	})
+
+	t.Run("unknown action", func(t *testing.T) {
+		s := newTestStore(t)
+		storeReviewed(t, s, "u", time.Now())
+		if _, err := s.ResolveReview("u", model.ReviewDecision{Action: "shrug"}); !errors.Is(err, model.ErrInvalidDecision) {
+			t.Errorf("expected ErrInvalidDecision, got %v", err)
+		}
+	})
+}
+
+func Test_store_syncExternalReview(t *testing.T) {
+	s := newTestStore(t)
+	i := storeReviewed(t, s, "x", time.Now())
+
+	// The CLI accepts the review, with an edit, straight on disk
+	i.Review = nil
+	i.Metadata = &model.MediaMetadata{Name: "Heat", Year: 1994}
+	data, err := json.Marshal(i)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(s.storePath, "x"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if !s.syncExternalReview("x") {
+		t.Fatal("expected the review to be picked up")
+	}
+	got, _ := s.GetItemByID("x")
+	if got.Review != nil || got.Metadata.Year != 1994 {
+		t.Errorf("expected the cache to follow the disk, got %+v", got)
+	}
+	if s.syncExternalReview("x") {
+		t.Error("expected nothing to pick up the second time")
+	}
+}
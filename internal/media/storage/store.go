@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"maps"
 	"os"
@@ -19,14 +20,26 @@ import (
 	"github.com/baalimago/go_away_boilerplate/pkg/misc"
 	"github.com/baalimago/kinoview/internal/agents"
 	"github.com/baalimago/kinoview/internal/agents/classifier"
+	"github.com/baalimago/kinoview/internal/media/moviehash"
+	"github.com/baalimago/kinoview/internal/media/parental"
+	"github.com/baalimago/kinoview/internal/media/thumbnail"
 	"github.com/baalimago/kinoview/internal/model"
 )
 
+// streamManager is the agents' StreamManager, plus what is only served to
+// clients: styled subtitles and the fonts they use.
+type streamManager interface {
+	agents.StreamManager
+	ExtractASS(item model.Item, streamIndex string) (string, error)
+	ExtractAttachment(item model.Item, streamIndex string) (string, error)
+}
+
 type store struct {
 	storePath       string
 	cacheMu         *sync.RWMutex
 	cache           map[string]model.Item
-	subtitleManager agents.StreamManager
+	subtitleManager streamManager
+	thumbs          *thumbnail.Cache
 
 	classifier               agents.Classifier
 	classifierMu             sync.RWMutex
@@ -46,6 +59,21 @@ type store struct {
 	memoryThreshold                float64
 	classificationMaxAttempts      int
 	classificationTimeout          time.Duration
+	// classificationBatchSize is the most items classified in one call of a
+	// batch classifier, see batch_classification.go.
+	classificationBatchSize int
+	// tagBackfill tags the videos classified before there were tags, see
+	// tag_backfill.go.
+	tagBackfill bool
+	// metadataProvider is asked for what the file itself doesn't say, before
+	// the classifier is, see preclassification.go.
+	metadataProvider agents.MetadataProvider
+	// reviewThreshold is the confidence below which classified fields are
+	// flagged for review.
+	reviewThreshold float64
+	// parental limits what each device lists and plays, and what the agents
+	// suggest, by its age rating.
+	parental *parental.Controls
 
 	// totalMemory returns the machine's total RAM in bytes. Defaults to
 	// totalSystemMemory; tests override it per store so the memory guard is
@@ -64,6 +92,12 @@ type store struct {
 	pendingRequeueMu sync.Mutex
 	pendingRequeue   map[string]struct{}
 
+	// queue of items waiting for classification, see classification_queue.go.
+	queue *classificationQueue
+
+	// historyMu serialises appends to the metadata histories, see history.go.
+	historyMu sync.Mutex
+
 	readyChan chan struct{}
 
 	// wg tracks every background goroutine Start spawns so Wait can block
@@ -75,12 +109,20 @@ type store struct {
 
 type StoreOption func(*store)
 
-func WithSubtitlesManager(subsM agents.StreamManager) StoreOption {
+func WithSubtitlesManager(subsM streamManager) StoreOption {
 	return func(s *store) {
 		s.subtitleManager = subsM
 	}
 }
 
+// WithThumbnailCache sets where thumbnails are stored. Defaults to
+// thumbnail.DefaultCacheDir.
+func WithThumbnailCache(c *thumbnail.Cache) StoreOption {
+	return func(s *store) {
+		s.thumbs = c
+	}
+}
+
 func WithClassifier(classifier agents.Classifier) StoreOption {
 	return func(s *store) {
 		s.classifier = classifier
@@ -94,6 +136,36 @@ func (s *store) SetClassifier(c agents.Classifier) {
 	s.classifierMu.Unlock()
 }
 
+// WithParentalControls to hide the videos above the age rating limit of the
+// device listing them, and refuse to play them.
+func WithParentalControls(c *parental.Controls) StoreOption {
+	return func(s *store) {
+		s.parental = c
+	}
+}
+
+// AllowedForAgents reports whether the agents may list and suggest the item,
+// see agents.ContentGate.
+func (s *store) AllowedForAgents(it model.Item) bool {
+	return s.parental.AllowedForAgents(it)
+}
+
+// WithTagBackfill to have the classifier tag the videos classified before
+// there were tags, when it is idle.
+func WithTagBackfill(enabled bool) StoreOption {
+	return func(s *store) {
+		s.tagBackfill = enabled
+	}
+}
+
+// WithMetadataProvider to look titles up in before asking the classifier.
+// Videos it knows all about are never sent to the classifier.
+func WithMetadataProvider(p agents.MetadataProvider) StoreOption {
+	return func(s *store) {
+		s.metadataProvider = p
+	}
+}
+
 func WithStorePath(storePath string) StoreOption {
 	return func(s *store) {
 		s.storePath = storePath
@@ -150,6 +222,25 @@ func WithClassificationTimeout(d time.Duration) StoreOption {
 	}
 }
 
+// WithClassificationBatchSize sets the most items, such as the episodes of a
+// season, classified in one call when the classifier is an
+// agents.BatchClassifier. Default 24. One or less classifies every item on its
+// own.
+func WithClassificationBatchSize(n int) StoreOption {
+	return func(s *store) {
+		s.classificationBatchSize = n
+	}
+}
+
+// WithReviewThreshold sets the confidence, from 0 to 1, below which a field
+// of classified metadata is flagged for review. Default 0.6. Zero disables
+// flagging by confidence.
+func WithReviewThreshold(t float64) StoreOption {
+	return func(s *store) {
+		s.reviewThreshold = t
+	}
+}
+
 // WithStartupWriteDelay sets the duration after Start() during which store writes
 // are deferred and batched. After the delay expires (or ctx cancels), all dirty
 // items are flushed to disk in a single batch. Default 30s. Zero or negative
@@ -178,6 +269,7 @@ func NewStore(opts ...StoreOption) *store {
 		storePath: storePath,
 		cache:     make(map[string]model.Item),
 		cacheMu:   &sync.RWMutex{},
+		thumbs:    thumbnail.NewCache(thumbnail.DefaultCacheDir()),
 		classifier: classifier.New(models.Configurations{
 			Model:     "gpt-5",
 			ConfigDir: kinoviewCfgPath,
@@ -199,6 +291,8 @@ func NewStore(opts ...StoreOption) *store {
 		memoryThreshold:               0.8,
 		classificationMaxAttempts:     5,
 		classificationTimeout:         5 * time.Minute,
+		classificationBatchSize:       24,
+		reviewThreshold:               0.6,
 		startupWriteWindow:            30 * time.Second,
 		dirty:                         make(map[string]struct{}),
 		pendingRequeue:                make(map[string]struct{}),
@@ -212,6 +306,7 @@ func NewStore(opts ...StoreOption) *store {
 	for _, opt := range opts {
 		opt(s)
 	}
+	s.queue = newClassificationQueue(path.Join(s.storePath, classificationQueueFile))
 
 	return s
 }
@@ -266,6 +361,11 @@ func (s *store) loadPersistedItems(storeDirPath string) error {
 		if closeErr != nil {
 			ancli.Warnf("failed to close file: '%v', err: %v", filePath, closeErr)
 		}
+		if item.Metadata != nil {
+			if err := item.Metadata.Validate(); err != nil {
+				ancli.Warnf("metadata of '%v' doesn't validate, keeping it as is: %v", item.Name, err)
+			}
+		}
 
 		underlyingFilePath := item.Path
 		if _, err := os.Stat(underlyingFilePath); err != nil {
@@ -358,6 +458,8 @@ func (s *store) Start(ctx context.Context) {
 // context so deferred writes land on disk before shutdown proceeds.
 func (s *store) Wait() {
 	s.wg.Wait()
+	// Changes since the delegator's last tick
+	s.queue.flush()
 }
 
 // generateID by creating a hash using sha256 on the contents of item.Path
@@ -395,17 +497,20 @@ func generateID(path string) string {
 
 func (s *store) store(i model.Item) error {
 	s.cacheMu.Lock()
-	s.cache[i.ID] = i
+	defer s.cacheMu.Unlock()
+	return s.storeLocked(i)
+}
 
+// storeLocked is store for callers which hold s.cacheMu.Lock(), so that
+// what they read from the cache doesn't change before they store it.
+func (s *store) storeLocked(i model.Item) error {
+	s.cache[i.ID] = i
 	if s.isInStartupWriteWindow() {
-		s.cacheMu.Unlock()
 		s.dirtyMu.Lock()
 		s.dirty[i.ID] = struct{}{}
 		s.dirtyMu.Unlock()
 		return nil
 	}
-	defer s.cacheMu.Unlock()
-
 	return s.persistToDisk(i)
 }
 
@@ -515,15 +620,29 @@ func (s *store) Store(ctx context.Context, i model.Item) error {
 		// been moved, update the path
 		if !hadID {
 			maybeNewPath := i.Path
+			maybeNewMIMEType := i.MIMEType
 			i = existingItem
 			i.Path = maybeNewPath
+			// Media type detection improves over time, so trust the
+			// latest scan over the persisted type
+			if maybeNewMIMEType != "" {
+				i.MIMEType = maybeNewMIMEType
+			}
 		}
 		i.Metadata = existingItem.Metadata
 	}
 	if !exists {
 		ancli.Noticef("registering new media: %v", i.Name)
 	}
+	before := i.Metadata
 
+	if strings.Contains(i.MIMEType, "video") && i.MovieHash == "" {
+		hash, err := moviehash.Compute(i.Path)
+		if err != nil && !errors.Is(err, moviehash.ErrTooSmall) {
+			ancli.Warnf("failed to compute moviehash of %v: %v", i.Name, err)
+		}
+		i.MovieHash = hash
+	}
 	if i.Metadata == nil && strings.Contains(i.MIMEType, "video") {
 		if strings.Contains(i.MIMEType, "video") {
 			err := s.handleVideoItem(&i)
@@ -537,12 +656,29 @@ func (s *store) Store(ctx context.Context, i model.Item) error {
 		if err != nil {
 			ancli.Errf("failed to handle image item, continuing. Error is: %v", err)
 		}
-		// Slight hack here, ideally thumbnail should be an models.Item and
-		// thumbnail properties should be flattned into the same struct
-		i.Thumbnail.ID = generateID(i.Thumbnail.Path)
+	}
+	if strings.HasPrefix(i.MIMEType, "audio") {
+		err := s.handleAudioItem(&i)
+		if err != nil {
+			ancli.Errf("failed to handle audio item, continuing. Error is: %v", err)
+		}
+		// Tags usually say all there is to say about a track, so only
+		// bother the classifier when they're lacking. The queueing is
+		// the same as for videos.
+		if i.Metadata == nil && needsMusicClassification(i) {
+			err := s.handleVideoItem(&i)
+			if err != nil {
+				ancli.Errf("failed to queue audio item for classification, continuing. Error is: %v", err)
+			}
+		}
 	}
 
-	return s.store(i)
+	if err := s.store(i); err != nil {
+		return err
+	}
+	// Pre-classification is all that sets metadata on the way in
+	s.recordMetadata(before, i, model.Provenance{Source: model.SourcePreclassify}, "")
+	return nil
 }
 
 func (s *store) Snapshot() (ret []model.Item) {
@@ -579,24 +715,21 @@ func (s *store) GetItemByName(name string) (model.Item, error) {
 // partial object such as {"showName": "Stargate SG-1"} therefore preserves
 // all other classified fields — agents can complete a missing field without
 // re-supplying the full classification.
-func (s *store) UpdateMetadata(item model.Item, metadata string) error {
-	var incoming map[string]any
-	if err := json.Unmarshal([]byte(metadata), &incoming); err != nil {
-		return fmt.Errorf("failed to unmarshal metadata, invalid json: %w", err)
-	}
-	merged := map[string]any{}
-	if item.Metadata != nil {
-		if err := json.Unmarshal(*item.Metadata, &merged); err != nil {
-			merged = map[string]any{}
-		}
-	}
-	maps.Copy(merged, incoming)
-	raw, err := json.Marshal(merged)
+func (s *store) UpdateMetadata(item model.Item, metadata string, by model.Provenance) error {
+	merged, err := model.PatchMetadata(item.Metadata, []byte(metadata))
 	if err != nil {
-		return fmt.Errorf("failed to marshal merged metadata: %w", err)
+		return err
 	}
-	item.Metadata = (*json.RawMessage)(&raw)
-	return s.store(item)
+	// The item may be older than the cache, what changed is what's cached
+	before := s.cachedMetadata(item.ID)
+	item.Metadata = merged
+	// Classified by whoever changed it last, no LLM if a human did
+	item.ClassificationModel = by.Model
+	if err := s.store(item); err != nil {
+		return err
+	}
+	s.recordMetadata(before, item, by, "")
+	return nil
 }
 
 // DeleteItem removes an item from the in-memory cache and deletes its on-disk
@@ -610,5 +743,8 @@ func (s *store) DeleteItem(id string) error {
 	if err := os.Remove(storePath); err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("failed to remove store file: %w", err)
 	}
+	if err := s.thumbs.Remove(id); err != nil {
+		ancli.Warnf("failed to remove thumbnails of: '%v', err: %v", id, err)
+	}
 	return nil
 }
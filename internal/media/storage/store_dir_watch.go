@@ -17,11 +17,13 @@ import (
 // reset items the classification station has not accepted yet.
 const requeueRetryInterval = 2 * time.Second
 
-// watchStoreDir reacts to classification resets that the `kinoview media` CLI
-// writes straight to the store directory. The CLI bypasses Store on purpose —
-// Store copies cached metadata back over re-scanned items, so clearing
-// metadata through it is impossible — which leaves the running server blind to
-// the reset: nothing re-queued the item until a restart. Watching our own
+// watchStoreDir reacts to classification resets, reviews and reverts that the
+// `kinoview media` CLI writes straight to the store directory, and to the
+// history it records them in. The CLI
+// bypasses Store on purpose — Store copies cached metadata back over
+// re-scanned items, so clearing metadata through it is impossible — which
+// leaves the running server blind to the reset: nothing re-queued the item
+// until a restart. Watching our own
 // store directory closes that gap: self-writes always match the cache and are
 // ignored, so only external resets trigger a requeue.
 func (s *store) watchStoreDir(ctx context.Context) {
@@ -35,6 +37,14 @@ func (s *store) watchStoreDir(ctx context.Context) {
 		ancli.Errf("store dir watcher: failed to watch %v: %v", s.storePath, err)
 		return
 	}
+	// Reverts are recorded after they're stored, and only taken once they are,
+	// so the history is watched too
+	history := path.Join(s.storePath, historyDir)
+	if err := os.MkdirAll(history, 0o755); err != nil {
+		ancli.Errf("store dir watcher: failed to create %v: %v", history, err)
+	} else if err := w.Add(history); err != nil {
+		ancli.Errf("store dir watcher: failed to watch %v: %v", history, err)
+	}
 	ancli.Noticef("watching store directory for external classification resets: %v", s.storePath)
 	for {
 		select {
@@ -49,8 +59,16 @@ func (s *store) watchStoreDir(ctx context.Context) {
 			if !ok {
 				return
 			}
-			if ev.Has(fsnotify.Write) || ev.Has(fsnotify.Create) {
-				s.requeueExternalReset(path.Base(ev.Name))
+			if !ev.Has(fsnotify.Write) && !ev.Has(fsnotify.Create) {
+				continue
+			}
+			if path.Dir(ev.Name) == history {
+				s.syncExternalRevert(strings.TrimSuffix(path.Base(ev.Name), ".jsonl"))
+				continue
+			}
+			id := path.Base(ev.Name)
+			if !s.requeueExternalReset(id) && !s.syncExternalReview(id) {
+				s.syncExternalRevert(id)
 			}
 		}
 	}
@@ -97,6 +115,9 @@ func (s *store) requeueExternalReset(id string) bool {
 	}
 
 	cached.Metadata = nil
+	cached.Confidence = disk.Confidence
+	cached.ClassificationModel = disk.ClassificationModel
+	cached.Review = disk.Review
 	cached.ClassificationAttempts = disk.ClassificationAttempts
 	cached.ClassificationLastTry = disk.ClassificationLastTry
 	cached.ClassificationError = disk.ClassificationError
@@ -110,6 +131,71 @@ func (s *store) requeueExternalReset(id string) bool {
 	return true
 }
 
+// syncExternalReview picks up reviews `kinoview media review` accepted or
+// edited on disk, so that the server neither keeps the item in review nor
+// writes the old metadata back over the reviewed one. Rejected reviews are
+// resets, see requeueExternalReset. Reports whether it acted.
+func (s *store) syncExternalReview(id string) bool {
+	s.cacheMu.RLock()
+	cached, ok := s.cache[id]
+	s.cacheMu.RUnlock()
+	if !ok || cached.Review == nil {
+		return false
+	}
+	disk, err := readStoreItem(s.storePath, id)
+	if err != nil || disk.Review != nil || disk.Metadata == nil {
+		return false
+	}
+
+	cached.Metadata = disk.Metadata
+	cached.Confidence = disk.Confidence
+	cached.ClassificationModel = disk.ClassificationModel
+	cached.Review = nil
+	s.cacheMu.Lock()
+	s.cache[id] = cached
+	s.cacheMu.Unlock()
+
+	ancli.Noticef("store dir: picked up external review: %v", cached.Name)
+	return true
+}
+
+// syncExternalRevert picks up metadata `kinoview media list` reverted on
+// disk, so that the server doesn't write the metadata it has cached back
+// over it. Only metadata which the history says is the latest is taken:
+// anything else on disk is the server's own write, racing the cache.
+// Reverts to no metadata are resets, see requeueExternalReset. Reports
+// whether it acted.
+func (s *store) syncExternalRevert(id string) bool {
+	s.cacheMu.RLock()
+	cached, ok := s.cache[id]
+	s.cacheMu.RUnlock()
+	if !ok {
+		return false
+	}
+	disk, err := readStoreItem(s.storePath, id)
+	if err != nil || disk.Metadata == nil || len(model.DiffMetadata(cached.Metadata, disk.Metadata)) == 0 {
+		return false
+	}
+	changes, err := readHistory(s.storePath, id)
+	if err != nil || len(changes) == 0 || len(model.DiffMetadata(changes[len(changes)-1].Metadata, disk.Metadata)) != 0 {
+		return false
+	}
+
+	cached.Metadata = disk.Metadata
+	cached.Confidence = disk.Confidence
+	cached.ClassificationModel = disk.ClassificationModel
+	cached.Review = disk.Review
+	cached.ClassificationAttempts = disk.ClassificationAttempts
+	cached.ClassificationLastTry = disk.ClassificationLastTry
+	cached.ClassificationError = disk.ClassificationError
+	s.cacheMu.Lock()
+	s.cache[id] = cached
+	s.cacheMu.Unlock()
+
+	ancli.Noticef("store dir: picked up external metadata change: %v", cached.Name)
+	return true
+}
+
 // tryRequeue makes one attempt to enqueue a pending externally-reset item.
 // It applies the same stop-loss and backoff rules as the media watcher path,
 // and only consumes an attempt when the station accepts the item: a drop
@@ -118,29 +204,35 @@ func (s *store) requeueExternalReset(id string) bool {
 // are skipped silently before enqueuing, so a slow drain does not spam the
 // server log with "rate limit reached" warnings every tick.
 func (s *store) tryRequeue(id string) {
+	s.requeue(id, model.PriorityRetry)
+}
+
+// requeue the item like tryRequeue, queued no less urgent than p. Reports
+// whether the station accepted it.
+func (s *store) requeue(id string, p model.ClassificationPriority) bool {
 	s.cacheMu.RLock()
 	cached, ok := s.cache[id]
 	s.cacheMu.RUnlock()
 	if !ok || cached.Metadata != nil || !strings.Contains(cached.MIMEType, "video") {
 		s.unmarkPendingRequeue(id)
-		return
+		return false
 	}
 	if s.atMaxAttempts(cached) {
 		ancli.Warnf("classification permanently skipped for %v: max attempts (%v) reached", cached.Name, s.classificationMaxAttempts)
 		s.unmarkPendingRequeue(id)
-		return
+		return false
 	}
 	if s.inBackoff(cached) {
-		return // retry on a later tick
+		return false // retry on a later tick
 	}
-	if s.classificationStartupCooldown > 0 && time.Since(s.classificationStationStartTime) < s.classificationStartupCooldown {
-		return // cooldown still active; retry on a later tick
+	if s.inCooldown() {
+		return false // cooldown still active; retry on a later tick
 	}
 	if s.memoryHigh() {
-		return // memory pressure; retry on a later tick
+		return false // memory pressure; retry on a later tick
 	}
 	if s.rateLimiter != nil && !s.rateLimiter.peek() {
-		return // no token yet; retry on a later tick
+		return false // no token yet; retry on a later tick
 	}
 
 	attemptsBefore := cached.ClassificationAttempts
@@ -152,18 +244,19 @@ func (s *store) tryRequeue(id string) {
 	s.cache[id] = cached
 	s.cacheMu.Unlock()
 
-	s.AddToClassificationQueue(cached)
+	s.enqueue(cached, min(p, classificationPriority(cached)))
 	if _, inFlight := s.inFlight.Load(id); inFlight {
 		s.unmarkPendingRequeue(id)
-		return
+		return true
 	}
-	// Dropped (queue full, memory pressure): keep pending and restore the
+	// Dropped (cooldown, rate limit, memory pressure): keep pending and restore the
 	// attempt so a later retry still counts as one.
 	cached.ClassificationAttempts = attemptsBefore
 	cached.ClassificationLastTry = lastTryBefore
 	s.cacheMu.Lock()
 	s.cache[id] = cached
 	s.cacheMu.Unlock()
+	return false
 }
 
 // isExternalClassificationReset reports whether disk holds a classification
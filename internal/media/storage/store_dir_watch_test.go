@@ -12,7 +12,7 @@ import (
 
 func Test_isExternalClassificationReset(t *testing.T) {
 	t.Parallel()
-	raw := json.RawMessage(`{"name":"Done"}`)
+	raw := model.MediaMetadata{Name: "Done"}
 	tests := []struct {
 		name   string
 		cached model.Item
@@ -89,7 +89,7 @@ func Test_requeueExternalReset_picksUpReset(t *testing.T) {
 	t.Parallel()
 	dir := t.TempDir()
 	s := NewStore(WithStorePath(dir), WithClassificationStartupCooldown(0))
-	raw := json.RawMessage(`{"name":"Done"}`)
+	raw := model.MediaMetadata{Name: "Done"}
 	seedItem(t, s, model.Item{
 		ID: "id1", Name: "done.mkv", Path: "/media/done.mkv",
 		MIMEType: "video/x-matroska", Metadata: &raw, ClassificationAttempts: 3,
@@ -192,7 +192,7 @@ func Test_tryRequeue_restoresAttemptsWhenDropped(t *testing.T) {
 	t.Parallel()
 	dir := t.TempDir()
 	s := NewStore(WithStorePath(dir), WithClassificationStartupCooldown(0))
-	raw := json.RawMessage(`{"name":"Done"}`)
+	raw := model.MediaMetadata{Name: "Done"}
 	seedItem(t, s, model.Item{ID: "id1", Name: "a.mkv", Path: "/media/a.mkv", MIMEType: "video/mp4", Metadata: &raw, ClassificationAttempts: 1})
 	writeStoreItem(t, dir, "id1", model.Item{ID: "id1", Name: "a.mkv", Path: "/media/a.mkv", MIMEType: "video/mp4"})
 
@@ -221,7 +221,7 @@ func Test_requeueLoop_eventuallyEnqueues(t *testing.T) {
 	t.Parallel()
 	dir := t.TempDir()
 	s := NewStore(WithStorePath(dir), WithClassificationStartupCooldown(0))
-	raw := json.RawMessage(`{"name":"Done"}`)
+	raw := model.MediaMetadata{Name: "Done"}
 	seedItem(t, s, model.Item{
 		ID: "id1", Name: "done.mkv", Path: "/media/done.mkv",
 		MIMEType: "video/x-matroska", Metadata: &raw, ClassificationAttempts: 3,
@@ -266,7 +266,7 @@ func Test_watchStoreDir_picksUpExternalReset(t *testing.T) {
 	t.Parallel()
 	dir := t.TempDir()
 	s := NewStore(WithStorePath(dir), WithClassificationStartupCooldown(0))
-	raw := json.RawMessage(`{"name":"Done"}`)
+	raw := model.MediaMetadata{Name: "Done"}
 	seedItem(t, s, model.Item{
 		ID: "id1", Name: "done.mkv", Path: "/media/done.mkv",
 		MIMEType: "video/x-matroska", Metadata: &raw, ClassificationAttempts: 3,
@@ -302,12 +302,47 @@ func Test_watchStoreDir_picksUpExternalReset(t *testing.T) {
 	})
 }
 
+// The watcher picks up a revert the CLI writes, which is only taken once
+// the history has it, after the item is stored.
+func Test_watchStoreDir_picksUpExternalRevert(t *testing.T) {
+	t.Parallel()
+	s := newTestStore(t)
+	storeHistory(t, s)
+	go s.watchStoreDir(t.Context())
+	// Give fsnotify time to register the directories before the CLI writes.
+	time.Sleep(50 * time.Millisecond)
+
+	// RevertMetadata in two steps, with the watcher in between
+	cli := NewStore(WithStorePath(s.storePath), WithClassifier(nil))
+	if _, err := cli.Setup(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	reverted, err := cli.GetItemByID("h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := reverted.Metadata
+	reverted.Metadata = &model.MediaMetadata{Name: "Heat", Year: 1995}
+	if err := cli.store(reverted); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if it, _ := s.GetItemByID("h"); it.Metadata.Year == 1995 {
+		t.Fatal("expected the revert left alone until the history has it")
+	}
+	cli.recordMetadata(before, reverted, model.Provenance{Source: model.SourceHuman}, "reverted to version 1")
+	waitUntil(t, 3*time.Second, func() bool {
+		it, err := s.GetItemByID("h")
+		return err == nil && it.Metadata != nil && it.Metadata.Year == 1995
+	})
+}
+
 // The server's own writes must not be mistaken for external resets.
 func Test_watchStoreDir_ignoresServerWrites(t *testing.T) {
 	t.Parallel()
 	dir := t.TempDir()
 	s := NewStore(WithStorePath(dir), WithClassificationStartupCooldown(0))
-	raw := json.RawMessage(`{"name":"Done"}`)
+	raw := model.MediaMetadata{Name: "Done"}
 	seedItem(t, s, model.Item{
 		ID: "id1", Name: "done.mkv", Path: "/media/done.mkv",
 		MIMEType: "video/x-matroska", Metadata: &raw,
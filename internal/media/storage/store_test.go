@@ -116,7 +116,7 @@ func TestJSONStore_Store(t *testing.T) {
 		// Same content in both files, implying it has been moved
 		pre.WriteString(largeIshString.String())
 		post.WriteString(largeIshString.String())
-		want := json.RawMessage(`{"This":  "should stay"}`)
+		want := model.MediaMetadata{Extra: map[string]json.RawMessage{"This": json.RawMessage(`"should stay"`)}}
 		has := model.Item{Name: "with_ID", Path: pre.Name(), Metadata: &want}
 		id := generateID(has.Path)
 		has.ID = id
@@ -129,7 +129,7 @@ func TestJSONStore_Store(t *testing.T) {
 		newItemWithNoID.ID = ""
 		newItemWithNoID.Path = post.Name()
 		// Test should fail if this is overwritten
-		n := json.RawMessage(`{}`)
+		n := model.MediaMetadata{}
 		newItemWithNoID.Metadata = &n
 		err = s.Store(context.Background(), newItemWithNoID)
 		if err != nil {
@@ -139,7 +139,7 @@ func TestJSONStore_Store(t *testing.T) {
 		s.cacheMu.Lock()
 		t.Cleanup(s.cacheMu.Unlock)
 		got := s.cache[id].Metadata
-		testboil.FailTestIfDiff(t, string(*got), string(want))
+		testboil.FailTestIfDiff(t, mdString(t, got), mdString(t, &want))
 	})
 }
 
@@ -519,12 +519,12 @@ func Test_Stream_store_ffmpegSubsUtil_cache(t *testing.T) {
 		dir := t.TempDir()
 		s := NewStore(WithStorePath(dir), WithClassificationStartupCooldown(0))
 		s.classificationRequest = make(chan classificationCandidate, 10)
-		want := `{"some": "metadata"}`
+		want := `{"some":"metadata"}`
 		s.classifier = &mockClassifier{
 			SetupFunc: func(ctx context.Context) error { return nil },
 			ClassifyFunc: func(ctx context.Context, i model.Item) (model.Item, error) {
 				if i.Metadata == nil {
-					r := json.RawMessage(want)
+					r := model.MediaMetadata{Extra: map[string]json.RawMessage{"some": json.RawMessage(`"metadata"`)}}
 					i.Metadata = &r
 				}
 				return i, nil
@@ -551,7 +551,7 @@ func Test_Stream_store_ffmpegSubsUtil_cache(t *testing.T) {
 			got, exist := s.cache[item.ID]
 			s.cacheMu.RUnlock()
 			if exist && got.Metadata != nil {
-				gotMetadata := string(*got.Metadata)
+				gotMetadata := mdString(t, got.Metadata)
 				if gotMetadata != want {
 					t.Errorf("expected metadata to be set, got: %v", gotMetadata)
 				}
@@ -574,7 +574,7 @@ func Test_Stream_store_ffmpegSubsUtil_cache(t *testing.T) {
 		s.classifier = &mockClassifier{
 			SetupFunc: func(ctx context.Context) error { return nil },
 			ClassifyFunc: func(ctx context.Context, i model.Item) (model.Item, error) {
-				r := json.RawMessage(`{"initial":"metadata"}`)
+				r := model.MediaMetadata{Extra: map[string]json.RawMessage{"initial": json.RawMessage(`"metadata"`)}}
 				i.Metadata = &r
 				return i, nil
 			},
@@ -600,11 +600,11 @@ func Test_Stream_store_ffmpegSubsUtil_cache(t *testing.T) {
 		if original.Metadata == nil {
 			t.Fatalf("expected original to have metadata: %v", original)
 		}
-		originalMeta := string(*original.Metadata)
+		originalMeta := mdString(t, original.Metadata)
 		s.SetClassifier(&mockClassifier{
 			SetupFunc: func(ctx context.Context) error { return nil },
 			ClassifyFunc: func(ctx context.Context, i model.Item) (model.Item, error) {
-				r := json.RawMessage(`{"new":"metadata"}`)
+				r := model.MediaMetadata{Extra: map[string]json.RawMessage{"new": json.RawMessage(`"metadata"`)}}
 				i.Metadata = &r
 				return i, nil
 			},
@@ -620,7 +620,7 @@ func Test_Stream_store_ffmpegSubsUtil_cache(t *testing.T) {
 			s.cacheMu.RLock()
 			got := s.cache[item.ID]
 			s.cacheMu.RUnlock()
-			if got.Metadata != nil && string(*got.Metadata) == originalMeta {
+			if got.Metadata != nil && mdString(t, got.Metadata) == originalMeta {
 				break
 			}
 			time.Sleep(10 * time.Millisecond)
@@ -628,7 +628,7 @@ func Test_Stream_store_ffmpegSubsUtil_cache(t *testing.T) {
 		s.cacheMu.Lock()
 		t.Cleanup(s.cacheMu.Unlock)
 		got := s.cache[item.ID]
-		gotMeta := string(*got.Metadata)
+		gotMeta := mdString(t, got.Metadata)
 		if gotMeta != originalMeta {
 			t.Errorf("metadata should not be overwritten: got %v, want %v", gotMeta, originalMeta)
 		}
@@ -658,7 +658,7 @@ func Test_store_UpdateMetadata_mergesPartial(t *testing.T) {
 		t.Fatalf("Setup failed: %v", err)
 	}
 
-	rawMeta := json.RawMessage(`{"name":"Endgame","year":2004,"season":8,"episode":10}`)
+	rawMeta := model.MediaMetadata{Name: "Endgame", Year: 2004, Season: 8, Episode: 10}
 	item := model.Item{ID: "sg1", Name: "Stargate.SG-1.S08E10.mkv", Metadata: &rawMeta}
 	if err := s.store(item); err != nil {
 		t.Fatalf("store failed: %v", err)
@@ -666,7 +666,7 @@ func Test_store_UpdateMetadata_mergesPartial(t *testing.T) {
 
 	// The concierge completes the missing series name without re-supplying the
 	// rest of the classification.
-	err := s.UpdateMetadata(item, `{"showName":"Stargate SG-1"}`)
+	err := s.UpdateMetadata(item, `{"showName":"Stargate SG-1"}`, model.Provenance{Source: model.SourceAgent})
 	if err != nil {
 		t.Fatalf("UpdateMetadata failed: %v", err)
 	}
@@ -678,18 +678,15 @@ func Test_store_UpdateMetadata_mergesPartial(t *testing.T) {
 	if got.Metadata == nil {
 		t.Fatal("metadata is nil after merge")
 	}
-	var md map[string]any
-	if err := json.Unmarshal(*got.Metadata, &md); err != nil {
-		t.Fatalf("failed to unmarshal merged metadata: %v", err)
+	md := got.Metadata
+	if md.ShowName != "Stargate SG-1" {
+		t.Errorf("showName = %v, want %q", md.ShowName, "Stargate SG-1")
 	}
-	if md["showName"] != "Stargate SG-1" {
-		t.Errorf("showName = %v, want %q", md["showName"], "Stargate SG-1")
+	if md.Name != "Endgame" {
+		t.Errorf("name = %v, want %q — partial update must not drop existing fields", md.Name, "Endgame")
 	}
-	if md["name"] != "Endgame" {
-		t.Errorf("name = %v, want %q — partial update must not drop existing fields", md["name"], "Endgame")
-	}
-	if md["season"] != float64(8) || md["episode"] != float64(10) || md["year"] != float64(2004) {
-		t.Errorf("existing fields altered: %v", md)
+	if md.Season != 8 || md.Episode != 10 || md.Year != 2004 {
+		t.Errorf("existing fields altered: %+v", md)
 	}
 }
 
@@ -700,7 +697,7 @@ func Test_store_UpdateMetadata_invalidJSON(t *testing.T) {
 		t.Fatalf("Setup failed: %v", err)
 	}
 	item := model.Item{ID: "x", Name: "n"}
-	if err := s.UpdateMetadata(item, `{not json`); err == nil {
+	if err := s.UpdateMetadata(item, `{not json`, model.Provenance{Source: model.SourceAgent}); err == nil {
 		t.Fatal("expected error for invalid metadata JSON, got nil")
 	}
 }
@@ -757,6 +754,33 @@ func Test_store_Store(t *testing.T) {
 		}
 	})
 
+	t.Run("rescan refreshes mime type of existing item", func(t *testing.T) {
+		s := newTestStore(t)
+		p := path.Join(t.TempDir(), "movie.mkv")
+		if err := os.WriteFile(p, []byte("\x1a\x45\xdf\xa3matroska"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		raw := model.MediaMetadata{Name: "Movie"}
+		existing := model.Item{ID: generateID(p), Name: "movie.mkv", Path: p, MIMEType: "video/webm", Metadata: &raw}
+		s.cacheMu.Lock()
+		s.cache[existing.ID] = existing
+		s.cacheMu.Unlock()
+
+		if err := s.Store(context.Background(), model.Item{Name: "movie.mkv", Path: p, MIMEType: "video/x-matroska"}); err != nil {
+			t.Fatalf("Store failed: %v", err)
+		}
+		got, err := s.GetItemByID(existing.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.MIMEType != "video/x-matroska" {
+			t.Fatalf("expected mime type to be refreshed, got: %v", got.MIMEType)
+		}
+		if got.Metadata == nil {
+			t.Fatal("expected metadata to be kept")
+		}
+	})
+
 	t.Run("writes item to cache and file", func(t *testing.T) {
 		dir := t.TempDir()
 		s := NewStore(WithStorePath(dir))
@@ -1385,3 +1409,12 @@ func Test_startupWriteBatching(t *testing.T) {
 		}
 	})
 }
+
+func mdString(t *testing.T, md *model.MediaMetadata) string {
+	t.Helper()
+	b, err := json.Marshal(md)
+	if err != nil {
+		t.Fatalf("failed to marshal metadata: %v", err)
+	}
+	return string(b)
+}
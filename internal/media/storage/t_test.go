@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/baalimago/kinoview/internal/agents"
+	"github.com/baalimago/kinoview/internal/media/thumbnail"
 	"github.com/baalimago/kinoview/internal/model"
 )
 
@@ -73,15 +74,45 @@ func (m *mockResponseWriter) WriteHeader(statusCode int) {
 }
 
 type mockSubtitleManager struct {
-	shouldFail   bool
-	shouldReturn model.MediaInfo
+	shouldFail    bool
+	shouldReturn  model.MediaInfo
+	extractedPath string
+	// extractedPaths by stream index, over extractedPath
+	extractedPaths map[string]string
+	syncedPath     string
+	assPath        string
+	attachmentPath string
+}
+
+func (m *mockSubtitleManager) ExtractASS(item model.Item, streamIndex string) (string, error) {
+	if m.shouldFail {
+		return "", errors.New("whopsidops")
+	}
+	return m.assPath, nil
+}
+
+func (m *mockSubtitleManager) ExtractAttachment(item model.Item, streamIndex string) (string, error) {
+	if m.shouldFail {
+		return "", errors.New("whopsidops")
+	}
+	return m.attachmentPath, nil
+}
+
+func (m *mockSubtitleManager) SyncSubtitles(item model.Item, streamIndex string) (string, error) {
+	if m.shouldFail {
+		return "", errors.New("whopsidops")
+	}
+	return m.syncedPath, nil
 }
 
 func (m *mockSubtitleManager) ExtractSubtitles(item model.Item, streamIndex string) (string, error) {
 	if m.shouldFail {
 		return "", errors.New("whopsidops")
 	}
-	return "", nil
+	if p, ok := m.extractedPaths[streamIndex]; ok {
+		return p, nil
+	}
+	return m.extractedPath, nil
 }
 
 func (m *mockSubtitleManager) Find(item model.Item) (model.MediaInfo, error) {
@@ -93,7 +124,10 @@ func (m *mockSubtitleManager) Find(item model.Item) (model.MediaInfo, error) {
 
 func newTestStore(t *testing.T) *store {
 	t.Helper()
-	s := NewStore(WithStorePath(t.TempDir()))
+	s := NewStore(
+		WithStorePath(t.TempDir()),
+		WithThumbnailCache(thumbnail.NewCache(t.TempDir())),
+	)
 	s.classifier = &mockClassifier{
 		SetupFunc: func(ctx context.Context) error { return nil },
 		ClassifyFunc: func(ctx context.Context, i model.Item) (model.Item, error) {
@@ -102,3 +136,55 @@ func newTestStore(t *testing.T) *store {
 	}
 	return s
 }
+
+// mockBatchClassifier is a mockClassifier which also classifies in batches.
+type mockBatchClassifier struct {
+	mockClassifier
+	BatchFunc func(context.Context, []model.Item) ([]model.Item, error)
+}
+
+func (m *mockBatchClassifier) ClassifyBatch(ctx context.Context, items []model.Item) ([]model.Item, error) {
+	return m.BatchFunc(ctx, items)
+}
+
+func (m *mockBatchClassifier) Clone() agents.Classifier {
+	return m
+}
+
+// mockTagClassifier is a mockClassifier which also classifies tags alone.
+type mockTagClassifier struct {
+	mockClassifier
+	TagsFunc func(context.Context, model.Item) (model.Item, error)
+}
+
+func (m *mockTagClassifier) ClassifyTags(ctx context.Context, i model.Item) (model.Item, error) {
+	return m.TagsFunc(ctx, i)
+}
+
+func (m *mockTagClassifier) Clone() agents.Classifier {
+	return m
+}
+
+// mockMetadataProvider answers lookups from a fixed set of titles.
+type mockMetadataProvider struct {
+	titles []model.MediaMetadata
+}
+
+func (m *mockMetadataProvider) LookupTitle(title string, year int) ([]model.MediaMetadata, error) {
+	var ret []model.MediaMetadata
+	for _, md := range m.titles {
+		if (md.Name == title || md.ShowName == title) && (year == 0 || md.Year == year) {
+			ret = append(ret, md)
+		}
+	}
+	return ret, nil
+}
+
+func (m *mockMetadataProvider) LookupID(id string) (model.MediaMetadata, error) {
+	for _, md := range m.titles {
+		if md.IMDbID == id {
+			return md, nil
+		}
+	}
+	return model.MediaMetadata{}, model.ErrNoTitle
+}
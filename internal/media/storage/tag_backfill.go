@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/baalimago/go_away_boilerplate/pkg/ancli"
+	"github.com/baalimago/kinoview/internal/agents"
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+// tagBackfillInterval is the most often the backfill tags a video.
+const tagBackfillInterval = 5 * time.Second
+
+// needsTags reports whether i is a video whose tags were never asked for,
+// or by an older version. Videos waiting for review are left to whoever
+// reviews them.
+func needsTags(i model.Item) bool {
+	if !strings.Contains(i.MIMEType, "video") || i.Metadata == nil || i.Review != nil {
+		return false
+	}
+	return i.TagsVersion < model.TagsVersion
+}
+
+// backfillTags of the videos which need them, one at a time, by asking the
+// classifier for the tags alone. The backfill gives way to classification:
+// it only runs while nothing is queued and the station isn't cooling down,
+// and takes its turns from the same rate limiter. Videos which fail to be
+// tagged aren't tried again until the next start.
+func (s *store) backfillTags(ctx context.Context, interval time.Duration) {
+	s.classifierMu.RLock()
+	c := s.classifier.Clone()
+	s.classifierMu.RUnlock()
+	tc, ok := c.(agents.TagClassifier)
+	if !ok {
+		ancli.Noticef("classifier can't classify tags alone, not backfilling them")
+		return
+	}
+	if err := c.Setup(ctx); err != nil {
+		ancli.Warnf("failed to setup the classifier of the tag backfill: %v", err)
+		return
+	}
+
+	tried := map[string]struct{}{}
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+		}
+		if s.inCooldown() || s.queue.len() > 0 || s.memoryHigh() {
+			continue
+		}
+		i, ok := s.nextToTag(tried)
+		if !ok {
+			continue
+		}
+		if s.rateLimiter != nil && !s.rateLimiter.allow() {
+			continue
+		}
+		tried[i.ID] = struct{}{}
+		s.tagItem(ctx, c, tc, i)
+	}
+}
+
+// nextToTag is the video needing tags which hasn't been tried, by ID.
+func (s *store) nextToTag(tried map[string]struct{}) (model.Item, bool) {
+	s.cacheMu.RLock()
+	defer s.cacheMu.RUnlock()
+	var ids []string
+	for id, i := range s.cache {
+		if _, ok := tried[id]; !ok && needsTags(i) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return model.Item{}, false
+	}
+	return s.cache[slices.Min(ids)], true
+}
+
+// tagItem i with tc, c being the classifier it is. The item is marked as
+// tagged even if the classifier knew no tags, so it isn't asked again.
+func (s *store) tagItem(ctx context.Context, c agents.Classifier, tc agents.TagClassifier, i model.Item) {
+	classifyCtx, cancel := s.classificationContext(ctx)
+	tagged, err := tc.ClassifyTags(classifyCtx, i)
+	cancel()
+	if err != nil {
+		ancli.Warnf("failed to backfill the tags of %v: %v", i.Name, err)
+		return
+	}
+
+	// The item may have changed meanwhile, only the tags are taken. Locked
+	// until stored, so nothing changes it in between.
+	s.cacheMu.Lock()
+	current, ok := s.cache[i.ID]
+	if !ok || current.Metadata == nil {
+		s.cacheMu.Unlock()
+		return
+	}
+	before := current.Metadata
+	md := *current.Metadata
+	if tagged.Metadata != nil {
+		md.SetTags(*tagged.Metadata)
+	}
+	current.Metadata = &md
+	current.TagsVersion = model.TagsVersion
+	// Cloned, the cached item shares the map
+	confidence := maps.Clone(current.Confidence)
+	for _, key := range model.TagKeys {
+		v, ok := tagged.Confidence[key]
+		if !ok {
+			continue
+		}
+		if confidence == nil {
+			confidence = model.Confidence{}
+		}
+		confidence[key] = v
+	}
+	current.Confidence = confidence
+	err = s.storeLocked(current)
+	s.cacheMu.Unlock()
+	if err != nil {
+		ancli.Warnf("failed to store the tags of %v: %v", i.Name, err)
+		return
+	}
+	s.recordMetadata(before, current, classifiedProvenance(c, tagged), "tags backfilled")
+	ancli.Noticef("backfilled the tags of %v", i.Name)
+}
@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+func Test_needsTags(t *testing.T) {
+	tagged := model.MediaMetadata{Name: "Heat", Genre: "Crime", Mood: []string{"tense"}, AgeRating: "R"}
+	genreOnly := model.MediaMetadata{Name: "Heat", Genre: "Crime"}
+	untagged := model.MediaMetadata{Name: "Heat"}
+	tests := []struct {
+		name string
+		item model.Item
+		want bool
+	}{
+		{"untagged video", model.Item{MIMEType: "video/mp4", Metadata: &untagged}, true},
+		{"tagged video", model.Item{MIMEType: "video/mp4", Metadata: &tagged, TagsVersion: model.TagsVersion}, false},
+		{"tags unknown", model.Item{MIMEType: "video/mp4", Metadata: &untagged, TagsVersion: model.TagsVersion}, false},
+		{"tagged by an older version", model.Item{MIMEType: "video/mp4", Metadata: &tagged, TagsVersion: model.TagsVersion - 1}, true},
+		{"genre only", model.Item{MIMEType: "video/mp4", Metadata: &genreOnly}, true},
+		{"unclassified video", model.Item{MIMEType: "video/mp4"}, false},
+		{"in review", model.Item{MIMEType: "video/mp4", Metadata: &untagged, Review: &model.Review{}}, false},
+		{"not a video", model.Item{MIMEType: "audio/mpeg", Metadata: &untagged}, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := needsTags(tc.item); got != tc.want {
+				t.Errorf("needsTags = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func Test_store_backfillTags(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	s := newTestStore(t)
+	heat := model.MediaMetadata{Name: "Heat", Year: 1995}
+	failing := model.MediaMetadata{Name: "Failing"}
+	tagged := model.MediaMetadata{Name: "Tagged", Genre: "Drama", Mood: []string{"gloomy"}, AgeRating: "PG-13"}
+	s.cache["a-heat"] = model.Item{ID: "a-heat", Name: "heat.mkv", MIMEType: "video/mp4", Metadata: &heat, Confidence: model.Confidence{"name": 1}}
+	s.cache["b-failing"] = model.Item{ID: "b-failing", Name: "failing.mkv", MIMEType: "video/mp4", Metadata: &failing}
+	s.cache["c-tagged"] = model.Item{ID: "c-tagged", Name: "tagged.mkv", MIMEType: "video/mp4", Metadata: &tagged, TagsVersion: model.TagsVersion}
+	s.cache["d-unknown"] = model.Item{ID: "d-unknown", Name: "unknown.mkv", MIMEType: "video/mp4", Metadata: &model.MediaMetadata{Name: "Unknown"}}
+
+	var calls atomic.Int32
+	asked := make(chan string, 10)
+	s.classifier = &mockTagClassifier{TagsFunc: func(ctx context.Context, i model.Item) (model.Item, error) {
+		calls.Add(1)
+		asked <- i.ID
+		switch i.ID {
+		case "b-failing":
+			return model.Item{}, context.DeadlineExceeded
+		case "d-unknown":
+			return i, nil
+		}
+		md := *i.Metadata
+		md.Genre = "Crime"
+		md.Mood = []string{"tense"}
+		md.AgeRating = "R"
+		i.Metadata = &md
+		i.Confidence = model.Confidence{"genre": 0.9, "name": 0.1}
+		return i, nil
+	}}
+
+	done := make(chan struct{})
+	go func() {
+		s.backfillTags(ctx, time.Millisecond)
+		close(done)
+	}()
+	for _, want := range []string{"a-heat", "b-failing", "d-unknown"} {
+		select {
+		case got := <-asked:
+			if got != want {
+				t.Fatalf("expected %v to be tagged, got %v", want, got)
+			}
+		case <-ctx.Done():
+			t.Fatalf("timed out waiting for %v", want)
+		}
+	}
+	// Nothing else needs tags, and the failing one isn't tried again
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	<-done
+	if n := calls.Load(); n != 3 {
+		t.Fatalf("expected 3 calls, got %v", n)
+	}
+	// Not asked again after a restart, even knowing no tags
+	for id, want := range map[string]bool{"a-heat": false, "b-failing": true, "d-unknown": false} {
+		stored, err := readStoreItem(s.storePath, id)
+		if id == "b-failing" {
+			// Never stored, only cached
+			stored, err = s.GetItemByID(id)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := needsTags(stored); got != want {
+			t.Errorf("%v: needsTags = %v, want %v", id, got, want)
+		}
+	}
+
+	got, err := s.GetItemByID("a-heat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Metadata.Genre != "Crime" || got.Metadata.AgeRating != "R" || got.Metadata.Name != "Heat" {
+		t.Errorf("unexpected metadata: %+v", got.Metadata)
+	}
+	if got.Confidence["name"] != 1 || got.Confidence["genre"] != 0.9 {
+		t.Errorf("expected only the confidence of the tags to change, got %v", got.Confidence)
+	}
+	history, err := s.History("a-heat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) == 0 || history[len(history)-1].Note != "tags backfilled" {
+		t.Errorf("expected the backfill in the history, got %+v", history)
+	}
+}
+
+func Test_store_backfillTags_waitsForQueue(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	s := newTestStore(t)
+	md := model.MediaMetadata{Name: "Heat"}
+	s.cache["heat"] = model.Item{ID: "heat", Name: "heat.mkv", MIMEType: "video/mp4", Metadata: &md}
+	s.queue.push(queued("new"), model.PriorityNew)
+	s.classifier = &mockTagClassifier{TagsFunc: func(ctx context.Context, i model.Item) (model.Item, error) {
+		t.Error("expected nothing to be tagged while items wait for classification")
+		return i, nil
+	}}
+	s.backfillTags(ctx, time.Millisecond)
+}
@@ -0,0 +1,171 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/baalimago/go_away_boilerplate/pkg/ancli"
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+// assCodecs are the styled subtitle codecs which can be passed through as
+// they are, for a client side renderer.
+var assCodecs = map[string]bool{
+	"ass": true,
+	"ssa": true,
+}
+
+// ErrUnsupportedFormat is returned when a stream can't be served in the
+// requested format.
+var ErrUnsupportedFormat = errors.New("stream can't be served in that format")
+
+// subtitleFormats lists the formats a subtitle stream can be served as.
+func subtitleFormats(s model.Stream) []string {
+	codec := strings.ToLower(s.CodecName)
+	switch {
+	case bitmapCodecs[codec] && !s.OCR:
+		return nil
+	case assCodecs[codec] && s.ExternalPath == "":
+		return []string{model.FormatVTT, model.FormatASS}
+	default:
+		return []string{model.FormatVTT}
+	}
+}
+
+// markFormats sets the formats of the subtitle streams of info.
+func markFormats(info model.MediaInfo) {
+	for i, s := range info.Streams {
+		if s.CodecType == "subtitle" {
+			info.Streams[i].Formats = subtitleFormats(s)
+		}
+	}
+}
+
+// ExtractASS extracts the ASS/SSA subtitle stream at streamIndex as is, with
+// its styling, positioning and typesetting intact. Like ExtractSubtitles,
+// the result is kept on file.
+func (m *Manager) ExtractASS(item model.Item, streamIndex string) (string, error) {
+	destPath := filepath.Join(m.storePath, fmt.Sprintf("%s_%s.ass", item.ID, streamIndex))
+	if m.cached(destPath, item.Path) {
+		return destPath, nil
+	}
+
+	st, ok := m.embeddedStream(item, streamIndex)
+	if !ok {
+		return "", fmt.Errorf("%w: no embedded stream %s in %s", ErrUnsupportedFormat, streamIndex, item.Name)
+	}
+	if !assCodecs[strings.ToLower(st.CodecName)] {
+		return "", fmt.Errorf("%w: stream %s is %s, not ass", ErrUnsupportedFormat, streamIndex, st.CodecName)
+	}
+
+	defer m.lockExtraction(destPath)()
+	if m.cached(destPath, item.Path) {
+		return destPath, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	args := []string{
+		"-y",
+		"-i", item.Path,
+		"-map", "0:" + streamIndex,
+		"-c:s", "copy",
+		"-f", "ass",
+		partialPath(destPath),
+	}
+	if m.debug {
+		ancli.Noticef("Extracting ASS subtitle %s for %s. Command:\nffmpeg %v", streamIndex, item.Name, strings.Join(args, " "))
+	}
+	if err := m.runner.Run(ctx, "ffmpeg", args...); err != nil {
+		os.Remove(partialPath(destPath))
+		return "", fmt.Errorf("ffmpeg extraction failed: %w", err)
+	}
+	if err := m.commit(destPath); err != nil {
+		return "", fmt.Errorf("ffmpeg extraction of stream %s failed: %w", streamIndex, err)
+	}
+	return destPath, nil
+}
+
+// ExtractAttachment extracts the attachment stream at streamIndex, typically
+// a font used by the ASS subtitles of a Matroska file. The file keeps its
+// attached name, after the item ID and stream index.
+func (m *Manager) ExtractAttachment(item model.Item, streamIndex string) (string, error) {
+	st, ok := m.embeddedStream(item, streamIndex)
+	if !ok || st.CodecType != "attachment" {
+		return "", fmt.Errorf("%w: stream %s of %s is not an attachment", ErrUnsupportedFormat, streamIndex, item.Name)
+	}
+	name := filepath.Base(st.Tags.Filename)
+	if name == "." || name == string(filepath.Separator) {
+		name = "attachment"
+	}
+	destPath := filepath.Join(m.storePath, fmt.Sprintf("%s_%s_%s", item.ID, streamIndex, name))
+	if m.cached(destPath, item.Path) {
+		return destPath, nil
+	}
+
+	defer m.lockExtraction(destPath)()
+	if m.cached(destPath, item.Path) {
+		return destPath, nil
+	}
+	partPath := partialPath(destPath)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	// ffmpeg insists on an output, even if all we want are the
+	// attachments, so decode nothing into nowhere.
+	args := []string{
+		"-y",
+		"-dump_attachment:" + streamIndex, partPath,
+		"-i", item.Path,
+		"-t", "0",
+		"-f", "null", "-",
+	}
+	if err := m.runner.Run(ctx, "ffmpeg", args...); err != nil {
+		// The null output fails on files without audio or video, after
+		// the attachment has been dumped.
+		if _, statErr := os.Stat(partPath); statErr != nil {
+			return "", fmt.Errorf("ffmpeg attachment dump failed: %w", err)
+		}
+	}
+	if err := m.commit(destPath); err != nil {
+		return "", fmt.Errorf("ffmpeg attachment dump failed: %w", err)
+	}
+	return destPath, nil
+}
+
+// ShiftASS returns the ASS script in data with every event moved by offset.
+// Events which would start before the beginning are clamped to it.
+func ShiftASS(data []byte, offset time.Duration) []byte {
+	lines := strings.SplitAfter(string(data), "\n")
+	for i, l := range lines {
+		kind, rest, ok := strings.Cut(l, ":")
+		if !ok || (kind != "Dialogue" && kind != "Comment") {
+			continue
+		}
+		// Layer, Start, End and everything else
+		fields := strings.SplitN(rest, ",", 4)
+		if len(fields) < 4 {
+			continue
+		}
+		start, errS := parseVTTTime(strings.TrimSpace(fields[1]))
+		end, errE := parseVTTTime(strings.TrimSpace(fields[2]))
+		if errS != nil || errE != nil {
+			continue
+		}
+		fields[1] = formatASSTime(max(start+offset, 0))
+		fields[2] = formatASSTime(max(end+offset, 0))
+		lines[i] = kind + ":" + strings.Join(fields, ",")
+	}
+	return []byte(strings.Join(lines, ""))
+}
+
+// formatASSTime formats d as H:MM:SS.cc, ASS timestamps being centiseconds.
+func formatASSTime(d time.Duration) string {
+	cs := d.Milliseconds() / 10
+	return fmt.Sprintf("%d:%02d:%02d.%02d", cs/360_000, cs/6000%60, cs/100%60, cs%100)
+}
@@ -0,0 +1,133 @@
+package stream
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+func TestMarkFormats(t *testing.T) {
+	info := model.MediaInfo{Streams: []model.Stream{
+		{Index: 0, CodecType: "video", CodecName: "h264"},
+		{Index: 1, CodecType: "subtitle", CodecName: "subrip"},
+		{Index: 2, CodecType: "subtitle", CodecName: "ass"},
+		{Index: 3, CodecType: "subtitle", CodecName: "hdmv_pgs_subtitle"},
+		{Index: 4, CodecType: "subtitle", CodecName: "dvd_subtitle", OCR: true},
+		{Index: 5, CodecType: "subtitle", CodecName: "ass", ExternalPath: "/media/vid.ass"},
+	}}
+	markFormats(info)
+	want := map[int][]string{
+		1: {model.FormatVTT},
+		2: {model.FormatVTT, model.FormatASS},
+		4: {model.FormatVTT},
+		5: {model.FormatVTT},
+	}
+	for _, s := range info.Streams {
+		if !slices.Equal(s.Formats, want[s.Index]) {
+			t.Errorf("stream %d (%s): formats = %v, want %v", s.Index, s.CodecName, s.Formats, want[s.Index])
+		}
+	}
+}
+
+func TestExtractASS(t *testing.T) {
+	tmp := t.TempDir()
+	var calls int
+	runner := &mockRunner{
+		runCallback: func(name string, args ...string) error {
+			calls++
+			if !slices.Contains(args, "copy") {
+				t.Errorf("expected the stream to be copied, got: %v", args)
+			}
+			return os.WriteFile(args[len(args)-1], []byte("[Script Info]\n\n[Events]\nDialogue: 0,0:00:01.00,0:00:02.00,Default,,0,0,0,,Hi\n"), 0o644)
+		},
+	}
+	m, err := NewManager(WithStoragePath(tmp), withRunner(runner))
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := model.Item{ID: "vid", Name: "vid.mkv", Path: "/media/vid.mkv"}
+	m.mediaCache[item.ID] = model.MediaInfo{Streams: []model.Stream{
+		{Index: 2, CodecType: "subtitle", CodecName: "ass"},
+		{Index: 3, CodecType: "subtitle", CodecName: "subrip"},
+	}}
+
+	p, err := m.ExtractASS(item, "2")
+	if err != nil {
+		t.Fatalf("ExtractASS: %v", err)
+	}
+	if p != filepath.Join(tmp, "vid_2.ass") {
+		t.Errorf("unexpected path: %v", p)
+	}
+	if _, err := m.ExtractASS(item, "2"); err != nil || calls != 1 {
+		t.Errorf("expected the kept file to be reused, calls: %d, err: %v", calls, err)
+	}
+
+	for _, idx := range []string{"3", "9"} {
+		if _, err := m.ExtractASS(item, idx); !errors.Is(err, ErrUnsupportedFormat) {
+			t.Errorf("stream %v: expected ErrUnsupportedFormat, got: %v", idx, err)
+		}
+	}
+}
+
+func TestExtractAttachment(t *testing.T) {
+	tmp := t.TempDir()
+	runner := &mockRunner{
+		runCallback: func(name string, args ...string) error {
+			i := slices.Index(args, "-dump_attachment:4")
+			if i < 0 {
+				t.Fatalf("expected attachment 4 to be dumped, got: %v", args)
+			}
+			if err := os.WriteFile(args[i+1], []byte("font"), 0o644); err != nil {
+				return err
+			}
+			// As ffmpeg does on files without a decodable stream
+			return errors.New("output file is empty")
+		},
+	}
+	m, err := NewManager(WithStoragePath(tmp), withRunner(runner))
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := model.Item{ID: "vid", Name: "vid.mkv", Path: "/media/vid.mkv"}
+	m.mediaCache[item.ID] = model.MediaInfo{Streams: []model.Stream{
+		{Index: 2, CodecType: "subtitle", CodecName: "ass"},
+		{Index: 4, CodecType: "attachment", Tags: model.Tags{Filename: "../Fancy.ttf", MIMEType: "font/ttf"}},
+	}}
+
+	p, err := m.ExtractAttachment(item, "4")
+	if err != nil {
+		t.Fatalf("ExtractAttachment: %v", err)
+	}
+	if p != filepath.Join(tmp, "vid_4_Fancy.ttf") {
+		t.Errorf("unexpected path: %v", p)
+	}
+	if _, err := m.ExtractAttachment(item, "2"); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("expected ErrUnsupportedFormat for a subtitle stream, got: %v", err)
+	}
+}
+
+func TestShiftASS(t *testing.T) {
+	in := strings.Join([]string{
+		"[Events]",
+		"Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text",
+		"Dialogue: 0,0:00:01.00,0:00:03.50,Default,,0,0,0,,Hi, there",
+		"Comment: 0,1:59:59.99,2:00:01.00,Default,,0,0,0,,note",
+		"",
+	}, "\n")
+	want := strings.Join([]string{
+		"[Events]",
+		"Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text",
+		"Dialogue: 0,0:00:00.00,0:00:02.00,Default,,0,0,0,,Hi, there",
+		"Comment: 0,1:59:58.49,1:59:59.50,Default,,0,0,0,,note",
+		"",
+	}, "\n")
+	if got := string(ShiftASS([]byte(in), -1500*time.Millisecond)); got != want {
+		t.Errorf("ShiftASS:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,321 @@
+package stream
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/baalimago/go_away_boilerplate/pkg/ancli"
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+const (
+	// DefaultCacheLimit is the size the extracted subtitles, fonts and
+	// synced subtitles may take up before the least recently used ones are
+	// evicted.
+	DefaultCacheLimit int64 = 256 << 20
+	// partialSuffix marks files which are still being written. They're
+	// renamed into place once complete and valid.
+	partialSuffix = ".part"
+	// staleAfter is when leftover partial files and OCR frame directories
+	// are assumed to belong to a crashed extraction.
+	staleAfter = time.Hour
+)
+
+// ErrInvalidSubtitles is returned when an extraction produces a file which
+// doesn't hold any subtitles, such as after ffmpeg crashed halfway.
+var ErrInvalidSubtitles = errors.New("invalid subtitles")
+
+// WithCacheLimit sets the size, in bytes, the extracted files may take up in
+// the storage path, see DefaultCacheLimit. Zero or less disables the limit.
+func WithCacheLimit(bytes int64) Option {
+	return func(m *Manager) {
+		m.cacheLimit = bytes
+	}
+}
+
+// validator checks the contents of an extracted file.
+type validator func(data []byte) error
+
+// validVTT requires a WebVTT header and at least one cue.
+func validVTT(data []byte) error {
+	if !bytes.HasPrefix(bytes.TrimPrefix(data, []byte("\ufeff")), []byte("WEBVTT")) {
+		return fmt.Errorf("%w: missing WEBVTT header", ErrInvalidSubtitles)
+	}
+	if len(parseCues(data)) == 0 {
+		return fmt.Errorf("%w: no cues", ErrInvalidSubtitles)
+	}
+	return nil
+}
+
+// validASS requires an events section with at least one event.
+func validASS(data []byte) error {
+	if !bytes.Contains(data, []byte("[Events]")) {
+		return fmt.Errorf("%w: missing [Events] section", ErrInvalidSubtitles)
+	}
+	if !bytes.Contains(data, []byte("\nDialogue:")) {
+		return fmt.Errorf("%w: no dialogue", ErrInvalidSubtitles)
+	}
+	return nil
+}
+
+// validNonEmpty accepts anything but an empty file.
+func validNonEmpty(data []byte) error {
+	if len(data) == 0 {
+		return fmt.Errorf("%w: empty file", ErrInvalidSubtitles)
+	}
+	return nil
+}
+
+// validatorFor returns the validator of a cached file, by its extension.
+func validatorFor(path string) validator {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".vtt":
+		return validVTT
+	case ".ass", ".ssa":
+		return validASS
+	default:
+		return validNonEmpty
+	}
+}
+
+// cached reports if destPath holds a valid extraction which is newer than all
+// of sources. A usable file is touched, as its modification time is what the
+// cache limit evicts by. Anything else at destPath is removed, so that it's
+// extracted anew.
+func (m *Manager) cached(destPath string, sources ...string) bool {
+	fi, err := os.Stat(destPath)
+	if err != nil {
+		return false
+	}
+	if err := m.check(destPath, fi, sources...); err != nil {
+		if m.debug {
+			ancli.Noticef("Discarding cached '%s': %v", destPath, err)
+		}
+		os.Remove(destPath)
+		return false
+	}
+	now := time.Now()
+	os.Chtimes(destPath, now, now)
+	return true
+}
+
+// check the cached file at p, returning why it's no longer usable.
+func (m *Manager) check(p string, fi os.FileInfo, sources ...string) error {
+	for _, src := range sources {
+		// A source which is gone can't be extracted from again either, so
+		// the cached file is as good as it gets.
+		if sfi, err := os.Stat(src); err == nil && sfi.ModTime().After(fi.ModTime()) {
+			return fmt.Errorf("'%s' changed since", src)
+		}
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return err
+	}
+	return validatorFor(p)(data)
+}
+
+// partialPath is where the extraction to destPath is written until it's done.
+func partialPath(destPath string) string {
+	return destPath + partialSuffix
+}
+
+// commit validates the completed extraction at partialPath(destPath) and
+// moves it into place, then evicts whatever exceeds the cache limit. Invalid
+// extractions are removed.
+func (m *Manager) commit(destPath string) error {
+	part := partialPath(destPath)
+	data, err := os.ReadFile(part)
+	if err != nil {
+		return fmt.Errorf("failed to read extraction: %w", err)
+	}
+	if err := validatorFor(destPath)(data); err != nil {
+		os.Remove(part)
+		return err
+	}
+	if err := os.Rename(part, destPath); err != nil {
+		os.Remove(part)
+		return fmt.Errorf("failed to move extraction into place: %w", err)
+	}
+	if err := m.evict(destPath); err != nil {
+		ancli.Warnf("failed to enforce subtitle cache limit: %v", err)
+	}
+	return nil
+}
+
+type cacheEntry struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// cacheEntries lists the files in the storage path, oldest first. Partial
+// files and OCR frame directories are left out.
+func (m *Manager) cacheEntries() ([]cacheEntry, error) {
+	dirEntries, err := os.ReadDir(m.storePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subtitle cache: %w", err)
+	}
+	var entries []cacheEntry
+	for _, e := range dirEntries {
+		if !e.Type().IsRegular() || strings.HasSuffix(e.Name(), partialSuffix) {
+			continue
+		}
+		fi, err := e.Info()
+		if err != nil {
+			continue
+		}
+		entries = append(entries, cacheEntry{
+			path:    filepath.Join(m.storePath, e.Name()),
+			size:    fi.Size(),
+			modTime: fi.ModTime(),
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].modTime.Before(entries[j].modTime) })
+	return entries, nil
+}
+
+// evict the least recently used files until the storage path is within the
+// cache limit. keep is never evicted, as it's about to be served.
+func (m *Manager) evict(keep string) error {
+	entries, err := m.cacheEntries()
+	if err != nil {
+		return err
+	}
+	_, err = m.evictEntries(entries, keep)
+	return err
+}
+
+func (m *Manager) evictEntries(entries []cacheEntry, keep string) (GCStats, error) {
+	var stats GCStats
+	if m.cacheLimit <= 0 {
+		return stats, nil
+	}
+	var total int64
+	for _, e := range entries {
+		total += e.size
+	}
+	for _, e := range entries {
+		if total <= m.cacheLimit {
+			break
+		}
+		if e.path == keep {
+			continue
+		}
+		if err := os.Remove(e.path); err != nil && !os.IsNotExist(err) {
+			return stats, fmt.Errorf("failed to evict '%s': %w", e.path, err)
+		}
+		total -= e.size
+		stats.Removed++
+		stats.Freed += e.size
+	}
+	return stats, nil
+}
+
+// GCStats summarises a GC run.
+type GCStats struct {
+	// Removed files and directories, and the bytes they took up.
+	Removed int
+	Freed   int64
+	// Size of the cache afterwards.
+	Size int64
+}
+
+// GC cleans up the storage path. It removes partial files and OCR frames
+// left by crashed extractions, extractions which are invalid or older than
+// the media they're from, and, if items isn't nil, those of media no longer
+// in items. Then the cache limit is enforced.
+func (m *Manager) GC(items []model.Item) (GCStats, error) {
+	var byID map[string]model.Item
+	if items != nil {
+		byID = make(map[string]model.Item, len(items))
+		for _, i := range items {
+			byID[i.ID] = i
+		}
+	}
+
+	dirEntries, err := os.ReadDir(m.storePath)
+	if err != nil {
+		return GCStats{}, fmt.Errorf("failed to list subtitle cache: %w", err)
+	}
+	var stats GCStats
+	remove := func(p string, size int64, why string) {
+		if m.debug {
+			ancli.Noticef("Removing '%s': %s", p, why)
+		}
+		if err := os.RemoveAll(p); err != nil {
+			ancli.Warnf("failed to remove '%s': %v", p, err)
+			return
+		}
+		stats.Removed++
+		stats.Freed += size
+	}
+	for _, e := range dirEntries {
+		p := filepath.Join(m.storePath, e.Name())
+		fi, err := e.Info()
+		if err != nil {
+			continue
+		}
+		stale := time.Since(fi.ModTime()) > staleAfter
+		if e.IsDir() {
+			if strings.Contains(e.Name(), "_ocr-") && stale {
+				remove(p, 0, "leftover OCR frames")
+			}
+			continue
+		}
+		if strings.HasSuffix(e.Name(), partialSuffix) {
+			if stale {
+				remove(p, fi.Size(), "leftover partial extraction")
+			}
+			continue
+		}
+		id, _, ok := strings.Cut(e.Name(), "_")
+		if !ok {
+			continue
+		}
+		item, known := byID[id]
+		if byID != nil && !known {
+			remove(p, fi.Size(), "media no longer indexed")
+			continue
+		}
+		var sources []string
+		if known {
+			sources = append(sources, item.Path)
+		}
+		if err := m.check(p, fi, sources...); err != nil {
+			remove(p, fi.Size(), err.Error())
+		}
+	}
+
+	entries, err := m.cacheEntries()
+	if err != nil {
+		return stats, err
+	}
+	evicted, err := m.evictEntries(entries, "")
+	stats.Removed += evicted.Removed
+	stats.Freed += evicted.Freed
+	for _, e := range entries {
+		stats.Size += e.size
+	}
+	stats.Size -= evicted.Freed
+	return stats, err
+}
+
+// sourcePath returns the file the subtitle stream at streamIndex is read
+// from: the sidecar file of external streams, the media itself otherwise.
+// Sidecar files are only known once item has been probed.
+func (m *Manager) sourcePath(item model.Item, streamIndex string) string {
+	if idx, err := strconv.Atoi(streamIndex); err == nil && idx < 0 {
+		if p, err := m.findExternalPath(item, idx); err == nil {
+			return p
+		}
+	}
+	return item.Path
+}
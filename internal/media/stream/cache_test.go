@@ -0,0 +1,167 @@
+package stream
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+// writeAged writes data to p and backdates it by age.
+func writeAged(t *testing.T, p, data string, age time.Duration) {
+	t.Helper()
+	if err := os.WriteFile(p, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	at := time.Now().Add(-age)
+	if err := os.Chtimes(p, at, at); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func exists(p string) bool {
+	_, err := os.Stat(p)
+	return err == nil
+}
+
+func TestExtractSubtitles_Revalidates(t *testing.T) {
+	tmp := t.TempDir()
+	var runs int
+	mr := &mockRunner{
+		runCallback: func(name string, args ...string) error {
+			runs++
+			return os.WriteFile(args[len(args)-1], []byte(testVTT), 0o644)
+		},
+	}
+	m, _ := NewManager(withRunner(mr), WithStoragePath(tmp))
+	item := model.Item{ID: "vid", Name: "vid.mkv", Path: filepath.Join(tmp, "vid.mkv")}
+	dest := filepath.Join(tmp, "vid_2.vtt")
+
+	// Left by a crashed ffmpeg
+	writeAged(t, item.Path, "video", time.Hour)
+	writeAged(t, dest, "", 0)
+	if _, err := m.ExtractSubtitles(item, "2"); err != nil {
+		t.Fatal(err)
+	}
+	if runs != 1 {
+		t.Fatalf("expected an empty file to be extracted anew, runs: %d", runs)
+	}
+	if exists(partialPath(dest)) {
+		t.Error("partial file left behind")
+	}
+
+	if _, err := m.ExtractSubtitles(item, "2"); err != nil || runs != 1 {
+		t.Fatalf("expected a valid file to be reused, runs: %d, err: %v", runs, err)
+	}
+
+	// The media is replaced
+	writeAged(t, dest, testVTT, time.Minute)
+	writeAged(t, item.Path, "other video", 0)
+	if _, err := m.ExtractSubtitles(item, "2"); err != nil || runs != 2 {
+		t.Fatalf("expected a changed source to be extracted anew, runs: %d, err: %v", runs, err)
+	}
+}
+
+func TestExtractSubtitles_InvalidExtraction(t *testing.T) {
+	tmp := t.TempDir()
+	mr := &mockRunner{
+		runCallback: func(name string, args ...string) error {
+			// Truncated before the first cue
+			return os.WriteFile(args[len(args)-1], []byte("WEBVTT\n\n00:00:01.0"), 0o644)
+		},
+	}
+	m, _ := NewManager(withRunner(mr), WithStoragePath(tmp))
+	item := model.Item{ID: "vid", Name: "vid.mkv", Path: "/media/vid.mkv"}
+
+	_, err := m.ExtractSubtitles(item, "2")
+	if !errors.Is(err, ErrInvalidSubtitles) {
+		t.Fatalf("expected ErrInvalidSubtitles, got: %v", err)
+	}
+	dest := filepath.Join(tmp, "vid_2.vtt")
+	if exists(dest) || exists(partialPath(dest)) {
+		t.Error("invalid extraction was kept")
+	}
+}
+
+func TestCommit_EvictsLeastRecentlyUsed(t *testing.T) {
+	tmp := t.TempDir()
+	m, _ := NewManager(withRunner(&mockRunner{}), WithStoragePath(tmp), WithCacheLimit(2*int64(len(testVTT))))
+	oldest := filepath.Join(tmp, "a_1.vtt")
+	older := filepath.Join(tmp, "b_1.vtt")
+	writeAged(t, oldest, testVTT, 2*time.Hour)
+	writeAged(t, older, testVTT, time.Hour)
+
+	// Using it makes it the most recent
+	if !m.cached(oldest) {
+		t.Fatal("expected a valid cached file")
+	}
+
+	dest := filepath.Join(tmp, "c_1.vtt")
+	writeAged(t, partialPath(dest), testVTT, 0)
+	if err := m.commit(dest); err != nil {
+		t.Fatal(err)
+	}
+	if !exists(dest) || !exists(oldest) || exists(older) {
+		t.Errorf("expected only %s to be evicted", older)
+	}
+}
+
+func TestGC(t *testing.T) {
+	tmp := t.TempDir()
+	media := t.TempDir()
+	m, _ := NewManager(withRunner(&mockRunner{}), WithStoragePath(tmp), WithCacheLimit(0))
+
+	kept := model.Item{ID: "kept", Path: filepath.Join(media, "kept.mkv")}
+	changed := model.Item{ID: "changed", Path: filepath.Join(media, "changed.mkv")}
+	writeAged(t, kept.Path, "video", time.Hour)
+	writeAged(t, changed.Path, "video", 0)
+
+	files := map[string]bool{
+		"kept_2.vtt":             true,
+		"kept_2.ass":             false, // invalid
+		"kept_3.vtt.part":        true,  // might still be extracting
+		"kept_4.vtt.part":        false, // crashed
+		"changed_2.vtt":          false,
+		"gone_2.vtt":             false,
+		"kept_5_font.ttf":        true,
+		"kept_6.synced.vtt":      true,
+		"kept_7_ocr-123/001.png": false,
+	}
+	for name := range files {
+		p := filepath.Join(tmp, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		data := testVTT
+		switch filepath.Ext(name) {
+		case ".ass":
+			data = "[Script Info]\n"
+		case ".ttf":
+			data = "font"
+		}
+		age := time.Minute
+		if name == "kept_4.vtt.part" {
+			age = 2 * time.Hour
+		}
+		writeAged(t, p, data, age)
+	}
+	ocrDir := filepath.Join(tmp, "kept_7_ocr-123")
+	at := time.Now().Add(-2 * time.Hour)
+	os.Chtimes(ocrDir, at, at)
+
+	stats, err := m.GC([]model.Item{kept, changed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range files {
+		if got := exists(filepath.Join(tmp, name)); got != want {
+			t.Errorf("%s: exists = %v, want %v", name, got, want)
+		}
+	}
+	if stats.Removed != 5 {
+		t.Errorf("expected 5 removals, got: %+v", stats)
+	}
+}
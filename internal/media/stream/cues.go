@@ -0,0 +1,57 @@
+package stream
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Cue is a subtitle cue and its text.
+type Cue struct {
+	Start, End time.Duration
+	// Text of the cue, its lines joined by "\n", with the markup stripped.
+	Text string
+}
+
+// cueMarkup matches WebVTT tags, such as "<i>" and "<c.yellow>", and the ASS
+// override blocks some SubRip files carry, such as "{\an8}".
+var cueMarkup = regexp.MustCompile(`<[^>]*>|\{\\[^}]*\}`)
+
+// ReadCues reads the cues of a WebVTT or SubRip subtitle, ordered by start.
+// Cues without text are skipped.
+func ReadCues(data []byte) []Cue {
+	var cues []Cue
+	var cur *Cue
+	var text []string
+	flush := func() {
+		if cur != nil && len(text) > 0 {
+			cur.Text = strings.Join(text, "\n")
+			cues = append(cues, *cur)
+		}
+		cur, text = nil, nil
+	}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if start, end, _, ok := parseTimingLine(line); ok {
+			flush()
+			cur = &Cue{Start: start, End: end}
+			continue
+		}
+		if line == "" {
+			flush()
+			continue
+		}
+		if cur != nil {
+			if t := strings.TrimSpace(cueMarkup.ReplaceAllString(line, "")); t != "" {
+				text = append(text, t)
+			}
+		}
+	}
+	flush()
+	sort.SliceStable(cues, func(i, j int) bool { return cues[i].Start < cues[j].Start })
+	return cues
+}
@@ -0,0 +1,21 @@
+package stream
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReadCues(t *testing.T) {
+	in := "WEBVTT\n\nNOTE a comment\n\n2\n00:00:05.000 --> 00:00:06.000 align:start\n<i>Second</i>\n\n" +
+		"1\n00:00:01,000 --> 00:00:02,500\n{\\an8}First line\n- and a second\n\n3\n00:00:07.000 --> 00:00:08.000\n\n"
+	cues := ReadCues([]byte(in))
+	if len(cues) != 2 {
+		t.Fatalf("expected 2 cues, got: %+v", cues)
+	}
+	if c := cues[0]; c.Start != time.Second || c.End != 2500*time.Millisecond || c.Text != "First line\n- and a second" {
+		t.Errorf("unexpected first cue: %+v", c)
+	}
+	if c := cues[1]; c.Start != 5*time.Second || c.Text != "Second" {
+		t.Errorf("unexpected second cue: %+v", c)
+	}
+}
@@ -0,0 +1,144 @@
+package stream
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/baalimago/kinoview/internal/lang"
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+// Cue classes of the subtitles merged by MergeVTT.
+const (
+	DualPrimaryClass   = "primary"
+	DualSecondaryClass = "secondary"
+)
+
+// dualStyle sets the secondary subtitles apart from the primary ones, for
+// players honouring WebVTT style blocks.
+const dualStyle = `STYLE
+::cue(.` + DualPrimaryClass + `) {
+  color: #ffffff;
+}
+::cue(.` + DualSecondaryClass + `) {
+  color: #ffe08a;
+  font-size: 85%;
+}
+`
+
+// MergeVTT merges two subtitles into one WebVTT, each cue stacking the
+// primary line over the secondary one, in the cue classes DualPrimaryClass
+// and DualSecondaryClass. The cues are split wherever either subtitle
+// changes, so that both show for as long as they would on their own.
+// Markup of the original cues is dropped.
+func MergeVTT(primary, secondary []byte) []byte {
+	pCues, sCues := ReadCues(primary), ReadCues(secondary)
+
+	var bounds []time.Duration
+	for _, c := range slices.Concat(pCues, sCues) {
+		bounds = append(bounds, c.Start, c.End)
+	}
+	slices.Sort(bounds)
+	bounds = slices.Compact(bounds)
+
+	type merged struct {
+		start, end time.Duration
+		p, s       string
+	}
+	var out []merged
+	for i := 0; i+1 < len(bounds); i++ {
+		start, end := bounds[i], bounds[i+1]
+		p, s := activeText(pCues, start, end), activeText(sCues, start, end)
+		if p == "" && s == "" {
+			continue
+		}
+		if n := len(out); n > 0 && out[n-1].end == start && out[n-1].p == p && out[n-1].s == s {
+			out[n-1].end = end
+			continue
+		}
+		out = append(out, merged{start, end, p, s})
+	}
+
+	var b bytes.Buffer
+	b.WriteString("WEBVTT\n\n" + dualStyle)
+	for i, c := range out {
+		fmt.Fprintf(&b, "\n%d\n%s --> %s\n", i+1, formatVTTTime(c.start), formatVTTTime(c.end))
+		if c.p != "" {
+			fmt.Fprintf(&b, "<c.%s>%s</c>\n", DualPrimaryClass, c.p)
+		}
+		if c.s != "" {
+			fmt.Fprintf(&b, "<c.%s>%s</c>\n", DualSecondaryClass, c.s)
+		}
+	}
+	return b.Bytes()
+}
+
+// activeText joins the text of the cues showing throughout start to end.
+func activeText(cues []Cue, start, end time.Duration) string {
+	var text []string
+	for _, c := range cues {
+		if c.Start >= end {
+			break
+		}
+		if c.Start <= start && c.End >= end {
+			text = append(text, c.Text)
+		}
+	}
+	return strings.Join(text, "\n")
+}
+
+// dualPairs lists the pairs of subtitle streams in different languages, for
+// MergeVTT. Each language is represented by one stream: text over OCR, full
+// subtitles over those for the hearing impaired, default over not. Forced
+// and commentary streams are left out, as are those of unknown language.
+func dualPairs(streams []model.Stream) []model.DualPair {
+	rank := func(s model.Stream) int {
+		r := 0
+		if s.OCR {
+			r += 4
+		}
+		if s.Disposition.HearingImpaired == 1 {
+			r += 2
+		}
+		if s.Disposition.Default != 1 {
+			r++
+		}
+		return r
+	}
+
+	var langs []string
+	best := make(map[string]model.Stream)
+	for _, s := range streams {
+		if s.CodecType != "subtitle" || !slices.Contains(s.Formats, model.FormatVTT) ||
+			s.Disposition.Forced == 1 || s.Disposition.Comment == 1 {
+			continue
+		}
+		l := lang.Normalize(s.Tags.Language)
+		if l == "" {
+			continue
+		}
+		cur, ok := best[l]
+		if !ok {
+			langs = append(langs, l)
+		}
+		if !ok || rank(s) < rank(cur) {
+			best[l] = s
+		}
+	}
+
+	var pairs []model.DualPair
+	for i, a := range langs {
+		for _, b := range langs[i+1:] {
+			pairs = append(pairs, model.DualPair{
+				Primary:           best[a].Index,
+				Secondary:         best[b].Index,
+				PrimaryLanguage:   a,
+				SecondaryLanguage: b,
+			})
+		}
+	}
+	return pairs
+}
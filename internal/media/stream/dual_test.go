@@ -0,0 +1,53 @@
+package stream
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+func TestMergeVTT(t *testing.T) {
+	primary := "WEBVTT\n\n00:00:01.000 --> 00:00:04.000\n<i>Moshi moshi</i>\n\n00:00:10.000 --> 00:00:11.000\nSayounara\n"
+	secondary := "WEBVTT\n\n00:00:02.000 --> 00:00:03.000\nHello?\n\n00:00:03.000 --> 00:00:04.000\nHello?\n\n00:00:12.000 --> 00:00:13.000\nBye\n"
+	got := string(MergeVTT([]byte(primary), []byte(secondary)))
+
+	if !strings.HasPrefix(got, "WEBVTT\n\nSTYLE\n::cue(.primary)") {
+		t.Errorf("expected a header and style block, got:\n%s", got)
+	}
+	_, cues, _ := strings.Cut(got, "}\n\n")
+	want := strings.Join([]string{
+		"1\n00:00:01.000 --> 00:00:02.000\n<c.primary>Moshi moshi</c>\n",
+		// Identical neighbours are joined
+		"2\n00:00:02.000 --> 00:00:04.000\n<c.primary>Moshi moshi</c>\n<c.secondary>Hello?</c>\n",
+		"3\n00:00:10.000 --> 00:00:11.000\n<c.primary>Sayounara</c>\n",
+		"4\n00:00:12.000 --> 00:00:13.000\n<c.secondary>Bye</c>\n",
+	}, "\n")
+	if cues != want {
+		t.Errorf("cues:\n%s\nwant:\n%s", cues, want)
+	}
+	if len(parseCues([]byte(got))) != 4 {
+		t.Error("expected the result to be valid WebVTT")
+	}
+}
+
+func TestDualPairs(t *testing.T) {
+	vtt := []string{model.FormatVTT}
+	streams := []model.Stream{
+		{Index: 0, CodecType: "video"},
+		{Index: 2, CodecType: "subtitle", Formats: vtt, Tags: model.Tags{Language: "jpn"}, Disposition: model.Disposition{HearingImpaired: 1}},
+		{Index: 3, CodecType: "subtitle", Formats: vtt, Tags: model.Tags{Language: "eng"}, Disposition: model.Disposition{Forced: 1}},
+		{Index: 4, CodecType: "subtitle", Formats: vtt, Tags: model.Tags{Language: "jpn"}},
+		{Index: 5, CodecType: "subtitle", Formats: nil, Tags: model.Tags{Language: "swe"}}, // bitmap
+		{Index: 6, CodecType: "subtitle", Formats: vtt, Tags: model.Tags{Language: "und"}},
+		{Index: -1, CodecType: "subtitle", Formats: vtt, Tags: model.Tags{Language: "English"}, ExternalPath: "/m/en.srt"},
+	}
+	want := []model.DualPair{{Primary: 4, Secondary: -1, PrimaryLanguage: "ja", SecondaryLanguage: "en"}}
+	if got := dualPairs(streams); !slices.Equal(got, want) {
+		t.Errorf("dualPairs = %+v, want %+v", got, want)
+	}
+	if got := dualPairs(streams[:4]); len(got) != 0 {
+		t.Errorf("expected no pairs of a single language, got %+v", got)
+	}
+}
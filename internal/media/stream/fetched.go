@@ -0,0 +1,57 @@
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// fetchedManifest is kept next to the downloaded subtitles of an item, see
+// pattern 2 of findExternal.
+const fetchedManifest = "fetched.json"
+
+// Fetched tells where a downloaded subtitle came from.
+type Fetched struct {
+	Provider string `json:"provider"`
+	// MatchedBy is how the provider matched it to the media, such as by
+	// "moviehash", "id" or "title".
+	MatchedBy string `json:"matchedBy"`
+	Release   string `json:"release,omitempty"`
+}
+
+// RecordFetched notes in dir that its subtitle fileName was fetched as f,
+// so that its stream tells the provider and match method.
+func RecordFetched(dir, fileName string, f Fetched) error {
+	fetched := readFetched(dir)
+	if fetched == nil {
+		fetched = make(map[string]Fetched)
+	}
+	fetched[filepath.Base(fileName)] = f
+	data, err := json.MarshalIndent(fetched, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal fetched subtitles: %w", err)
+	}
+	p := filepath.Join(dir, fetchedManifest)
+	if err := os.WriteFile(p+partialSuffix, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write fetched subtitles: %w", err)
+	}
+	if err := os.Rename(p+partialSuffix, p); err != nil {
+		return fmt.Errorf("failed to write fetched subtitles: %w", err)
+	}
+	return nil
+}
+
+// readFetched returns the subtitles recorded in dir by file name, nil if
+// there are none.
+func readFetched(dir string) map[string]Fetched {
+	data, err := os.ReadFile(filepath.Join(dir, fetchedManifest))
+	if err != nil {
+		return nil
+	}
+	var fetched map[string]Fetched
+	if err := json.Unmarshal(data, &fetched); err != nil {
+		return nil
+	}
+	return fetched
+}
@@ -0,0 +1,43 @@
+package stream
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+func TestRecordFetched(t *testing.T) {
+	cache := t.TempDir()
+	dir := filepath.Join(cache, "subtitles", "vid")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"hashed.en.srt", "manual.sv.srt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("1\n00:00:01,000 --> 00:00:02,000\nHi\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := RecordFetched(dir, filepath.Join(dir, "hashed.en.srt"), Fetched{Provider: "opensubtitles", MatchedBy: "moviehash"}); err != nil {
+		t.Fatal(err)
+	}
+
+	m, _ := NewManager(withRunner(&mockRunner{}), WithStoragePath(t.TempDir()), WithSubtitleCachePath(cache))
+	streams := m.findExternal(model.Item{ID: "vid", Name: "vid.mkv", Path: filepath.Join(t.TempDir(), "vid.mkv")})
+	if len(streams) != 2 {
+		t.Fatalf("expected 2 external streams, got: %+v", streams)
+	}
+	for _, s := range streams {
+		switch s.Tags.Title {
+		case "hashed.en.srt":
+			if s.Provider != "opensubtitles" || s.MatchedBy != "moviehash" {
+				t.Errorf("expected the recorded origin, got: %+v", s)
+			}
+		default:
+			if s.Provider != "" || s.MatchedBy != "" {
+				t.Errorf("expected no origin for %s, got: %+v", s.Tags.Title, s)
+			}
+		}
+	}
+}
@@ -0,0 +1,328 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/baalimago/kinoview/internal/lang"
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+const (
+	// ocrFrameRate at which bitmap subtitles are rendered, and so the
+	// resolution of the recognised cue timings.
+	ocrFrameRate = 5
+	// ocrLastCue is how long the last cue is shown, as there's no following
+	// frame to end it.
+	ocrLastCue = 4 * time.Second
+	// ocrInk is the luma below which a pixel of a rendered (negated) frame
+	// counts as part of the text.
+	ocrInk = 128
+)
+
+// bitmapCodecs are the subtitle codecs which carry images rather than text.
+var bitmapCodecs = map[string]bool{
+	"hdmv_pgs_subtitle": true,
+	"dvd_subtitle":      true,
+	"dvb_subtitle":      true,
+	"xsub":              true,
+}
+
+// WithOCREngine sets the path to the tesseract binary used to recognise the
+// text of bitmap subtitles. Defaults to tesseract on PATH; empty disables
+// OCR, leaving bitmap subtitles unextractable.
+func WithOCREngine(path string) Option {
+	return func(m *Manager) {
+		m.ocrEngine = path
+	}
+}
+
+// OCR engine settings, see ResolveOCREngine.
+const (
+	// OCRAuto uses tesseract if it's on PATH, and disables OCR otherwise.
+	OCRAuto = "auto"
+	// OCROff disables OCR.
+	OCROff = "off"
+)
+
+// ResolveOCREngine to the path of a tesseract binary which runs, from
+// setting: OCRAuto, OCROff or the name or path of the binary. Empty with no
+// error means OCR is disabled, either by OCROff or by OCRAuto not finding
+// tesseract. A binary which is asked for but can't be run is an error, so
+// that OCR expected to work doesn't silently go missing.
+func ResolveOCREngine(ctx context.Context, setting string) (string, error) {
+	switch setting {
+	case OCROff:
+		return "", nil
+	case OCRAuto, "":
+		p, err := exec.LookPath("tesseract")
+		if err != nil {
+			return "", nil
+		}
+		if err := exec.CommandContext(ctx, p, "--version").Run(); err != nil {
+			return "", fmt.Errorf("tesseract at %v doesn't run: %w", p, err)
+		}
+		return p, nil
+	}
+	p, err := exec.LookPath(setting)
+	if err != nil {
+		return "", fmt.Errorf("OCR engine %q not found: %w", setting, err)
+	}
+	if err := exec.CommandContext(ctx, p, "--version").Run(); err != nil {
+		return "", fmt.Errorf("OCR engine %v doesn't run: %w", p, err)
+	}
+	return p, nil
+}
+
+// markOCR flags the bitmap subtitle streams of info which can be extracted
+// through OCR.
+func (m *Manager) markOCR(info model.MediaInfo) {
+	if m.ocrEngine == "" {
+		return
+	}
+	for i, s := range info.Streams {
+		if s.CodecType == "subtitle" && bitmapCodecs[strings.ToLower(s.CodecName)] {
+			info.Streams[i].OCR = true
+		}
+	}
+}
+
+// embeddedStream returns the embedded stream at streamIndex, probing item if
+// it hasn't been already.
+func (m *Manager) embeddedStream(item model.Item, streamIndex string) (model.Stream, bool) {
+	index, err := strconv.Atoi(streamIndex)
+	if err != nil {
+		return model.Stream{}, false
+	}
+	m.mediaMu.RLock()
+	info, ok := m.mediaCache[item.ID]
+	m.mediaMu.RUnlock()
+	if !ok {
+		if info, err = m.Find(item); err != nil {
+			return model.Stream{}, false
+		}
+	}
+	for _, s := range info.Streams {
+		if s.Index == index && s.ExternalPath == "" {
+			return s, true
+		}
+	}
+	return model.Stream{}, false
+}
+
+// ocrSubtitles converts the bitmap subtitle stream st to a .vtt at destPath.
+// ffmpeg renders the subtitles onto a blank canvas, keeping only the frames
+// where they change, and each frame is then read by tesseract.
+func (m *Manager) ocrSubtitles(ctx context.Context, item model.Item, st model.Stream, destPath string) error {
+	if m.ocrEngine == "" {
+		return fmt.Errorf("stream %d is %s, bitmap subtitles need tesseract for OCR", st.Index, st.CodecName)
+	}
+	frameDir, err := os.MkdirTemp(m.storePath, fmt.Sprintf("%s_%d_ocr-*", item.ID, st.Index))
+	if err != nil {
+		return fmt.Errorf("failed to create OCR frame dir: %w", err)
+	}
+	defer os.RemoveAll(frameDir)
+
+	w, h := st.Width, st.Height
+	if w == 0 || h == 0 {
+		w, h = 1920, 1080
+	}
+	filter := fmt.Sprintf(
+		"color=c=black:s=%dx%d:r=%d[bg];[bg][0:%d]overlay=shortest=1,mpdecimate,negate,format=gray[out]",
+		w, h, ocrFrameRate, st.Index)
+	args := []string{
+		"-v", "quiet",
+		"-i", item.Path,
+		"-filter_complex", filter,
+		"-map", "[out]",
+		"-fps_mode", "vfr",
+		// Name the frames by their timestamp, in 1/ocrFrameRate units.
+		"-frame_pts", "1",
+		filepath.Join(frameDir, "%012d.png"),
+	}
+	if err := m.runner.Run(ctx, "ffmpeg", args...); err != nil {
+		return fmt.Errorf("ffmpeg subtitle render failed: %w", err)
+	}
+
+	frames, err := renderedFrames(frameDir)
+	if err != nil {
+		return err
+	}
+	tessLang := tesseractLang(st.Tags.Language)
+	var cues []ocrCue
+	for i, f := range frames {
+		end := f.at + ocrLastCue
+		if i+1 < len(frames) {
+			end = frames[i+1].at
+		}
+		text, err := m.recognise(ctx, f.path, tessLang)
+		if err != nil {
+			return err
+		}
+		if text == "" {
+			continue
+		}
+		// mpdecimate lets through frames which merely flicker, so join
+		// the cues which read the same.
+		if n := len(cues); n > 0 && cues[n-1].text == text && cues[n-1].end == f.at {
+			cues[n-1].end = end
+			continue
+		}
+		cues = append(cues, ocrCue{start: f.at, end: end, text: text})
+	}
+
+	if err := os.WriteFile(destPath, formatOCRVTT(cues), 0o644); err != nil {
+		return fmt.Errorf("failed to write OCR subtitles: %w", err)
+	}
+	return nil
+}
+
+type renderedFrame struct {
+	path string
+	at   time.Duration
+}
+
+// renderedFrames lists the frames in dir in order of their timestamp.
+func renderedFrames(dir string) ([]renderedFrame, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list OCR frames: %w", err)
+	}
+	var frames []renderedFrame
+	for _, e := range entries {
+		pts, err := strconv.ParseInt(strings.TrimSuffix(e.Name(), ".png"), 10, 64)
+		if err != nil {
+			continue
+		}
+		frames = append(frames, renderedFrame{
+			path: filepath.Join(dir, e.Name()),
+			at:   time.Duration(pts) * time.Second / ocrFrameRate,
+		})
+	}
+	sort.Slice(frames, func(i, j int) bool { return frames[i].at < frames[j].at })
+	return frames, nil
+}
+
+// recognise the text in a rendered frame. Blank frames, which is when no
+// subtitle is shown, are skipped without bothering tesseract. The text is
+// cropped out first, which both speeds up and improves the recognition.
+func (m *Manager) recognise(ctx context.Context, framePath, tessLang string) (string, error) {
+	f, err := os.Open(framePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open OCR frame: %w", err)
+	}
+	img, err := png.Decode(f)
+	f.Close()
+	if err != nil {
+		return "", fmt.Errorf("failed to decode OCR frame: %w", err)
+	}
+	box, ok := inkBounds(img)
+	if !ok {
+		return "", nil
+	}
+	cropped := framePath + ".crop.png"
+	out, err := os.Create(cropped)
+	if err != nil {
+		return "", fmt.Errorf("failed to create cropped OCR frame: %w", err)
+	}
+	err = png.Encode(out, subImage(img, box))
+	out.Close()
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cropped OCR frame: %w", err)
+	}
+
+	// psm 6: a single uniform block of text, which a subtitle is.
+	text, err := m.runner.Output(ctx, m.ocrEngine, cropped, "stdout", "-l", tessLang, "--psm", "6")
+	if err != nil {
+		return "", fmt.Errorf("tesseract failed: %w", err)
+	}
+	return cleanOCRText(string(text)), nil
+}
+
+// inkBounds returns the bounding box of the text in a negated frame, padded
+// a little, or false if the frame is blank.
+func inkBounds(img image.Image) (image.Rectangle, bool) {
+	b := img.Bounds()
+	box := image.Rectangle{Min: b.Max, Max: b.Min}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bl, _ := img.At(x, y).RGBA()
+			if (r+g+bl)/3>>8 >= ocrInk {
+				continue
+			}
+			box.Min.X = min(box.Min.X, x)
+			box.Min.Y = min(box.Min.Y, y)
+			box.Max.X = max(box.Max.X, x+1)
+			box.Max.Y = max(box.Max.Y, y+1)
+		}
+	}
+	if box.Empty() {
+		return image.Rectangle{}, false
+	}
+	const pad = 10
+	return image.Rect(box.Min.X-pad, box.Min.Y-pad, box.Max.X+pad, box.Max.Y+pad).Intersect(b), true
+}
+
+func subImage(img image.Image, r image.Rectangle) image.Image {
+	if s, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		return s.SubImage(r)
+	}
+	return img
+}
+
+// cleanOCRText trims the blank lines and trailing form feed tesseract emits.
+func cleanOCRText(s string) string {
+	var lines []string
+	for _, l := range strings.Split(s, "\n") {
+		l = strings.TrimSpace(strings.Trim(l, "\f"))
+		if l != "" {
+			lines = append(lines, l)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// tesseractLang returns the tesseract language of a stream language tag.
+// Tesseract names its models by ISO 639-2/T code, with a few exceptions.
+// Untagged streams are read as English.
+func tesseractLang(tag string) string {
+	switch n := lang.Normalize(tag); n {
+	case "":
+		return "eng"
+	case "zh":
+		return "chi_sim"
+	case "nb":
+		return "nor"
+	default:
+		if t := lang.Alpha3T(n); t != "und" {
+			return t
+		}
+		return "eng"
+	}
+}
+
+type ocrCue struct {
+	start, end time.Duration
+	text       string
+}
+
+func formatOCRVTT(cues []ocrCue) []byte {
+	var sb strings.Builder
+	sb.WriteString("WEBVTT\n\nNOTE\nRecognised from bitmap subtitles by OCR.\n\n")
+	for _, c := range cues {
+		sb.WriteString(formatVTTTime(c.start) + " --> " + formatVTTTime(c.end) + "\n")
+		sb.WriteString(c.text + "\n\n")
+	}
+	return []byte(sb.String())
+}
@@ -0,0 +1,207 @@
+package stream
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+// writeFrame writes a negated subtitle frame: white, with a black bar of
+// textWidth standing in for the text. Zero width is a blank frame.
+func writeFrame(t *testing.T, path string, textWidth int) {
+	t.Helper()
+	img := image.NewGray(image.Rect(0, 0, 320, 180))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	if textWidth > 0 {
+		draw.Draw(img, image.Rect(40, 140, 40+textWidth, 160), image.NewUniform(color.Black), image.Point{}, draw.Src)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExtractSubtitles_OCR(t *testing.T) {
+	tmp := t.TempDir()
+	var tessLang string
+	runner := &mockRunner{
+		runCallback: func(name string, args ...string) error {
+			dir := filepath.Dir(args[len(args)-1])
+			// Frame names are timestamps in 1/ocrFrameRate units
+			writeFrame(t, filepath.Join(dir, "000000000010.png"), 200) // 2s
+			writeFrame(t, filepath.Join(dir, "000000000015.png"), 200) // 3s, flicker
+			writeFrame(t, filepath.Join(dir, "000000000020.png"), 0)   // 4s, blank
+			writeFrame(t, filepath.Join(dir, "000000000030.png"), 100) // 6s
+			return nil
+		},
+		outputFunc: func(name string, args ...string) ([]byte, error) {
+			if name != "tesseract" {
+				t.Fatalf("unexpected command: %v", name)
+			}
+			tessLang = args[3]
+			f, err := os.Open(args[0])
+			if err != nil {
+				return nil, err
+			}
+			defer f.Close()
+			cfg, err := png.DecodeConfig(f)
+			if err != nil {
+				return nil, err
+			}
+			if cfg.Width > 200 {
+				return []byte("Hello\nthere\n\n\f"), nil
+			}
+			return []byte("World\n\f"), nil
+		},
+	}
+	m, err := NewManager(WithStoragePath(tmp), withRunner(runner), WithOCREngine("tesseract"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := model.Item{ID: "vid", Name: "vid.mkv", Path: "/media/vid.mkv"}
+	m.mediaCache[item.ID] = model.MediaInfo{Streams: []model.Stream{
+		{Index: 3, CodecType: "subtitle", CodecName: "hdmv_pgs_subtitle", Tags: model.Tags{Language: "ger"}},
+	}}
+
+	got, err := m.ExtractSubtitles(item, "3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "WEBVTT\n\nNOTE\nRecognised from bitmap subtitles by OCR.\n\n" +
+		"00:00:02.000 --> 00:00:04.000\nHello\nthere\n\n" +
+		"00:00:06.000 --> 00:00:10.000\nWorld\n\n"
+	if string(data) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", data, want)
+	}
+	if tessLang != "deu" {
+		t.Errorf("expected tesseract language deu, got %q", tessLang)
+	}
+
+	// The frames are cleaned up, only the .vtt remains.
+	entries, _ := os.ReadDir(tmp)
+	if len(entries) != 1 {
+		t.Errorf("expected only the .vtt in the store, got %d entries", len(entries))
+	}
+}
+
+func TestResolveOCREngine(t *testing.T) {
+	ctx := context.Background()
+	if p, err := ResolveOCREngine(ctx, OCROff); p != "" || err != nil {
+		t.Errorf("off = %q, %v, want disabled", p, err)
+	}
+	if _, err := ResolveOCREngine(ctx, filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("expected a missing engine asked for to fail")
+	}
+
+	dir := t.TempDir()
+	broken := filepath.Join(dir, "broken")
+	working := filepath.Join(dir, "working")
+	if err := os.WriteFile(broken, []byte("#!/bin/sh\nexit 1\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(working, []byte("#!/bin/sh\necho tesseract 5\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ResolveOCREngine(ctx, broken); err == nil {
+		t.Error("expected an engine which doesn't run to fail")
+	}
+	if p, err := ResolveOCREngine(ctx, working); p != working || err != nil {
+		t.Errorf("working engine = %q, %v", p, err)
+	}
+}
+
+func TestExtractSubtitles_OCRUnavailable(t *testing.T) {
+	m, err := NewManager(WithStoragePath(t.TempDir()), withRunner(&mockRunner{}), WithOCREngine(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := model.Item{ID: "vid", Name: "vid.mkv", Path: "/media/vid.mkv"}
+	m.mediaCache[item.ID] = model.MediaInfo{Streams: []model.Stream{
+		{Index: 3, CodecType: "subtitle", CodecName: "dvd_subtitle"},
+	}}
+	_, err = m.ExtractSubtitles(item, "3")
+	if err == nil || !strings.Contains(err.Error(), "tesseract") {
+		t.Fatalf("expected error naming tesseract, got: %v", err)
+	}
+}
+
+func TestMarkOCR(t *testing.T) {
+	info := func() model.MediaInfo {
+		return model.MediaInfo{Streams: []model.Stream{
+			{Index: 0, CodecType: "video", CodecName: "h264"},
+			{Index: 1, CodecType: "subtitle", CodecName: "subrip"},
+			{Index: 2, CodecType: "subtitle", CodecName: "hdmv_pgs_subtitle"},
+		}}
+	}
+
+	with := info()
+	(&Manager{ocrEngine: "tesseract"}).markOCR(with)
+	for _, s := range with.Streams {
+		if s.OCR != (s.Index == 2) {
+			t.Errorf("stream %d (%s): OCR = %v", s.Index, s.CodecName, s.OCR)
+		}
+	}
+
+	without := info()
+	(&Manager{}).markOCR(without)
+	for _, s := range without.Streams {
+		if s.OCR {
+			t.Errorf("stream %d marked for OCR without an engine", s.Index)
+		}
+	}
+}
+
+func TestInkBounds(t *testing.T) {
+	blank := image.NewGray(image.Rect(0, 0, 100, 50))
+	draw.Draw(blank, blank.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	if _, ok := inkBounds(blank); ok {
+		t.Error("expected blank frame to have no ink")
+	}
+
+	blank.SetGray(50, 40, color.Gray{Y: 0})
+	box, ok := inkBounds(blank)
+	if !ok {
+		t.Fatal("expected ink to be found")
+	}
+	if want := image.Rect(40, 30, 61, 50); box != want {
+		t.Errorf("got %v, want %v padded and clamped", box, want)
+	}
+}
+
+func TestTesseractLang(t *testing.T) {
+	for tag, want := range map[string]string{
+		"":    "eng",
+		"und": "eng",
+		"eng": "eng",
+		"fre": "fra",
+		"chi": "chi_sim",
+		"nob": "nor",
+		"tlh": "eng",
+	} {
+		if got := tesseractLang(tag); got != want {
+			t.Errorf("tesseractLang(%q) = %q, want %q", tag, got, want)
+		}
+	}
+}
+
+func TestCleanOCRText(t *testing.T) {
+	if got := cleanOCRText("  Hello \n\n there\n\f"); got != "Hello\nthere" {
+		t.Errorf("got %q", got)
+	}
+}
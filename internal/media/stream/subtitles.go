@@ -15,6 +15,7 @@ import (
 
 	"github.com/baalimago/go_away_boilerplate/pkg/ancli"
 	"github.com/baalimago/go_away_boilerplate/pkg/misc"
+	"github.com/baalimago/kinoview/internal/lang"
 	"github.com/baalimago/kinoview/internal/model"
 )
 
@@ -53,6 +54,10 @@ type Manager struct {
 	storePath         string
 	subtitleCachePath string
 	runner            CommandRunner
+	// ocrEngine is the tesseract binary, empty when OCR is unavailable.
+	ocrEngine string
+	// cacheLimit is the size storePath may take up, see WithCacheLimit.
+	cacheLimit int64
 
 	debug bool
 
@@ -60,8 +65,14 @@ type Manager struct {
 	mediaCache map[string]model.MediaInfo
 	mediaMu    sync.RWMutex
 
-	// extractionMu prevents multiple extractions of the same subtitle at once
+	// extracting holds a lock per file being extracted, so the same subtitle
+	// isn't extracted twice at once while others go ahead, see
+	// lockExtraction. extractionMu guards the map.
+	extracting   map[string]*extractionLock
 	extractionMu sync.Mutex
+
+	// syncMu serialises subtitle syncing, see SyncSubtitles
+	syncMu sync.Mutex
 }
 
 type Option func(*Manager)
@@ -98,9 +109,14 @@ func NewManager(opts ...Option) (*Manager, error) {
 	m := &Manager{
 		storePath:  defaultPath,
 		runner:     defaultRunner{},
+		cacheLimit: DefaultCacheLimit,
 		mediaCache: make(map[string]model.MediaInfo),
 	}
 
+	if p, err := exec.LookPath("tesseract"); err == nil {
+		m.ocrEngine = p
+	}
+
 	if misc.Truthy(os.Getenv("DEBUG")) || misc.Truthy(os.Getenv("DEBUG_SUBS")) {
 		m.debug = true
 	}
@@ -130,6 +146,7 @@ func (m *Manager) Find(item model.Item) (model.MediaInfo, error) {
 		info = m.stripExternal(info)
 		extStreams := m.findExternal(item)
 		info.Streams = append(info.Streams, extStreams...)
+		info.DualPairs = dualPairs(info.Streams)
 		m.mediaMu.Lock()
 		m.mediaCache[item.ID] = info
 		m.mediaMu.Unlock()
@@ -155,10 +172,13 @@ func (m *Manager) Find(item model.Item) (model.MediaInfo, error) {
 	if err := json.Unmarshal(out, &info); err != nil {
 		return model.MediaInfo{}, fmt.Errorf("failed to unmarshal media info: %w", err)
 	}
+	m.markOCR(info)
+	markFormats(info)
 
 	// Discover external sidecar subtitles and merge them in.
 	extStreams := m.findExternal(item)
 	info.Streams = append(info.Streams, extStreams...)
+	info.DualPairs = dualPairs(info.Streams)
 
 	m.mediaMu.Lock()
 	m.mediaCache[item.ID] = info
@@ -195,6 +215,8 @@ func (m *Manager) findExternal(item model.Item) []model.Stream {
 	basename := strings.TrimSuffix(item.Name, filepath.Ext(item.Name))
 
 	var paths []string
+	var fetched map[string]Fetched
+	var cacheDir string
 
 	// Pattern 1: Subs/{basename}/*.srt, *.vtt
 	subsDir := filepath.Join(dir, "Subs", basename)
@@ -202,8 +224,9 @@ func (m *Manager) findExternal(item model.Item) []model.Stream {
 
 	// Pattern 2: subtitle cache path (<cache>/subtitles/<item.ID>/*.srt, *.vtt)
 	if m.subtitleCachePath != "" {
-		cacheDir := filepath.Join(m.subtitleCachePath, "subtitles", item.ID)
+		cacheDir = filepath.Join(m.subtitleCachePath, "subtitles", item.ID)
 		paths = append(paths, globFiles(cacheDir)...)
+		fetched = readFetched(cacheDir)
 	}
 
 	// Pattern 3: {basename}.srt, {basename}.vtt in same dir
@@ -242,6 +265,10 @@ func (m *Manager) findExternal(item model.Item) []model.Stream {
 		}
 		// Extract language hint from filename (e.g., "5_English.srt" → "English")
 		lang := extractLanguage(p)
+		var f Fetched
+		if filepath.Dir(p) == cacheDir {
+			f = fetched[filepath.Base(p)]
+		}
 		streams = append(streams, model.Stream{
 			Index:          -(1 + i), // negative indices: -1, -2, -3...
 			CodecName:      codecName,
@@ -249,10 +276,13 @@ func (m *Manager) findExternal(item model.Item) []model.Stream {
 			CodecType:      "subtitle",
 			CodecTagString: codecTag,
 			ExternalPath:   p,
+			Formats:        []string{model.FormatVTT},
 			Tags: model.Tags{
 				Language: lang,
 				Title:    filepath.Base(p),
 			},
+			Provider:  f.Provider,
+			MatchedBy: f.MatchedBy,
 		})
 	}
 
@@ -285,35 +315,32 @@ func sidecarFiles(dir, basename string) []string {
 	return files
 }
 
-// extractLanguage attempts to extract a language string from the filename.
-// e.g., "5_English.srt" → "eng", "English.srt" → "eng"
+// extractLanguage attempts to extract an ISO 639-2 language code from the
+// filename. e.g., "5_English.srt" → "eng", "movie.sv.srt" → "swe"
 func extractLanguage(path string) string {
 	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
-	// Try splitting on underscore and looking for known languages
-	parts := strings.SplitSeq(name, "_")
-	for part := range parts {
-		pl := strings.ToLower(part)
-		switch pl {
-		case "english", "eng", "en":
-			return "eng"
-		case "swedish", "swe", "sv":
-			return "swe"
-		case "french", "fre", "fr":
-			return "fre"
-		case "german", "ger", "de":
-			return "ger"
-		case "spanish", "spa", "es":
-			return "spa"
-		case "italian", "ita", "it":
-			return "ita"
+	candidates := strings.Split(name, "_")
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		candidates = append(candidates, name[i+1:])
+	}
+	for i, c := range candidates {
+		if !lang.Known(c) {
+			continue
 		}
+		// Two letter codes are too ambiguous ("no", "it") unless they
+		// end the name, as in "5_en" or "movie.en".
+		if len(c) == 2 && i < len(candidates)-1 {
+			continue
+		}
+		return lang.Alpha3(c)
 	}
 	return "und" // undetermined
 }
 
 // Extract subtitles for a specific stream index to .vtt format.
 // Stores the result in the configured storePath.
-// If the file already exists, returns the path immediately.
+// If a valid file already exists, and the source hasn't changed since,
+// returns the path immediately.
 //
 // For embedded streams (positive index): uses ffmpeg -map 0:<idx>
 // For external streams (negative index): converts the sidecar .srt/.vtt file
@@ -322,21 +349,21 @@ func (m *Manager) ExtractSubtitles(item model.Item, streamIndex string) (string,
 	filename := fmt.Sprintf("%s_%s.vtt", item.ID, streamIndex)
 	destPath := filepath.Join(m.storePath, filename)
 
-	// Check if already exists
-	if _, err := os.Stat(destPath); err == nil {
-		// File exists, assume it's good
-		// In a production system we might want to check size > 0
+	source := m.sourcePath(item, streamIndex)
+	if m.cached(destPath, source) {
 		return destPath, nil
 	}
 
 	// Lock purely to prevent thundering herd on the exact same file
-	m.extractionMu.Lock()
-	defer m.extractionMu.Unlock()
+	defer m.lockExtraction(destPath)()
 
 	// Double check after lock
-	if _, err := os.Stat(destPath); err == nil {
+	if m.cached(destPath, source) {
 		return destPath, nil
 	}
+	// Extractions are written aside and moved into place once complete, so
+	// that a crash never leaves a truncated file to be served.
+	partPath := partialPath(destPath)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer cancel()
@@ -361,8 +388,24 @@ func (m *Manager) ExtractSubtitles(item model.Item, streamIndex string) (string,
 			"-y",
 			"-i", extPath,
 			"-f", "webvtt",
-			destPath,
+			partPath,
+		}
+	} else if st, ok := m.embeddedStream(item, streamIndex); ok && bitmapCodecs[strings.ToLower(st.CodecName)] {
+		// Bitmap subtitle: ffmpeg can't convert these to text on its own
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+		defer cancel()
+		start := time.Now()
+		if err := m.ocrSubtitles(ctx, item, st, partPath); err != nil {
+			os.Remove(partPath)
+			return "", fmt.Errorf("OCR extraction failed: %w", err)
+		}
+		if err := m.commit(destPath); err != nil {
+			return "", fmt.Errorf("OCR extraction failed: %w", err)
+		}
+		if m.debug {
+			ancli.Okf("Extracted subtitle %s for %s by OCR in %v", streamIndex, item.Name, time.Since(start))
 		}
+		return destPath, nil
 	} else {
 		// Embedded subtitle: use ffmpeg map
 		mapArg := "0:" + streamIndex
@@ -371,7 +414,7 @@ func (m *Manager) ExtractSubtitles(item model.Item, streamIndex string) (string,
 			"-i", item.Path,
 			"-map", mapArg,
 			"-f", "webvtt",
-			destPath,
+			partPath,
 		}
 	}
 
@@ -381,8 +424,12 @@ func (m *Manager) ExtractSubtitles(item model.Item, streamIndex string) (string,
 		ancli.Noticef("Extracting subtitle %s for %s. Command:\nffmpeg %v", streamIndex, item.Name, strings.Join(args, " "))
 	}
 	if err := m.runner.Run(ctx, "ffmpeg", args...); err != nil {
+		os.Remove(partPath)
 		return "", fmt.Errorf("ffmpeg extraction failed: %w", err)
 	}
+	if err := m.commit(destPath); err != nil {
+		return "", fmt.Errorf("ffmpeg extraction of stream %s failed: %w", streamIndex, err)
+	}
 
 	if m.debug {
 		ancli.Okf("Extracted subtitle %s for %s in %v", streamIndex, item.Name, time.Since(start))
@@ -390,6 +437,40 @@ func (m *Manager) ExtractSubtitles(item model.Item, streamIndex string) (string,
 	return destPath, nil
 }
 
+// extractionLock is the lock of one file being extracted, refs counting
+// those holding or waiting for it.
+type extractionLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// lockExtraction of the file at destPath, blocking while another extraction
+// of it runs, and returns the unlock. Extractions of other files, such as a
+// long OCR pass, don't hold it up.
+func (m *Manager) lockExtraction(destPath string) (unlock func()) {
+	m.extractionMu.Lock()
+	if m.extracting == nil {
+		m.extracting = make(map[string]*extractionLock)
+	}
+	l, ok := m.extracting[destPath]
+	if !ok {
+		l = &extractionLock{}
+		m.extracting[destPath] = l
+	}
+	l.refs++
+	m.extractionMu.Unlock()
+
+	l.mu.Lock()
+	return func() {
+		l.mu.Unlock()
+		m.extractionMu.Lock()
+		defer m.extractionMu.Unlock()
+		if l.refs--; l.refs == 0 {
+			delete(m.extracting, destPath)
+		}
+	}
+}
+
 // findExternalPath returns the absolute path to an external subtitle file
 // for the given stream index from the cached media info.
 func (m *Manager) findExternalPath(item model.Item, index int) (string, error) {
@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/baalimago/kinoview/internal/model"
 )
@@ -17,6 +18,8 @@ type mockRunner struct {
 	runErrMap map[string]error
 	// runCallback allows side effects (like creating files)
 	runCallback func(name string, args ...string) error
+	// outputFunc, when set, answers every Output call
+	outputFunc func(name string, args ...string) ([]byte, error)
 }
 
 func (m *mockRunner) Run(ctx context.Context, name string, args ...string) error {
@@ -32,6 +35,9 @@ func (m *mockRunner) Run(ctx context.Context, name string, args ...string) error
 }
 
 func (m *mockRunner) Output(ctx context.Context, name string, args ...string) ([]byte, error) {
+	if m.outputFunc != nil {
+		return m.outputFunc(name, args...)
+	}
 	if out, ok := m.outputMap[name]; ok {
 		return out, nil
 	}
@@ -41,6 +47,9 @@ func (m *mockRunner) Output(ctx context.Context, name string, args ...string) ([
 	return []byte{}, nil
 }
 
+// testVTT is the least an extraction has to produce to be kept.
+const testVTT = "WEBVTT\n\n00:00:01.000 --> 00:00:02.000\nHello\n"
+
 func TestNewManager(t *testing.T) {
 	// Test default path
 	m, err := NewManager()
@@ -146,7 +155,7 @@ func TestExtractSubtitles(t *testing.T) {
 		if name == "ffmpeg" {
 			// Find the output file argument (last one)
 			outPath := args[len(args)-1]
-			return os.WriteFile(outPath, []byte("WEBVTT"), 0o644)
+			return os.WriteFile(outPath, []byte(testVTT), 0o644)
 		}
 		return nil
 	}
@@ -233,6 +242,10 @@ func TestExtractLanguage(t *testing.T) {
 		{"7_ENG.srt", "eng"},
 		{"unknown.srt", "und"},
 		{"no_language_hint.vtt", "und"},
+		{"3_Japanese.srt", "jpn"},
+		{"movie.sv.srt", "swe"},
+		{"4_ja.vtt", "jpn"},
+		{"The.It.Crowd.srt", "und"},
 	}
 	for _, tt := range tests {
 		got := extractLanguage(tt.filename)
@@ -413,7 +426,7 @@ func TestExtractSubtitles_External(t *testing.T) {
 	mr.runCallback = func(name string, args ...string) error {
 		if name == "ffmpeg" {
 			outPath := args[len(args)-1]
-			return os.WriteFile(outPath, []byte("WEBVTT"), 0o644)
+			return os.WriteFile(outPath, []byte(testVTT), 0o644)
 		}
 		return nil
 	}
@@ -457,7 +470,7 @@ func TestExtractSubtitles_External_CacheMiss(t *testing.T) {
 	mr.runCallback = func(name string, args ...string) error {
 		if name == "ffmpeg" {
 			outPath := args[len(args)-1]
-			return os.WriteFile(outPath, []byte("WEBVTT"), 0o644)
+			return os.WriteFile(outPath, []byte(testVTT), 0o644)
 		}
 		return nil
 	}
@@ -639,3 +652,38 @@ func TestFindExternal_SubtitlePaths(t *testing.T) {
 		}
 	}
 }
+
+func TestLockExtraction(t *testing.T) {
+	m := &Manager{}
+	unlockA := m.lockExtraction("a.vtt")
+
+	done := make(chan struct{})
+	go func() {
+		m.lockExtraction("b.vtt")()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected the extraction of another file not to wait")
+	}
+
+	released := make(chan struct{})
+	go func() {
+		m.lockExtraction("a.vtt")()
+		close(released)
+	}()
+	select {
+	case <-released:
+		t.Fatal("expected the same file to wait for its extraction")
+	case <-time.After(50 * time.Millisecond):
+	}
+	unlockA()
+	<-released
+
+	m.extractionMu.Lock()
+	defer m.extractionMu.Unlock()
+	if len(m.extracting) != 0 {
+		t.Errorf("expected no locks left, got %v", m.extracting)
+	}
+}
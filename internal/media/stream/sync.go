@@ -0,0 +1,454 @@
+package stream
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/baalimago/go_away_boilerplate/pkg/ancli"
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+const (
+	// syncSampleRate of the audio decoded for voice-activity detection.
+	// Speech energy sits well below 4 kHz, so this is plenty.
+	syncSampleRate = 8000
+	// syncFrame is the resolution of the speech mask and of the solver.
+	syncFrame = 10 * time.Millisecond
+	// syncMaxOffset bounds the global offset search, in either direction.
+	syncMaxOffset = 2 * time.Minute
+	// syncMinCues is the least amount of cues needed for a meaningful fit.
+	syncMinCues = 10
+)
+
+// frameRateRatios are the scale factors produced by subtitles timed against
+// a release at another frame rate. Drift which doesn't match any of these is
+// found by the piecewise refinement.
+var frameRateRatios = []float64{
+	1,
+	25 / 23.976, 23.976 / 25,
+	24 / 23.976, 23.976 / 24,
+	25 / 24, 24 / 25,
+}
+
+// ErrSyncFailed is returned when the cues can't be matched to the speech in
+// the audio track with any confidence.
+var ErrSyncFailed = errors.New("no confident subtitle sync found")
+
+// cue is the timing of a single subtitle cue.
+type cue struct {
+	start, end time.Duration
+}
+
+// syncFit maps an original cue time t to scale*t + offset.
+type syncFit struct {
+	scale  float64
+	offset time.Duration
+	// score is the net amount of cue frames landing on speech, minus those
+	// landing on silence, after the fit is applied.
+	score int
+}
+
+func (f syncFit) apply(t time.Duration) time.Duration {
+	r := time.Duration(f.scale*float64(t)) + f.offset
+	if r < 0 {
+		return 0
+	}
+	return r
+}
+
+func (f syncFit) String() string {
+	return fmt.Sprintf("offset=%+.3fs scale=%.5f", f.offset.Seconds(), f.scale)
+}
+
+// SyncSubtitles aligns the subtitle stream at streamIndex to the speech in
+// the first audio track of item, solving for a constant offset and a linear
+// drift. The corrected .vtt is written next to the extracted one and reused
+// on subsequent calls.
+func (m *Manager) SyncSubtitles(item model.Item, streamIndex string) (string, error) {
+	destPath := filepath.Join(m.storePath, fmt.Sprintf("%s_%s.synced.vtt", item.ID, streamIndex))
+	sources := []string{item.Path, m.sourcePath(item, streamIndex)}
+	if m.cached(destPath, sources...) {
+		return destPath, nil
+	}
+
+	srcPath, err := m.ExtractSubtitles(item, streamIndex)
+	if err != nil {
+		return "", fmt.Errorf("failed to extract subtitles: %w", err)
+	}
+
+	// Decoding the audio of a feature film takes a while, and a fair bit of
+	// memory, so do one at a time.
+	m.syncMu.Lock()
+	defer m.syncMu.Unlock()
+	if m.cached(destPath, sources...) {
+		return destPath, nil
+	}
+
+	src, err := os.ReadFile(srcPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read subtitles: %w", err)
+	}
+	cues := parseCues(src)
+	if len(cues) < syncMinCues {
+		return "", fmt.Errorf("%w: only %d cue(s) in subtitle stream %s", ErrSyncFailed, len(cues), streamIndex)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	start := time.Now()
+	speech, err := m.speechMask(ctx, item)
+	if err != nil {
+		return "", err
+	}
+
+	fit, err := solveSync(cues, speech)
+	if err != nil {
+		return "", fmt.Errorf("failed to sync subtitle stream %s of %s: %w", streamIndex, item.Name, err)
+	}
+	if m.debug {
+		ancli.Okf("Synced subtitle %s for %s in %v: %v", streamIndex, item.Name, time.Since(start), fit)
+	}
+
+	if err := os.WriteFile(partialPath(destPath), retimeVTT(src, fit), 0o644); err != nil {
+		return "", fmt.Errorf("failed to write synced subtitles: %w", err)
+	}
+	if err := m.commit(destPath); err != nil {
+		return "", fmt.Errorf("failed to write synced subtitles: %w", err)
+	}
+	return destPath, nil
+}
+
+// speechMask decodes the first audio track of item and runs voice-activity
+// detection on it, one entry per syncFrame.
+func (m *Manager) speechMask(ctx context.Context, item model.Item) ([]bool, error) {
+	args := []string{
+		"-v", "quiet",
+		"-i", item.Path,
+		"-map", "0:a:0",
+		"-vn", "-sn", "-dn",
+		"-ac", "1",
+		"-ar", strconv.Itoa(syncSampleRate),
+		// Keep roughly to the voice band, so that rumble and cymbals
+		// don't register as dialogue.
+		"-af", "highpass=f=200,lowpass=f=3400",
+		"-f", "s16le",
+		"-",
+	}
+	pcm, err := m.runner.Output(ctx, "ffmpeg", args...)
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg audio decode failed for %s: %w", item.Name, err)
+	}
+	return detectSpeech(pcm, syncSampleRate), nil
+}
+
+// detectSpeech is an energy-based voice-activity detector over little-endian
+// 16 bit mono PCM. The threshold adapts to the track: a frame is speech when
+// its energy is well above the noise floor, relative to the loud parts.
+func detectSpeech(pcm []byte, sampleRate int) []bool {
+	frameLen := int(int64(sampleRate) * int64(syncFrame) / int64(time.Second))
+	n := len(pcm) / 2 / frameLen
+	if n == 0 {
+		return nil
+	}
+	energy := make([]float64, n)
+	for f := range n {
+		var sum float64
+		for i := range frameLen {
+			off := (f*frameLen + i) * 2
+			s := float64(int16(binary.LittleEndian.Uint16(pcm[off:])))
+			sum += s * s
+		}
+		energy[f] = 10 * math.Log10(sum/float64(frameLen)+1)
+	}
+
+	sorted := append([]float64(nil), energy...)
+	sort.Float64s(sorted)
+	floor := sorted[n/10]
+	loud := sorted[n*95/100]
+	threshold := floor + 0.4*(loud-floor)
+
+	raw := make([]bool, n)
+	for f, e := range energy {
+		raw[f] = e > threshold && loud-floor > 6
+	}
+
+	// Bridge the short pauses between words, and drop blips too short to
+	// be speech.
+	const bridge, minRun = 20, 8
+	speech := make([]bool, n)
+	copy(speech, raw)
+	last := -1
+	for f, v := range raw {
+		if !v {
+			continue
+		}
+		if last >= 0 && f-last > 1 && f-last <= bridge {
+			for g := last + 1; g < f; g++ {
+				speech[g] = true
+			}
+		}
+		last = f
+	}
+	for f := 0; f < n; {
+		if !speech[f] {
+			f++
+			continue
+		}
+		g := f
+		for g < n && speech[g] {
+			g++
+		}
+		if g-f < minRun {
+			for i := f; i < g; i++ {
+				speech[i] = false
+			}
+		}
+		f = g
+	}
+	return speech
+}
+
+// solveSync finds the fit which best lines up cues with speech. First a
+// global offset search for each frame rate ratio, then the residual drift is
+// estimated by fitting a line through the local offsets of stretches of the
+// subtitles, and the offset is refined once more.
+func solveSync(cues []cue, speech []bool) (syncFit, error) {
+	if len(cues) < syncMinCues {
+		return syncFit{}, fmt.Errorf("%w: too few cues", ErrSyncFailed)
+	}
+	if len(speech) == 0 {
+		return syncFit{}, fmt.Errorf("%w: no audio", ErrSyncFailed)
+	}
+	prefix := speechPrefix(speech)
+	maxShift := int(syncMaxOffset / syncFrame)
+
+	best := syncFit{score: math.MinInt}
+	for _, scale := range frameRateRatios {
+		f := bestOffset(cues, prefix, scale, 0, maxShift)
+		if f.score > best.score {
+			best = f
+		}
+	}
+
+	if refined, ok := refineDrift(cues, prefix, best); ok && refined.score > best.score {
+		best = refined
+	}
+
+	// Demand that a clear majority of cue time lands on speech, anything
+	// less is as likely to be chance as a match.
+	var total int
+	for _, c := range cues {
+		total += int((c.end - c.start) / syncFrame)
+	}
+	if best.score < total/4 {
+		return syncFit{}, fmt.Errorf("%w: best %v only scores %d of %d", ErrSyncFailed, best, best.score, total)
+	}
+	return best, nil
+}
+
+// refineDrift splits the cues into stretches, finds the best local offset of
+// each stretch relative to fit, and folds a least-squares line through those
+// offsets back into the fit.
+func refineDrift(cues []cue, prefix []int, fit syncFit) (syncFit, bool) {
+	const stretches = 8
+	per := len(cues) / stretches
+	if per < syncMinCues/2 {
+		return fit, false
+	}
+	localShift := int(5 * time.Second / syncFrame)
+
+	var xs, ys []float64
+	for i := range stretches {
+		part := cues[i*per : (i+1)*per]
+		local := bestOffset(part, prefix, fit.scale, fit.offset, localShift)
+		if local.score <= 0 {
+			continue
+		}
+		mid := fit.apply((part[0].start + part[len(part)-1].end) / 2)
+		xs = append(xs, mid.Seconds())
+		ys = append(ys, (local.offset - fit.offset).Seconds())
+	}
+	if len(xs) < 3 {
+		return fit, false
+	}
+	slope, intercept := linearFit(xs, ys)
+
+	// t2 = t1 + slope*t1 + intercept, where t1 = fit.apply(t)
+	scale := fit.scale * (1 + slope)
+	offset := time.Duration((1+slope)*float64(fit.offset)) + time.Duration(intercept*float64(time.Second))
+	// Settle the offset of the new scale with a narrow search.
+	refined := bestOffset(cues, prefix, scale, offset, int(time.Second/syncFrame))
+	return refined, true
+}
+
+// bestOffset searches offsets within ±shift frames of around.
+func bestOffset(cues []cue, prefix []int, scale float64, around time.Duration, shift int) syncFit {
+	best := syncFit{scale: scale, score: math.MinInt}
+	for s := -shift; s <= shift; s++ {
+		f := syncFit{scale: scale, offset: around + time.Duration(s)*syncFrame}
+		f.score = scoreFit(cues, prefix, f)
+		// Prefer the smallest correction among equals.
+		if f.score > best.score || (f.score == best.score && absDuration(f.offset) < absDuration(best.offset)) {
+			best = f
+		}
+	}
+	return best
+}
+
+// scoreFit counts cue frames on speech minus cue frames on silence, once fit
+// is applied. Cue time which falls outside the audio counts as silence.
+func scoreFit(cues []cue, prefix []int, fit syncFit) int {
+	n := len(prefix) - 1
+	score := 0
+	for _, c := range cues {
+		a := int(fit.apply(c.start) / syncFrame)
+		b := int(fit.apply(c.end) / syncFrame)
+		if b <= a {
+			continue
+		}
+		ca, cb := min(a, n), min(b, n)
+		onSpeech := prefix[cb] - prefix[ca]
+		score += 2*onSpeech - (b - a)
+	}
+	return score
+}
+
+// speechPrefix returns the running count of speech frames, so that the
+// speech within any span is a subtraction away.
+func speechPrefix(speech []bool) []int {
+	prefix := make([]int, len(speech)+1)
+	for i, v := range speech {
+		prefix[i+1] = prefix[i]
+		if v {
+			prefix[i+1]++
+		}
+	}
+	return prefix
+}
+
+func linearFit(xs, ys []float64) (slope, intercept float64) {
+	var mx, my float64
+	for i := range xs {
+		mx += xs[i]
+		my += ys[i]
+	}
+	mx /= float64(len(xs))
+	my /= float64(len(ys))
+	var sxy, sxx float64
+	for i := range xs {
+		sxy += (xs[i] - mx) * (ys[i] - my)
+		sxx += (xs[i] - mx) * (xs[i] - mx)
+	}
+	if sxx == 0 {
+		return 0, my
+	}
+	slope = sxy / sxx
+	return slope, my - slope*mx
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// ShiftVTT returns the .vtt in data with every cue moved by offset. Cues
+// which would start before the beginning are clamped to it.
+func ShiftVTT(data []byte, offset time.Duration) []byte {
+	return retimeVTT(data, syncFit{scale: 1, offset: offset})
+}
+
+// parseCues returns the cue timings of a .vtt or .srt file.
+func parseCues(data []byte) []cue {
+	var cues []cue
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		start, end, _, ok := parseTimingLine(sc.Text())
+		if ok && end > start {
+			cues = append(cues, cue{start: start, end: end})
+		}
+	}
+	sort.Slice(cues, func(i, j int) bool { return cues[i].start < cues[j].start })
+	return cues
+}
+
+// retimeVTT rewrites every cue timing line of a .vtt with fit applied,
+// leaving the text and cue settings untouched.
+func retimeVTT(data []byte, fit syncFit) []byte {
+	var out bytes.Buffer
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := sc.Text()
+		start, end, settings, ok := parseTimingLine(line)
+		if ok {
+			line = formatVTTTime(fit.apply(start)) + " --> " + formatVTTTime(fit.apply(end)) + settings
+		}
+		out.WriteString(line)
+		out.WriteByte('\n')
+	}
+	return out.Bytes()
+}
+
+// parseTimingLine parses "00:01:02.500 --> 00:01:04.000 align:start". Both
+// the WebVTT and SubRip (comma) decimal separators are accepted, and hours
+// are optional. settings is everything after the end time, leading space
+// included.
+func parseTimingLine(line string) (start, end time.Duration, settings string, ok bool) {
+	from, rest, found := strings.Cut(line, "-->")
+	if !found {
+		return 0, 0, "", false
+	}
+	rest = strings.TrimLeft(rest, " \t")
+	to := rest
+	if i := strings.IndexAny(rest, " \t"); i >= 0 {
+		to, settings = rest[:i], rest[i:]
+	}
+	start, errS := parseVTTTime(strings.TrimSpace(from))
+	end, errE := parseVTTTime(to)
+	if errS != nil || errE != nil {
+		return 0, 0, "", false
+	}
+	return start, end, settings, true
+}
+
+func parseVTTTime(s string) (time.Duration, error) {
+	s = strings.Replace(s, ",", ".", 1)
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("malformed timestamp: %q", s)
+	}
+	var d time.Duration
+	for i, p := range parts[:len(parts)-1] {
+		v, err := strconv.Atoi(p)
+		if err != nil {
+			return 0, fmt.Errorf("malformed timestamp: %q", s)
+		}
+		unit := time.Minute
+		if len(parts) == 3 && i == 0 {
+			unit = time.Hour
+		}
+		d += time.Duration(v) * unit
+	}
+	secs, err := strconv.ParseFloat(parts[len(parts)-1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("malformed timestamp: %q", s)
+	}
+	return d + time.Duration(math.Round(secs*1000))*time.Millisecond, nil
+}
+
+func formatVTTTime(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3_600_000, ms/60_000%60, ms/1000%60, ms%1000)
+}
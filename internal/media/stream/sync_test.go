@@ -0,0 +1,212 @@
+package stream
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+// syntheticCues are spread like dialogue: a second or few of speech, then a
+// pause of varying length.
+func syntheticCues(n int, seed int64) []cue {
+	r := rand.New(rand.NewSource(seed))
+	var cues []cue
+	t := 5 * time.Second
+	for range n {
+		d := time.Duration(1000+r.Intn(3000)) * time.Millisecond
+		cues = append(cues, cue{start: t, end: t + d})
+		t += d + time.Duration(500+r.Intn(6000))*time.Millisecond
+	}
+	return cues
+}
+
+// speechFor renders the speech mask which cues would match once fit is
+// applied, with some audio left over at the end.
+func speechFor(cues []cue, fit syncFit) []bool {
+	end := fit.apply(cues[len(cues)-1].end) + 30*time.Second
+	speech := make([]bool, int(end/syncFrame))
+	for _, c := range cues {
+		for f := int(fit.apply(c.start) / syncFrame); f < int(fit.apply(c.end)/syncFrame); f++ {
+			speech[f] = true
+		}
+	}
+	return speech
+}
+
+func TestSolveSync(t *testing.T) {
+	cues := syntheticCues(200, 1)
+	for name, want := range map[string]syncFit{
+		"in sync":          {scale: 1},
+		"late":             {scale: 1, offset: 3200 * time.Millisecond},
+		"early":            {scale: 1, offset: -45 * time.Second},
+		"frame rate":       {scale: 25 / 23.976, offset: -7500 * time.Millisecond},
+		"drift":            {scale: 1.002, offset: 1500 * time.Millisecond},
+		"drift and offset": {scale: 0.9985, offset: 20 * time.Second},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := solveSync(cues, speechFor(cues, want))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			// Judge the fit on where it puts the cues, as a slightly
+			// different scale and offset can be equally good.
+			for _, c := range []cue{cues[0], cues[len(cues)/2], cues[len(cues)-1]} {
+				if diff := absDuration(got.apply(c.start) - want.apply(c.start)); diff > 100*time.Millisecond {
+					t.Errorf("fit %v puts cue at %v off by %v, want %v", got, c.start, diff, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSolveSync_NoMatch(t *testing.T) {
+	cues := syntheticCues(50, 2)
+	other := syntheticCues(50, 3)
+	_, err := solveSync(cues, make([]bool, len(speechFor(other, syncFit{scale: 1}))))
+	if !errors.Is(err, ErrSyncFailed) {
+		t.Fatalf("expected ErrSyncFailed for silent audio, got: %v", err)
+	}
+	if _, err := solveSync(cues[:3], speechFor(cues, syncFit{scale: 1})); !errors.Is(err, ErrSyncFailed) {
+		t.Fatalf("expected ErrSyncFailed for too few cues, got: %v", err)
+	}
+}
+
+// pcmFor renders 16 bit PCM with a tone wherever speech is set, over a faint
+// noise floor.
+func pcmFor(speech []bool, sampleRate int) []byte {
+	r := rand.New(rand.NewSource(4))
+	frameLen := sampleRate * int(syncFrame) / int(time.Second)
+	pcm := make([]byte, len(speech)*frameLen*2)
+	for f, v := range speech {
+		for i := range frameLen {
+			n := f*frameLen + i
+			s := float64(r.Intn(200) - 100)
+			if v {
+				s += 6000 * math.Sin(2*math.Pi*300*float64(n)/float64(sampleRate))
+			}
+			binary.LittleEndian.PutUint16(pcm[n*2:], uint16(int16(s)))
+		}
+	}
+	return pcm
+}
+
+func TestDetectSpeech(t *testing.T) {
+	cues := syntheticCues(20, 5)
+	want := speechFor(cues, syncFit{scale: 1})
+	got := detectSpeech(pcmFor(want, syncSampleRate), syncSampleRate)
+	if len(got) != len(want) {
+		t.Fatalf("expected %d frames, got %d", len(want), len(got))
+	}
+	var wrong int
+	for i := range want {
+		if got[i] != want[i] {
+			wrong++
+		}
+	}
+	if wrong > len(want)/50 {
+		t.Errorf("%d of %d frames misdetected", wrong, len(want))
+	}
+
+	if got := detectSpeech(nil, syncSampleRate); got != nil {
+		t.Errorf("expected no frames for no audio, got %d", len(got))
+	}
+}
+
+func TestParseTimingLine(t *testing.T) {
+	start, end, settings, ok := parseTimingLine("00:01:02.500 --> 00:01:04,250 align:start line:0")
+	if !ok {
+		t.Fatal("expected timing line to parse")
+	}
+	if start != 62500*time.Millisecond || end != 64250*time.Millisecond {
+		t.Errorf("got %v --> %v", start, end)
+	}
+	if settings != " align:start line:0" {
+		t.Errorf("unexpected settings: %q", settings)
+	}
+
+	start, end, _, ok = parseTimingLine("01:02.000 --> 01:03.000")
+	if !ok || start != 62*time.Second || end != 63*time.Second {
+		t.Errorf("expected hourless timestamps to parse, got %v --> %v (%v)", start, end, ok)
+	}
+
+	for _, line := range []string{"Hello -->", "WEBVTT", "aa:00:00.000 --> 00:00:01.000"} {
+		if _, _, _, ok := parseTimingLine(line); ok {
+			t.Errorf("expected %q not to parse", line)
+		}
+	}
+}
+
+func TestFormatVTTTime(t *testing.T) {
+	if got := formatVTTTime(3*time.Hour + 4*time.Minute + 5*time.Second + 6*time.Millisecond); got != "03:04:05.006" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestRetimeVTT(t *testing.T) {
+	src := "WEBVTT\n\n1\n00:00:10.000 --> 00:00:12.000 align:start\nHello --> there\n\n00:00:01.000 --> 00:00:02.000\nBye\n"
+	got := string(retimeVTT([]byte(src), syncFit{scale: 1, offset: -1500 * time.Millisecond}))
+	want := "WEBVTT\n\n1\n00:00:08.500 --> 00:00:10.500 align:start\nHello --> there\n\n00:00:00.000 --> 00:00:00.500\nBye\n"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSyncSubtitles(t *testing.T) {
+	tmp := t.TempDir()
+	cues := syntheticCues(30, 6)
+	shift := syncFit{scale: 1, offset: 2 * time.Second}
+
+	var vtt strings.Builder
+	vtt.WriteString("WEBVTT\n\n")
+	for _, c := range cues {
+		vtt.WriteString(formatVTTTime(c.start) + " --> " + formatVTTTime(c.end) + "\nline\n\n")
+	}
+	if err := os.WriteFile(filepath.Join(tmp, "vid_-1.vtt"), []byte(vtt.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	runner := &mockRunner{outputMap: map[string][]byte{
+		"ffmpeg": pcmFor(speechFor(cues, shift), syncSampleRate),
+	}}
+	m, err := NewManager(WithStoragePath(tmp), withRunner(runner))
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := model.Item{ID: "vid", Name: "vid.mkv", Path: "/media/vid.mkv"}
+
+	got, err := m.SyncSubtitles(item, "-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != filepath.Join(tmp, "vid_-1.synced.vtt") {
+		t.Errorf("unexpected path: %v", got)
+	}
+	data, err := os.ReadFile(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	synced := parseCues(data)
+	if len(synced) != len(cues) {
+		t.Fatalf("expected %d cues, got %d", len(cues), len(synced))
+	}
+	for i := range cues {
+		if diff := absDuration(synced[i].start - shift.apply(cues[i].start)); diff > 100*time.Millisecond {
+			t.Fatalf("cue %d off by %v after sync", i, diff)
+		}
+	}
+
+	// The result is reused, without decoding the audio again.
+	runner.outputMap = nil
+	runner.runErrMap = map[string]error{"ffmpeg": errors.New("should not run")}
+	if again, err := m.SyncSubtitles(item, "-1"); err != nil || again != got {
+		t.Errorf("expected cached result, got %v, %v", again, err)
+	}
+}
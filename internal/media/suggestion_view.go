@@ -1,9 +1,7 @@
 package media
 
 import (
-	"encoding/json"
 	"path/filepath"
-	"strconv"
 	"strings"
 
 	"github.com/baalimago/kinoview/internal/model"
@@ -18,7 +16,10 @@ import (
 // the same extraction the shows browser uses. Metadata showName (when the
 // concierge or a newer classifier has filled it) is preferred over the path.
 func resolveSuggestionView(item model.Item) model.SuggestionView {
-	md := metadataMap(item.Metadata)
+	md := model.MediaMetadata{}
+	if item.Metadata != nil {
+		md = *item.Metadata
+	}
 
 	season, episode, hasMetaPos := parseSeasonEpisodeFromMetadata(item)
 	pathShowName, pathSeason, pathEpisode, hasPath := extractShowMetadata(item)
@@ -28,24 +29,24 @@ func resolveSuggestionView(item model.Item) model.SuggestionView {
 		season, episode = pathSeason, pathEpisode
 	}
 
-	showName := mdString(md, "showName")
+	showName := md.ShowName
 	if showName == "" {
 		showName = pathShowName
 	}
 
-	name := mdString(md, "name")
-	altName := mdString(md, "alt_name")
+	name := md.Name
+	altName := md.AltName
 
 	view := model.SuggestionView{
 		Kind:        suggestionKind(md, season, episode),
 		Title:       showName,
 		Season:      season,
 		Episode:     episode,
-		Year:        mdInt(md, "year"),
-		DurationMin: mdInt(md, "duration_min"),
-		Language:    mdLanguage(md),
-		Description: mdString(md, "description"),
-		Actors:      mdStrings(md, "actors"),
+		Year:        md.Year,
+		DurationMin: md.DurationMin,
+		Language:    md.Language,
+		Description: md.Description,
+		Actors:      md.Actors,
 	}
 
 	switch view.Kind {
@@ -60,7 +61,7 @@ func resolveSuggestionView(item model.Item) model.SuggestionView {
 		// Label the card with the main media; the extras' own name becomes
 		// the secondary title.
 		if view.Title == "" {
-			view.Title = mdString(md, "extra_to")
+			view.Title = md.ExtraTo
 		}
 		if view.Title == "" {
 			view.Title = cleanFilename(item)
@@ -87,14 +88,14 @@ func enrichSuggestions(recs []model.Suggestion) []model.Suggestion {
 	return out
 }
 
-func suggestionKind(md map[string]any, season, episode int) string {
-	if mdString(md, "extra_to") != "" {
+func suggestionKind(md model.MediaMetadata, season, episode int) string {
+	if md.ExtraTo != "" {
 		return "extras"
 	}
 	if season > 0 && episode > 0 {
 		return "episode"
 	}
-	if mdString(md, "name") != "" || mdString(md, "alt_name") != "" {
+	if md.Name != "" || md.AltName != "" {
 		return "movie"
 	}
 	return "media"
@@ -113,73 +114,6 @@ func episodeTitle(name, altName, series string) string {
 	return ""
 }
 
-func metadataMap(raw *json.RawMessage) map[string]any {
-	if raw == nil {
-		return nil
-	}
-	var md map[string]any
-	if err := json.Unmarshal(*raw, &md); err != nil {
-		return nil
-	}
-	return md
-}
-
-// mdLanguage reads the spoken-language field. The classifier format spells it
-// "langugae" (historical typo); accept the corrected spelling too.
-func mdLanguage(md map[string]any) string {
-	if s := mdString(md, "language"); s != "" {
-		return s
-	}
-	return mdString(md, "langugae")
-}
-
-func mdString(md map[string]any, key string) string {
-	v, ok := md[key]
-	if !ok {
-		return ""
-	}
-	switch s := v.(type) {
-	case string:
-		return strings.TrimSpace(s)
-	case float64:
-		return strconv.FormatFloat(s, 'f', -1, 64)
-	}
-	return ""
-}
-
-func mdInt(md map[string]any, key string) int {
-	v, ok := md[key]
-	if !ok {
-		return 0
-	}
-	switch n := v.(type) {
-	case float64:
-		return int(n)
-	case string:
-		i, _ := strconv.Atoi(n)
-		return i
-	}
-	return 0
-}
-
-func mdStrings(md map[string]any, key string) []string {
-	v, ok := md[key]
-	if !ok {
-		return nil
-	}
-	arr, ok := v.([]any)
-	if !ok {
-		return nil
-	}
-	out := make([]string, 0, len(arr))
-	for _, e := range arr {
-		if s, ok := e.(string); ok && strings.TrimSpace(s) != "" {
-			out = append(out, s)
-		}
-	}
-	return out
-}
-
 func firstNonEmpty(vals ...string) string {
 	for _, v := range vals {
 		if v != "" {
@@ -190,7 +190,10 @@ func TestSuggestionView_EmptyViewNotPersisted(t *testing.T) {
 }
 
 func itemWithMeta(path, meta string) model.Item {
-	raw := json.RawMessage(meta)
+	var raw model.MediaMetadata
+	if err := json.Unmarshal([]byte(meta), &raw); err != nil {
+		panic(err)
+	}
 	return model.Item{
 		Name:     path,
 		Path:     path,
@@ -0,0 +1,255 @@
+package thumbnail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"os"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/baalimago/go_away_boilerplate/pkg/ancli"
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+// Size of a cached thumbnail.
+type Size struct {
+	Name   string
+	Width  int
+	Height int
+	// Crop to exactly Width x Height, instead of fitting within them.
+	Crop bool
+}
+
+var (
+	// SizeGrid is the square tile of the gallery grids.
+	SizeGrid = Size{Name: "grid", Width: ThumbnailWidth, Height: ThumbnailHeight, Crop: true}
+	// SizeCard is used for cards, such as suggestions and albums.
+	SizeCard = Size{Name: "card", Width: 640, Height: 360}
+	// SizeHero is used for full-width banners.
+	SizeHero = Size{Name: "hero", Width: 1920, Height: 1080}
+
+	Sizes = []Size{SizeGrid, SizeCard, SizeHero}
+)
+
+// SizeByName returns the size with the given name.
+func SizeByName(name string) (Size, bool) {
+	for _, s := range Sizes {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return Size{}, false
+}
+
+const (
+	FormatJPEG = "jpeg"
+	FormatWebP = "webp"
+	FormatAVIF = "avif"
+)
+
+// variantFormats are the formats which are derived from the jpeg on request,
+// in order of preference.
+var variantFormats = []string{FormatAVIF, FormatWebP}
+
+var formatExt = map[string]string{
+	FormatJPEG: ".jpg",
+	FormatWebP: ".webp",
+	FormatAVIF: ".avif",
+}
+
+var ffmpegLookPath = "ffmpeg"
+
+// encodeVariant re-encodes the image at src into dst, with the format given
+// by the extension of dst.
+var encodeVariant = func(ctx context.Context, src, dst string) error {
+	if _, err := exec.LookPath(ffmpegLookPath); err != nil {
+		return fmt.Errorf("ffmpeg not found: %w", err)
+	}
+	out, err := exec.CommandContext(ctx, ffmpegLookPath,
+		"-y", "-loglevel", "error", "-i", src, "-frames:v", "1", dst).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ffmpeg failed: %w, output: %s", err, out)
+	}
+	return nil
+}
+
+// Cache stores thumbnails outside of the media tree, keyed by item ID.
+// Thumbnails are generated as jpeg in every size. WebP and AVIF variants are
+// derived from those when first requested by a client which accepts them,
+// using ffmpeg.
+type Cache struct {
+	dir string
+
+	mu sync.Mutex
+	// unsupported holds the formats which couldn't be encoded, so that
+	// clients aren't kept waiting on an encoder which keeps failing.
+	unsupported map[string]bool
+}
+
+// NewCache storing thumbnails in dir.
+func NewCache(dir string) *Cache {
+	return &Cache{dir: dir, unsupported: make(map[string]bool)}
+}
+
+// DefaultCacheDir is <user cache dir>/kinoview/thumbnails.
+func DefaultCacheDir() string {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		ancli.Warnf("failed to find user cache dir: %v", err)
+	}
+	return path.Join(cacheDir, "kinoview", "thumbnails")
+}
+
+// Dir where the thumbnails are stored.
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// Path to the thumbnail of item id in size and format.
+func (c *Cache) Path(id string, size Size, format string) string {
+	return path.Join(c.dir, id, size.Name+formatExt[format])
+}
+
+// Has reports if the thumbnails of item id have been generated.
+func (c *Cache) Has(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, s := range Sizes {
+		if _, err := os.Stat(c.Path(id, s, FormatJPEG)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Image describes the grid thumbnail of item id, as stored on the item.
+func (c *Cache) Image(id string) model.Image {
+	return model.Image{
+		ID:       id,
+		Path:     c.Path(id, SizeGrid, FormatJPEG),
+		Encoding: FormatJPEG,
+		Width:    SizeGrid.Width,
+		Height:   SizeGrid.Height,
+	}
+}
+
+// Generate the thumbnails of item id from src, replacing any previous ones.
+// src is expected to be upright, see Orient.
+func (c *Cache) Generate(id string, src image.Image) (model.Image, error) {
+	if id == "" {
+		return model.Image{}, errors.New("missing item id")
+	}
+	if err := c.Remove(id); err != nil {
+		return model.Image{}, err
+	}
+	if err := os.MkdirAll(path.Join(c.dir, id), 0o755); err != nil {
+		return model.Image{}, fmt.Errorf("failed to create thumbnail dir: %w", err)
+	}
+	for _, s := range Sizes {
+		var img image.Image
+		var err error
+		if s.Crop {
+			img, err = CenterResize(src, s.Width, s.Height)
+		} else {
+			img = Fit(src, s.Width, s.Height)
+		}
+		if err != nil {
+			return model.Image{}, fmt.Errorf("failed to resize to %v: %w", s.Name, err)
+		}
+		if err := SaveImage(img, FormatJPEG, c.Path(id, s, FormatJPEG)); err != nil {
+			return model.Image{}, fmt.Errorf("failed to save %v thumbnail: %w", s.Name, err)
+		}
+	}
+	return c.Image(id), nil
+}
+
+// Remove all thumbnails of item id.
+func (c *Cache) Remove(id string) error {
+	if id == "" {
+		return nil
+	}
+	if err := os.RemoveAll(path.Join(c.dir, id)); err != nil {
+		return fmt.Errorf("failed to remove thumbnails: %w", err)
+	}
+	return nil
+}
+
+// Negotiate picks the best thumbnail of item id in size for a client sending
+// the accept header. Returns the path and content type, or os.ErrNotExist if
+// there's no thumbnail.
+func (c *Cache) Negotiate(ctx context.Context, id string, size Size, accept string) (string, string, error) {
+	jpegPath := c.Path(id, size, FormatJPEG)
+	jpegInfo, err := os.Stat(jpegPath)
+	if err != nil {
+		return "", "", err
+	}
+	for _, format := range variantFormats {
+		if !accepts(accept, "image/"+format) || c.isUnsupported(format) {
+			continue
+		}
+		p := c.Path(id, size, format)
+		if info, err := os.Stat(p); err == nil && !info.ModTime().Before(jpegInfo.ModTime()) {
+			return p, "image/" + format, nil
+		}
+		if err := c.encode(ctx, jpegPath, p); err != nil {
+			ancli.Warnf("failed to encode %v thumbnails, serving jpeg from now on: %v", format, err)
+			c.markUnsupported(format)
+			continue
+		}
+		return p, "image/" + format, nil
+	}
+	return jpegPath, "image/jpeg", nil
+}
+
+// encode via a temporary file, so that concurrent requests never serve a
+// partially written variant.
+func (c *Cache) encode(ctx context.Context, src, dst string) error {
+	ext := path.Ext(dst)
+	tmp := strings.TrimSuffix(dst, ext) + ".tmp" + strconv.FormatInt(time.Now().UnixNano(), 36) + ext
+	defer os.Remove(tmp)
+	if err := encodeVariant(ctx, src, tmp); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
+
+func (c *Cache) isUnsupported(format string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.unsupported[format]
+}
+
+func (c *Cache) markUnsupported(format string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.unsupported[format] = true
+}
+
+// accepts reports if the accept header explicitly lists mimeType with a
+// non-zero quality. Wildcards don't count, browsers list the image formats
+// they support.
+func accepts(accept, mimeType string) bool {
+	for part := range strings.SplitSeq(accept, ",") {
+		mt, params, _ := strings.Cut(part, ";")
+		if !strings.EqualFold(strings.TrimSpace(mt), mimeType) {
+			continue
+		}
+		for param := range strings.SplitSeq(params, ";") {
+			k, v, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && k == "q" {
+				if q, err := strconv.ParseFloat(v, 64); err == nil && q == 0 {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,170 @@
+package thumbnail
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/baalimago/go_away_boilerplate/pkg/testboil"
+)
+
+// fakeEncoder replaces encodeVariant for the duration of the test, copying
+// the source as-is, or failing with err.
+func fakeEncoder(t *testing.T, err error) *int {
+	t.Helper()
+	calls := 0
+	orig := encodeVariant
+	encodeVariant = func(ctx context.Context, src, dst string) error {
+		calls++
+		if err != nil {
+			return err
+		}
+		b, readErr := os.ReadFile(src)
+		if readErr != nil {
+			return readErr
+		}
+		return os.WriteFile(dst, b, 0o644)
+	}
+	t.Cleanup(func() { encodeVariant = orig })
+	return &calls
+}
+
+func TestCache_Generate(t *testing.T) {
+	c := NewCache(t.TempDir())
+	if c.Has("id") {
+		t.Fatal("expected empty cache")
+	}
+	src := filled(1000, 500, color.RGBA{R: 255, A: 255})
+	img, err := c.Generate("id", src)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	testboil.FailTestIfDiff(t, img.ID, "id")
+	testboil.FailTestIfDiff(t, img.Path, c.Path("id", SizeGrid, FormatJPEG))
+	if !c.Has("id") {
+		t.Fatal("expected thumbnails to be generated")
+	}
+
+	want := map[string][2]int{"grid": {300, 300}, "card": {640, 320}, "hero": {1000, 500}}
+	for _, s := range Sizes {
+		loaded, err := LoadImage(c.Path("id", s, FormatJPEG))
+		if err != nil {
+			t.Fatalf("failed to load %v: %v", s.Name, err)
+		}
+		if w := want[s.Name]; loaded.Width != w[0] || loaded.Height != w[1] {
+			t.Errorf("%v: got %vx%v, want %vx%v", s.Name, loaded.Width, loaded.Height, w[0], w[1])
+		}
+	}
+
+	if err := c.Remove("id"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.Has("id") {
+		t.Fatal("expected thumbnails to be removed")
+	}
+}
+
+func TestCache_Negotiate(t *testing.T) {
+	ctx := context.Background()
+	setup := func(t *testing.T) *Cache {
+		t.Helper()
+		c := NewCache(t.TempDir())
+		if _, err := c.Generate("id", image.NewRGBA(image.Rect(0, 0, 10, 10))); err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	t.Run("jpeg by default", func(t *testing.T) {
+		c := setup(t)
+		calls := fakeEncoder(t, nil)
+		p, ct, err := c.Negotiate(ctx, "id", SizeCard, "image/*,*/*;q=0.8")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		testboil.FailTestIfDiff(t, p, c.Path("id", SizeCard, FormatJPEG))
+		testboil.FailTestIfDiff(t, ct, "image/jpeg")
+		testboil.FailTestIfDiff(t, *calls, 0)
+	})
+
+	t.Run("prefers avif, encodes once", func(t *testing.T) {
+		c := setup(t)
+		calls := fakeEncoder(t, nil)
+		for range 2 {
+			p, ct, err := c.Negotiate(ctx, "id", SizeGrid, "image/avif,image/webp,image/*")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			testboil.FailTestIfDiff(t, p, c.Path("id", SizeGrid, FormatAVIF))
+			testboil.FailTestIfDiff(t, ct, "image/avif")
+		}
+		testboil.FailTestIfDiff(t, *calls, 1)
+	})
+
+	t.Run("q=0 rejects format", func(t *testing.T) {
+		c := setup(t)
+		fakeEncoder(t, nil)
+		_, ct, err := c.Negotiate(ctx, "id", SizeGrid, "image/avif;q=0, image/webp;q=0.9")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		testboil.FailTestIfDiff(t, ct, "image/webp")
+	})
+
+	t.Run("re-encodes stale variant", func(t *testing.T) {
+		c := setup(t)
+		calls := fakeEncoder(t, nil)
+		if _, _, err := c.Negotiate(ctx, "id", SizeGrid, "image/webp"); err != nil {
+			t.Fatal(err)
+		}
+		old := time.Now().Add(-time.Hour)
+		if err := os.Chtimes(c.Path("id", SizeGrid, FormatWebP), old, old); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := c.Negotiate(ctx, "id", SizeGrid, "image/webp"); err != nil {
+			t.Fatal(err)
+		}
+		testboil.FailTestIfDiff(t, *calls, 2)
+	})
+
+	t.Run("falls back to jpeg when encoder fails", func(t *testing.T) {
+		c := setup(t)
+		calls := fakeEncoder(t, errors.New("no encoder"))
+		for range 2 {
+			_, ct, err := c.Negotiate(ctx, "id", SizeGrid, "image/webp")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			testboil.FailTestIfDiff(t, ct, "image/jpeg")
+		}
+		testboil.FailTestIfDiff(t, *calls, 1)
+		matches, _ := filepath.Glob(filepath.Join(c.Dir(), "id", "*.webp"))
+		if len(matches) != 0 {
+			t.Fatalf("expected no leftover files, got: %v", matches)
+		}
+	})
+
+	t.Run("missing thumbnail", func(t *testing.T) {
+		c := NewCache(t.TempDir())
+		_, _, err := c.Negotiate(ctx, "nope", SizeGrid, "")
+		if !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("expected ErrNotExist, got: %v", err)
+		}
+	})
+}
+
+func TestFit(t *testing.T) {
+	small := filled(10, 10, color.RGBA{A: 255})
+	if Fit(small, 100, 100) != image.Image(small) {
+		t.Fatal("expected small image to be returned as-is")
+	}
+	tall := Fit(filled(100, 400, color.RGBA{A: 255}), 640, 360)
+	if tall.Bounds().Dx() != 90 || tall.Bounds().Dy() != 360 {
+		t.Fatalf("unexpected size: %v", tall.Bounds())
+	}
+}
@@ -0,0 +1,42 @@
+package thumbnail
+
+import (
+	"os"
+	"path"
+	"strings"
+)
+
+// coverNames are the stems of album art files commonly found next to the
+// tracks of an album, in order of preference.
+var coverNames = []string{"cover", "folder", "front", "album"}
+
+// FindCoverArt returns the path to an album art image in the directory of
+// mediaPath, such as cover.jpg or folder.png. Returns an empty string if
+// there is none.
+func FindCoverArt(mediaPath string) string {
+	entries, err := os.ReadDir(path.Dir(mediaPath))
+	if err != nil {
+		return ""
+	}
+	found := make(map[string]string)
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		name := e.Name()
+		ext := strings.ToLower(path.Ext(name))
+		if ext != ".jpg" && ext != ".jpeg" && ext != ".png" {
+			continue
+		}
+		stem := strings.ToLower(strings.TrimSuffix(name, path.Ext(name)))
+		if _, exists := found[stem]; !exists {
+			found[stem] = path.Join(path.Dir(mediaPath), name)
+		}
+	}
+	for _, n := range coverNames {
+		if p, ok := found[n]; ok {
+			return p
+		}
+	}
+	return ""
+}
@@ -96,3 +96,62 @@ func CenterResize(src image.Image, width int, height int) (image.Image, error) {
 	}
 	return dst, nil
 }
+
+// Orient applies the EXIF orientation to src so that it displays upright.
+// Orientations 0 and 1, or unknown ones, return src as-is.
+func Orient(src image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dstW, dstH := w, h
+	// 5-8 rotate a quarter turn, swapping width and height
+	if orientation >= 5 {
+		dstW, dstH = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := range dstH {
+		for x := range dstW {
+			var sx, sy int
+			switch orientation {
+			case 2: // mirrored horizontally
+				sx, sy = w-1-x, y
+			case 3: // rotated 180
+				sx, sy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				sx, sy = x, h-1-y
+			case 5: // transposed
+				sx, sy = y, x
+			case 6: // needs a clockwise quarter turn
+				sx, sy = y, h-1-x
+			case 7: // transversed
+				sx, sy = w-1-y, h-1-x
+			case 8: // needs a counter-clockwise quarter turn
+				sx, sy = w-1-y, x
+			}
+			dst.Set(x, y, src.At(b.Min.X+sx, b.Min.Y+sy))
+		}
+	}
+	return dst
+}
+
+// Fit scales src down to fit within width x height while keeping the aspect
+// ratio. Images which already fit are returned as-is.
+func Fit(src image.Image, width int, height int) image.Image {
+	b := src.Bounds()
+	srcW, srcH := b.Dx(), b.Dy()
+	if srcW <= width && srcH <= height {
+		return src
+	}
+	scale := max(float64(srcW)/float64(width), float64(srcH)/float64(height))
+	dstW := max(int(float64(srcW)/scale), 1)
+	dstH := max(int(float64(srcH)/scale), 1)
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := range dstH {
+		for x := range dstW {
+			dst.Set(x, y, src.At(b.Min.X+int(float64(x)*scale), b.Min.Y+int(float64(y)*scale)))
+		}
+	}
+	return dst
+}
@@ -288,3 +288,35 @@ func TestCenterResize(t *testing.T) {
 		}
 	})
 }
+
+func TestOrient(t *testing.T) {
+	marker := color.RGBA{R: 255, A: 255}
+	src := filled(3, 2, color.RGBA{B: 255, A: 255})
+	src.Set(0, 0, marker)
+
+	tests := []struct {
+		orientation  int
+		wantW, wantH int
+		markerX      int
+		markerY      int
+	}{
+		{0, 3, 2, 0, 0},
+		{1, 3, 2, 0, 0},
+		{2, 3, 2, 2, 0},
+		{3, 3, 2, 2, 1},
+		{4, 3, 2, 0, 1},
+		{5, 2, 3, 0, 0},
+		{6, 2, 3, 1, 0},
+		{7, 2, 3, 1, 2},
+		{8, 2, 3, 0, 2},
+	}
+	for _, tc := range tests {
+		dst := Orient(src, tc.orientation)
+		if dst.Bounds().Dx() != tc.wantW || dst.Bounds().Dy() != tc.wantH {
+			t.Fatalf("orientation %v: size %v", tc.orientation, dst.Bounds())
+		}
+		if got := rgba(dst.At(tc.markerX, tc.markerY)); got != marker {
+			t.Errorf("orientation %v: marker not at (%v, %v)", tc.orientation, tc.markerX, tc.markerY)
+		}
+	}
+}
@@ -3,10 +3,10 @@ package thumbnail
 import (
 	"errors"
 	"fmt"
+	"image"
 	"path"
 	"strings"
 
-	"github.com/baalimago/go_away_boilerplate/pkg/ancli"
 	"github.com/baalimago/kinoview/internal/model"
 )
 
@@ -16,6 +16,8 @@ var (
 	ThumbnailSuffix = "thumb"
 )
 
+// IsThumbnail reports if imgPath looks like a thumbnail which older versions
+// of kinoview wrote next to the media, see GetThumbnailPath.
 func IsThumbnail(imgPath string) bool {
 	base := path.Base(imgPath)
 	ext := path.Ext(base)
@@ -23,31 +25,9 @@ func IsThumbnail(imgPath string) bool {
 	return strings.HasSuffix(name, "_"+ThumbnailSuffix)
 }
 
-// CreateThumbnail by looking at the image MIMEtype and then take
-// appropriate actions to create it.
-//
-// For images, scale the image to 300x300px without changing the ratio
-// by first scaling it by the smallest side too 300px, then
-// cropping/trimming the excess of the longest side
-func CreateThumbnail(i model.Item) (model.Image, error) {
-	// Simply return if it seems like the image is itself a thumbnail to
-	// avoid recursive thumbnail creation
-	if IsThumbnail(i.Path) {
-		return model.Image{}, nil
-	}
-	ancli.Noticef("attempting to create thumbnail for: '%v'", i.Name)
-	// WIP: Detect file type and delegate
-	switch i.MIMEType {
-	case "image/jpeg", "image/png", "image/gif":
-		return createImageThumbnail(i)
-	case "video/mp4", "video/webm":
-		// WIP: Video thumbnail generation
-		return model.Image{}, errors.New("thumbnail creation for video is not yet implemented")
-	default:
-		return model.Image{}, errors.New("unhandled MIMEtype")
-	}
-}
-
+// GetThumbnailPath returns where older versions of kinoview stored the
+// thumbnail of mediaPath: next to it, as <name>_thumb<ext>. Thumbnails are
+// nowadays kept in the Cache.
 func GetThumbnailPath(mediaPath string) string {
 	base := path.Base(mediaPath)
 	ext := path.Ext(base)
@@ -56,29 +36,20 @@ func GetThumbnailPath(mediaPath string) string {
 	return path.Join(path.Dir(mediaPath), thumbName)
 }
 
-func createImageThumbnail(i model.Item) (model.Image, error) {
-	full, err := LoadImage(i.Path)
-	if err != nil {
-		return model.Image{}, fmt.Errorf("createImageThumbnail failed to LoadImage: %err", err)
-	}
-	thumbRaw, err := CenterResize(full.Raw, ThumbnailWidth, ThumbnailHeight)
-	if err != nil {
-		return model.Image{}, fmt.Errorf("createImageThumbnail failed to CenterResize: %v", err)
+// Source loads the image to create thumbnails of item i from, rotated
+// upright by its EXIF orientation.
+func Source(i model.Item) (image.Image, error) {
+	switch i.MIMEType {
+	case "image/jpeg", "image/png", "image/gif":
+	default:
+		return nil, errors.New("unhandled MIMEtype")
 	}
-
-	thumbPath := GetThumbnailPath(i.Path)
-	err = SaveImage(thumbRaw, full.Encoding, thumbPath)
+	full, err := LoadImage(i.Path)
 	if err != nil {
-		return model.Image{}, fmt.Errorf("createImageThumbnail failed to SaveImage: %v", err)
+		return nil, fmt.Errorf("failed to load image: %w", err)
 	}
-
-	thumb := model.Image{
-		Width:  ThumbnailWidth,
-		Height: ThumbnailHeight,
-		Path:   thumbPath,
-		Raw:    thumbRaw,
+	if i.Photo == nil {
+		return full.Raw, nil
 	}
-
-	ancli.Okf("thmbnail for: '%v' created at: '%v'", i.Name, thumb.Path)
-	return thumb, nil
+	return Orient(full.Raw, i.Photo.Orientation), nil
 }
@@ -2,6 +2,7 @@ package thumbnail
 
 import (
 	"image"
+	"image/color"
 	"image/png"
 	"os"
 	"path/filepath"
@@ -12,55 +13,71 @@ import (
 	"github.com/baalimago/kinoview/internal/model"
 )
 
-// TestCreateThumbnail validates thumbnail creation
-func TestCreateThumbnail(t *testing.T) {
-	t.Run("creates thumbnail with correct suffix", func(t *testing.T) {
-		// Create temp image file
+// TestSource validates loading of thumbnail sources
+func TestSource(t *testing.T) {
+	t.Run("loads image", func(t *testing.T) {
 		tmpDir := t.TempDir()
 		imgPath := filepath.Join(tmpDir, "test.png")
 		createTestImage(t, imgPath)
 
-		item := model.Item{
+		img, err := Source(model.Item{
 			Path:     imgPath,
 			Name:     "test.png",
 			MIMEType: "image/png",
-		}
-
-		thumb, err := CreateThumbnail(item)
+		})
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
-
-		// Verify thumbnail path contains ThumbnailSuffix
-		if !strings.Contains(thumb.Path, ThumbnailSuffix) {
-			t.Errorf("thumbnail path missing suffix: %s", debug.IndentedJsonFmt(thumb))
-		}
-
-		// Verify thumbnail exists
-		if _, err := os.Stat(thumb.Path); os.IsNotExist(err) {
-			t.Errorf("thumbnail file not created at: %s", thumb.Path)
+		if img.Bounds().Dx() != 100 || img.Bounds().Dy() != 100 {
+			t.Errorf("unexpected size: %s", debug.IndentedJsonFmt(img.Bounds()))
 		}
 	})
 
-	t.Run("skips thumbnail creation for existing thumbnails", func(t *testing.T) {
+	t.Run("rotates by exif orientation", func(t *testing.T) {
 		tmpDir := t.TempDir()
-		thumbPath := filepath.Join(tmpDir, "test_thumb.png")
-		createTestImage(t, thumbPath)
-
-		item := model.Item{
-			Path:     thumbPath,
-			Name:     "test_thumb.png",
-			MIMEType: "image/png",
+		imgPath := filepath.Join(tmpDir, "rotated.png")
+		red := color.RGBA{R: 255, A: 255}
+		blue := color.RGBA{B: 255, A: 255}
+		// Left half red, right half blue. A clockwise quarter turn puts
+		// red on top.
+		img := image.NewRGBA(image.Rect(0, 0, 40, 20))
+		for y := range 20 {
+			for x := range 40 {
+				if x < 20 {
+					img.Set(x, y, red)
+				} else {
+					img.Set(x, y, blue)
+				}
+			}
+		}
+		if err := SaveImage(img, "png", imgPath); err != nil {
+			t.Fatal(err)
 		}
 
-		thumb, err := CreateThumbnail(item)
+		got, err := Source(model.Item{
+			Path:     imgPath,
+			Name:     "rotated.png",
+			MIMEType: "image/png",
+			Photo:    &model.PhotoMetadata{Orientation: 6},
+		})
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
+		if got.Bounds().Dx() != 20 || got.Bounds().Dy() != 40 {
+			t.Fatalf("expected 20x40, got: %v", got.Bounds())
+		}
+		if c := color.RGBAModel.Convert(got.At(10, 5)); c != red {
+			t.Errorf("expected red on top, got: %v", c)
+		}
+		if c := color.RGBAModel.Convert(got.At(10, 35)); c != blue {
+			t.Errorf("expected blue at the bottom, got: %v", c)
+		}
+	})
 
-		// Should return empty Image to avoid recursion
-		if thumb.Path != "" {
-			t.Errorf("expected empty Image for existing thumbnail, got: %v", thumb)
+	t.Run("errors on unsupported MIME", func(t *testing.T) {
+		_, err := Source(model.Item{Path: "video.mp4", MIMEType: "video/mp4"})
+		if err == nil {
+			t.Fatal("expected error")
 		}
 	})
 }
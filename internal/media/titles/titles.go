@@ -0,0 +1,343 @@
+// Package titles looks up the metadata of movies and shows in a local
+// dataset: a tab separated dump with a header row, such as IMDb's
+// title.basics.tsv. Being a file on disk, lookups work offline and always
+// answer the same, unlike the classifier browsing the web.
+//
+// The columns are found by their header, those of IMDb or plainer aliases:
+//
+//	tconst / id              the ID of the title, required
+//	primaryTitle / title     its name, required
+//	originalTitle            its name in the original language
+//	titleType / type         movie, tvSeries, tvEpisode...
+//	startYear / year
+//	runtimeMinutes / runtime
+//	genres                   comma separated
+//	plot / description
+//	actors / cast            comma separated
+//
+// Other columns are ignored, as are empty values and IMDb's \N.
+package titles
+
+import (
+	"bufio"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/baalimago/go_away_boilerplate/pkg/ancli"
+	"github.com/baalimago/kinoview/internal/agents"
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+var _ agents.MetadataProvider = (*Dataset)(nil)
+
+// columns of the dataset, by their index in a row, -1 when missing.
+type columns struct {
+	id, title, original, kind, year, runtime, genres, plot, actors int
+}
+
+// entry of the index: where to find the row of a title.
+type entry struct {
+	offset int64
+	year   int
+}
+
+// Dataset is an index of the titles in a TSV file. The file is indexed by
+// Index or on the first lookup, keeping the IDs and names of the titles in
+// memory along with where their rows are: about 110 bytes a title, some 1.3GB
+// for IMDb's full dump of 12 million titles. The rows themselves are read
+// from disk.
+type Dataset struct {
+	path string
+	cols columns
+
+	once    sync.Once
+	err     error
+	byTitle map[string][]entry
+	byID    map[string]int64
+}
+
+// Open the dataset at path, checking its header.
+func Open(path string) (*Dataset, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open titles: %w", err)
+	}
+	defer f.Close()
+	header, err := bufio.NewReader(f).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("read header of %v: %w", path, err)
+	}
+	cols := parseHeader(header)
+	if cols.id == -1 || cols.title == -1 {
+		return nil, fmt.Errorf("%v: header needs an ID column (tconst or id) and a title column (primaryTitle or title)", path)
+	}
+	return &Dataset{path: path, cols: cols}, nil
+}
+
+func parseHeader(header string) columns {
+	cols := columns{-1, -1, -1, -1, -1, -1, -1, -1, -1}
+	for n, name := range strings.Split(strings.TrimRight(header, "\r\n"), "\t") {
+		var col *int
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "tconst", "id":
+			col = &cols.id
+		case "primarytitle", "title":
+			col = &cols.title
+		case "originaltitle":
+			col = &cols.original
+		case "titletype", "type":
+			col = &cols.kind
+		case "startyear", "year":
+			col = &cols.year
+		case "runtimeminutes", "runtime":
+			col = &cols.runtime
+		case "genres":
+			col = &cols.genres
+		case "plot", "description":
+			col = &cols.plot
+		case "actors", "cast":
+			col = &cols.actors
+		default:
+			continue
+		}
+		if *col == -1 {
+			*col = n
+		}
+	}
+	return cols
+}
+
+// LookupTitle by its name, and its year unless 0. When no title is of that
+// year, those a year off are returned, as datasets don't agree on whether
+// a movie is of the year it premiered or was released. Names are compared
+// without case or punctuation.
+func (d *Dataset) LookupTitle(title string, year int) ([]model.MediaMetadata, error) {
+	if err := d.index(); err != nil {
+		return nil, err
+	}
+	entries := d.byTitle[normalize(title)]
+	if year > 0 {
+		exact := slices.DeleteFunc(slices.Clone(entries), func(e entry) bool { return e.year != year })
+		if len(exact) == 0 {
+			exact = slices.DeleteFunc(slices.Clone(entries), func(e entry) bool { return e.year < year-1 || e.year > year+1 })
+		}
+		entries = exact
+	}
+	ret := make([]model.MediaMetadata, 0, len(entries))
+	for _, e := range entries {
+		md, err := d.read(e.offset)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, md)
+	}
+	slices.SortStableFunc(ret, func(a, b model.MediaMetadata) int {
+		return cmp.Or(cmp.Compare(a.Year, b.Year), cmp.Compare(a.IMDbID, b.IMDbID))
+	})
+	return ret, nil
+}
+
+// LookupID by the ID of the title, such as tt0113277.
+func (d *Dataset) LookupID(id string) (model.MediaMetadata, error) {
+	if err := d.index(); err != nil {
+		return model.MediaMetadata{}, err
+	}
+	offset, ok := d.byID[strings.TrimSpace(id)]
+	if !ok {
+		return model.MediaMetadata{}, fmt.Errorf("%w: %q", model.ErrNoTitle, id)
+	}
+	return d.read(offset)
+}
+
+// Index the dataset now rather than on the first lookup, which would stall
+// for the tens of seconds a full dump takes. Refused when the machine lacks
+// the memory the index would take.
+func (d *Dataset) Index() error {
+	return d.index()
+}
+
+// indexBytesPerFileByte is about how much memory the index takes per byte of
+// the dataset, measured on IMDb's title.basics.tsv.
+const indexBytesPerFileByte = 1.2
+
+// availableMemory of the machine in bytes, 0 if unknown. A variable so that
+// tests can stub it.
+var availableMemory = func() uint64 {
+	data, err := os.ReadFile("/proc/meminfo")
+	if err != nil {
+		return 0
+	}
+	for line := range strings.SplitSeq(string(data), "\n") {
+		if !strings.HasPrefix(line, "MemAvailable:") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return 0
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0
+		}
+		return kb * 1024
+	}
+	return 0
+}
+
+// index the dataset, once.
+func (d *Dataset) index() error {
+	d.once.Do(func() {
+		d.err = d.build()
+		if d.err != nil {
+			d.err = fmt.Errorf("index %v: %w", d.path, d.err)
+		}
+	})
+	return d.err
+}
+
+func (d *Dataset) build() error {
+	f, err := os.Open(d.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	need := uint64(float64(info.Size()) * indexBytesPerFileByte)
+	if avail := availableMemory(); avail > 0 && need > avail {
+		return fmt.Errorf("the index would take about %vMB of memory, only %vMB is available", need>>20, avail>>20)
+	}
+	ancli.Noticef("indexing titles of %v, about %vMB of memory", d.path, need>>20)
+	began := time.Now()
+
+	d.byTitle = map[string][]entry{}
+	d.byID = map[string]int64{}
+	r := bufio.NewReaderSize(f, 1<<20)
+	var offset int64
+	first := true
+	for {
+		line, err := r.ReadString('\n')
+		start := offset
+		offset += int64(len(line))
+		if first {
+			first = false
+		} else if line != "" {
+			d.add(start, strings.Split(strings.TrimRight(line, "\r\n"), "\t"))
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	ancli.Noticef("indexed %v titles of %v in %v", len(d.byID), d.path, time.Since(began).Round(time.Millisecond))
+	return nil
+}
+
+func (d *Dataset) add(offset int64, row []string) {
+	id := d.field(row, d.cols.id)
+	if id == "" {
+		return
+	}
+	// Cloned, the row would otherwise be kept alive by its ID
+	d.byID[strings.Clone(id)] = offset
+	// Episodes are found through their show, their own names ("Episode
+	// #1.1") would only crowd the lookups
+	if d.field(row, d.cols.kind) == "tvEpisode" {
+		return
+	}
+	e := entry{offset: offset, year: atoi(d.field(row, d.cols.year))}
+	title := normalize(d.field(row, d.cols.title))
+	if title != "" {
+		d.byTitle[title] = append(d.byTitle[title], e)
+	}
+	if original := normalize(d.field(row, d.cols.original)); original != "" && original != title {
+		d.byTitle[original] = append(d.byTitle[original], e)
+	}
+}
+
+// read the row at offset as metadata.
+func (d *Dataset) read(offset int64) (model.MediaMetadata, error) {
+	f, err := os.Open(d.path)
+	if err != nil {
+		return model.MediaMetadata{}, err
+	}
+	defer f.Close()
+	line, err := bufio.NewReader(io.NewSectionReader(f, offset, 1<<40)).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return model.MediaMetadata{}, fmt.Errorf("read %v: %w", d.path, err)
+	}
+	return d.metadata(strings.Split(strings.TrimRight(line, "\r\n"), "\t")), nil
+}
+
+func (d *Dataset) metadata(row []string) model.MediaMetadata {
+	md := model.MediaMetadata{
+		IMDbID:      d.field(row, d.cols.id),
+		Year:        atoi(d.field(row, d.cols.year)),
+		DurationMin: atoi(d.field(row, d.cols.runtime)),
+		Description: d.field(row, d.cols.plot),
+		Genre:       strings.Join(list(d.field(row, d.cols.genres)), ", "),
+		Actors:      list(d.field(row, d.cols.actors)),
+	}
+	title := d.field(row, d.cols.title)
+	switch d.field(row, d.cols.kind) {
+	case "tvSeries", "tvMiniSeries":
+		md.ShowName = title
+	default:
+		md.Name = title
+	}
+	if original := d.field(row, d.cols.original); original != title {
+		md.AltName = original
+	}
+	return md
+}
+
+// field n of row, "" if there's no such column or it's \N.
+func (d *Dataset) field(row []string, n int) string {
+	if n < 0 || n >= len(row) {
+		return ""
+	}
+	v := strings.TrimSpace(row[n])
+	if v == `\N` {
+		return ""
+	}
+	return v
+}
+
+func list(s string) []string {
+	var ret []string
+	for v := range strings.SplitSeq(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
+func atoi(s string) int {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// normalize a title for comparison, so that "2001: A Space Odyssey" is
+// "2001 a space odyssey".
+func normalize(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
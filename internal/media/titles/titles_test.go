@@ -0,0 +1,161 @@
+package titles
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/baalimago/kinoview/internal/model"
+)
+
+const imdbBasics = "tconst\ttitleType\tprimaryTitle\toriginalTitle\tisAdult\tstartYear\tendYear\truntimeMinutes\tgenres\n" +
+	"tt0113277\tmovie\tHeat\tHeat\t0\t1995\t\\N\t170\tAction,Crime,Drama\n" +
+	"tt0097523\tmovie\tHeat\tHeat\t0\t1986\t\\N\t101\tAction\n" +
+	"tt0062622\tmovie\t2001: A Space Odyssey\t2001: A Space Odyssey\t0\t1968\t\\N\t149\tAdventure,Sci-Fi\n" +
+	"tt0903747\ttvSeries\tBreaking Bad\tBreaking Bad\t0\t2008\t2013\t49\tCrime,Drama,Thriller\n" +
+	"tt0959621\ttvEpisode\tPilot\tPilot\t0\t2008\t\\N\t58\tCrime\n" +
+	"tt0245429\tmovie\tSpirited Away\tSen to Chihiro no kamikakushi\t0\t2001\t\\N\t125\tAnimation\n"
+
+func writeDataset(t *testing.T, content string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "titles.tsv")
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func names(mds []model.MediaMetadata) []string {
+	var ret []string
+	for _, md := range mds {
+		ret = append(ret, md.IMDbID)
+	}
+	return ret
+}
+
+func TestOpen_header(t *testing.T) {
+	if _, err := Open(writeDataset(t, "id\tname\n1\tHeat\n")); err == nil {
+		t.Error("expected a dataset without a title column to be refused")
+	}
+	if _, err := Open(filepath.Join(t.TempDir(), "missing.tsv")); err == nil {
+		t.Error("expected a missing dataset to be refused")
+	}
+}
+
+func TestLookupTitle(t *testing.T) {
+	d, err := Open(writeDataset(t, imdbBasics))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		title string
+		year  int
+		want  []string
+	}{
+		{"every match oldest first", "heat", 0, []string{"tt0097523", "tt0113277"}},
+		{"by year", "Heat", 1995, []string{"tt0113277"}},
+		{"a year off", "Heat", 1996, []string{"tt0113277"}},
+		{"no such year", "Heat", 2020, nil},
+		{"punctuation", "2001 A Space Odyssey", 1968, []string{"tt0062622"}},
+		{"original title", "Sen to Chihiro no Kamikakushi", 0, []string{"tt0245429"}},
+		{"episodes left out", "Pilot", 0, nil},
+		{"unknown", "Nothing", 0, nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := d.LookupTitle(tc.title, tc.year)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(names(got), tc.want) {
+				t.Errorf("LookupTitle(%q, %v) = %v, want %v", tc.title, tc.year, names(got), tc.want)
+			}
+		})
+	}
+
+	got, _ := d.LookupTitle("Heat", 1995)
+	want := model.MediaMetadata{Name: "Heat", Year: 1995, DurationMin: 170, Genre: "Action, Crime, Drama", IMDbID: "tt0113277"}
+	if !reflect.DeepEqual(got[0], want) {
+		t.Errorf("got %+v, want %+v", got[0], want)
+	}
+}
+
+func TestLookupID(t *testing.T) {
+	d, err := Open(writeDataset(t, imdbBasics))
+	if err != nil {
+		t.Fatal(err)
+	}
+	show, err := d.LookupID("tt0903747")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if show.ShowName != "Breaking Bad" || show.Name != "" || show.Year != 2008 {
+		t.Errorf("expected a show, got %+v", show)
+	}
+	episode, err := d.LookupID("tt0959621")
+	if err != nil || episode.Name != "Pilot" {
+		t.Errorf("expected episodes by ID, got %+v, %v", episode, err)
+	}
+	spirited, _ := d.LookupID("tt0245429")
+	if spirited.AltName != "Sen to Chihiro no kamikakushi" {
+		t.Errorf("expected the original title as alt name, got %+v", spirited)
+	}
+	if _, err := d.LookupID("tt0000000"); !errors.Is(err, model.ErrNoTitle) {
+		t.Errorf("expected ErrNoTitle, got %v", err)
+	}
+}
+
+func TestLookup_plainColumns(t *testing.T) {
+	content := strings.Join([]string{
+		"id\ttitle\tyear\tdescription\tcast",
+		"m1\tThe Matrix\t1999\tA hacker learns the truth.\tKeanu Reeves, Carrie-Anne Moss",
+		"m2\tNo Trailing Newline\t2000\t\\N\t",
+	}, "\n")
+	d, err := Open(writeDataset(t, content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := d.LookupTitle("the matrix", 1999)
+	if err != nil || len(got) != 1 {
+		t.Fatalf("LookupTitle = %+v, %v", got, err)
+	}
+	want := model.MediaMetadata{Name: "The Matrix", Year: 1999, Description: "A hacker learns the truth.", Actors: []string{"Keanu Reeves", "Carrie-Anne Moss"}, IMDbID: "m1"}
+	if !reflect.DeepEqual(got[0], want) {
+		t.Errorf("got %+v, want %+v", got[0], want)
+	}
+	last, err := d.LookupID("m2")
+	if err != nil || last.Name != "No Trailing Newline" || last.Description != "" {
+		t.Errorf("expected the last row without a newline, got %+v, %v", last, err)
+	}
+}
+
+func TestIndex_memory(t *testing.T) {
+	was := availableMemory
+	t.Cleanup(func() { availableMemory = was })
+
+	availableMemory = func() uint64 { return 1 }
+	d, err := Open(writeDataset(t, imdbBasics))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Index(); err == nil {
+		t.Fatal("expected an index larger than the memory available to be refused")
+	}
+	if _, err := d.LookupID("tt0113277"); err == nil {
+		t.Error("expected lookups to fail without an index")
+	}
+
+	availableMemory = func() uint64 { return 1 << 30 }
+	d, err = Open(writeDataset(t, imdbBasics))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Index(); err != nil {
+		t.Fatalf("Index: %v", err)
+	}
+}
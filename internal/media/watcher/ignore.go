@@ -0,0 +1,257 @@
+package watcher
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// IgnoreFileName is the name of the per-directory ignore file. The same name
+// is used for the global ignore file in the kinoview config dir.
+const IgnoreFileName = ".kinoviewignore"
+
+// ignorePattern is one compiled line of an ignore file.
+type ignorePattern struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// ignoreRules holds the global patterns plus the patterns of every
+// .kinoviewignore file found while walking the watched tree. Semantics follow
+// gitignore: patterns are relative to the directory holding the ignore file,
+// a pattern without a slash matches at any depth, a trailing slash only
+// matches directories, '!' re-includes and the last matching pattern wins.
+// As with git, nothing below an ignored directory can be re-included.
+//
+// It is only touched from the Watch goroutine, so it's not locked.
+type ignoreRules struct {
+	root   string
+	global []ignorePattern
+	perDir map[string][]ignorePattern
+}
+
+func newIgnoreRules() *ignoreRules {
+	return &ignoreRules{perDir: make(map[string][]ignorePattern)}
+}
+
+// parseIgnore compiles the lines of an ignore file. Invalid patterns are
+// returned as an error together with the patterns which did compile.
+func parseIgnore(b []byte) ([]ignorePattern, error) {
+	var ret []ignorePattern
+	var errs []error
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for sc.Scan() {
+		p, ok, err := compileIgnorePattern(sc.Text())
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if ok {
+			ret = append(ret, p)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	return ret, errors.Join(errs...)
+}
+
+func compileIgnorePattern(line string) (ignorePattern, bool, error) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignorePattern{}, false, nil
+	}
+	var p ignorePattern
+	switch {
+	case strings.HasPrefix(line, "!"):
+		p.negate = true
+		line = line[1:]
+	case strings.HasPrefix(line, `\!`), strings.HasPrefix(line, `\#`):
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return ignorePattern{}, false, nil
+	}
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	var sb strings.Builder
+	sb.WriteString("^")
+	if !anchored {
+		sb.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case strings.HasPrefix(line[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(line[i:], "/**") && i+3 == len(line):
+			sb.WriteString("(?:/.*)?")
+			i += 2
+		case strings.HasPrefix(line[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(line[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := line[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(line):
+			i++
+			sb.WriteString(regexp.QuoteMeta(string(line[i])))
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+	re, err := regexp.Compile(sb.String())
+	if err != nil {
+		return ignorePattern{}, false, fmt.Errorf("invalid ignore pattern '%v': %w", line, err)
+	}
+	p.re = re
+	return p, true, nil
+}
+
+// loadGlobal reads the global ignore file. A missing file clears the global
+// patterns.
+func (ir *ignoreRules) loadGlobal(p string) error {
+	if p == "" {
+		return nil
+	}
+	b, err := os.ReadFile(p)
+	if err != nil {
+		ir.global = nil
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("read global ignore file: %w", err)
+	}
+	ir.global, err = parseIgnore(b)
+	return err
+}
+
+// loadDir (re)reads the ignore file in dir. A missing file drops the
+// directory's patterns. Returns true if the patterns changed.
+func (ir *ignoreRules) loadDir(dir string) (bool, error) {
+	before := ir.perDir[dir]
+	b, err := os.ReadFile(filepath.Join(dir, IgnoreFileName))
+	if err != nil {
+		delete(ir.perDir, dir)
+		if os.IsNotExist(err) {
+			return len(before) != 0, nil
+		}
+		return len(before) != 0, fmt.Errorf("read ignore file in '%v': %w", dir, err)
+	}
+	patterns, err := parseIgnore(b)
+	if len(patterns) == 0 {
+		delete(ir.perDir, dir)
+	} else {
+		ir.perDir[dir] = patterns
+	}
+	return !samePatterns(before, patterns), err
+}
+
+func samePatterns(a, b []ignorePattern) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].re.String() != b[i].re.String() || a[i].negate != b[i].negate || a[i].dirOnly != b[i].dirOnly {
+			return false
+		}
+	}
+	return true
+}
+
+// Ignored reports if p should be skipped by the watcher. Every ancestor of p
+// below the watch root is checked as well, so files inside an ignored
+// directory stay ignored even if the directory is still being watched.
+func (ir *ignoreRules) Ignored(p string, isDir bool) bool {
+	if ir == nil {
+		return false
+	}
+	p = filepath.Clean(p)
+	if filepath.Base(p) == IgnoreFileName {
+		return true
+	}
+	if len(ir.global) == 0 && len(ir.perDir) == 0 {
+		return false
+	}
+	var ancestors []string
+	if ir.root != "" {
+		for d := filepath.Dir(p); d != ir.root && strings.HasPrefix(d, ir.root+string(filepath.Separator)); d = filepath.Dir(d) {
+			ancestors = append(ancestors, d)
+		}
+	}
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		if ir.matches(ancestors[i], true) {
+			return true
+		}
+	}
+	return ir.matches(p, isDir)
+}
+
+// matches evaluates p against the global patterns, then the ignore files
+// from the root downwards, with the last matching pattern deciding.
+func (ir *ignoreRules) matches(p string, isDir bool) bool {
+	ignored := false
+	rel := filepath.Base(p)
+	if ir.root != "" {
+		if r, err := filepath.Rel(ir.root, p); err == nil && !strings.HasPrefix(r, "..") {
+			rel = r
+		}
+	}
+	ignored = evalPatterns(ir.global, filepath.ToSlash(rel), isDir, ignored)
+
+	var dirs []string
+	for d := filepath.Dir(p); ; d = filepath.Dir(d) {
+		if _, ok := ir.perDir[d]; ok {
+			dirs = append(dirs, d)
+		}
+		if d == ir.root || d == filepath.Dir(d) {
+			break
+		}
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		r, err := filepath.Rel(dirs[i], p)
+		if err != nil {
+			continue
+		}
+		ignored = evalPatterns(ir.perDir[dirs[i]], filepath.ToSlash(r), isDir, ignored)
+	}
+	return ignored
+}
+
+func evalPatterns(patterns []ignorePattern, rel string, isDir, ignored bool) bool {
+	for _, pat := range patterns {
+		if pat.dirOnly && !isDir {
+			continue
+		}
+		if pat.re.MatchString(rel) {
+			ignored = !pat.negate
+		}
+	}
+	return ignored
+}
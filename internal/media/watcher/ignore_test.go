@@ -0,0 +1,244 @@
+package watcher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/baalimago/kinoview/internal/model"
+	"github.com/fsnotify/fsnotify"
+)
+
+var jpegMagic = []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x10, 0x4A, 0x46, 0x49, 0x46, 0x00, 0x01, 0x01, 0x00, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00}
+
+func writeFile(t *testing.T, p string, b []byte) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_ignoreRules_Ignored(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, IgnoreFileName), []byte(`
+# comments and blank lines are skipped
+
+*sample*
+trailers/
+/top-only.mkv
+!keep-sample.mkv
+Shows/**/extras
+`))
+	writeFile(t, filepath.Join(root, "Movies", IgnoreFileName), []byte("*.nfo.jpg\n!still-sample.mkv\n"))
+
+	ir := newIgnoreRules()
+	ir.root = root
+	for _, d := range []string{root, filepath.Join(root, "Movies")} {
+		if _, err := ir.loadDir(d); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		rel   string
+		isDir bool
+		want  bool
+	}{
+		{"movie.mkv", false, false},
+		{"movie-sample.mkv", false, true},
+		{"Movies/a/movie.sample.mp4", false, true},
+		{"keep-sample.mkv", false, false},
+		{"trailers", true, true},
+		{"trailers", false, false},
+		{"Movies/trailers/t.mp4", false, true},
+		{"top-only.mkv", false, true},
+		{"Movies/top-only.mkv", false, false},
+		{"Shows/Foo/Season 1/extras", true, true},
+		{"Shows/Foo/Season 1/extras/e.mkv", false, true},
+		{"Movies/poster.nfo.jpg", false, true},
+		{"poster.nfo.jpg", false, false},
+		{"Movies/still-sample.mkv", false, false},
+		{IgnoreFileName, false, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.rel, func(t *testing.T) {
+			got := ir.Ignored(filepath.Join(root, tc.rel), tc.isDir)
+			if got != tc.want {
+				t.Fatalf("Ignored(%q, %v) = %v, want %v", tc.rel, tc.isDir, got, tc.want)
+			}
+		})
+	}
+
+	t.Run("nil rules ignore nothing", func(t *testing.T) {
+		var nilRules *ignoreRules
+		if nilRules.Ignored(filepath.Join(root, "movie-sample.mkv"), false) {
+			t.Fatal("expected nil rules to ignore nothing")
+		}
+	})
+}
+
+func Test_ignoreRules_global(t *testing.T) {
+	root := t.TempDir()
+	globalPath := filepath.Join(t.TempDir(), IgnoreFileName)
+	writeFile(t, globalPath, []byte("/Incoming/\n*.part\n"))
+
+	ir := newIgnoreRules()
+	ir.root = root
+	if err := ir.loadGlobal(globalPath); err != nil {
+		t.Fatal(err)
+	}
+	if !ir.Ignored(filepath.Join(root, "Incoming", "movie.mkv"), false) {
+		t.Error("expected anchored global dir pattern to apply relative to the watch root")
+	}
+	if ir.Ignored(filepath.Join(root, "Movies", "Incoming", "movie.mkv"), false) {
+		t.Error("expected anchored global pattern not to match deeper directories")
+	}
+	if !ir.Ignored(filepath.Join(root, "Movies", "movie.mkv.part"), false) {
+		t.Error("expected unanchored global pattern to match at any depth")
+	}
+
+	t.Run("per-dir file can re-include globally ignored file", func(t *testing.T) {
+		writeFile(t, filepath.Join(root, "Movies", IgnoreFileName), []byte("!*.part\n"))
+		if _, err := ir.loadDir(filepath.Join(root, "Movies")); err != nil {
+			t.Fatal(err)
+		}
+		if ir.Ignored(filepath.Join(root, "Movies", "movie.mkv.part"), false) {
+			t.Error("expected per-dir negation to win over the global pattern")
+		}
+	})
+
+	t.Run("missing global file clears patterns", func(t *testing.T) {
+		if err := os.Remove(globalPath); err != nil {
+			t.Fatal(err)
+		}
+		if err := ir.loadGlobal(globalPath); err != nil {
+			t.Fatal(err)
+		}
+		if ir.Ignored(filepath.Join(root, "Incoming", "movie.mkv"), false) {
+			t.Error("expected no global patterns after the file was removed")
+		}
+	})
+}
+
+func Test_walkDo_ignore(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, IgnoreFileName), []byte("skipped/\n*_sample.jpg\n"))
+	writeFile(t, filepath.Join(root, "keep.jpg"), jpegMagic)
+	writeFile(t, filepath.Join(root, "keep_sample.jpg"), jpegMagic)
+	writeFile(t, filepath.Join(root, "skipped", "image.jpg"), jpegMagic)
+
+	rw := newTestRecursiveWatcher(t)
+	rw.updates = make(chan model.Item, 10)
+	rw.ignore.root = root
+	if err := filepath.WalkDir(root, rw.walkDo); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	close(rw.updates)
+	var got []string
+	for i := range rw.updates {
+		got = append(got, i.Name)
+	}
+	if !slices.Equal(got, []string{"keep.jpg"}) {
+		t.Fatalf("expected only keep.jpg, got: %v", got)
+	}
+	if slices.Contains(rw.watcher.WatchList(), filepath.Join(root, "skipped")) {
+		t.Fatal("expected ignored directory not to be watched")
+	}
+}
+
+func Test_handleEvent_ignoreHotReload(t *testing.T) {
+	root := t.TempDir()
+	ignorePath := filepath.Join(root, IgnoreFileName)
+	writeFile(t, ignorePath, []byte("*.jpg\n"))
+	writeFile(t, filepath.Join(root, "image.jpg"), jpegMagic)
+
+	rw := newTestRecursiveWatcher(t)
+	rw.updates = make(chan model.Item, 10)
+	rw.ignore.root = root
+	if err := filepath.WalkDir(root, rw.walkDo); err != nil {
+		t.Fatal(err)
+	}
+	if len(rw.updates) != 0 {
+		t.Fatalf("expected no updates while ignored, got %v", len(rw.updates))
+	}
+
+	t.Run("events for ignored files are dropped", func(t *testing.T) {
+		err := rw.handleEvent(fsnotify.Event{Name: filepath.Join(root, "image.jpg"), Op: fsnotify.Write})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rw.updates) != 0 {
+			t.Fatalf("expected no updates for ignored file, got %v", len(rw.updates))
+		}
+	})
+
+	t.Run("changed ignore file rescans directory", func(t *testing.T) {
+		writeFile(t, ignorePath, []byte("*.png\n"))
+		err := rw.handleEvent(fsnotify.Event{Name: ignorePath, Op: fsnotify.Write})
+		if err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case i := <-rw.updates:
+			if i.Name != "image.jpg" {
+				t.Fatalf("expected image.jpg, got: %v", i.Name)
+			}
+		default:
+			t.Fatal("expected previously ignored file to be emitted after reload")
+		}
+	})
+
+	t.Run("removed ignore file drops rules", func(t *testing.T) {
+		writeFile(t, ignorePath, []byte("*.jpg\n"))
+		if err := rw.handleEvent(fsnotify.Event{Name: ignorePath, Op: fsnotify.Write}); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Remove(ignorePath); err != nil {
+			t.Fatal(err)
+		}
+		if err := rw.handleEvent(fsnotify.Event{Name: ignorePath, Op: fsnotify.Remove}); err != nil {
+			t.Fatal(err)
+		}
+		if rw.ignore.Ignored(filepath.Join(root, "image.jpg"), false) {
+			t.Fatal("expected rules to be dropped with the ignore file")
+		}
+	})
+}
+
+func Test_Watch_globalIgnoreHotReload(t *testing.T) {
+	root := t.TempDir()
+	cfgDir := t.TempDir()
+	globalPath := filepath.Join(cfgDir, IgnoreFileName)
+	writeFile(t, globalPath, []byte("*.jpg\n"))
+	writeFile(t, filepath.Join(root, "image.jpg"), jpegMagic)
+
+	rw, err := NewRecursiveWatcher(WithGlobalIgnoreFile(globalPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rw.updates = make(chan model.Item, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go func() { _ = rw.Watch(ctx, root) }()
+
+	time.Sleep(50 * time.Millisecond)
+	if len(rw.updates) != 0 {
+		t.Fatalf("expected globally ignored file not to be emitted, got %v updates", len(rw.updates))
+	}
+
+	writeFile(t, globalPath, []byte("*.png\n"))
+	select {
+	case i := <-rw.updates:
+		if i.Name != "image.jpg" {
+			t.Fatalf("expected image.jpg, got: %v", i.Name)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected file to be emitted after global ignore file changed")
+	}
+}
@@ -5,12 +5,13 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
 
 	"github.com/baalimago/go_away_boilerplate/pkg/ancli"
+	"github.com/baalimago/kinoview/internal/media/mediatype"
 	"github.com/baalimago/kinoview/internal/model"
 	"github.com/fsnotify/fsnotify"
 )
@@ -20,19 +21,47 @@ type recursiveWatcher struct {
 	updates chan model.Item
 	errChan chan error
 	warnlog func(msg string, a ...any)
+
+	ignore           *ignoreRules
+	globalIgnorePath string
+	probe            bool
+}
+
+type RecursiveWatcherOption func(*recursiveWatcher)
+
+// WithGlobalIgnoreFile sets the path to an ignore file whose patterns apply
+// to the entire watched tree, relative to the watch root. The file is
+// hot-reloaded the same way as the per-directory ignore files.
+func WithGlobalIgnoreFile(p string) RecursiveWatcherOption {
+	return func(rw *recursiveWatcher) {
+		rw.globalIgnorePath = p
+	}
+}
+
+// WithFFProbe enables confirming media types with ffprobe when the content
+// sniffing can't tell and the type is guessed from the file extension.
+func WithFFProbe(probe bool) RecursiveWatcherOption {
+	return func(rw *recursiveWatcher) {
+		rw.probe = probe
+	}
 }
 
-func NewRecursiveWatcher() (*recursiveWatcher, error) {
+func NewRecursiveWatcher(opts ...RecursiveWatcherOption) (*recursiveWatcher, error) {
 	w, err := fsnotify.NewWatcher()
 	if err != nil {
 		return nil, fmt.Errorf("newRecursiveWatcher failed to create fsnotify.Watcher: %w", err)
 	}
-	return &recursiveWatcher{
-		w,
-		make(chan model.Item),
-		make(chan error),
-		ancli.Warnf,
-	}, nil
+	rw := &recursiveWatcher{
+		watcher: w,
+		updates: make(chan model.Item),
+		errChan: make(chan error),
+		warnlog: ancli.Warnf,
+		ignore:  newIgnoreRules(),
+	}
+	for _, opt := range opts {
+		opt(rw)
+	}
+	return rw, nil
 }
 
 func (rw *recursiveWatcher) Setup(ctx context.Context) (<-chan model.Item, <-chan error, error) {
@@ -50,7 +79,7 @@ func (rw *recursiveWatcher) Close() error {
 }
 
 // checkFile and emit model.Item on updates channel if file is
-// is video-like or image-like
+// is video-like or image-like, see mediatype.Detect
 func (rw *recursiveWatcher) checkFile(p string) error {
 	_, err := os.Stat(p)
 	if err != nil {
@@ -64,14 +93,23 @@ func (rw *recursiveWatcher) checkFile(p string) error {
 		return err
 	}
 	defer f.Close()
-	buf := make([]byte, 512)
-	n, err := f.Read(buf)
-	if err != nil {
+	buf := make([]byte, mediatype.SniffLen)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
 		return err
 	}
-	mimeType := http.DetectContentType(buf[:n])
+	mimeType, sure := mediatype.Detect(p, buf[:n])
+	if !sure && rw.probe && mediatype.IsMedia(mimeType) {
+		probed, err := mediatype.Probe(context.Background(), p)
+		if err != nil {
+			rw.warnlog("ffprobe could not confirm '%v' as '%v': %v", p, mimeType, err)
+		} else {
+			mimeType = probed
+		}
+	}
 
-	if mimeType[:5] == "video" || mimeType[:5] == "image" {
+	if strings.HasPrefix(mimeType, "video/") || strings.HasPrefix(mimeType, "image/") ||
+		strings.HasPrefix(mimeType, "audio/") {
 		rw.updates <- model.Item{Name: path.Base(p), Path: p, MIMEType: mimeType}
 	}
 
@@ -87,11 +125,22 @@ func (rw *recursiveWatcher) walkDo(p string, info os.DirEntry, err error) error
 		}
 		return err
 	}
+	if rw.ignore.Ignored(p, info.IsDir()) {
+		if info.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	}
 	if info.IsDir() {
 		err = rw.watcher.Add(p)
 		if err != nil {
 			return fmt.Errorf("failed to add recursive path: %v", err)
 		}
+		if rw.ignore != nil {
+			if _, err := rw.ignore.loadDir(p); err != nil {
+				rw.warnlog("ignore file: %v", err)
+			}
+		}
 		return nil
 	}
 
@@ -109,7 +158,66 @@ func (rw *recursiveWatcher) handleError(err error) {
 	}
 }
 
+// handleIgnoreEvent reloads the ignore file touched by ev. When the rules of a
+// directory change, the directory is walked again so that files which are no
+// longer ignored are picked up. Files which became ignored stay indexed, since
+// the watcher has no way of retracting items.
+func (rw *recursiveWatcher) handleIgnoreEvent(ev fsnotify.Event) error {
+	if rw.ignore == nil {
+		return nil
+	}
+	if rw.globalIgnorePath != "" && filepath.Clean(ev.Name) == filepath.Clean(rw.globalIgnorePath) {
+		ancli.Noticef("reloading global ignore file: %v", ev.Name)
+		if err := rw.ignore.loadGlobal(rw.globalIgnorePath); err != nil {
+			return err
+		}
+		if rw.ignore.root == "" {
+			return nil
+		}
+		return filepath.WalkDir(rw.ignore.root, rw.walkDo)
+	}
+	dir := filepath.Dir(ev.Name)
+	changed, err := rw.ignore.loadDir(dir)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		return nil
+	}
+	ancli.Noticef("reloaded ignore file: %v", ev.Name)
+	return filepath.WalkDir(dir, rw.walkDo)
+}
+
+func (rw *recursiveWatcher) isIgnoreEvent(ev fsnotify.Event) bool {
+	if filepath.Base(ev.Name) == IgnoreFileName {
+		return true
+	}
+	return rw.globalIgnorePath != "" && filepath.Clean(ev.Name) == filepath.Clean(rw.globalIgnorePath)
+}
+
+// isGlobalIgnoreDirEvent reports events for files next to the global ignore
+// file. The directory is watched only to hot-reload that single file.
+func (rw *recursiveWatcher) isGlobalIgnoreDirEvent(ev fsnotify.Event) bool {
+	if rw.globalIgnorePath == "" || rw.ignore == nil {
+		return false
+	}
+	dir := filepath.Dir(filepath.Clean(rw.globalIgnorePath))
+	if rw.ignore.root != "" && (dir == rw.ignore.root || strings.HasPrefix(dir, rw.ignore.root+string(filepath.Separator))) {
+		return false
+	}
+	return filepath.Dir(ev.Name) == dir
+}
+
 func (rw *recursiveWatcher) handleEvent(ev fsnotify.Event) error {
+	if rw.isIgnoreEvent(ev) {
+		if ev.Has(fsnotify.Write) || ev.Has(fsnotify.Create) || ev.Has(fsnotify.Remove) || ev.Has(fsnotify.Rename) {
+			return rw.handleIgnoreEvent(ev)
+		}
+		return nil
+	}
+	if rw.isGlobalIgnoreDirEvent(ev) {
+		return nil
+	}
 	if ev.Has(fsnotify.Write) || ev.Has(fsnotify.Create) {
 		ancli.Noticef("Got file event: %v", ev)
 
@@ -120,6 +228,9 @@ func (rw *recursiveWatcher) handleEvent(ev fsnotify.Event) error {
 				// It might have disappeared, treat as non-fatal
 				return nil
 			}
+			if rw.ignore.Ignored(ev.Name, fi.IsDir()) {
+				return nil
+			}
 			if fi.IsDir() {
 				// Add the new directory itself
 				if err := rw.watcher.Add(ev.Name); err != nil {
@@ -127,22 +238,7 @@ func (rw *recursiveWatcher) handleEvent(ev fsnotify.Event) error {
 				}
 
 				// Walk it to pick up any existing children
-				return filepath.WalkDir(ev.Name, func(p string, d os.DirEntry, err error) error {
-					if err != nil {
-						if errors.Is(err, io.EOF) {
-							rw.warnlog("skipping: '%v', got EOF error", p)
-							return nil
-						}
-						return err
-					}
-					if d.IsDir() {
-						if err := rw.watcher.Add(p); err != nil {
-							return fmt.Errorf("failed to add recursive path: %v", err)
-						}
-						return nil
-					}
-					return rw.checkFile(p)
-				})
+				return filepath.WalkDir(ev.Name, rw.walkDo)
 			}
 
 			// It's a file: check it
@@ -150,6 +246,9 @@ func (rw *recursiveWatcher) handleEvent(ev fsnotify.Event) error {
 		}
 
 		// Non-create write events: treat as file updates
+		if rw.ignore.Ignored(ev.Name, false) {
+			return nil
+		}
 		return rw.checkFile(ev.Name)
 	}
 
@@ -172,11 +271,34 @@ func (rw recursiveWatcher) checkPath(path string) error {
 	return nil
 }
 
+// watchGlobalIgnoreDir adds the directory of the global ignore file to the
+// watcher so that the file can be hot-reloaded. fsnotify can't watch files
+// which don't exist yet, hence the directory.
+func (rw *recursiveWatcher) watchGlobalIgnoreDir() {
+	if rw.globalIgnorePath == "" {
+		return
+	}
+	dir := filepath.Dir(rw.globalIgnorePath)
+	if _, err := os.Stat(dir); err != nil {
+		return
+	}
+	if err := rw.watcher.Add(dir); err != nil {
+		rw.warnlog("failed to watch global ignore dir: %v", err)
+	}
+}
+
 func (rw *recursiveWatcher) Watch(ctx context.Context, path string) error {
 	err := rw.checkPath(path)
 	if err != nil {
 		return fmt.Errorf("recursiveWatcher pathCheck failed: %v", err)
 	}
+	if rw.ignore != nil {
+		rw.ignore.root = filepath.Clean(path)
+		if err := rw.ignore.loadGlobal(rw.globalIgnorePath); err != nil {
+			rw.warnlog("global ignore file: %v", err)
+		}
+		rw.watchGlobalIgnoreDir()
+	}
 	err = filepath.WalkDir(path, rw.walkDo)
 	if err != nil {
 		if !errors.Is(err, io.EOF) {
@@ -740,3 +740,35 @@ func Test_Watch_CreateNestedDirectories_AreWatchedAndIndexed(t *testing.T) {
 	cancel()
 	<-done
 }
+
+func Test_recursiveWatcher_checkFile_mediaTypes(t *testing.T) {
+	dir := t.TempDir()
+	mkv := filepath.Join(dir, "movie.mkv")
+	if err := os.WriteFile(mkv, []byte("\x1a\x45\xdf\xa3\x93\x42\x82\x88matroska\x42\x87\x81\x04"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mp3 := filepath.Join(dir, "song.mp3")
+	if err := os.WriteFile(mp3, []byte("ID3\x03\x00\x00\x00\x00\x00\x00"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	txt := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(txt, []byte("just some notes"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rw := newTestRecursiveWatcher(t)
+	rw.updates = make(chan model.Item, 3)
+	for _, p := range []string{mkv, mp3, txt} {
+		if err := rw.checkFile(p); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if len(rw.updates) != 2 {
+		t.Fatalf("expected exactly two updates, got: %v", len(rw.updates))
+	}
+	got := <-rw.updates
+	testboil.FailTestIfDiff(t, got.MIMEType, "video/x-matroska")
+	got = <-rw.updates
+	testboil.FailTestIfDiff(t, got.MIMEType, "audio/mpeg")
+}
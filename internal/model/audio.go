@@ -0,0 +1,50 @@
+package model
+
+// AudioTags holds the tags read from an audio file, such as ID3 or Vorbis
+// comments. Zero values mean that the tag was missing.
+type AudioTags struct {
+	Title       string `json:"title,omitempty"`
+	Artist      string `json:"artist,omitempty"`
+	AlbumArtist string `json:"albumArtist,omitempty"`
+	Album       string `json:"album,omitempty"`
+	Genre       string `json:"genre,omitempty"`
+	Year        int    `json:"year,omitempty"`
+	Track       int    `json:"track,omitempty"`
+	TrackTotal  int    `json:"trackTotal,omitempty"`
+	Disc        int    `json:"disc,omitempty"`
+}
+
+// IsEmpty reports if no tag at all was found.
+func (t AudioTags) IsEmpty() bool {
+	return t == AudioTags{}
+}
+
+// MusicTrack is an audio Item enriched with its resolved position on an
+// album.
+type MusicTrack struct {
+	Item
+	Title string `json:"title"`
+	Track int    `json:"track"`
+	Disc  int    `json:"disc"`
+}
+
+// MusicAlbum groups the tracks of one album.
+type MusicAlbum struct {
+	Title string `json:"title"`
+	Year  int    `json:"year,omitempty"`
+	// CoverID is the ID of the thumbnail image of the album, empty if there
+	// is none.
+	CoverID string       `json:"coverId,omitempty"`
+	Tracks  []MusicTrack `json:"tracks"`
+}
+
+// MusicArtist groups the albums of one artist.
+type MusicArtist struct {
+	Name   string       `json:"name"`
+	Albums []MusicAlbum `json:"albums"`
+}
+
+// MusicResponse is the top-level API response of the music library.
+type MusicResponse struct {
+	Artists []MusicArtist `json:"artists"`
+}
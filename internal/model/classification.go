@@ -0,0 +1,114 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+// ClassificationPriority orders the classification queue, the most urgent
+// being the lowest. Items of the same priority are classified in the order
+// they were queued.
+type ClassificationPriority uint8
+
+const (
+	// PriorityBrowsed is for items someone is looking at right now.
+	PriorityBrowsed ClassificationPriority = iota
+	// PrioritySuggested is for items the concierge wants to suggest.
+	PrioritySuggested
+	// PriorityNew is for items which haven't been tried yet.
+	PriorityNew
+	// PriorityRetry is for items which failed to classify before.
+	PriorityRetry
+)
+
+func (p *ClassificationPriority) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "browsed":
+		*p = PriorityBrowsed
+	case "suggested":
+		*p = PrioritySuggested
+	case "new":
+		*p = PriorityNew
+	case "retry":
+		*p = PriorityRetry
+	default:
+		return errors.New("invalid classification priority")
+	}
+	return nil
+}
+
+func (p ClassificationPriority) MarshalText() ([]byte, error) {
+	switch p {
+	case PriorityBrowsed:
+		return []byte("browsed"), nil
+	case PrioritySuggested:
+		return []byte("suggested"), nil
+	case PriorityNew:
+		return []byte("new"), nil
+	case PriorityRetry:
+		return []byte("retry"), nil
+	default:
+		return nil, errors.New("invalid classification priority")
+	}
+}
+
+// QueuedClassification is an item waiting for a classification worker.
+type QueuedClassification struct {
+	ID       string                 `json:"id"`
+	Name     string                 `json:"name"`
+	Priority ClassificationPriority `json:"priority"`
+	// Position in the queue, from 0 for the next one to be classified.
+	Position int       `json:"position"`
+	Since    time.Time `json:"since"`
+	// ETA is when the item is expected to be classified, zero until a
+	// classification has been timed.
+	ETA time.Time `json:"eta,omitzero"`
+}
+
+// ClassificationAssignment is an item a classification worker is busy with.
+type ClassificationAssignment struct {
+	Worker int       `json:"worker"`
+	ID     string    `json:"id"`
+	Name   string    `json:"name"`
+	Since  time.Time `json:"since"`
+	// Batch is how many items, this one included, are classified in one go,
+	// such as the episodes of a season. Zero when it's just the one.
+	Batch int `json:"batch,omitempty"`
+}
+
+// ClassificationQueue is the state of the classification station, the body
+// of GET /gallery/classification/queue.
+type ClassificationQueue struct {
+	Queued  []QueuedClassification     `json:"queued"`
+	Workers []ClassificationAssignment `json:"workers"`
+	// AverageSeconds a classification takes, 0 until one has been timed.
+	AverageSeconds float64 `json:"average_seconds"`
+}
+
+// ClassificationResult is what became of one attempt of a model to classify
+// an item.
+type ClassificationResult string
+
+const (
+	// ResultAccepted is a classification which was kept.
+	ResultAccepted ClassificationResult = "accepted"
+	// ResultUnsure is a classification the model wasn't confident enough
+	// in, handed on to the next model.
+	ResultUnsure ClassificationResult = "unsure"
+	// ResultFailed is a classification which failed, such as an answer
+	// which isn't metadata.
+	ResultFailed ClassificationResult = "failed"
+)
+
+// ClassificationOutcome is one attempt of a model to classify an item, as
+// logged by the classifier routing between models.
+type ClassificationOutcome struct {
+	Time   time.Time            `json:"time"`
+	Model  string               `json:"model"`
+	ItemID string               `json:"itemID"`
+	Result ClassificationResult `json:"result"`
+	// Confidence is the lowest the model had in a field, 0 if it didn't
+	// say.
+	Confidence float64 `json:"confidence,omitempty"`
+	Error      string  `json:"error,omitempty"`
+}
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestClassificationPriority_text(t *testing.T) {
+	for _, p := range []ClassificationPriority{PriorityBrowsed, PrioritySuggested, PriorityNew, PriorityRetry} {
+		b, err := json.Marshal(p)
+		if err != nil {
+			t.Fatalf("marshal %d: %v", p, err)
+		}
+		var got ClassificationPriority
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Fatalf("unmarshal %s: %v", b, err)
+		}
+		if got != p {
+			t.Errorf("round trip of %s = %d, want %d", b, got, p)
+		}
+	}
+	if _, err := json.Marshal(ClassificationPriority(42)); err == nil {
+		t.Error("expected an unknown priority not to marshal")
+	}
+	var p ClassificationPriority
+	if err := json.Unmarshal([]byte(`"urgent"`), &p); err == nil {
+		t.Error("expected an unknown priority not to unmarshal")
+	}
+}
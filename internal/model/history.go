@@ -0,0 +1,139 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"maps"
+	"slices"
+	"time"
+)
+
+// MetadataSource is what changed the metadata of an item.
+type MetadataSource string
+
+const (
+	// SourceClassifier is the classification station's LLM.
+	SourceClassifier MetadataSource = "classifier"
+	// SourcePreclassify is the metadata worked out from the file name,
+	// folders and sidecars, without the classifier.
+	SourcePreclassify MetadataSource = "preclassify"
+	// SourceAgent is an agent's tool, such as the concierge's
+	// update_metadata.
+	SourceAgent MetadataSource = "agent"
+	// SourceHuman is someone using the CLI or the API.
+	SourceHuman MetadataSource = "human"
+)
+
+// Provenance of a metadata change.
+type Provenance struct {
+	Source MetadataSource `json:"source"`
+	// Actor names who within the source, e.g. "concierge" or the command
+	// a human used.
+	Actor string `json:"actor,omitempty"`
+	// Model is the LLM behind the change, if any.
+	Model string `json:"model,omitempty"`
+}
+
+// FieldChange is the change of one metadata field, by its JSON key. From is
+// empty when the field was added, To when it was removed.
+type FieldChange struct {
+	Field string          `json:"field"`
+	From  json.RawMessage `json:"from,omitempty"`
+	To    json.RawMessage `json:"to,omitempty"`
+}
+
+// MetadataChange is one entry of the metadata history of an item.
+type MetadataChange struct {
+	// Version counts the changes of the item from 1, oldest first.
+	Version int        `json:"version,omitempty"`
+	Time    time.Time  `json:"time"`
+	By      Provenance `json:"by"`
+	// Note says why, when the provenance doesn't, e.g. "review rejected".
+	Note string `json:"note,omitempty"`
+	// Metadata as it is after the change, nil if it was cleared.
+	Metadata *MediaMetadata `json:"metadata"`
+	Diff     []FieldChange  `json:"diff"`
+}
+
+var (
+	// ErrNoItem is returned for the history of an item the store doesn't
+	// have.
+	ErrNoItem = errors.New("no such item")
+	// ErrNoVersion is returned when reverting to a version an item never
+	// had.
+	ErrNoVersion = errors.New("no such version")
+)
+
+// HistoryResponse is the body of GET /gallery/items/{id}/history.
+type HistoryResponse struct {
+	ID      string           `json:"id"`
+	Changes []MetadataChange `json:"changes"`
+}
+
+// RevertRequest is the body of POST /gallery/items/{id}/history.
+type RevertRequest struct {
+	Version int `json:"version"`
+}
+
+// DiffMetadata lists the fields which differ between from and to, by JSON
+// key in order. Metadata which isn't a JSON object is compared as a whole,
+// under the key "metadata".
+func DiffMetadata(from, to *MediaMetadata) []FieldChange {
+	a, aOK := metadataObject(from)
+	b, bOK := metadataObject(to)
+	if !aOK || !bOK {
+		fromJSON, toJSON := metadataJSON(from), metadataJSON(to)
+		if bytes.Equal(fromJSON, toJSON) {
+			return nil
+		}
+		return []FieldChange{{Field: "metadata", From: fromJSON, To: toJSON}}
+	}
+	keys := slices.Sorted(maps.Keys(a))
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+
+	var diff []FieldChange
+	for _, k := range keys {
+		if !bytes.Equal(compactJSON(a[k]), compactJSON(b[k])) {
+			diff = append(diff, FieldChange{Field: k, From: a[k], To: b[k]})
+		}
+	}
+	return diff
+}
+
+// metadataObject returns the fields of md by JSON key, reporting whether it
+// is an object. Nil metadata has no fields.
+func metadataObject(md *MediaMetadata) (map[string]json.RawMessage, bool) {
+	if md == nil {
+		return nil, true
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(metadataJSON(md), &fields); err != nil {
+		return nil, false
+	}
+	return fields, true
+}
+
+func metadataJSON(md *MediaMetadata) json.RawMessage {
+	if md == nil {
+		return nil
+	}
+	b, err := json.Marshal(md)
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+func compactJSON(raw json.RawMessage) []byte {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return raw
+	}
+	return buf.Bytes()
+}
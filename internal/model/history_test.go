@@ -0,0 +1,56 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDiffMetadata(t *testing.T) {
+	heat := &MediaMetadata{Name: "Heat", Year: 1995, Actors: []string{"Al Pacino"}}
+
+	t.Run("classified", func(t *testing.T) {
+		diff := DiffMetadata(nil, heat)
+		if len(diff) != 3 || diff[0].Field != "actors" || diff[0].From != nil || string(diff[2].To) != "1995" {
+			t.Errorf("unexpected diff: %+v", diff)
+		}
+	})
+
+	t.Run("edited", func(t *testing.T) {
+		edited := &MediaMetadata{Name: "Heat", Year: 1994, Description: "Cops and robbers."}
+		diff := DiffMetadata(heat, edited)
+		want := []FieldChange{
+			{Field: "actors", From: json.RawMessage(`["Al Pacino"]`)},
+			{Field: "description", To: json.RawMessage(`"Cops and robbers."`)},
+			{Field: "year", From: json.RawMessage(`1995`), To: json.RawMessage(`1994`)},
+		}
+		if len(diff) != len(want) {
+			t.Fatalf("diff = %+v, want %+v", diff, want)
+		}
+		for n := range want {
+			if diff[n].Field != want[n].Field || string(diff[n].From) != string(want[n].From) || string(diff[n].To) != string(want[n].To) {
+				t.Errorf("diff[%v] = %+v, want %+v", n, diff[n], want[n])
+			}
+		}
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		same := *heat
+		if diff := DiffMetadata(heat, &same); diff != nil {
+			t.Errorf("expected no diff, got %+v", diff)
+		}
+		if diff := DiffMetadata(nil, nil); diff != nil {
+			t.Errorf("expected no diff, got %+v", diff)
+		}
+	})
+
+	t.Run("not an object", func(t *testing.T) {
+		var odd MediaMetadata
+		if err := json.Unmarshal([]byte(`"Heat (1995)"`), &odd); err != nil {
+			t.Fatal(err)
+		}
+		diff := DiffMetadata(&odd, heat)
+		if len(diff) != 1 || diff[0].Field != "metadata" || string(diff[0].From) != `"Heat (1995)"` {
+			t.Errorf("unexpected diff: %+v", diff)
+		}
+	})
+}
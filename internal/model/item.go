@@ -5,6 +5,8 @@ import (
 	"image"
 	"strings"
 	"time"
+
+	"github.com/baalimago/kinoview/internal/lang"
 )
 
 type PaginatedRequest struct {
@@ -13,6 +15,13 @@ type PaginatedRequest struct {
 	// Search is an optional global search query (case-insensitive) across name, path, and metadata.
 	Search   string `json:"search"`
 	MIMEType string `json:"MIMEType"`
+	// TakenFrom and TakenTo optionally limit the items to photos taken
+	// within [TakenFrom, TakenTo).
+	TakenFrom time.Time `json:"takenFrom,omitzero"`
+	TakenTo   time.Time `json:"takenTo,omitzero"`
+	// Tags optionally limit the items to those of some genre, mood or age
+	// rating, or without some content warning.
+	Tags TagFilter `json:"tags,omitzero"`
 }
 
 type PaginatedResponse[T any] struct {
@@ -37,17 +46,48 @@ type Item struct {
 	Thumbnail Image
 	Name      string
 	MIMEType  string
-	Metadata  *json.RawMessage
+	Metadata  *MediaMetadata
 
 	ClassificationAttempts int       `json:"classificationAttempts,omitempty"`
 	ClassificationLastTry  time.Time `json:"classificationLastTry"`
 	ClassificationError    string    `json:"classificationError,omitempty"`
+	// ClassificationModel is the LLM model which classified the metadata,
+	// empty if none did.
+	ClassificationModel string `json:"classificationModel,omitempty"`
+	// TagsVersion of the tags the classifier was asked for, 0 if it never
+	// was. Set even if it didn't know any, so they aren't asked for again.
+	TagsVersion int `json:"tagsVersion,omitempty"`
+
+	// Confidence the classifier had in the metadata, nil if it didn't say.
+	Confidence Confidence `json:"confidence,omitempty"`
+	// Review is set while the metadata waits for someone to look at it.
+	Review *Review `json:"review,omitempty"`
 
 	// SubtitlePaths are user-specified absolute paths to external subtitle files
 	// associated with this media item. Files must exist and have a subtitle extension
 	// (.srt, .vtt, .sub, .ass, .ssa). Used by the stream manager's findExternal
 	// discovery and surfaced in the media list command.
 	SubtitlePaths []string `json:"subtitlePaths,omitempty"`
+
+	// SubtitleOffsets are the user's timing corrections of subtitle streams,
+	// in milliseconds keyed by stream index. Positive offsets show the
+	// subtitles later.
+	SubtitleOffsets map[string]int64 `json:"subtitleOffsets,omitempty"`
+
+	// Audio holds the tags read from audio items, nil for other media.
+	Audio *AudioTags `json:"audio,omitempty"`
+
+	// Photo holds the EXIF metadata of images, nil if there was none.
+	Photo *PhotoMetadata `json:"photo,omitempty"`
+
+	// MovieHash is the OpenSubtitles hash of videos, identifying the exact
+	// release when looking for subtitles.
+	MovieHash string `json:"movieHash,omitempty"`
+}
+
+// SubtitleOffset of the subtitle stream at streamIndex, zero if unset.
+func (i Item) SubtitleOffset(streamIndex string) time.Duration {
+	return time.Duration(i.SubtitleOffsets[streamIndex]) * time.Millisecond
 }
 
 type ViewMetadata struct {
@@ -87,6 +127,9 @@ type ClientContext struct {
 	StartTime      time.Time      `json:"startTime"`
 	ViewingHistory []ViewMetadata `json:"viewingHistory"`
 	LastPlayedName string         `json:"lastPlayedName"`
+	// SubtitleLanguages preferred on the client device, overriding the
+	// server default when set.
+	SubtitleLanguages lang.Preferences `json:"subtitleLanguages,omitempty"`
 }
 
 type ClientContextDelta struct {
@@ -117,6 +160,7 @@ func (cc *ClientContext) UnmarshalJSON(data []byte) error {
 		}
 		cc.StartTime = t
 	}
+	cc.SubtitleLanguages = lang.FromTags(cc.SubtitleLanguages)
 	return nil
 }
 
@@ -194,17 +238,47 @@ func MatchesGlobalSearch(it Item, needle string) bool {
 		return true
 	}
 
-	if it.Metadata != nil {
-		var metadata map[string]any
-		if err := json.Unmarshal(*it.Metadata, &metadata); err == nil {
-			if SearchMetadata(metadata, needle) {
+	if a := it.Audio; a != nil {
+		for _, tag := range []string{a.Title, a.Artist, a.AlbumArtist, a.Album, a.Genre} {
+			if strings.Contains(strings.ToLower(tag), needle) {
 				return true
 			}
 		}
 	}
+
+	if p := it.Photo; p != nil {
+		for _, tag := range []string{p.CameraMake, p.CameraModel, p.LensModel} {
+			if strings.Contains(strings.ToLower(tag), needle) {
+				return true
+			}
+		}
+	}
+
+	if it.Metadata != nil && it.Metadata.Matches(needle) {
+		return true
+	}
 	return false
 }
 
+// TakenWithin reports if the item is a photo taken within [from, to). Zero
+// bounds are open. Items without a capture date only match when both bounds
+// are open.
+func TakenWithin(it Item, from, to time.Time) bool {
+	if from.IsZero() && to.IsZero() {
+		return true
+	}
+	if it.Photo == nil || it.Photo.TakenAt.IsZero() {
+		return false
+	}
+	if !from.IsZero() && it.Photo.TakenAt.Before(from) {
+		return false
+	}
+	if !to.IsZero() && !it.Photo.TakenAt.Before(to) {
+		return false
+	}
+	return true
+}
+
 // SearchMetadata recursively searches through metadata for a substring match.
 func SearchMetadata(data any, needle string) bool {
 	switch v := data.(type) {
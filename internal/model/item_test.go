@@ -55,6 +55,19 @@ func TestClientContextUnmarshalJSON_RFC3339Format(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "subtitle languages are normalised",
+			jsonData: `{
+				"sessionId": "session-789",
+				"subtitleLanguages": ["swe", "ja-JP", "sv", "und"]
+			}`,
+			expectErr: false,
+			validate: func(t *testing.T, cc *ClientContext) {
+				if got := cc.SubtitleLanguages.String(); got != "sv,ja" {
+					t.Errorf("expected subtitleLanguages 'sv,ja', got %q", got)
+				}
+			},
+		},
 		{
 			name: "Empty startTime should remain zero",
 			jsonData: `{
@@ -323,7 +336,7 @@ func TestMatchesGlobalSearch(t *testing.T) {
 	})
 
 	t.Run("matches metadata fields", func(t *testing.T) {
-		md := json.RawMessage(`{"title":"Inception","director":"Christopher Nolan"}`)
+		md := MediaMetadata{Name: "Inception", Extra: map[string]json.RawMessage{"director": json.RawMessage(`"Christopher Nolan"`)}}
 		it := Item{Name: "movie.mp4", Path: "/v/", Metadata: &md}
 		if !MatchesGlobalSearch(it, "nolan") {
 			t.Fatal("should match metadata director")
@@ -334,7 +347,7 @@ func TestMatchesGlobalSearch(t *testing.T) {
 	})
 
 	t.Run("matches metadata array values", func(t *testing.T) {
-		md := json.RawMessage(`{"tags":["action","sci-fi"]}`)
+		md := MediaMetadata{Extra: map[string]json.RawMessage{"tags": json.RawMessage(`["action","sci-fi"]`)}}
 		it := Item{Name: "f.mp4", Path: "/p/", Metadata: &md}
 		if !MatchesGlobalSearch(it, "sci-fi") {
 			t.Fatal("should match tag")
@@ -351,11 +364,14 @@ func TestMatchesGlobalSearch(t *testing.T) {
 		}
 	})
 
-	t.Run("handles invalid JSON metadata gracefully", func(t *testing.T) {
-		md := json.RawMessage(`not valid json`)
+	t.Run("handles non-object metadata gracefully", func(t *testing.T) {
+		var md MediaMetadata
+		if err := json.Unmarshal([]byte(`"not an object"`), &md); err != nil {
+			t.Fatal(err)
+		}
 		it := Item{Name: "broken.mp4", Path: "/v/", Metadata: &md}
-		if MatchesGlobalSearch(it, "valid") {
-			t.Fatal("should not panic on invalid metadata JSON")
+		if MatchesGlobalSearch(it, "object") {
+			t.Fatal("should not search non-object metadata")
 		}
 		if !MatchesGlobalSearch(it, "broken") {
 			t.Fatal("should match name despite invalid metadata")
@@ -440,3 +456,13 @@ func TestSearchMetadata(t *testing.T) {
 		}
 	})
 }
+
+func TestItemSubtitleOffset(t *testing.T) {
+	item := Item{SubtitleOffsets: map[string]int64{"-1": -1500}}
+	if got := item.SubtitleOffset("-1"); got != -1500*time.Millisecond {
+		t.Errorf("expected -1.5s, got %v", got)
+	}
+	if got := item.SubtitleOffset("2"); got != 0 {
+		t.Errorf("expected no offset for unset stream, got %v", got)
+	}
+}
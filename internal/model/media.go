@@ -1,7 +1,61 @@
 package model
 
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
 type MediaInfo struct {
 	Streams []Stream `json:"streams"`
+	// DualPairs are the subtitle streams in different languages which can be
+	// shown at once, see GET /gallery/streams/{vid}/dual/{primary}/{secondary}.
+	DualPairs []DualPair `json:"dualPairs,omitempty"`
+}
+
+// Duration of the media, as the longest of its video and audio streams. Zero
+// if ffprobe didn't say.
+func (mi MediaInfo) Duration() time.Duration {
+	var longest time.Duration
+	for _, s := range mi.Streams {
+		if s.CodecType != "video" && s.CodecType != "audio" {
+			continue
+		}
+		longest = max(longest, s.duration())
+	}
+	return longest
+}
+
+// duration of the stream. Matroska leaves it to the DURATION tag, as in
+// 01:32:01.376000000.
+func (s Stream) duration() time.Duration {
+	if sec, err := strconv.ParseFloat(s.Duration, 64); err == nil {
+		return time.Duration(sec * float64(time.Second))
+	}
+	h, rest, ok := strings.Cut(s.Tags.Duration, ":")
+	if !ok {
+		return 0
+	}
+	m, sec, ok := strings.Cut(rest, ":")
+	if !ok {
+		return 0
+	}
+	hours, errH := strconv.Atoi(h)
+	minutes, errM := strconv.Atoi(m)
+	seconds, errS := strconv.ParseFloat(sec, 64)
+	if errH != nil || errM != nil || errS != nil {
+		return 0
+	}
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + time.Duration(seconds*float64(time.Second))
+}
+
+// DualPair is a pair of subtitle streams in different languages. Either may
+// be the primary, shown on top.
+type DualPair struct {
+	Primary           int    `json:"primary"`
+	Secondary         int    `json:"secondary"`
+	PrimaryLanguage   string `json:"primaryLanguage"`
+	SecondaryLanguage string `json:"secondaryLanguage"`
 }
 
 type Stream struct {
@@ -42,8 +96,25 @@ type Stream struct {
 	Duration           string      `json:"duration,omitempty"`
 	Disposition        Disposition `json:"disposition"`
 	Tags               Tags        `json:"tags"`
+	// OCR is set on bitmap subtitle streams (PGS, VobSub, DVB) which are
+	// extracted as text by optical character recognition. Expect the odd
+	// misread character.
+	OCR bool `json:"ocr,omitempty"`
+	// Formats a subtitle stream can be served as, "vtt" and for styled
+	// subtitles "ass". Empty if it can't be served at all.
+	Formats []string `json:"formats,omitempty"`
+	// Provider a fetched external subtitle was downloaded from, and how it
+	// was matched to the media, such as "moviehash" for the exact release.
+	Provider  string `json:"provider,omitempty"`
+	MatchedBy string `json:"matchedBy,omitempty"`
 }
 
+// Subtitle formats served by the stream endpoint.
+const (
+	FormatVTT = "vtt"
+	FormatASS = "ass"
+)
+
 type Disposition struct {
 	Default         int `json:"default"`
 	Dub             int `json:"dub"`
@@ -62,6 +133,8 @@ type Disposition struct {
 type Tags struct {
 	Title                    string `json:"title,omitempty"`
 	Language                 string `json:"language,omitempty"`
+	Filename                 string `json:"filename,omitempty"`
+	MIMEType                 string `json:"mimetype,omitempty"`
 	BPS                      string `json:"BPS,omitempty"`
 	Duration                 string `json:"DURATION,omitempty"`
 	NumberOfFrames           string `json:"NUMBER_OF_FRAMES,omitempty"`
@@ -3,6 +3,7 @@ package model
 import (
 	"encoding/json"
 	"testing"
+	"time"
 )
 
 func TestMediaInfo_UnmarshalJSON(t *testing.T) {
@@ -67,3 +68,42 @@ func TestMediaInfo_UnmarshalJSON(t *testing.T) {
 		t.Errorf("Expected disposition default 1, got %d", stream.Disposition.Default)
 	}
 }
+
+func TestMediaInfo_Duration(t *testing.T) {
+	tests := []struct {
+		name string
+		info MediaInfo
+		want time.Duration
+	}{
+		{
+			name: "stream duration",
+			info: MediaInfo{Streams: []Stream{{CodecType: "video", Duration: "5400.5"}}},
+			want: 90*time.Minute + 500*time.Millisecond,
+		},
+		{
+			name: "matroska tag, longest stream",
+			info: MediaInfo{Streams: []Stream{
+				{CodecType: "video", Tags: Tags{Duration: "01:32:01.000000000"}},
+				{CodecType: "audio", Tags: Tags{Duration: "01:32:02.000000000"}},
+			}},
+			want: time.Hour + 32*time.Minute + 2*time.Second,
+		},
+		{
+			name: "subtitles don't count",
+			info: MediaInfo{Streams: []Stream{{CodecType: "subtitle", Duration: "7200"}}},
+			want: 0,
+		},
+		{
+			name: "unknown",
+			info: MediaInfo{Streams: []Stream{{CodecType: "video", Tags: Tags{Duration: "soon"}}}},
+			want: 0,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.info.Duration(); got != tc.want {
+				t.Errorf("Duration() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,392 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MediaMetadata is the classified metadata of an item, as described by
+// constants.MetadataFormat and constants.MusicMetadataFormat. Zero values are
+// unknown, except numbers decoded as an explicit 0, such as the season of
+// specials, which are encoded as such again.
+//
+// It is decoded leniently, since it's written by LLMs: numbers may be quoted,
+// actors may be a comma separated string. Values which can't be used, and
+// fields which aren't known here, are kept in Extra so that nothing written is
+// lost. Validate reports whether all of it made sense.
+type MediaMetadata struct {
+	Name        string
+	ShowName    string
+	AltName     string
+	Actors      []string
+	Year        int
+	Description string
+	Language    string
+	DurationMin int
+	Season      int
+	Episode     int
+	ExtraTo     string
+
+	Artist      string
+	AlbumArtist string
+	Album       string
+	Track       int
+	Disc        int
+	Genre       string
+	// Mood, AgeRating and ContentWarnings tag videos, along with Genre.
+	Mood            []string
+	AgeRating       string
+	ContentWarnings []string
+
+	IMDbID string
+	TMDbID string
+
+	// Extra holds the fields which aren't known above, and known fields
+	// whose value couldn't be used, by their JSON key.
+	Extra map[string]json.RawMessage
+
+	// invalid holds metadata which isn't a JSON object at all, kept as is.
+	invalid json.RawMessage
+	// zeros has the bit of the index in fields of each number decoded as an
+	// explicit 0.
+	zeros uint64
+}
+
+// metadataField maps a JSON key to the MediaMetadata field it's decoded to.
+// Exactly one of str, num and strs is set.
+type metadataField struct {
+	key string
+	// aliases are other keys accepted when decoding, the field is always
+	// encoded as key.
+	aliases []string
+	// misspelt keys are kept for compatibility, their aliases win when
+	// both are present.
+	misspelt bool
+	str      *string
+	num      *int
+	strs     *[]string
+}
+
+// decodeKeys of the field, in the order they're tried.
+func (f metadataField) decodeKeys() []string {
+	if f.misspelt {
+		return append(slices.Clone(f.aliases), f.key)
+	}
+	return append([]string{f.key}, f.aliases...)
+}
+
+// placeholders written by LLMs for numbers they don't know, which are
+// unknown rather than unusable.
+var placeholders = []string{"", "unknown", "n/a", "na", "none", "null", "nil", "tbd", "tba", "unk", "?", "-"}
+
+func (m *MediaMetadata) fields() []metadataField {
+	return []metadataField{
+		{key: "name", str: &m.Name},
+		{key: "showName", str: &m.ShowName},
+		{key: "alt_name", str: &m.AltName},
+		{key: "actors", strs: &m.Actors},
+		{key: "year", num: &m.Year},
+		{key: "description", str: &m.Description},
+		// The classifier format has always spelled it "langugae"
+		{key: "langugae", aliases: []string{"language"}, misspelt: true, str: &m.Language},
+		{key: "duration_min", num: &m.DurationMin},
+		{key: "season", num: &m.Season},
+		{key: "episode", num: &m.Episode},
+		{key: "extra_to", str: &m.ExtraTo},
+		{key: "artist", str: &m.Artist},
+		{key: "album_artist", str: &m.AlbumArtist},
+		{key: "album", str: &m.Album},
+		{key: "track", num: &m.Track},
+		{key: "disc", num: &m.Disc},
+		{key: "genre", str: &m.Genre},
+		{key: "mood", strs: &m.Mood},
+		{key: "age_rating", str: &m.AgeRating},
+		{key: "content_warnings", aliases: []string{"warnings"}, strs: &m.ContentWarnings},
+		{key: "imdb_id", str: &m.IMDbID},
+		{key: "tmdb_id", str: &m.TMDbID},
+	}
+}
+
+// ParseMetadata decodes metadata written by a classifier or a user. Unlike
+// decoding stored items, it fails on metadata which doesn't validate.
+func ParseMetadata(data []byte) (*MediaMetadata, error) {
+	var md MediaMetadata
+	if err := json.Unmarshal(data, &md); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+	}
+	if err := md.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid metadata: %w", err)
+	}
+	return &md, nil
+}
+
+// UnmarshalJSON decodes leniently, it only fails on malformed JSON.
+func (m *MediaMetadata) UnmarshalJSON(data []byte) error {
+	*m = MediaMetadata{}
+	if !json.Valid(data) {
+		return errors.New("metadata is not valid json")
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		m.invalid = append(json.RawMessage(nil), data...)
+		return nil
+	}
+	for idx, f := range m.fields() {
+		keys := f.decodeKeys()
+		for _, key := range keys {
+			v, ok := raw[key]
+			if !ok {
+				continue
+			}
+			if f.decode(v) {
+				for _, k := range keys {
+					delete(raw, k)
+				}
+				if f.num != nil && *f.num == 0 && isZero(v) {
+					m.zeros |= 1 << idx
+				}
+			}
+			break
+		}
+	}
+	if len(raw) > 0 {
+		m.Extra = raw
+	}
+	return nil
+}
+
+// isZero reports whether v is the number 0, quoted or not.
+func isZero(v json.RawMessage) bool {
+	var s string
+	if json.Unmarshal(v, &s) == nil {
+		v = json.RawMessage(strings.TrimSpace(s))
+	}
+	var n float64
+	return json.Unmarshal(v, &n) == nil && n == 0
+}
+
+// set reports whether the field at idx in fields is known.
+func (m *MediaMetadata) set(idx int, f metadataField) bool {
+	switch {
+	case f.str != nil:
+		return *f.str != ""
+	case f.num != nil:
+		return *f.num != 0 || m.zeros&(1<<idx) != 0
+	default:
+		return len(*f.strs) > 0
+	}
+}
+
+func (f metadataField) decode(v json.RawMessage) bool {
+	if string(v) == "null" {
+		return true
+	}
+	var s string
+	isString := json.Unmarshal(v, &s) == nil
+	s = strings.TrimSpace(s)
+	var n float64
+	isNumber := json.Unmarshal(v, &n) == nil
+	switch {
+	case f.str != nil:
+		switch {
+		case isString:
+			*f.str = s
+		case isNumber:
+			*f.str = strconv.FormatFloat(n, 'f', -1, 64)
+		default:
+			return false
+		}
+	case f.num != nil:
+		switch {
+		case isNumber:
+			*f.num = int(n)
+		case isString && slices.Contains(placeholders, strings.ToLower(s)):
+		case isString:
+			i, err := strconv.Atoi(s)
+			if err != nil {
+				return false
+			}
+			*f.num = i
+		default:
+			return false
+		}
+	case f.strs != nil:
+		if isString {
+			for a := range strings.SplitSeq(s, ",") {
+				if a = strings.TrimSpace(a); a != "" {
+					*f.strs = append(*f.strs, a)
+				}
+			}
+			return true
+		}
+		var arr []json.RawMessage
+		if json.Unmarshal(v, &arr) != nil {
+			return false
+		}
+		for _, e := range arr {
+			var a string
+			if json.Unmarshal(e, &a) == nil && strings.TrimSpace(a) != "" {
+				*f.strs = append(*f.strs, strings.TrimSpace(a))
+			}
+		}
+	}
+	return true
+}
+
+// MarshalJSON encodes the metadata as the flat object the classifier
+// formats describe, Extra included.
+func (m MediaMetadata) MarshalJSON() ([]byte, error) {
+	if m.invalid != nil {
+		return m.invalid, nil
+	}
+	out := make(map[string]json.RawMessage, len(m.Extra))
+	maps.Copy(out, m.Extra)
+	for idx, f := range m.fields() {
+		if !m.set(idx, f) {
+			continue
+		}
+		var v any
+		switch {
+		case f.str != nil:
+			v = *f.str
+		case f.num != nil:
+			v = *f.num
+		default:
+			v = *f.strs
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %v: %w", f.key, err)
+		}
+		out[f.key] = b
+	}
+	return json.Marshal(out)
+}
+
+// IsEmpty reports if nothing at all is known.
+func (m MediaMetadata) IsEmpty() bool {
+	if len(m.Extra) > 0 || m.invalid != nil {
+		return false
+	}
+	for idx, f := range m.fields() {
+		if m.set(idx, f) {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate reports values which couldn't be used, or which can't be right.
+func (m MediaMetadata) Validate() error {
+	if m.invalid != nil {
+		return fmt.Errorf("not a json object: %s", m.invalid)
+	}
+	if m.IsEmpty() {
+		return errors.New("no fields set")
+	}
+	var errs []error
+	for _, f := range m.fields() {
+		for _, key := range append([]string{f.key}, f.aliases...) {
+			if v, ok := m.Extra[key]; ok {
+				errs = append(errs, fmt.Errorf("%v: unusable value %s", key, v))
+			}
+		}
+		if f.num != nil && *f.num < 0 {
+			errs = append(errs, fmt.Errorf("%v: negative value %v", f.key, *f.num))
+		}
+	}
+	// The first films were shot in the late 1880s, and nothing is released
+	// years from now
+	if m.Year != 0 && (m.Year < 1880 || m.Year > time.Now().Year()+5) {
+		errs = append(errs, fmt.Errorf("year: %v is out of range", m.Year))
+	}
+	return errors.Join(errs...)
+}
+
+// Has reports if the field of the JSON key is set.
+func (m MediaMetadata) Has(key string) bool {
+	for idx, f := range m.fields() {
+		if f.key != key && !slices.Contains(f.aliases, key) {
+			continue
+		}
+		return m.set(idx, f)
+	}
+	_, ok := m.Extra[key]
+	return ok
+}
+
+// ExtraString is the string value of an unknown field, empty if there is none.
+func (m MediaMetadata) ExtraString(key string) string {
+	var s string
+	if v, ok := m.Extra[key]; ok && json.Unmarshal(v, &s) == nil {
+		return strings.TrimSpace(s)
+	}
+	return ""
+}
+
+// Matches reports if any text of the metadata contains the lowercase needle.
+func (m MediaMetadata) Matches(needle string) bool {
+	for _, f := range m.fields() {
+		texts := []string{}
+		switch {
+		case f.str != nil:
+			texts = append(texts, *f.str)
+		case f.strs != nil:
+			texts = *f.strs
+		}
+		for _, t := range texts {
+			if strings.Contains(strings.ToLower(t), needle) {
+				return true
+			}
+		}
+	}
+	for _, v := range m.Extra {
+		var extra any
+		if json.Unmarshal(v, &extra) == nil && SearchMetadata(extra, needle) {
+			return true
+		}
+	}
+	return false
+}
+
+// PatchMetadata merges the top-level keys of the JSON object patch into base,
+// the patch winning, and validates the result. base may be nil.
+func PatchMetadata(base *MediaMetadata, patch []byte) (*MediaMetadata, error) {
+	var incoming map[string]json.RawMessage
+	if err := json.Unmarshal(patch, &incoming); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal metadata, invalid json: %w", err)
+	}
+	merged := map[string]json.RawMessage{}
+	if base != nil {
+		b, err := json.Marshal(base)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal current metadata: %w", err)
+		}
+		// Current metadata which isn't an object is replaced by the patch
+		_ = json.Unmarshal(b, &merged)
+	}
+	// A patch spelling a key differently still replaces it
+	for _, f := range (&MediaMetadata{}).fields() {
+		keys := append([]string{f.key}, f.aliases...)
+		for _, key := range keys {
+			if _, ok := incoming[key]; ok {
+				for _, k := range keys {
+					delete(merged, k)
+				}
+				break
+			}
+		}
+	}
+	maps.Copy(merged, incoming)
+	b, err := json.Marshal(merged)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal merged metadata: %w", err)
+	}
+	return ParseMetadata(b)
+}
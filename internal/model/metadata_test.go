@@ -0,0 +1,187 @@
+package model
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMediaMetadata_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want MediaMetadata
+	}{
+		{
+			name: "typed fields",
+			in:   `{"name":"Heat","year":1995,"actors":["Al Pacino","Robert De Niro"],"langugae":"en"}`,
+			want: MediaMetadata{Name: "Heat", Year: 1995, Actors: []string{"Al Pacino", "Robert De Niro"}, Language: "en"},
+		},
+		{
+			name: "quoted numbers and comma separated actors",
+			in:   `{"season":"2","episode":6.0,"actors":"A, B,"}`,
+			want: MediaMetadata{Season: 2, Episode: 6, Actors: []string{"A", "B"}},
+		},
+		{
+			name: "language alias",
+			in:   `{"language":"sv"}`,
+			want: MediaMetadata{Language: "sv"},
+		},
+		{
+			name: "tags",
+			in:   `{"genre":"Crime, Thriller","mood":"tense, dark","age_rating":"R","warnings":["violence"]}`,
+			want: MediaMetadata{Genre: "Crime, Thriller", Mood: []string{"tense", "dark"}, AgeRating: "R", ContentWarnings: []string{"violence"}},
+		},
+		{
+			name: "correctly spelt language wins",
+			in:   `{"langugae":"en","language":"sv"}`,
+			want: MediaMetadata{Language: "sv"},
+		},
+		{
+			name: "placeholder numbers are unknown",
+			in:   `{"name":"Heat","year":"unknown","season":"N/A","episode":" - "}`,
+			want: MediaMetadata{Name: "Heat"},
+		},
+		{
+			name: "unknown and unusable values are kept",
+			in:   `{"name":"Heat","id":"tt0113277","year":"the nineties"}`,
+			want: MediaMetadata{Name: "Heat", Extra: map[string]json.RawMessage{
+				"id":   json.RawMessage(`"tt0113277"`),
+				"year": json.RawMessage(`"the nineties"`),
+			}},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var got MediaMetadata
+			if err := json.Unmarshal([]byte(tc.in), &got); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+
+	t.Run("malformed json fails", func(t *testing.T) {
+		var got MediaMetadata
+		if err := json.Unmarshal([]byte(`{"name":`), &got); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
+func TestMediaMetadata_roundTrip(t *testing.T) {
+	in := `{"alt_name":"Alt","custom":{"nested":true},"langugae":"en","name":"Heat","year":1995}`
+	var md MediaMetadata
+	if err := json.Unmarshal([]byte(in), &md); err != nil {
+		t.Fatal(err)
+	}
+	got, err := json.Marshal(md)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != in {
+		t.Errorf("got %s, want %s", got, in)
+	}
+
+	// Explicit zeros, such as the season of specials, aren't unknown
+	for in, want := range map[string]string{
+		`{"episode":0,"season":0,"showName":"Doctor Who"}`: `{"episode":0,"season":0,"showName":"Doctor Who"}`,
+		`{"season":"0","showName":"Doctor Who"}`:           `{"season":0,"showName":"Doctor Who"}`,
+		`{"season":"unknown","showName":"Doctor Who"}`:     `{"showName":"Doctor Who"}`,
+	} {
+		var md MediaMetadata
+		if err := json.Unmarshal([]byte(in), &md); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := json.Marshal(md); string(got) != want {
+			t.Errorf("%s: got %s, want %s", in, got, want)
+		}
+	}
+	var special MediaMetadata
+	if err := json.Unmarshal([]byte(`{"season":0}`), &special); err != nil {
+		t.Fatal(err)
+	}
+	if !special.Has("season") || special.Has("episode") || special.IsEmpty() {
+		t.Errorf("expected only the season known, got %+v", special)
+	}
+
+	var notObject MediaMetadata
+	if err := json.Unmarshal([]byte(`["a"]`), &notObject); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := json.Marshal(notObject); string(got) != `["a"]` {
+		t.Errorf("non-object metadata not kept as is: %s", got)
+	}
+}
+
+func TestParseMetadata(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		wantErr string
+	}{
+		{name: "valid", in: `{"name":"Heat","year":1995}`},
+		{name: "placeholder", in: `{"name":"Heat","year":"unknown"}`},
+		{name: "not an object", in: `"Heat"`, wantErr: "not a json object"},
+		{name: "empty", in: `{}`, wantErr: "no fields set"},
+		{name: "unusable value", in: `{"name":"Heat","season":"one"}`, wantErr: "season: unusable value"},
+		{name: "negative", in: `{"name":"Heat","episode":-1}`, wantErr: "negative value"},
+		{name: "year out of range", in: `{"name":"Heat","year":1500}`, wantErr: "out of range"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseMetadata([]byte(tc.in))
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("error = %v, want it to contain %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestPatchMetadata(t *testing.T) {
+	base := &MediaMetadata{Name: "Endgame", Season: 8, Language: "en", Extra: map[string]json.RawMessage{"id": json.RawMessage(`"x"`)}}
+	got, err := PatchMetadata(base, []byte(`{"showName":"Stargate SG-1","language":"sv"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &MediaMetadata{Name: "Endgame", ShowName: "Stargate SG-1", Season: 8, Language: "sv", Extra: map[string]json.RawMessage{"id": json.RawMessage(`"x"`)}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	special, err := PatchMetadata(base, []byte(`{"season":0}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := json.Marshal(special); !strings.Contains(string(b), `"season":0`) {
+		t.Errorf("expected the season patched to 0 kept, got %s", b)
+	}
+
+	if _, err := PatchMetadata(nil, []byte(`{not json`)); err == nil {
+		t.Error("expected error for invalid json")
+	}
+	if _, err := PatchMetadata(base, []byte(`{"year":"soon"}`)); err == nil {
+		t.Error("expected error for a patch which doesn't validate")
+	}
+}
+
+func TestMediaMetadata_Matches(t *testing.T) {
+	md := MediaMetadata{Name: "Heat", Actors: []string{"Al Pacino"}, Extra: map[string]json.RawMessage{"studio": json.RawMessage(`"Warner Bros"`)}}
+	for _, needle := range []string{"heat", "pacino", "warner"} {
+		if !md.Matches(needle) {
+			t.Errorf("expected %q to match", needle)
+		}
+	}
+	if md.Matches("matrix") {
+		t.Error("expected no match")
+	}
+}
@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// PhotoMetadata is the EXIF metadata of a photo.
+type PhotoMetadata struct {
+	TakenAt     time.Time `json:"takenAt,omitzero"`
+	CameraMake  string    `json:"cameraMake,omitempty"`
+	CameraModel string    `json:"cameraModel,omitempty"`
+	LensModel   string    `json:"lensModel,omitempty"`
+	// Orientation is the EXIF orientation, 1-8. 0 and 1 both mean upright.
+	Orientation int          `json:"orientation,omitempty"`
+	GPS         *Coordinates `json:"gps,omitempty"`
+}
+
+// Coordinates in decimal degrees, negative for south and west.
+type Coordinates struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// PhotoAlbum groups photos taken within the same period.
+type PhotoAlbum struct {
+	// Title is the period, such as "2024-05", or "Undated".
+	Title   string `json:"title"`
+	CoverID string `json:"coverId,omitempty"`
+	Photos  []Item `json:"photos"`
+}
+
+type PhotoAlbumsResponse struct {
+	Albums []PhotoAlbum `json:"albums"`
+}
@@ -0,0 +1,25 @@
+package model
+
+// Quote is a line of dialogue found in the subtitles of an item.
+type Quote struct {
+	ItemID string `json:"itemId"`
+	Name   string `json:"name"`
+	// StreamIndex of the subtitle stream the line is from.
+	StreamIndex int `json:"streamIndex"`
+	// Start and End of the line in seconds into the video, with the
+	// subtitle offset of the stream applied.
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
+	// Link plays the video from where the line is said.
+	Link string `json:"link"`
+}
+
+// QuotesResponse is the body of GET /gallery/quotes.
+type QuotesResponse struct {
+	Query  string  `json:"query"`
+	Quotes []Quote `json:"quotes"`
+	// Total is the number of matches, of which at most the limit are in
+	// Quotes.
+	Total int `json:"total"`
+}
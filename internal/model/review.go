@@ -0,0 +1,88 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Confidence the classifier had in each metadata field, from 0 (a guess) to 1
+// (certain), by the field's JSON key.
+type Confidence map[string]float64
+
+// ParseConfidence decodes the confidence object a classifier answered with.
+// It's as lenient as the metadata: scores may be quoted or given in percent.
+// Scores which can't be read are left out.
+func ParseConfidence(data []byte) Confidence {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil
+	}
+	c := make(Confidence, len(raw))
+	for k, v := range raw {
+		var f float64
+		if err := json.Unmarshal(v, &f); err != nil {
+			var s string
+			if json.Unmarshal(v, &s) != nil {
+				continue
+			}
+			f, err = strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "%"), 64)
+			if err != nil {
+				continue
+			}
+		}
+		if f > 1 {
+			f /= 100
+		}
+		c[k] = min(max(f, 0), 1)
+	}
+	if len(c) == 0 {
+		return nil
+	}
+	return c
+}
+
+// Review is set on items whose metadata shouldn't be trusted as is, until
+// someone accepts, edits or rejects it. The metadata is kept meanwhile, so
+// the item is browsable as usual.
+type Review struct {
+	// Reasons the metadata was flagged, e.g. low confidence in a field or a
+	// duration which doesn't match the file.
+	Reasons []string  `json:"reasons"`
+	Since   time.Time `json:"since"`
+}
+
+var (
+	// ErrNoReview is returned when resolving the review of an item which
+	// isn't waiting for one.
+	ErrNoReview = errors.New("no review pending")
+	// ErrInvalidDecision is returned when a review decision can't be
+	// carried out, such as an edit which doesn't validate.
+	ErrInvalidDecision = errors.New("invalid review decision")
+)
+
+type ReviewAction string
+
+const (
+	// ReviewAccept keeps the metadata as it is.
+	ReviewAccept ReviewAction = "accept"
+	// ReviewEdit patches the metadata, see PatchMetadata, and keeps it.
+	ReviewEdit ReviewAction = "edit"
+	// ReviewReject discards the metadata, so that the item is classified
+	// anew.
+	ReviewReject ReviewAction = "reject"
+)
+
+// ReviewDecision resolves the review of an item.
+type ReviewDecision struct {
+	Action ReviewAction `json:"action"`
+	// Metadata is the patch applied when editing.
+	Metadata json.RawMessage `json:"metadata,omitempty"`
+}
+
+// ReviewsResponse is the body of GET /gallery/review.
+type ReviewsResponse struct {
+	Items []Item `json:"items"`
+}
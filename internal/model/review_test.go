@@ -0,0 +1,28 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseConfidence(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want Confidence
+	}{
+		{"scores", `{"name":0.9,"year":0.4}`, Confidence{"name": 0.9, "year": 0.4}},
+		{"quoted and percent", `{"name":"0.5","year":"40%","season":80}`, Confidence{"name": 0.5, "year": 0.4, "season": 0.8}},
+		{"clamped", `{"name":-1}`, Confidence{"name": 0}},
+		{"unreadable left out", `{"name":"sure","year":0.4}`, Confidence{"year": 0.4}},
+		{"not an object", `[0.4]`, nil},
+		{"empty", `{}`, nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ParseConfidence([]byte(tc.in)); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("ParseConfidence(%s) = %v, want %v", tc.in, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package model
+
+import (
+	"slices"
+	"strings"
+)
+
+// TagKeys are the JSON keys of the tags of videos: their genre, mood, age
+// rating and content warnings. The classifier fills them in with the rest of
+// the metadata, or on their own for videos classified before they existed.
+var TagKeys = []string{"genre", "mood", "age_rating", "content_warnings"}
+
+// TagsVersion of the tags the classifier is asked for. Videos whose tags
+// were asked for by an older version, or never, have them backfilled.
+const TagsVersion = 1
+
+// HasTags reports whether any tag is known.
+func (m MediaMetadata) HasTags() bool {
+	return m.Genre != "" || len(m.Mood) > 0 || m.AgeRating != "" || len(m.ContentWarnings) > 0
+}
+
+// Genres of the metadata, Genre being comma separated.
+func (m MediaMetadata) Genres() []string {
+	return ParseTagList(m.Genre)
+}
+
+// SetTags to those of o, where o has any.
+func (m *MediaMetadata) SetTags(o MediaMetadata) {
+	if o.Genre != "" {
+		m.Genre = o.Genre
+	}
+	if len(o.Mood) > 0 {
+		m.Mood = o.Mood
+	}
+	if o.AgeRating != "" {
+		m.AgeRating = o.AgeRating
+	}
+	if len(o.ContentWarnings) > 0 {
+		m.ContentWarnings = o.ContentWarnings
+	}
+}
+
+// TagFilter narrows items down by their tags. Tags are compared without
+// case, and empty fields don't filter.
+type TagFilter struct {
+	// Genres of which the item must have one.
+	Genres []string `json:"genres,omitempty"`
+	// Moods of which the item must have one.
+	Moods []string `json:"moods,omitempty"`
+	// AgeRatings of which the item must have one, such as PG-13.
+	AgeRatings []string `json:"ageRatings,omitempty"`
+	// WithoutWarnings leaves out the items with a content warning which
+	// contains any of these, "violence" leaving out "graphic violence".
+	// Items whose warnings aren't known are kept.
+	WithoutWarnings []string `json:"withoutWarnings,omitempty"`
+}
+
+// IsZero reports whether the filter lets every item through.
+func (f TagFilter) IsZero() bool {
+	return len(f.Genres) == 0 && len(f.Moods) == 0 && len(f.AgeRatings) == 0 && len(f.WithoutWarnings) == 0
+}
+
+// Matches reports whether the item passes the filter.
+func (f TagFilter) Matches(it Item) bool {
+	if f.IsZero() {
+		return true
+	}
+	var md MediaMetadata
+	if it.Metadata != nil {
+		md = *it.Metadata
+	}
+	if len(f.Genres) > 0 && !anyTag(md.Genres(), f.Genres) {
+		return false
+	}
+	if len(f.Moods) > 0 && !anyTag(md.Mood, f.Moods) {
+		return false
+	}
+	if len(f.AgeRatings) > 0 && !anyTag([]string{md.AgeRating}, f.AgeRatings) {
+		return false
+	}
+	for _, w := range md.ContentWarnings {
+		w = strings.ToLower(w)
+		for _, without := range f.WithoutWarnings {
+			if strings.Contains(w, strings.ToLower(without)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// anyTag reports whether any of tags is one of want.
+func anyTag(tags, want []string) bool {
+	return slices.ContainsFunc(tags, func(t string) bool {
+		return t != "" && slices.ContainsFunc(want, func(w string) bool {
+			return strings.EqualFold(t, w)
+		})
+	})
+}
+
+// ParseTagList of comma separated tags, nil if there are none.
+func ParseTagList(s string) []string {
+	var ret []string
+	for t := range strings.SplitSeq(s, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			ret = append(ret, t)
+		}
+	}
+	return ret
+}
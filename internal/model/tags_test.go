@@ -0,0 +1,48 @@
+package model
+
+import "testing"
+
+func TestTagFilter_Matches(t *testing.T) {
+	heat := Item{Metadata: &MediaMetadata{
+		Genre:           "Crime, Thriller",
+		Mood:            []string{"tense"},
+		AgeRating:       "R",
+		ContentWarnings: []string{"graphic violence", "language"},
+	}}
+	unclassified := Item{}
+
+	tests := []struct {
+		name   string
+		filter TagFilter
+		item   Item
+		want   bool
+	}{
+		{name: "no filter", item: unclassified, want: true},
+		{name: "one of the genres", filter: TagFilter{Genres: []string{"thriller", "comedy"}}, item: heat, want: true},
+		{name: "other genre", filter: TagFilter{Genres: []string{"comedy"}}, item: heat, want: false},
+		{name: "mood", filter: TagFilter{Moods: []string{"Tense"}}, item: heat, want: true},
+		{name: "age rating", filter: TagFilter{AgeRatings: []string{"G", "PG"}}, item: heat, want: false},
+		{name: "warning contained", filter: TagFilter{WithoutWarnings: []string{"violence"}}, item: heat, want: false},
+		{name: "warning not there", filter: TagFilter{WithoutWarnings: []string{"drug use"}}, item: heat, want: true},
+		{name: "unknown warnings are kept", filter: TagFilter{WithoutWarnings: []string{"violence"}}, item: unclassified, want: true},
+		{name: "unknown genre is left out", filter: TagFilter{Genres: []string{"crime"}}, item: unclassified, want: false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.filter.Matches(tc.item); got != tc.want {
+				t.Errorf("Matches = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMediaMetadata_SetTags(t *testing.T) {
+	md := MediaMetadata{Name: "Heat", Genre: "Crime"}
+	md.SetTags(MediaMetadata{Name: "Other", Mood: []string{"tense"}, AgeRating: "R"})
+	if md.Name != "Heat" || md.Genre != "Crime" || md.AgeRating != "R" || len(md.Mood) != 1 {
+		t.Errorf("unexpected metadata: %+v", md)
+	}
+	if !md.HasTags() || (MediaMetadata{Name: "Heat"}).HasTags() {
+		t.Error("HasTags is off")
+	}
+}
@@ -0,0 +1,6 @@
+package model
+
+import "errors"
+
+// ErrNoTitle is returned when looking up a title a dataset doesn't have.
+var ErrNoTitle = errors.New("no such title")